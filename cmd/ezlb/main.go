@@ -2,31 +2,79 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/easzlab/ezlb/pkg/admin"
+	"github.com/easzlab/ezlb/pkg/cluster"
 	"github.com/easzlab/ezlb/pkg/config"
 	"github.com/easzlab/ezlb/pkg/logutil"
+	"github.com/easzlab/ezlb/pkg/lvs"
 	"github.com/easzlab/ezlb/pkg/server"
+	"github.com/easzlab/ezlb/pkg/simulate"
+	"github.com/easzlab/ezlb/pkg/soak"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 var (
-	BuildTime   string
-	BuildCommit string
-	Version     = "0.5.1"
-	configPath  string
-	showVersion bool
+	BuildTime    string
+	BuildCommit  string
+	Version      = "0.5.1"
+	configPath   string
+	adminAddress string
+	adminTLS     admin.ClientTLSConfig
+	showVersion  bool
+	observeOnly  bool
+	prune        bool
+	readOnly     bool
+	dryRun       bool
+	serviceName  string
+	requests     int
+	client       string
+	diagnostics  string
+	outputFormat string
+	exportFormat string
+	soakOpts     soak.Options
+	adoptOpts    struct {
+		service     string
+		healthCheck bool
+		dryRun      bool
+	}
 )
 
+// exitCodePanic is used when the daemon crashed, so supervisors
+// can distinguish crashes from graceful exits and ordinary errors.
+const exitCodePanic = 70
+
+// exitCodeDrift is used when once --observe-only found drift, so scripts can
+// distinguish drift from a failed check.
+const exitCodeDrift = 2
+
 func main() {
 	rootCmd := newRootCommand()
 	if err := rootCmd.Execute(); err != nil {
+		if errors.Is(err, server.ErrPanic) {
+			os.Exit(exitCodePanic)
+		}
+		if errors.Is(err, server.ErrDrift) {
+			os.Exit(exitCodeDrift)
+		}
 		os.Exit(1)
 	}
 }
@@ -53,6 +101,19 @@ func newRootCommand() *cobra.Command {
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show version information")
 	rootCmd.AddCommand(newOnceCommand())
 	rootCmd.AddCommand(newStartCommand())
+	rootCmd.AddCommand(newMaintenanceCommand())
+	rootCmd.AddCommand(newClusterCommand())
+	rootCmd.AddCommand(newPeersCommand())
+	rootCmd.AddCommand(newDiffCommand())
+	rootCmd.AddCommand(newPersistenceCommand())
+	rootCmd.AddCommand(newValidateCommand())
+	rootCmd.AddCommand(newAdoptCommand())
+	rootCmd.AddCommand(newExportCommand())
+	rootCmd.AddCommand(newImportCommand())
+	rootCmd.AddCommand(newSimulateCommand())
+	rootCmd.AddCommand(newNormalizeCommand())
+	rootCmd.AddCommand(newHashPreviewCommand())
+	rootCmd.AddCommand(newSoakCommand())
 
 	return rootCmd
 }
@@ -64,7 +125,12 @@ func newOnceCommand() *cobra.Command {
 		RunE:  runOnce,
 	}
 
-	onceCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file")
+	onceCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	onceCmd.Flags().BoolVar(&readOnly, "read-only", false, "Refuse every IPVS change, e.g. to check what a run would fail on")
+	onceCmd.Flags().BoolVar(&observeOnly, "observe-only", false, "Change nothing, report drift from the config and exit with status 2 if there is any")
+	onceCmd.Flags().BoolVar(&prune, "prune", false, "Delete every IPVS service that is not in the config, not only those ezlb manages")
+	onceCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the IPVS changes the run would apply without applying them")
+	onceCmd.Flags().StringVar(&diagnostics, "diagnostics", "", "Write a JSON summary of attempted changes and classified errors to this file, - for stderr")
 	return onceCmd
 }
 
@@ -75,10 +141,248 @@ func newStartCommand() *cobra.Command {
 		RunE:  startDaemon,
 	}
 
-	startCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file")
+	startCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	startCmd.Flags().BoolVar(&observeOnly, "observe-only", false, "Never change IPVS or iptables rules, only report drift from the config")
+	startCmd.Flags().BoolVar(&readOnly, "read-only", false, "Run health checks, metrics and the admin API but refuse every IPVS change")
+	startCmd.Flags().BoolVar(&prune, "prune", false, "Delete every IPVS service that is not in the config, not only those ezlb manages")
 	return startCmd
 }
 
+func newMaintenanceCommand() *cobra.Command {
+	maintenanceCmd := &cobra.Command{
+		Use:       "maintenance on|off|status",
+		Short:     "Drain or restore all services on a running daemon via the admin API",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"on", "off", "status"},
+		RunE:      runMaintenance,
+	}
+
+	maintenanceCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory (used to find global.admin_address)")
+	maintenanceCmd.Flags().StringVar(&adminAddress, "admin-address", "", "Admin server address, overrides global.admin_address")
+	addAdminTLSFlags(maintenanceCmd)
+	return maintenanceCmd
+}
+
+// addAdminTLSFlags adds the flags for reaching admin servers over TLS.
+func addAdminTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&adminTLS.CAFile, "tls-ca-file", "", "CA to verify admin servers with instead of the system roots; enables TLS")
+	cmd.Flags().StringVar(&adminTLS.CertFile, "tls-cert-file", "", "Client certificate for admin servers with global.admin_tls.client_ca_file; enables TLS")
+	cmd.Flags().StringVar(&adminTLS.KeyFile, "tls-key-file", "", "Key of --tls-cert-file")
+	cmd.Flags().StringVar(&adminTLS.ServerName, "tls-server-name", "", "Name to verify admin server certificates against, e.g. for a unix socket; enables TLS")
+}
+
+func newClusterCommand() *cobra.Command {
+	clusterCmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "Inspect a cluster of peer directors via their admin APIs",
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show an aggregated view of this director and its global.ha.peers",
+		Args:  cobra.NoArgs,
+		RunE:  runClusterStatus,
+	}
+	statusCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory (used to find global.admin_address and global.ha)")
+	statusCmd.Flags().StringVar(&adminAddress, "admin-address", "", "Admin server address of this director, overrides global.admin_address")
+	addAdminTLSFlags(statusCmd)
+
+	clusterCmd.AddCommand(statusCmd)
+	return clusterCmd
+}
+
+func newPeersCommand() *cobra.Command {
+	peersCmd := &cobra.Command{
+		Use:   "peers",
+		Short: "Compare this director with its global.ha.peers",
+	}
+
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Report service config and state mismatches between this director and its peers",
+		Args:  cobra.NoArgs,
+		RunE:  runPeersDiff,
+	}
+	diffCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory (used to find global.admin_address and global.ha)")
+	diffCmd.Flags().StringVar(&adminAddress, "admin-address", "", "Admin server address of this director, overrides global.admin_address")
+	addAdminTLSFlags(diffCmd)
+
+	peersCmd.AddCommand(diffCmd)
+	return peersCmd
+}
+
+func newDiffCommand() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show what applying a config would change in IPVS and SNAT rules, without applying it",
+		Long: "Load a candidate config, read the kernel state and print the differences: " +
+			"services, destinations, weights, forwarding methods and SNAT rules. Nothing is changed. " +
+			"Health checks do not run, so every backend counts as healthy. Exits non-zero if there are differences.",
+		Args: cobra.NoArgs,
+		RunE: runDiff,
+	}
+
+	diffCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to the candidate config file or directory")
+	diffCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+	return diffCmd
+}
+
+func newPersistenceCommand() *cobra.Command {
+	persistenceCmd := &cobra.Command{
+		Use:   "persistence",
+		Short: "List the IPVS persistence templates of the configured services",
+		Long: "Read the persistence templates from /proc/net/ip_vs_conn and print which backend each " +
+			"client sticks to and when the template expires, e.g. to see why a client keeps hitting a drained backend.",
+		Args: cobra.NoArgs,
+		RunE: runPersistence,
+	}
+
+	persistenceCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	persistenceCmd.Flags().StringVar(&serviceName, "service", "", "Only list templates of this service")
+	persistenceCmd.Flags().StringVar(&client, "client", "", "Only list templates of this client address or network")
+	persistenceCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+	return persistenceCmd
+}
+
+func newValidateCommand() *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check a config without applying it",
+		Long: "Load and validate the config, then check that its VIPs do not overlap the admin server or " +
+			"serve as backends and that the kernel provides its IPVS schedulers. IPVS is not touched. " +
+			"Exits non-zero if there are problems.",
+		RunE: runValidate,
+	}
+	validateCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	validateCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+	return validateCmd
+}
+
+func newAdoptCommand() *cobra.Command {
+	adoptCmd := &cobra.Command{
+		Use:   "adopt",
+		Short: "Take over an existing IPVS service by adding it to the config",
+		Long: "Read an IPVS service and its destinations from the kernel, for example one set up with " +
+			"ipvsadm, and append the matching service to the config, so that the running daemon manages " +
+			"it from its next reload without changing it. A config directory gets a new file named " +
+			"after the service. Health checks are disabled in the added service unless --health-check " +
+			"is given, so adopting cannot take backends out of rotation.",
+		Args: cobra.NoArgs,
+		RunE: runAdopt,
+	}
+
+	adoptCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	adoptCmd.Flags().StringVar(&adoptOpts.service, "service", "", "IPVS service to adopt: VIP:port/protocol or fwmark:MARK")
+	adoptCmd.Flags().StringVar(&serviceName, "name", "", "Name of the service in the config")
+	adoptCmd.Flags().BoolVar(&adoptOpts.healthCheck, "health-check", false, "Enable health checks for the adopted backends")
+	adoptCmd.Flags().BoolVar(&adoptOpts.dryRun, "dry-run", false, "Print the service without changing the config")
+	_ = adoptCmd.MarkFlagRequired("service")
+	_ = adoptCmd.MarkFlagRequired("name")
+	return adoptCmd
+}
+
+func newExportCommand() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the IPVS services and destinations of the kernel",
+		Long: "Print every IPVS service and destination of the kernel, including the ones ezlb does not " +
+			"manage. The ipvsadm format is that of `ipvsadm -S -n` and can be restored with `ipvsadm -R` " +
+			"or `ezlb import`; the json format also carries connection counts.",
+		Args: cobra.NoArgs,
+		RunE: runExport,
+	}
+
+	exportCmd.Flags().StringVar(&exportFormat, "format", "ipvsadm", "Output format: ipvsadm or json")
+	return exportCmd
+}
+
+func newImportCommand() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Add IPVS services and destinations from ipvsadm rules",
+		Long: "Apply rules in the format of `ipvsadm -S`, as written by `ezlb export`, to the kernel, " +
+			"like `ipvsadm -R`: services are added, then their destinations. Existing services are " +
+			"kept unless the rules start with -C. FILE - reads the rules from stdin. Services imported " +
+			"this way are not managed by ezlb until they are added to its config, e.g. with `ezlb adopt`.",
+		Args: cobra.ExactArgs(1),
+		RunE: runImport,
+	}
+	return importCmd
+}
+
+func newSimulateCommand() *cobra.Command {
+	simulateCmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Estimate how the configured schedulers and weights distribute connections",
+		Long: "Simulate new connections against the configured services without touching IPVS. " +
+			"The model is static: all backends are healthy, connections never close and every " +
+			"connection comes from a different client.",
+		Args: cobra.NoArgs,
+		RunE: runSimulate,
+	}
+
+	simulateCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	simulateCmd.Flags().StringVar(&serviceName, "service", "", "Service to simulate (default: all services)")
+	simulateCmd.Flags().IntVar(&requests, "requests", 10000, "Number of new connections to simulate")
+	return simulateCmd
+}
+
+func newNormalizeCommand() *cobra.Command {
+	normalizeCmd := &cobra.Command{
+		Use:   "normalize",
+		Short: "Print the config with all defaults filled in",
+		Long: "Load and validate the config and print it as a single YAML document with every " +
+			"default ezlb applies stated explicitly. The output is stable and is itself a valid config.",
+		Args: cobra.NoArgs,
+		RunE: runNormalize,
+	}
+
+	normalizeCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	return normalizeCmd
+}
+
+func newSoakCommand() *cobra.Command {
+	soakCmd := &cobra.Command{
+		Use:   "soak",
+		Short: "Stress the reconciler with a continuously mutated sandbox config",
+		Long: "Continuously add and remove sandbox services and backends, change weights and flap " +
+			"synthetic health, reconciling after every change and asserting that IPVS matches the " +
+			"config, that no drift is reported and that goroutines do not leak. Sandbox services use " +
+			"VIPs in 198.18.0.0/16 and are removed on exit. Builds with the integration tag run " +
+			"against real IPVS, other builds against the in-memory fake.",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		RunE:   runSoak,
+	}
+
+	soakCmd.Flags().DurationVar(&soakOpts.Duration, "duration", time.Hour, "How long to run")
+	soakCmd.Flags().IntVar(&soakOpts.Steps, "steps", 0, "Stop after this many mutations (default: until --duration)")
+	soakCmd.Flags().DurationVar(&soakOpts.Interval, "interval", 10*time.Millisecond, "Pause between mutations")
+	soakCmd.Flags().IntVar(&soakOpts.MaxServices, "services", 20, "Maximum number of sandbox services")
+	soakCmd.Flags().IntVar(&soakOpts.MaxBackends, "backends", 8, "Maximum number of backends per sandbox service")
+	soakCmd.Flags().Int64Var(&soakOpts.Seed, "seed", 0, "Random seed, to reproduce a failed run (default: current time)")
+	return soakCmd
+}
+
+func newHashPreviewCommand() *cobra.Command {
+	hashPreviewCmd := &cobra.Command{
+		Use:   "hash-preview",
+		Short: "Show which backend a client maps to under a service's sh, dh or mh scheduler",
+		Long: "Compute the hash bucket and backend the kernel's sh, dh or mh scheduler picks for a client, " +
+			"from the configured weights, assuming all backends of the most preferred priority tier are " +
+			"healthy and were created together with the service.",
+		Args: cobra.NoArgs,
+		RunE: runHashPreview,
+	}
+
+	hashPreviewCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config file or directory")
+	hashPreviewCmd.Flags().StringVar(&serviceName, "service", "", "Service to preview")
+	hashPreviewCmd.Flags().StringVar(&client, "client", "", "Client IP, optionally with a port (ip or ip:port)")
+	_ = hashPreviewCmd.MarkFlagRequired("service")
+	_ = hashPreviewCmd.MarkFlagRequired("client")
+	return hashPreviewCmd
+}
+
 // startDaemon starts the server in daemon mode with signal handling.
 func startDaemon(cmd *cobra.Command, args []string) error {
 	// Phase 1: Bootstrap logger (stdout only, info level) for early startup messages
@@ -116,6 +420,9 @@ func startDaemon(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		logger.Fatal("failed to create server", zap.Error(err))
 	}
+	srv.SetObserveOnly(observeOnly)
+	srv.SetReadOnly(readOnly)
+	srv.SetPrune(prune)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -160,18 +467,776 @@ func runOnce(cmd *cobra.Command, args []string) error {
 
 	// Phase 4: Create server
 	srv, err := server.NewServer(configPath, loggers.System, loggers.Traffic)
+	if err != nil {
+		err = fmt.Errorf("failed to create server: %w", err)
+		writeOnceDiagnostics(server.NewOnceDiagnostics(server.StageInit, err, nil))
+		return err
+	}
+	srv.SetReadOnly(readOnly)
+	srv.SetObserveOnly(observeOnly)
+	srv.SetPrune(prune)
+	// The plan is computed from reads only: the SNAT manager creates its
+	// chains on the first reconcile, which a dry run never starts
+	if dryRun {
+		return printPlan(cmd, srv)
+	}
+
+	err = srv.RunOnce()
+	writeOnceDiagnostics(server.NewOnceDiagnostics(server.StageReconcile, err, srv.LastOperations()))
+	if errors.Is(err, server.ErrLockNotHeld) {
+		// Another node applies this run; skipping it is not a failure
+		loggers.System.Info("distributed lock held by another node, skipping reconcile")
+		return nil
+	}
+	return err
+}
+
+// printPlan prints the IPVS changes a once run would apply.
+func printPlan(cmd *cobra.Command, srv *server.Server) error {
+	plan, err := srv.Plan()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	for _, op := range plan.Operations() {
+		fmt.Fprintln(out, formatOperation(op, color))
+	}
+	if plan.Empty() {
+		fmt.Fprintln(out, "no changes")
+	}
+	return nil
+}
+
+// writeOnceDiagnostics writes the --diagnostics summary, if requested. A
+// failure to write it is reported but does not change the exit status.
+func writeOnceDiagnostics(diag server.OnceDiagnostics) {
+	if diagnostics == "" {
+		return
+	}
+	data, err := json.MarshalIndent(diag, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to encode diagnostics: %v\n", err)
+		return
+	}
+	data = append(data, '\n')
+
+	if diagnostics == "-" {
+		_, err = os.Stderr.Write(data)
+	} else {
+		err = os.WriteFile(diagnostics, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write diagnostics: %v\n", err)
+	}
+}
+
+// runMaintenance toggles or queries maintenance mode on a running daemon.
+func runMaintenance(cmd *cobra.Command, args []string) error {
+	var body io.Reader
+	method := http.MethodGet
+	switch args[0] {
+	case "on":
+		method = http.MethodPut
+		body = strings.NewReader(`{"enabled":true}`)
+	case "off":
+		method = http.MethodPut
+		body = strings.NewReader(`{"enabled":false}`)
+	case "status":
+	default:
+		return fmt.Errorf("unknown maintenance action %q (supported: on, off, status)", args[0])
+	}
+
+	addr, tlsConfig, err := resolveAdminAddress()
+	if err != nil {
+		return err
+	}
+
+	client, baseURL := admin.ClientFor(&http.Client{Timeout: 10 * time.Second}, addr, tlsConfig)
+	req, err := http.NewRequest(method, baseURL+"/api/v1/maintenance", body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach admin server at %s: %w", addr, err)
+	}
+	defer resp.Body.Close()
+
+	var state struct {
+		Enabled bool `json:"enabled"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("admin server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return fmt.Errorf("failed to decode admin response: %w", err)
+	}
+
+	if state.Enabled {
+		fmt.Println("maintenance: on")
+	} else {
+		fmt.Println("maintenance: off")
+	}
+	return nil
+}
+
+// loadHAConfig pre-reads global.admin_address (or the --admin-address flag)
+// and the global.ha section from the config file. The returned TLS config is
+// set if the peers serve their admin API over TLS (see adminClientTLS).
+func loadHAConfig() (string, config.HAConfig, *tls.Config, error) {
+	v := viper.New()
+	if err := config.ReadConfigInto(v, configPath); err != nil {
+		return "", config.HAConfig{}, nil, err
+	}
+	var cfg struct {
+		Global struct {
+			Instance     string                   `mapstructure:"instance"`
+			AdminAddress string                   `mapstructure:"admin_address"`
+			AdminTLS     config.ListenerTLSConfig `mapstructure:"admin_tls"`
+			HA           config.HAConfig          `mapstructure:"ha"`
+		} `mapstructure:"global"`
+	}
+	if err := v.Unmarshal(&cfg); err != nil {
+		return "", config.HAConfig{}, nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	tlsConfig, err := adminClientTLS(cfg.Global.AdminTLS.CertFile != "")
+	if err != nil {
+		return "", config.HAConfig{}, nil, err
+	}
+
+	global := config.GlobalConfig{Instance: cfg.Global.Instance, AdminAddress: cfg.Global.AdminAddress}
+	local := global.GetAdminAddress()
+	if adminAddress != "" {
+		local = adminAddress
+	}
+	return local, cfg.Global.HA, tlsConfig, nil
+}
+
+// adminClientTLS returns the TLS config for reaching admin servers, or nil
+// for plain HTTP. TLS is used when configured is set, as the config serves
+// the admin API over TLS (global.admin_tls), or a --tls-* flag is given.
+func adminClientTLS(configured bool) (*tls.Config, error) {
+	if !configured && adminTLS == (admin.ClientTLSConfig{}) {
+		return nil, nil
+	}
+	return adminTLS.Load()
+}
+
+// fetchNodeStatuses queries the given directors with the global.ha timeout.
+func fetchNodeStatuses(ha config.HAConfig, tlsConfig *tls.Config, addrs []string) []cluster.NodeResult {
+	timeout := ha.GetTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return cluster.FetchStatuses(ctx, &http.Client{Timeout: timeout}, tlsConfig, addrs)
+}
+
+// runClusterStatus queries this director and its peers and prints the aggregated view.
+func runClusterStatus(cmd *cobra.Command, args []string) error {
+	local, ha, tlsConfig, err := loadHAConfig()
+	if err != nil {
+		return err
+	}
+
+	addrs := []string{}
+	if local != "" {
+		addrs = append(addrs, local)
+	}
+	addrs = append(addrs, ha.Peers...)
+	if len(addrs) == 0 {
+		return fmt.Errorf("no directors to query: configure global.admin_address and global.ha.peers")
+	}
+
+	summary := cluster.Summarize(fetchNodeStatuses(ha, tlsConfig, addrs))
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "ADDRESS\tNODE\tROLE\tGENERATION\tCONFIG\tMAINTENANCE\tUNHEALTHY")
+	for _, node := range summary.Nodes {
+		if node.Err != nil {
+			fmt.Fprintf(out, "%s\t-\tunreachable\t-\t-\t-\t-\n", node.Address)
+			continue
+		}
+		role := "backup"
+		if len(node.Status.HeldVIPs) > 0 {
+			role = "master"
+		}
+		unhealthy := 0
+		for _, healthy := range node.Status.Backends {
+			if !healthy {
+				unhealthy++
+			}
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%d\t%.12s\t%t\t%d\n",
+			node.Address, node.Status.Node, role, node.Status.ConfigGeneration,
+			node.Status.ConfigHash, node.Status.Maintenance, unhealthy)
+	}
+	out.Flush()
+
+	for _, node := range summary.Nodes {
+		if node.Err != nil {
+			fmt.Printf("\nwarning: %v\n", node.Err)
+		} else if since := node.Status.ReconcileFailingSince; since != nil {
+			fmt.Printf("\nwarning: %s: reconciles failing since %s: %s\n", node.Address, since.Format(time.RFC3339), node.Status.ReconcileError)
+		}
+	}
+	switch len(summary.Masters) {
+	case 0:
+		fmt.Println("\nmaster: none (no node holds a service VIP)")
+	case 1:
+		fmt.Printf("\nmaster: %s\n", summary.Masters[0])
+	default:
+		fmt.Printf("\nmaster: split brain, VIPs held by %s\n", strings.Join(summary.Masters, ", "))
+	}
+	if summary.ConfigConsistent() {
+		fmt.Println("config: consistent")
+	} else {
+		fmt.Println("config: MISMATCH")
+	}
+
+	if len(summary.HealthDivergence) > 0 {
+		fmt.Println("health divergence:")
+		backends := make([]string, 0, len(summary.HealthDivergence))
+		for backend := range summary.HealthDivergence {
+			backends = append(backends, backend)
+		}
+		sort.Strings(backends)
+		for _, backend := range backends {
+			var views []string
+			for _, node := range summary.Nodes {
+				healthy, ok := summary.HealthDivergence[backend][node.Address]
+				switch {
+				case node.Err != nil:
+					continue
+				case !ok:
+					views = append(views, node.Address+"=absent")
+				case healthy:
+					views = append(views, node.Address+"=healthy")
+				default:
+					views = append(views, node.Address+"=unhealthy")
+				}
+			}
+			fmt.Printf("  %s: %s\n", backend, strings.Join(views, " "))
+		}
+	}
+	return nil
+}
+
+// runPeersDiff compares the local director's services and state with each peer.
+func runPeersDiff(cmd *cobra.Command, args []string) error {
+	local, ha, tlsConfig, err := loadHAConfig()
+	if err != nil {
+		return err
+	}
+	if local == "" {
+		return fmt.Errorf("global.admin_address is not configured; use --admin-address")
+	}
+	if len(ha.Peers) == 0 {
+		return fmt.Errorf("no peers configured in global.ha.peers")
+	}
+
+	results := fetchNodeStatuses(ha, tlsConfig, append([]string{local}, ha.Peers...))
+	if results[0].Err != nil {
+		return results[0].Err
+	}
+
+	mismatches := cluster.Diff(results[0], results[1:])
+	if len(mismatches) == 0 {
+		fmt.Printf("all %d peers match %s (config %.12s)\n", len(ha.Peers), local, results[0].Status.ConfigHash)
+		return nil
+	}
+	for _, mismatch := range mismatches {
+		fmt.Println(mismatch)
+	}
+	return fmt.Errorf("%d mismatches found", len(mismatches))
+}
+
+// diffItem is the JSON form of a difference printed by ezlb diff.
+type diffItem struct {
+	Service string `json:"service,omitempty"`
+	Kind    string `json:"kind"`
+	Target  string `json:"target"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// runDiff prints the differences between a candidate config and the kernel.
+func runDiff(cmd *cobra.Command, args []string) error {
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("unknown output format %q, must be text or json", outputFormat)
+	}
+
+	logger := logutil.NewBootstrapLogger()
+	defer logger.Sync()
+	srv, err := server.NewServer(configPath, logger.WithOptions(zap.IncreaseLevel(zap.WarnLevel)), zap.NewNop())
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	// Diff only reads IPVS and iptables; the server's SNAT chains are not
+	// created until a reconcile runs
+	drifts, err := srv.Diff()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		items := make([]diffItem, 0, len(drifts))
+		for _, drift := range drifts {
+			items = append(items, diffItem{Service: drift.Service, Kind: drift.Kind, Target: drift.Target, Detail: drift.Detail})
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(items); err != nil {
+			return err
+		}
+	} else {
+		color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+		for _, drift := range drifts {
+			fmt.Fprintln(out, formatDrift(drift, color))
+		}
+		if len(drifts) == 0 {
+			fmt.Fprintln(out, "no differences")
+		}
+	}
+
+	if len(drifts) > 0 {
+		return fmt.Errorf("%d differences found", len(drifts))
+	}
+	return nil
+}
+
+// runPersistence prints the persistence templates of the configured services.
+func runPersistence(cmd *cobra.Command, args []string) error {
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("unknown output format %q, must be text or json", outputFormat)
+	}
+
+	logger := logutil.NewBootstrapLogger()
+	defer logger.Sync()
+	srv, err := server.NewServer(configPath, logger.WithOptions(zap.IncreaseLevel(zap.WarnLevel)), zap.NewNop())
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
+	all, err := srv.PersistenceTemplates()
+	if err != nil {
+		return err
+	}
+	templates := make([]admin.PersistenceTemplate, 0, len(all))
+	for _, template := range all {
+		if (serviceName == "" || template.Service == serviceName) && (client == "" || template.Client == client) {
+			templates = append(templates, template)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(admin.PersistenceTemplates{Templates: templates})
+	}
+	now := time.Now()
+	for _, template := range templates {
+		fmt.Fprintf(out, "%s: %s -> %s (expires in %s)\n",
+			template.Service, template.Client, template.Backend, template.ExpiresAt.Sub(now).Round(time.Second))
+	}
+	if len(templates) == 0 {
+		fmt.Fprintln(out, "no persistence templates")
+	}
+	return nil
+}
+
+// Validate problems that are not found by config.Lint.
+const (
+	problemInvalid              = "invalid"
+	problemSchedulerUnavailable = "scheduler_unavailable"
+)
+
+// runValidate prints the problems found in the config.
+func runValidate(cmd *cobra.Command, args []string) error {
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("unknown output format %q, must be text or json", outputFormat)
+	}
+
+	var problems []config.Problem
+	cfgManager, err := config.NewManager(configPath, zap.NewNop())
+	if err != nil {
+		problems = append(problems, config.Problem{Check: problemInvalid, Message: err.Error()})
+	} else {
+		cfg := cfgManager.GetConfig()
+		problems = append(problems, config.Lint(cfg)...)
+		checked := make(map[string]bool)
+		for _, svc := range config.EnabledServices(cfg.Services) {
+			if checked[svc.Scheduler] {
+				continue
+			}
+			checked[svc.Scheduler] = true
+			// Only a known-missing module is a problem; hosts without a module index are not
+			if err := lvs.CheckScheduler(svc.Scheduler); errors.Is(err, lvs.ErrSchedulerUnavailable) {
+				problems = append(problems, config.Problem{Check: problemSchedulerUnavailable, Service: svc.Name, Message: err.Error()})
+			}
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(append([]config.Problem{}, problems...)); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			fmt.Fprintln(out, problem)
+		}
+		if len(problems) == 0 {
+			fmt.Fprintln(out, "config is valid")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+	return nil
+}
+
+// runExport prints the kernel's IPVS services.
+func runExport(cmd *cobra.Command, args []string) error {
+	if exportFormat != "ipvsadm" && exportFormat != "json" {
+		return fmt.Errorf("unknown format %q, must be ipvsadm or json", exportFormat)
+	}
+	lvsMgr, err := lvs.NewManager(zap.NewNop())
+	if err != nil {
+		return fmt.Errorf("failed to open IPVS: %w", err)
+	}
+	defer lvsMgr.Close()
+
+	out := cmd.OutOrStdout()
+	if exportFormat == "ipvsadm" {
+		return lvsMgr.Save(out)
+	}
+	services, err := lvsMgr.Dump()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(services)
+}
+
+// runImport applies ipvsadm rules to the kernel.
+func runImport(cmd *cobra.Command, args []string) error {
+	in := cmd.InOrStdin()
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	lvsMgr, err := lvs.NewManager(zap.NewNop())
+	if err != nil {
+		return fmt.Errorf("failed to open IPVS: %w", err)
+	}
+	defer lvsMgr.Close()
+	return lvsMgr.Restore(in)
+}
+
+// runAdopt appends an existing IPVS service to the config.
+func runAdopt(cmd *cobra.Command, args []string) error {
+	key, err := lvs.ParseServiceKey(adoptOpts.service)
+	if err != nil {
+		return err
+	}
+	lvsMgr, err := lvs.NewManager(zap.NewNop())
+	if err != nil {
+		return fmt.Errorf("failed to open IPVS: %w", err)
+	}
+	defer lvsMgr.Close()
+	ipvsSvc, err := lvsMgr.GetService(key)
+	if err != nil {
+		return err
+	}
+	dests, err := lvsMgr.GetDestinations(ipvsSvc)
+	if err != nil {
+		return err
+	}
+	svc, err := lvs.IPVSToServiceConfig(serviceName, ipvsSvc, dests)
+	if err != nil {
+		return err
+	}
+	if !adoptOpts.healthCheck {
+		disabled := false
+		svc.HealthCheck.Enabled = &disabled
+	}
 
-	return srv.RunOnce()
+	out := cmd.OutOrStdout()
+	stanza, err := config.MarshalService(svc)
+	if err != nil {
+		return err
+	}
+	out.Write(stanza)
+	if adoptOpts.dryRun {
+		return nil
+	}
+	file, err := config.AppendService(configPath, svc)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "added service %q with %d backends to %s\n", svc.Name, len(svc.Backends), file)
+	return nil
+}
+
+// formatDrift formats a difference as a diff line: + for something that
+// would be created, - for something that would be or might be removed and
+// ~ for something that would be changed.
+func formatDrift(drift lvs.Drift, color bool) string {
+	sign, code := "~", "33"
+	switch drift.Kind {
+	case lvs.DriftMissingService, lvs.DriftMissingDestination, lvs.DriftMissingSNATRule:
+		sign, code = "+", "32"
+	case lvs.DriftUnexpectedDestination, lvs.DriftUnexpectedSNATRule, lvs.DriftUnconfiguredService:
+		sign, code = "-", "31"
+	}
+	line := sign + " " + drift.String()
+	if color {
+		line = "\033[" + code + "m" + line + "\033[0m"
+	}
+	return line
+}
+
+// formatOperation renders a planned operation as a diff line: + for a
+// create, - for a delete and ~ for an update, colored if requested.
+func formatOperation(op lvs.Operation, color bool) string {
+	sign, code := "~", "33"
+	switch op.Action {
+	case lvs.ActionCreate:
+		sign, code = "+", "32"
+	case lvs.ActionDelete:
+		sign, code = "-", "31"
+	}
+	line := sign + " " + op.String()
+	if color {
+		line = "\033[" + code + "m" + line + "\033[0m"
+	}
+	return line
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runSoak runs the soak test until the duration or step count is reached or
+// the process is interrupted.
+func runSoak(cmd *cobra.Command, args []string) error {
+	logger := logutil.NewBootstrapLogger()
+	defer logger.Sync()
+
+	if !cmd.Flags().Changed("seed") {
+		soakOpts.Seed = time.Now().UnixNano()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("starting soak run",
+		zap.Duration("duration", soakOpts.Duration),
+		zap.Int("steps", soakOpts.Steps),
+		zap.Int64("seed", soakOpts.Seed),
+	)
+	result, err := soak.Run(ctx, soakOpts, logger)
+	logger.Info("soak run finished",
+		zap.Int("steps", result.Steps),
+		zap.Any("mutations", result.Mutations),
+		zap.Int("max_services", result.MaxServices),
+		zap.Int("base_goroutines", result.BaseGoroutines),
+		zap.Int("max_goroutines", result.MaxGoroutines),
+		zap.Uint64("heap_start_bytes", result.HeapStart),
+		zap.Uint64("heap_end_bytes", result.HeapEnd),
+	)
+	return err
+}
+
+// runNormalize prints the normalized config.
+func runNormalize(cmd *cobra.Command, args []string) error {
+	cfgManager, err := config.NewManager(configPath, zap.NewNop())
+	if err != nil {
+		return err
+	}
+	out, err := config.Marshal(cfgManager.GetConfig())
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(out)
+	return err
+}
+
+// simulatedServices returns the service of cfg named name, or all services
+// if name is empty. path names the config in errors.
+func simulatedServices(cfg *config.Config, name, path string) ([]config.ServiceConfig, error) {
+	if len(cfg.Services) == 0 {
+		return nil, fmt.Errorf("no services configured in %s", path)
+	}
+	var services []config.ServiceConfig
+	for _, svc := range cfg.Services {
+		if name == "" || svc.Name == name {
+			services = append(services, svc)
+		}
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("service %q not found in %s", name, path)
+	}
+	return services, nil
+}
+
+// runSimulate prints the simulated connection distribution of the configured services.
+func runSimulate(cmd *cobra.Command, args []string) error {
+	cfgManager, err := config.NewManager(configPath, zap.NewNop())
+	if err != nil {
+		return err
+	}
+
+	services, err := simulatedServices(cfgManager.GetConfig(), serviceName, configPath)
+	if err != nil {
+		return err
+	}
+
+	// The halves of a dual-stack service balance separately, each over the
+	// backends of its address family
+	var halves []config.ServiceConfig
+	var labels []string
+	for _, svc := range services {
+		split := config.SplitDualStack(svc)
+		for j, half := range split {
+			label := half.Name
+			if len(split) > 1 {
+				label += []string{" (IPv4)", " (IPv6)"}[j]
+			}
+			halves = append(halves, half)
+			labels = append(labels, label)
+		}
+	}
+	for i, svc := range halves {
+		result, err := simulate.Run(svc, requests)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("service %s (scheduler %s, %d connections)\n", labels[i], result.Scheduler, result.Requests)
+
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(out, "BACKEND\tWEIGHT\tPRIORITY\tCONNECTIONS\tSHARE\tWEIGHT SHARE")
+		for _, backend := range result.Backends {
+			if backend.Standby {
+				fmt.Fprintf(out, "%s\t%d\t%d\t-\tstandby\t-\n", backend.Address, backend.Weight, backend.Priority)
+				continue
+			}
+			fmt.Fprintf(out, "%s\t%d\t%d\t%d\t%.1f%%\t%.1f%%\n", backend.Address, backend.Weight, backend.Priority,
+				backend.Connections, backend.Share*100, backend.WeightShare*100)
+		}
+		out.Flush()
+		if result.Dropped > 0 {
+			fmt.Printf("dropped: %d (%.1f%%) hashed to a backend with weight 0\n",
+				result.Dropped, float64(result.Dropped)/float64(result.Requests)*100)
+		}
+	}
+	return nil
+}
+
+// runHashPreview prints the backend a client is hashed to.
+func runHashPreview(cmd *cobra.Command, args []string) error {
+	clientIP, clientPort, err := parseClient(client)
+	if err != nil {
+		return err
+	}
+
+	cfgManager, err := config.NewManager(configPath, zap.NewNop())
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range cfgManager.GetConfig().Services {
+		if svc.Name != serviceName {
+			continue
+		}
+		mapping, err := simulate.Preview(svc, clientIP, clientPort)
+		if err != nil {
+			return err
+		}
+
+		clientDesc := mapping.Client.String()
+		if mapping.ClientPort != 0 {
+			clientDesc = net.JoinHostPort(clientDesc, strconv.Itoa(mapping.ClientPort))
+		}
+		fmt.Printf("service %s (scheduler %s)\n", mapping.Service, mapping.Scheduler)
+		fmt.Printf("client:  %s\n", clientDesc)
+		fmt.Printf("hashed:  %s\n", mapping.HashedAddress)
+		fmt.Printf("bucket:  %d\n", mapping.Bucket)
+		fmt.Printf("backend: %s\n", mapping.Backend)
+		if mapping.Dropped {
+			fmt.Println("the backend has weight 0, so IPVS finds no destination and drops the connection")
+		}
+		if mapping.Scheduler == "dh" {
+			fmt.Println("dh hashes the destination VIP, so every client of this service maps to the same backend")
+		}
+		return nil
+	}
+	return fmt.Errorf("service %q not found in %s", serviceName, configPath)
+}
+
+// parseClient parses an "ip" or "ip:port" client address.
+func parseClient(value string) (net.IP, int, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip, 0, nil
+	}
+	host, portStr, err := net.SplitHostPort(value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid client %q: %w", value, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid client IP %q", host)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return nil, 0, fmt.Errorf("invalid client port %q", portStr)
+	}
+	return ip, port, nil
+}
+
+// resolveAdminAddress returns the --admin-address flag or, if unset,
+// global.admin_address from the config file, and the TLS config to reach it
+// with (see adminClientTLS).
+func resolveAdminAddress() (string, *tls.Config, error) {
+	if adminAddress != "" {
+		tlsConfig, err := adminClientTLS(false)
+		return adminAddress, tlsConfig, err
+	}
+
+	v := viper.New()
+	if err := config.ReadConfigInto(v, configPath); err != nil {
+		return "", nil, err
+	}
+	global := config.GlobalConfig{
+		Instance:     v.GetString("global.instance"),
+		AdminAddress: v.GetString("global.admin_address"),
+	}
+	addr := global.GetAdminAddress()
+	if addr == "" {
+		return "", nil, fmt.Errorf("global.admin_address is not configured; use --admin-address")
+	}
+	tlsConfig, err := adminClientTLS(v.GetString("global.admin_tls.cert_file") != "")
+	return addr, tlsConfig, err
 }
 
 // loadLogConfig pre-reads only the global.log section from the config file.
 // This allows building proper loggers before the full config validation runs.
 func loadLogConfig(path string) (config.LogConfig, error) {
 	v := viper.New()
-	v.SetConfigFile(path)
 
 	// Set defaults matching config.NewManager
 	v.SetDefault("global.log.level", "info")
@@ -181,8 +1246,8 @@ func loadLogConfig(path string) (config.LogConfig, error) {
 	v.SetDefault("global.log.max_age", 0)
 	v.SetDefault("global.log.compress", false)
 
-	if err := v.ReadInConfig(); err != nil {
-		return config.LogConfig{}, fmt.Errorf("failed to read config file: %w", err)
+	if err := config.ReadConfigInto(v, path); err != nil {
+		return config.LogConfig{}, err
 	}
 
 	var cfg struct {
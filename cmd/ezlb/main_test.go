@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestSimulatedServices(t *testing.T) {
+	if _, err := simulatedServices(&config.Config{}, "", "ezlb.yaml"); err == nil || err.Error() != "no services configured in ezlb.yaml" {
+		t.Fatalf("expected a no services error, got %v", err)
+	}
+
+	cfg := &config.Config{Services: []config.ServiceConfig{{Name: "web"}, {Name: "api"}}}
+	if services, err := simulatedServices(cfg, "", "ezlb.yaml"); err != nil || len(services) != 2 {
+		t.Fatalf("expected every service, got %v, %v", services, err)
+	}
+	if services, err := simulatedServices(cfg, "api", "ezlb.yaml"); err != nil || len(services) != 1 || services[0].Name != "api" {
+		t.Fatalf("expected service api, got %v, %v", services, err)
+	}
+	if _, err := simulatedServices(cfg, "db", "ezlb.yaml"); err == nil || !strings.Contains(err.Error(), `service "db" not found`) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// AppliedChange is an IPVS change attempted by a reconcile, with the reason
+// the reconcile ran.
+type AppliedChange struct {
+	Time     time.Time `json:"time"`
+	Reason   string    `json:"reason"`
+	Service  string    `json:"service,omitempty"`
+	Resource string    `json:"resource"`
+	Action   string    `json:"action"`
+	Target   string    `json:"target"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ChangeHistory lists recent applied changes, oldest first.
+type ChangeHistory struct {
+	Changes []AppliedChange `json:"changes"`
+}
+
+// ChangeProvider reports the recent changes served by the change history
+// endpoint.
+type ChangeProvider interface {
+	Changes() []AppliedChange
+}
+
+// SetChangeProvider sets the provider used by the change history endpoint.
+func (s *Server) SetChangeProvider(provider ChangeProvider) {
+	s.changes = provider
+}
+
+// handleChanges serves the recent applied changes, optionally limited to a
+// service, a target and the changes since a time.
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+	if s.changes == nil {
+		http.Error(w, "change history not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "invalid since: expected an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	service, target := query.Get("service"), query.Get("target")
+
+	history := ChangeHistory{Changes: []AppliedChange{}}
+	for _, change := range s.changes.Changes() {
+		if service != "" && change.Service != service {
+			continue
+		}
+		if target != "" && change.Target != target {
+			continue
+		}
+		if change.Time.Before(since) {
+			continue
+		}
+		history.Changes = append(history.Changes, change)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
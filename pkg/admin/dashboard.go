@@ -0,0 +1,94 @@
+package admin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// DashboardState is the read-only snapshot rendered by the embedded web UI.
+type DashboardState struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Services    []DashboardService `json:"services"`
+	Events      []Event            `json:"events"`
+	Maintenance bool               `json:"maintenance"`
+}
+
+// DashboardService describes a configured virtual service and its backends.
+type DashboardService struct {
+	Name      string             `json:"name"`
+	Listen    string             `json:"listen"`
+	Protocol  string             `json:"protocol"`
+	Scheduler string             `json:"scheduler"`
+	Backends  []DashboardBackend `json:"backends"`
+	Traffic   []TrafficPoint     `json:"traffic"`
+}
+
+// DashboardBackend describes a backend of a virtual service.
+type DashboardBackend struct {
+	OverrideWeight *int                 `json:"override_weight,omitempty"`
+	ResponseTime   *BackendResponseTime `json:"response_time,omitempty"`
+	Address        string               `json:"address"`
+	ForwardMethod  string               `json:"forward_method"`
+	Excluded       string               `json:"excluded,omitempty"`
+	Weight         int                  `json:"weight"`
+	Priority       int                  `json:"priority"`
+	Healthy        bool                 `json:"healthy"`
+	Degraded       bool                 `json:"degraded"`
+}
+
+// BackendResponseTime summarizes the recent HTTP health check response times
+// of a backend, in milliseconds.
+type BackendResponseTime struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+// TrafficPoint is a sample of cumulative service counters taken by the stats poller.
+type TrafficPoint struct {
+	Time        time.Time `json:"time"`
+	Connections uint64    `json:"connections"`
+	InBytes     uint64    `json:"in_bytes"`
+	OutBytes    uint64    `json:"out_bytes"`
+}
+
+// Event is a notable change recorded by the director, newest last.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// DashboardProvider builds the state shown by the embedded web UI.
+type DashboardProvider interface {
+	DashboardState() DashboardState
+}
+
+// SetDashboardProvider sets the provider used by the dashboard endpoints.
+func (s *Server) SetDashboardProvider(provider DashboardProvider) {
+	s.dashboard = provider
+}
+
+// handleDashboard serves the embedded single-page dashboard.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// handleDashboardState serves the JSON snapshot polled by the dashboard.
+func (s *Server) handleDashboardState(w http.ResponseWriter, r *http.Request) {
+	if s.dashboard == nil {
+		http.Error(w, "dashboard not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.dashboard.DashboardState())
+}
@@ -0,0 +1,265 @@
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/secret"
+)
+
+// unixPrefix marks a listen address as a unix socket path, e.g.
+// unix:/run/ezlb/admin.sock.
+const unixPrefix = "unix:"
+
+// TLSConfig holds the certificate files of a TLS listener. Setting
+// ClientCAFile requires clients to present a certificate signed by one of
+// its CAs (mutual TLS).
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// IsEnabled reports whether the listener serves TLS.
+func (c TLSConfig) IsEnabled() bool {
+	return c.CertFile != ""
+}
+
+// load builds the server TLS config. The certificate and key are loaded
+// again when either file changes, so a renewed certificate is served without
+// a restart; the key file must not be accessible by other users.
+func (c TLSConfig) load() (*tls.Config, error) {
+	pair := &keyPair{certFile: c.CertFile, keyFile: c.KeyFile}
+	if _, err := pair.load(); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{GetCertificate: pair.getCertificate, MinVersion: tls.VersionTLS12}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA file %s contains no certificates", c.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// ClientTLSConfig holds the files a client uses to reach a TLS admin
+// listener. CAFile verifies the server instead of the system roots; CertFile
+// and KeyFile are presented to a listener that requires client certificates.
+// ServerName overrides the name the server certificate is verified against,
+// e.g. for a unix socket.
+type ClientTLSConfig struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// Load builds the client TLS config.
+func (c ClientTLSConfig) Load() (*tls.Config, error) {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	tlsConfig := &tls.Config{ServerName: c.ServerName, MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no certificates", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := (&keyPair{certFile: c.CertFile, keyFile: c.KeyFile}).load()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+	return tlsConfig, nil
+}
+
+// keyPair is a certificate and key loaded from files, cached until either
+// file's modification time changes.
+type keyPair struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// load returns the certificate, reading the files again if they changed.
+func (k *keyPair) load() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	certInfo, err := os.Stat(k.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(k.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if k.cert != nil && certInfo.ModTime().Equal(k.certMod) && keyInfo.ModTime().Equal(k.keyMod) {
+		return k.cert, nil
+	}
+	if err := secret.CheckFile(k.keyFile); err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	k.cert, k.certMod, k.keyMod = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	return k.cert, nil
+}
+
+// getCertificate serves the current certificate. While the files cannot be
+// loaded, e.g. halfway through a renewal, the last loaded one is served.
+func (k *keyPair) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := k.load()
+	if err != nil {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if k.cert != nil {
+			return k.cert, nil
+		}
+	}
+	return cert, err
+}
+
+// IsUnixAddress reports whether address names a unix socket.
+func IsUnixAddress(address string) bool {
+	return strings.HasPrefix(address, unixPrefix)
+}
+
+// socketPath returns the path of a unix socket address, accepting both
+// unix:/path and unix:///path.
+func socketPath(address string) string {
+	path := strings.TrimPrefix(address, unixPrefix)
+	if strings.HasPrefix(path, "//") {
+		path = strings.TrimPrefix(path, "//")
+	}
+	return path
+}
+
+// BindAddress returns the address a listener binds to. An address without
+// a host, such as :9095, binds to localhost only; 0.0.0.0:9095 or [::]:9095
+// bind to every interface.
+func BindAddress(address string) string {
+	if IsUnixAddress(address) {
+		return address
+	}
+	if host, port, err := net.SplitHostPort(address); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return address
+}
+
+// listen opens a listener on a host:port or unix socket address, serving
+// TLS if tlsConfig is enabled. A stale socket file left by a previous run is
+// replaced and a missing socket directory created; the socket is only
+// accessible to its owner and group. A socket that still accepts connections
+// belongs to a running daemon and is left alone.
+func listen(address string, tlsConfig TLSConfig) (net.Listener, error) {
+	var listener net.Listener
+	if IsUnixAddress(address) {
+		path := socketPath(address)
+		if path == "" {
+			return nil, fmt.Errorf("invalid listen address %q: empty socket path", address)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create socket directory: %w", err)
+		}
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			conn, err := net.DialTimeout("unix", path, time.Second)
+			if err == nil {
+				conn.Close()
+				return nil, fmt.Errorf("failed to create listener: socket %s is in use by another process", path)
+			}
+			// Only a socket nobody listens on any more is stale
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				os.Remove(path)
+			}
+		}
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create listener: %w", err)
+		}
+		if err := os.Chmod(path, 0660); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+		}
+		listener = l
+	} else {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+		l, err := net.Listen("tcp", BindAddress(address))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create listener: %w", err)
+		}
+		listener = l
+	}
+
+	if !tlsConfig.IsEnabled() {
+		return listener, nil
+	}
+	serverTLS, err := tlsConfig.load()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return tls.NewListener(listener, serverTLS), nil
+}
+
+// ClientFor adapts client to reach the admin server at address and returns
+// the base URL of its API. For a unix socket address the returned client
+// dials the socket. With tlsConfig the server is reached over TLS, for a
+// listener with global.admin_tls; otherwise over plain HTTP.
+func ClientFor(client *http.Client, address string, tlsConfig *tls.Config) (*http.Client, string) {
+	if !IsUnixAddress(address) && tlsConfig == nil {
+		return client, "http://" + BindAddress(address)
+	}
+	adapted := *client
+	transport := &http.Transport{}
+	host := BindAddress(address)
+	if IsUnixAddress(address) {
+		path := socketPath(address)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		host = "unix"
+	}
+	scheme := "http://"
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
+		scheme = "https://"
+	}
+	adapted.Transport = transport
+	return &adapted, scheme + host
+}
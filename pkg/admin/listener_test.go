@@ -0,0 +1,314 @@
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestBindAddress(t *testing.T) {
+	tests := map[string]string{
+		":9095":               "127.0.0.1:9095",
+		"0.0.0.0:9095":        "0.0.0.0:9095",
+		"10.0.0.1:9095":       "10.0.0.1:9095",
+		"[::]:9095":           "[::]:9095",
+		"unix:/run/ezlb.sock": "unix:/run/ezlb.sock",
+	}
+	for address, want := range tests {
+		if got := BindAddress(address); got != want {
+			t.Errorf("BindAddress(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+// startTestServer starts an admin server and stops it when the test ends.
+func startTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	server := NewServer(cfg, zap.NewNop())
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+	return server
+}
+
+// getStatus returns the status code of a GET request.
+func getStatus(t *testing.T, client *http.Client, url string) int {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	// A stale socket file from a previous run is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	address := "unix:" + socket
+	startTestServer(t, Config{ListenAddr: address, MetricsEnabled: true})
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0660 {
+		t.Errorf("expected socket permissions 0660, got %o", perm)
+	}
+
+	client, baseURL := ClientFor(&http.Client{Timeout: 5 * time.Second}, address, nil)
+	if code := getStatus(t, client, baseURL+"/health"); code != http.StatusOK {
+		t.Errorf("expected /health over the socket to return 200, got %d", code)
+	}
+}
+
+func TestServerUnixSocketInUse(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	address := "unix:" + socket
+	startTestServer(t, Config{ListenAddr: address})
+
+	// A second daemon must not take over the socket of a running one
+	second := NewServer(Config{ListenAddr: address}, zap.NewNop())
+	if err := second.Start(); err == nil || !strings.Contains(err.Error(), "in use by another process") {
+		second.Stop(context.Background())
+		t.Fatalf("expected the socket in use to be refused, got %v", err)
+	}
+
+	client, baseURL := ClientFor(&http.Client{Timeout: 5 * time.Second}, address, nil)
+	if code := getStatus(t, client, baseURL+"/health"); code != http.StatusOK {
+		t.Errorf("expected the running server to keep its socket, got %d", code)
+	}
+}
+
+func TestSeparateMetricsListener(t *testing.T) {
+	server := startTestServer(t, Config{
+		ListenAddr:     "127.0.0.1:0",
+		MetricsAddr:    "127.0.0.1:0",
+		MetricsEnabled: true,
+		MetricsPath:    "/metrics",
+	})
+	if server.MetricsAddr() == "" || server.MetricsAddr() == server.Addr() {
+		t.Fatalf("expected a separate metrics listener, got admin %q and metrics %q", server.Addr(), server.MetricsAddr())
+	}
+
+	if code := getStatus(t, http.DefaultClient, "http://"+server.MetricsAddr()+"/metrics"); code != http.StatusOK {
+		t.Errorf("expected metrics on the metrics listener, got %d", code)
+	}
+	if code := getStatus(t, http.DefaultClient, "http://"+server.Addr()+"/metrics"); code != http.StatusNotFound {
+		t.Errorf("expected no metrics on the admin listener, got %d", code)
+	}
+	if code := getStatus(t, http.DefaultClient, "http://"+server.MetricsAddr()+"/health"); code != http.StatusNotFound {
+		t.Errorf("expected no admin API on the metrics listener, got %d", code)
+	}
+}
+
+func TestMetricsListenerOnly(t *testing.T) {
+	server := startTestServer(t, Config{MetricsAddr: "127.0.0.1:0", MetricsEnabled: true})
+	if !server.IsEnabled() || server.Addr() != "" || server.MetricsAddr() == "" {
+		t.Fatalf("expected only the metrics listener, got admin %q and metrics %q", server.Addr(), server.MetricsAddr())
+	}
+	if code := getStatus(t, http.DefaultClient, "http://"+server.MetricsAddr()+"/metrics"); code != http.StatusOK {
+		t.Errorf("expected metrics on the metrics listener, got %d", code)
+	}
+}
+
+// testPKI holds the files of a test CA and of a server certificate for
+// 127.0.0.1 it issued, and a client certificate issued by the same CA.
+type testPKI struct {
+	caFile, certFile, keyFile     string
+	clientCertFile, clientKeyFile string
+	caPool                        *x509.CertPool
+	client                        tls.Certificate
+}
+
+// newTestPKI creates a testPKI in a temp directory.
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ezlb test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "ezlb test"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		return der, key
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	pki := testPKI{caFile: writePEM("ca.pem", "CERTIFICATE", caDER), caPool: x509.NewCertPool()}
+	pki.caPool.AddCert(caCert)
+	serverDER, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	serverKeyDER, _ := x509.MarshalECPrivateKey(serverKey)
+	pki.certFile = writePEM("server.pem", "CERTIFICATE", serverDER)
+	pki.keyFile = writePEM("server-key.pem", "EC PRIVATE KEY", serverKeyDER)
+	clientDER, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+	pki.client = tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+	clientKeyDER, _ := x509.MarshalECPrivateKey(clientKey)
+	pki.clientCertFile = writePEM("client.pem", "CERTIFICATE", clientDER)
+	pki.clientKeyFile = writePEM("client-key.pem", "EC PRIVATE KEY", clientKeyDER)
+	return pki
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	server := startTestServer(t, Config{
+		ListenAddr: "127.0.0.1:0",
+		TLS:        TLSConfig{CertFile: pki.certFile, KeyFile: pki.keyFile, ClientCAFile: pki.caFile},
+	})
+	url := "https://" + server.Addr() + "/health"
+
+	withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pki.caPool}}}
+	if resp, err := withoutCert.Get(url); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected a client without a certificate to be rejected")
+	}
+
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      pki.caPool,
+		Certificates: []tls.Certificate{pki.client},
+	}}}
+	if code := getStatus(t, withCert, url); code != http.StatusOK {
+		t.Errorf("expected a client certificate to be accepted, got %d", code)
+	}
+}
+
+func TestClientForMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	serverTLS := TLSConfig{CertFile: pki.certFile, KeyFile: pki.keyFile, ClientCAFile: pki.caFile}
+	tcpServer := startTestServer(t, Config{ListenAddr: "127.0.0.1:0", TLS: serverTLS})
+	socket := "unix:" + filepath.Join(t.TempDir(), "admin.sock")
+	startTestServer(t, Config{ListenAddr: socket, TLS: serverTLS})
+
+	clientTLS, err := ClientTLSConfig{CAFile: pki.caFile, CertFile: pki.clientCertFile, KeyFile: pki.clientKeyFile}.Load()
+	if err != nil {
+		t.Fatalf("failed to load client TLS config: %v", err)
+	}
+	client, baseURL := ClientFor(&http.Client{Timeout: 5 * time.Second}, tcpServer.Addr(), clientTLS)
+	if baseURL != "https://"+tcpServer.Addr() {
+		t.Errorf("expected an https base URL, got %s", baseURL)
+	}
+	if code := getStatus(t, client, baseURL+"/health"); code != http.StatusOK {
+		t.Errorf("expected /health over mutual TLS to return 200, got %d", code)
+	}
+
+	// The server certificate is issued for 127.0.0.1, not the socket
+	clientTLS.ServerName = "127.0.0.1"
+	client, baseURL = ClientFor(&http.Client{Timeout: 5 * time.Second}, socket, clientTLS)
+	if code := getStatus(t, client, baseURL+"/health"); code != http.StatusOK {
+		t.Errorf("expected /health over TLS on the socket to return 200, got %d", code)
+	}
+
+	if _, err := (ClientTLSConfig{CertFile: pki.clientCertFile}).Load(); err == nil {
+		t.Error("expected a client certificate without a key to be rejected")
+	}
+}
+
+func TestServerTLSMissingCertificate(t *testing.T) {
+	server := NewServer(Config{
+		ListenAddr: "127.0.0.1:0",
+		TLS:        TLSConfig{CertFile: "/nonexistent/cert.pem", KeyFile: "/nonexistent/key.pem"},
+	}, zap.NewNop())
+	if err := server.Start(); err == nil {
+		server.Stop(context.Background())
+		t.Fatal("expected a missing certificate to fail the start")
+	}
+}
+
+func TestServerTLSRejectsKeyReadableByOthers(t *testing.T) {
+	pki := newTestPKI(t)
+	if err := os.Chmod(pki.keyFile, 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(Config{
+		ListenAddr: "127.0.0.1:0",
+		TLS:        TLSConfig{CertFile: pki.certFile, KeyFile: pki.keyFile},
+	}, zap.NewNop())
+	if err := server.Start(); err == nil {
+		server.Stop(context.Background())
+		t.Fatal("expected a key file readable by other users to fail the start")
+	}
+}
+
+func TestServerTLSReloadsRenewedCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	server := startTestServer(t, Config{
+		ListenAddr: "127.0.0.1:0",
+		TLS:        TLSConfig{CertFile: pki.certFile, KeyFile: pki.keyFile},
+	})
+	url := "https://" + server.Addr() + "/health"
+
+	// Renew with a certificate from another CA, which only a client trusting
+	// that CA accepts.
+	renewed := newTestPKI(t)
+	later := time.Now().Add(time.Second)
+	for src, dst := range map[string]string{renewed.certFile: pki.certFile, renewed.keyFile: pki.keyFile} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dst, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: renewed.caPool}}}
+	if code := getStatus(t, client, url); code != http.StatusOK {
+		t.Errorf("expected the renewed certificate to be served, got %d", code)
+	}
+}
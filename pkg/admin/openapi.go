@@ -0,0 +1,18 @@
+package admin
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3 document of the admin API. It is maintained by
+// hand next to the handlers and must be updated whenever a route changes.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// handleOpenAPI serves the OpenAPI document so clients can be generated from a running director.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
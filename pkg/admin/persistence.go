@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// PersistenceTemplate is a client's stickiness to a backend of a persistent
+// service, as kept by IPVS until ExpiresAt.
+type PersistenceTemplate struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Service   string    `json:"service"`
+	Client    string    `json:"client"`
+	Backend   string    `json:"backend"`
+}
+
+// PersistenceTemplates lists the current persistence templates.
+type PersistenceTemplates struct {
+	Templates []PersistenceTemplate `json:"templates"`
+}
+
+// PersistenceProvider reads the templates served by the persistence endpoint.
+type PersistenceProvider interface {
+	PersistenceTemplates() ([]PersistenceTemplate, error)
+}
+
+// SetPersistenceProvider sets the provider used by the persistence endpoint.
+func (s *Server) SetPersistenceProvider(provider PersistenceProvider) {
+	s.persistence = provider
+}
+
+// handlePersistence serves the current persistence templates, optionally
+// limited to a service, a client and a backend.
+func (s *Server) handlePersistence(w http.ResponseWriter, r *http.Request) {
+	if s.persistence == nil {
+		http.Error(w, "persistence templates not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	templates, err := s.persistence.PersistenceTemplates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	service, client, backend := query.Get("service"), query.Get("client"), query.Get("backend")
+	result := PersistenceTemplates{Templates: []PersistenceTemplate{}}
+	for _, template := range templates {
+		if service != "" && template.Service != service {
+			continue
+		}
+		if client != "" && template.Client != client {
+			continue
+		}
+		if backend != "" && template.Backend != backend {
+			continue
+		}
+		result.Templates = append(result.Templates, template)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// PlannedChange is an IPVS change the next reconcile would apply.
+type PlannedChange struct {
+	Service  string `json:"service,omitempty"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+}
+
+// ReconcilePlan lists the IPVS changes the next reconcile would apply, in
+// the order it applies them.
+type ReconcilePlan struct {
+	Changes []PlannedChange `json:"changes"`
+}
+
+// PlanProvider computes the reconcile plan served by the plan endpoint.
+type PlanProvider interface {
+	Plan() (ReconcilePlan, error)
+}
+
+// SetPlanProvider sets the provider used by the reconcile plan endpoint.
+func (s *Server) SetPlanProvider(provider PlanProvider) {
+	s.plan = provider
+}
+
+// handlePlan serves the changes the next reconcile would apply.
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	if s.plan == nil {
+		http.Error(w, "reconcile plan not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	plan, err := s.plan.Plan()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
@@ -2,6 +2,8 @@ package admin
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,24 +12,71 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+
+	"github.com/easzlab/ezlb/pkg/config"
 )
 
+// ErrNotFound is returned by admin handlers when the requested service or backend does not exist.
+var ErrNotFound = errors.New("not found")
+
+// WeightOverride describes a runtime backend weight override as reported by the admin API.
+type WeightOverride struct {
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	Service          string     `json:"service"`
+	Backend          string     `json:"backend"`
+	Status           string     `json:"status"`
+	Weight           int        `json:"weight"`
+	ConfiguredWeight int        `json:"configured_weight"`
+}
+
+// WeightOverrideHandler applies and resets runtime backend weight overrides.
+// A ttl of zero means the override stays in effect until explicitly reset.
+type WeightOverrideHandler interface {
+	SetWeightOverride(service, backend string, weight int, ttl time.Duration) error
+	ResetWeightOverride(service, backend string) error
+	WeightOverrides() []WeightOverride
+}
+
+// MaintenanceController toggles and reports director-wide maintenance mode.
+type MaintenanceController interface {
+	SetMaintenance(enabled bool)
+	InMaintenance() bool
+}
+
 // Server provides an HTTP admin interface for metrics and health checks.
 type Server struct {
 	listener        net.Listener
 	logger          *zap.Logger
 	server          *http.Server
+	metricsServer   *http.Server
 	healthCheckFunc func() map[string]bool
+	overrideHandler WeightOverrideHandler
+	maintenance     MaintenanceController
+	dashboard       DashboardProvider
+	status          StatusProvider
+	plan            PlanProvider
+	changes         ChangeProvider
+	persistence     PersistenceProvider
 	listenAddr      string
 	actualAddr      string
+	metricsAddr     string
+	actualMetrics   string
 	metricsPath     string
+	tls             TLSConfig
+	metricsTLS      TLSConfig
 	metricsEnabled  bool
 }
 
 // Config holds the configuration for the admin server.
+// ListenAddr and MetricsAddr are host:port or unix:/path addresses (see
+// BindAddress). If MetricsAddr is set, metrics are served on their own
+// listener instead of the admin one.
 type Config struct {
 	ListenAddr     string
+	MetricsAddr    string
 	MetricsPath    string
+	TLS            TLSConfig
+	MetricsTLS     TLSConfig
 	MetricsEnabled bool
 }
 
@@ -35,8 +84,11 @@ type Config struct {
 func NewServer(cfg Config, logger *zap.Logger) *Server {
 	return &Server{
 		listenAddr:     cfg.ListenAddr,
+		metricsAddr:    cfg.MetricsAddr,
 		metricsEnabled: cfg.MetricsEnabled,
 		metricsPath:    cfg.MetricsPath,
+		tls:            cfg.TLS,
+		metricsTLS:     cfg.MetricsTLS,
 		logger:         logger,
 	}
 }
@@ -46,24 +98,38 @@ func (s *Server) SetHealthCheckFunc(fn func() map[string]bool) {
 	s.healthCheckFunc = fn
 }
 
+// SetWeightOverrideHandler sets the handler used by the backend weight override API.
+func (s *Server) SetWeightOverrideHandler(handler WeightOverrideHandler) {
+	s.overrideHandler = handler
+}
+
+// SetMaintenanceController sets the controller used by the maintenance mode API.
+func (s *Server) SetMaintenanceController(controller MaintenanceController) {
+	s.maintenance = controller
+}
+
 // Start starts the admin HTTP server in a background goroutine.
 // Returns an error if the server cannot start.
 func (s *Server) Start() error {
-	if s.listenAddr == "" {
+	if s.listenAddr == "" && s.metricsAddr == "" {
 		s.logger.Info("admin server disabled: no listen address configured")
 		return nil
 	}
 
+	if s.metricsAddr != "" && s.metricsEnabled {
+		if err := s.startMetricsServer(); err != nil {
+			return err
+		}
+	}
+	if s.listenAddr == "" {
+		return nil
+	}
+
 	mux := http.NewServeMux()
 
-	// Register metrics endpoint if enabled
-	if s.metricsEnabled {
-		metricsPath := s.metricsPath
-		if metricsPath == "" {
-			metricsPath = "/metrics"
-		}
-		mux.Handle(metricsPath, promhttp.Handler())
-		s.logger.Info("metrics endpoint registered", zap.String("path", metricsPath))
+	// Register metrics endpoint if enabled and not served on its own listener
+	if s.metricsEnabled && s.metricsAddr == "" {
+		s.registerMetrics(mux)
 	}
 
 	// Register health check endpoint
@@ -72,29 +138,47 @@ func (s *Server) Start() error {
 	// Register config reload endpoint (placeholder for future use)
 	mux.HandleFunc("/reload", s.handleReload)
 
-	s.server = &http.Server{
-		Addr:         s.listenAddr,
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
-	}
+	// Register runtime weight override endpoints
+	mux.HandleFunc("PATCH /api/v1/services/{name}/backends/{addr}", s.handleSetWeightOverride)
+	mux.HandleFunc("DELETE /api/v1/services/{name}/backends/{addr}", s.handleResetWeightOverride)
+	mux.HandleFunc("GET /api/v1/overrides", s.handleListWeightOverrides)
 
-	// Validate address format
-	if _, _, err := net.SplitHostPort(s.listenAddr); err != nil {
-		return fmt.Errorf("invalid admin listen address %q: %w", s.listenAddr, err)
-	}
+	// Register maintenance mode endpoints
+	mux.HandleFunc("GET /api/v1/maintenance", s.handleGetMaintenance)
+	mux.HandleFunc("PUT /api/v1/maintenance", s.handleSetMaintenance)
+
+	// Register read-only dashboard
+	mux.Handle("GET /{$}", http.RedirectHandler("/dashboard", http.StatusFound))
+	mux.HandleFunc("GET /dashboard", s.handleDashboard)
+	mux.HandleFunc("GET /api/v1/dashboard", s.handleDashboardState)
+
+	// Register node status endpoint used for cluster aggregation
+	mux.HandleFunc("GET /api/v1/status", s.handleStatus)
+
+	// Register the plan of the next reconcile
+	mux.HandleFunc("GET /api/v1/plan", s.handlePlan)
+
+	// Register the history of applied changes
+	mux.HandleFunc("GET /api/v1/changes", s.handleChanges)
+
+	// Register the current persistence templates
+	mux.HandleFunc("GET /api/v1/persistence", s.handlePersistence)
+
+	// Register the OpenAPI document describing this API
+	mux.HandleFunc("GET /api/v1/openapi.yaml", s.handleOpenAPI)
 
 	// Create listener to get actual address (important for :0 port)
-	listener, err := net.Listen("tcp", s.listenAddr)
+	listener, err := listen(s.listenAddr, s.tls)
 	if err != nil {
-		return fmt.Errorf("failed to create listener: %w", err)
+		s.stopMetricsServer(context.Background())
+		return fmt.Errorf("admin server: %w", err)
 	}
 	s.listener = listener
 	s.actualAddr = listener.Addr().String()
+	s.server = newHTTPServer(mux)
 
 	go func() {
-		s.logger.Info("admin server starting", zap.String("addr", s.actualAddr))
+		s.logger.Info("admin server starting", zap.String("addr", s.actualAddr), zap.Bool("tls", s.tls.IsEnabled()))
 		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("admin server error", zap.Error(err))
 		}
@@ -103,14 +187,63 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// newHTTPServer returns an HTTP server with the admin timeouts.
+func newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+}
+
+// registerMetrics registers the Prometheus handler on mux.
+func (s *Server) registerMetrics(mux *http.ServeMux) {
+	metricsPath := s.metricsPath
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+	mux.Handle(metricsPath, promhttp.Handler())
+	s.logger.Info("metrics endpoint registered", zap.String("path", metricsPath))
+}
+
+// startMetricsServer serves the metrics endpoint on its own listener.
+func (s *Server) startMetricsServer() error {
+	mux := http.NewServeMux()
+	s.registerMetrics(mux)
+	listener, err := listen(s.metricsAddr, s.metricsTLS)
+	if err != nil {
+		return fmt.Errorf("metrics server: %w", err)
+	}
+	s.actualMetrics = listener.Addr().String()
+	s.metricsServer = newHTTPServer(mux)
+
+	go func() {
+		s.logger.Info("metrics server starting", zap.String("addr", s.actualMetrics), zap.Bool("tls", s.metricsTLS.IsEnabled()))
+		if err := s.metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("metrics server error", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// stopMetricsServer shuts down the metrics listener, if running.
+func (s *Server) stopMetricsServer(ctx context.Context) error {
+	if s.metricsServer == nil {
+		return nil
+	}
+	return s.metricsServer.Shutdown(ctx)
+}
+
 // Stop gracefully shuts down the admin server.
 func (s *Server) Stop(ctx context.Context) error {
+	metricsErr := s.stopMetricsServer(ctx)
 	if s.server == nil {
-		return nil
+		return metricsErr
 	}
 
 	s.logger.Info("admin server stopping")
-	return s.server.Shutdown(ctx)
+	return errors.Join(s.server.Shutdown(ctx), metricsErr)
 }
 
 // handleHealth handles health check requests.
@@ -128,7 +261,10 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		backendHealth = s.healthCheckFunc()
 	}
 
-	response := fmt.Sprintf(`{"status":"healthy","backends":%s}`, formatHealthJSON(backendHealth))
+	inMaintenance := s.maintenance != nil && s.maintenance.InMaintenance()
+
+	response := fmt.Sprintf(`{"status":"healthy","maintenance":%t,"backends":%s}`,
+		inMaintenance, formatHealthJSON(backendHealth))
 	w.Write([]byte(response))
 }
 
@@ -145,6 +281,138 @@ func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"reload triggered"}`))
 }
 
+// weightOverrideRequest is the request body accepted by the weight override endpoint.
+type weightOverrideRequest struct {
+	Weight *int   `json:"weight"`
+	TTL    string `json:"ttl"`
+}
+
+// handleSetWeightOverride applies a temporary weight override to a backend.
+func (s *Server) handleSetWeightOverride(w http.ResponseWriter, r *http.Request) {
+	if s.overrideHandler == nil {
+		http.Error(w, "weight override not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req weightOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Weight == nil || *req.Weight < 0 {
+		http.Error(w, "weight must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	if *req.Weight > config.MaxIPVSWeight {
+		http.Error(w, fmt.Sprintf("weight must not exceed %d", config.MaxIPVSWeight), http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 {
+			http.Error(w, fmt.Sprintf("invalid ttl %q", req.TTL), http.StatusBadRequest)
+			return
+		}
+		ttl = parsed
+	}
+
+	service := r.PathValue("name")
+	backend := r.PathValue("addr")
+	if err := s.overrideHandler.SetWeightOverride(service, backend, *req.Weight, ttl); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	s.logger.Info("backend weight overridden via admin API",
+		zap.String("service", service),
+		zap.String("backend", backend),
+		zap.Int("weight", *req.Weight),
+		zap.Duration("ttl", ttl),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"overridden"}`))
+}
+
+// handleResetWeightOverride removes a backend weight override, restoring the configured weight.
+func (s *Server) handleResetWeightOverride(w http.ResponseWriter, r *http.Request) {
+	if s.overrideHandler == nil {
+		http.Error(w, "weight override not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	service := r.PathValue("name")
+	backend := r.PathValue("addr")
+	if err := s.overrideHandler.ResetWeightOverride(service, backend); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	s.logger.Info("backend weight override reset via admin API",
+		zap.String("service", service),
+		zap.String("backend", backend),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"reset"}`))
+}
+
+// handleListWeightOverrides lists all active backend weight overrides.
+func (s *Server) handleListWeightOverrides(w http.ResponseWriter, r *http.Request) {
+	overrides := []WeightOverride{}
+	if s.overrideHandler != nil {
+		overrides = s.overrideHandler.WeightOverrides()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// writeOverrideError maps a weight override handler error to an HTTP response.
+func writeOverrideError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// maintenanceState is the request and response body of the maintenance mode endpoint.
+type maintenanceState struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// handleGetMaintenance reports whether maintenance mode is enabled.
+func (s *Server) handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	enabled := s.maintenance != nil && s.maintenance.InMaintenance()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maintenanceState{Enabled: &enabled})
+}
+
+// handleSetMaintenance enables or disables maintenance mode.
+func (s *Server) handleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.maintenance == nil {
+		http.Error(w, "maintenance mode not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req maintenanceState
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		http.Error(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	s.maintenance.SetMaintenance(*req.Enabled)
+	s.logger.Info("maintenance mode changed via admin API", zap.Bool("enabled", *req.Enabled))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
 // formatHealthJSON converts health map to JSON string.
 func formatHealthJSON(health map[string]bool) string {
 	if health == nil {
@@ -164,7 +432,7 @@ func formatHealthJSON(health map[string]bool) string {
 
 // IsEnabled returns true if the admin server is configured to run.
 func (s *Server) IsEnabled() bool {
-	return s.listenAddr != ""
+	return s.listenAddr != "" || s.metricsAddr != ""
 }
 
 // Addr returns the actual address the server is listening on.
@@ -172,3 +440,9 @@ func (s *Server) IsEnabled() bool {
 func (s *Server) Addr() string {
 	return s.actualAddr
 }
+
+// MetricsAddr returns the actual address of the separate metrics listener,
+// or "" if metrics are served by the admin listener.
+func (s *Server) MetricsAddr() string {
+	return s.actualMetrics
+}
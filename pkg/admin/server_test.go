@@ -2,9 +2,11 @@ package admin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -424,3 +426,425 @@ func TestDefaultMetricsPath(t *testing.T) {
 		t.Errorf("expected status 200, got %d", resp.StatusCode)
 	}
 }
+
+// fakeOverrideHandler records weight override calls for testing.
+type fakeOverrideHandler struct {
+	overrides map[string]int
+}
+
+func (f *fakeOverrideHandler) SetWeightOverride(service, backend string, weight int, _ time.Duration) error {
+	if service != "web" {
+		return fmt.Errorf("service %q: %w", service, ErrNotFound)
+	}
+	f.overrides[backend] = weight
+	return nil
+}
+
+func (f *fakeOverrideHandler) ResetWeightOverride(_, backend string) error {
+	if _, exists := f.overrides[backend]; !exists {
+		return ErrNotFound
+	}
+	delete(f.overrides, backend)
+	return nil
+}
+
+func (f *fakeOverrideHandler) WeightOverrides() []WeightOverride {
+	var result []WeightOverride
+	for backend, weight := range f.overrides {
+		result = append(result, WeightOverride{Service: "web", Backend: backend, Weight: weight, Status: "overridden"})
+	}
+	return result
+}
+
+func TestWeightOverrideEndpoints(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	handler := &fakeOverrideHandler{overrides: make(map[string]int)}
+	server.SetWeightOverrideHandler(handler)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	baseURL := fmt.Sprintf("http://%s/api/v1/services", server.Addr())
+	do := func(method, url, body string) int {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do(http.MethodPatch, baseURL+"/web/backends/10.0.0.1:80", `{"weight":0,"ttl":"5m"}`); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if weight, exists := handler.overrides["10.0.0.1:80"]; !exists || weight != 0 {
+		t.Errorf("expected override weight 0 to be recorded, got %v", handler.overrides)
+	}
+
+	if code := do(http.MethodPatch, baseURL+"/web/backends/10.0.0.1:80", `{"ttl":"5m"}`); code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for missing weight, got %d", code)
+	}
+	if code := do(http.MethodPatch, baseURL+"/web/backends/10.0.0.1:80", `{"weight":1,"ttl":"soon"}`); code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid ttl, got %d", code)
+	}
+	if code := do(http.MethodPatch, baseURL+"/api/backends/10.0.0.1:80", `{"weight":1}`); code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown service, got %d", code)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/overrides", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to list overrides: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"status":"overridden"`) {
+		t.Errorf("expected override list to report overridden status, got %s", body)
+	}
+
+	if code := do(http.MethodDelete, baseURL+"/web/backends/10.0.0.1:80", ""); code != http.StatusOK {
+		t.Errorf("expected status 200 on reset, got %d", code)
+	}
+	if code := do(http.MethodDelete, baseURL+"/web/backends/10.0.0.1:80", ""); code != http.StatusNotFound {
+		t.Errorf("expected status 404 on second reset, got %d", code)
+	}
+}
+
+// fakeMaintenanceController records maintenance mode for testing.
+type fakeMaintenanceController struct {
+	enabled bool
+}
+
+func (f *fakeMaintenanceController) SetMaintenance(enabled bool) { f.enabled = enabled }
+func (f *fakeMaintenanceController) InMaintenance() bool         { return f.enabled }
+
+func TestMaintenanceEndpoints(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	controller := &fakeMaintenanceController{}
+	server.SetMaintenanceController(controller)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	url := fmt.Sprintf("http://%s/api/v1/maintenance", server.Addr())
+	req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(`{"enabled":true}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if !controller.enabled {
+		t.Error("expected maintenance mode to be enabled")
+	}
+
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"enabled":true`) {
+		t.Errorf("expected maintenance status enabled, got %s", body)
+	}
+
+	resp, err = http.Get(fmt.Sprintf("http://%s/health", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"maintenance":true`) {
+		t.Errorf("expected health response to report maintenance, got %s", body)
+	}
+}
+
+type fakeDashboardProvider struct{}
+
+func (fakeDashboardProvider) DashboardState() DashboardState {
+	return DashboardState{
+		Maintenance: true,
+		Services: []DashboardService{{
+			Name:     "web",
+			Listen:   "10.0.0.1:80",
+			Protocol: "tcp",
+			Backends: []DashboardBackend{{Address: "192.168.1.1:8080", Weight: 5, Healthy: true}},
+		}},
+		Events: []Event{{Kind: "config", Message: "configuration reloaded (1 services)"}},
+	}
+}
+
+func TestDashboardEndpoints(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	server.SetDashboardProvider(fakeDashboardProvider{})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Request.URL.Path != "/dashboard" {
+		t.Errorf("expected redirect to /dashboard, got %s", resp.Request.URL.Path)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(body), "/api/v1/dashboard") {
+		t.Errorf("expected dashboard HTML, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = http.Get(fmt.Sprintf("http://%s/api/v1/dashboard", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	var state DashboardState
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to decode dashboard state: %v", err)
+	}
+	if !state.Maintenance || len(state.Services) != 1 || len(state.Events) != 1 {
+		t.Errorf("unexpected dashboard state: %+v", state)
+	}
+	if state.Services[0].Backends[0].Address != "192.168.1.1:8080" {
+		t.Errorf("unexpected backend: %+v", state.Services[0].Backends[0])
+	}
+}
+
+func TestDashboardStateUnavailable(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/dashboard", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+}
+
+type fakePlanProvider struct {
+	plan ReconcilePlan
+	err  error
+}
+
+func (p fakePlanProvider) Plan() (ReconcilePlan, error) {
+	return p.plan, p.err
+}
+
+func TestPlanEndpoint(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	provider := &fakePlanProvider{plan: ReconcilePlan{Changes: []PlannedChange{
+		{Service: "web", Resource: "destination", Action: "create", Target: "192.168.1.12:8080"},
+	}}}
+	server.SetPlanProvider(provider)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/plan", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	var plan ReconcilePlan
+	err = json.NewDecoder(resp.Body).Decode(&plan)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to decode plan: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0] != provider.plan.Changes[0] {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+
+	provider.err = fmt.Errorf("failed to get current IPVS services")
+	resp, err = http.Get(fmt.Sprintf("http://%s/api/v1/plan", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status 500 when the plan fails, got %d", resp.StatusCode)
+	}
+}
+
+type fakePersistenceProvider struct {
+	templates []PersistenceTemplate
+	err       error
+}
+
+func (p *fakePersistenceProvider) PersistenceTemplates() ([]PersistenceTemplate, error) {
+	return p.templates, p.err
+}
+
+func TestPersistenceEndpoint(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	expires := time.Date(2026, 3, 1, 14, 5, 0, 0, time.UTC)
+	provider := &fakePersistenceProvider{templates: []PersistenceTemplate{
+		{ExpiresAt: expires, Service: "web", Client: "192.168.0.1", Backend: "192.168.1.10:8080"},
+		{ExpiresAt: expires, Service: "web", Client: "192.168.0.2", Backend: "192.168.1.11:8080"},
+		{ExpiresAt: expires, Service: "api", Client: "192.168.0.1", Backend: "192.168.2.10:8080"},
+	}}
+	server.SetPersistenceProvider(provider)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	tests := []struct {
+		query    string
+		backends []string
+	}{
+		{"", []string{"192.168.1.10:8080", "192.168.1.11:8080", "192.168.2.10:8080"}},
+		{"?service=web", []string{"192.168.1.10:8080", "192.168.1.11:8080"}},
+		{"?client=192.168.0.1&backend=192.168.2.10:8080", []string{"192.168.2.10:8080"}},
+		{"?service=db", []string{}},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/persistence%s", server.Addr(), tt.query))
+		if err != nil {
+			t.Fatalf("failed to make request: %v", err)
+		}
+		var result PersistenceTemplates
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to decode templates: %v", err)
+		}
+		backends := []string{}
+		for _, template := range result.Templates {
+			backends = append(backends, template.Backend)
+		}
+		if !reflect.DeepEqual(backends, tt.backends) {
+			t.Errorf("query %q: expected backends %v, got %v", tt.query, tt.backends, backends)
+		}
+	}
+
+	provider.err = fmt.Errorf("read /proc/net/ip_vs_conn: no such file or directory")
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/persistence", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status 500 when the templates cannot be read, got %d", resp.StatusCode)
+	}
+}
+
+type fakeChangeProvider []AppliedChange
+
+func (p fakeChangeProvider) Changes() []AppliedChange {
+	return p
+}
+
+func TestChangesEndpoint(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	start := time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC)
+	server.SetChangeProvider(fakeChangeProvider{
+		{Time: start, Reason: "config change", Service: "web", Resource: "destination", Action: "create", Target: "192.168.1.10:8080"},
+		{Time: start.Add(2 * time.Minute), Reason: "health change", Service: "web", Resource: "destination", Action: "delete", Target: "192.168.1.10:8080"},
+		{Time: start.Add(3 * time.Minute), Reason: "health change", Service: "api", Resource: "destination", Action: "delete", Target: "192.168.2.10:8080"},
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	tests := []struct {
+		query   string
+		reasons []string
+	}{
+		{"", []string{"config change", "health change", "health change"}},
+		{"?service=web", []string{"config change", "health change"}},
+		{"?target=192.168.1.10:8080&since=2026-03-01T14:01:00Z", []string{"health change"}},
+		{"?service=db", []string{}},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/changes%s", server.Addr(), tt.query))
+		if err != nil {
+			t.Fatalf("failed to make request: %v", err)
+		}
+		var history ChangeHistory
+		err = json.NewDecoder(resp.Body).Decode(&history)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%q: failed to decode changes: %v", tt.query, err)
+		}
+		reasons := []string{}
+		for _, change := range history.Changes {
+			reasons = append(reasons, change.Reason)
+		}
+		if !reflect.DeepEqual(reasons, tt.reasons) {
+			t.Errorf("%q: expected reasons %v, got %v", tt.query, tt.reasons, reasons)
+		}
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/changes?since=yesterday", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid since, got %d", resp.StatusCode)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	server := NewServer(Config{ListenAddr: "127.0.0.1:0"}, zap.NewNop())
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/openapi.yaml", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	// Every route registered by Start must be documented.
+	spec := string(body)
+	for _, path := range []string{
+		"/health:",
+		"/reload:",
+		"/metrics:",
+		"/dashboard:",
+		"/api/v1/openapi.yaml:",
+		"/api/v1/dashboard:",
+		"/api/v1/status:",
+		"/api/v1/plan:",
+		"/api/v1/changes:",
+		"/api/v1/persistence:",
+		"/api/v1/services/{name}/backends/{addr}:",
+		"/api/v1/overrides:",
+		"/api/v1/maintenance:",
+	} {
+		if !strings.Contains(spec, "\n  "+path) {
+			t.Errorf("OpenAPI document does not describe %s", strings.TrimSuffix(path, ":"))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// NodeStatus reports the state of this director as seen by cluster peers.
+type NodeStatus struct {
+	Backends         map[string]bool   `json:"backends"`
+	ServiceHashes    map[string]string `json:"service_hashes"`
+	Node             string            `json:"node"`
+	ConfigHash       string            `json:"config_hash"`
+	HeldVIPs         []string          `json:"held_vips"`
+	ConfigGeneration uint64            `json:"config_generation"`
+	Services         int               `json:"services"`
+	Maintenance      bool              `json:"maintenance"`
+	// ReconcileFailingSince is when reconciles started failing with
+	// ReconcileError, nil while they succeed.
+	ReconcileFailingSince *time.Time `json:"reconcile_failing_since,omitempty"`
+	ReconcileError        string     `json:"reconcile_error,omitempty"`
+}
+
+// StatusProvider reports the node status served to cluster peers.
+type StatusProvider interface {
+	NodeStatus() NodeStatus
+}
+
+// SetStatusProvider sets the provider used by the node status endpoint.
+func (s *Server) SetStatusProvider(provider StatusProvider) {
+	s.status = provider
+}
+
+// handleStatus serves this director's node status.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if s.status == nil {
+		http.Error(w, "node status not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.status.NodeStatus())
+}
@@ -0,0 +1,135 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+)
+
+// NodeResult is the outcome of querying one director's admin API.
+type NodeResult struct {
+	Err     error
+	Status  *admin.NodeStatus
+	Address string
+}
+
+// Summary is the aggregated view of a director cluster.
+type Summary struct {
+	// HealthDivergence maps a backend address to the health reported by each
+	// node, for backends on which the reachable nodes disagree.
+	HealthDivergence map[string]map[string]bool
+	Nodes            []NodeResult
+	// Masters lists the nodes currently holding at least one service VIP.
+	Masters []string
+	// ConfigHashes maps each distinct config hash to the nodes reporting it.
+	ConfigHashes map[string][]string
+}
+
+// ConfigConsistent reports whether all reachable nodes run the same services.
+func (s Summary) ConfigConsistent() bool {
+	return len(s.ConfigHashes) <= 1
+}
+
+// FetchStatuses queries /api/v1/status on every address concurrently, over
+// TLS if tlsConfig is set. The results keep the order of addrs.
+func FetchStatuses(ctx context.Context, client *http.Client, tlsConfig *tls.Config, addrs []string) []NodeResult {
+	results := make([]NodeResult, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			status, err := fetchStatus(ctx, client, tlsConfig, addr)
+			results[i] = NodeResult{Address: addr, Status: status, Err: err}
+		}(i, addr)
+	}
+	wg.Wait()
+	return results
+}
+
+// fetchStatus queries a single director, at a host:port or, for the local
+// director, possibly a unix socket address.
+func fetchStatus(ctx context.Context, client *http.Client, tlsConfig *tls.Config, addr string) (*admin.NodeStatus, error) {
+	client, baseURL := admin.ClientFor(client, addr, tlsConfig)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v1/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach admin server at %s: %w", addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("admin server at %s returned %s: %s", addr, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var status admin.NodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode status from %s: %w", addr, err)
+	}
+	return &status, nil
+}
+
+// Summarize aggregates node results into masters, config consistency and
+// backend health divergence. Unreachable nodes are kept in Nodes but ignored
+// for the comparisons.
+func Summarize(nodes []NodeResult) Summary {
+	summary := Summary{
+		HealthDivergence: make(map[string]map[string]bool),
+		Nodes:            nodes,
+		ConfigHashes:     make(map[string][]string),
+	}
+
+	health := make(map[string]map[string]bool)
+	reachable := 0
+	for _, node := range nodes {
+		if node.Status == nil {
+			continue
+		}
+		reachable++
+		if len(node.Status.HeldVIPs) > 0 {
+			summary.Masters = append(summary.Masters, node.Address)
+		}
+		summary.ConfigHashes[node.Status.ConfigHash] = append(summary.ConfigHashes[node.Status.ConfigHash], node.Address)
+		for backend, healthy := range node.Status.Backends {
+			if health[backend] == nil {
+				health[backend] = make(map[string]bool)
+			}
+			health[backend][node.Address] = healthy
+		}
+	}
+
+	for backend, byNode := range health {
+		if len(byNode) != reachable || !unanimous(byNode) {
+			summary.HealthDivergence[backend] = byNode
+		}
+	}
+	sort.Strings(summary.Masters)
+	return summary
+}
+
+// unanimous reports whether all nodes agree on a backend's health.
+func unanimous(byNode map[string]bool) bool {
+	first := true
+	var value bool
+	for _, healthy := range byNode {
+		if first {
+			value, first = healthy, false
+			continue
+		}
+		if healthy != value {
+			return false
+		}
+	}
+	return true
+}
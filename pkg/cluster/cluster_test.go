@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+)
+
+func newStatusServer(t *testing.T, status admin.NodeStatus) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/status" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestFetchStatusesAndSummarize(t *testing.T) {
+	master := newStatusServer(t, admin.NodeStatus{
+		Node:       "lb-1",
+		ConfigHash: "abc",
+		HeldVIPs:   []string{"10.0.0.1"},
+		Backends:   map[string]bool{"192.168.1.10:8080": true, "192.168.1.11:8080": true},
+	})
+	backup := newStatusServer(t, admin.NodeStatus{
+		Node:       "lb-2",
+		ConfigHash: "def",
+		Backends:   map[string]bool{"192.168.1.10:8080": true, "192.168.1.11:8080": false},
+	})
+
+	results := FetchStatuses(context.Background(), http.DefaultClient, nil, []string{master, backup, "127.0.0.1:1"})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Status == nil || results[0].Status.Node != "lb-1" {
+		t.Fatalf("unexpected first result: %+v", results[0])
+	}
+	if results[2].Err == nil {
+		t.Error("expected an error for the unreachable node")
+	}
+
+	summary := Summarize(results)
+	if len(summary.Masters) != 1 || summary.Masters[0] != master {
+		t.Errorf("expected %s as the only master, got %v", master, summary.Masters)
+	}
+	if summary.ConfigConsistent() {
+		t.Error("expected differing config hashes to be reported as inconsistent")
+	}
+	if len(summary.HealthDivergence) != 1 {
+		t.Fatalf("expected 1 divergent backend, got %v", summary.HealthDivergence)
+	}
+	if view := summary.HealthDivergence["192.168.1.11:8080"]; view[master] != true || view[backup] != false {
+		t.Errorf("unexpected divergence view: %v", view)
+	}
+}
+
+func TestSummarize_ConsistentCluster(t *testing.T) {
+	status := &admin.NodeStatus{ConfigHash: "abc", Backends: map[string]bool{"192.168.1.10:8080": true}}
+	summary := Summarize([]NodeResult{
+		{Address: "10.0.0.2:9095", Status: status},
+		{Address: "10.0.0.3:9095", Status: status},
+	})
+	if !summary.ConfigConsistent() {
+		t.Error("expected matching config hashes to be consistent")
+	}
+	if len(summary.HealthDivergence) != 0 {
+		t.Errorf("expected no health divergence, got %v", summary.HealthDivergence)
+	}
+	if len(summary.Masters) != 0 {
+		t.Errorf("expected no master without held VIPs, got %v", summary.Masters)
+	}
+}
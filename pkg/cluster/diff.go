@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Mismatch describes one difference between the reference director and a peer.
+type Mismatch struct {
+	Peer    string
+	Subject string
+	Detail  string
+}
+
+// String formats the mismatch for CLI output.
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: %s: %s", m.Peer, m.Subject, m.Detail)
+}
+
+// Diff compares every peer against the reference node (normally the local
+// director) and reports service definitions that are missing, extra or
+// different, a differing maintenance mode, and unreachable peers.
+func Diff(reference NodeResult, peers []NodeResult) []Mismatch {
+	var mismatches []Mismatch
+	for _, peer := range peers {
+		if peer.Status == nil {
+			mismatches = append(mismatches, Mismatch{Peer: peer.Address, Subject: "node", Detail: fmt.Sprintf("unreachable: %v", peer.Err)})
+			continue
+		}
+		if peer.Status.ConfigHash == reference.Status.ConfigHash && peer.Status.Maintenance == reference.Status.Maintenance {
+			continue
+		}
+		before := len(mismatches)
+
+		names := make(map[string]struct{})
+		for name := range reference.Status.ServiceHashes {
+			names[name] = struct{}{}
+		}
+		for name := range peer.Status.ServiceHashes {
+			names[name] = struct{}{}
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			local, inReference := reference.Status.ServiceHashes[name]
+			remote, inPeer := peer.Status.ServiceHashes[name]
+			subject := "service " + name
+			switch {
+			case !inPeer:
+				mismatches = append(mismatches, Mismatch{Peer: peer.Address, Subject: subject, Detail: "missing on peer"})
+			case !inReference:
+				mismatches = append(mismatches, Mismatch{Peer: peer.Address, Subject: subject, Detail: "only on peer"})
+			case local != remote:
+				mismatches = append(mismatches, Mismatch{Peer: peer.Address, Subject: subject, Detail: fmt.Sprintf("definition differs (%.12s != %.12s)", local, remote)})
+			}
+		}
+
+		// Service order is part of the overall hash; report it when nothing else differs.
+		if peer.Status.ConfigHash != reference.Status.ConfigHash && len(mismatches) == before {
+			mismatches = append(mismatches, Mismatch{Peer: peer.Address, Subject: "services", Detail: "same definitions in a different order"})
+		}
+		if peer.Status.Maintenance != reference.Status.Maintenance {
+			mismatches = append(mismatches, Mismatch{Peer: peer.Address, Subject: "maintenance", Detail: fmt.Sprintf("%t on peer, %t locally", peer.Status.Maintenance, reference.Status.Maintenance)})
+		}
+	}
+	return mismatches
+}
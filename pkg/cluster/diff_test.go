@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+)
+
+func TestDiff(t *testing.T) {
+	reference := NodeResult{Address: "10.0.0.1:9095", Status: &admin.NodeStatus{
+		ConfigHash:    "all",
+		ServiceHashes: map[string]string{"web": "w1", "api": "a1", "dns": "d1"},
+	}}
+	peers := []NodeResult{
+		{Address: "10.0.0.2:9095", Status: &admin.NodeStatus{
+			ConfigHash:    "all",
+			ServiceHashes: map[string]string{"web": "w1", "api": "a1", "dns": "d1"},
+		}},
+		{Address: "10.0.0.3:9095", Status: &admin.NodeStatus{
+			ConfigHash:    "other",
+			ServiceHashes: map[string]string{"web": "w2", "api": "a1", "smtp": "s1"},
+			Maintenance:   true,
+		}},
+		{Address: "10.0.0.4:9095", Err: errors.New("connection refused")},
+	}
+
+	mismatches := Diff(reference, peers)
+	var got []string
+	for _, mismatch := range mismatches {
+		got = append(got, mismatch.String())
+	}
+
+	want := []string{
+		"10.0.0.3:9095: service dns: missing on peer",
+		"10.0.0.3:9095: service smtp: only on peer",
+		"10.0.0.3:9095: service web: definition differs (w1 != w2)",
+		"10.0.0.3:9095: maintenance: true on peer, false locally",
+		"10.0.0.4:9095: node: unreachable: connection refused",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected mismatches:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiff_ServiceOrder(t *testing.T) {
+	hashes := map[string]string{"web": "w1", "api": "a1"}
+	reference := NodeResult{Address: "10.0.0.1:9095", Status: &admin.NodeStatus{ConfigHash: "ab", ServiceHashes: hashes}}
+	peer := NodeResult{Address: "10.0.0.2:9095", Status: &admin.NodeStatus{ConfigHash: "ba", ServiceHashes: hashes}}
+
+	mismatches := Diff(reference, []NodeResult{peer})
+	if len(mismatches) != 1 || mismatches[0].Subject != "services" {
+		t.Errorf("expected a single service order mismatch, got %v", mismatches)
+	}
+}
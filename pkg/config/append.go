@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+// AppendService adds a service to the local config at path and returns the
+// file it wrote. A config file gets the service appended to its services
+// list, keeping the rest of the file as it is where possible; a config
+// directory gets a new file named after the service. The config must load
+// before and after the change; if the written config does not load, the
+// change is undone.
+func AppendService(path string, svc ServiceConfig) (string, error) {
+	if isRemote(path) {
+		return "", fmt.Errorf("cannot add a service to remote config %s", path)
+	}
+	current, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		return "", fmt.Errorf("current config: %w", err)
+	}
+	// Validate fills in defaults, which must not end up in the written stanza
+	added := svc
+	added.Backends = slices.Clone(svc.Backends)
+	candidate := *current.GetConfig()
+	candidate.Services = append(slices.Clone(candidate.Services), added)
+	if err := Validate(&candidate); err != nil {
+		return "", err
+	}
+
+	node, err := encodeNode(reflect.ValueOf(svc))
+	if err != nil {
+		return "", err
+	}
+	var file string
+	var undo func() error
+	if isDir(path) {
+		if strings.ContainsAny(svc.Name, `/\`) || strings.HasPrefix(svc.Name, ".") {
+			return "", fmt.Errorf("service name %q cannot name a file in %s", svc.Name, path)
+		}
+		file = filepath.Join(path, svc.Name+".yaml")
+		if _, err := os.Lstat(file); err == nil {
+			return "", fmt.Errorf("%s already exists", file)
+		}
+		data, err := encodeYAML(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "services"},
+			{Kind: yaml.SequenceNode, Content: []*yaml.Node{node}},
+		}})
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return "", err
+		}
+		undo = func() error { return os.Remove(file) }
+	} else {
+		file = path
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		data, err := appendServiceNode(old, node)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(file, data, 0); err != nil {
+			return "", err
+		}
+		undo = func() error { return os.WriteFile(file, old, 0) }
+	}
+
+	if _, err := NewManager(path, zap.NewNop()); err != nil {
+		if undoErr := undo(); undoErr != nil {
+			return "", fmt.Errorf("updated config does not load: %w; restoring it failed: %v", err, undoErr)
+		}
+		return "", fmt.Errorf("updated config does not load, left unchanged: %w", err)
+	}
+	return file, nil
+}
+
+// MarshalService returns a service as a YAML services list item, leaving out
+// unset settings.
+func MarshalService(svc ServiceConfig) ([]byte, error) {
+	node, err := encodeNode(reflect.ValueOf(svc))
+	if err != nil {
+		return nil, err
+	}
+	return encodeYAML([]*yaml.Node{node})
+}
+
+// appendServiceNode appends a service to the services list of a config
+// document. When services is the last top-level key of a block-style
+// document, the service is appended as text so that comments and formatting
+// are kept; otherwise the document is re-encoded.
+func appendServiceNode(data []byte, service *yaml.Node) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config is not a YAML mapping")
+	}
+
+	stanza, err := encodeYAML([]*yaml.Node{service})
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	index := -1
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == "services" {
+			index = i
+		}
+	}
+	if index < 0 {
+		return []byte(text + "\nservices:\n" + indent(string(stanza), 2)), nil
+	}
+	services := root.Content[index+1]
+	if index == len(root.Content)-2 && services.Kind == yaml.SequenceNode &&
+		services.Style&yaml.FlowStyle == 0 && len(services.Content) > 0 {
+		// Items start two columns after their dash
+		return []byte(text + "\n" + indent(string(stanza), services.Content[0].Column-3)), nil
+	}
+
+	if services.Kind != yaml.SequenceNode {
+		services.Kind, services.Tag, services.Value = yaml.SequenceNode, "", ""
+	}
+	services.Style = 0
+	services.Content = append(services.Content, service)
+	return encodeYAML(&doc)
+}
+
+// encodeYAML encodes v as a YAML document indented by two spaces.
+func encodeYAML(v any) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// indent prefixes every line of text with n spaces.
+func indent(text string, n int) string {
+	prefix := strings.Repeat(" ", max(n, 0))
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
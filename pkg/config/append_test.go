@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// adoptedService returns a service as ezlb adopt generates it.
+func adoptedService() ServiceConfig {
+	return ServiceConfig{
+		Name:      "adopted",
+		Listen:    "10.0.0.9:80",
+		Protocol:  "tcp",
+		Scheduler: "wlc",
+		Backends:  []BackendConfig{{Address: "192.168.9.1:8080", Weight: intPtr(2)}},
+	}
+}
+
+// loadServices returns the names of the services of the config at path.
+func loadServices(t *testing.T, path string) []string {
+	t.Helper()
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("config does not load: %v", err)
+	}
+	var names []string
+	for _, svc := range mgr.GetConfig().Services {
+		names = append(names, svc.Name)
+	}
+	return names
+}
+
+func TestAppendService_File(t *testing.T) {
+	path := writeTestYAML(t, "# managed by ops\n"+validYAML)
+	file, err := AppendService(path, adoptedService())
+	if err != nil {
+		t.Fatalf("AppendService failed: %v", err)
+	}
+	if file != path {
+		t.Errorf("expected the config file to be written, got %s", file)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# managed by ops\n"+validYAML) {
+		t.Errorf("expected the service to be appended to the unchanged file, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "\n  - name: adopted\n    listen: 10.0.0.9:80\n") {
+		t.Errorf("expected the service indented like the other services, got:\n%s", data)
+	}
+	if names := loadServices(t, path); strings.Join(names, ",") != "web-service,adopted" {
+		t.Errorf("unexpected services %v", names)
+	}
+}
+
+func TestAppendService_ServicesNotLast(t *testing.T) {
+	path := writeTestYAML(t, `services:
+  - name: web-service # the web tier
+    listen: 10.0.0.1:80
+    scheduler: rr
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+global:
+  log:
+    level: info
+`)
+	if _, err := AppendService(path, adoptedService()); err != nil {
+		t.Fatalf("AppendService failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# the web tier") {
+		t.Errorf("expected comments to be kept, got:\n%s", data)
+	}
+	if names := loadServices(t, path); strings.Join(names, ",") != "web-service,adopted" {
+		t.Errorf("unexpected services %v", names)
+	}
+}
+
+func TestAppendService_Directory(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"00-global.yaml": confdirGlobalYAML,
+		"10-web.yaml":    confdirWebYAML,
+	})
+	file, err := AppendService(dir, adoptedService())
+	if err != nil {
+		t.Fatalf("AppendService failed: %v", err)
+	}
+	if file != filepath.Join(dir, "adopted.yaml") {
+		t.Errorf("expected a new file named after the service, got %s", file)
+	}
+	if names := loadServices(t, dir); strings.Join(names, ",") != "web,adopted" {
+		t.Errorf("unexpected services %v", names)
+	}
+
+	if _, err := AppendService(dir, adoptedService()); err == nil {
+		t.Error("expected adding the service again to fail")
+	}
+}
+
+func TestAppendService_InvalidLeavesConfigUnchanged(t *testing.T) {
+	path := writeTestYAML(t, validYAML)
+	svc := adoptedService()
+	svc.Name = "web-service"
+	if _, err := AppendService(path, svc); err == nil || !strings.Contains(err.Error(), "duplicate service name") {
+		t.Fatalf("expected a duplicate name error, got: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != validYAML {
+		t.Errorf("expected the config to be unchanged, got:\n%s", data)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validateBackendGroups checks the top-level backend groups: each needs a
+// backend and lists an address only once.
+func validateBackendGroups(groups map[string][]BackendConfig) error {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(groups[name]) == 0 {
+			return fmt.Errorf("backend_groups.%s: at least one backend is required", name)
+		}
+		seen := make(map[string]bool, len(groups[name]))
+		for j, backend := range groups[name] {
+			if backend.Address == "" {
+				return fmt.Errorf("backend_groups.%s: backend[%d]: address is required", name, j)
+			}
+			if seen[backend.Address] {
+				return fmt.Errorf("backend_groups.%s: backend[%d]: duplicate address %q", name, j, backend.Address)
+			}
+			seen[backend.Address] = true
+		}
+	}
+	return nil
+}
+
+// expandBackendGroups returns the service's backends followed by those of
+// the groups it references, in order. A group backend whose address is
+// already listed is left out: a backend of the service itself overrides it,
+// so a service can adjust one backend of a shared pool, and the same backend
+// in several groups is taken once. Two groups listing an address with
+// different settings are an error. Expanding an expanded service again
+// yields the same backends.
+func expandBackendGroups(svc ServiceConfig, groups map[string][]BackendConfig) ([]BackendConfig, error) {
+	backends := append([]BackendConfig(nil), svc.Backends...)
+	own := make(map[string]bool, len(svc.Backends))
+	for _, backend := range svc.Backends {
+		own[backend.Address] = true
+	}
+	fromGroup := make(map[string]string)
+	for _, name := range svc.BackendGroups {
+		group, ok := groups[name]
+		if !ok {
+			// Viper lowercases map keys
+			group, ok = groups[strings.ToLower(name)]
+		}
+		if !ok {
+			return nil, fmt.Errorf("backend_groups: unknown group %q", name)
+		}
+		for _, backend := range group {
+			if own[backend.Address] {
+				continue
+			}
+			if previous, ok := fromGroup[backend.Address]; ok {
+				if index := backendIndex(backends, backend.Address); !sameBackend(backends[index], backend) {
+					return nil, fmt.Errorf("backend_groups: groups %q and %q list backend %q with different settings", previous, name, backend.Address)
+				}
+				continue
+			}
+			fromGroup[backend.Address] = name
+			backends = append(backends, backend)
+		}
+	}
+	return backends, nil
+}
+
+// backendIndex returns the index of the backend with the given address, or -1.
+func backendIndex(backends []BackendConfig, address string) int {
+	for i, backend := range backends {
+		if backend.Address == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameBackend reports whether two backend entries have the same effective
+// settings; an unset weight equals an explicit weight of 1.
+func sameBackend(a, b BackendConfig) bool {
+	if a.GetWeight() != b.GetWeight() {
+		return false
+	}
+	a.Weight, b.Weight = nil, nil
+	return a == b
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const backendGroupsYAML = `
+backend_groups:
+  Web-Pool:
+    - address: 192.168.1.10:8080
+      weight: 5
+    - address: 192.168.1.11:8080
+      weight: 3
+  extra:
+    - address: 192.168.1.11:8080
+      weight: 3
+    - address: 192.168.1.12:8080
+      weight: 1
+services:
+  - name: web-http
+    listen: 10.0.0.1:80
+    scheduler: wrr
+    backend_groups: [Web-Pool, extra]
+  - name: web-alt
+    listen: 10.0.0.2:80
+    scheduler: wrr
+    backend_groups: [Web-Pool]
+    backends:
+      - address: 192.168.1.11:8080
+        weight: 1
+`
+
+func TestManager_LoadBackendGroups(t *testing.T) {
+	mgr, err := NewManager(writeTestYAML(t, backendGroupsYAML), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	cfg := mgr.GetConfig()
+
+	addresses := func(svc ServiceConfig) string {
+		var list []string
+		for _, backend := range svc.Backends {
+			list = append(list, fmt.Sprintf("%s/%d", backend.Address, backend.GetWeight()))
+		}
+		return strings.Join(list, " ")
+	}
+	if got, want := addresses(cfg.Services[0]), "192.168.1.10:8080/5 192.168.1.11:8080/3 192.168.1.12:8080/1"; got != want {
+		t.Errorf("web-http: expected backends %s, got %s", want, got)
+	}
+	if got, want := addresses(cfg.Services[1]), "192.168.1.11:8080/1 192.168.1.10:8080/5"; got != want {
+		t.Errorf("web-alt: expected backends %s, got %s", want, got)
+	}
+
+	// Validating again leaves the expanded backends as they are
+	expanded := append([]BackendConfig(nil), cfg.Services[0].Backends...)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate of the loaded config failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Services[0].Backends, expanded) {
+		t.Errorf("expected validation to be idempotent, got %v", cfg.Services[0].Backends)
+	}
+	if normalized := cfg.Normalized(); normalized.Services[0].BackendGroups != nil || len(normalized.Services[0].Backends) != 3 {
+		t.Errorf("expected normalized services to list the expanded backends only, got %+v", normalized.Services[0])
+	}
+}
+
+func TestValidate_BackendGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{
+			name: "group only",
+			modify: func(c *Config) {
+				c.BackendGroups = map[string][]BackendConfig{"pool": {{Address: "192.168.1.2:8080", Weight: intPtr(1)}}}
+				c.Services[0].Backends = nil
+				c.Services[0].BackendGroups = []string{"pool"}
+			},
+		},
+		{
+			name: "unknown group",
+			modify: func(c *Config) {
+				c.Services[0].BackendGroups = []string{"missing"}
+			},
+			wantErr: `backend_groups: unknown group "missing"`,
+		},
+		{
+			name: "empty group",
+			modify: func(c *Config) {
+				c.BackendGroups = map[string][]BackendConfig{"pool": nil}
+			},
+			wantErr: "backend_groups.pool: at least one backend is required",
+		},
+		{
+			name: "duplicate address in group",
+			modify: func(c *Config) {
+				c.BackendGroups = map[string][]BackendConfig{"pool": {
+					{Address: "192.168.1.2:8080", Weight: intPtr(1)},
+					{Address: "192.168.1.2:8080", Weight: intPtr(2)},
+				}}
+			},
+			wantErr: `backend_groups.pool: backend[1]: duplicate address "192.168.1.2:8080"`,
+		},
+		{
+			name: "conflicting groups",
+			modify: func(c *Config) {
+				c.BackendGroups = map[string][]BackendConfig{
+					"a": {{Address: "192.168.1.2:8080", Weight: intPtr(1)}},
+					"b": {{Address: "192.168.1.2:8080", Weight: intPtr(2)}},
+				}
+				c.Services[0].BackendGroups = []string{"a", "b"}
+			},
+			wantErr: `groups "a" and "b" list backend "192.168.1.2:8080" with different settings`,
+		},
+		{
+			name: "invalid group backend",
+			modify: func(c *Config) {
+				c.BackendGroups = map[string][]BackendConfig{"pool": {{Address: "192.168.1.2:8080", Weight: intPtr(-1)}}}
+				c.Services[0].BackendGroups = []string{"pool"}
+			},
+			wantErr: "weight must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid config, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestManager_BackendGroupsInDirectory(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"00-pools.yaml": "backend_groups:\n  pool:\n    - address: 192.168.1.10:8080\n      weight: 1\n",
+		"10-web.yaml":   "services:\n  - name: web\n    listen: 10.0.0.1:80\n    scheduler: rr\n    backend_groups: [pool]\n",
+	})
+	mgr, err := NewManager(dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if backends := mgr.GetConfig().Services[0].Backends; len(backends) != 1 || backends[0].Address != "192.168.1.10:8080" {
+		t.Errorf("expected the group's backend, got %v", backends)
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// dirReloadDelay coalesces the bursts of events a directory receives when
+// several files are written at once, so they are reloaded together.
+const dirReloadDelay = 200 * time.Millisecond
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// isConfigFile reports whether name is a file merged from a config directory:
+// a *.yaml or *.yml file that is not hidden, which skips editor swap files.
+func isConfigFile(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, ".") {
+		return false
+	}
+	ext := filepath.Ext(base)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// configDirFiles returns the config files of a directory in lexical order,
+// including symlinks to files such as those of a Kubernetes ConfigMap mount.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !isConfigFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+		} else if !entry.Type().IsRegular() {
+			continue
+		}
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadConfigInto reads the config at path into v. path is either a single
+// config file, the URL of a remote config (see isRemote) or a conf.d style
+// directory whose *.yaml and *.yml files are merged in lexical order: their
+// services lists are concatenated, maps such as global are merged key by key,
+// and for any other setting defined in several files the last file wins.
+func ReadConfigInto(v *viper.Viper, path string) error {
+	if isRemote(path) {
+		data, err := fetchRemote(context.Background(), path)
+		if err != nil {
+			return err
+		}
+		return readRemoteInto(v, data)
+	}
+	if !isDir(path) {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		return nil
+	}
+
+	files, err := configDirFiles(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config directory: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("failed to read config directory: no *.yaml files in %s", path)
+	}
+
+	merged := make(map[string]any)
+	var services []any
+	for _, file := range files {
+		fileViper := viper.New()
+		fileViper.SetConfigFile(file)
+		if err := fileViper.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+		settings := fileViper.AllSettings()
+		if fileServices, ok := settings["services"]; ok {
+			list, ok := fileServices.([]any)
+			if !ok {
+				return fmt.Errorf("config file %s: services must be a list", file)
+			}
+			services = append(services, list...)
+			delete(settings, "services")
+		}
+		mergeSettings(merged, settings)
+	}
+	if services != nil {
+		merged["services"] = services
+	}
+	return v.MergeConfigMap(merged)
+}
+
+// mergeSettings merges src into dst, recursing into maps present in both.
+func mergeSettings(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeSettings(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const confdirGlobalYAML = `
+global:
+  log:
+    level: info
+  metrics_path: /metrics
+`
+
+const confdirWebYAML = `
+services:
+  - name: web
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: wrr
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 5
+`
+
+const confdirAPIYAML = `
+global:
+  log:
+    level: debug
+services:
+  - name: api
+    listen: 10.0.0.2:80
+    protocol: tcp
+    scheduler: rr
+    backends:
+      - address: 192.168.1.20:8080
+        weight: 1
+`
+
+// writeTestDir writes the given files into a fresh directory.
+func writeTestDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestManager_LoadDirectory(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"00-global.yaml": confdirGlobalYAML,
+		"10-web.yaml":    confdirWebYAML,
+		"20-api.yml":     confdirAPIYAML,
+		"README.md":      "not a config file",
+		".hidden.yaml":   "{{{invalid yaml",
+	})
+
+	mgr, err := NewManager(dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("expected NewManager to succeed, got: %v", err)
+	}
+	cfg := mgr.GetConfig()
+
+	if len(cfg.Services) != 2 {
+		t.Fatalf("expected services from both files, got %d", len(cfg.Services))
+	}
+	if cfg.Services[0].Name != "web" || cfg.Services[1].Name != "api" {
+		t.Errorf("expected services in file order, got %q and %q", cfg.Services[0].Name, cfg.Services[1].Name)
+	}
+	// The later file overrides the log level but keeps the other global keys
+	if cfg.Global.Log.Level != "debug" {
+		t.Errorf("expected log level from the later file, got %q", cfg.Global.Log.Level)
+	}
+	if cfg.Global.MetricsPath != "/metrics" {
+		t.Errorf("expected metrics_path from the earlier file, got %q", cfg.Global.MetricsPath)
+	}
+}
+
+func TestManager_LoadDirectoryValidatesCombined(t *testing.T) {
+	// Each file is valid on its own, but together the service names clash
+	dir := writeTestDir(t, map[string]string{
+		"10-web.yaml": confdirWebYAML,
+		"20-web.yaml": strings.Replace(confdirWebYAML, "10.0.0.1:80", "10.0.0.3:80", 1),
+	})
+
+	_, err := NewManager(dir, zap.NewNop())
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("expected duplicate service error, got: %v", err)
+	}
+}
+
+func TestManager_LoadEmptyDirectory(t *testing.T) {
+	_, err := NewManager(t.TempDir(), zap.NewNop())
+	if err == nil || !strings.Contains(err.Error(), "no *.yaml files") {
+		t.Fatalf("expected empty directory error, got: %v", err)
+	}
+}
+
+func TestManager_WatchDirectory(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"00-global.yaml": confdirGlobalYAML,
+		"10-web.yaml":    confdirWebYAML,
+	})
+	mgr, err := NewManager(dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("expected NewManager to succeed, got: %v", err)
+	}
+	mgr.WatchConfig()
+
+	if err := os.WriteFile(filepath.Join(dir, "20-api.yaml"), []byte(confdirAPIYAML), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	select {
+	case <-mgr.OnChange():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a reload after a file was added")
+	}
+	if got := len(mgr.GetConfig().Services); got != 2 {
+		t.Fatalf("expected 2 services after adding a file, got %d", got)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "20-api.yaml")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	select {
+	case <-mgr.OnChange():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a reload after a file was removed")
+	}
+	cfg := mgr.GetConfig()
+	if len(cfg.Services) != 1 || cfg.Global.Log.Level != "info" {
+		t.Errorf("expected the removed file's settings to be gone, got %d services and level %q",
+			len(cfg.Services), cfg.Global.Log.Level)
+	}
+	if mgr.Generation() != 3 {
+		t.Errorf("expected generation 3, got %d", mgr.Generation())
+	}
+}
@@ -1,34 +1,216 @@
 package config
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/easzlab/ezlb/pkg/featuregate"
+	"github.com/easzlab/ezlb/pkg/secret"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
-// Config represents the top-level configuration structure.
+// Config represents the top-level configuration structure. BackendGroups
+// holds named backend pools that services reference by name instead of
+// repeating the backends; validation expands the references into the
+// services' backends (see expandBackendGroups).
 type Config struct {
-	Services []ServiceConfig `yaml:"services" mapstructure:"services"`
-	Global   GlobalConfig    `yaml:"global"   mapstructure:"global"`
+	BackendGroups map[string][]BackendConfig `yaml:"backend_groups" mapstructure:"backend_groups"`
+	Services      []ServiceConfig            `yaml:"services"       mapstructure:"services"`
+	Global        GlobalConfig               `yaml:"global"         mapstructure:"global"`
+}
+
+// ServicesHash returns a stable SHA-256 digest of the service definitions.
+// Node-local global settings such as admin_address are deliberately excluded,
+// so directors of one cluster report the same hash when their services match.
+func (c *Config) ServicesHash() string {
+	return hashJSON(c.Services)
+}
+
+// Hash returns a stable SHA-256 digest of a single service definition.
+func (s ServiceConfig) Hash() string {
+	return hashJSON(s)
+}
+
+// hashJSON returns the hex SHA-256 digest of the JSON encoding of v.
+func hashJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // GlobalConfig holds global settings.
 type GlobalConfig struct {
-	CleanupOnExit  *bool     `yaml:"cleanup_on_exit" mapstructure:"cleanup_on_exit"`
-	MetricsEnabled *bool     `yaml:"metrics_enabled" mapstructure:"metrics_enabled"`
-	AdminAddress   string    `yaml:"admin_address"   mapstructure:"admin_address"`
-	MetricsPath    string    `yaml:"metrics_path"    mapstructure:"metrics_path"`
-	Log            LogConfig `yaml:"log"            mapstructure:"log"`
+	CleanupOnExit        *bool             `yaml:"cleanup_on_exit"        mapstructure:"cleanup_on_exit"`
+	CleanupOnPanic       *bool             `yaml:"cleanup_on_panic"       mapstructure:"cleanup_on_panic"`
+	MetricsEnabled       *bool             `yaml:"metrics_enabled"        mapstructure:"metrics_enabled"`
+	TunnelSetup          *bool             `yaml:"tunnel_setup"           mapstructure:"tunnel_setup"`
+	CheckHostListeners   *bool             `yaml:"check_host_listeners"   mapstructure:"check_host_listeners"`
+	Prune                *bool             `yaml:"prune"                  mapstructure:"prune"`
+	DetectLocalBackends  *bool             `yaml:"detect_local_backends"  mapstructure:"detect_local_backends"`
+	ReadOnly             *bool             `yaml:"read_only"              mapstructure:"read_only"`
+	Instance             string            `yaml:"instance"               mapstructure:"instance"`
+	AdminAddress         string            `yaml:"admin_address"          mapstructure:"admin_address"`
+	MetricsAddress       string            `yaml:"metrics_address"        mapstructure:"metrics_address"`
+	MetricsPath          string            `yaml:"metrics_path"           mapstructure:"metrics_path"`
+	StateFile            string            `yaml:"state_file"             mapstructure:"state_file"`
+	MinReconcileInterval string            `yaml:"min_reconcile_interval" mapstructure:"min_reconcile_interval"`
+	ResyncInterval       string            `yaml:"resync_interval"        mapstructure:"resync_interval"`
+	DriftCheckInterval   string            `yaml:"drift_check_interval"   mapstructure:"drift_check_interval"`
+	IPVSCacheTTL         string            `yaml:"ipvs_cache_ttl"         mapstructure:"ipvs_cache_ttl"`
+	IgnoreServices       []string          `yaml:"ignore_services"        mapstructure:"ignore_services"`
+	AdminTLS             ListenerTLSConfig `yaml:"admin_tls"              mapstructure:"admin_tls"`
+	MetricsTLS           ListenerTLSConfig `yaml:"metrics_tls"            mapstructure:"metrics_tls"`
+	Log                  LogConfig         `yaml:"log"                    mapstructure:"log"`
+	SelfMonitor          SelfMonitorConfig `yaml:"self_monitor"           mapstructure:"self_monitor"`
+	HA                   HAConfig          `yaml:"ha"                     mapstructure:"ha"`
+	Drain                DrainConfig       `yaml:"drain"                  mapstructure:"drain"`
+	DNS                  DNSConfig         `yaml:"dns"                    mapstructure:"dns"`
+	RemoteConfig         RemoteConfig      `yaml:"remote_config"          mapstructure:"remote_config"`
+	Lock                 LockConfig        `yaml:"lock"                   mapstructure:"lock"`
+	Snapshot             SnapshotConfig    `yaml:"snapshot"               mapstructure:"snapshot"`
+	HealthCheck          HealthCheckConfig `yaml:"health_check"           mapstructure:"health_check"`
+	FeatureGates         map[string]bool   `yaml:"feature_gates"          mapstructure:"feature_gates"`
+}
+
+// maxInstanceLength keeps instance-scoped iptables chain names, such as
+// EZLB-FORWARD-<instance>, within the kernel's 28 byte limit.
+const maxInstanceLength = 15
+
+// defaultSocketDir holds the admin sockets of admin_address "unix:".
+const defaultSocketDir = "/run/ezlb"
+
+// validInstanceName reports whether name is a valid instance name: lowercase
+// letters, digits and hyphens, not starting or ending with a hyphen.
+func validInstanceName(name string) bool {
+	if name == "" || len(name) > maxInstanceLength || name[0] == '-' || name[len(name)-1] == '-' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// InstanceScoped returns name suffixed with the instance name, e.g.
+// "ezlb-edge-a" for name "ezlb" and instance "edge-a", or name unchanged
+// for the default (unnamed) instance.
+func (g GlobalConfig) InstanceScoped(name string) string {
+	if g.Instance == "" {
+		return name
+	}
+	return name + "-" + g.Instance
+}
+
+// GetAdminAddress returns the admin listener address. "unix:" without a
+// path selects the instance's default socket, /run/ezlb/<instance>.sock
+// (/run/ezlb/ezlb.sock for the default instance).
+func (g GlobalConfig) GetAdminAddress() string {
+	if g.AdminAddress == "unix:" {
+		name := g.Instance
+		if name == "" {
+			name = "ezlb"
+		}
+		return "unix:" + defaultSocketDir + "/" + name + ".sock"
+	}
+	return g.AdminAddress
+}
+
+// DefaultStateDir holds the state file unless global.state_file is set.
+var DefaultStateDir = "/var/lib/ezlb"
+
+// GetStateFile returns the file recording the IPVS services ezlb manages, so
+// services removed from the config are deleted even by a later process.
+// Defaults to state.json in DefaultStateDir, or state-<instance>.json for a
+// named instance.
+func (g GlobalConfig) GetStateFile() string {
+	if g.StateFile != "" {
+		return g.StateFile
+	}
+	if g.Instance != "" {
+		return filepath.Join(DefaultStateDir, "state-"+g.Instance+".json")
+	}
+	return filepath.Join(DefaultStateDir, "state.json")
+}
+
+// ScopedLock returns the lock settings with the key scoped to the instance,
+// so instances on one host never contend for each other's lock.
+func (g GlobalConfig) ScopedLock() LockConfig {
+	lock := g.Lock
+	if g.Instance != "" && lock.Key != "" {
+		lock.Key = strings.TrimSuffix(lock.Key, "/") + "/" + g.Instance
+	}
+	return lock
+}
+
+// GetMinReconcileInterval returns the minimum time between reconciles
+// triggered by health changes and runtime actions. Defaults to 0 (no limit)
+// if not set or invalid.
+func (g GlobalConfig) GetMinReconcileInterval() time.Duration {
+	duration, err := time.ParseDuration(g.MinReconcileInterval)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// minResyncInterval keeps the periodic resync and drift check from dumping
+// IPVS over netlink in a tight loop.
+const minResyncInterval = time.Second
+
+// GetResyncInterval returns how often the daemon reconciles even without a
+// config or health change, repairing rules changed or flushed by other
+// tools. Defaults to 0 (disabled) if not set or invalid.
+func (g GlobalConfig) GetResyncInterval() time.Duration {
+	duration, err := time.ParseDuration(g.ResyncInterval)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// GetDriftCheckInterval returns how often the daemon compares the kernel
+// with the desired state and reports differences. Defaults to 0 (disabled,
+// or every 30s in observe-only mode) if not set or invalid.
+func (g GlobalConfig) GetDriftCheckInterval() time.Duration {
+	duration, err := time.ParseDuration(g.DriftCheckInterval)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// GetIPVSCacheTTL returns how long the IPVS state read by reconciles is
+// cached. Defaults to 0 (disabled) if not set or invalid.
+func (g GlobalConfig) GetIPVSCacheTTL() time.Duration {
+	duration, err := time.ParseDuration(g.IPVSCacheTTL)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
 }
 
 // LogConfig holds unified logging configuration.
 type LogConfig struct {
 	Traffic    TrafficLogConfig `yaml:"traffic"     mapstructure:"traffic"`
+	Syslog     SyslogConfig     `yaml:"syslog"      mapstructure:"syslog"`
 	Level      string           `yaml:"level"       mapstructure:"level"`
 	Home       string           `yaml:"home"        mapstructure:"home"`
 	MaxSize    int              `yaml:"max_size"    mapstructure:"max_size"`
@@ -86,6 +268,7 @@ func (l LogConfig) GetMaxAge() int {
 type TrafficLogConfig struct {
 	Enabled  *bool  `yaml:"enabled"  mapstructure:"enabled"`
 	Interval string `yaml:"interval" mapstructure:"interval"`
+	Window   string `yaml:"window"   mapstructure:"window"`
 }
 
 // IsEnabled returns whether traffic logging is enabled. Defaults to true.
@@ -112,247 +295,1667 @@ func (t TrafficLogConfig) GetInterval() time.Duration {
 	return duration
 }
 
-// IsCleanupOnExit returns whether to clean up IPVS and iptables rules on exit.
-// Defaults to true if not explicitly set.
-func (g GlobalConfig) IsCleanupOnExit() bool {
-	if g.CleanupOnExit == nil {
-		return true
+// GetWindow returns the window over which traffic rates are computed.
+// Defaults to 1m if not set or invalid. Rates always span at least two
+// samples, so a window shorter than the interval acts as one interval.
+func (t TrafficLogConfig) GetWindow() time.Duration {
+	if t.Window == "" {
+		return time.Minute
 	}
-	return *g.CleanupOnExit
+	duration, err := time.ParseDuration(t.Window)
+	if err != nil {
+		return time.Minute
+	}
+	return duration
 }
 
-// IsMetricsEnabled returns whether metrics are enabled.
-// Defaults to true if not explicitly set.
-func (g GlobalConfig) IsMetricsEnabled() bool {
-	if g.MetricsEnabled == nil {
-		return true
-	}
-	return *g.MetricsEnabled
+// ServiceStatsConfig overrides the global traffic sampling interval and rate
+// window for one service: busy services want tight windows, quiet ones want
+// smoothing. Empty fields inherit global.log.traffic.
+type ServiceStatsConfig struct {
+	Interval string `yaml:"interval" mapstructure:"interval"`
+	Window   string `yaml:"window"   mapstructure:"window"`
 }
 
-// GetMetricsPath returns the metrics endpoint path.
-// Defaults to "/metrics" if not set.
-func (g GlobalConfig) GetMetricsPath() string {
-	if g.MetricsPath == "" {
-		return "/metrics"
+// minServiceStatsInterval is the shortest per-service sampling interval.
+const minServiceStatsInterval = time.Second
+
+// GetInterval returns the service's sampling interval, falling back to the
+// global traffic interval. Values below 1s are clamped to 1s.
+func (s ServiceStatsConfig) GetInterval(global TrafficLogConfig) time.Duration {
+	if s.Interval == "" {
+		return global.GetInterval()
 	}
-	return g.MetricsPath
+	duration, err := time.ParseDuration(s.Interval)
+	if err != nil {
+		return global.GetInterval()
+	}
+	return max(duration, minServiceStatsInterval)
 }
 
-// ServiceConfig defines a virtual service with its backends and health check settings.
-type ServiceConfig struct {
-	TrafficLog  *bool             `yaml:"traffic_log"       mapstructure:"traffic_log"`
-	Name        string            `yaml:"name"              mapstructure:"name"`
-	Listen      string            `yaml:"listen"            mapstructure:"listen"`
-	Protocol    string            `yaml:"protocol"          mapstructure:"protocol"`
-	Scheduler   string            `yaml:"scheduler"         mapstructure:"scheduler"`
-	SnatIP      string            `yaml:"snat_ip"           mapstructure:"snat_ip"`
-	Backends    []BackendConfig   `yaml:"backends"          mapstructure:"backends"`
-	HealthCheck HealthCheckConfig `yaml:"health_check"      mapstructure:"health_check"`
-	FullNAT     bool              `yaml:"full_nat"          mapstructure:"full_nat"`
+// GetWindow returns the service's rate window, falling back to the global
+// traffic window.
+func (s ServiceStatsConfig) GetWindow(global TrafficLogConfig) time.Duration {
+	if s.Window == "" {
+		return global.GetWindow()
+	}
+	duration, err := time.ParseDuration(s.Window)
+	if err != nil {
+		return global.GetWindow()
+	}
+	return duration
 }
 
-// HealthCheckConfig defines per-service health check parameters.
-type HealthCheckConfig struct {
-	Enabled            *bool  `yaml:"enabled"              mapstructure:"enabled"`
-	Type               string `yaml:"type"                 mapstructure:"type"`
-	Interval           string `yaml:"interval"             mapstructure:"interval"`
-	Timeout            string `yaml:"timeout"              mapstructure:"timeout"`
-	HTTPPath           string `yaml:"http_path"            mapstructure:"http_path"`
-	FailCount          int    `yaml:"fail_count"           mapstructure:"fail_count"`
-	RiseCount          int    `yaml:"rise_count"           mapstructure:"rise_count"`
-	HTTPExpectedStatus int    `yaml:"http_expected_status" mapstructure:"http_expected_status"`
+// SyslogConfig holds settings for the optional RFC 5424 syslog sink that
+// receives health state-transition events (backend down/up, service
+// degraded/restored). Regular application logs are not sent to syslog.
+type SyslogConfig struct {
+	Enabled  *bool  `yaml:"enabled"  mapstructure:"enabled"`
+	Network  string `yaml:"network"  mapstructure:"network"` // udp, tcp or unixgram
+	Address  string `yaml:"address"  mapstructure:"address"`
+	Facility string `yaml:"facility" mapstructure:"facility"`
+	AppName  string `yaml:"app_name" mapstructure:"app_name"`
 }
 
-// IsEnabled returns whether health check is enabled for this service.
-// Defaults to true if not explicitly set.
-func (h HealthCheckConfig) IsEnabled() bool {
-	if h.Enabled == nil {
-		return true
-	}
-	return *h.Enabled
+// IsEnabled returns whether the syslog sink is enabled.
+// Defaults to false if not explicitly set.
+func (s SyslogConfig) IsEnabled() bool {
+	return s.Enabled != nil && *s.Enabled
 }
 
-// GetInterval parses and returns the health check interval duration.
-// Defaults to 5s if not set or invalid.
-func (h HealthCheckConfig) GetInterval() time.Duration {
-	if h.Interval == "" {
-		return 5 * time.Second
+// GetNetwork returns the syslog transport.
+// Defaults to "unixgram" (the local syslog socket) if no address is set,
+// otherwise to "udp".
+func (s SyslogConfig) GetNetwork() string {
+	if s.Network != "" {
+		return s.Network
 	}
-	duration, err := time.ParseDuration(h.Interval)
-	if err != nil {
-		return 5 * time.Second
+	if s.Address == "" {
+		return "unixgram"
 	}
-	return duration
+	return "udp"
 }
 
-// GetTimeout parses and returns the health check timeout duration.
-// Defaults to 3s if not set or invalid.
-func (h HealthCheckConfig) GetTimeout() time.Duration {
-	if h.Timeout == "" {
-		return 3 * time.Second
+// GetAddress returns the syslog destination.
+// Defaults to "/dev/log" if not set.
+func (s SyslogConfig) GetAddress() string {
+	if s.Address == "" {
+		return "/dev/log"
 	}
-	duration, err := time.ParseDuration(h.Timeout)
-	if err != nil {
-		return 3 * time.Second
+	return s.Address
+}
+
+// GetFacility returns the syslog facility name.
+// Defaults to "daemon" if not set.
+func (s SyslogConfig) GetFacility() string {
+	if s.Facility == "" {
+		return "daemon"
 	}
-	return duration
+	return s.Facility
 }
 
-// GetType returns the health check type.
-// Defaults to "tcp" if not set.
-func (h HealthCheckConfig) GetType() string {
-	if h.Type == "" {
-		return "tcp"
+// GetAppName returns the APP-NAME field of syslog messages.
+// Defaults to "ezlb" if not set.
+func (s SyslogConfig) GetAppName() string {
+	if s.AppName == "" {
+		return "ezlb"
 	}
-	return h.Type
+	return s.AppName
 }
 
-// GetHTTPPath returns the HTTP health check request path.
-// Defaults to "/" if not set.
-func (h HealthCheckConfig) GetHTTPPath() string {
-	if h.HTTPPath == "" {
-		return "/"
+// SyslogFacilities maps supported facility names to their RFC 5424 codes.
+var SyslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "daemon": 3, "auth": 4, "syslog": 5,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SelfMonitorConfig holds settings for the process self-monitor, which reports
+// goroutine, heap and file descriptor usage and warns when budgets are exceeded.
+// A zero threshold disables the corresponding alarm.
+type SelfMonitorConfig struct {
+	Enabled       *bool  `yaml:"enabled"        mapstructure:"enabled"`
+	Interval      string `yaml:"interval"       mapstructure:"interval"`
+	MaxGoroutines int    `yaml:"max_goroutines" mapstructure:"max_goroutines"`
+	MaxHeapMB     int    `yaml:"max_heap_mb"    mapstructure:"max_heap_mb"`
+	MaxOpenFDs    int    `yaml:"max_open_fds"   mapstructure:"max_open_fds"`
+}
+
+// IsEnabled returns whether the self-monitor is enabled. Defaults to true.
+func (s SelfMonitorConfig) IsEnabled() bool {
+	if s.Enabled == nil {
+		return true
 	}
-	return h.HTTPPath
+	return *s.Enabled
 }
 
-// GetHTTPExpectedStatus returns the expected HTTP response status code.
-// Defaults to 200 if not set.
-func (h HealthCheckConfig) GetHTTPExpectedStatus() int {
-	if h.HTTPExpectedStatus <= 0 {
-		return 200
+// GetInterval parses and returns the self-monitor sampling interval.
+// Defaults to 30s if not set or invalid.
+func (s SelfMonitorConfig) GetInterval() time.Duration {
+	if s.Interval == "" {
+		return 30 * time.Second
 	}
-	return h.HTTPExpectedStatus
+	duration, err := time.ParseDuration(s.Interval)
+	if err != nil || duration <= 0 {
+		return 30 * time.Second
+	}
+	return duration
 }
 
-// GetFailCount returns the consecutive failure threshold.
-// Defaults to 3 if not set.
-func (h HealthCheckConfig) GetFailCount() int {
-	if h.FailCount <= 0 {
-		return 3
+// DrainConfig controls the watch over draining destinations, i.e. those kept
+// in IPVS with weight 0 by a weight override or maintenance mode. A drain
+// completes when the destination has no connections left or the timeout
+// expires.
+type DrainConfig struct {
+	Enabled  *bool  `yaml:"enabled"  mapstructure:"enabled"`
+	Remove   *bool  `yaml:"remove"   mapstructure:"remove"`
+	Interval string `yaml:"interval" mapstructure:"interval"`
+	Timeout  string `yaml:"timeout"  mapstructure:"timeout"`
+}
+
+// IsEnabled returns whether draining destinations are watched. Defaults to true.
+func (d DrainConfig) IsEnabled() bool {
+	if d.Enabled == nil {
+		return true
 	}
-	return h.FailCount
+	return *d.Enabled
 }
 
-// GetRiseCount returns the consecutive success threshold.
-// Defaults to 2 if not set.
-func (h HealthCheckConfig) GetRiseCount() int {
-	if h.RiseCount <= 0 {
-		return 2
+// IsRemove returns whether a destination is deleted from IPVS once drained,
+// until its weight is raised again. Defaults to false.
+func (d DrainConfig) IsRemove() bool {
+	if d.Remove == nil {
+		return false
 	}
-	return h.RiseCount
+	return *d.Remove
 }
 
-// BackendConfig defines a real server (destination).
-type BackendConfig struct {
-	Address string `yaml:"address" mapstructure:"address"`
-	Weight  int    `yaml:"weight"  mapstructure:"weight"`
+// DNSConfig controls re-resolution of backends addressed by hostname.
+// Go's resolver does not expose record TTLs, so hostnames are re-resolved on
+// a fixed interval instead.
+type DNSConfig struct {
+	Interval string `yaml:"interval" mapstructure:"interval"`
 }
 
-// validSchedulers is the set of supported IPVS scheduling algorithms.
-var validSchedulers = map[string]bool{
-	"rr":  true,
-	"wrr": true,
-	"lc":  true,
-	"wlc": true,
-	"dh":  true,
-	"sh":  true,
+// GetInterval returns how often hostname backends are re-resolved.
+// Defaults to 30s if not set or invalid.
+func (d DNSConfig) GetInterval() time.Duration {
+	duration, err := time.ParseDuration(d.Interval)
+	if err != nil || duration <= 0 {
+		return 30 * time.Second
+	}
+	return duration
 }
 
-// validProtocols is the set of supported protocols.
-var validProtocols = map[string]bool{
-	"tcp": true,
-	"udp": true,
+// RemoteConfig configures how a config loaded from a URL (see IsRemote) is
+// refreshed.
+type RemoteConfig struct {
+	Interval string `yaml:"interval" mapstructure:"interval"`
 }
 
-// Manager handles configuration loading, validation, and hot-reload.
-type Manager struct {
-	viper      *viper.Viper
-	current    *Config
-	onChange   chan struct{}
-	onReload   func()
-	logger     *zap.Logger
-	configPath string
-	mu         sync.RWMutex
+// GetInterval returns how often a remote config is polled for changes.
+// Defaults to 30s if not set or invalid.
+func (r RemoteConfig) GetInterval() time.Duration {
+	duration, err := time.ParseDuration(r.Interval)
+	if err != nil || duration <= 0 {
+		return 30 * time.Second
+	}
+	return duration
 }
 
-// NewManager creates a config Manager, loads and validates the initial configuration.
-func NewManager(configPath string, logger *zap.Logger) (*Manager, error) {
-	viperInstance := viper.New()
-	viperInstance.SetConfigFile(configPath)
+// LockConfig configures a distributed lock that `ezlb once` acquires before
+// applying changes, so that when a fleet of hosts runs it from cron against
+// shared state only the lock holder applies. Backend is "consul" or "etcd";
+// Address is the base URL of its HTTP API and Token an optional ACL token,
+// which may reference a file or environment variable (see package secret).
+type LockConfig struct {
+	Backend string `yaml:"backend" mapstructure:"backend"`
+	Address string `yaml:"address" mapstructure:"address"`
+	Key     string `yaml:"key"     mapstructure:"key"`
+	Token   string `yaml:"token"   mapstructure:"token"`
+	TTL     string `yaml:"ttl"     mapstructure:"ttl"`
+	Wait    string `yaml:"wait"    mapstructure:"wait"`
+}
 
-	// Set defaults
-	viperInstance.SetDefault("global.log.level", "info")
-	viperInstance.SetDefault("global.log.home", "./logs")
-	viperInstance.SetDefault("global.log.max_size", 50)
-	viperInstance.SetDefault("global.log.max_backups", 3)
-	viperInstance.SetDefault("global.log.max_age", 0)
-	viperInstance.SetDefault("global.log.compress", false)
-	viperInstance.SetDefault("global.log.traffic.enabled", true)
-	viperInstance.SetDefault("global.log.traffic.interval", "15s")
-	viperInstance.SetDefault("global.cleanup_on_exit", true)
-	viperInstance.SetDefault("global.metrics_enabled", true)
-	viperInstance.SetDefault("global.metrics_path", "/metrics")
+// SnapshotConfig configures periodic snapshots of the config and the kernel
+// state into timestamped subdirectories of Dir, for audits of past load
+// balancer configurations. Snapshots older than Retention are removed.
+type SnapshotConfig struct {
+	Dir       string `yaml:"dir"       mapstructure:"dir"`
+	Interval  string `yaml:"interval"  mapstructure:"interval"`
+	Retention string `yaml:"retention" mapstructure:"retention"`
+}
 
-	manager := &Manager{
-		viper:      viperInstance,
-		configPath: configPath,
-		onChange:   make(chan struct{}, 1),
-		logger:     logger,
+// IsEnabled returns whether a snapshot directory is configured.
+func (s SnapshotConfig) IsEnabled() bool {
+	return s.Dir != ""
+}
+
+// GetInterval returns how often a snapshot is taken.
+// Defaults to 1h if not set or invalid.
+func (s SnapshotConfig) GetInterval() time.Duration {
+	duration, err := time.ParseDuration(s.Interval)
+	if err != nil || duration <= 0 {
+		return time.Hour
 	}
+	return duration
+}
 
-	cfg, err := manager.Load()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+// GetRetention returns how long snapshots are kept, 0 for forever.
+// Defaults to 720h (30 days) if not set or invalid.
+func (s SnapshotConfig) GetRetention() time.Duration {
+	duration, err := time.ParseDuration(s.Retention)
+	if err != nil || duration < 0 {
+		return 30 * 24 * time.Hour
 	}
-	manager.current = cfg
+	return duration
+}
 
-	return manager, nil
+// IsEnabled returns whether a lock backend is configured.
+func (l LockConfig) IsEnabled() bool {
+	return l.Backend != ""
 }
 
-// Load reads the config file, unmarshals it, and validates.
-func (m *Manager) Load() (*Config, error) {
-	if err := m.viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+// GetTTL returns how long the lock outlives a holder that died without
+// releasing it. Defaults to 30s if not set or invalid.
+func (l LockConfig) GetTTL() time.Duration {
+	duration, err := time.ParseDuration(l.TTL)
+	if err != nil || duration <= 0 {
+		return 30 * time.Second
 	}
+	return duration
+}
 
-	var cfg Config
-	if err := m.viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+// GetWait returns how long to wait for a lock held by another node before
+// skipping the run. Defaults to 0, trying only once.
+func (l LockConfig) GetWait() time.Duration {
+	duration, err := time.ParseDuration(l.Wait)
+	if err != nil || duration < 0 {
+		return 0
 	}
+	return duration
+}
 
-	if err := Validate(&cfg); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+// GetInterval returns how often draining destinations are polled.
+// Defaults to 5s if not set or invalid.
+func (d DrainConfig) GetInterval() time.Duration {
+	duration, err := time.ParseDuration(d.Interval)
+	if err != nil || duration <= 0 {
+		return 5 * time.Second
 	}
-
-	return &cfg, nil
+	return duration
 }
 
-// Validate checks the configuration for correctness.
-func Validate(cfg *Config) error {
-	// Validate log level
-	logLevel := cfg.Global.Log.GetLevel()
-	if !validLogLevels[logLevel] {
-		return fmt.Errorf("global.log.level: unsupported level %q (supported: debug, info, warn, error)", logLevel)
+// GetTimeout returns how long a drain may take before it is considered
+// complete anyway. Zero (the default) waits for the last connection.
+func (d DrainConfig) GetTimeout() time.Duration {
+	duration, err := time.ParseDuration(d.Timeout)
+	if err != nil || duration < 0 {
+		return 0
 	}
+	return duration
+}
 
-	// Validate traffic logging interval
-	if cfg.Global.Log.Traffic.Interval != "" {
-		interval, err := time.ParseDuration(cfg.Global.Log.Traffic.Interval)
-		if err != nil {
-			return fmt.Errorf("global.log.traffic.interval: invalid duration %q: %w", cfg.Global.Log.Traffic.Interval, err)
-		}
-		if interval < 5*time.Second {
-			return fmt.Errorf("global.log.traffic.interval: minimum interval is 5s, got %v", interval)
+// ListenerTLSConfig serves the admin or metrics listener over TLS when
+// cert_file and key_file are set. With client_ca_file, clients must present
+// a certificate signed by one of its CAs (mutual TLS).
+type ListenerTLSConfig struct {
+	CertFile     string `yaml:"cert_file"      mapstructure:"cert_file"`
+	KeyFile      string `yaml:"key_file"       mapstructure:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file" mapstructure:"client_ca_file"`
+}
+
+// validListenerAddress checks an admin or metrics listener address: a
+// host:port, where an empty host means localhost, or unix:/path/to/socket.
+func validListenerAddress(address string) error {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		if strings.TrimPrefix(path, "//") == "" {
+			return fmt.Errorf("empty unix socket path in %q", address)
 		}
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if host != "" && net.ParseIP(host) == nil && host != "localhost" {
+		return fmt.Errorf("invalid address %q: host must be an IP address or localhost", address)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid address %q: invalid port", address)
 	}
+	return nil
+}
 
-	if len(cfg.Services) == 0 {
-		return fmt.Errorf("at least one service must be defined")
+// validate checks that the certificate and key are set together and that
+// client_ca_file comes with them.
+func (t ListenerTLSConfig) validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
 	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		return fmt.Errorf("client_ca_file requires cert_file and key_file")
+	}
+	return nil
+}
 
-	nameSet := make(map[string]bool)
+// HAConfig lists the peer directors of a high-availability cluster, used by
+// cluster-wide status and consistency commands.
+type HAConfig struct {
+	Peers   []string `yaml:"peers"   mapstructure:"peers"` // admin addresses (host:port) of peer directors
+	Timeout string   `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// GetTimeout returns the per-peer request timeout.
+// Defaults to 3s if not set or invalid.
+func (h HAConfig) GetTimeout() time.Duration {
+	if h.Timeout == "" {
+		return 3 * time.Second
+	}
+	duration, err := time.ParseDuration(h.Timeout)
+	if err != nil || duration <= 0 {
+		return 3 * time.Second
+	}
+	return duration
+}
+
+// IsCleanupOnExit returns whether to clean up IPVS and iptables rules on exit.
+// Defaults to true if not explicitly set.
+func (g GlobalConfig) IsCleanupOnExit() bool {
+	if g.CleanupOnExit == nil {
+		return true
+	}
+	return *g.CleanupOnExit
+}
+
+// IsCleanupOnPanic returns whether to attempt IPVS and iptables cleanup when the
+// daemon main loop crashes. Defaults to the cleanup_on_exit setting if not explicitly set.
+func (g GlobalConfig) IsCleanupOnPanic() bool {
+	if g.CleanupOnPanic == nil {
+		return g.IsCleanupOnExit()
+	}
+	return *g.CleanupOnPanic
+}
+
+// IsTunnelSetup returns whether ezlb should prepare the director for tunnel
+// (IPIP) backends by loading the ipip module and adjusting tunl0 sysctls.
+// Defaults to false, in which case the setup is only verified.
+func (g GlobalConfig) IsTunnelSetup() bool {
+	if g.TunnelSetup == nil {
+		return false
+	}
+	return *g.TunnelSetup
+}
+
+// IsCheckHostListeners returns whether ezlb compares the configured VIP:ports
+// with the host's listening sockets and warns about collisions, which make
+// connections reach the local daemon instead of the backends in NAT mode.
+// Defaults to false.
+func (g GlobalConfig) IsCheckHostListeners() bool {
+	if g.CheckHostListeners == nil {
+		return false
+	}
+	return *g.CheckHostListeners
+}
+
+// IsDetectLocalBackends returns whether backends whose IP is assigned to the
+// director are programmed with the local-node forwarding method, which IPVS
+// requires for them, unless their forward_method is set. Defaults to true.
+func (g GlobalConfig) IsDetectLocalBackends() bool {
+	if g.DetectLocalBackends == nil {
+		return true
+	}
+	return *g.DetectLocalBackends
+}
+
+// IsPrune returns whether reconciles delete every IPVS service that is not in
+// the config, not only those ezlb created or recorded in its state file.
+// Defaults to false.
+func (g GlobalConfig) IsPrune() bool {
+	if g.Prune == nil {
+		return false
+	}
+	return *g.Prune
+}
+
+// IsReadOnly returns whether every IPVS change is refused while health
+// checks, metrics and the admin API keep running. Defaults to false.
+func (g GlobalConfig) IsReadOnly() bool {
+	if g.ReadOnly == nil {
+		return false
+	}
+	return *g.ReadOnly
+}
+
+// IsMetricsEnabled returns whether metrics are enabled.
+// Defaults to true if not explicitly set.
+func (g GlobalConfig) IsMetricsEnabled() bool {
+	if g.MetricsEnabled == nil {
+		return true
+	}
+	return *g.MetricsEnabled
+}
+
+// GetMetricsPath returns the metrics endpoint path.
+// Defaults to "/metrics" if not set.
+func (g GlobalConfig) GetMetricsPath() string {
+	if g.MetricsPath == "" {
+		return "/metrics"
+	}
+	return g.MetricsPath
+}
+
+// ServiceConfig defines a virtual service with its backends and health check settings.
+// A service is matched by its listen address, or by firewall mark when FWMark
+// is set, in which case listen must be empty. Listen may list several
+// addresses or a port range; each becomes its own IPVS virtual service with
+// the same backends (see ExpandListens). A dual-stack service sets IPv4 VIPs
+// in Listen and IPv6 VIPs in ListenV6, and each family's VIPs get the
+// backends of that family (see SplitDualStack). MarkGroup lists VIP:ports that
+// ezlb marks with FWMark itself, making their connections share persistence.
+// ForwardMethod is the default forward_method of backends that set none.
+// On multi-homed directors, OutputInterface pins the FullNAT SNAT and FORWARD
+// rules to one uplink and RouteTable routes the backends through that
+// uplink's policy-routing table. DSCP, if set, marks the service's traffic to
+// and from its VIP:ports with that DSCP value so the network can prioritize it.
+// OPS enables IPVS one-packet scheduling, balancing every UDP datagram on its
+// own instead of per connection. Backend weights above MaxWeight are scaled
+// down proportionally before they are programmed (see NormalizeWeights). A
+// service with Enabled set to false is kept in the config but removed from
+// IPVS and not health-checked. BackendGroups names top-level backend groups
+// whose backends are added to the service's own. FlushConntrack deletes the
+// conntrack entries of a NAT backend when its destination is deleted. Merge
+// shares the virtual service with another controller: ezlb only deletes the
+// destinations it created and leaves the others alone.
+type ServiceConfig struct {
+	Enabled         *bool              `yaml:"enabled"          mapstructure:"enabled"`
+	TrafficLog      *bool              `yaml:"traffic_log"      mapstructure:"traffic_log"`
+	DSCP            *int               `yaml:"dscp"             mapstructure:"dscp"`
+	Name            string             `yaml:"name"             mapstructure:"name"`
+	Listen          string             `yaml:"listen"           mapstructure:"listen"`
+	ListenV6        string             `yaml:"listen_v6"        mapstructure:"listen_v6"`
+	Protocol        string             `yaml:"protocol"         mapstructure:"protocol"`
+	Scheduler       string             `yaml:"scheduler"        mapstructure:"scheduler"`
+	SnatIP          string             `yaml:"snat_ip"          mapstructure:"snat_ip"`
+	OutputInterface string             `yaml:"output_interface" mapstructure:"output_interface"`
+	ForwardMethod   string             `yaml:"forward_method"   mapstructure:"forward_method"`
+	Backends        []BackendConfig    `yaml:"backends"         mapstructure:"backends"`
+	HealthCheck     HealthCheckConfig  `yaml:"health_check"     mapstructure:"health_check"`
+	Stats           ServiceStatsConfig `yaml:"stats"            mapstructure:"stats"`
+	Persistence     PersistenceConfig  `yaml:"persistence"      mapstructure:"persistence"`
+	PreStop         PreStopConfig      `yaml:"pre_stop"         mapstructure:"pre_stop"`
+	Synthetic       SyntheticConfig    `yaml:"synthetic"        mapstructure:"synthetic"`
+	MarkGroup       []string           `yaml:"mark_group"       mapstructure:"mark_group"`
+	FWMark          uint32             `yaml:"fwmark"           mapstructure:"fwmark"`
+	RouteTable      uint32             `yaml:"route_table"      mapstructure:"route_table"`
+	BackendGroups   []string           `yaml:"backend_groups"   mapstructure:"backend_groups"`
+	MaxWeight       int                `yaml:"max_weight"       mapstructure:"max_weight"`
+	FullNAT         bool               `yaml:"full_nat"         mapstructure:"full_nat"`
+	OPS             bool               `yaml:"ops"              mapstructure:"ops"`
+	FlushConntrack  bool               `yaml:"flush_conntrack"  mapstructure:"flush_conntrack"`
+	Merge           bool               `yaml:"merge"            mapstructure:"merge"`
+}
+
+// IsEnabled returns whether the service is enabled. Defaults to true if not set.
+func (s ServiceConfig) IsEnabled() bool {
+	if s.Enabled == nil {
+		return true
+	}
+	return *s.Enabled
+}
+
+// EnabledServices returns the services that are enabled, in their original order.
+func EnabledServices(services []ServiceConfig) []ServiceConfig {
+	enabled := make([]ServiceConfig, 0, len(services))
+	for _, svc := range services {
+		if svc.IsEnabled() {
+			enabled = append(enabled, svc)
+		}
+	}
+	return enabled
+}
+
+// PersistenceConfig enables IPVS session persistence (sticky sessions):
+// connections from the same client, or from the same client network when a
+// netmask is set, go to the same backend until the timeout expires.
+type PersistenceConfig struct {
+	Timeout string `yaml:"timeout" mapstructure:"timeout"`
+	// Netmask groups clients: a dotted IPv4 mask such as 255.255.255.0 or a
+	// prefix length such as 24 (IPv4) or 64 (IPv6). Defaults to a host mask.
+	Netmask string `yaml:"netmask" mapstructure:"netmask"`
+}
+
+// IsEnabled returns whether session persistence is configured.
+func (p PersistenceConfig) IsEnabled() bool {
+	return p.Timeout != ""
+}
+
+// GetTimeout returns the persistence timeout. Defaults to 300s (the ipvsadm
+// default) if invalid.
+func (p PersistenceConfig) GetTimeout() time.Duration {
+	duration, err := time.ParseDuration(p.Timeout)
+	if err != nil || duration < time.Second {
+		return 300 * time.Second
+	}
+	return duration
+}
+
+// GetPrefixLength returns the client netmask as a prefix length for an IPv4
+// or IPv6 service. Defaults to a host mask (32 or 128) if not set.
+func (p PersistenceConfig) GetPrefixLength(ipv6 bool) (int, error) {
+	bits := net.IPv4len * 8
+	if ipv6 {
+		bits = net.IPv6len * 8
+	}
+	if p.Netmask == "" {
+		return bits, nil
+	}
+
+	if strings.Contains(p.Netmask, ".") {
+		mask := net.ParseIP(p.Netmask).To4()
+		if ipv6 || mask == nil {
+			return 0, fmt.Errorf("invalid netmask %q", p.Netmask)
+		}
+		ones, size := net.IPMask(mask).Size()
+		if size == 0 {
+			return 0, fmt.Errorf("netmask %q is not contiguous", p.Netmask)
+		}
+		return ones, nil
+	}
+
+	ones, err := strconv.Atoi(strings.TrimPrefix(p.Netmask, "/"))
+	if err != nil || ones < 1 || ones > bits {
+		return 0, fmt.Errorf("invalid netmask %q (prefix length must be 1-%d)", p.Netmask, bits)
+	}
+	return ones, nil
+}
+
+// PreStopConfig defines a hook called before a backend removed from the
+// service's config is deleted from IPVS, so that its sessions can be migrated
+// first. Either URL receives a POST or Command is run; the hook acknowledges
+// with a 2xx response or exit status 0.
+type PreStopConfig struct {
+	URL     string   `yaml:"url"     mapstructure:"url"`
+	Timeout string   `yaml:"timeout" mapstructure:"timeout"`
+	Command []string `yaml:"command" mapstructure:"command"`
+}
+
+// IsEnabled returns whether a pre-stop hook is configured.
+func (p PreStopConfig) IsEnabled() bool {
+	return p.URL != "" || len(p.Command) > 0
+}
+
+// GetTimeout returns how long to wait for the hook to acknowledge before the
+// backend is removed anyway. Defaults to 30s if not set or invalid.
+func (p PreStopConfig) GetTimeout() time.Duration {
+	duration, err := time.ParseDuration(p.Timeout)
+	if err != nil || duration <= 0 {
+		return 30 * time.Second
+	}
+	return duration
+}
+
+// SyntheticConfig defines a synthetic transaction that connects to the
+// service's VIP, through IPVS and any SNAT rules, the way a client would. It
+// measures the service as a whole, unlike health checks, which probe each
+// backend directly. With HTTPPath set a GET request is sent over the
+// connection. Netns runs the probe from another network namespace, a name
+// under /var/run/netns or a path, as traffic from the director itself may
+// bypass the IPVS path.
+type SyntheticConfig struct {
+	Enabled            *bool  `yaml:"enabled"              mapstructure:"enabled"`
+	Interval           string `yaml:"interval"             mapstructure:"interval"`
+	Timeout            string `yaml:"timeout"              mapstructure:"timeout"`
+	HTTPPath           string `yaml:"http_path"            mapstructure:"http_path"`
+	Netns              string `yaml:"netns"                mapstructure:"netns"`
+	HTTPExpectedStatus int    `yaml:"http_expected_status" mapstructure:"http_expected_status"`
+}
+
+// IsEnabled returns whether the synthetic probe is enabled. Defaults to false.
+func (s SyntheticConfig) IsEnabled() bool {
+	return s.Enabled != nil && *s.Enabled
+}
+
+// GetInterval returns the probe interval. Defaults to 10s if not set or invalid.
+func (s SyntheticConfig) GetInterval() time.Duration {
+	duration, err := time.ParseDuration(s.Interval)
+	if err != nil || duration <= 0 {
+		return 10 * time.Second
+	}
+	return duration
+}
+
+// GetTimeout returns the probe timeout. Defaults to 3s if not set or invalid.
+func (s SyntheticConfig) GetTimeout() time.Duration {
+	duration, err := time.ParseDuration(s.Timeout)
+	if err != nil || duration <= 0 {
+		return 3 * time.Second
+	}
+	return duration
+}
+
+// GetHTTPExpectedStatus returns the expected HTTP status code. Defaults to 200.
+func (s SyntheticConfig) GetHTTPExpectedStatus() int {
+	if s.HTTPExpectedStatus == 0 {
+		return 200
+	}
+	return s.HTTPExpectedStatus
+}
+
+// HealthCheckConfig defines per-service health check parameters.
+type HealthCheckConfig struct {
+	Enabled                *bool             `yaml:"enabled"                   mapstructure:"enabled"`
+	Type                   string            `yaml:"type"                      mapstructure:"type"`
+	Interval               string            `yaml:"interval"                  mapstructure:"interval"`
+	Timeout                string            `yaml:"timeout"                   mapstructure:"timeout"`
+	HTTPPath               string            `yaml:"http_path"                 mapstructure:"http_path"`
+	FailCount              int               `yaml:"fail_count"                mapstructure:"fail_count"`
+	RiseCount              int               `yaml:"rise_count"                mapstructure:"rise_count"`
+	HTTPExpectedStatus     int               `yaml:"http_expected_status"      mapstructure:"http_expected_status"`
+	HTTPKeepAlive          *bool             `yaml:"http_keep_alive"           mapstructure:"http_keep_alive"`
+	HTTPMaxIdleConns       int               `yaml:"http_max_idle_conns"       mapstructure:"http_max_idle_conns"`
+	HTTPFollowRedirects    *bool             `yaml:"http_follow_redirects"     mapstructure:"http_follow_redirects"`
+	HTTPRetryAfterDegraded *bool             `yaml:"http_retry_after_degraded" mapstructure:"http_retry_after_degraded"`
+	HTTP5xxDegraded        *bool             `yaml:"http_5xx_degraded"         mapstructure:"http_5xx_degraded"`
+	HTTPExpectBody         string            `yaml:"http_expect_body"          mapstructure:"http_expect_body"`
+	HTTPExpectBodyRegex    *bool             `yaml:"http_expect_body_regex"    mapstructure:"http_expect_body_regex"`
+	HTTPMethod             string            `yaml:"http_method"               mapstructure:"http_method"`
+	HTTPHost               string            `yaml:"http_host"                 mapstructure:"http_host"`
+	HTTPHeaders            map[string]string `yaml:"http_headers"              mapstructure:"http_headers"`
+	TCPSend                string            `yaml:"tcp_send"                  mapstructure:"tcp_send"`
+	TCPExpect              string            `yaml:"tcp_expect"                mapstructure:"tcp_expect"`
+	TLSServerName          string            `yaml:"tls_server_name"           mapstructure:"tls_server_name"`
+	TLSVerify              *bool             `yaml:"tls_verify"                mapstructure:"tls_verify"`
+	CertExpiryWarnDays     int               `yaml:"cert_expiry_warn_days"     mapstructure:"cert_expiry_warn_days"`
+	DegradedLatency        string            `yaml:"degraded_latency"          mapstructure:"degraded_latency"`
+	MaxLatency             string            `yaml:"max_latency"               mapstructure:"max_latency"`
+	DegradedWeightFactor   float64           `yaml:"degraded_weight_factor"    mapstructure:"degraded_weight_factor"`
+	DNSQueryName           string            `yaml:"dns_query_name"            mapstructure:"dns_query_name"`
+	DNSQueryType           string            `yaml:"dns_query_type"            mapstructure:"dns_query_type"`
+	DNSExpectedRcode       string            `yaml:"dns_expected_rcode"        mapstructure:"dns_expected_rcode"`
+	DNSProtocol            string            `yaml:"dns_protocol"              mapstructure:"dns_protocol"`
+	Identity               IdentityConfig    `yaml:"identity"                  mapstructure:"identity"`
+}
+
+// IdentityConfig describes how HTTP and HTTPS health checks verify that a
+// backend runs the expected application, so a recycled IP never receives
+// traffic meant for another service. Empty fields are not checked.
+type IdentityConfig struct {
+	Header      string `yaml:"header"       mapstructure:"header"`
+	HeaderValue string `yaml:"header_value" mapstructure:"header_value"`
+	TLSSAN      string `yaml:"tls_san"      mapstructure:"tls_san"`
+	AgentPath   string `yaml:"agent_path"   mapstructure:"agent_path"`
+	AgentID     string `yaml:"agent_id"     mapstructure:"agent_id"`
+}
+
+// IsEnabled returns whether any identity verification is configured.
+func (i IdentityConfig) IsEnabled() bool {
+	return i.Header != "" || i.TLSSAN != "" || i.AgentPath != ""
+}
+
+// inherit returns h with every unset field taken from defaults, the
+// global.health_check section. tls_server_name, http_host and identity
+// describe a single application and are never inherited.
+func (h HealthCheckConfig) inherit(defaults HealthCheckConfig) HealthCheckConfig {
+	if h.Enabled == nil {
+		h.Enabled = defaults.Enabled
+	}
+	if h.Type == "" {
+		h.Type = defaults.Type
+	}
+	if h.Interval == "" {
+		h.Interval = defaults.Interval
+	}
+	if h.Timeout == "" {
+		h.Timeout = defaults.Timeout
+	}
+	if h.HTTPPath == "" {
+		h.HTTPPath = defaults.HTTPPath
+	}
+	if h.FailCount == 0 {
+		h.FailCount = defaults.FailCount
+	}
+	if h.RiseCount == 0 {
+		h.RiseCount = defaults.RiseCount
+	}
+	if h.HTTPExpectedStatus == 0 {
+		h.HTTPExpectedStatus = defaults.HTTPExpectedStatus
+	}
+	if h.HTTPKeepAlive == nil {
+		h.HTTPKeepAlive = defaults.HTTPKeepAlive
+	}
+	if h.HTTPMaxIdleConns == 0 {
+		h.HTTPMaxIdleConns = defaults.HTTPMaxIdleConns
+	}
+	if h.HTTPFollowRedirects == nil {
+		h.HTTPFollowRedirects = defaults.HTTPFollowRedirects
+	}
+	if h.HTTPRetryAfterDegraded == nil {
+		h.HTTPRetryAfterDegraded = defaults.HTTPRetryAfterDegraded
+	}
+	if h.HTTP5xxDegraded == nil {
+		h.HTTP5xxDegraded = defaults.HTTP5xxDegraded
+	}
+	if h.HTTPExpectBody == "" {
+		h.HTTPExpectBody = defaults.HTTPExpectBody
+	}
+	if h.HTTPExpectBodyRegex == nil {
+		h.HTTPExpectBodyRegex = defaults.HTTPExpectBodyRegex
+	}
+	if h.HTTPMethod == "" {
+		h.HTTPMethod = defaults.HTTPMethod
+	}
+	if h.HTTPHeaders == nil {
+		h.HTTPHeaders = defaults.HTTPHeaders
+	}
+	if h.TCPSend == "" {
+		h.TCPSend = defaults.TCPSend
+	}
+	if h.TCPExpect == "" {
+		h.TCPExpect = defaults.TCPExpect
+	}
+	if h.TLSVerify == nil {
+		h.TLSVerify = defaults.TLSVerify
+	}
+	if h.CertExpiryWarnDays == 0 {
+		h.CertExpiryWarnDays = defaults.CertExpiryWarnDays
+	}
+	if h.DegradedLatency == "" {
+		h.DegradedLatency = defaults.DegradedLatency
+	}
+	if h.MaxLatency == "" {
+		h.MaxLatency = defaults.MaxLatency
+	}
+	if h.DegradedWeightFactor == 0 {
+		h.DegradedWeightFactor = defaults.DegradedWeightFactor
+	}
+	if h.DNSQueryName == "" {
+		h.DNSQueryName = defaults.DNSQueryName
+	}
+	if h.DNSQueryType == "" {
+		h.DNSQueryType = defaults.DNSQueryType
+	}
+	if h.DNSExpectedRcode == "" {
+		h.DNSExpectedRcode = defaults.DNSExpectedRcode
+	}
+	if h.DNSProtocol == "" {
+		h.DNSProtocol = defaults.DNSProtocol
+	}
+	return h
+}
+
+// IsEnabled returns whether health check is enabled for this service.
+// Defaults to true if not explicitly set.
+func (h HealthCheckConfig) IsEnabled() bool {
+	if h.Enabled == nil {
+		return true
+	}
+	return *h.Enabled
+}
+
+// GetInterval parses and returns the health check interval duration.
+// Defaults to 5s if not set or invalid.
+func (h HealthCheckConfig) GetInterval() time.Duration {
+	if h.Interval == "" {
+		return 5 * time.Second
+	}
+	duration, err := time.ParseDuration(h.Interval)
+	if err != nil {
+		return 5 * time.Second
+	}
+	return duration
+}
+
+// GetTimeout parses and returns the health check timeout duration.
+// Defaults to 3s if not set or invalid.
+func (h HealthCheckConfig) GetTimeout() time.Duration {
+	if h.Timeout == "" {
+		return 3 * time.Second
+	}
+	duration, err := time.ParseDuration(h.Timeout)
+	if err != nil {
+		return 3 * time.Second
+	}
+	return duration
+}
+
+// GetType returns the health check type.
+// Defaults to "tcp" if not set.
+func (h HealthCheckConfig) GetType() string {
+	if h.Type == "" {
+		return "tcp"
+	}
+	return h.Type
+}
+
+// GetDNSQueryType returns the record type queried by DNS health checks.
+// Defaults to "A" if not set.
+func (h HealthCheckConfig) GetDNSQueryType() string {
+	if h.DNSQueryType == "" {
+		return "A"
+	}
+	return h.DNSQueryType
+}
+
+// GetDNSExpectedRcode returns the response code of a healthy backend in DNS
+// health checks. Defaults to "NOERROR" if not set.
+func (h HealthCheckConfig) GetDNSExpectedRcode() string {
+	if h.DNSExpectedRcode == "" {
+		return "NOERROR"
+	}
+	return h.DNSExpectedRcode
+}
+
+// GetDNSProtocol returns the transport of DNS health checks, udp or tcp.
+// Defaults to "udp" if not set.
+func (h HealthCheckConfig) GetDNSProtocol() string {
+	if h.DNSProtocol == "" {
+		return "udp"
+	}
+	return h.DNSProtocol
+}
+
+// GetHTTPPath returns the HTTP health check request path.
+// Defaults to "/" if not set.
+func (h HealthCheckConfig) GetHTTPPath() string {
+	if h.HTTPPath == "" {
+		return "/"
+	}
+	return h.HTTPPath
+}
+
+// GetHTTPMethod returns the method of HTTP health check requests.
+// Defaults to "GET" if not set.
+func (h HealthCheckConfig) GetHTTPMethod() string {
+	if h.HTTPMethod == "" {
+		return "GET"
+	}
+	return h.HTTPMethod
+}
+
+// GetHTTPExpectedStatus returns the expected HTTP response status code.
+// Defaults to 200 if not set.
+func (h HealthCheckConfig) GetHTTPExpectedStatus() int {
+	if h.HTTPExpectedStatus <= 0 {
+		return 200
+	}
+	return h.HTTPExpectedStatus
+}
+
+// IsHTTPKeepAlive returns whether HTTP probe connections are reused between checks.
+// Defaults to false, so every probe opens and closes its own connection.
+func (h HealthCheckConfig) IsHTTPKeepAlive() bool {
+	if h.HTTPKeepAlive == nil {
+		return false
+	}
+	return *h.HTTPKeepAlive
+}
+
+// IsHTTPFollowRedirects returns whether HTTP probes follow redirects and judge
+// the final response. Defaults to true; disable it so a redirect to a login or
+// maintenance page does not mask a broken application.
+func (h HealthCheckConfig) IsHTTPFollowRedirects() bool {
+	if h.HTTPFollowRedirects == nil {
+		return true
+	}
+	return *h.HTTPFollowRedirects
+}
+
+// IsHTTPRetryAfterDegraded returns whether a 429 or 503 response carrying a
+// Retry-After header marks the backend degraded (kept in rotation, probes
+// paused until Retry-After) instead of counting as a failed check.
+// Defaults to false.
+func (h HealthCheckConfig) IsHTTPRetryAfterDegraded() bool {
+	if h.HTTPRetryAfterDegraded == nil {
+		return false
+	}
+	return *h.HTTPRetryAfterDegraded
+}
+
+// IsHTTP5xxDegraded returns whether 5xx responses mark a healthy backend
+// degraded (weight scaled down) until fail_count consecutive failed checks
+// remove it, so short error bursts do not take it out of rotation at once.
+// Defaults to false.
+func (h HealthCheckConfig) IsHTTP5xxDegraded() bool {
+	if h.HTTP5xxDegraded == nil {
+		return false
+	}
+	return *h.HTTP5xxDegraded
+}
+
+// IsHTTPExpectBodyRegex returns whether http_expect_body is a regular
+// expression the response body must match, rather than a substring it must
+// contain. Defaults to false.
+func (h HealthCheckConfig) IsHTTPExpectBodyRegex() bool {
+	if h.HTTPExpectBodyRegex == nil {
+		return false
+	}
+	return *h.HTTPExpectBodyRegex
+}
+
+// IsTLSVerify returns whether https probes verify the backend certificate chain
+// and host name. Defaults to false, since backends are usually probed by IP
+// while their certificates are issued for the service host name.
+func (h HealthCheckConfig) IsTLSVerify() bool {
+	if h.TLSVerify == nil {
+		return false
+	}
+	return *h.TLSVerify
+}
+
+// GetCertExpiryWarnDays returns how many days before notAfter a backend
+// certificate starts producing expiry warnings.
+// Defaults to 14 if not set.
+func (h HealthCheckConfig) GetCertExpiryWarnDays() int {
+	if h.CertExpiryWarnDays <= 0 {
+		return 14
+	}
+	return h.CertExpiryWarnDays
+}
+
+// GetDegradedLatency returns the probe duration above which a successful check
+// marks the backend degraded. Returns 0 (disabled) if not set or invalid.
+func (h HealthCheckConfig) GetDegradedLatency() time.Duration {
+	if h.DegradedLatency == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(h.DegradedLatency)
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// GetMaxLatency returns the probe duration above which a successful check
+// counts as a failure. Returns 0 (disabled) if not set or invalid.
+func (h HealthCheckConfig) GetMaxLatency() time.Duration {
+	if h.MaxLatency == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(h.MaxLatency)
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// GetDegradedWeightFactor returns the factor applied to the weight of degraded
+// backends. Defaults to 0.5 if not set.
+func (h HealthCheckConfig) GetDegradedWeightFactor() float64 {
+	if h.DegradedWeightFactor <= 0 {
+		return 0.5
+	}
+	return h.DegradedWeightFactor
+}
+
+// GetFailCount returns the consecutive failure threshold.
+// Defaults to 3 if not set.
+func (h HealthCheckConfig) GetFailCount() int {
+	if h.FailCount <= 0 {
+		return 3
+	}
+	return h.FailCount
+}
+
+// GetRiseCount returns the consecutive success threshold.
+// Defaults to 2 if not set.
+func (h HealthCheckConfig) GetRiseCount() int {
+	if h.RiseCount <= 0 {
+		return 2
+	}
+	return h.RiseCount
+}
+
+// BackendConfig defines a real server (destination).
+// A weight of 0 keeps the destination in IPVS without new connections, so its
+// existing connections drain.
+// Backends with a higher priority value form standby tiers that only receive
+// traffic when every backend of all more preferred tiers is unhealthy. Backup
+// backends stay in IPVS with weight 0 while any primary backend is healthy.
+type BackendConfig struct {
+	Address       string `yaml:"address"        mapstructure:"address"`
+	ForwardMethod string `yaml:"forward_method" mapstructure:"forward_method"`
+	Weight        *int   `yaml:"weight"         mapstructure:"weight"`
+	Role          string `yaml:"role"           mapstructure:"role"`     // "primary" (default) or "backup"
+	Priority      int    `yaml:"priority"       mapstructure:"priority"` // failover tier, 0 = most preferred
+}
+
+// GetWeight returns the configured weight of the backend. Defaults to 1 if
+// not set, so only an explicit weight of 0 drains a backend.
+func (b BackendConfig) GetWeight() int {
+	if b.Weight == nil {
+		return 1
+	}
+	return *b.Weight
+}
+
+// Backend roles.
+const (
+	RolePrimary = "primary"
+	RoleBackup  = "backup"
+)
+
+// IsBackup reports whether the backend only receives traffic while every
+// primary backend of its service is unhealthy.
+func (b BackendConfig) IsBackup() bool {
+	return b.Role == RoleBackup
+}
+
+// GetForwardMethod returns the IPVS forwarding method for this backend.
+// Defaults to "nat" if not set.
+func (b BackendConfig) GetForwardMethod() string {
+	if b.ForwardMethod == "" {
+		return "nat"
+	}
+	return b.ForwardMethod
+}
+
+// validSchedulers is the set of supported IPVS scheduling algorithms.
+var validSchedulers = map[string]bool{
+	"rr":    true,
+	"wrr":   true,
+	"lc":    true,
+	"wlc":   true,
+	"dh":    true,
+	"sh":    true,
+	"mh":    true,
+	"sed":   true,
+	"nq":    true,
+	"fo":    true,
+	"ovf":   true,
+	"lblc":  true,
+	"lblcr": true,
+}
+
+// validInterfaceName reports whether name is a valid Linux network interface
+// name: at most 15 bytes, without '/', ':' or whitespace.
+func validInterfaceName(name string) bool {
+	if name == "" || len(name) > 15 || name == "." || name == ".." {
+		return false
+	}
+	return !strings.ContainsAny(name, "/: \t\n")
+}
+
+// validHostname reports whether name is a valid DNS hostname (RFC 1123):
+// dot-separated labels of letters, digits and hyphens, each 1-63 bytes long
+// and not starting or ending with a hyphen.
+func validHostname(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsHostname reports whether the backend is addressed by hostname rather
+// than IP, and so needs resolving before it can be programmed into IPVS.
+func (b BackendConfig) IsHostname() bool {
+	host, _, err := net.SplitHostPort(b.Address)
+	return err == nil && net.ParseIP(host) == nil
+}
+
+// defaultMarkGroupPersistence is the persistence timeout of mark group
+// services that do not set one.
+const defaultMarkGroupPersistence = "300s"
+
+// validForwardMethods is the set of supported IPVS forwarding methods.
+var validForwardMethods = map[string]bool{
+	"nat":    true,
+	"dr":     true,
+	"tunnel": true,
+	"local":  true,
+}
+
+// validHealthCheckTypes is the set of supported health check types.
+var validHealthCheckTypes = map[string]bool{
+	"tcp":   true,
+	"http":  true,
+	"https": true,
+	"dns":   true,
+	"redis": true,
+	"mysql": true,
+}
+
+// validDNSQueryTypes is the set of record types DNS health checks can query.
+var validDNSQueryTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "MX": true, "NS": true,
+	"PTR": true, "SOA": true, "SRV": true, "TXT": true,
+}
+
+// validDNSRcodes is the set of response codes DNS health checks can expect.
+var validDNSRcodes = map[string]bool{
+	"NOERROR": true, "FORMERR": true, "SERVFAIL": true,
+	"NXDOMAIN": true, "NOTIMP": true, "REFUSED": true,
+}
+
+// validHTTPMethods is the set of methods HTTP health checks can send.
+var validHTTPMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "OPTIONS": true,
+}
+
+// validateHTTPRequest validates the method, Host header and extra headers of
+// an HTTP health check request.
+func validateHTTPRequest(hc HealthCheckConfig) error {
+	method := hc.GetHTTPMethod()
+	if !validHTTPMethods[method] {
+		return fmt.Errorf("unsupported health_check.http_method %q (supported: GET, HEAD, POST, OPTIONS)", method)
+	}
+	if method == "HEAD" && hc.HTTPExpectBody != "" {
+		return fmt.Errorf("health_check.http_expect_body cannot be used with http_method HEAD")
+	}
+	if hc.HTTPHost != "" {
+		if u, err := url.Parse("http://" + hc.HTTPHost); err != nil || u.Host != hc.HTTPHost || u.Hostname() == "" {
+			return fmt.Errorf("health_check.http_host %q must be a host name or host:port", hc.HTTPHost)
+		}
+	}
+	for name, value := range hc.HTTPHeaders {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isHeaderTokenChar(r) }) >= 0 {
+			return fmt.Errorf("health_check.http_headers: invalid header name %q", name)
+		}
+		if strings.EqualFold(name, "Host") {
+			return fmt.Errorf("health_check.http_headers: set the Host header with http_host")
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("health_check.http_headers: value of %s must not contain line breaks", name)
+		}
+	}
+	return nil
+}
+
+// isHeaderTokenChar reports whether r may appear in an HTTP header name
+// (RFC 9110 token).
+func isHeaderTokenChar(r rune) bool {
+	return r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r))
+}
+
+// validProtocols is the set of supported protocols.
+var validProtocols = map[string]bool{
+	"tcp": true,
+	"udp": true,
+}
+
+// validateDNSCheck validates the query of a DNS health check.
+func validateDNSCheck(hc HealthCheckConfig) error {
+	name := strings.TrimSuffix(hc.DNSQueryName, ".")
+	if hc.DNSQueryName == "" {
+		return fmt.Errorf("health_check.dns_query_name is required for dns health checks")
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("health_check.dns_query_name %q is longer than 253 characters", hc.DNSQueryName)
+	}
+	if name != "" {
+		for label := range strings.SplitSeq(name, ".") {
+			if label == "" || len(label) > 63 {
+				return fmt.Errorf("health_check.dns_query_name %q has an empty or too long label", hc.DNSQueryName)
+			}
+		}
+	}
+	if queryType := hc.GetDNSQueryType(); !validDNSQueryTypes[queryType] {
+		return fmt.Errorf("unsupported health_check.dns_query_type %q (supported: A, AAAA, CNAME, MX, NS, PTR, SOA, SRV, TXT)", queryType)
+	}
+	if rcode := hc.GetDNSExpectedRcode(); !validDNSRcodes[rcode] {
+		return fmt.Errorf("unsupported health_check.dns_expected_rcode %q (supported: NOERROR, FORMERR, SERVFAIL, NXDOMAIN, NOTIMP, REFUSED)", rcode)
+	}
+	if protocol := hc.GetDNSProtocol(); protocol != "udp" && protocol != "tcp" {
+		return fmt.Errorf("unsupported health_check.dns_protocol %q (supported: udp, tcp)", protocol)
+	}
+	return nil
+}
+
+// canonicalServiceKey parses an IPVS service given as VIP:port/protocol or
+// fwmark:MARK with an optional /ipv6 suffix, and returns it in the form
+// printed by lvs.ServiceKey, so that equal services compare equal.
+func canonicalServiceKey(s string) (string, error) {
+	if mark, ok := strings.CutPrefix(s, "fwmark:"); ok {
+		mark, ipv6 := strings.CutSuffix(mark, "/ipv6")
+		value, err := strconv.ParseUint(mark, 10, 32)
+		if err != nil || value == 0 {
+			return "", fmt.Errorf("invalid service %q: fwmark must be a positive integer", s)
+		}
+		if ipv6 {
+			return fmt.Sprintf("fwmark:%d/ipv6", value), nil
+		}
+		return fmt.Sprintf("fwmark:%d", value), nil
+	}
+
+	address, protocol, ok := strings.Cut(s, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid service %q: expected VIP:port/protocol or fwmark:MARK", s)
+	}
+	if !validProtocols[protocol] {
+		return "", fmt.Errorf("invalid service %q: unsupported protocol %q (supported: tcp, udp)", s, protocol)
+	}
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid service %q: %w", s, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", fmt.Errorf("invalid service %q: invalid IP address %q", s, host)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", fmt.Errorf("invalid service %q: invalid port %q", s, portStr)
+	}
+	return net.JoinHostPort(ip.String(), strconv.FormatUint(port, 10)) + "/" + protocol, nil
+}
+
+// Manager handles configuration loading, validation, and hot-reload.
+type Manager struct {
+	viper      *viper.Viper
+	current    *Config
+	onChange   chan Change
+	onReload   func()
+	logger     *zap.Logger
+	configPath string
+	dir        bool
+	remote     bool
+	// remoteData is the last fetched remote config document.
+	remoteData []byte
+	generation uint64
+	mu         sync.RWMutex
+}
+
+// newViper returns a viper instance with the config defaults set.
+func newViper() *viper.Viper {
+	viperInstance := viper.New()
+
+	// Set defaults
+	viperInstance.SetDefault("global.log.level", "info")
+	viperInstance.SetDefault("global.log.home", "./logs")
+	viperInstance.SetDefault("global.log.max_size", 50)
+	viperInstance.SetDefault("global.log.max_backups", 3)
+	viperInstance.SetDefault("global.log.max_age", 0)
+	viperInstance.SetDefault("global.log.compress", false)
+	viperInstance.SetDefault("global.log.traffic.enabled", true)
+	viperInstance.SetDefault("global.log.traffic.interval", "15s")
+	viperInstance.SetDefault("global.cleanup_on_exit", true)
+	viperInstance.SetDefault("global.metrics_enabled", true)
+	viperInstance.SetDefault("global.metrics_path", "/metrics")
+
+	return viperInstance
+}
+
+// NewManager creates a config Manager, loads and validates the initial configuration.
+// configPath is either a config file, a directory of config files or the URL
+// of a remote config (see isRemote).
+func NewManager(configPath string, logger *zap.Logger) (*Manager, error) {
+	viperInstance := newViper()
+	remote := isRemote(configPath)
+	dir := !remote && isDir(configPath)
+	if !dir && !remote {
+		viperInstance.SetConfigFile(configPath)
+	}
+
+	manager := &Manager{
+		viper:      viperInstance,
+		configPath: configPath,
+		dir:        dir,
+		remote:     remote,
+		onChange:   make(chan Change, 1),
+		logger:     logger,
+	}
+
+	cfg, err := manager.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	manager.current = cfg
+	manager.generation = 1
+
+	return manager, nil
+}
+
+// Load reads the config file, merges the files of the config directory or
+// fetches the remote config, unmarshals the result, and validates.
+func (m *Manager) Load() (*Config, error) {
+	v := m.viper
+	if m.remote {
+		data, err := fetchRemote(context.Background(), m.configPath)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.remoteData = data
+		m.mu.Unlock()
+		v = newViper()
+		if err := readRemoteInto(v, data); err != nil {
+			return nil, err
+		}
+	} else if m.dir {
+		// Start from a fresh instance so settings of removed files do not linger
+		v = newViper()
+		if err := ReadConfigInto(v, m.configPath); err != nil {
+			return nil, err
+		}
+	} else if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	if err := Validate(&cfg); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks the configuration for correctness.
+func Validate(cfg *Config) error {
+	// Validate log level
+	logLevel := cfg.Global.Log.GetLevel()
+	if !validLogLevels[logLevel] {
+		return fmt.Errorf("global.log.level: unsupported level %q (supported: debug, info, warn, error)", logLevel)
+	}
+
+	if cfg.Global.Instance != "" && !validInstanceName(cfg.Global.Instance) {
+		return fmt.Errorf("global.instance: invalid name %q: use at most %d lowercase letters, digits and hyphens", cfg.Global.Instance, maxInstanceLength)
+	}
+
+	if cfg.Global.StateFile != "" && !filepath.IsAbs(cfg.Global.StateFile) {
+		return fmt.Errorf("global.state_file: %q must be an absolute path", cfg.Global.StateFile)
+	}
+
+	// Validate the admin and metrics listeners
+	if cfg.Global.AdminAddress != "" {
+		if err := validListenerAddress(cfg.Global.GetAdminAddress()); err != nil {
+			return fmt.Errorf("global.admin_address: %w", err)
+		}
+	}
+	if cfg.Global.MetricsAddress != "" {
+		if err := validListenerAddress(cfg.Global.MetricsAddress); err != nil {
+			return fmt.Errorf("global.metrics_address: %w", err)
+		}
+	}
+	if err := cfg.Global.AdminTLS.validate(); err != nil {
+		return fmt.Errorf("global.admin_tls: %w", err)
+	}
+	if err := cfg.Global.MetricsTLS.validate(); err != nil {
+		return fmt.Errorf("global.metrics_tls: %w", err)
+	}
+	if cfg.Global.MetricsTLS.CertFile != "" && cfg.Global.MetricsAddress == "" {
+		return fmt.Errorf("global.metrics_tls: requires global.metrics_address; metrics on the admin listener use global.admin_tls")
+	}
+
+	// Validate traffic logging interval
+	if cfg.Global.Log.Traffic.Interval != "" {
+		interval, err := time.ParseDuration(cfg.Global.Log.Traffic.Interval)
+		if err != nil {
+			return fmt.Errorf("global.log.traffic.interval: invalid duration %q: %w", cfg.Global.Log.Traffic.Interval, err)
+		}
+		if interval < 5*time.Second {
+			return fmt.Errorf("global.log.traffic.interval: minimum interval is 5s, got %v", interval)
+		}
+	}
+	if cfg.Global.Log.Traffic.Window != "" {
+		window, err := time.ParseDuration(cfg.Global.Log.Traffic.Window)
+		if err != nil {
+			return fmt.Errorf("global.log.traffic.window: invalid duration %q: %w", cfg.Global.Log.Traffic.Window, err)
+		}
+		if interval := cfg.Global.Log.Traffic.GetInterval(); window < interval {
+			return fmt.Errorf("global.log.traffic.window: %v must not be shorter than the interval %v", window, interval)
+		}
+	}
+
+	// Validate syslog sink settings
+	if syslogCfg := cfg.Global.Log.Syslog; syslogCfg.IsEnabled() {
+		switch syslogCfg.GetNetwork() {
+		case "udp", "tcp", "unixgram":
+		default:
+			return fmt.Errorf("global.log.syslog.network: unsupported network %q (supported: udp, tcp, unixgram)", syslogCfg.Network)
+		}
+		if syslogCfg.GetNetwork() != "unixgram" {
+			if _, _, err := net.SplitHostPort(syslogCfg.Address); err != nil {
+				return fmt.Errorf("global.log.syslog.address: invalid address %q: %w", syslogCfg.Address, err)
+			}
+		}
+		if _, ok := SyslogFacilities[syslogCfg.GetFacility()]; !ok {
+			return fmt.Errorf("global.log.syslog.facility: unsupported facility %q", syslogCfg.Facility)
+		}
+	}
+
+	// Validate self-monitor settings
+	selfMon := cfg.Global.SelfMonitor
+	if selfMon.Interval != "" {
+		interval, err := time.ParseDuration(selfMon.Interval)
+		if err != nil {
+			return fmt.Errorf("global.self_monitor.interval: invalid duration %q: %w", selfMon.Interval, err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("global.self_monitor.interval: must be positive, got %v", interval)
+		}
+	}
+	if selfMon.MaxGoroutines < 0 || selfMon.MaxHeapMB < 0 || selfMon.MaxOpenFDs < 0 {
+		return fmt.Errorf("global.self_monitor: thresholds must not be negative")
+	}
+
+	// Validate HA peers
+	for i, peer := range cfg.Global.HA.Peers {
+		if _, _, err := net.SplitHostPort(peer); err != nil {
+			return fmt.Errorf("global.ha.peers[%d]: invalid address %q: %w", i, peer, err)
+		}
+	}
+	if cfg.Global.HA.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Global.HA.Timeout)
+		if err != nil {
+			return fmt.Errorf("global.ha.timeout: invalid duration %q: %w", cfg.Global.HA.Timeout, err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("global.ha.timeout: must be positive, got %v", timeout)
+		}
+	}
+
+	// Validate drain watch settings
+	if drain := cfg.Global.Drain; drain.Interval != "" {
+		interval, err := time.ParseDuration(drain.Interval)
+		if err != nil {
+			return fmt.Errorf("global.drain.interval: invalid duration %q: %w", drain.Interval, err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("global.drain.interval: must be positive, got %v", interval)
+		}
+	}
+	if drain := cfg.Global.Drain; drain.Timeout != "" {
+		timeout, err := time.ParseDuration(drain.Timeout)
+		if err != nil {
+			return fmt.Errorf("global.drain.timeout: invalid duration %q: %w", drain.Timeout, err)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("global.drain.timeout: must not be negative, got %v", timeout)
+		}
+	}
+
+	// Validate health check defaults; the rest is checked on the services inheriting them
+	if defaults := cfg.Global.HealthCheck; defaults.Interval != "" {
+		if _, err := time.ParseDuration(defaults.Interval); err != nil {
+			return fmt.Errorf("global.health_check.interval: invalid duration %q: %w", defaults.Interval, err)
+		}
+	}
+	if defaults := cfg.Global.HealthCheck; defaults.Timeout != "" {
+		if _, err := time.ParseDuration(defaults.Timeout); err != nil {
+			return fmt.Errorf("global.health_check.timeout: invalid duration %q: %w", defaults.Timeout, err)
+		}
+	}
+	if checkType := cfg.Global.HealthCheck.GetType(); !validHealthCheckTypes[checkType] {
+		return fmt.Errorf("global.health_check.type: unsupported type %q (supported: tcp, http, https, dns, redis, mysql)", checkType)
+	}
+	if defaults := cfg.Global.HealthCheck; defaults.TLSServerName != "" || defaults.HTTPHost != "" || defaults.Identity != (IdentityConfig{}) {
+		return fmt.Errorf("global.health_check: tls_server_name, http_host and identity can only be set per service")
+	}
+
+	// Validate hostname re-resolution settings
+	if dns := cfg.Global.DNS; dns.Interval != "" {
+		interval, err := time.ParseDuration(dns.Interval)
+		if err != nil {
+			return fmt.Errorf("global.dns.interval: invalid duration %q: %w", dns.Interval, err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("global.dns.interval: must be positive, got %v", interval)
+		}
+	}
+
+	// Validate the reconcile rate limit
+	if interval := cfg.Global.MinReconcileInterval; interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil {
+			return fmt.Errorf("global.min_reconcile_interval: invalid duration %q: %w", interval, err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("global.min_reconcile_interval: must not be negative, got %v", duration)
+		}
+	}
+
+	// Validate the periodic resync
+	if interval := cfg.Global.ResyncInterval; interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil {
+			return fmt.Errorf("global.resync_interval: invalid duration %q: %w", interval, err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("global.resync_interval: must not be negative, got %v", duration)
+		}
+		if duration > 0 && duration < minResyncInterval {
+			return fmt.Errorf("global.resync_interval: must be at least %v or 0 to disable, got %v", minResyncInterval, duration)
+		}
+	}
+
+	// Validate the drift check
+	if interval := cfg.Global.DriftCheckInterval; interval != "" {
+		duration, err := time.ParseDuration(interval)
+		if err != nil {
+			return fmt.Errorf("global.drift_check_interval: invalid duration %q: %w", interval, err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("global.drift_check_interval: must not be negative, got %v", duration)
+		}
+		if duration > 0 && duration < minResyncInterval {
+			return fmt.Errorf("global.drift_check_interval: must be at least %v or 0 to disable, got %v", minResyncInterval, duration)
+		}
+	}
+
+	// Validate the IPVS state cache
+	if ttl := cfg.Global.IPVSCacheTTL; ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return fmt.Errorf("global.ipvs_cache_ttl: invalid duration %q: %w", ttl, err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("global.ipvs_cache_ttl: must not be negative, got %v", duration)
+		}
+	}
+
+	// Validate the IPVS services ezlb must leave alone
+	ignored := make(map[string]bool, len(cfg.Global.IgnoreServices))
+	for i, service := range cfg.Global.IgnoreServices {
+		key, err := canonicalServiceKey(service)
+		if err != nil {
+			return fmt.Errorf("global.ignore_services[%d]: %w", i, err)
+		}
+		ignored[key] = true
+	}
+
+	// Validate remote config polling
+	if remote := cfg.Global.RemoteConfig; remote.Interval != "" {
+		interval, err := time.ParseDuration(remote.Interval)
+		if err != nil {
+			return fmt.Errorf("global.remote_config.interval: invalid duration %q: %w", remote.Interval, err)
+		}
+		if interval < time.Second {
+			return fmt.Errorf("global.remote_config.interval: minimum interval is 1s, got %v", interval)
+		}
+	}
+
+	// Validate the once-mode distributed lock
+	if lock := cfg.Global.Lock; lock.IsEnabled() {
+		if lock.Backend != "consul" && lock.Backend != "etcd" {
+			return fmt.Errorf("global.lock.backend: unsupported backend %q (supported: consul, etcd)", lock.Backend)
+		}
+		lockURL, err := url.Parse(lock.Address)
+		if err != nil || (lockURL.Scheme != "http" && lockURL.Scheme != "https") || lockURL.Host == "" {
+			return fmt.Errorf("global.lock.address: must be an http or https URL, got %q", lock.Address)
+		}
+		if lock.Key == "" {
+			return fmt.Errorf("global.lock.key: is required")
+		}
+		if err := secret.Validate(lock.Token); err != nil {
+			return fmt.Errorf("global.lock.token: %w", err)
+		}
+		if lock.TTL != "" {
+			ttl, err := time.ParseDuration(lock.TTL)
+			if err != nil {
+				return fmt.Errorf("global.lock.ttl: invalid duration %q: %w", lock.TTL, err)
+			}
+			if ttl < 10*time.Second || ttl%time.Second != 0 {
+				return fmt.Errorf("global.lock.ttl: must be a whole number of seconds of at least 10s, got %v", ttl)
+			}
+		}
+		if lock.Wait != "" {
+			wait, err := time.ParseDuration(lock.Wait)
+			if err != nil {
+				return fmt.Errorf("global.lock.wait: invalid duration %q: %w", lock.Wait, err)
+			}
+			if wait < 0 {
+				return fmt.Errorf("global.lock.wait: must not be negative, got %v", wait)
+			}
+		}
+	} else if cfg.Global.Lock != (LockConfig{}) {
+		return fmt.Errorf("global.lock: backend is required")
+	}
+
+	// Validate config snapshots
+	if snapshot := cfg.Global.Snapshot; snapshot.IsEnabled() {
+		if !filepath.IsAbs(snapshot.Dir) {
+			return fmt.Errorf("global.snapshot.dir: %q must be an absolute path", snapshot.Dir)
+		}
+		if snapshot.Interval != "" {
+			interval, err := time.ParseDuration(snapshot.Interval)
+			if err != nil {
+				return fmt.Errorf("global.snapshot.interval: invalid duration %q: %w", snapshot.Interval, err)
+			}
+			if interval < time.Minute {
+				return fmt.Errorf("global.snapshot.interval: must be at least 1m, got %v", interval)
+			}
+		}
+		if snapshot.Retention != "" {
+			retention, err := time.ParseDuration(snapshot.Retention)
+			if err != nil {
+				return fmt.Errorf("global.snapshot.retention: invalid duration %q: %w", snapshot.Retention, err)
+			}
+			if retention < 0 {
+				return fmt.Errorf("global.snapshot.retention: must not be negative, got %v", retention)
+			}
+		}
+	} else if cfg.Global.Snapshot != (SnapshotConfig{}) {
+		return fmt.Errorf("global.snapshot: dir is required")
+	}
+
+	if err := featuregate.Validate(cfg.Global.FeatureGates); err != nil {
+		return fmt.Errorf("global.feature_gates: %w", err)
+	}
+
+	if len(cfg.Services) == 0 {
+		return fmt.Errorf("at least one service must be defined")
+	}
+
+	if err := validateBackendGroups(cfg.BackendGroups); err != nil {
+		return err
+	}
+
+	nameSet := make(map[string]bool)
 	listenSet := make(map[string]bool)
 
 	for i, svc := range cfg.Services {
@@ -364,16 +1967,80 @@ func Validate(cfg *Config) error {
 		}
 		nameSet[svc.Name] = true
 
-		// Validate listen address
-		host, port, err := net.SplitHostPort(svc.Listen)
-		if err != nil {
-			return fmt.Errorf("service %q: invalid listen address %q: %w", svc.Name, svc.Listen, err)
-		}
-		if net.ParseIP(host) == nil {
-			return fmt.Errorf("service %q: invalid listen IP %q", svc.Name, host)
+		if len(svc.BackendGroups) > 0 {
+			backends, err := expandBackendGroups(svc, cfg.BackendGroups)
+			if err != nil {
+				return fmt.Errorf("service %q: %w", svc.Name, err)
+			}
+			cfg.Services[i].Backends = backends
+			svc.Backends = backends
 		}
-		if port == "" || port == "0" {
-			return fmt.Errorf("service %q: listen port must be a positive number", svc.Name)
+
+		// Validate listen address, or for fwmark services the backends' address
+		// family, which the IPVS service takes instead of a VIP
+		var ipv6 bool
+		if svc.FWMark != 0 {
+			if svc.Listen != "" || svc.ListenV6 != "" {
+				return fmt.Errorf("service %q: listen must be empty when fwmark is set", svc.Name)
+			}
+			if svc.FullNAT {
+				return fmt.Errorf("service %q: full_nat is not supported with fwmark", svc.Name)
+			}
+			for j, backend := range svc.Backends {
+				host, _, err := net.SplitHostPort(backend.Address)
+				ip := net.ParseIP(host)
+				if err != nil || ip == nil {
+					// Reported by the backend checks below
+					continue
+				}
+				if j > 0 && (ip.To4() == nil) != ipv6 {
+					return fmt.Errorf("service %q: fwmark service backends must share one address family", svc.Name)
+				}
+				ipv6 = ip.To4() == nil
+			}
+		} else {
+			if len(svc.MarkGroup) > 0 {
+				return fmt.Errorf("service %q: mark_group requires fwmark", svc.Name)
+			}
+			listens, err := svc.ListenAddresses()
+			if err != nil {
+				return fmt.Errorf("service %q: %w", svc.Name, err)
+			}
+			if svc.Listen == "" {
+				return fmt.Errorf("service %q: listen is required", svc.Name)
+			}
+			// With listen_v6, listen holds the IPv4 VIPs and listen_v6 the IPv6 VIPs
+			v4Listens, _ := expandListen(svc.Listen)
+			for k, listen := range listens {
+				host, port, err := net.SplitHostPort(listen)
+				if err != nil {
+					return fmt.Errorf("service %q: invalid listen address %q: %w", svc.Name, listen, err)
+				}
+				ip := net.ParseIP(host)
+				if ip == nil {
+					return fmt.Errorf("service %q: invalid listen IP %q", svc.Name, host)
+				}
+				if port == "" || port == "0" {
+					return fmt.Errorf("service %q: listen port must be a positive number", svc.Name)
+				}
+				if svc.IsDualStack() {
+					if k < len(v4Listens) && ip.To4() == nil {
+						return fmt.Errorf("service %q: listen address %q must be IPv4 when listen_v6 is set", svc.Name, listen)
+					}
+					if k >= len(v4Listens) && ip.To4() != nil {
+						return fmt.Errorf("service %q: listen_v6 address %q must be IPv6", svc.Name, listen)
+					}
+					continue
+				}
+				if k > 0 && (ip.To4() == nil) != ipv6 {
+					return fmt.Errorf("service %q: listen addresses must share one address family", svc.Name)
+				}
+				ipv6 = ip.To4() == nil
+			}
+			// Settings that are IPv6-aware must hold for the IPv6 half
+			if svc.IsDualStack() {
+				ipv6 = true
+			}
 		}
 
 		// Validate protocol (default to tcp)
@@ -382,23 +2049,215 @@ func Validate(cfg *Config) error {
 			cfg.Services[i].Protocol = "tcp"
 			protocol = "tcp"
 		}
-		if !validProtocols[protocol] {
-			return fmt.Errorf("service %q: unsupported protocol %q (supported: tcp, udp)", svc.Name, protocol)
+		protocols := cfg.Services[i].Protocols()
+		for k, p := range protocols {
+			if !validProtocols[p] || slices.Index(protocols, p) != k {
+				return fmt.Errorf("service %q: unsupported protocol %q (supported: tcp, udp, tcp+udp)", svc.Name, protocol)
+			}
+		}
+		if len(protocols) == 0 {
+			return fmt.Errorf("service %q: unsupported protocol %q (supported: tcp, udp, tcp+udp)", svc.Name, protocol)
+		}
+		if len(protocols) > 1 && svc.FWMark != 0 {
+			return fmt.Errorf("service %q: protocol %q is not supported for fwmark services", svc.Name, protocol)
+		}
+
+		if svc.MaxWeight < 0 || svc.MaxWeight > MaxIPVSWeight {
+			return fmt.Errorf("service %q: max_weight must be between 1 and %d, or 0 for the default %d", svc.Name, MaxIPVSWeight, DefaultMaxWeight)
+		}
+
+		if svc.OPS && !slices.Contains(protocols, "udp") {
+			return fmt.Errorf("service %q: ops requires protocol udp or tcp+udp", svc.Name)
 		}
 
 		// Deduplicate by listen address + protocol (IPVS allows same IP:Port for different protocols)
-		listenKey := svc.Listen + "/" + protocol
-		if listenSet[listenKey] {
-			return fmt.Errorf("service %q: duplicate listen address %q for protocol %q", svc.Name, svc.Listen, protocol)
+		// and fwmark services by mark + address family
+		if svc.FWMark != 0 {
+			markKey := fmt.Sprintf("fwmark:%d/ipv6=%t", svc.FWMark, ipv6)
+			if listenSet[markKey] {
+				return fmt.Errorf("service %q: duplicate fwmark %d", svc.Name, svc.FWMark)
+			}
+			listenSet[markKey] = true
+			ignoreKey := fmt.Sprintf("fwmark:%d", svc.FWMark)
+			if ipv6 {
+				ignoreKey += "/ipv6"
+			}
+			if ignored[ignoreKey] {
+				return fmt.Errorf("service %q: fwmark %d is in global.ignore_services", svc.Name, svc.FWMark)
+			}
+		} else {
+			listens, _ := svc.ListenAddresses()
+			for _, listen := range listens {
+				for _, p := range protocols {
+					listenKey := listen + "/" + p
+					if listenSet[listenKey] {
+						return fmt.Errorf("service %q: duplicate listen address %q for protocol %q", svc.Name, listen, p)
+					}
+					listenSet[listenKey] = true
+					if key, _ := canonicalServiceKey(listenKey); ignored[key] {
+						return fmt.Errorf("service %q: listen address %q for protocol %q is in global.ignore_services", svc.Name, listen, p)
+					}
+				}
+			}
+		}
+
+		// Mark group addresses must not be served by a listen service as well.
+		// The mangle rules are IPv4 only, like the SNAT rules.
+		for _, address := range svc.MarkGroup {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("service %q: invalid mark_group address %q: %w", svc.Name, address, err)
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.To4() == nil {
+				return fmt.Errorf("service %q: mark_group address %q must be an IPv4 VIP", svc.Name, address)
+			}
+			if port == "" || port == "0" {
+				return fmt.Errorf("service %q: mark_group port must be a positive number in %q", svc.Name, address)
+			}
+			listenKey := address + "/" + protocol
+			if listenSet[listenKey] {
+				return fmt.Errorf("service %q: mark_group address %q for protocol %q is already in use", svc.Name, address, protocol)
+			}
+			listenSet[listenKey] = true
+		}
+		// DSCP is set by mangle rules on the VIP:ports, which are IPv4 only
+		if svc.DSCP != nil {
+			if *svc.DSCP < 0 || *svc.DSCP > 63 {
+				return fmt.Errorf("service %q: dscp must be between 0 and 63, got %d", svc.Name, *svc.DSCP)
+			}
+			if ipv6 {
+				return fmt.Errorf("service %q: dscp is not supported for IPv6 services", svc.Name)
+			}
+			if svc.FWMark != 0 && len(svc.MarkGroup) == 0 {
+				return fmt.Errorf("service %q: dscp on a fwmark service requires mark_group", svc.Name)
+			}
+		}
+		if len(svc.MarkGroup) > 0 && svc.Persistence.Timeout == "" {
+			// Stickiness across the group's ports is the point of a mark group
+			cfg.Services[i].Persistence.Timeout = defaultMarkGroupPersistence
+			svc.Persistence.Timeout = defaultMarkGroupPersistence
 		}
-		listenSet[listenKey] = true
 
 		// Validate scheduler
 		if !validSchedulers[svc.Scheduler] {
-			return fmt.Errorf("service %q: unsupported scheduler %q (supported: rr, wrr, lc, wlc, dh, sh)", svc.Name, svc.Scheduler)
+			return fmt.Errorf("service %q: unsupported scheduler %q (supported: rr, wrr, lc, wlc, dh, sh, mh, sed, nq, fo, ovf, lblc, lblcr)", svc.Name, svc.Scheduler)
+		}
+
+		// Validate session persistence
+		if svc.Persistence.Timeout != "" {
+			timeout, err := time.ParseDuration(svc.Persistence.Timeout)
+			if err != nil {
+				return fmt.Errorf("service %q: invalid persistence.timeout %q: %w", svc.Name, svc.Persistence.Timeout, err)
+			}
+			if timeout < time.Second || timeout%time.Second != 0 {
+				return fmt.Errorf("service %q: persistence.timeout must be a whole number of seconds, got %v", svc.Name, timeout)
+			}
+		}
+		if svc.Persistence.Netmask != "" {
+			if !svc.Persistence.IsEnabled() {
+				return fmt.Errorf("service %q: persistence.netmask requires persistence.timeout", svc.Name)
+			}
+			if _, err := svc.Persistence.GetPrefixLength(ipv6); err != nil {
+				return fmt.Errorf("service %q: persistence.netmask: %w", svc.Name, err)
+			}
+			if svc.IsDualStack() {
+				if _, err := svc.Persistence.GetPrefixLength(false); err != nil {
+					return fmt.Errorf("service %q: persistence.netmask: %w", svc.Name, err)
+				}
+			}
+		}
+
+		// Validate pre-stop hook
+		if svc.PreStop.URL != "" && len(svc.PreStop.Command) > 0 {
+			return fmt.Errorf("service %q: pre_stop.url and pre_stop.command are mutually exclusive", svc.Name)
+		}
+		if svc.PreStop.URL != "" {
+			hookURL, err := url.Parse(svc.PreStop.URL)
+			if err != nil || (hookURL.Scheme != "http" && hookURL.Scheme != "https") || hookURL.Host == "" {
+				return fmt.Errorf("service %q: pre_stop.url must be an http or https URL, got %q", svc.Name, svc.PreStop.URL)
+			}
+		}
+		if len(svc.PreStop.Command) > 0 && svc.PreStop.Command[0] == "" {
+			return fmt.Errorf("service %q: pre_stop.command must start with a program", svc.Name)
+		}
+		if svc.PreStop.Timeout != "" {
+			if !svc.PreStop.IsEnabled() {
+				return fmt.Errorf("service %q: pre_stop.timeout requires pre_stop.url or pre_stop.command", svc.Name)
+			}
+			timeout, err := time.ParseDuration(svc.PreStop.Timeout)
+			if err != nil {
+				return fmt.Errorf("service %q: invalid pre_stop.timeout %q: %w", svc.Name, svc.PreStop.Timeout, err)
+			}
+			if timeout <= 0 {
+				return fmt.Errorf("service %q: pre_stop.timeout must be positive, got %v", svc.Name, timeout)
+			}
+		}
+
+		// Validate the synthetic probe
+		if synthetic := svc.Synthetic; synthetic.IsEnabled() {
+			if svc.FWMark != 0 {
+				return fmt.Errorf("service %q: synthetic requires a listen address, not fwmark", svc.Name)
+			}
+			if svc.Protocol == "udp" {
+				return fmt.Errorf("service %q: synthetic is not supported for udp services", svc.Name)
+			}
+			for _, field := range []struct{ name, value string }{
+				{"interval", synthetic.Interval},
+				{"timeout", synthetic.Timeout},
+			} {
+				if field.value == "" {
+					continue
+				}
+				duration, err := time.ParseDuration(field.value)
+				if err != nil {
+					return fmt.Errorf("service %q: invalid synthetic.%s %q: %w", svc.Name, field.name, field.value, err)
+				}
+				if duration <= 0 {
+					return fmt.Errorf("service %q: synthetic.%s must be positive, got %v", svc.Name, field.name, duration)
+				}
+			}
+			if synthetic.GetTimeout() >= synthetic.GetInterval() {
+				return fmt.Errorf("service %q: synthetic.timeout (%v) must be less than synthetic.interval (%v)",
+					svc.Name, synthetic.GetTimeout(), synthetic.GetInterval())
+			}
+			if synthetic.HTTPPath != "" && !strings.HasPrefix(synthetic.HTTPPath, "/") {
+				return fmt.Errorf("service %q: synthetic.http_path must start with /, got %q", svc.Name, synthetic.HTTPPath)
+			}
+			if synthetic.HTTPExpectedStatus != 0 {
+				if synthetic.HTTPPath == "" {
+					return fmt.Errorf("service %q: synthetic.http_expected_status requires synthetic.http_path", svc.Name)
+				}
+				if synthetic.HTTPExpectedStatus < 100 || synthetic.HTTPExpectedStatus > 599 {
+					return fmt.Errorf("service %q: invalid synthetic.http_expected_status %d", svc.Name, synthetic.HTTPExpectedStatus)
+				}
+			}
+			if netns := synthetic.Netns; netns != "" && strings.Contains(netns, "/") && !filepath.IsAbs(netns) {
+				return fmt.Errorf("service %q: synthetic.netns must be a namespace name or an absolute path, got %q", svc.Name, netns)
+			}
+		}
+
+		// Validate per-service stats sampling
+		if svc.Stats.Interval != "" {
+			if _, err := time.ParseDuration(svc.Stats.Interval); err != nil {
+				return fmt.Errorf("service %q: invalid stats.interval %q: %w", svc.Name, svc.Stats.Interval, err)
+			}
+		}
+		if svc.Stats.Window != "" {
+			if _, err := time.ParseDuration(svc.Stats.Window); err != nil {
+				return fmt.Errorf("service %q: invalid stats.window %q: %w", svc.Name, svc.Stats.Window, err)
+			}
+		}
+		if svc.Stats.Interval != "" || svc.Stats.Window != "" {
+			interval := svc.Stats.GetInterval(cfg.Global.Log.Traffic)
+			if window := svc.Stats.GetWindow(cfg.Global.Log.Traffic); window < interval {
+				return fmt.Errorf("service %q: stats.window %v must not be shorter than the sampling interval %v", svc.Name, window, interval)
+			}
 		}
 
-		// Validate health check parameters
+		// Validate health check parameters, after filling in global.health_check defaults
+		cfg.Services[i].HealthCheck = svc.HealthCheck.inherit(cfg.Global.HealthCheck)
+		svc.HealthCheck = cfg.Services[i].HealthCheck
 		if svc.HealthCheck.IsEnabled() {
 			if svc.HealthCheck.Interval != "" {
 				if _, err := time.ParseDuration(svc.HealthCheck.Interval); err != nil {
@@ -413,12 +2272,24 @@ func Validate(cfg *Config) error {
 
 			// Validate health check type
 			checkType := svc.HealthCheck.GetType()
-			if checkType != "tcp" && checkType != "http" {
-				return fmt.Errorf("service %q: unsupported health_check.type %q (supported: tcp, http)", svc.Name, checkType)
+			if !validHealthCheckTypes[checkType] {
+				return fmt.Errorf("service %q: unsupported health_check.type %q (supported: tcp, http, https, dns, redis, mysql)", svc.Name, checkType)
+			}
+
+			// Validate TCP-specific parameters
+			if checkType == "tcp" && len(svc.HealthCheck.TCPExpect) > 4096 {
+				return fmt.Errorf("service %q: health_check.tcp_expect must not be longer than 4096 bytes", svc.Name)
+			}
+
+			// Validate DNS-specific parameters
+			if checkType == "dns" {
+				if err := validateDNSCheck(svc.HealthCheck); err != nil {
+					return fmt.Errorf("service %q: %w", svc.Name, err)
+				}
 			}
 
 			// Validate HTTP-specific parameters
-			if checkType == "http" {
+			if checkType == "http" || checkType == "https" {
 				if svc.HealthCheck.HTTPPath != "" && svc.HealthCheck.HTTPPath[0] != '/' {
 					return fmt.Errorf("service %q: health_check.http_path must start with '/'", svc.Name)
 				}
@@ -426,6 +2297,57 @@ func Validate(cfg *Config) error {
 					(svc.HealthCheck.HTTPExpectedStatus < 100 || svc.HealthCheck.HTTPExpectedStatus > 599) {
 					return fmt.Errorf("service %q: health_check.http_expected_status must be between 100 and 599", svc.Name)
 				}
+				if svc.HealthCheck.HTTPMaxIdleConns < 0 {
+					return fmt.Errorf("service %q: health_check.http_max_idle_conns must not be negative", svc.Name)
+				}
+				if err := validateHTTPRequest(svc.HealthCheck); err != nil {
+					return fmt.Errorf("service %q: %w", svc.Name, err)
+				}
+				if svc.HealthCheck.HTTPExpectBody != "" && svc.HealthCheck.IsHTTPExpectBodyRegex() {
+					if _, err := regexp.Compile(svc.HealthCheck.HTTPExpectBody); err != nil {
+						return fmt.Errorf("service %q: invalid health_check.http_expect_body regex %q: %w", svc.Name, svc.HealthCheck.HTTPExpectBody, err)
+					}
+				}
+			}
+			if identity := svc.HealthCheck.Identity; identity.IsEnabled() || identity.HeaderValue != "" || identity.AgentID != "" {
+				if checkType != "http" && checkType != "https" {
+					return fmt.Errorf("service %q: health_check.identity requires an http or https health check", svc.Name)
+				}
+				if identity.HeaderValue != "" && identity.Header == "" {
+					return fmt.Errorf("service %q: health_check.identity.header_value requires identity.header", svc.Name)
+				}
+				if identity.TLSSAN != "" && checkType != "https" {
+					return fmt.Errorf("service %q: health_check.identity.tls_san requires an https health check", svc.Name)
+				}
+				if (identity.AgentPath == "") != (identity.AgentID == "") {
+					return fmt.Errorf("service %q: health_check.identity.agent_path and agent_id must be set together", svc.Name)
+				}
+				if identity.AgentPath != "" && identity.AgentPath[0] != '/' {
+					return fmt.Errorf("service %q: health_check.identity.agent_path must start with '/'", svc.Name)
+				}
+			}
+			if svc.HealthCheck.DegradedLatency != "" {
+				if _, err := time.ParseDuration(svc.HealthCheck.DegradedLatency); err != nil {
+					return fmt.Errorf("service %q: invalid health_check.degraded_latency %q: %w", svc.Name, svc.HealthCheck.DegradedLatency, err)
+				}
+			}
+			if svc.HealthCheck.MaxLatency != "" {
+				maxLatency, err := time.ParseDuration(svc.HealthCheck.MaxLatency)
+				if err != nil {
+					return fmt.Errorf("service %q: invalid health_check.max_latency %q: %w", svc.Name, svc.HealthCheck.MaxLatency, err)
+				}
+				if maxLatency <= 0 || maxLatency >= svc.HealthCheck.GetTimeout() {
+					return fmt.Errorf("service %q: health_check.max_latency must be positive and below timeout %v, got %v", svc.Name, svc.HealthCheck.GetTimeout(), maxLatency)
+				}
+				if degraded := svc.HealthCheck.GetDegradedLatency(); degraded >= maxLatency {
+					return fmt.Errorf("service %q: health_check.degraded_latency %v must be below max_latency %v", svc.Name, degraded, maxLatency)
+				}
+			}
+			if svc.HealthCheck.DegradedWeightFactor < 0 || svc.HealthCheck.DegradedWeightFactor > 1 {
+				return fmt.Errorf("service %q: health_check.degraded_weight_factor must be between 0 and 1", svc.Name)
+			}
+			if svc.HealthCheck.CertExpiryWarnDays < 0 {
+				return fmt.Errorf("service %q: health_check.cert_expiry_warn_days must not be negative", svc.Name)
 			}
 		}
 
@@ -439,12 +2361,41 @@ func Validate(cfg *Config) error {
 			}
 		}
 
+		// Validate multi-homed routing hints
+		if svc.OutputInterface != "" {
+			if !svc.FullNAT {
+				return fmt.Errorf("service %q: output_interface requires full_nat to be enabled", svc.Name)
+			}
+			if !validInterfaceName(svc.OutputInterface) {
+				return fmt.Errorf("service %q: invalid output_interface %q", svc.Name, svc.OutputInterface)
+			}
+		}
+		if svc.RouteTable != 0 {
+			if svc.OutputInterface == "" {
+				return fmt.Errorf("service %q: route_table requires output_interface", svc.Name)
+			}
+			if svc.RouteTable >= 253 && svc.RouteTable <= 255 {
+				return fmt.Errorf("service %q: route_table %d is reserved (default, main, local)", svc.Name, svc.RouteTable)
+			}
+		}
+
+		// Validate the service-wide forwarding method
+		if svc.ForwardMethod != "" {
+			if !validForwardMethods[svc.ForwardMethod] {
+				return fmt.Errorf("service %q: unsupported forward_method %q (supported: nat, dr, tunnel, local)", svc.Name, svc.ForwardMethod)
+			}
+			if svc.FullNAT && svc.ForwardMethod != "nat" {
+				return fmt.Errorf("service %q: forward_method %q cannot be combined with full_nat", svc.Name, svc.ForwardMethod)
+			}
+		}
+
 		// Validate backends
 		if len(svc.Backends) == 0 {
 			return fmt.Errorf("service %q: at least one backend is required", svc.Name)
 		}
 
 		backendSet := make(map[string]bool)
+		var v4Backends, v6Backends, hostnameBackends int
 		for j, backend := range svc.Backends {
 			if backend.Address == "" {
 				return fmt.Errorf("service %q: backend[%d]: address is required", svc.Name, j)
@@ -454,18 +2405,69 @@ func Validate(cfg *Config) error {
 				return fmt.Errorf("service %q: backend[%d]: invalid address %q: %w", svc.Name, j, backend.Address, err)
 			}
 			if net.ParseIP(backendHost) == nil {
-				return fmt.Errorf("service %q: backend[%d]: invalid IP %q", svc.Name, j, backendHost)
+				if !validHostname(backendHost) {
+					return fmt.Errorf("service %q: backend[%d]: invalid IP or hostname %q", svc.Name, j, backendHost)
+				}
+				if svc.FWMark != 0 {
+					return fmt.Errorf("service %q: backend[%d]: fwmark services require IP backends, got hostname %q", svc.Name, j, backendHost)
+				}
 			}
 			if backendPort == "" || backendPort == "0" {
 				return fmt.Errorf("service %q: backend[%d]: port must be a positive number", svc.Name, j)
 			}
+			switch backendIPv6, isIP := backend.isIPv6(); {
+			case !isIP:
+				hostnameBackends++
+			case backendIPv6:
+				v6Backends++
+			default:
+				v4Backends++
+			}
 			if backendSet[backend.Address] {
 				return fmt.Errorf("service %q: backend[%d]: duplicate address %q", svc.Name, j, backend.Address)
 			}
 			backendSet[backend.Address] = true
 
-			if backend.Weight <= 0 {
-				return fmt.Errorf("service %q: backend[%d]: weight must be a positive integer", svc.Name, j)
+			if backend.GetWeight() < 0 {
+				return fmt.Errorf("service %q: backend[%d]: weight must not be negative", svc.Name, j)
+			}
+			if backend.GetWeight() > MaxIPVSWeight {
+				return fmt.Errorf("service %q: backend[%d]: weight must not exceed %d", svc.Name, j, MaxIPVSWeight)
+			}
+
+			if backend.Role != "" && backend.Role != RolePrimary && backend.Role != RoleBackup {
+				return fmt.Errorf("service %q: backend[%d]: role must be %q or %q, got %q", svc.Name, j, RolePrimary, RoleBackup, backend.Role)
+			}
+
+			if backend.Priority < 0 {
+				return fmt.Errorf("service %q: backend[%d]: priority must not be negative", svc.Name, j)
+			}
+
+			// Backends without their own forward_method inherit the service's
+			if backend.ForwardMethod == "" && svc.ForwardMethod != "" {
+				cfg.Services[i].Backends[j].ForwardMethod = svc.ForwardMethod
+				backend.ForwardMethod = svc.ForwardMethod
+			}
+			forwardMethod := backend.GetForwardMethod()
+			if !validForwardMethods[forwardMethod] {
+				return fmt.Errorf("service %q: backend[%d]: unsupported forward_method %q (supported: nat, dr, tunnel, local)", svc.Name, j, forwardMethod)
+			}
+			if svc.FullNAT && forwardMethod != "nat" {
+				return fmt.Errorf("service %q: backend[%d]: forward_method %q cannot be combined with full_nat", svc.Name, j, forwardMethod)
+			}
+
+			// IPVS only forwards across address families through tunnels
+			if backendIPv6, isIP := backend.isIPv6(); isIP && svc.FWMark == 0 && !svc.IsDualStack() &&
+				backendIPv6 != ipv6 && forwardMethod != "tunnel" {
+				return fmt.Errorf("service %q: backend[%d]: address %q is not in the address family of the listen address, which only forward_method tunnel allows", svc.Name, j, backend.Address)
+			}
+		}
+		if svc.IsDualStack() && hostnameBackends == 0 {
+			if v4Backends == 0 {
+				return fmt.Errorf("service %q: listen has no IPv4 backend", svc.Name)
+			}
+			if v6Backends == 0 {
+				return fmt.Errorf("service %q: listen_v6 has no IPv6 backend", svc.Name)
 			}
 		}
 	}
@@ -473,37 +2475,64 @@ func Validate(cfg *Config) error {
 	return nil
 }
 
-// WatchConfig starts watching the config file for changes.
-// On change, it reloads and validates; if valid, updates current config and notifies via onChange channel.
+// WatchConfig starts watching the config file, or every file of the config
+// directory, for changes, or polling the remote config. On change, it reloads
+// and validates; if valid, updates current config and notifies via onChange
+// channel.
 func (m *Manager) WatchConfig() {
-	m.viper.OnConfigChange(func(event fsnotify.Event) {
-		m.logger.Info("config file changed", zap.String("file", event.Name))
-
-		cfg, err := m.Load()
-		if err != nil {
-			m.logger.Error("failed to reload config, keeping previous config", zap.Error(err))
-			return
-		}
+	if m.remote {
+		m.watchRemote()
+		return
+	}
+	m.watchLocal()
+}
 
-		m.mu.Lock()
-		m.current = cfg
-		m.mu.Unlock()
+// reload is called when file changed. It keeps the previous config if the new
+// one fails to load.
+func (m *Manager) reload(file string) {
+	m.logger.Info("config file changed", zap.String("file", file))
 
-		m.logger.Info("config reloaded successfully")
+	cfg, err := m.Load()
+	if err != nil {
+		m.logger.Error("failed to reload config, keeping previous config", zap.Error(err))
+		return
+	}
 
-		// Increment config reload counter via callback if registered
-		if m.onReload != nil {
-			m.onReload()
-		}
+	m.mu.Lock()
+	old := m.current
+	m.current = cfg
+	m.generation++
+	change := Change{Generation: m.generation, Old: old, New: cfg}
+	m.mu.Unlock()
+
+	change.Diffs = DiffConfigs(old, cfg)
+	m.logger.Info("config reloaded successfully", zap.Int("changed_sections", len(change.Diffs)))
+
+	// Increment config reload counter via callback if registered
+	if m.onReload != nil {
+		m.onReload()
+	}
 
-		// Non-blocking send to notify listeners
+	// Non-blocking send to notify listeners. A change the listener has not
+	// received yet is replaced by one diffed from its old config, so the
+	// diff covers both reloads.
+	for {
 		select {
-		case m.onChange <- struct{}{}:
-		default:
+		case m.onChange <- change:
+			return
+		case pending := <-m.onChange:
+			change.Old = pending.Old
+			change.Diffs = DiffConfigs(pending.Old, cfg)
 		}
-	})
+	}
+}
 
-	m.viper.WatchConfig()
+// Generation returns the number of times the configuration has been loaded
+// successfully, starting at 1 for the initial load.
+func (m *Manager) Generation() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generation
 }
 
 // GetConfig returns a snapshot of the current configuration.
@@ -513,8 +2542,9 @@ func (m *Manager) GetConfig() *Config {
 	return m.current
 }
 
-// OnChange returns a read-only channel that signals when config has changed.
-func (m *Manager) OnChange() <-chan struct{} {
+// OnChange returns a read-only channel that signals when config has changed,
+// with what changed since the previously received change.
+func (m *Manager) OnChange() <-chan Change {
 	return m.onChange
 }
 
@@ -3,17 +3,13 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"go.uber.org/zap"
 )
 
-// boolPtr is a helper to create a pointer to a bool value.
-func boolPtr(b bool) *bool {
-	return &b
-}
-
 // validServiceConfig returns a minimal valid ServiceConfig for testing.
 func validServiceConfig() ServiceConfig {
 	return ServiceConfig{
@@ -25,7 +21,7 @@ func validServiceConfig() ServiceConfig {
 			Enabled: boolPtr(true),
 		},
 		Backends: []BackendConfig{
-			{Address: "192.168.1.1:8080", Weight: 1},
+			{Address: "192.168.1.1:8080", Weight: intPtr(1)},
 		},
 	}
 }
@@ -140,6 +136,72 @@ func TestValidate_ProtocolUDP(t *testing.T) {
 	}
 }
 
+func TestValidate_MultiProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		fwmark   uint32
+		wantErr  bool
+	}{
+		{name: "tcp+udp", protocol: "tcp+udp"},
+		{name: "udp+tcp", protocol: "udp+tcp"},
+		{name: "joined list", protocol: "tcp,udp"},
+		{name: "repeated protocol", protocol: "tcp+tcp", wantErr: true},
+		{name: "unknown protocol", protocol: "tcp+sctp", wantErr: true},
+		{name: "empty part", protocol: "+", wantErr: true},
+		{name: "fwmark", protocol: "tcp+udp", fwmark: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Protocol = tt.protocol
+			if tt.fwmark != 0 {
+				cfg.Services[0].Listen = ""
+				cfg.Services[0].FWMark = tt.fwmark
+			}
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_MultiProtocolDuplicateListen(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Protocol = "tcp+udp"
+	dns := validServiceConfig()
+	dns.Name = "dns"
+	dns.Protocol = "udp"
+	cfg.Services = append(cfg.Services, dns)
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), `for protocol "udp"`) {
+		t.Fatalf("expected a duplicate listen address error for udp, got: %v", err)
+	}
+}
+
+func TestValidate_OPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		wantErr  bool
+	}{
+		{name: "udp", protocol: "udp"},
+		{name: "tcp", protocol: "tcp", wantErr: true},
+		{name: "default protocol", protocol: "", wantErr: true},
+		{name: "tcp+udp", protocol: "tcp+udp"},
+		{name: "udp+tcp", protocol: "udp+tcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Protocol = tt.protocol
+			cfg.Services[0].OPS = true
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidate_ProtocolUnsupported(t *testing.T) {
 	cfg := validConfig()
 	cfg.Services[0].Protocol = "sctp"
@@ -216,6 +278,118 @@ func TestValidate_HealthCheckTimeoutInvalid(t *testing.T) {
 	}
 }
 
+func TestValidate_HealthCheckMaxLatency(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxLatency string
+		degraded   string
+		wantErr    bool
+	}{
+		{"valid", "500ms", "", false},
+		{"above degraded", "500ms", "200ms", false},
+		{"invalid", "slow", "", true},
+		{"not below timeout", "3s", "", true},
+		{"not above degraded", "200ms", "200ms", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].HealthCheck.Timeout = "3s"
+			cfg.Services[0].HealthCheck.MaxLatency = tt.maxLatency
+			cfg.Services[0].HealthCheck.DegradedLatency = tt.degraded
+			err := Validate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := validConfig()
+	cfg.Global.HealthCheck.MaxLatency = "250ms"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if got := cfg.Services[0].HealthCheck.GetMaxLatency(); got != 250*time.Millisecond {
+		t.Errorf("expected max_latency to be inherited from global.health_check, got %v", got)
+	}
+}
+
+func TestValidate_HealthCheckDegradedSettingsInvalid(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].HealthCheck.DegradedLatency = "slow"
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for invalid health_check.degraded_latency, got nil")
+	}
+
+	cfg = validConfig()
+	cfg.Services[0].HealthCheck.DegradedWeightFactor = 1.5
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for health_check.degraded_weight_factor above 1, got nil")
+	}
+}
+
+func TestValidate_HealthCheckIdentity(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkType string
+		identity  IdentityConfig
+		wantErr   bool
+	}{
+		{"header on http", "http", IdentityConfig{Header: "X-App", HeaderValue: "web"}, false},
+		{"tls san on https", "https", IdentityConfig{TLSSAN: "api.example.com"}, false},
+		{"agent on http", "http", IdentityConfig{AgentPath: "/ezlb-id", AgentID: "web"}, false},
+		{"identity on tcp", "tcp", IdentityConfig{Header: "X-App"}, true},
+		{"tls san on http", "http", IdentityConfig{TLSSAN: "api.example.com"}, true},
+		{"header value without header", "http", IdentityConfig{HeaderValue: "web"}, true},
+		{"agent path without id", "http", IdentityConfig{AgentPath: "/ezlb-id"}, true},
+		{"agent path relative", "http", IdentityConfig{AgentPath: "ezlb-id", AgentID: "web"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].HealthCheck.Type = tt.checkType
+			cfg.Services[0].HealthCheck.Identity = tt.identity
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Persistence(t *testing.T) {
+	tests := []struct {
+		name        string
+		listen      string
+		persistence PersistenceConfig
+		wantErr     bool
+	}{
+		{"disabled", "10.0.0.1:80", PersistenceConfig{}, false},
+		{"timeout only", "10.0.0.1:80", PersistenceConfig{Timeout: "300s"}, false},
+		{"dotted netmask", "10.0.0.1:80", PersistenceConfig{Timeout: "5m", Netmask: "255.255.255.0"}, false},
+		{"prefix netmask", "10.0.0.1:80", PersistenceConfig{Timeout: "5m", Netmask: "/24"}, false},
+		{"ipv6 prefix", "[2001:db8::1]:80", PersistenceConfig{Timeout: "5m", Netmask: "64"}, false},
+		{"invalid timeout", "10.0.0.1:80", PersistenceConfig{Timeout: "soon"}, true},
+		{"fractional timeout", "10.0.0.1:80", PersistenceConfig{Timeout: "1500ms"}, true},
+		{"netmask without timeout", "10.0.0.1:80", PersistenceConfig{Netmask: "24"}, true},
+		{"non-contiguous netmask", "10.0.0.1:80", PersistenceConfig{Timeout: "5m", Netmask: "255.0.255.0"}, true},
+		{"prefix too long", "10.0.0.1:80", PersistenceConfig{Timeout: "5m", Netmask: "33"}, true},
+		{"dotted netmask on ipv6", "[2001:db8::1]:80", PersistenceConfig{Timeout: "5m", Netmask: "255.255.255.0"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Listen = tt.listen
+			if strings.HasPrefix(tt.listen, "[") {
+				cfg.Services[0].Backends[0].Address = "[2001:db8::10]:8080"
+			}
+			cfg.Services[0].Persistence = tt.persistence
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidate_HealthCheckTypeHTTP(t *testing.T) {
 	cfg := validConfig()
 	cfg.Services[0].HealthCheck.Type = "http"
@@ -256,6 +430,94 @@ func TestValidate_HealthCheckHTTPExpectedStatusInvalid(t *testing.T) {
 	}
 }
 
+func TestValidate_HealthCheckExpectBodyRegex(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].HealthCheck = HealthCheckConfig{Type: "http", HTTPExpectBody: `"status":"ok`, HTTPExpectBodyRegex: boolPtr(false)}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected a substring not to be parsed as a regex, got %v", err)
+	}
+
+	cfg.Services[0].HealthCheck.HTTPExpectBodyRegex = boolPtr(true)
+	cfg.Services[0].HealthCheck.HTTPExpectBody = `"status":"(ok`
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid health_check.http_expect_body regex") {
+		t.Fatalf("expected an invalid regex error, got %v", err)
+	}
+}
+
+func TestValidate_HealthCheckHTTPRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		hc      HealthCheckConfig
+		wantErr string
+	}{
+		{"defaults", HealthCheckConfig{}, ""},
+		{"all options", HealthCheckConfig{HTTPMethod: "POST", HTTPHost: "api.example.com:8080", HTTPHeaders: map[string]string{"X-Probe": "1"}}, ""},
+		{"bad method", HealthCheckConfig{HTTPMethod: "DELETE"}, "unsupported health_check.http_method \"DELETE\""},
+		{"head with body", HealthCheckConfig{HTTPMethod: "HEAD", HTTPExpectBody: "ok"}, "cannot be used with http_method HEAD"},
+		{"bad host", HealthCheckConfig{HTTPHost: "api.example.com/healthz"}, "must be a host name or host:port"},
+		{"bad header name", HealthCheckConfig{HTTPHeaders: map[string]string{"X Probe": "1"}}, "invalid header name"},
+		{"host header", HealthCheckConfig{HTTPHeaders: map[string]string{"host": "api.example.com"}}, "set the Host header with http_host"},
+		{"header injection", HealthCheckConfig{HTTPHeaders: map[string]string{"X-Probe": "1\r\nX-Evil: 1"}}, "must not contain line breaks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.hc.Type = "http"
+			cfg.Services[0].HealthCheck = tt.hc
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := validConfig()
+	cfg.Global.HealthCheck.HTTPHost = "api.example.com"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "http_host and identity can only be set per service") {
+		t.Fatalf("expected global http_host to be rejected, got %v", err)
+	}
+}
+
+func TestValidate_HealthCheckDNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		hc      HealthCheckConfig
+		wantErr string
+	}{
+		{"defaults", HealthCheckConfig{DNSQueryName: "example.com."}, ""},
+		{"root over tcp", HealthCheckConfig{DNSQueryName: ".", DNSQueryType: "NS", DNSExpectedRcode: "NOERROR", DNSProtocol: "tcp"}, ""},
+		{"missing name", HealthCheckConfig{}, "dns_query_name is required"},
+		{"empty label", HealthCheckConfig{DNSQueryName: "example..com"}, "has an empty or too long label"},
+		{"bad type", HealthCheckConfig{DNSQueryName: "example.com", DNSQueryType: "ANY"}, "unsupported health_check.dns_query_type \"ANY\""},
+		{"bad rcode", HealthCheckConfig{DNSQueryName: "example.com", DNSExpectedRcode: "YXDOMAIN"}, "unsupported health_check.dns_expected_rcode"},
+		{"bad protocol", HealthCheckConfig{DNSQueryName: "example.com", DNSProtocol: "doh"}, "unsupported health_check.dns_protocol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.hc.Type = "dns"
+			cfg.Services[0].HealthCheck = tt.hc
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestGetType_Default(t *testing.T) {
 	hc := HealthCheckConfig{}
 	if hc.GetType() != "tcp" {
@@ -401,13 +663,97 @@ func TestValidate_BackendAddressInvalid(t *testing.T) {
 
 func TestValidate_BackendIPInvalid(t *testing.T) {
 	cfg := validConfig()
-	cfg.Services[0].Backends[0].Address = "abc:8080"
+	cfg.Services[0].Backends[0].Address = "bad_host!:8080"
 	err := Validate(cfg)
 	if err == nil {
 		t.Fatal("expected error for invalid backend IP, got nil")
 	}
 }
 
+func TestValidate_BackendHostname(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		fwmark  uint32
+		wantErr bool
+	}{
+		{name: "fqdn", address: "backend.example.com:8080"},
+		{name: "single label", address: "backend:8080"},
+		{name: "trailing dot", address: "backend.example.com.:8080"},
+		{name: "leading hyphen", address: "-backend.example.com:8080", wantErr: true},
+		{name: "empty label", address: "backend..example.com:8080", wantErr: true},
+		{name: "fwmark service", address: "backend.example.com:8080", fwmark: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			if tt.fwmark != 0 {
+				cfg.Services[0].Listen = ""
+				cfg.Services[0].FWMark = tt.fwmark
+			}
+			cfg.Services[0].Backends[0].Address = tt.address
+			err := Validate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_DNSInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval string
+		wantErr  bool
+	}{
+		{name: "unset", interval: ""},
+		{name: "valid", interval: "1m"},
+		{name: "invalid", interval: "soon", wantErr: true},
+		{name: "zero", interval: "0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Global.DNS.Interval = tt.interval
+			err := Validate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Lock(t *testing.T) {
+	tests := []struct {
+		name    string
+		lock    LockConfig
+		wantErr bool
+	}{
+		{name: "unset", lock: LockConfig{}},
+		{name: "consul", lock: LockConfig{Backend: "consul", Address: "http://127.0.0.1:8500", Key: "ezlb/once"}},
+		{name: "etcd with ttl and wait", lock: LockConfig{Backend: "etcd", Address: "https://etcd:2379", Key: "/ezlb/once", TTL: "1m", Wait: "30s"}},
+		{name: "unknown backend", lock: LockConfig{Backend: "zookeeper", Address: "http://zk:2181", Key: "ezlb"}, wantErr: true},
+		{name: "missing backend", lock: LockConfig{Address: "http://127.0.0.1:8500", Key: "ezlb"}, wantErr: true},
+		{name: "address without scheme", lock: LockConfig{Backend: "consul", Address: "127.0.0.1:8500", Key: "ezlb"}, wantErr: true},
+		{name: "missing key", lock: LockConfig{Backend: "consul", Address: "http://127.0.0.1:8500"}, wantErr: true},
+		{name: "token from env", lock: LockConfig{Backend: "consul", Address: "http://127.0.0.1:8500", Key: "ezlb", Token: "env:CONSUL_HTTP_TOKEN"}},
+		{name: "token file without path", lock: LockConfig{Backend: "consul", Address: "http://127.0.0.1:8500", Key: "ezlb", Token: "file:"}, wantErr: true},
+		{name: "ttl too short", lock: LockConfig{Backend: "consul", Address: "http://127.0.0.1:8500", Key: "ezlb", TTL: "5s"}, wantErr: true},
+		{name: "ttl fractional", lock: LockConfig{Backend: "consul", Address: "http://127.0.0.1:8500", Key: "ezlb", TTL: "10500ms"}, wantErr: true},
+		{name: "negative wait", lock: LockConfig{Backend: "etcd", Address: "http://etcd:2379", Key: "ezlb", Wait: "-1s"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Global.Lock = tt.lock
+			err := Validate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidate_BackendPortZero(t *testing.T) {
 	cfg := validConfig()
 	cfg.Services[0].Backends[0].Address = "192.168.1.1:0"
@@ -421,7 +767,7 @@ func TestValidate_BackendAddressDuplicate(t *testing.T) {
 	cfg := validConfig()
 	cfg.Services[0].Backends = append(cfg.Services[0].Backends, BackendConfig{
 		Address: "192.168.1.1:8080",
-		Weight:  2,
+		Weight:  intPtr(2),
 	})
 	err := Validate(cfg)
 	if err == nil {
@@ -431,22 +777,285 @@ func TestValidate_BackendAddressDuplicate(t *testing.T) {
 
 func TestValidate_BackendWeightZero(t *testing.T) {
 	cfg := validConfig()
-	cfg.Services[0].Backends[0].Weight = 0
-	err := Validate(cfg)
-	if err == nil {
-		t.Fatal("expected error for backend weight 0, got nil")
+	cfg.Services[0].Backends[0].Weight = intPtr(0)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected backend weight 0 to pass validation, got: %v", err)
+	}
+}
+
+func TestNewManager_BackendWeightDefault(t *testing.T) {
+	path := writeTestYAML(t, `
+services:
+  - name: web
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    backends:
+      - address: 192.168.1.1:8080
+      - address: 192.168.1.2:8080
+        weight: 0
+`)
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	backends := mgr.GetConfig().Services[0].Backends
+	if weight := backends[0].GetWeight(); weight != 1 {
+		t.Errorf("expected a backend without weight to default to 1, got %d", weight)
+	}
+	if weight := backends[1].GetWeight(); weight != 0 {
+		t.Errorf("expected an explicit weight of 0 to drain the backend, got %d", weight)
 	}
 }
 
 func TestValidate_BackendWeightNegative(t *testing.T) {
 	cfg := validConfig()
-	cfg.Services[0].Backends[0].Weight = -1
+	cfg.Services[0].Backends[0].Weight = intPtr(-1)
 	err := Validate(cfg)
 	if err == nil {
 		t.Fatal("expected error for negative backend weight, got nil")
 	}
 }
 
+func TestValidate_BackendForwardMethod(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Backends[0].ForwardMethod = "tunnel"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected forward_method tunnel to be valid, got: %v", err)
+	}
+
+	cfg.Services[0].Backends[0].ForwardMethod = "bypass"
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for unsupported forward_method, got nil")
+	}
+}
+
+func TestValidate_BackendPriorityNegative(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Backends[0].Priority = -1
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for negative backend priority, got nil")
+	}
+}
+
+func TestValidate_BackendRole(t *testing.T) {
+	for _, role := range []string{"", RolePrimary, RoleBackup} {
+		cfg := validConfig()
+		cfg.Services[0].Backends[0].Role = role
+		if err := Validate(cfg); err != nil {
+			t.Errorf("expected role %q to pass validation, got: %v", role, err)
+		}
+	}
+	cfg := validConfig()
+	cfg.Services[0].Backends[0].Role = "standby"
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for unknown backend role, got nil")
+	}
+}
+
+func TestValidate_BackendForwardMethodWithFullNAT(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].FullNAT = true
+	cfg.Services[0].Backends[0].ForwardMethod = "dr"
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for forward_method dr combined with full_nat, got nil")
+	}
+}
+
+func TestValidate_FWMark(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(svc *ServiceConfig)
+		wantErr bool
+	}{
+		{name: "fwmark without listen", modify: func(svc *ServiceConfig) { svc.Listen = "" }},
+		{name: "fwmark with listen", modify: func(svc *ServiceConfig) {}, wantErr: true},
+		{name: "fwmark with full_nat", modify: func(svc *ServiceConfig) {
+			svc.Listen = ""
+			svc.FullNAT = true
+		}, wantErr: true},
+		{name: "mixed backend families", modify: func(svc *ServiceConfig) {
+			svc.Listen = ""
+			svc.Backends = append(svc.Backends, BackendConfig{Address: "[2001:db8::1]:8080", Weight: intPtr(1)})
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].FWMark = 1
+			tt.modify(&cfg.Services[0])
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_MarkGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		fwmark    uint32
+		markGroup []string
+		wantErr   bool
+	}{
+		{name: "valid", fwmark: 1, markGroup: []string{"10.0.0.4:80", "10.0.0.4:443"}},
+		{name: "without fwmark", markGroup: []string{"10.0.0.4:80"}, wantErr: true},
+		{name: "invalid address", fwmark: 1, markGroup: []string{"10.0.0.4"}, wantErr: true},
+		{name: "ipv6 vip", fwmark: 1, markGroup: []string{"[2001:db8::1]:80"}, wantErr: true},
+		{name: "duplicate address", fwmark: 1, markGroup: []string{"10.0.0.4:80", "10.0.0.4:80"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			if tt.fwmark != 0 {
+				cfg.Services[0].Listen = ""
+				cfg.Services[0].FWMark = tt.fwmark
+			}
+			cfg.Services[0].MarkGroup = tt.markGroup
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_MarkGroupDefaultsPersistence(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Listen = ""
+	cfg.Services[0].FWMark = 1
+	cfg.Services[0].MarkGroup = []string{"10.0.0.4:80", "10.0.0.4:443"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected valid config, got: %v", err)
+	}
+	if got := cfg.Services[0].Persistence.Timeout; got != "300s" {
+		t.Errorf("expected mark group persistence to default to 300s, got %q", got)
+	}
+}
+
+func TestValidate_DSCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		dscp    int
+		modify  func(svc *ServiceConfig)
+		wantErr bool
+	}{
+		{name: "expedited forwarding", dscp: 46, modify: func(svc *ServiceConfig) {}},
+		{name: "zero", dscp: 0, modify: func(svc *ServiceConfig) {}},
+		{name: "negative", dscp: -1, modify: func(svc *ServiceConfig) {}, wantErr: true},
+		{name: "too large", dscp: 64, modify: func(svc *ServiceConfig) {}, wantErr: true},
+		{name: "ipv6 vip", dscp: 46, modify: func(svc *ServiceConfig) {
+			svc.Listen = "[2001:db8::100]:80"
+			svc.Backends = []BackendConfig{{Address: "[2001:db8::1]:8080", Weight: intPtr(1)}}
+		}, wantErr: true},
+		{name: "fwmark with mark_group", dscp: 46, modify: func(svc *ServiceConfig) {
+			svc.Listen = ""
+			svc.FWMark = 1
+			svc.MarkGroup = []string{"10.0.0.4:80"}
+		}},
+		{name: "fwmark without mark_group", dscp: 46, modify: func(svc *ServiceConfig) {
+			svc.Listen = ""
+			svc.FWMark = 1
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].DSCP = &tt.dscp
+			tt.modify(&cfg.Services[0])
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_PreStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		preStop PreStopConfig
+		wantErr bool
+	}{
+		{name: "webhook", preStop: PreStopConfig{URL: "https://orchestrator.local/pre-stop", Timeout: "1m"}},
+		{name: "command", preStop: PreStopConfig{Command: []string{"/usr/local/bin/migrate", "--wait"}}},
+		{name: "both", preStop: PreStopConfig{URL: "http://orchestrator.local", Command: []string{"true"}}, wantErr: true},
+		{name: "not http", preStop: PreStopConfig{URL: "ftp://orchestrator.local"}, wantErr: true},
+		{name: "empty program", preStop: PreStopConfig{Command: []string{""}}, wantErr: true},
+		{name: "timeout without hook", preStop: PreStopConfig{Timeout: "10s"}, wantErr: true},
+		{name: "invalid timeout", preStop: PreStopConfig{URL: "http://orchestrator.local", Timeout: "soon"}, wantErr: true},
+		{name: "zero timeout", preStop: PreStopConfig{URL: "http://orchestrator.local", Timeout: "0s"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].PreStop = tt.preStop
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_DuplicateFWMark(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Listen = ""
+	cfg.Services[0].FWMark = 1
+	second := cfg.Services[0]
+	second.Name = "second"
+	second.Backends = []BackendConfig{{Address: "192.168.1.20:8080", Weight: intPtr(1)}}
+	cfg.Services = append(cfg.Services, second)
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for duplicate fwmark, got nil")
+	}
+
+	// The same mark may be used once per address family
+	cfg.Services[1].Backends = []BackendConfig{{Address: "[2001:db8::20]:8080", Weight: intPtr(1)}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected the same fwmark for IPv4 and IPv6 to be valid, got: %v", err)
+	}
+}
+
+func TestValidate_ServiceForwardMethod(t *testing.T) {
+	tests := []struct {
+		name          string
+		forwardMethod string
+		fullNAT       bool
+		wantErr       bool
+	}{
+		{name: "tunnel", forwardMethod: "tunnel"},
+		{name: "unsupported", forwardMethod: "bypass", wantErr: true},
+		{name: "tunnel with full_nat", forwardMethod: "tunnel", fullNAT: true, wantErr: true},
+		{name: "nat with full_nat", forwardMethod: "nat", fullNAT: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].ForwardMethod = tt.forwardMethod
+			cfg.Services[0].FullNAT = tt.fullNAT
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ServiceForwardMethodInherited(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].ForwardMethod = "tunnel"
+	cfg.Services[0].Backends = append(cfg.Services[0].Backends,
+		BackendConfig{Address: "192.168.1.99:8080", Weight: intPtr(1), ForwardMethod: "dr"})
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected valid config, got: %v", err)
+	}
+
+	backends := cfg.Services[0].Backends
+	if got := backends[0].ForwardMethod; got != "tunnel" {
+		t.Errorf("expected backend to inherit forward_method tunnel, got %q", got)
+	}
+	if got := backends[len(backends)-1].ForwardMethod; got != "dr" {
+		t.Errorf("expected explicit backend forward_method dr to be kept, got %q", got)
+	}
+}
+
 // --- HealthCheckConfig method tests ---
 
 func TestHealthCheckConfig_IsEnabled_DefaultTrue(t *testing.T) {
@@ -470,6 +1079,38 @@ func TestHealthCheckConfig_IsEnabled_ExplicitFalse(t *testing.T) {
 	}
 }
 
+func TestHealthCheckConfig_HTTPRedirectAndRetryAfterDefaults(t *testing.T) {
+	hc := HealthCheckConfig{}
+	if !hc.IsHTTPFollowRedirects() {
+		t.Error("expected IsHTTPFollowRedirects to default to true")
+	}
+	if hc.IsHTTPRetryAfterDegraded() {
+		t.Error("expected IsHTTPRetryAfterDegraded to default to false")
+	}
+
+	hc.HTTPFollowRedirects = boolPtr(false)
+	hc.HTTPRetryAfterDegraded = boolPtr(true)
+	if hc.IsHTTPFollowRedirects() || !hc.IsHTTPRetryAfterDegraded() {
+		t.Error("expected explicit values to override defaults")
+	}
+}
+
+func TestHealthCheckConfig_DegradedDefaults(t *testing.T) {
+	hc := HealthCheckConfig{}
+	if hc.GetDegradedLatency() != 0 {
+		t.Errorf("expected degraded latency to be disabled by default, got %v", hc.GetDegradedLatency())
+	}
+	if hc.GetDegradedWeightFactor() != 0.5 {
+		t.Errorf("expected default degraded weight factor 0.5, got %v", hc.GetDegradedWeightFactor())
+	}
+
+	hc.DegradedLatency = "250ms"
+	hc.DegradedWeightFactor = 0.25
+	if hc.GetDegradedLatency() != 250*time.Millisecond || hc.GetDegradedWeightFactor() != 0.25 {
+		t.Error("expected explicit degraded settings to be returned")
+	}
+}
+
 func TestHealthCheckConfig_GetInterval_Default(t *testing.T) {
 	hc := HealthCheckConfig{}
 	if hc.GetInterval() != 5*time.Second {
@@ -661,6 +1302,29 @@ func TestManager_OnChangeChannel(t *testing.T) {
 	}
 }
 
+func TestEnabledServices(t *testing.T) {
+	services := []ServiceConfig{
+		{Name: "default"},
+		{Name: "disabled", Enabled: boolPtr(false)},
+		{Name: "enabled", Enabled: boolPtr(true)},
+	}
+	enabled := EnabledServices(services)
+	if len(enabled) != 2 || enabled[0].Name != "default" || enabled[1].Name != "enabled" {
+		t.Errorf("expected default and enabled services, got %+v", enabled)
+	}
+}
+
+func TestManager_LoadYAML_ServiceDisabled(t *testing.T) {
+	path := writeTestYAML(t, strings.Replace(validYAML, "    listen: 10.0.0.1:80\n", "    listen: 10.0.0.1:80\n    enabled: false\n", 1))
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("expected a disabled service to load, got: %v", err)
+	}
+	if svc := mgr.GetConfig().Services[0]; svc.IsEnabled() {
+		t.Error("expected the service to be disabled")
+	}
+}
+
 // --- GlobalConfig.IsCleanupOnExit tests ---
 
 func TestGlobalConfig_IsCleanupOnExit_DefaultTrue(t *testing.T) {
@@ -684,6 +1348,17 @@ func TestGlobalConfig_IsCleanupOnExit_ExplicitFalse(t *testing.T) {
 	}
 }
 
+func TestGlobalConfig_IsCleanupOnPanic_FollowsCleanupOnExit(t *testing.T) {
+	g := GlobalConfig{CleanupOnExit: boolPtr(false)}
+	if g.IsCleanupOnPanic() {
+		t.Error("expected IsCleanupOnPanic to follow cleanup_on_exit when not set")
+	}
+	g.CleanupOnPanic = boolPtr(true)
+	if !g.IsCleanupOnPanic() {
+		t.Error("expected IsCleanupOnPanic to return true when explicitly true")
+	}
+}
+
 func TestManager_LoadYAML_CleanupOnExitDefault(t *testing.T) {
 	// cleanup_on_exit not set in YAML — should default to true
 	path := writeTestYAML(t, validYAML)
@@ -785,6 +1460,71 @@ func TestTrafficLogConfig_GetInterval_Valid(t *testing.T) {
 	}
 }
 
+func TestServiceStatsConfig_Defaults(t *testing.T) {
+	global := TrafficLogConfig{Interval: "30s", Window: "5m"}
+	if got := (ServiceStatsConfig{}).GetInterval(global); got != 30*time.Second {
+		t.Errorf("expected inherited interval 30s, got %v", got)
+	}
+	if got := (ServiceStatsConfig{}).GetWindow(global); got != 5*time.Minute {
+		t.Errorf("expected inherited window 5m, got %v", got)
+	}
+	if got := (ServiceStatsConfig{Interval: "100ms"}).GetInterval(global); got != time.Second {
+		t.Errorf("expected clamped interval 1s, got %v", got)
+	}
+	if got := (ServiceStatsConfig{Window: "10s"}).GetWindow(global); got != 10*time.Second {
+		t.Errorf("expected window 10s, got %v", got)
+	}
+	if got := (TrafficLogConfig{}).GetWindow(); got != time.Minute {
+		t.Errorf("expected default window 1m, got %v", got)
+	}
+}
+
+func TestValidate_ServiceStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		stats        ServiceStatsConfig
+		globalWindow string
+		wantErr      bool
+	}{
+		{"inherit global", ServiceStatsConfig{}, "", false},
+		{"tight window", ServiceStatsConfig{Interval: "1s", Window: "10s"}, "", false},
+		{"invalid interval", ServiceStatsConfig{Interval: "bad"}, "", true},
+		{"invalid window", ServiceStatsConfig{Window: "bad"}, "", true},
+		{"window shorter than interval", ServiceStatsConfig{Interval: "30s", Window: "10s"}, "", true},
+		{"global window shorter than interval", ServiceStatsConfig{}, "1s", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Stats = tt.stats
+			cfg.Global.Log.Traffic.Window = tt.globalWindow
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDrainConfig_Defaults(t *testing.T) {
+	d := DrainConfig{}
+	if !d.IsEnabled() || d.IsRemove() {
+		t.Errorf("expected drain watch enabled without removal by default")
+	}
+	if d.GetInterval() != 5*time.Second || d.GetTimeout() != 0 {
+		t.Errorf("expected 5s interval and no timeout, got %v and %v", d.GetInterval(), d.GetTimeout())
+	}
+
+	cfg := validConfig()
+	cfg.Global.Drain = DrainConfig{Interval: "0s"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for zero drain interval")
+	}
+	cfg.Global.Drain = DrainConfig{Timeout: "soon"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for invalid drain timeout")
+	}
+}
+
 // --- Validate log-related tests ---
 
 func TestValidate_LogLevelInvalid(t *testing.T) {
@@ -908,3 +1648,562 @@ services:
 		t.Error("expected IsCleanupOnExit to return false when cleanup_on_exit: false in config")
 	}
 }
+
+// --- SelfMonitorConfig tests ---
+
+func TestSelfMonitorConfig_Defaults(t *testing.T) {
+	cfg := SelfMonitorConfig{}
+	if !cfg.IsEnabled() {
+		t.Error("expected self-monitor to be enabled by default")
+	}
+	if cfg.GetInterval() != 30*time.Second {
+		t.Errorf("expected default interval 30s, got %v", cfg.GetInterval())
+	}
+}
+
+func TestValidate_SelfMonitorInvalid(t *testing.T) {
+	cfg := &Config{
+		Global: GlobalConfig{SelfMonitor: SelfMonitorConfig{Interval: "soon"}},
+		Services: []ServiceConfig{{
+			Name: "svc", Listen: "10.0.0.1:80", Scheduler: "rr",
+			Backends: []BackendConfig{{Address: "192.168.1.1:80", Weight: intPtr(1)}},
+		}},
+	}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for invalid self_monitor.interval")
+	}
+
+	cfg.Global.SelfMonitor = SelfMonitorConfig{MaxGoroutines: -1}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for negative self_monitor threshold")
+	}
+}
+
+func TestValidate_HAPeers(t *testing.T) {
+	cfg := validConfig()
+	cfg.Global.HA = HAConfig{Peers: []string{"10.0.0.2:9095", "10.0.0.3:9095"}, Timeout: "2s"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected valid HA peers to pass validation, got: %v", err)
+	}
+
+	cfg.Global.HA.Peers = []string{"10.0.0.2"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for peer address without port")
+	}
+
+	cfg.Global.HA = HAConfig{Timeout: "-1s"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for negative ha.timeout")
+	}
+
+	if (HAConfig{}).GetTimeout() != 3*time.Second {
+		t.Errorf("expected default ha.timeout 3s, got %v", (HAConfig{}).GetTimeout())
+	}
+}
+
+func TestConfig_ServicesHashIgnoresNodeLocalSettings(t *testing.T) {
+	a := validConfig()
+	b := validConfig()
+	b.Global.AdminAddress = "10.0.0.2:9095"
+	if a.ServicesHash() != b.ServicesHash() {
+		t.Error("expected node-local global settings not to affect the services hash")
+	}
+
+	b.Services[0].Backends[0].Weight = intPtr(b.Services[0].Backends[0].GetWeight() + 1)
+	if a.ServicesHash() == b.ServicesHash() {
+		t.Error("expected a backend weight change to change the services hash")
+	}
+}
+
+func TestManager_GenerationStartsAtOne(t *testing.T) {
+	mgr, err := NewManager(writeTestYAML(t, validYAML), zap.NewNop())
+	if err != nil {
+		t.Fatalf("expected NewManager to succeed, got: %v", err)
+	}
+	if mgr.Generation() != 1 {
+		t.Errorf("expected generation 1 after initial load, got %d", mgr.Generation())
+	}
+}
+
+func TestValidate_HTTPSHealthCheck(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].HealthCheck.Type = "https"
+	cfg.Services[0].HealthCheck.TLSServerName = "api.example.com"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected https health check to pass validation, got: %v", err)
+	}
+	if cfg.Services[0].HealthCheck.IsTLSVerify() {
+		t.Error("expected tls_verify to default to false")
+	}
+	if cfg.Services[0].HealthCheck.GetCertExpiryWarnDays() != 14 {
+		t.Errorf("expected default cert_expiry_warn_days 14, got %d", cfg.Services[0].HealthCheck.GetCertExpiryWarnDays())
+	}
+
+	cfg.Services[0].HealthCheck.CertExpiryWarnDays = -1
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for negative cert_expiry_warn_days")
+	}
+}
+
+func TestValidate_Syslog(t *testing.T) {
+	cfg := validConfig()
+	cfg.Global.Log.Syslog = SyslogConfig{Enabled: boolPtr(true)}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected local syslog to pass validation, got: %v", err)
+	}
+	if cfg.Global.Log.Syslog.GetNetwork() != "unixgram" || cfg.Global.Log.Syslog.GetAddress() != "/dev/log" {
+		t.Errorf("expected local syslog defaults, got %s %s", cfg.Global.Log.Syslog.GetNetwork(), cfg.Global.Log.Syslog.GetAddress())
+	}
+
+	cfg.Global.Log.Syslog.Address = "syslog.example.com"
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for remote syslog address without port")
+	}
+
+	cfg.Global.Log.Syslog = SyslogConfig{Enabled: boolPtr(true), Address: "10.0.0.9:514", Facility: "mail2"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for unsupported facility")
+	}
+}
+
+func TestValidate_FeatureGates(t *testing.T) {
+	tests := []struct {
+		name    string
+		gates   map[string]bool
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "known", gates: map[string]bool{"adaptive_weights": true, "ebpf_dataplane": false}},
+		{name: "unknown", gates: map[string]bool{"warp_drive": true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Global.FeatureGates = tt.gates
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_KernelSchedulers(t *testing.T) {
+	for _, scheduler := range []string{"mh", "sed", "nq", "fo", "ovf", "lblc", "lblcr"} {
+		cfg := validConfig()
+		cfg.Services[0].Scheduler = scheduler
+		if err := Validate(cfg); err != nil {
+			t.Errorf("expected scheduler %q to be valid, got: %v", scheduler, err)
+		}
+	}
+}
+
+func TestValidate_OutputInterface(t *testing.T) {
+	tests := []struct {
+		name            string
+		fullNAT         bool
+		outputInterface string
+		routeTable      uint32
+		wantErr         bool
+	}{
+		{name: "interface", fullNAT: true, outputInterface: "eth1"},
+		{name: "interface and table", fullNAT: true, outputInterface: "bond0.100", routeTable: 100},
+		{name: "interface without full_nat", outputInterface: "eth1", wantErr: true},
+		{name: "invalid interface", fullNAT: true, outputInterface: "eth/1", wantErr: true},
+		{name: "interface name too long", fullNAT: true, outputInterface: "averyveryverylongif", wantErr: true},
+		{name: "table without interface", fullNAT: true, routeTable: 100, wantErr: true},
+		{name: "reserved table", fullNAT: true, outputInterface: "eth1", routeTable: 254, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].FullNAT = tt.fullNAT
+			cfg.Services[0].OutputInterface = tt.outputInterface
+			cfg.Services[0].RouteTable = tt.routeTable
+			if err := Validate(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_GlobalHealthCheckDefaults(t *testing.T) {
+	cfg := validConfig()
+	cfg.Global.HealthCheck = HealthCheckConfig{
+		Type:          "http",
+		Interval:      "2s",
+		Timeout:       "1s",
+		HTTPPath:      "/healthz",
+		FailCount:     5,
+		RiseCount:     3,
+		HTTPKeepAlive: boolPtr(true),
+	}
+	override := validServiceConfig()
+	override.Name = "override-svc"
+	override.Listen = "10.0.0.2:80"
+	override.HealthCheck = HealthCheckConfig{Type: "tcp", Interval: "10s", FailCount: 1}
+	cfg.Services = append(cfg.Services, override)
+
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inherited := cfg.Services[0].HealthCheck
+	if inherited.GetType() != "http" || inherited.GetInterval() != 2*time.Second || inherited.GetTimeout() != time.Second ||
+		inherited.GetHTTPPath() != "/healthz" || inherited.GetFailCount() != 5 || inherited.GetRiseCount() != 3 || !inherited.IsHTTPKeepAlive() {
+		t.Errorf("expected service to inherit the global defaults, got %+v", inherited)
+	}
+	if !inherited.IsEnabled() {
+		t.Error("expected service's own enabled setting to be kept")
+	}
+
+	overridden := cfg.Services[1].HealthCheck
+	if overridden.GetType() != "tcp" || overridden.GetInterval() != 10*time.Second || overridden.GetFailCount() != 1 {
+		t.Errorf("expected service settings to override the defaults, got %+v", overridden)
+	}
+	if overridden.GetTimeout() != time.Second || overridden.GetRiseCount() != 3 {
+		t.Errorf("expected unset service settings to inherit the defaults, got %+v", overridden)
+	}
+}
+
+func TestValidate_GlobalHealthCheckDisabled(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].HealthCheck = HealthCheckConfig{}
+	cfg.Global.HealthCheck.Enabled = boolPtr(false)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Services[0].HealthCheck.IsEnabled() {
+		t.Error("expected health checks to be disabled by the global default")
+	}
+}
+
+func TestValidate_GlobalHealthCheckInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults HealthCheckConfig
+		wantErr  bool
+	}{
+		{name: "empty", defaults: HealthCheckConfig{}},
+		{name: "invalid interval", defaults: HealthCheckConfig{Interval: "often"}, wantErr: true},
+		{name: "invalid timeout", defaults: HealthCheckConfig{Timeout: "-"}, wantErr: true},
+		{name: "invalid type", defaults: HealthCheckConfig{Type: "icmp"}, wantErr: true},
+		{name: "tls server name", defaults: HealthCheckConfig{TLSServerName: "example.com"}, wantErr: true},
+		{name: "identity", defaults: HealthCheckConfig{Identity: IdentityConfig{Header: "X-App"}}, wantErr: true},
+		// Invalid inherited values are reported for the service using them
+		{name: "invalid inherited path", defaults: HealthCheckConfig{Type: "http", HTTPPath: "healthz"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Global.HealthCheck = tt.defaults
+			err := Validate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewManager_GlobalHealthCheckDefaults(t *testing.T) {
+	path := writeTestYAML(t, `
+global:
+  health_check:
+    interval: 7s
+    fail_count: 4
+services:
+  - name: web
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    backends:
+      - address: 192.168.1.1:8080
+        weight: 1
+`)
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	healthCheck := mgr.GetConfig().Services[0].HealthCheck
+	if healthCheck.GetInterval() != 7*time.Second || healthCheck.GetFailCount() != 4 {
+		t.Errorf("expected interval 7s and fail_count 4 from global.health_check, got %+v", healthCheck)
+	}
+}
+
+func TestValidate_ListenerAddresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*GlobalConfig)
+		wantErr string
+	}{
+		{"localhost port", func(g *GlobalConfig) { g.AdminAddress = ":9095" }, ""},
+		{"unix socket", func(g *GlobalConfig) { g.AdminAddress = "unix:///run/ezlb/admin.sock" }, ""},
+		{"separate metrics", func(g *GlobalConfig) { g.MetricsAddress = "0.0.0.0:9100" }, ""},
+		{"default socket", func(g *GlobalConfig) { g.AdminAddress = "unix:" }, ""},
+		{"empty socket path", func(g *GlobalConfig) { g.AdminAddress = "unix://" }, "global.admin_address"},
+		{"empty metrics socket path", func(g *GlobalConfig) { g.MetricsAddress = "unix:" }, "global.metrics_address"},
+		{"missing port", func(g *GlobalConfig) { g.MetricsAddress = "10.0.0.1" }, "global.metrics_address"},
+		{"hostname", func(g *GlobalConfig) { g.AdminAddress = "director.example.com:9095" }, "global.admin_address"},
+		{"cert without key", func(g *GlobalConfig) { g.AdminTLS.CertFile = "/etc/ezlb/cert.pem" }, "global.admin_tls"},
+		{"client CA without cert", func(g *GlobalConfig) { g.AdminTLS.ClientCAFile = "/etc/ezlb/ca.pem" }, "global.admin_tls"},
+		{"metrics TLS without listener", func(g *GlobalConfig) {
+			g.MetricsTLS = ListenerTLSConfig{CertFile: "/etc/ezlb/cert.pem", KeyFile: "/etc/ezlb/key.pem"}
+		}, "global.metrics_tls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg.Global)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid config, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Instance(t *testing.T) {
+	for _, name := range []string{"edge-a", "t1", "tenant-b-edge01"} {
+		cfg := validConfig()
+		cfg.Global.Instance = name
+		if err := Validate(cfg); err != nil {
+			t.Errorf("expected instance %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"Edge", "edge_a", "-edge", "edge-", "tenant-b-edge012"} {
+		cfg := validConfig()
+		cfg.Global.Instance = name
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "global.instance") {
+			t.Errorf("expected instance %q to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestGlobalConfig_InstanceScoping(t *testing.T) {
+	var defaults GlobalConfig
+	defaults.AdminAddress = "unix:"
+	defaults.Lock.Key = "ezlb/once"
+	if got := defaults.InstanceScoped("ezlb"); got != "ezlb" {
+		t.Errorf("expected the default instance to leave names unchanged, got %q", got)
+	}
+	if got := defaults.GetAdminAddress(); got != "unix:/run/ezlb/ezlb.sock" {
+		t.Errorf("unexpected default socket %q", got)
+	}
+	if got := defaults.ScopedLock().Key; got != "ezlb/once" {
+		t.Errorf("expected the default instance to keep the lock key, got %q", got)
+	}
+
+	named := defaults
+	named.Instance = "edge-a"
+	if got := named.InstanceScoped("ezlb"); got != "ezlb-edge-a" {
+		t.Errorf("unexpected scoped name %q", got)
+	}
+	if got := named.GetAdminAddress(); got != "unix:/run/ezlb/edge-a.sock" {
+		t.Errorf("unexpected instance socket %q", got)
+	}
+	if got := named.ScopedLock().Key; got != "ezlb/once/edge-a" {
+		t.Errorf("unexpected scoped lock key %q", got)
+	}
+	if named.Lock.Key != "ezlb/once" {
+		t.Errorf("expected ScopedLock not to modify the config")
+	}
+
+	named.AdminAddress = "127.0.0.1:9095"
+	if got := named.GetAdminAddress(); got != "127.0.0.1:9095" {
+		t.Errorf("expected an explicit address to be kept, got %q", got)
+	}
+}
+
+func TestGlobalConfig_GetStateFile(t *testing.T) {
+	var global GlobalConfig
+	if got := global.GetStateFile(); got != DefaultStateDir+"/state.json" {
+		t.Errorf("unexpected default state file %q", got)
+	}
+	global.Instance = "edge-a"
+	if got := global.GetStateFile(); got != DefaultStateDir+"/state-edge-a.json" {
+		t.Errorf("unexpected instance state file %q", got)
+	}
+	global.StateFile = "/tmp/ezlb.json"
+	if got := global.GetStateFile(); got != "/tmp/ezlb.json" {
+		t.Errorf("expected an explicit state file to be kept, got %q", got)
+	}
+
+	cfg := validConfig()
+	cfg.Global.StateFile = "state.json"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "global.state_file") {
+		t.Errorf("expected a relative state_file to be rejected, got %v", err)
+	}
+}
+
+func TestGlobalConfig_GetResyncInterval(t *testing.T) {
+	var global GlobalConfig
+	if got := global.GetResyncInterval(); got != 0 {
+		t.Errorf("expected resync to be disabled by default, got %v", got)
+	}
+	global.ResyncInterval = "5m"
+	if got := global.GetResyncInterval(); got != 5*time.Minute {
+		t.Errorf("expected 5m, got %v", got)
+	}
+
+	for value, wantErr := range map[string]string{
+		"0s":    "",
+		"30s":   "",
+		"soon":  "invalid duration",
+		"-1m":   "must not be negative",
+		"100ms": "must be at least 1s",
+	} {
+		cfg := validConfig()
+		cfg.Global.ResyncInterval = value
+		err := Validate(cfg)
+		if wantErr == "" {
+			if err != nil {
+				t.Errorf("resync_interval %q: unexpected error: %v", value, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "global.resync_interval: "+wantErr) {
+			t.Errorf("resync_interval %q: expected error containing %q, got %v", value, wantErr, err)
+		}
+	}
+}
+
+func TestGlobalConfig_GetDriftCheckInterval(t *testing.T) {
+	var global GlobalConfig
+	if got := global.GetDriftCheckInterval(); got != 0 {
+		t.Errorf("expected the drift check to be disabled by default, got %v", got)
+	}
+	global.DriftCheckInterval = "1m"
+	if got := global.GetDriftCheckInterval(); got != time.Minute {
+		t.Errorf("expected 1m, got %v", got)
+	}
+
+	cfg := validConfig()
+	cfg.Global.DriftCheckInterval = "10ms"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "global.drift_check_interval: must be at least 1s") {
+		t.Errorf("expected a too short drift_check_interval to be rejected, got %v", err)
+	}
+}
+
+func TestGlobalConfig_GetIPVSCacheTTL(t *testing.T) {
+	var global GlobalConfig
+	if got := global.GetIPVSCacheTTL(); got != 0 {
+		t.Errorf("expected the cache to be disabled by default, got %v", got)
+	}
+	global.IPVSCacheTTL = "500ms"
+	if got := global.GetIPVSCacheTTL(); got != 500*time.Millisecond {
+		t.Errorf("expected 500ms, got %v", got)
+	}
+
+	for value, wantErr := range map[string]string{
+		"forever": "invalid duration",
+		"-1s":     "must not be negative",
+	} {
+		cfg := validConfig()
+		cfg.Global.IPVSCacheTTL = value
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "global.ipvs_cache_ttl: "+wantErr) {
+			t.Errorf("ipvs_cache_ttl %q: expected error containing %q, got %v", value, wantErr, err)
+		}
+	}
+}
+
+func TestValidate_IgnoreServices(t *testing.T) {
+	for _, tt := range []struct {
+		ignore  []string
+		wantErr string
+	}{
+		{[]string{"192.168.0.5:443/tcp", "[2001:db8::1]:53/udp", "fwmark:7", "fwmark:8/ipv6"}, ""},
+		{[]string{"192.168.0.5:443"}, "global.ignore_services[0]: invalid service \"192.168.0.5:443\": expected VIP:port/protocol"},
+		{[]string{"fwmark:0"}, "global.ignore_services[0]: invalid service \"fwmark:0\": fwmark must be a positive integer"},
+		{[]string{"fwmark:1", "192.168.0.5:443/sctp"}, "global.ignore_services[1]: invalid service \"192.168.0.5:443/sctp\": unsupported protocol"},
+		{[]string{"10.0.0.1:80/tcp"}, "service \"test-svc\": listen address \"10.0.0.1:80\" for protocol \"tcp\" is in global.ignore_services"},
+		{[]string{"10.0.0.1:80/udp"}, ""},
+	} {
+		cfg := validConfig()
+		cfg.Global.IgnoreServices = tt.ignore
+		err := Validate(cfg)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ignore_services %v: unexpected error: %v", tt.ignore, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ignore_services %v: expected error containing %q, got %v", tt.ignore, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_Synthetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		synthetic SyntheticConfig
+		protocol  string
+		wantErr   string
+	}{
+		{"disabled with invalid fields", SyntheticConfig{Interval: "soon"}, "tcp", ""},
+		{"defaults", SyntheticConfig{Enabled: boolPtr(true)}, "tcp", ""},
+		{"http", SyntheticConfig{Enabled: boolPtr(true), HTTPPath: "/healthz", HTTPExpectedStatus: 204, Netns: "probe"}, "tcp", ""},
+		{"udp", SyntheticConfig{Enabled: boolPtr(true)}, "udp", "synthetic is not supported for udp services"},
+		{"invalid interval", SyntheticConfig{Enabled: boolPtr(true), Interval: "soon"}, "tcp", "invalid synthetic.interval"},
+		{"timeout not below interval", SyntheticConfig{Enabled: boolPtr(true), Interval: "5s", Timeout: "5s"}, "tcp", "must be less than synthetic.interval"},
+		{"relative path", SyntheticConfig{Enabled: boolPtr(true), HTTPPath: "healthz"}, "tcp", "synthetic.http_path must start with /"},
+		{"status without path", SyntheticConfig{Enabled: boolPtr(true), HTTPExpectedStatus: 200}, "tcp", "requires synthetic.http_path"},
+		{"relative netns path", SyntheticConfig{Enabled: boolPtr(true), Netns: "run/netns/probe"}, "tcp", "synthetic.netns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Protocol = tt.protocol
+			cfg.Services[0].Synthetic = tt.synthetic
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Snapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot SnapshotConfig
+		wantErr  string
+	}{
+		{"disabled", SnapshotConfig{}, ""},
+		{"valid", SnapshotConfig{Dir: "/var/lib/ezlb/snapshots", Interval: "1h", Retention: "0"}, ""},
+		{"no dir", SnapshotConfig{Interval: "1h"}, "global.snapshot: dir is required"},
+		{"relative dir", SnapshotConfig{Dir: "snapshots"}, "global.snapshot.dir"},
+		{"short interval", SnapshotConfig{Dir: "/snapshots", Interval: "10s"}, "global.snapshot.interval"},
+		{"negative retention", SnapshotConfig{Dir: "/snapshots", Retention: "-1h"}, "global.snapshot.retention"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Global.Snapshot = tt.snapshot
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	var defaults SnapshotConfig
+	if defaults.GetInterval() != time.Hour || defaults.GetRetention() != 30*24*time.Hour {
+		t.Errorf("unexpected defaults %v, %v", defaults.GetInterval(), defaults.GetRetention())
+	}
+}
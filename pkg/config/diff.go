@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change describes a successful config reload. It is sent on the channel
+// returned by Manager.OnChange.
+type Change struct {
+	Generation uint64
+	Old        *Config
+	New        *Config
+	// Diffs lists what changed, global settings first, then the services in
+	// config order followed by removed services.
+	Diffs []SectionDiff
+}
+
+// SectionDiff lists the changes of the global settings (Service is empty)
+// or of one service.
+type SectionDiff struct {
+	Service string
+	Changes []string
+}
+
+// String formats the diff for logs, e.g.
+// "svc web: +backend 10.1.1.5:80, scheduler rr→wrr".
+func (d SectionDiff) String() string {
+	if d.Service == "" {
+		return "global: " + strings.Join(d.Changes, ", ")
+	}
+	return fmt.Sprintf("svc %s: %s", d.Service, strings.Join(d.Changes, ", "))
+}
+
+// DiffConfigs returns what changed between two configs: added and removed
+// services, backends added, removed or changed by address, and any other
+// setting, by its YAML name. A nil old config counts as empty.
+func DiffConfigs(old, new *Config) []SectionDiff {
+	if old == nil {
+		old = &Config{}
+	}
+	var diffs []SectionDiff
+	if changes := fieldChanges("", reflect.ValueOf(old.Global), reflect.ValueOf(new.Global)); len(changes) > 0 {
+		diffs = append(diffs, SectionDiff{Changes: changes})
+	}
+
+	oldServices := make(map[string]ServiceConfig, len(old.Services))
+	for _, svc := range old.Services {
+		oldServices[svc.Name] = svc
+	}
+	seen := make(map[string]bool, len(new.Services))
+	for _, svc := range new.Services {
+		seen[svc.Name] = true
+		prev, ok := oldServices[svc.Name]
+		if !ok {
+			diffs = append(diffs, SectionDiff{Service: svc.Name, Changes: []string{"added"}})
+			continue
+		}
+		if changes := serviceChanges(prev, svc); len(changes) > 0 {
+			diffs = append(diffs, SectionDiff{Service: svc.Name, Changes: changes})
+		}
+	}
+	for _, svc := range old.Services {
+		if !seen[svc.Name] {
+			diffs = append(diffs, SectionDiff{Service: svc.Name, Changes: []string{"removed"}})
+		}
+	}
+	return diffs
+}
+
+// serviceChanges lists the changes of a service, its backend changes first.
+func serviceChanges(old, new ServiceConfig) []string {
+	var changes []string
+	oldBackends := make(map[string]BackendConfig, len(old.Backends))
+	for _, backend := range old.Backends {
+		oldBackends[backend.Address] = backend
+	}
+	newBackends := make(map[string]bool, len(new.Backends))
+	for _, backend := range new.Backends {
+		newBackends[backend.Address] = true
+		prev, ok := oldBackends[backend.Address]
+		if !ok {
+			changes = append(changes, "+backend "+backend.Address)
+			continue
+		}
+		changes = append(changes, fieldChanges("backend "+backend.Address+" ", reflect.ValueOf(prev), reflect.ValueOf(backend))...)
+	}
+	for _, backend := range old.Backends {
+		if !newBackends[backend.Address] {
+			changes = append(changes, "-backend "+backend.Address)
+		}
+	}
+
+	old.Backends, new.Backends = nil, nil
+	return append(changes, fieldChanges("", reflect.ValueOf(old), reflect.ValueOf(new))...)
+}
+
+// fieldChanges compares two structs field by field. Scalar fields are shown
+// as "name old→new", other fields as "name changed". A backend's address
+// identifies it, so an address field is not compared.
+func fieldChanges(prefix string, old, new reflect.Value) []string {
+	var changes []string
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || name == "address" {
+			continue
+		}
+		oldField, newField := old.Field(i), new.Field(i)
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+		oldText, oldScalar := scalarText(oldField)
+		newText, newScalar := scalarText(newField)
+		if oldScalar && newScalar {
+			changes = append(changes, fmt.Sprintf("%s%s %s→%s", prefix, name, oldText, newText))
+		} else {
+			changes = append(changes, prefix+name+" changed")
+		}
+	}
+	return changes
+}
+
+// scalarText formats a string, number or bool field, or a pointer to one,
+// for a diff; unset values are shown as "unset".
+func scalarText(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "unset", true
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() == "" {
+			return "unset", true
+		}
+		return v.String(), true
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), true
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	old := validConfig()
+	removed := validServiceConfig()
+	removed.Name = "old-svc"
+	old.Services = append(old.Services, removed)
+
+	new := validConfig()
+	new.Global.MinReconcileInterval = "2s"
+	new.Services[0].Scheduler = "wrr"
+	new.Services[0].HealthCheck.Interval = "10s"
+	new.Services[0].Backends = []BackendConfig{
+		{Address: "192.168.1.1:8080", Weight: intPtr(3)},
+		{Address: "10.1.1.5:80", Weight: intPtr(1)},
+	}
+	added := validServiceConfig()
+	added.Name = "new-svc"
+	new.Services = append(new.Services, added)
+
+	got := DiffConfigs(old, new)
+	want := []SectionDiff{
+		{Changes: []string{"min_reconcile_interval unset→2s"}},
+		{Service: "test-svc", Changes: []string{
+			"backend 192.168.1.1:8080 weight 1→3",
+			"+backend 10.1.1.5:80",
+			"scheduler rr→wrr",
+			"health_check changed",
+		}},
+		{Service: "new-svc", Changes: []string{"added"}},
+		{Service: "old-svc", Changes: []string{"removed"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected diff:\ngot:  %v\nwant: %v", got, want)
+	}
+	if s := got[1].String(); s != "svc test-svc: backend 192.168.1.1:8080 weight 1→3, +backend 10.1.1.5:80, scheduler rr→wrr, health_check changed" {
+		t.Errorf("unexpected string %q", s)
+	}
+	if s := got[0].String(); s != "global: min_reconcile_interval unset→2s" {
+		t.Errorf("unexpected string %q", s)
+	}
+
+	if diffs := DiffConfigs(old, old); len(diffs) != 0 {
+		t.Errorf("expected no diff of identical configs, got %v", diffs)
+	}
+}
+
+func TestManager_ReloadSendsDiff(t *testing.T) {
+	path := writeTestYAML(t, validYAML)
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		mgr.reload(path)
+	}
+	// Two reloads before the listener receives are reported as one change
+	write(strings.Replace(validYAML, "scheduler: wrr", "scheduler: rr", 1))
+	write(strings.Replace(validYAML, "scheduler: wrr", "scheduler: rr", 1) + "      - address: 192.168.1.12:8080\n        weight: 1\n")
+
+	change := <-mgr.OnChange()
+	if change.Generation != 3 || change.Old.Services[0].Scheduler != "wrr" || change.New != mgr.GetConfig() {
+		t.Fatalf("unexpected change: generation %d", change.Generation)
+	}
+	want := []SectionDiff{{Service: "web-service", Changes: []string{"+backend 192.168.1.12:8080", "scheduler wrr→rr"}}}
+	if !reflect.DeepEqual(change.Diffs, want) {
+		t.Errorf("unexpected diff:\ngot:  %v\nwant: %v", change.Diffs, want)
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+)
+
+// Lint checks.
+const (
+	LintVIPOverlap      = "vip_overlap"
+	LintBackendIsVIP    = "backend_is_vip"
+	LintWeightPrecision = "weight_precision"
+)
+
+// Problem is a semantic problem found in a config that Validate accepts.
+type Problem struct {
+	Check   string `json:"check"`
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the problem for display.
+func (p Problem) String() string {
+	if p.Service == "" {
+		return fmt.Sprintf("%s: %s", p.Check, p.Message)
+	}
+	return fmt.Sprintf("%s: service %q: %s", p.Check, p.Service, p.Message)
+}
+
+// Lint reports the problems of a validated config that are legal to
+// configure but cannot work: VIP:ports the admin or metrics server also
+// listens on, backends addressed by one of the director's own VIPs, which
+// IPVS would forward to itself, and weights too disproportionate to keep
+// their ratio when scaled to max_weight. Problems are sorted by check and
+// service.
+func Lint(cfg *Config) []Problem {
+	var problems []Problem
+
+	type listener struct {
+		option string
+		ip     net.IP
+		port   string
+	}
+	var listeners []listener
+	for _, l := range []struct{ option, address string }{
+		{"global.admin_address", cfg.Global.AdminAddress},
+		{"global.metrics_address", cfg.Global.MetricsAddress},
+	} {
+		// Unix sockets cannot overlap a VIP, and a missing host binds localhost
+		host, port, err := net.SplitHostPort(l.address)
+		if err != nil || host == "" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			listeners = append(listeners, listener{option: l.option + " " + l.address, ip: ip, port: port})
+		}
+	}
+
+	vips := make(map[string][]string) // VIP -> services listening on it
+	for _, svc := range cfg.Services {
+		addresses, _ := svc.ListenAddresses()
+		addresses = append(addresses, svc.MarkGroup...)
+		for _, address := range addresses {
+			host, port, err := net.SplitHostPort(address)
+			ip := net.ParseIP(host)
+			if err != nil || ip == nil {
+				continue
+			}
+			if owners := vips[ip.String()]; !slices.Contains(owners, svc.Name) {
+				vips[ip.String()] = append(owners, svc.Name)
+			}
+			for _, l := range listeners {
+				if port == l.port && (l.ip.IsUnspecified() || ip.Equal(l.ip)) {
+					problems = append(problems, Problem{
+						Check:   LintVIPOverlap,
+						Service: svc.Name,
+						Message: fmt.Sprintf("VIP %s overlaps %s", address, l.option),
+					})
+				}
+			}
+		}
+	}
+
+	for _, svc := range cfg.Services {
+		for j, backend := range svc.Backends {
+			host, _, err := net.SplitHostPort(backend.Address)
+			ip := net.ParseIP(host)
+			if err != nil || ip == nil {
+				continue
+			}
+			owners := vips[ip.String()]
+			if len(owners) == 0 {
+				continue
+			}
+			message := fmt.Sprintf("backend[%d]: address %q uses the service's own VIP %s", j, backend.Address, ip)
+			if !slices.Contains(owners, svc.Name) {
+				message = fmt.Sprintf("backend[%d]: address %q uses VIP %s of service %q on this director", j, backend.Address, ip, owners[0])
+			}
+			problems = append(problems, Problem{Check: LintBackendIsVIP, Service: svc.Name, Message: message})
+		}
+	}
+
+	for _, svc := range cfg.Services {
+		for _, weight := range NormalizeWeights(svc) {
+			if weight.Lossy {
+				problems = append(problems, Problem{
+					Check:   LintWeightPrecision,
+					Service: svc.Name,
+					Message: fmt.Sprintf("backend %q: weight %d is programmed as %d to fit max_weight %d, changing its share of traffic",
+						weight.Address, weight.Weight, weight.Scaled, svc.GetMaxWeight()),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Check != problems[j].Check {
+			return problems[i].Check < problems[j].Check
+		}
+		return problems[i].Service < problems[j].Service
+	})
+	return problems
+}
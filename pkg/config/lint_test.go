@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_ValidConfig(t *testing.T) {
+	if problems := Lint(validConfig()); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestLint_VIPOverlapsAdminAddress(t *testing.T) {
+	tests := []struct {
+		admin string
+		want  bool
+	}{
+		{"0.0.0.0:80", true},
+		{":80", false},
+		{"unix:/run/ezlb.sock", false},
+		{"10.0.0.1:80", true},
+		{"10.0.0.2:80", false},
+		{"0.0.0.0:8080", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Global.AdminAddress = tt.admin
+		problems := Lint(cfg)
+		if got := len(problems) == 1 && problems[0].Check == LintVIPOverlap; got != tt.want {
+			t.Errorf("admin_address %q: got problems %v, want overlap %v", tt.admin, problems, tt.want)
+		}
+	}
+}
+
+func TestLint_BackendIsVIP(t *testing.T) {
+	cfg := validConfig()
+	other := validServiceConfig()
+	other.Name = "other-svc"
+	other.Listen = "10.0.0.2:443"
+	other.Backends = []BackendConfig{{Address: "10.0.0.1:80", Weight: intPtr(1)}}
+	cfg.Services = append(cfg.Services, other)
+	cfg.Services[0].Backends = append(cfg.Services[0].Backends, BackendConfig{Address: "10.0.0.1:8080", Weight: intPtr(1)})
+
+	problems := Lint(cfg)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	if problems[0].Service != "other-svc" || !strings.Contains(problems[0].Message, `VIP 10.0.0.1 of service "test-svc"`) {
+		t.Errorf("unexpected problem for the other service's VIP: %v", problems[0])
+	}
+	if problems[1].Service != "test-svc" || !strings.Contains(problems[1].Message, "own VIP 10.0.0.1") {
+		t.Errorf("unexpected problem for the service's own VIP: %v", problems[1])
+	}
+	if problems[1].Check != LintBackendIsVIP {
+		t.Errorf("expected check %q, got %q", LintBackendIsVIP, problems[1].Check)
+	}
+}
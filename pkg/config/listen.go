@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// maxListenRange caps the number of ports a listen port range expands to.
+const maxListenRange = 1024
+
+// decodeHook extends viper's default decode hooks so that a YAML list given
+// for a string option, such as listen, is joined into a comma-separated list.
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	joinListHook,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+)
+
+func joinListHook(from, to reflect.Type, data any) (any, error) {
+	if to.Kind() != reflect.String || from.Kind() != reflect.Slice {
+		return data, nil
+	}
+	value := reflect.ValueOf(data)
+	items := make([]string, value.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(items, ","), nil
+}
+
+// ListenAddresses returns the VIP:port addresses a service listens on, those
+// of Listen followed by those of ListenV6. Each holds one address or a
+// comma-separated list (a YAML list is joined on load), and each address may
+// use a port range such as 10.0.0.1:8000-8010. Entries that are not valid
+// addresses are returned as-is for Validate to report.
+func (s ServiceConfig) ListenAddresses() ([]string, error) {
+	addresses, err := expandListen(s.Listen)
+	if err != nil {
+		return nil, err
+	}
+	v6Addresses, err := expandListen(s.ListenV6)
+	if err != nil {
+		return nil, err
+	}
+	return append(addresses, v6Addresses...), nil
+}
+
+// expandListen expands one listen option into its addresses.
+func expandListen(listen string) ([]string, error) {
+	if listen == "" {
+		return nil, nil
+	}
+	var addresses []string
+	for _, entry := range strings.Split(listen, ",") {
+		entry = strings.TrimSpace(entry)
+		host, ports, err := net.SplitHostPort(entry)
+		first, last, isRange := strings.Cut(ports, "-")
+		if err != nil || !isRange {
+			addresses = append(addresses, entry)
+			continue
+		}
+
+		start, startErr := strconv.Atoi(first)
+		end, endErr := strconv.Atoi(last)
+		if startErr != nil || endErr != nil || start < 1 || end > 65535 || start > end {
+			return nil, fmt.Errorf("invalid listen port range %q", entry)
+		}
+		if end-start+1 > maxListenRange {
+			return nil, fmt.Errorf("listen port range %q exceeds %d ports", entry, maxListenRange)
+		}
+		for port := start; port <= end; port++ {
+			addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(port)))
+		}
+	}
+	return addresses, nil
+}
+
+// IsDualStack reports whether the service listens on IPv6 VIPs from
+// ListenV6 in addition to the IPv4 VIPs of Listen.
+func (s ServiceConfig) IsDualStack() bool {
+	return s.ListenV6 != ""
+}
+
+// SplitDualStack returns a dual-stack service as its IPv4 half, listening on
+// Listen, and its IPv6 half, listening on ListenV6, each with the backends of
+// its address family. Backends addressed by hostname are kept in both halves.
+// Other services are returned as the only element.
+func SplitDualStack(svc ServiceConfig) []ServiceConfig {
+	if !svc.IsDualStack() {
+		return []ServiceConfig{svc}
+	}
+	v4, v6 := svc, svc
+	v4.ListenV6, v4.Backends = "", nil
+	v6.Listen, v6.ListenV6, v6.Backends = svc.ListenV6, "", nil
+	for _, backend := range svc.Backends {
+		ipv6, ok := backend.isIPv6()
+		if !ok || !ipv6 {
+			v4.Backends = append(v4.Backends, backend)
+		}
+		if !ok || ipv6 {
+			v6.Backends = append(v6.Backends, backend)
+		}
+	}
+	return []ServiceConfig{v4, v6}
+}
+
+// isIPv6 reports the address family of a backend addressed by IP; ok is
+// false for hostnames and invalid addresses.
+func (b BackendConfig) isIPv6() (ipv6, ok bool) {
+	host, _, err := net.SplitHostPort(b.Address)
+	ip := net.ParseIP(host)
+	if err != nil || ip == nil {
+		return false, false
+	}
+	return ip.To4() == nil, true
+}
+
+// Protocols returns the protocols a service listens on. Protocol holds one
+// protocol or several joined by "+", such as tcp+udp (a YAML list is joined
+// with commas on load, which is accepted as well); it defaults to tcp.
+func (s ServiceConfig) Protocols() []string {
+	if s.Protocol == "" {
+		return []string{"tcp"}
+	}
+	return strings.FieldsFunc(s.Protocol, func(r rune) bool {
+		return r == '+' || r == ',' || r == ' '
+	})
+}
+
+// ExpandListens returns the services with one listen address and protocol
+// each: a service listening on several addresses or protocols becomes one
+// copy per address and protocol, sharing its name and backends. A dual-stack
+// service is split first (see SplitDualStack), so each copy has only the
+// backends of its VIP's address family. Firewall-mark services and services
+// whose listen cannot be expanded are returned unchanged.
+func ExpandListens(services []ServiceConfig) []ServiceConfig {
+	var split []ServiceConfig
+	for _, svc := range services {
+		split = append(split, SplitDualStack(svc)...)
+	}
+	expanded := make([]ServiceConfig, 0, len(split))
+	for _, svc := range split {
+		addresses, err := svc.ListenAddresses()
+		if err != nil || len(addresses) == 0 {
+			expanded = append(expanded, svc)
+			continue
+		}
+		protocols := svc.Protocols()
+		for _, address := range addresses {
+			svc.Listen = address
+			if len(protocols) == 1 {
+				expanded = append(expanded, svc)
+				continue
+			}
+			for _, protocol := range protocols {
+				svc.Protocol = protocol
+				expanded = append(expanded, svc)
+			}
+		}
+	}
+	return expanded
+}
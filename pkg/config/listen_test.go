@@ -0,0 +1,241 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestServiceConfig_ListenAddresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		listen  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", listen: ""},
+		{name: "single", listen: "10.0.0.1:80", want: []string{"10.0.0.1:80"}},
+		{name: "list", listen: "10.0.0.1:80, 10.0.0.2:80", want: []string{"10.0.0.1:80", "10.0.0.2:80"}},
+		{name: "port range", listen: "10.0.0.1:8000-8002", want: []string{"10.0.0.1:8000", "10.0.0.1:8001", "10.0.0.1:8002"}},
+		{name: "ipv6 port range", listen: "[2001:db8::1]:80-81", want: []string{"[2001:db8::1]:80", "[2001:db8::1]:81"}},
+		{name: "range and address", listen: "10.0.0.1:80-81,10.0.0.2:80", want: []string{"10.0.0.1:80", "10.0.0.1:81", "10.0.0.2:80"}},
+		{name: "invalid address kept", listen: "not-an-address", want: []string{"not-an-address"}},
+		{name: "reversed range", listen: "10.0.0.1:90-80", wantErr: true},
+		{name: "range beyond 65535", listen: "10.0.0.1:65535-65536", wantErr: true},
+		{name: "range too large", listen: "10.0.0.1:1-2000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ServiceConfig{Listen: tt.listen}.ListenAddresses()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListenAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListenAddresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandListens(t *testing.T) {
+	services := []ServiceConfig{
+		{Name: "web", Listen: "10.0.0.1:80,10.0.0.2:80", Backends: []BackendConfig{{Address: "192.168.1.1:8080", Weight: intPtr(1)}}},
+		{Name: "dns", Listen: "10.0.0.3:53"},
+		{Name: "marked", FWMark: 1},
+	}
+	expanded := ExpandListens(services)
+	var listens []string
+	for _, svc := range expanded {
+		listens = append(listens, svc.Name+"="+svc.Listen)
+	}
+	want := []string{"web=10.0.0.1:80", "web=10.0.0.2:80", "dns=10.0.0.3:53", "marked="}
+	if !slices.Equal(listens, want) {
+		t.Fatalf("ExpandListens() = %v, want %v", listens, want)
+	}
+	if len(expanded[1].Backends) != 1 {
+		t.Error("expected every expansion to keep the service's backends")
+	}
+	if services[0].Listen != "10.0.0.1:80,10.0.0.2:80" {
+		t.Error("expected the input services to be left unchanged")
+	}
+}
+
+func TestExpandListens_Protocols(t *testing.T) {
+	services := []ServiceConfig{
+		{Name: "dns", Listen: "10.0.0.1:53,10.0.0.2:53", Protocol: "tcp+udp"},
+		{Name: "web", Listen: "10.0.0.3:80"},
+	}
+	var keys []string
+	for _, svc := range ExpandListens(services) {
+		keys = append(keys, svc.Name+"="+svc.Listen+"/"+svc.Protocol)
+	}
+	want := []string{"dns=10.0.0.1:53/tcp", "dns=10.0.0.1:53/udp", "dns=10.0.0.2:53/tcp", "dns=10.0.0.2:53/udp", "web=10.0.0.3:80/"}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("ExpandListens() = %v, want %v", keys, want)
+	}
+}
+
+func TestManager_LoadYAML_ProtocolList(t *testing.T) {
+	path := writeTestYAML(t, strings.Replace(validYAML, "    protocol: tcp\n", "    protocol: [tcp, udp]\n", 1))
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("expected a protocol list to load, got: %v", err)
+	}
+	if protocols := mgr.GetConfig().Services[0].Protocols(); !slices.Equal(protocols, []string{"tcp", "udp"}) {
+		t.Errorf("expected tcp and udp, got %v", protocols)
+	}
+}
+
+func TestValidate_MultipleListens(t *testing.T) {
+	tests := []struct {
+		name    string
+		listen  string
+		wantErr bool
+	}{
+		{name: "list", listen: "10.0.0.1:80,10.0.0.2:80"},
+		{name: "port range", listen: "10.0.0.1:8000-8010"},
+		{name: "duplicate within list", listen: "10.0.0.1:80,10.0.0.1:80", wantErr: true},
+		{name: "range overlapping address", listen: "10.0.0.1:80,10.0.0.1:79-81", wantErr: true},
+		{name: "mixed address families", listen: "10.0.0.1:80,[2001:db8::1]:80", wantErr: true},
+		{name: "invalid entry", listen: "10.0.0.1:80,bogus", wantErr: true},
+		{name: "trailing comma", listen: "10.0.0.1:80,", wantErr: true},
+		{name: "reversed range", listen: "10.0.0.1:90-80", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Listen = tt.listen
+			err := Validate(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ListenOverlapsOtherService(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Listen = "10.0.0.1:80-90"
+	other := validServiceConfig()
+	other.Name = "other"
+	other.Listen = "10.0.0.1:85"
+	cfg.Services = append(cfg.Services, other)
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "duplicate listen address") {
+		t.Fatalf("expected a duplicate listen address error, got: %v", err)
+	}
+}
+
+func TestManager_LoadYAML_ListenList(t *testing.T) {
+	path := writeTestYAML(t, strings.Replace(validYAML, "    listen: 10.0.0.1:80\n", "    listen: [\"10.0.0.1:80\", \"10.0.0.2:80\"]\n", 1))
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("expected a listen list to load, got: %v", err)
+	}
+	if listen := mgr.GetConfig().Services[0].Listen; listen != "10.0.0.1:80,10.0.0.2:80" {
+		t.Errorf("expected the listen list to be joined, got %q", listen)
+	}
+}
+
+func TestSplitDualStack(t *testing.T) {
+	svc := ServiceConfig{
+		Name:     "web",
+		Listen:   "10.0.0.1:80",
+		ListenV6: "[2001:db8::1]:80,[2001:db8::2]:80",
+		Backends: []BackendConfig{
+			{Address: "192.168.1.1:8080", Weight: intPtr(1)},
+			{Address: "[2001:db8::10]:8080", Weight: intPtr(2)},
+			{Address: "app.example.com:8080", Weight: intPtr(3)},
+		},
+	}
+	halves := SplitDualStack(svc)
+	if len(halves) != 2 {
+		t.Fatalf("expected two halves, got %d", len(halves))
+	}
+	backendsOf := func(svc ServiceConfig) []string {
+		var addresses []string
+		for _, backend := range svc.Backends {
+			addresses = append(addresses, backend.Address)
+		}
+		return addresses
+	}
+	if halves[0].Listen != "10.0.0.1:80" || halves[0].IsDualStack() {
+		t.Errorf("expected the IPv4 half to listen on 10.0.0.1:80 only, got %q / %q", halves[0].Listen, halves[0].ListenV6)
+	}
+	if want := []string{"192.168.1.1:8080", "app.example.com:8080"}; !slices.Equal(backendsOf(halves[0]), want) {
+		t.Errorf("expected IPv4 backends %v, got %v", want, backendsOf(halves[0]))
+	}
+	if halves[1].Listen != "[2001:db8::1]:80,[2001:db8::2]:80" || halves[1].IsDualStack() {
+		t.Errorf("expected the IPv6 half to listen on listen_v6, got %q / %q", halves[1].Listen, halves[1].ListenV6)
+	}
+	if want := []string{"[2001:db8::10]:8080", "app.example.com:8080"}; !slices.Equal(backendsOf(halves[1]), want) {
+		t.Errorf("expected IPv6 backends %v, got %v", want, backendsOf(halves[1]))
+	}
+
+	if got := len(ExpandListens([]ServiceConfig{svc})); got != 3 {
+		t.Errorf("expected three IPVS services from the dual-stack service, got %d", got)
+	}
+	if got := SplitDualStack(ServiceConfig{Listen: "10.0.0.1:80"}); len(got) != 1 {
+		t.Errorf("expected a single-stack service to stay whole, got %d halves", len(got))
+	}
+}
+
+func TestValidate_DualStack(t *testing.T) {
+	tests := []struct {
+		name     string
+		listen   string
+		listenV6 string
+		backends []string
+		wantErr  string
+	}{
+		{name: "dual stack", listen: "10.0.0.1:80", listenV6: "[2001:db8::1]:80", backends: []string{"192.168.1.1:8080", "[2001:db8::10]:8080"}},
+		{name: "hostname backend covers both", listen: "10.0.0.1:80", listenV6: "[2001:db8::1]:80", backends: []string{"app.example.com:8080"}},
+		{name: "listen_v6 without listen", listenV6: "[2001:db8::1]:80", backends: []string{"[2001:db8::10]:8080"}, wantErr: "listen is required"},
+		{name: "ipv6 listen", listen: "[2001:db8::2]:80", listenV6: "[2001:db8::1]:80", backends: []string{"[2001:db8::10]:8080"}, wantErr: "must be IPv4 when listen_v6 is set"},
+		{name: "ipv4 listen_v6", listen: "10.0.0.1:80", listenV6: "10.0.0.2:80", backends: []string{"192.168.1.1:8080"}, wantErr: "listen_v6 address \"10.0.0.2:80\" must be IPv6"},
+		{name: "no ipv6 backend", listen: "10.0.0.1:80", listenV6: "[2001:db8::1]:80", backends: []string{"192.168.1.1:8080"}, wantErr: "listen_v6 has no IPv6 backend"},
+		{name: "no ipv4 backend", listen: "10.0.0.1:80", listenV6: "[2001:db8::1]:80", backends: []string{"[2001:db8::10]:8080"}, wantErr: "listen has no IPv4 backend"},
+		{name: "single stack family mismatch", listen: "10.0.0.1:80", backends: []string{"[2001:db8::10]:8080"}, wantErr: "not in the address family of the listen address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Services[0].Listen = tt.listen
+			cfg.Services[0].ListenV6 = tt.listenV6
+			cfg.Services[0].Backends = nil
+			for _, address := range tt.backends {
+				cfg.Services[0].Backends = append(cfg.Services[0].Backends, BackendConfig{Address: address, Weight: intPtr(1)})
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected a valid config, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_TunnelBackendCrossesFamilies(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Backends = []BackendConfig{{Address: "[2001:db8::10]:8080", Weight: intPtr(1), ForwardMethod: "tunnel"}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected a tunnel backend of the other family to be valid, got: %v", err)
+	}
+}
+
+func TestValidate_DualStackDSCP(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].ListenV6 = "[2001:db8::1]:80"
+	cfg.Services[0].Backends = append(cfg.Services[0].Backends, BackendConfig{Address: "[2001:db8::10]:8080", Weight: intPtr(1)})
+	dscp := 46
+	cfg.Services[0].DSCP = &dscp
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "dscp is not supported for IPv6") {
+		t.Fatalf("expected dscp to be rejected on a dual-stack service, got: %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Normalized returns a copy of the config with the defaults that ezlb applies
+// filled in explicitly, so that the config states everything it configures.
+// Settings that only matter with an option enabled, such as syslog details or
+// HTTP health check parameters, are filled in only when it is. The config is
+// expected to have been validated, which fills in inherited health check
+// settings and backend forwarding methods.
+func (c *Config) Normalized() *Config {
+	normalized := &Config{Global: c.Global.normalized()}
+	for _, svc := range c.Services {
+		normalized.Services = append(normalized.Services, svc.normalized())
+	}
+	return normalized
+}
+
+func (g GlobalConfig) normalized() GlobalConfig {
+	g.CleanupOnExit = boolPtr(g.IsCleanupOnExit())
+	g.CleanupOnPanic = boolPtr(g.IsCleanupOnPanic())
+	g.MetricsEnabled = boolPtr(g.IsMetricsEnabled())
+	g.TunnelSetup = boolPtr(g.IsTunnelSetup())
+	g.CheckHostListeners = boolPtr(g.IsCheckHostListeners())
+	g.Prune = boolPtr(g.IsPrune())
+	g.ReadOnly = boolPtr(g.IsReadOnly())
+	g.MetricsPath = g.GetMetricsPath()
+	g.StateFile = g.GetStateFile()
+	g.MinReconcileInterval = formatDuration(g.GetMinReconcileInterval())
+	g.ResyncInterval = formatDuration(g.GetResyncInterval())
+	g.DriftCheckInterval = formatDuration(g.GetDriftCheckInterval())
+
+	g.Log.Level = g.Log.GetLevel()
+	g.Log.Home = g.Log.GetHome()
+	g.Log.MaxSize = g.Log.GetMaxSize()
+	g.Log.MaxBackups = g.Log.GetMaxBackups()
+	g.Log.Traffic.Enabled = boolPtr(g.Log.Traffic.IsEnabled())
+	g.Log.Traffic.Interval = formatDuration(g.Log.Traffic.GetInterval())
+	g.Log.Traffic.Window = formatDuration(g.Log.Traffic.GetWindow())
+	g.Log.Syslog.Enabled = boolPtr(g.Log.Syslog.IsEnabled())
+	if g.Log.Syslog.IsEnabled() {
+		g.Log.Syslog.Network = g.Log.Syslog.GetNetwork()
+		g.Log.Syslog.Address = g.Log.Syslog.GetAddress()
+		g.Log.Syslog.Facility = g.Log.Syslog.GetFacility()
+		g.Log.Syslog.AppName = g.Log.Syslog.GetAppName()
+	}
+
+	g.SelfMonitor.Enabled = boolPtr(g.SelfMonitor.IsEnabled())
+	g.SelfMonitor.Interval = formatDuration(g.SelfMonitor.GetInterval())
+	g.HA.Peers = slices.Clone(g.HA.Peers)
+	g.HA.Timeout = formatDuration(g.HA.GetTimeout())
+	g.Drain.Enabled = boolPtr(g.Drain.IsEnabled())
+	g.Drain.Remove = boolPtr(g.Drain.IsRemove())
+	g.Drain.Interval = formatDuration(g.Drain.GetInterval())
+	g.Drain.Timeout = formatDuration(g.Drain.GetTimeout())
+	g.DNS.Interval = formatDuration(g.DNS.GetInterval())
+	g.RemoteConfig.Interval = formatDuration(g.RemoteConfig.GetInterval())
+	if g.Lock.IsEnabled() {
+		g.Lock.TTL = formatDuration(g.Lock.GetTTL())
+		g.Lock.Wait = formatDuration(g.Lock.GetWait())
+	}
+	if g.Snapshot.IsEnabled() {
+		g.Snapshot.Interval = formatDuration(g.Snapshot.GetInterval())
+		g.Snapshot.Retention = formatDuration(g.Snapshot.GetRetention())
+	}
+	g.HealthCheck = g.HealthCheck.normalized()
+	if g.FeatureGates != nil {
+		gates := make(map[string]bool, len(g.FeatureGates))
+		for name, enabled := range g.FeatureGates {
+			gates[name] = enabled
+		}
+		g.FeatureGates = gates
+	}
+	return g
+}
+
+func (s ServiceConfig) normalized() ServiceConfig {
+	s.Enabled = boolPtr(s.IsEnabled())
+	s.Protocol = strings.Join(s.Protocols(), "+")
+	s.HealthCheck = s.HealthCheck.normalized()
+	if s.Persistence.IsEnabled() {
+		s.Persistence.Timeout = formatDuration(s.Persistence.GetTimeout())
+	}
+	if s.PreStop.IsEnabled() {
+		s.PreStop.Timeout = formatDuration(s.PreStop.GetTimeout())
+	}
+	s.PreStop.Command = slices.Clone(s.PreStop.Command)
+	if s.Synthetic.IsEnabled() {
+		s.Synthetic.Interval = formatDuration(s.Synthetic.GetInterval())
+		s.Synthetic.Timeout = formatDuration(s.Synthetic.GetTimeout())
+		if s.Synthetic.HTTPPath != "" {
+			s.Synthetic.HTTPExpectedStatus = s.Synthetic.GetHTTPExpectedStatus()
+		}
+	}
+	s.MarkGroup = slices.Clone(s.MarkGroup)
+	s.MaxWeight = s.GetMaxWeight()
+	// Validation expanded the groups into the backends
+	s.BackendGroups = nil
+
+	backends := make([]BackendConfig, len(s.Backends))
+	for i, backend := range s.Backends {
+		backend.ForwardMethod = backend.GetForwardMethod()
+		backend.Weight = intPtr(backend.GetWeight())
+		if backend.Role == "" {
+			backend.Role = RolePrimary
+		}
+		backends[i] = backend
+	}
+	s.Backends = backends
+	return s
+}
+
+func (h HealthCheckConfig) normalized() HealthCheckConfig {
+	h.Enabled = boolPtr(h.IsEnabled())
+	h.Type = h.GetType()
+	h.Interval = formatDuration(h.GetInterval())
+	h.Timeout = formatDuration(h.GetTimeout())
+	h.FailCount = h.GetFailCount()
+	h.RiseCount = h.GetRiseCount()
+	if h.Type == "http" || h.Type == "https" {
+		h.HTTPPath = h.GetHTTPPath()
+		h.HTTPMethod = h.GetHTTPMethod()
+		h.HTTPExpectedStatus = h.GetHTTPExpectedStatus()
+		h.HTTPKeepAlive = boolPtr(h.IsHTTPKeepAlive())
+		h.HTTPFollowRedirects = boolPtr(h.IsHTTPFollowRedirects())
+		h.HTTPRetryAfterDegraded = boolPtr(h.IsHTTPRetryAfterDegraded())
+		h.HTTP5xxDegraded = boolPtr(h.IsHTTP5xxDegraded())
+		h.HTTPExpectBodyRegex = boolPtr(h.IsHTTPExpectBodyRegex())
+	}
+	if h.Type == "dns" {
+		h.DNSQueryType = h.GetDNSQueryType()
+		h.DNSExpectedRcode = h.GetDNSExpectedRcode()
+		h.DNSProtocol = h.GetDNSProtocol()
+	}
+	if h.Type == "https" {
+		h.TLSVerify = boolPtr(h.IsTLSVerify())
+		h.CertExpiryWarnDays = h.GetCertExpiryWarnDays()
+	}
+	return h
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// formatDuration formats d like time.Duration.String without trailing zero
+// units, e.g. 1m instead of 1m0s.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// MarshalYAML implements yaml.Marshaler. The config is emitted as normalized
+// (see Normalized), global first, with fields in declaration order, map keys
+// sorted and unset fields left out, so the same config always yields the same
+// document and the document loads back into the same config.
+func (c *Config) MarshalYAML() (any, error) {
+	normalized := c.Normalized()
+	global, err := encodeNode(reflect.ValueOf(normalized.Global))
+	if err != nil {
+		return nil, err
+	}
+	services, err := encodeNode(reflect.ValueOf(normalized.Services))
+	if err != nil {
+		return nil, err
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "global"}, global,
+		{Kind: yaml.ScalarNode, Value: "services"}, services,
+	}}, nil
+}
+
+// Marshal returns the normalized YAML document of the config.
+func Marshal(cfg *Config) ([]byte, error) {
+	return encodeYAML(cfg)
+}
+
+// encodeNode encodes v as a YAML node, keying struct fields by their yaml
+// tags and leaving out zero fields. A nil pointer is unset, while a pointer
+// to false is kept, as it overrides a default.
+func encodeNode(v reflect.Value) (*yaml.Node, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		return encodeNode(v.Elem())
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			value := v.Field(i)
+			if name == "" || name == "-" || isUnset(value) {
+				continue
+			}
+			child, err := encodeNode(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, child)
+		}
+		return node, nil
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for i := range v.Len() {
+			child, err := encodeNode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case reflect.Map:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, key := range keys {
+			child, err := encodeNode(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(key.Interface())}, child)
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(v.Interface()); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+// isUnset reports whether a field is left out of the YAML document: nil
+// pointers, empty slices and maps, and zero values of other kinds.
+func isUnset(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer:
+		return v.IsNil()
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if !isUnset(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// roundTrip marshals the config loaded from path, loads the result back and
+// returns both documents and configs.
+func roundTrip(t *testing.T, path string) (first, second []byte, loaded *Config) {
+	t.Helper()
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	first, err = Marshal(mgr.GetConfig())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	reloaded, err := NewManager(writeTestYAML(t, string(first)), zap.NewNop())
+	if err != nil {
+		t.Fatalf("marshalled config does not load: %v\n%s", err, first)
+	}
+	loaded = reloaded.GetConfig()
+	second, err = Marshal(loaded)
+	if err != nil {
+		t.Fatalf("Marshal of the reloaded config failed: %v", err)
+	}
+	return first, second, loaded
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	path := writeTestYAML(t, validYAML)
+	first, second, loaded := roundTrip(t, path)
+	if string(first) != string(second) {
+		t.Errorf("expected marshalling to be stable, got:\n%s\nthen:\n%s", first, second)
+	}
+
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if want := mgr.GetConfig().Normalized(); !reflect.DeepEqual(loaded.Normalized(), want) {
+		t.Errorf("expected the reloaded config to match the original\ngot:  %+v\nwant: %+v", loaded.Normalized(), want)
+	}
+}
+
+func TestMarshal_Example(t *testing.T) {
+	if _, err := os.Stat("../../examples/ezlb.yaml"); err != nil {
+		t.Skipf("example config not found: %v", err)
+	}
+	first, second, _ := roundTrip(t, "../../examples/ezlb.yaml")
+	if string(first) != string(second) {
+		t.Errorf("expected marshalling to be stable, got:\n%s\nthen:\n%s", first, second)
+	}
+}
+
+func TestMarshal_FillsDefaults(t *testing.T) {
+	out, err := Marshal(validConfig())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	doc := string(out)
+	for _, want := range []string{
+		"cleanup_on_exit: true",
+		"metrics_path: /metrics",
+		"protocol: tcp",
+		"forward_method: nat",
+		"role: primary",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("expected %q in the marshalled config:\n%s", want, doc)
+		}
+	}
+	if !strings.HasPrefix(doc, "global:\n") {
+		t.Errorf("expected global to come first:\n%s", doc)
+	}
+}
+
+func TestMarshal_KeepsExplicitFalse(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].HealthCheck.Enabled = boolPtr(false)
+	out, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(out), "enabled: false") {
+		t.Errorf("expected the disabled health check to be kept:\n%s", out)
+	}
+}
+
+func TestMarshal_SortsMapKeys(t *testing.T) {
+	cfg := validConfig()
+	cfg.Global.FeatureGates = map[string]bool{"zeta": true, "alpha": false, "mid": true}
+	for range 5 {
+		out, err := Marshal(cfg)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		doc := string(out)
+		alpha, mid, zeta := strings.Index(doc, "alpha:"), strings.Index(doc, "mid:"), strings.Index(doc, "zeta:")
+		if alpha < 0 || !(alpha < mid && mid < zeta) {
+			t.Fatalf("expected sorted feature gates:\n%s", doc)
+		}
+	}
+}
+
+func TestNormalized_DoesNotModifyConfig(t *testing.T) {
+	cfg := validConfig()
+	before := *cfg
+	before.Services = append([]ServiceConfig(nil), cfg.Services...)
+	cfg.Normalized()
+	if !reflect.DeepEqual(*cfg, before) {
+		t.Errorf("expected Normalized to leave the config unchanged")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{5 * time.Second, "5s"},
+		{time.Minute, "1m"},
+		{90 * time.Second, "1m30s"},
+		{time.Hour, "1h"},
+		{time.Hour + time.Second, "1h0m1s"},
+		{500 * time.Millisecond, "500ms"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
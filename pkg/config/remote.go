@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// maxRemoteConfigSize bounds the size of a config fetched from a URL.
+const maxRemoteConfigSize = 16 << 20
+
+var remoteHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// isRemote reports whether path is a URL to fetch the config from instead of
+// a local file or directory: an http:// or https:// URL serving the YAML, or
+// etcd://host:port/key (etcds:// for TLS) naming an etcd key that holds it.
+func isRemote(path string) bool {
+	for _, scheme := range []string{"http://", "https://", "etcd://", "etcds://"} {
+		if strings.HasPrefix(path, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchRemote returns the config document at a remote config URL. HTTP URLs
+// may carry basic auth credentials; etcd keys are read through the v3 JSON
+// gateway.
+func fetchRemote(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote config URL: %w", err)
+	}
+
+	var req *http.Request
+	switch u.Scheme {
+	case "http", "https":
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	case "etcd", "etcds":
+		endpoint := &url.URL{Scheme: "http", Host: u.Host, Path: "/v3/kv/range"}
+		if u.Scheme == "etcds" {
+			endpoint.Scheme = "https"
+		}
+		body, _ := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(u.Path))})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported remote config scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := remoteHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote config: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote config: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch remote config: %s returned %s", u.Redacted(), resp.Status)
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return data, nil
+	}
+
+	var result struct {
+		KVs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+	if len(result.KVs) == 0 {
+		return nil, fmt.Errorf("failed to fetch remote config: etcd key %q not found", u.Path)
+	}
+	value, err := base64.StdEncoding.DecodeString(result.KVs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode etcd value: %w", err)
+	}
+	return value, nil
+}
+
+// readRemoteInto reads a fetched YAML config document into v.
+func readRemoteInto(v *viper.Viper, data []byte) error {
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to read remote config: %w", err)
+	}
+	return nil
+}
+
+// watchRemote polls the remote config every global.remote_config.interval
+// and reloads it when the fetched document changes. A failed fetch keeps
+// the current config.
+func (m *Manager) watchRemote() {
+	go func() {
+		for {
+			time.Sleep(m.GetConfig().Global.RemoteConfig.GetInterval())
+			m.pollRemote()
+		}
+	}()
+}
+
+// pollRemote fetches the remote config once and reloads it if it changed.
+func (m *Manager) pollRemote() {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteHTTPClient.Timeout)
+	defer cancel()
+	data, err := fetchRemote(ctx, m.configPath)
+	if err != nil {
+		m.logger.Warn("failed to poll remote config, keeping current config", zap.Error(err))
+		return
+	}
+
+	m.mu.RLock()
+	unchanged := bytes.Equal(data, m.remoteData)
+	m.mu.RUnlock()
+	if !unchanged {
+		m.reload(m.redactedPath())
+	}
+}
+
+// redactedPath returns the config path with any URL password masked, for logs.
+func (m *Manager) redactedPath() string {
+	if u, err := url.Parse(m.configPath); err == nil && m.remote {
+		return u.Redacted()
+	}
+	return m.configPath
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// remoteConfigServer serves a config document that tests can replace.
+type remoteConfigServer struct {
+	mu   sync.Mutex
+	data string
+}
+
+func (s *remoteConfigServer) set(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+}
+
+func (s *remoteConfigServer) get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data
+}
+
+func TestIsRemote(t *testing.T) {
+	tests := map[string]bool{
+		"http://config.example.com/ezlb.yaml":  true,
+		"https://config.example.com/ezlb.yaml": true,
+		"etcd://10.0.0.5:2379/ezlb/config":     true,
+		"etcds://10.0.0.5:2379/ezlb/config":    true,
+		"/etc/ezlb/ezlb.yaml":                  false,
+		"conf.d":                               false,
+	}
+	for path, want := range tests {
+		if got := isRemote(path); got != want {
+			t.Errorf("isRemote(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestManager_RemoteHTTP(t *testing.T) {
+	source := &remoteConfigServer{data: validYAML}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(source.get()))
+	}))
+	defer ts.Close()
+
+	mgr, err := NewManager(ts.URL+"/ezlb.yaml", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if cfg := mgr.GetConfig(); len(cfg.Services) != 1 || cfg.Services[0].Name != "web-service" {
+		t.Fatalf("unexpected remote config: %+v", cfg.Services)
+	}
+
+	// An unchanged document is not reloaded
+	mgr.pollRemote()
+	if mgr.Generation() != 1 {
+		t.Fatalf("expected no reload of an unchanged config, got generation %d", mgr.Generation())
+	}
+
+	source.set(strings.Replace(validYAML, "web-service", "api-service", 1))
+	mgr.pollRemote()
+	if mgr.Generation() != 2 || mgr.GetConfig().Services[0].Name != "api-service" {
+		t.Fatalf("expected the changed config to be reloaded, got generation %d", mgr.Generation())
+	}
+	select {
+	case <-mgr.OnChange():
+	default:
+		t.Error("expected a change notification")
+	}
+
+	// An invalid document keeps the current config
+	source.set("{{{invalid yaml")
+	mgr.pollRemote()
+	if mgr.Generation() != 2 || mgr.GetConfig().Services[0].Name != "api-service" {
+		t.Errorf("expected an invalid config to be ignored, got generation %d", mgr.Generation())
+	}
+}
+
+func TestManager_RemoteHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	if _, err := NewManager(ts.URL+"/ezlb.yaml", zap.NewNop()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestManager_RemoteEtcd(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/kv/range" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Key string `json:"key"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		key, _ := base64.StdEncoding.DecodeString(req.Key)
+		if string(key) != "/ezlb/config" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		value := base64.StdEncoding.EncodeToString([]byte(validYAML))
+		w.Write([]byte(`{"kvs":[{"value":"` + value + `"}]}`))
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	mgr, err := NewManager("etcd://"+host+"/ezlb/config", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if cfg := mgr.GetConfig(); len(cfg.Services) != 1 || cfg.Services[0].Name != "web-service" {
+		t.Fatalf("unexpected remote config: %+v", cfg.Services)
+	}
+
+	if _, err := NewManager("etcd://"+host+"/missing", zap.NewNop()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected a missing key error, got %v", err)
+	}
+}
+
+func TestValidate_RemoteConfigInterval(t *testing.T) {
+	cfg := validConfig()
+	cfg.Global.RemoteConfig.Interval = "500ms"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "global.remote_config.interval") {
+		t.Fatalf("expected a minimum interval error, got %v", err)
+	}
+	cfg.Global.RemoteConfig.Interval = "10s"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected a valid interval, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"maps"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// watchLocal reloads a config file, or the config files of a directory,
+// whenever they change. Besides the watched directory it watches the
+// directories the config files' symlinks resolve to, and re-resolves them on
+// every event, so that a Kubernetes ConfigMap update, which atomically swaps
+// the ..data symlink to a new directory, or an editor replacing the file by a
+// rename, is followed for as long as the process runs.
+func (m *Manager) watchLocal() {
+	dir := m.configPath
+	if !m.dir {
+		dir = filepath.Dir(m.configPath)
+	}
+	dir = filepath.Clean(dir)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		m.logger.Error("failed to watch config", zap.String("path", m.configPath), zap.Error(err))
+		return
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		m.logger.Error("failed to watch config", zap.String("path", m.configPath), zap.Error(err))
+		return
+	}
+
+	targets := m.watchTargets()
+	targetDirs := rearm(watcher, dir, nil, targets, m.logger)
+
+	go func() {
+		defer watcher.Close()
+		var pending *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				name := filepath.Clean(event.Name)
+				relevant := m.dir && isConfigFile(name)
+				for file, target := range targets {
+					if name == file || name == target {
+						relevant = true
+					}
+				}
+				if current := m.watchTargets(); !maps.Equal(current, targets) {
+					targets = current
+					targetDirs = rearm(watcher, dir, targetDirs, targets, m.logger)
+					relevant = true
+				}
+				if !relevant {
+					continue
+				}
+				if pending != nil {
+					pending.Stop()
+				}
+				pending = time.AfterFunc(dirReloadDelay, func() { m.reload(name) })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				m.logger.Error("config watch error", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// watchTargets maps each config file to the file its symlinks resolve to; a
+// file that does not resolve, e.g. while it is being replaced, maps to "".
+func (m *Manager) watchTargets() map[string]string {
+	files := []string{filepath.Clean(m.configPath)}
+	if m.dir {
+		files, _ = configDirFiles(m.configPath)
+	}
+	targets := make(map[string]string, len(files))
+	for _, file := range files {
+		target, err := filepath.EvalSymlinks(file)
+		if err != nil {
+			target = ""
+		}
+		targets[file] = target
+	}
+	return targets
+}
+
+// rearm watches the directories of the symlink targets outside dir and
+// stops watching those no longer used. It returns the directories watched.
+func rearm(watcher *fsnotify.Watcher, dir string, watched map[string]bool, targets map[string]string, logger *zap.Logger) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, target := range targets {
+		if target != "" && filepath.Dir(target) != dir {
+			dirs[filepath.Dir(target)] = true
+		}
+	}
+	for d := range watched {
+		if !dirs[d] {
+			// The directory may already be gone, which removed its watch
+			_ = watcher.Remove(d)
+		}
+	}
+	for d := range dirs {
+		if watched[d] {
+			continue
+		}
+		if err := watcher.Add(d); err != nil {
+			logger.Warn("failed to watch config symlink target", zap.String("dir", d), zap.Error(err))
+			delete(dirs, d)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// configMapMount lays out files the way the kubelet mounts a ConfigMap: the
+// files live in a timestamped directory, ..data links to it and each file is
+// a symlink through ..data.
+type configMapMount struct {
+	t       *testing.T
+	dir     string
+	version int
+}
+
+func newConfigMapMount(t *testing.T, files map[string]string) *configMapMount {
+	m := &configMapMount{t: t, dir: t.TempDir()}
+	m.update(files)
+	for name := range files {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(m.dir, name)); err != nil {
+			t.Fatalf("failed to link %s: %v", name, err)
+		}
+	}
+	return m
+}
+
+// update writes files to a new version directory, atomically swaps ..data to
+// it and removes the previous version, like a ConfigMap update.
+func (m *configMapMount) update(files map[string]string) {
+	m.t.Helper()
+	m.version++
+	version := fmt.Sprintf("..2026_10_18_00_00_%02d", m.version)
+	if err := os.Mkdir(filepath.Join(m.dir, version), 0755); err != nil {
+		m.t.Fatalf("failed to create %s: %v", version, err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(m.dir, version, name), []byte(content), 0644); err != nil {
+			m.t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Symlink(version, filepath.Join(m.dir, "..data_tmp")); err != nil {
+		m.t.Fatalf("failed to link ..data_tmp: %v", err)
+	}
+	if err := os.Rename(filepath.Join(m.dir, "..data_tmp"), filepath.Join(m.dir, "..data")); err != nil {
+		m.t.Fatalf("failed to swap ..data: %v", err)
+	}
+	if m.version > 1 {
+		previous := fmt.Sprintf("..2026_10_18_00_00_%02d", m.version-1)
+		if err := os.RemoveAll(filepath.Join(m.dir, previous)); err != nil {
+			m.t.Fatalf("failed to remove %s: %v", previous, err)
+		}
+	}
+}
+
+// waitForScheduler waits for a reload that sets the first service's
+// scheduler.
+func waitForScheduler(t *testing.T, mgr *Manager, scheduler string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case change := <-mgr.OnChange():
+			if change.New.Services[0].Scheduler == scheduler {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expected a reload setting scheduler %s, got %s", scheduler, mgr.GetConfig().Services[0].Scheduler)
+		}
+	}
+}
+
+func TestManager_WatchConfigMapFile(t *testing.T) {
+	mount := newConfigMapMount(t, map[string]string{"ezlb.yaml": validYAML})
+	mgr, err := NewManager(filepath.Join(mount.dir, "ezlb.yaml"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	mgr.WatchConfig()
+
+	// The second update checks the watch followed the first swap
+	for _, scheduler := range []string{"rr", "lc"} {
+		mount.update(map[string]string{"ezlb.yaml": strings.Replace(validYAML, "scheduler: wrr", "scheduler: "+scheduler, 1)})
+		waitForScheduler(t, mgr, scheduler)
+	}
+}
+
+func TestManager_WatchConfigMapDirectory(t *testing.T) {
+	mount := newConfigMapMount(t, map[string]string{
+		"00-global.yaml": confdirGlobalYAML,
+		"10-web.yaml":    confdirWebYAML,
+	})
+	mgr, err := NewManager(mount.dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if got := len(mgr.GetConfig().Services); got != 1 {
+		t.Fatalf("expected the symlinked files to be loaded, got %d services", got)
+	}
+	mgr.WatchConfig()
+
+	for _, scheduler := range []string{"lc", "sh"} {
+		mount.update(map[string]string{
+			"00-global.yaml": confdirGlobalYAML,
+			"10-web.yaml":    strings.Replace(confdirWebYAML, "scheduler: wrr", "scheduler: "+scheduler, 1),
+		})
+		waitForScheduler(t, mgr, scheduler)
+	}
+}
+
+func TestManager_WatchFileReplacedByRename(t *testing.T) {
+	path := writeTestYAML(t, validYAML)
+	mgr, err := NewManager(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	mgr.WatchConfig()
+
+	for _, scheduler := range []string{"rr", "lc"} {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(strings.Replace(validYAML, "scheduler: wrr", "scheduler: "+scheduler, 1)), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatalf("failed to replace config: %v", err)
+		}
+		waitForScheduler(t, mgr, scheduler)
+	}
+}
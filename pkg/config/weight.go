@@ -0,0 +1,66 @@
+package config
+
+import (
+	"math"
+)
+
+// MaxIPVSWeight is the largest destination weight the kernel accepts; IPVS
+// keeps weights in a signed 32-bit integer.
+const MaxIPVSWeight = math.MaxInt32
+
+// DefaultMaxWeight is the largest weight programmed for a service that sets
+// no max_weight. It is the limit ipvsadm enforces, keeping the weights that
+// ezlb programs manageable with the usual tools.
+const DefaultMaxWeight = 65535
+
+// weightPrecisionTolerance is the relative error of a scaled weight above
+// which normalization counts as losing precision.
+const weightPrecisionTolerance = 0.01
+
+// GetMaxWeight returns the largest weight programmed for the service's
+// backends. Defaults to DefaultMaxWeight if not set.
+func (s ServiceConfig) GetMaxWeight() int {
+	if s.MaxWeight <= 0 {
+		return DefaultMaxWeight
+	}
+	return s.MaxWeight
+}
+
+// ScaledWeight is a backend weight scaled down by NormalizeWeights.
+type ScaledWeight struct {
+	Address string
+	Weight  int
+	Scaled  int
+	// Lossy is set when rounding changed the weight's share of the service by
+	// more than 1%, e.g. 1 scaled up to 1 next to 100000 scaled to 65535.
+	Lossy bool
+}
+
+// NormalizeWeights scales the backend weights of a service proportionally so
+// that the largest fits max_weight, keeping their ratios as closely as integer
+// weights allow. A positive weight never drops below 1, so every backend
+// keeps receiving traffic. It returns the backends with their scaled weights,
+// or nil when every weight already fits.
+func NormalizeWeights(svc ServiceConfig) []ScaledWeight {
+	limit := svc.GetMaxWeight()
+	largest := 0
+	for _, backend := range svc.Backends {
+		largest = max(largest, backend.GetWeight())
+	}
+	if largest <= limit {
+		return nil
+	}
+
+	factor := float64(limit) / float64(largest)
+	scaled := make([]ScaledWeight, 0, len(svc.Backends))
+	for _, backend := range svc.Backends {
+		weight := ScaledWeight{Address: backend.Address, Weight: backend.GetWeight()}
+		if backend.GetWeight() > 0 {
+			exact := float64(backend.GetWeight()) * factor
+			weight.Scaled = max(1, int(math.Round(exact)))
+			weight.Lossy = math.Abs(float64(weight.Scaled)-exact)/exact > weightPrecisionTolerance
+		}
+		scaled = append(scaled, weight)
+	}
+	return scaled
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWeights(t *testing.T) {
+	svc := validServiceConfig()
+	svc.Backends = []BackendConfig{
+		{Address: "192.168.1.1:8080", Weight: intPtr(1)},
+		{Address: "192.168.1.2:8080", Weight: intPtr(100000)},
+		{Address: "192.168.1.3:8080", Weight: intPtr(0)},
+		{Address: "192.168.1.4:8080", Weight: intPtr(40000)},
+	}
+	want := []ScaledWeight{
+		{Address: "192.168.1.1:8080", Weight: 1, Scaled: 1, Lossy: true},
+		{Address: "192.168.1.2:8080", Weight: 100000, Scaled: 65535},
+		{Address: "192.168.1.3:8080", Weight: 0, Scaled: 0},
+		{Address: "192.168.1.4:8080", Weight: 40000, Scaled: 26214},
+	}
+	if got := NormalizeWeights(svc); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected scaled weights:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	svc.MaxWeight = 200000
+	if got := NormalizeWeights(svc); got != nil {
+		t.Errorf("expected weights within max_weight to be kept, got %+v", got)
+	}
+}
+
+func TestValidate_Weights(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*ServiceConfig)
+		wantErr string
+	}{
+		{"kernel max weight", func(s *ServiceConfig) { s.Backends[0].Weight = intPtr(MaxIPVSWeight) }, ""},
+		{"above kernel max weight", func(s *ServiceConfig) { s.Backends[0].Weight = intPtr(MaxIPVSWeight + 1) }, "weight must not exceed"},
+		{"max_weight", func(s *ServiceConfig) { s.MaxWeight = 100 }, ""},
+		{"unset max_weight", func(s *ServiceConfig) { s.MaxWeight = 0 }, ""},
+		{"negative max_weight", func(s *ServiceConfig) { s.MaxWeight = -1 }, "or 0 for the default 65535"},
+		{"max_weight above kernel max", func(s *ServiceConfig) { s.MaxWeight = MaxIPVSWeight + 1 }, "max_weight must be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg.Services[0])
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid config, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLint_WeightPrecision(t *testing.T) {
+	cfg := validConfig()
+	cfg.Services[0].Backends = []BackendConfig{
+		{Address: "192.168.1.1:8080", Weight: intPtr(3)},
+		{Address: "192.168.1.2:8080", Weight: intPtr(1000000)},
+	}
+	problems := Lint(cfg)
+	if len(problems) != 1 || problems[0].Check != LintWeightPrecision || !strings.Contains(problems[0].Message, "weight 3 is programmed as 1") {
+		t.Fatalf("expected a weight precision problem, got %v", problems)
+	}
+
+	cfg.Services[0].Backends[0].Weight = intPtr(300000)
+	if problems := Lint(cfg); len(problems) != 0 {
+		t.Errorf("expected proportional weights to scale without problems, got %v", problems)
+	}
+}
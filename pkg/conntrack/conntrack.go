@@ -0,0 +1,68 @@
+// Package conntrack deletes the netfilter connection tracking entries of
+// removed IPVS destinations with the conntrack tool, so that long-lived UDP
+// and TCP flows stop being steered to a backend that is gone.
+package conntrack
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// noEntriesDeleted is the message conntrack fails with when no entry matched.
+const noEntriesDeleted = "0 flow entries have been deleted"
+
+// Filter selects the entries of connections through a VIP to a backend.
+// Protocol is "tcp", "udp" or empty for any; VIP is nil and VIPPort 0 for a
+// firewall-mark service, which matches its connections by backend only.
+type Filter struct {
+	Protocol    string
+	VIP         net.IP
+	VIPPort     uint16
+	Backend     net.IP
+	BackendPort uint16
+}
+
+// Args returns the conntrack arguments that delete the entries matching the
+// filter. With NAT forwarding an entry's reply source is the backend.
+func (f Filter) Args() []string {
+	args := []string{"-D"}
+	if f.Backend.To4() == nil {
+		args = append(args, "-f", "ipv6")
+	}
+	if f.Protocol != "" {
+		args = append(args, "-p", f.Protocol)
+	}
+	if f.VIP != nil {
+		args = append(args, "--orig-dst", f.VIP.String())
+	}
+	if f.Protocol != "" && f.VIPPort != 0 {
+		args = append(args, "--orig-port-dst", strconv.Itoa(int(f.VIPPort)))
+	}
+	args = append(args, "--reply-src", f.Backend.String())
+	if f.Protocol != "" && f.BackendPort != 0 {
+		args = append(args, "--reply-port-src", strconv.Itoa(int(f.BackendPort)))
+	}
+	return args
+}
+
+// runCommand runs the conntrack tool and returns its combined output. It is a
+// variable so tests can stub the tool.
+var runCommand = func(ctx context.Context, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "conntrack", args...).CombinedOutput()
+}
+
+// Delete deletes the entries matching filter. Finding none is not an error.
+func Delete(ctx context.Context, filter Filter) error {
+	output, err := runCommand(ctx, filter.Args()...)
+	if err == nil || strings.Contains(string(output), noEntriesDeleted) {
+		return nil
+	}
+	if message := strings.TrimSpace(string(output)); message != "" {
+		return fmt.Errorf("conntrack %s: %w: %s", strings.Join(filter.Args(), " "), err, message)
+	}
+	return fmt.Errorf("conntrack %s: %w", strings.Join(filter.Args(), " "), err)
+}
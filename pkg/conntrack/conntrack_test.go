@@ -0,0 +1,73 @@
+package conntrack
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			name:   "udp service",
+			filter: Filter{Protocol: "udp", VIP: net.ParseIP("10.0.0.1"), VIPPort: 53, Backend: net.ParseIP("192.168.1.10"), BackendPort: 5353},
+			want:   []string{"-D", "-p", "udp", "--orig-dst", "10.0.0.1", "--orig-port-dst", "53", "--reply-src", "192.168.1.10", "--reply-port-src", "5353"},
+		},
+		{
+			name:   "ipv6",
+			filter: Filter{Protocol: "tcp", VIP: net.ParseIP("2001:db8::1"), VIPPort: 443, Backend: net.ParseIP("2001:db8::10"), BackendPort: 8443},
+			want:   []string{"-D", "-f", "ipv6", "-p", "tcp", "--orig-dst", "2001:db8::1", "--orig-port-dst", "443", "--reply-src", "2001:db8::10", "--reply-port-src", "8443"},
+		},
+		{
+			name:   "firewall mark",
+			filter: Filter{Backend: net.ParseIP("192.168.1.10")},
+			want:   []string{"-D", "--reply-src", "192.168.1.10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	original := runCommand
+	t.Cleanup(func() { runCommand = original })
+	filter := Filter{Protocol: "udp", Backend: net.ParseIP("192.168.1.10"), BackendPort: 53}
+
+	var calls [][]string
+	runCommand = func(_ context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte("conntrack v1.4.7 (conntrack-tools): 2 flow entries have been deleted.\n"), nil
+	}
+	if err := Delete(context.Background(), filter); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0], filter.Args()) {
+		t.Errorf("expected conntrack to be run with %v, got %v", filter.Args(), calls)
+	}
+
+	runCommand = func(context.Context, ...string) ([]byte, error) {
+		return []byte("conntrack v1.4.7 (conntrack-tools): 0 flow entries have been deleted.\n"), errors.New("exit status 1")
+	}
+	if err := Delete(context.Background(), filter); err != nil {
+		t.Errorf("expected no matching entries not to be an error, got %v", err)
+	}
+
+	runCommand = func(context.Context, ...string) ([]byte, error) {
+		return []byte("conntrack v1.4.7 (conntrack-tools): Operation not permitted\n"), errors.New("exit status 1")
+	}
+	if err := Delete(context.Background(), filter); err == nil || !strings.Contains(err.Error(), "Operation not permitted") {
+		t.Errorf("expected the conntrack error, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package featuregate lets experimental subsystems ship disabled behind named
+// gates that operators enable through global.feature_gates. Gates are read
+// once at startup; changing them requires a restart.
+package featuregate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Stage is the maturity of a gated feature.
+type Stage string
+
+// Feature maturity stages.
+const (
+	Alpha Stage = "alpha"
+	Beta  Stage = "beta"
+)
+
+// Known feature gates. Names are snake_case because the config loader
+// lower-cases map keys.
+const (
+	EBPFDataplane   = "ebpf_dataplane"
+	BGPAnnouncer    = "bgp_announcer"
+	AdaptiveWeights = "adaptive_weights"
+)
+
+// Spec describes a feature gate.
+type Spec struct {
+	Stage   Stage
+	Default bool
+}
+
+// known holds every gate ezlb understands.
+var known = map[string]Spec{
+	EBPFDataplane:   {Stage: Alpha},
+	BGPAnnouncer:    {Stage: Alpha},
+	AdaptiveWeights: {Stage: Alpha},
+}
+
+// Names returns the known gate names in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate rejects settings for gates ezlb does not know.
+func Validate(settings map[string]bool) error {
+	for name := range settings {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("unknown feature gate %q (known: %s)", name, strings.Join(Names(), ", "))
+		}
+	}
+	return nil
+}
+
+// Gates is an immutable set of resolved feature gates.
+type Gates struct {
+	enabled    map[string]bool
+	configured map[string]bool
+}
+
+// New resolves the configured settings over the gates' defaults.
+func New(settings map[string]bool) (*Gates, error) {
+	if err := Validate(settings); err != nil {
+		return nil, err
+	}
+	gates := &Gates{
+		enabled:    make(map[string]bool, len(known)),
+		configured: make(map[string]bool, len(settings)),
+	}
+	for name, spec := range known {
+		gates.enabled[name] = spec.Default
+	}
+	for name, enabled := range settings {
+		gates.enabled[name] = enabled
+		gates.configured[name] = true
+	}
+	return gates, nil
+}
+
+// Enabled reports whether a gate is on. Unknown gates are always off.
+func (g *Gates) Enabled(name string) bool {
+	return g != nil && g.enabled[name]
+}
+
+// Gate is the resolved state of one feature gate.
+type Gate struct {
+	Name    string
+	Stage   Stage
+	Enabled bool
+	// Default is set when the gate was not configured explicitly.
+	Default bool
+}
+
+// List returns the state of every known gate, sorted by name.
+func (g *Gates) List() []Gate {
+	gates := make([]Gate, 0, len(known))
+	for _, name := range Names() {
+		gates = append(gates, Gate{
+			Name:    name,
+			Stage:   known[name].Stage,
+			Enabled: g.Enabled(name),
+			Default: g == nil || !g.configured[name],
+		})
+	}
+	return gates
+}
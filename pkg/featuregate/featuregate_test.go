@@ -0,0 +1,51 @@
+package featuregate
+
+import "testing"
+
+func TestNew_Defaults(t *testing.T) {
+	gates, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, gate := range gates.List() {
+		if gate.Enabled || !gate.Default {
+			t.Errorf("expected %s to be disabled by default, got %+v", gate.Name, gate)
+		}
+	}
+}
+
+func TestNew_Settings(t *testing.T) {
+	gates, err := New(map[string]bool{AdaptiveWeights: true, BGPAnnouncer: false})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !gates.Enabled(AdaptiveWeights) {
+		t.Error("expected adaptive_weights to be enabled")
+	}
+	if gates.Enabled(BGPAnnouncer) || gates.Enabled(EBPFDataplane) {
+		t.Error("expected other gates to stay disabled")
+	}
+	if gates.Enabled("no_such_gate") {
+		t.Error("expected unknown gate to be disabled")
+	}
+
+	for _, gate := range gates.List() {
+		wantDefault := gate.Name == EBPFDataplane
+		if gate.Default != wantDefault {
+			t.Errorf("expected %s Default=%v, got %v", gate.Name, wantDefault, gate.Default)
+		}
+	}
+}
+
+func TestNew_UnknownGate(t *testing.T) {
+	if _, err := New(map[string]bool{"warp_drive": true}); err == nil {
+		t.Fatal("expected error for unknown feature gate, got nil")
+	}
+}
+
+func TestEnabled_NilGates(t *testing.T) {
+	var gates *Gates
+	if gates.Enabled(AdaptiveWeights) {
+		t.Error("expected nil gates to report every gate disabled")
+	}
+}
@@ -0,0 +1,50 @@
+package healthcheck
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// observeCertificate exports the notAfter of a backend certificate seen by an
+// https probe, for every service sharing the probe, and warns once per
+// certificate when it expires within warnWithin.
+func (m *Manager) observeCertificate(key probeKey, cert *x509.Certificate, warnWithin time.Duration) {
+	m.mu.Lock()
+	status, exists := m.statuses[key]
+	var services []string
+	alreadyWarned := false
+	if exists {
+		services = sortedNames(status.services)
+		alreadyWarned = status.certWarnedFor.Equal(cert.NotAfter)
+	}
+	remaining := time.Until(cert.NotAfter)
+	if exists && remaining <= warnWithin {
+		status.certWarnedFor = cert.NotAfter
+	}
+	m.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	for _, service := range services {
+		metrics.SetBackendCertExpiry(service, key.address, cert.NotAfter)
+	}
+	if remaining > warnWithin || alreadyWarned {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.Strings("services", services),
+		zap.String("address", key.address),
+		zap.String("subject", cert.Subject.CommonName),
+		zap.Time("not_after", cert.NotAfter),
+	}
+	if remaining <= 0 {
+		m.logger.Error("backend certificate has expired", fields...)
+		return
+	}
+	m.logger.Warn("backend certificate expires soon", append(fields, zap.Duration("remaining", remaining.Round(time.Hour)))...)
+}
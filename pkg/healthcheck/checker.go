@@ -1,11 +1,19 @@
 package healthcheck
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
+	"regexp"
+	"strconv"
 	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
 )
 
 // Checker defines the interface for health check probes.
@@ -14,61 +22,353 @@ type Checker interface {
 	Check(address string) error
 }
 
-// TCPChecker implements health checking via TCP connection attempts.
+// maxTCPExpectRead bounds how much a TCP check reads while waiting for the
+// expected data.
+const maxTCPExpectRead = 4096
+
+// TCPCheckerOptions holds the parameters of a TCPChecker.
+type TCPCheckerOptions struct {
+	Timeout time.Duration
+	// Send is written to the connection once it is established.
+	Send string
+	// Expect must then be received within the first maxTCPExpectRead bytes,
+	// e.g. the "220 " of an SMTP banner.
+	Expect string
+}
+
+// TCPChecker implements health checking via TCP connection attempts,
+// optionally followed by a send/expect exchange.
 type TCPChecker struct {
 	timeout time.Duration
+	send    string
+	expect  string
 }
 
 // NewTCPChecker creates a new TCPChecker with the given timeout.
 func NewTCPChecker(timeout time.Duration) *TCPChecker {
+	return NewTCPCheckerWithOptions(TCPCheckerOptions{Timeout: timeout})
+}
+
+// NewTCPCheckerWithOptions creates a new TCPChecker with the given options.
+func NewTCPCheckerWithOptions(opts TCPCheckerOptions) *TCPChecker {
 	return &TCPChecker{
-		timeout: timeout,
+		timeout: opts.Timeout,
+		send:    opts.Send,
+		expect:  opts.Expect,
 	}
 }
 
-// Check attempts to establish a TCP connection to the given address.
-// Returns nil if the connection succeeds (healthy), or an error if it fails (unhealthy).
+// Check attempts to establish a TCP connection to the given address, then
+// sends and expects the configured data. Returns nil if the connection
+// succeeds and the expected data arrives (healthy), or an error otherwise
+// (unhealthy).
 func (c *TCPChecker) Check(address string) error {
 	conn, err := net.DialTimeout("tcp", address, c.timeout)
 	if err != nil {
 		return fmt.Errorf("tcp health check failed for %s: %w", address, err)
 	}
-	conn.Close()
+	defer conn.Close()
+	if c.send == "" && c.expect == "" {
+		return nil
+	}
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if c.send != "" {
+		if _, err := io.WriteString(conn, c.send); err != nil {
+			return fmt.Errorf("tcp health check failed for %s: %w", address, err)
+		}
+	}
+	if c.expect == "" {
+		return nil
+	}
+	received := make([]byte, 0, maxTCPExpectRead)
+	for !bytes.Contains(received, []byte(c.expect)) {
+		if len(received) == maxTCPExpectRead {
+			return fmt.Errorf("tcp health check failed for %s: expected %q, got %q", address, c.expect, received)
+		}
+		n, err := conn.Read(received[len(received):cap(received)])
+		received = received[:len(received)+n]
+		if err != nil && !bytes.Contains(received, []byte(c.expect)) {
+			return fmt.Errorf("tcp health check failed for %s: expected %q, got %q: %w", address, c.expect, received, err)
+		}
+	}
 	return nil
 }
 
+// DegradedError is returned when a backend is reachable but impaired: it asked
+// to back off (HTTP 429 or 503 with a Retry-After header), answered with a
+// server error, or responded slower than the configured latency threshold.
+type DegradedError struct {
+	Address    string
+	StatusCode int           // HTTP status code, 0 for slow responses
+	RetryAfter time.Duration // probe pause requested by the backend
+	Latency    time.Duration // duration of a slow but otherwise successful probe
+}
+
+// Error implements the error interface.
+func (e *DegradedError) Error() string {
+	switch {
+	case e.Latency > 0:
+		return fmt.Sprintf("backend %s degraded: slow response in %v", e.Address, e.Latency)
+	case e.RetryAfter > 0:
+		return fmt.Sprintf("backend %s degraded: status %d, retry after %v", e.Address, e.StatusCode, e.RetryAfter)
+	default:
+		return fmt.Sprintf("backend %s degraded: status %d", e.Address, e.StatusCode)
+	}
+}
+
+// LatencyError is returned when a probe succeeded but took longer than the
+// configured max_latency, so an overloaded backend that still accepts
+// connections counts as failing.
+type LatencyError struct {
+	Address    string
+	Latency    time.Duration
+	MaxLatency time.Duration
+}
+
+// Error implements the error interface.
+func (e *LatencyError) Error() string {
+	return fmt.Sprintf("backend %s too slow: probe took %v, max_latency is %v", e.Address, e.Latency, e.MaxLatency)
+}
+
+// httpIdleConnTimeout bounds how long a kept-alive probe connection may sit idle.
+const httpIdleConnTimeout = 2 * time.Minute
+
+// maxExpectBodySize bounds how much of a response body is searched for the
+// expected body.
+const maxExpectBodySize = 64 << 10
+
+// HTTPCheckerOptions holds the parameters of an HTTPChecker.
+type HTTPCheckerOptions struct {
+	Path           string
+	Timeout        time.Duration
+	ExpectedStatus int
+	// Method is the request method. Defaults to GET.
+	Method string
+	// Host is sent as the Host header instead of the backend address, so
+	// backends routing by virtual host can be probed. It is also the default
+	// SNI of https probes.
+	Host string
+	// Headers are added to every request.
+	Headers map[string]string
+	// KeepAlive reuses probe connections between checks. When false (the
+	// default) every probe opens and closes its own connection.
+	KeepAlive bool
+	// MaxIdleConnsPerHost bounds the idle pool kept per backend when KeepAlive is enabled.
+	MaxIdleConnsPerHost int
+	// TLS probes the backend over https instead of http.
+	TLS bool
+	// TLSServerName is sent as SNI and used for verification instead of the backend IP.
+	TLSServerName string
+	// TLSVerify verifies the certificate chain and host name of https backends.
+	TLSVerify bool
+	// OnCertificate, if set, receives the leaf certificate of every https response.
+	OnCertificate func(address string, cert *x509.Certificate)
+	// FollowRedirects follows redirects and judges the final response. When
+	// false the redirect response itself is compared with ExpectedStatus.
+	FollowRedirects bool
+	// RetryAfterDegraded reports 429/503 responses with a Retry-After header
+	// as a *DegradedError instead of a plain failure.
+	RetryAfterDegraded bool
+	// ServerErrorDegraded reports 5xx responses as a *DegradedError.
+	ServerErrorDegraded bool
+	// ExpectBody, if set, must be contained in the first maxExpectBodySize
+	// bytes of the response body, so a backend answering the expected status
+	// with an error payload fails the check.
+	ExpectBody string
+	// ExpectBodyRegex treats ExpectBody as a regular expression the body must
+	// match. It must compile.
+	ExpectBodyRegex bool
+	// Identity is verified on every successful probe; a mismatch is
+	// reported as an *IdentityError.
+	Identity config.IdentityConfig
+}
+
 // HTTPChecker implements health checking via HTTP GET requests.
 type HTTPChecker struct {
-	client         *http.Client
-	path           string
-	expectedStatus int
+	client              *http.Client
+	transport           *http.Transport
+	onCertificate       func(address string, cert *x509.Certificate)
+	identity            config.IdentityConfig
+	scheme              string
+	path                string
+	method              string
+	host                string
+	headers             http.Header
+	expectedStatus      int
+	retryAfterDegraded  bool
+	serverErrorDegraded bool
+	expectBody          string
+	expectBodyRegex     *regexp.Regexp
 }
 
 // NewHTTPChecker creates a new HTTPChecker with the given parameters.
+// Probe connections are not kept alive and redirects are followed.
 func NewHTTPChecker(timeout time.Duration, path string, expectedStatus int) *HTTPChecker {
-	return &HTTPChecker{
-		client: &http.Client{
-			Timeout: timeout,
-		},
-		path:           path,
-		expectedStatus: expectedStatus,
+	return NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:         timeout,
+		Path:            path,
+		ExpectedStatus:  expectedStatus,
+		FollowRedirects: true,
+	})
+}
+
+// NewHTTPCheckerWithOptions creates a new HTTPChecker with its own transport,
+// so connection reuse is controlled per service instead of by the shared
+// http.DefaultTransport.
+func NewHTTPCheckerWithOptions(opts HTTPCheckerOptions) *HTTPChecker {
+	transport := &http.Transport{
+		Proxy:             nil,
+		DisableKeepAlives: !opts.KeepAlive,
+		IdleConnTimeout:   httpIdleConnTimeout,
+	}
+	if opts.KeepAlive {
+		maxIdle := opts.MaxIdleConnsPerHost
+		if maxIdle <= 0 {
+			maxIdle = 1
+		}
+		transport.MaxIdleConnsPerHost = maxIdle
+	}
+	scheme := "http"
+	if opts.TLS {
+		scheme = "https"
+		serverName := opts.TLSServerName
+		if serverName == "" && opts.Host != "" {
+			serverName = hostname(opts.Host)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: !opts.TLSVerify,
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
 	}
+	if !opts.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	checker := &HTTPChecker{
+		client:              client,
+		transport:           transport,
+		onCertificate:       opts.OnCertificate,
+		identity:            opts.Identity,
+		scheme:              scheme,
+		path:                opts.Path,
+		method:              opts.Method,
+		host:                opts.Host,
+		headers:             make(http.Header, len(opts.Headers)),
+		expectedStatus:      opts.ExpectedStatus,
+		retryAfterDegraded:  opts.RetryAfterDegraded,
+		serverErrorDegraded: opts.ServerErrorDegraded,
+		expectBody:          opts.ExpectBody,
+	}
+	if checker.method == "" {
+		checker.method = http.MethodGet
+	}
+	for name, value := range opts.Headers {
+		checker.headers.Set(name, value)
+	}
+	if opts.ExpectBody != "" && opts.ExpectBodyRegex {
+		checker.expectBodyRegex = regexp.MustCompile(opts.ExpectBody)
+	}
+	return checker
+}
+
+// CloseIdleConnections closes any kept-alive probe connections.
+func (c *HTTPChecker) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
 }
 
-// Check sends an HTTP GET request to the given address and verifies the response status code.
+// Check sends an HTTP request to the given address and verifies the response status code.
 // Returns nil if the status code matches the expected value, or an error otherwise.
 func (c *HTTPChecker) Check(address string) error {
-	url := fmt.Sprintf("http://%s%s", address, c.path)
-	resp, err := c.client.Get(url)
+	resp, err := c.do(c.method, address, c.path)
 	if err != nil {
 		return fmt.Errorf("http health check failed for %s: %w", address, err)
 	}
+	if c.onCertificate != nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		c.onCertificate(address, resp.TLS.PeerCertificates[0])
+	}
+	var body []byte
+	if c.expectBody != "" && resp.StatusCode == c.expectedStatus {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxExpectBodySize))
+	}
 	io.Copy(io.Discard, resp.Body)
 	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("http health check failed for %s: %w", address, err)
+	}
 
 	if resp.StatusCode != c.expectedStatus {
+		if c.retryAfterDegraded && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok && retryAfter > 0 {
+				return &DegradedError{Address: address, StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+			}
+		}
+		if c.serverErrorDegraded && resp.StatusCode >= http.StatusInternalServerError {
+			return &DegradedError{Address: address, StatusCode: resp.StatusCode}
+		}
 		return fmt.Errorf("http health check failed for %s: expected status %d, got %d",
 			address, c.expectedStatus, resp.StatusCode)
 	}
-	return nil
+	if !c.bodyMatches(body) {
+		return fmt.Errorf("http health check failed for %s: response body does not match %q", address, c.expectBody)
+	}
+	return c.verifyIdentity(address, resp)
+}
+
+// do sends a request with the configured Host header and extra headers.
+func (c *HTTPChecker) do(method, address, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", c.scheme, address, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = c.host
+	maps.Copy(req.Header, c.headers)
+	return c.client.Do(req)
+}
+
+// hostname returns the host of a host[:port] string.
+func hostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
+
+// bodyMatches reports whether a response body contains, or with a regular
+// expression matches, the expected body. Any body matches if none is expected.
+func (c *HTTPChecker) bodyMatches(body []byte) bool {
+	switch {
+	case c.expectBody == "":
+		return true
+	case c.expectBodyRegex != nil:
+		return c.expectBodyRegex.Match(body)
+	}
+	return bytes.Contains(body, []byte(c.expectBody))
+}
+
+// parseRetryAfter parses a Retry-After header given in delay-seconds or as an HTTP-date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
 }
@@ -1,9 +1,13 @@
 package healthcheck
 
 import (
+	"crypto/x509"
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -70,6 +74,74 @@ func TestNewTCPChecker(t *testing.T) {
 	}
 }
 
+// startTCPServer serves every connection with handle and returns its address.
+func startTCPServer(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			handle(conn)
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestTCPChecker_SendExpect(t *testing.T) {
+	// An SMTP server sending its banner in two segments
+	smtp := startTCPServer(t, func(conn net.Conn) {
+		conn.Write([]byte("22"))
+		time.Sleep(10 * time.Millisecond)
+		conn.Write([]byte("0 mail.example.com ESMTP\r\n"))
+		time.Sleep(50 * time.Millisecond)
+	})
+	// A daemon answering PONG to PING
+	pong := startTCPServer(t, func(conn net.Conn) {
+		buf := make([]byte, 16)
+		if n, _ := conn.Read(buf); string(buf[:n]) == "PING\n" {
+			conn.Write([]byte("PONG\n"))
+		}
+	})
+	// A server closing the connection right away
+	closing := startTCPServer(t, func(conn net.Conn) {})
+
+	tests := []struct {
+		name    string
+		address string
+		opts    TCPCheckerOptions
+		wantErr string
+	}{
+		{"banner", smtp, TCPCheckerOptions{Expect: "220 "}, ""},
+		{"wrong banner", smtp, TCPCheckerOptions{Expect: "554 "}, `expected "554 ", got "220 mail.example.com ESMTP\r\n"`},
+		{"send and expect", pong, TCPCheckerOptions{Send: "PING\n", Expect: "PONG"}, ""},
+		{"send only", pong, TCPCheckerOptions{Send: "PING\n"}, ""},
+		{"closed", closing, TCPCheckerOptions{Expect: "220 "}, "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Timeout = time.Second
+			err := NewTCPCheckerWithOptions(tt.opts).Check(tt.address)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected successful health check, got error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 // --- HTTPChecker tests ---
 
 func TestHTTPChecker_Success(t *testing.T) {
@@ -104,6 +176,80 @@ func TestHTTPChecker_UnexpectedStatus(t *testing.T) {
 	}
 }
 
+func TestHTTPChecker_ExpectBody(t *testing.T) {
+	body := `{"status":"degraded","db":"down"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	tests := []struct {
+		name       string
+		expectBody string
+		regex      bool
+		wantErr    bool
+	}{
+		{"no expectation", "", false, false},
+		{"substring present", `"db":"down"`, false, false},
+		{"substring missing", `"status":"ok"`, false, true},
+		{"regex matches", `"status":"(ok|degraded)"`, true, false},
+		{"regex does not match", `^\{"status":"ok"`, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+				Timeout:         3 * time.Second,
+				Path:            "/",
+				ExpectedStatus:  200,
+				ExpectBody:      tt.expectBody,
+				ExpectBodyRegex: tt.regex,
+			})
+			err := checker.Check(address)
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "response body does not match")) {
+				t.Fatalf("expected a body mismatch error, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected successful health check, got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPChecker_RequestOptions(t *testing.T) {
+	var method, host, token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, host, token = r.Method, r.Host, r.Header.Get("X-Probe-Token")
+		if r.Host != "api.example.com" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	if err := NewHTTPChecker(3*time.Second, "/", 200).Check(address); err == nil {
+		t.Fatal("expected a probe with the backend address as Host to miss the virtual host")
+	}
+	if method != http.MethodGet || host != address {
+		t.Errorf("expected a GET with Host %s by default, got %s with Host %s", address, method, host)
+	}
+
+	checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:        3 * time.Second,
+		Path:           "/",
+		ExpectedStatus: 200,
+		Method:         http.MethodHead,
+		Host:           "api.example.com",
+		Headers:        map[string]string{"x-probe-token": "secret"},
+	})
+	if err := checker.Check(address); err != nil {
+		t.Fatalf("expected successful health check, got error: %v", err)
+	}
+	if method != http.MethodHead || host != "api.example.com" || token != "secret" {
+		t.Errorf("got %s with Host %s and token %q, want HEAD with Host api.example.com and token secret", method, host, token)
+	}
+}
+
 func TestHTTPChecker_ConnectionRefused(t *testing.T) {
 	checker := NewHTTPChecker(1*time.Second, "/healthz", 200)
 	err := checker.Check("127.0.0.1:1")
@@ -168,3 +314,191 @@ func TestNewHTTPChecker(t *testing.T) {
 		t.Errorf("expected timeout 5s, got %v", checker.client.Timeout)
 	}
 }
+
+func TestHTTPChecker_KeepAlive(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive bool
+		wantConns int32
+	}{
+		{name: "keep-alive disabled opens a connection per probe", keepAlive: false, wantConns: 3},
+		{name: "keep-alive enabled reuses the idle connection", keepAlive: true, wantConns: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var newConns atomic.Int32
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					newConns.Add(1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+				Timeout:        time.Second,
+				Path:           "/",
+				ExpectedStatus: 200,
+				KeepAlive:      tt.keepAlive,
+			})
+			defer checker.CloseIdleConnections()
+
+			address := server.Listener.Addr().String()
+			for i := 0; i < 3; i++ {
+				if err := checker.Check(address); err != nil {
+					t.Fatalf("probe %d failed: %v", i, err)
+				}
+			}
+
+			if got := newConns.Load(); got != tt.wantConns {
+				t.Errorf("expected %d connections, got %d", tt.wantConns, got)
+			}
+		})
+	}
+}
+
+func TestHTTPChecker_HTTPSReportsCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var seen *x509.Certificate
+	checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:        time.Second,
+		Path:           "/",
+		ExpectedStatus: 200,
+		TLS:            true,
+		OnCertificate: func(_ string, cert *x509.Certificate) {
+			seen = cert
+		},
+	})
+
+	if err := checker.Check(server.Listener.Addr().String()); err != nil {
+		t.Fatalf("expected https probe to succeed without verification, got: %v", err)
+	}
+	if seen == nil || !seen.NotAfter.Equal(server.Certificate().NotAfter) {
+		t.Errorf("expected the server certificate to be reported, got %v", seen)
+	}
+
+	verifying := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:        time.Second,
+		Path:           "/",
+		ExpectedStatus: 200,
+		TLS:            true,
+		TLSVerify:      true,
+	})
+	if err := verifying.Check(server.Listener.Addr().String()); err == nil {
+		t.Error("expected verification of the self-signed certificate to fail")
+	}
+}
+
+func TestHTTPChecker_NoFollowRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	address := server.Listener.Addr().String()
+
+	// By default the redirect is followed and the final 200 is judged.
+	if err := NewHTTPChecker(3*time.Second, "/healthz", 200).Check(address); err != nil {
+		t.Fatalf("expected followed redirect to succeed, got error: %v", err)
+	}
+
+	checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:        3 * time.Second,
+		Path:           "/healthz",
+		ExpectedStatus: http.StatusFound,
+	})
+	if err := checker.Check(address); err != nil {
+		t.Fatalf("expected redirect response to match expected status, got error: %v", err)
+	}
+}
+
+func TestHTTPChecker_RetryAfterDegraded(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	address := server.Listener.Addr().String()
+
+	var degraded *DegradedError
+	plain := NewHTTPChecker(3*time.Second, "/healthz", 200).Check(address)
+	if plain == nil || errors.As(plain, &degraded) {
+		t.Fatalf("expected plain failure without RetryAfterDegraded, got %v", plain)
+	}
+
+	checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:            3 * time.Second,
+		Path:               "/healthz",
+		ExpectedStatus:     200,
+		RetryAfterDegraded: true,
+	})
+	err := checker.Check(address)
+	if !errors.As(err, &degraded) {
+		t.Fatalf("expected *DegradedError, got %v", err)
+	}
+	if degraded.StatusCode != http.StatusServiceUnavailable || degraded.RetryAfter != 30*time.Second {
+		t.Errorf("unexpected degraded error: %+v", degraded)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(45 * time.Second).Format(http.TimeFormat), 45 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHTTPChecker_ServerErrorDegraded(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+		Timeout:             3 * time.Second,
+		Path:                "/healthz",
+		ExpectedStatus:      200,
+		ServerErrorDegraded: true,
+	})
+	var degraded *DegradedError
+	err := checker.Check(server.Listener.Addr().String())
+	if !errors.As(err, &degraded) {
+		t.Fatalf("expected *DegradedError, got %v", err)
+	}
+	if degraded.StatusCode != http.StatusBadGateway || degraded.RetryAfter != 0 {
+		t.Errorf("unexpected degraded error: %+v", degraded)
+	}
+}
@@ -0,0 +1,152 @@
+package healthcheck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+)
+
+// dnsRecordTypes maps the record types a DNS health check can query to
+// their numeric values.
+var dnsRecordTypes = map[string]uint16{
+	"A":     1,
+	"NS":    2,
+	"CNAME": 5,
+	"SOA":   6,
+	"PTR":   12,
+	"MX":    15,
+	"TXT":   16,
+	"AAAA":  28,
+	"SRV":   33,
+}
+
+// dnsRcodes maps DNS response codes to their names.
+var dnsRcodes = map[uint16]string{
+	0: "NOERROR",
+	1: "FORMERR",
+	2: "SERVFAIL",
+	3: "NXDOMAIN",
+	4: "NOTIMP",
+	5: "REFUSED",
+}
+
+// dnsMaxUDPResponse is the largest UDP response a DNS probe reads.
+const dnsMaxUDPResponse = 4096
+
+// DNSCheckerOptions holds the parameters of a DNSChecker.
+type DNSCheckerOptions struct {
+	// Name is the queried domain name, e.g. example.com.
+	Name string
+	// Type is the queried record type, e.g. A.
+	Type string
+	// ExpectedRcode is the response code of a healthy backend, e.g. NOERROR.
+	ExpectedRcode string
+	// Protocol is udp or tcp.
+	Protocol string
+	Timeout  time.Duration
+}
+
+// DNSChecker implements health checking by sending a DNS query to the
+// backend and comparing the response code with the expected one.
+type DNSChecker struct {
+	question      []byte
+	name          string
+	expectedRcode string
+	protocol      string
+	timeout       time.Duration
+}
+
+// NewDNSChecker creates a new DNSChecker. The name and record type must have
+// been validated with the config.
+func NewDNSChecker(opts DNSCheckerOptions) *DNSChecker {
+	return &DNSChecker{
+		question:      dnsQuestion(opts.Name, dnsRecordTypes[opts.Type]),
+		name:          opts.Name,
+		expectedRcode: opts.ExpectedRcode,
+		protocol:      opts.Protocol,
+		timeout:       opts.Timeout,
+	}
+}
+
+// Check sends the query to the given address. Returns nil if the backend
+// answers with the expected response code (healthy), or an error if it
+// answers with another one, e.g. SERVFAIL, or not at all (unhealthy).
+func (c *DNSChecker) Check(address string) error {
+	conn, err := net.DialTimeout(c.protocol, address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("dns health check failed for %s: %w", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	id := uint16(rand.Uint32())
+	query := binary.BigEndian.AppendUint16(nil, id)
+	query = append(query, c.question...)
+	response, err := c.exchange(conn, query)
+	if err != nil {
+		return fmt.Errorf("dns health check failed for %s: %w", address, err)
+	}
+
+	if len(response) < 12 || binary.BigEndian.Uint16(response) != id || response[2]&0x80 == 0 {
+		return fmt.Errorf("dns health check failed for %s: invalid response", address)
+	}
+	rcode := uint16(response[3] & 0x0f)
+	name, ok := dnsRcodes[rcode]
+	if !ok {
+		name = fmt.Sprintf("RCODE%d", rcode)
+	}
+	if name != c.expectedRcode {
+		return fmt.Errorf("dns health check failed for %s: query for %s returned %s, expected %s", address, c.name, name, c.expectedRcode)
+	}
+	return nil
+}
+
+// exchange sends a query and reads the response, with the two-byte length
+// prefix of DNS over TCP.
+func (c *DNSChecker) exchange(conn net.Conn, query []byte) ([]byte, error) {
+	if c.protocol == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, dnsMaxUDPResponse)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// dnsQuestion encodes a recursive query for name and record type qtype in
+// class IN, without the leading message ID.
+func dnsQuestion(name string, qtype uint16) []byte {
+	// Flags with recursion desired, one question, no other records
+	msg := []byte{0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for label := range strings.SplitSeq(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, 1)
+}
@@ -0,0 +1,129 @@
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dnsResponse answers a DNS query with the given response code, echoing its
+// ID and question. It returns nil if the question is not the expected one.
+func dnsResponse(t *testing.T, query []byte, want []byte, rcode byte) []byte {
+	t.Helper()
+	if len(query) < 12 || !bytes.Equal(query[2:], want) {
+		t.Errorf("unexpected query %x, want question %x", query, want)
+		return nil
+	}
+	response := append([]byte(nil), query...)
+	response[2] |= 0x80
+	response[3] = rcode
+	return response
+}
+
+// startUDPDNSServer answers every query with rcode and returns its address.
+func startUDPDNSServer(t *testing.T, question []byte, rcode byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start UDP listener: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if response := dnsResponse(t, buf[:n], question, rcode); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSChecker_UDP(t *testing.T) {
+	question := dnsQuestion("example.com.", dnsRecordTypes["A"])
+	healthy := startUDPDNSServer(t, question, 0)
+	failing := startUDPDNSServer(t, question, 2)
+
+	checker := NewDNSChecker(DNSCheckerOptions{Name: "example.com.", Type: "A", ExpectedRcode: "NOERROR", Protocol: "udp", Timeout: time.Second})
+	if err := checker.Check(healthy); err != nil {
+		t.Fatalf("expected successful health check, got error: %v", err)
+	}
+	err := checker.Check(failing)
+	if err == nil || !strings.Contains(err.Error(), "returned SERVFAIL, expected NOERROR") {
+		t.Fatalf("expected SERVFAIL to fail the check, got %v", err)
+	}
+
+	// A backend that expects NXDOMAIN for a name it does not serve
+	nxdomain := NewDNSChecker(DNSCheckerOptions{Name: "example.com.", Type: "A", ExpectedRcode: "NXDOMAIN", Protocol: "udp", Timeout: time.Second})
+	if err := nxdomain.Check(healthy); err == nil {
+		t.Error("expected NOERROR to fail a check expecting NXDOMAIN")
+	}
+}
+
+func TestDNSChecker_TCP(t *testing.T) {
+	question := dnsQuestion("_sip._tcp.example.com", dnsRecordTypes["SRV"])
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start TCP listener: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					if response := dnsResponse(t, query, question, 0); response != nil {
+						conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+					}
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	checker := NewDNSChecker(DNSCheckerOptions{Name: "_sip._tcp.example.com", Type: "SRV", ExpectedRcode: "NOERROR", Protocol: "tcp", Timeout: time.Second})
+	if err := checker.Check(listener.Addr().String()); err != nil {
+		t.Fatalf("expected successful health check, got error: %v", err)
+	}
+}
+
+func TestDNSChecker_Timeout(t *testing.T) {
+	// A UDP socket that never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start UDP listener: %v", err)
+	}
+	defer conn.Close()
+
+	checker := NewDNSChecker(DNSCheckerOptions{Name: "example.com", Type: "A", ExpectedRcode: "NOERROR", Protocol: "udp", Timeout: 50 * time.Millisecond})
+	if err := checker.Check(conn.LocalAddr().String()); err == nil {
+		t.Fatal("expected a timeout to fail the check")
+	}
+}
+
+func TestDNSQuestion_Encoding(t *testing.T) {
+	want := []byte{
+		0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0, 28, 0, 1,
+	}
+	if got := dnsQuestion("example.com.", dnsRecordTypes["AAAA"]); !bytes.Equal(got, want) {
+		t.Errorf("dnsQuestion() = %x, want %x", got, want)
+	}
+	if got := dnsQuestion(".", dnsRecordTypes["NS"]); !bytes.Equal(got[10:], []byte{0, 0, 2, 0, 1}) {
+		t.Errorf("expected the root name to encode as a single zero label, got %x", got[10:])
+	}
+}
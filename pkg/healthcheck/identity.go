@@ -0,0 +1,75 @@
+package healthcheck
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxAgentIDSize bounds how much of an identity agent response is read.
+const maxAgentIDSize = 4096
+
+// IdentityError is returned when a reachable backend does not prove it runs
+// the expected application, e.g. because its IP was recycled by another service.
+type IdentityError struct {
+	Address string
+	Reason  string
+}
+
+// Error implements the error interface.
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("backend %s failed identity verification: %s", e.Address, e.Reason)
+}
+
+// verifyIdentity checks a successful probe response against the configured
+// identity: an expected response header, a certificate SAN and an agent ID.
+func (c *HTTPChecker) verifyIdentity(address string, resp *http.Response) error {
+	identity := c.identity
+
+	if identity.Header != "" {
+		value := resp.Header.Get(identity.Header)
+		if value == "" {
+			return &IdentityError{Address: address, Reason: fmt.Sprintf("response header %s is missing", identity.Header)}
+		}
+		if identity.HeaderValue != "" && value != identity.HeaderValue {
+			return &IdentityError{Address: address, Reason: fmt.Sprintf("response header %s is %q, want %q", identity.Header, value, identity.HeaderValue)}
+		}
+	}
+
+	if identity.TLSSAN != "" {
+		if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+			return &IdentityError{Address: address, Reason: "no TLS certificate presented"}
+		}
+		if err := resp.TLS.PeerCertificates[0].VerifyHostname(identity.TLSSAN); err != nil {
+			return &IdentityError{Address: address, Reason: fmt.Sprintf("certificate is not valid for %q", identity.TLSSAN)}
+		}
+	}
+
+	if identity.AgentPath != "" {
+		return c.verifyAgentID(address)
+	}
+	return nil
+}
+
+// verifyAgentID asks the backend's identity agent for its ID and compares it
+// with the configured one.
+func (c *HTTPChecker) verifyAgentID(address string) error {
+	resp, err := c.do(http.MethodGet, address, c.identity.AgentPath)
+	if err != nil {
+		return fmt.Errorf("identity agent check failed for %s: %w", address, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAgentIDSize))
+	if err != nil {
+		return fmt.Errorf("identity agent check failed for %s: %w", address, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &IdentityError{Address: address, Reason: fmt.Sprintf("identity agent returned status %d", resp.StatusCode)}
+	}
+	if id := strings.TrimSpace(string(body)); id != c.identity.AgentID {
+		return &IdentityError{Address: address, Reason: fmt.Sprintf("agent reported ID %q, want %q", id, c.identity.AgentID)}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package healthcheck
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+func TestHTTPChecker_IdentityHeaderAndAgent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App", "web")
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/ezlb-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("web-service\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	newChecker := func(identity config.IdentityConfig) *HTTPChecker {
+		return NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+			Timeout:        3 * time.Second,
+			Path:           "/healthz",
+			ExpectedStatus: 200,
+			Identity:       identity,
+		})
+	}
+
+	tests := []struct {
+		name     string
+		identity config.IdentityConfig
+		wantErr  bool
+	}{
+		{"header present", config.IdentityConfig{Header: "X-App"}, false},
+		{"header value matches", config.IdentityConfig{Header: "X-App", HeaderValue: "web"}, false},
+		{"header value differs", config.IdentityConfig{Header: "X-App", HeaderValue: "billing"}, true},
+		{"header missing", config.IdentityConfig{Header: "X-Other"}, true},
+		{"agent id matches", config.IdentityConfig{AgentPath: "/ezlb-id", AgentID: "web-service"}, false},
+		{"agent id differs", config.IdentityConfig{AgentPath: "/ezlb-id", AgentID: "billing"}, true},
+		{"agent missing", config.IdentityConfig{AgentPath: "/missing", AgentID: "web-service"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newChecker(tt.identity).Check(address)
+			var identityErr *IdentityError
+			if tt.wantErr != errors.As(err, &identityErr) {
+				t.Errorf("Check() = %v, want identity error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPChecker_IdentityTLSSAN(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The httptest certificate is issued for example.com.
+	for san, wantErr := range map[string]bool{"example.com": false, "api.example.org": true} {
+		checker := NewHTTPCheckerWithOptions(HTTPCheckerOptions{
+			Timeout:        time.Second,
+			Path:           "/",
+			ExpectedStatus: 200,
+			TLS:            true,
+			Identity:       config.IdentityConfig{TLSSAN: san},
+		})
+		err := checker.Check(server.Listener.Addr().String())
+		var identityErr *IdentityError
+		if wantErr != errors.As(err, &identityErr) {
+			t.Errorf("tls_san %q: Check() = %v, want identity error: %v", san, err, wantErr)
+		}
+	}
+}
+
+func TestHandleCheckResult_IdentityVerification(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+
+	svcCheck := &serviceCheckConfig{
+		failCount:      3,
+		riseCount:      2,
+		enabled:        true,
+		verifyIdentity: true,
+	}
+
+	// New backends awaiting verification start unhealthy
+	mgr.mu.Lock()
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
+		address: "192.168.1.1:8080",
+		healthy: !svcCheck.verifyIdentity,
+	}
+	mgr.mu.Unlock()
+	if mgr.IsHealthy("192.168.1.1:8080") {
+		t.Fatal("expected unverified backend to be excluded")
+	}
+
+	// The first verified probe admits the backend without waiting for rise_count
+	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
+	if !mgr.IsHealthy("192.168.1.1:8080") {
+		t.Fatal("expected verified backend to be healthy after its first probe")
+	}
+
+	// An identity mismatch removes it at once, regardless of fail_count
+	mismatch := &IdentityError{Address: "192.168.1.1:8080", Reason: "agent reported ID \"billing\""}
+	mgr.handleCheckResult("192.168.1.1:8080", mismatch, svcCheck)
+	if mgr.IsHealthy("192.168.1.1:8080") {
+		t.Fatal("expected identity mismatch to mark the backend unhealthy immediately")
+	}
+
+	// Afterwards, the normal rise_count applies
+	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
+	if mgr.IsHealthy("192.168.1.1:8080") {
+		t.Error("expected rise_count to apply after an identity mismatch")
+	}
+	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
+	if !mgr.IsHealthy("192.168.1.1:8080") {
+		t.Error("expected backend to recover after rise_count verified probes")
+	}
+}
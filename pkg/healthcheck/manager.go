@@ -2,6 +2,11 @@ package healthcheck
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,28 +14,166 @@ import (
 	"go.uber.org/zap"
 )
 
-// backendStatus tracks the health state and consecutive check results for a single backend.
+// backendStatus tracks the health state and consecutive check results of one
+// probe: a backend address checked with one check profile. Services sharing
+// the address and profile share the probe.
 type backendStatus struct {
 	cancel           context.CancelFunc
+	certWarnedFor    time.Time // notAfter of the certificate an expiry warning was logged for
+	retryAt          time.Time // probes are paused until then after a Retry-After response
+	unhealthySince   time.Time
+	services         map[string]bool
 	address          string
+	lastError        string          // error of the last failed probe while unhealthy
+	responseTimes    []time.Duration // recent HTTP check response times, a ring of responseTimeSamples
+	responseTimeNext int             // index in responseTimes the next sample overwrites
 	consecutiveFails int
 	consecutiveOK    int
 	healthy          bool
+	degraded         bool
+	probed           bool // at least one probe result has been handled
 }
 
-// serviceCheckConfig holds the health check parameters for a specific service's backends.
+// maxRetryAfter caps how long a Retry-After header may pause probing a backend,
+// so a misbehaving server cannot hide a later outage.
+const maxRetryAfter = 5 * time.Minute
+
+// idleConnCloser is implemented by checkers that keep idle probe connections.
+type idleConnCloser interface {
+	CloseIdleConnections()
+}
+
+// checkProfile holds the resolved health check settings that affect a probe.
+// Services whose settings resolve to the same profile share their probes of a
+// backend. Settings that do not apply to the check type are left zero.
+type checkProfile struct {
+	identity            config.IdentityConfig
+	checkType           string
+	httpPath            string
+	httpExpectBody      string
+	httpMethod          string
+	httpHost            string
+	httpHeaders         string // canonical encoding, maps are not comparable
+	tcpSend             string
+	tcpExpect           string
+	tlsServerName       string
+	dnsQueryName        string
+	dnsQueryType        string
+	dnsExpectedRcode    string
+	dnsProtocol         string
+	interval            time.Duration
+	timeout             time.Duration
+	degradedLatency     time.Duration
+	maxLatency          time.Duration
+	failCount           int
+	riseCount           int
+	expectedStatus      int
+	maxIdleConns        int
+	certExpiryWarnDays  int
+	keepAlive           bool
+	followRedirects     bool
+	retryAfterDegraded  bool
+	serverErrDegraded   bool
+	httpExpectBodyRegex bool
+	tlsVerify           bool
+}
+
+// newCheckProfile resolves the check profile of a health check configuration.
+func newCheckProfile(hc config.HealthCheckConfig) checkProfile {
+	profile := checkProfile{
+		checkType:       hc.GetType(),
+		interval:        hc.GetInterval(),
+		timeout:         hc.GetTimeout(),
+		degradedLatency: hc.GetDegradedLatency(),
+		maxLatency:      hc.GetMaxLatency(),
+		failCount:       hc.GetFailCount(),
+		riseCount:       hc.GetRiseCount(),
+	}
+	if profile.checkType == "http" || profile.checkType == "https" {
+		profile.identity = hc.Identity
+		profile.httpPath = hc.GetHTTPPath()
+		profile.expectedStatus = hc.GetHTTPExpectedStatus()
+		profile.keepAlive = hc.IsHTTPKeepAlive()
+		profile.maxIdleConns = hc.HTTPMaxIdleConns
+		profile.followRedirects = hc.IsHTTPFollowRedirects()
+		profile.retryAfterDegraded = hc.IsHTTPRetryAfterDegraded()
+		profile.serverErrDegraded = hc.IsHTTP5xxDegraded()
+		profile.httpExpectBody = hc.HTTPExpectBody
+		profile.httpExpectBodyRegex = hc.IsHTTPExpectBodyRegex()
+		profile.httpMethod = hc.GetHTTPMethod()
+		profile.httpHost = hc.HTTPHost
+		profile.httpHeaders = encodeHeaders(hc.HTTPHeaders)
+	}
+	if profile.checkType == "tcp" {
+		profile.tcpSend = hc.TCPSend
+		profile.tcpExpect = hc.TCPExpect
+	}
+	if profile.checkType == "dns" {
+		profile.dnsQueryName = hc.DNSQueryName
+		profile.dnsQueryType = hc.GetDNSQueryType()
+		profile.dnsExpectedRcode = hc.GetDNSExpectedRcode()
+		profile.dnsProtocol = hc.GetDNSProtocol()
+	}
+	if profile.checkType == "https" {
+		profile.tlsServerName = hc.TLSServerName
+		profile.tlsVerify = hc.IsTLSVerify()
+		profile.certExpiryWarnDays = hc.GetCertExpiryWarnDays()
+	}
+	return profile
+}
+
+// encodeHeaders encodes health check request headers as sorted
+// "name: value" lines, so they can be part of a comparable checkProfile.
+func encodeHeaders(headers map[string]string) string {
+	lines := make([]string, 0, len(headers))
+	for name, value := range headers {
+		lines = append(lines, http.CanonicalHeaderKey(name)+": "+value)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// decodeHeaders decodes headers encoded by encodeHeaders.
+func decodeHeaders(encoded string) map[string]string {
+	headers := make(map[string]string)
+	for line := range strings.SplitSeq(encoded, "\n") {
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// probeKey identifies a probe: a backend address and the profile it is checked with.
+type probeKey struct {
+	address string
+	profile checkProfile
+}
+
+// serviceCheckConfig holds the health check parameters of a check profile,
+// shared by every service that resolves to it.
 type serviceCheckConfig struct {
-	checker   Checker
-	interval  time.Duration
-	failCount int
-	riseCount int
-	enabled   bool
+	checker Checker
+	profile checkProfile
+	// degradedLatency marks successful probes slower than this as degraded,
+	// and maxLatency counts them as failed; 0 disables either.
+	degradedLatency time.Duration
+	maxLatency      time.Duration
+	interval        time.Duration
+	failCount       int
+	riseCount       int
+	enabled         bool
+	// verifyIdentity keeps new backends out of rotation until their first
+	// probe has verified that they run the expected application.
+	verifyIdentity bool
 }
 
 // Manager orchestrates health checks for all backends across all services.
+// A backend used by several services is probed once per distinct check
+// profile, and the probe is stopped when the last service using it goes.
 type Manager struct {
 	services map[string]*serviceCheckConfig
-	statuses map[string]*backendStatus
+	statuses map[probeKey]*backendStatus
 	onChange func()
 	logger   *zap.Logger
 	mu       sync.RWMutex
@@ -41,7 +184,7 @@ type Manager struct {
 func NewManager(onChange func(), logger *zap.Logger) *Manager {
 	return &Manager{
 		services: make(map[string]*serviceCheckConfig),
-		statuses: make(map[string]*backendStatus),
+		statuses: make(map[probeKey]*backendStatus),
 		onChange: onChange,
 		logger:   logger,
 	}
@@ -50,129 +193,225 @@ func NewManager(onChange func(), logger *zap.Logger) *Manager {
 // IsHealthy returns whether the given backend address is considered healthy.
 // Backends belonging to services with health check disabled always return true.
 // Backends not tracked (unknown) are considered healthy by default.
+// A backend probed with several check profiles is healthy only if every
+// probe reports it healthy; use IsServiceHealthy for a single service's view.
 func (m *Manager) IsHealthy(address string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	status, exists := m.statuses[address]
-	if !exists {
-		return true
+	for key, status := range m.statuses {
+		if key.address == address && !status.healthy {
+			return false
+		}
 	}
-	return status.healthy
+	return true
+}
+
+// IsServiceHealthy returns whether a backend is healthy according to the
+// health check of the given service. Untracked backends are healthy.
+func (m *Manager) IsServiceHealthy(service, address string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.serviceStatusLocked(service, address)
+	return status == nil || status.healthy
+}
+
+// IsServiceDegraded returns whether a backend is degraded according to the
+// health check of the given service.
+func (m *Manager) IsServiceDegraded(service, address string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.serviceStatusLocked(service, address)
+	return status != nil && status.degraded
+}
+
+// Failure describes why a backend is unhealthy: the error of its last failed
+// probe, how many probes in a row failed and when it was taken out.
+type Failure struct {
+	Since            time.Time
+	LastError        string
+	ConsecutiveFails int
+}
+
+// ServiceFailure returns why a backend is unhealthy according to the health
+// check of the given service. It reports false if the backend is healthy or
+// not tracked.
+func (m *Manager) ServiceFailure(service, address string) (Failure, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.serviceStatusLocked(service, address)
+	if status == nil || status.healthy {
+		return Failure{}, false
+	}
+	return Failure{Since: status.unhealthySince, LastError: status.lastError, ConsecutiveFails: status.consecutiveFails}, true
+}
+
+// serviceStatusLocked returns the probe of a backend used by a service, or nil.
+// Must be called with m.mu held.
+func (m *Manager) serviceStatusLocked(service, address string) *backendStatus {
+	svcCheck, exists := m.services[service]
+	if !exists || !svcCheck.enabled {
+		return nil
+	}
+	return m.statuses[probeKey{address: address, profile: svcCheck.profile}]
 }
 
 // UpdateTargets synchronizes the health check targets with the current configuration.
-// It starts checks for new backends, stops checks for removed backends,
-// and handles enable/disable transitions for each service.
+// It starts a probe for every backend and check profile not yet probed, records
+// which services use each probe, and stops probes no service uses any more.
+// Services with health check disabled use no probes.
 func (m *Manager) UpdateTargets(ctx context.Context, services []config.ServiceConfig) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Build the new desired state
-	newServiceNames := make(map[string]bool)
-	newBackendAddresses := make(map[string]bool)
+	// Reuse the checks of profiles already in use so running probes keep theirs
+	checks := make(map[checkProfile]*serviceCheckConfig)
+	for _, svcCheck := range m.services {
+		if svcCheck.enabled {
+			checks[svcCheck.profile] = svcCheck
+		}
+	}
 
+	newServices := make(map[string]*serviceCheckConfig, len(services))
+	references := make(map[probeKey]map[string]bool)
 	for _, svcCfg := range services {
-		newServiceNames[svcCfg.Name] = true
-
 		if !svcCfg.HealthCheck.IsEnabled() {
-			// Service has health check disabled
-			oldSvcCheck, existed := m.services[svcCfg.Name]
-			if existed && oldSvcCheck.enabled {
-				// Transition: enabled -> disabled, stop all checks for this service's backends
-				m.stopServiceBackendsLocked(svcCfg)
-			}
-			m.services[svcCfg.Name] = &serviceCheckConfig{
-				enabled: false,
-			}
-			// Mark backends as not tracked (will return healthy by default)
-			for _, backend := range svcCfg.Backends {
-				newBackendAddresses[backend.Address] = true
-			}
+			newServices[svcCfg.Name] = &serviceCheckConfig{enabled: false}
 			continue
 		}
 
-		// Service has health check enabled — select checker by type
-		var checker Checker
-		switch svcCfg.HealthCheck.GetType() {
-		case "http":
-			checker = NewHTTPChecker(
-				svcCfg.HealthCheck.GetTimeout(),
-				svcCfg.HealthCheck.GetHTTPPath(),
-				svcCfg.HealthCheck.GetHTTPExpectedStatus(),
-			)
-		default:
-			checker = NewTCPChecker(svcCfg.HealthCheck.GetTimeout())
-		}
-		svcCheck := &serviceCheckConfig{
-			checker:   checker,
-			interval:  svcCfg.HealthCheck.GetInterval(),
-			failCount: svcCfg.HealthCheck.GetFailCount(),
-			riseCount: svcCfg.HealthCheck.GetRiseCount(),
-			enabled:   true,
+		profile := newCheckProfile(svcCfg.HealthCheck)
+		svcCheck, exists := checks[profile]
+		if !exists {
+			svcCheck = m.newServiceCheck(svcCfg.HealthCheck, profile)
+			checks[profile] = svcCheck
 		}
-		m.services[svcCfg.Name] = svcCheck
+		newServices[svcCfg.Name] = svcCheck
 
 		for _, backend := range svcCfg.Backends {
-			newBackendAddresses[backend.Address] = true
-
-			if _, exists := m.statuses[backend.Address]; !exists {
-				// New backend: start health check, initial state is healthy
-				m.startBackendCheckLocked(ctx, backend.Address, svcCheck)
+			key := probeKey{address: backend.Address, profile: profile}
+			if references[key] == nil {
+				references[key] = make(map[string]bool)
 			}
+			references[key][svcCfg.Name] = true
 		}
 	}
+	m.services = newServices
 
-	// Stop checks for removed services
-	for svcName := range m.services {
-		if !newServiceNames[svcName] {
-			delete(m.services, svcName)
+	for key, serviceNames := range references {
+		status, exists := m.statuses[key]
+		if !exists {
+			// New probe: start health check, initial state is healthy
+			status = m.startBackendCheckLocked(ctx, key.address, checks[key.profile])
 		}
+		if len(serviceNames) > 1 && len(serviceNames) != len(status.services) {
+			m.logger.Debug("sharing health probe between services",
+				zap.String("address", key.address),
+				zap.Strings("services", sortedNames(serviceNames)),
+			)
+		}
+		status.services = serviceNames
 	}
 
-	// Stop checks for removed backends
-	for address, status := range m.statuses {
-		if !newBackendAddresses[address] {
-			if status.cancel != nil {
-				status.cancel()
-			}
-			delete(m.statuses, address)
-			m.logger.Info("stopped health check for removed backend", zap.String("address", address))
+	// Stop probes no longer used by any service
+	for key, status := range m.statuses {
+		if references[key] != nil {
+			continue
 		}
+		if status.cancel != nil {
+			status.cancel()
+		}
+		delete(m.statuses, key)
+		m.logger.Info("stopped health check for removed backend", zap.String("address", key.address))
 	}
 }
 
-// stopServiceBackendsLocked stops health checks for all backends of a service.
-// Must be called with m.mu held.
-func (m *Manager) stopServiceBackendsLocked(svcCfg config.ServiceConfig) {
-	for _, backend := range svcCfg.Backends {
-		if status, exists := m.statuses[backend.Address]; exists {
-			if status.cancel != nil {
-				status.cancel()
+// newServiceCheck creates the checker and parameters of a check profile.
+func (m *Manager) newServiceCheck(hc config.HealthCheckConfig, profile checkProfile) *serviceCheckConfig {
+	var checker Checker
+	switch profile.checkType {
+	case "http", "https":
+		opts := HTTPCheckerOptions{
+			Timeout:             profile.timeout,
+			Path:                profile.httpPath,
+			ExpectedStatus:      profile.expectedStatus,
+			KeepAlive:           profile.keepAlive,
+			MaxIdleConnsPerHost: profile.maxIdleConns,
+			FollowRedirects:     profile.followRedirects,
+			RetryAfterDegraded:  profile.retryAfterDegraded,
+			ServerErrorDegraded: profile.serverErrDegraded,
+			ExpectBody:          profile.httpExpectBody,
+			ExpectBodyRegex:     profile.httpExpectBodyRegex,
+			Method:              profile.httpMethod,
+			Host:                profile.httpHost,
+			Headers:             decodeHeaders(profile.httpHeaders),
+			Identity:            profile.identity,
+		}
+		if profile.checkType == "https" {
+			warnWithin := time.Duration(profile.certExpiryWarnDays) * 24 * time.Hour
+			opts.TLS = true
+			opts.TLSServerName = profile.tlsServerName
+			opts.TLSVerify = profile.tlsVerify
+			opts.OnCertificate = func(address string, cert *x509.Certificate) {
+				m.observeCertificate(probeKey{address: address, profile: profile}, cert, warnWithin)
 			}
-			delete(m.statuses, backend.Address)
-			m.logger.Info("stopped health check (service disabled)",
-				zap.String("service", svcCfg.Name),
-				zap.String("address", backend.Address),
-			)
 		}
+		checker = NewHTTPCheckerWithOptions(opts)
+	case "dns":
+		checker = NewDNSChecker(DNSCheckerOptions{
+			Name:          profile.dnsQueryName,
+			Type:          profile.dnsQueryType,
+			ExpectedRcode: profile.dnsExpectedRcode,
+			Protocol:      profile.dnsProtocol,
+			Timeout:       profile.timeout,
+		})
+	case "redis":
+		checker = NewRedisChecker(profile.timeout)
+	case "mysql":
+		checker = NewMySQLChecker(profile.timeout)
+	default:
+		checker = NewTCPCheckerWithOptions(TCPCheckerOptions{
+			Timeout: profile.timeout,
+			Send:    profile.tcpSend,
+			Expect:  profile.tcpExpect,
+		})
+	}
+	return &serviceCheckConfig{
+		checker:         checker,
+		profile:         profile,
+		degradedLatency: profile.degradedLatency,
+		maxLatency:      profile.maxLatency,
+		interval:        profile.interval,
+		failCount:       profile.failCount,
+		riseCount:       profile.riseCount,
+		enabled:         true,
+		verifyIdentity:  hc.Identity.IsEnabled(),
 	}
 }
 
 // startBackendCheckLocked starts a health check goroutine for a single backend.
+// New backends start healthy unless their identity must be verified first.
 // Must be called with m.mu held.
-func (m *Manager) startBackendCheckLocked(ctx context.Context, address string, svcCheck *serviceCheckConfig) {
+func (m *Manager) startBackendCheckLocked(ctx context.Context, address string, svcCheck *serviceCheckConfig) *backendStatus {
 	checkCtx, cancel := context.WithCancel(ctx)
 	status := &backendStatus{
 		address: address,
-		healthy: true,
+		healthy: !svcCheck.verifyIdentity,
 		cancel:  cancel,
 	}
-	m.statuses[address] = status
+	if !status.healthy {
+		status.unhealthySince = time.Now()
+		status.lastError = "identity not verified yet"
+	}
+	m.statuses[probeKey{address: address, profile: svcCheck.profile}] = status
 
 	m.logger.Info("started health check for backend", zap.String("address", address))
 
 	go m.runCheck(checkCtx, address, svcCheck)
+	return status
 }
 
 // runCheck is the health check loop for a single backend.
@@ -181,58 +420,138 @@ func (m *Manager) runCheck(ctx context.Context, address string, svcCheck *servic
 	ticker := time.NewTicker(svcCheck.interval)
 	defer ticker.Stop()
 
+	// Backends awaiting identity verification are probed right away so they
+	// do not stay out of rotation for a whole interval.
+	if svcCheck.verifyIdentity {
+		m.probe(address, svcCheck)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
+			// Release kept-alive probe sockets so removed backends don't leak FDs
+			if closer, ok := svcCheck.checker.(idleConnCloser); ok {
+				closer.CloseIdleConnections()
+			}
 			return
 		case <-ticker.C:
-			err := svcCheck.checker.Check(address)
-			m.handleCheckResult(address, err, svcCheck)
+			if m.probePaused(address, svcCheck) {
+				continue
+			}
+			m.probe(address, svcCheck)
 		}
 	}
 }
 
+// probe runs a single check against a backend and handles its result.
+func (m *Manager) probe(address string, svcCheck *serviceCheckConfig) {
+	start := time.Now()
+	err := svcCheck.checker.Check(address)
+	latency := time.Since(start)
+	if (svcCheck.profile.checkType == "http" || svcCheck.profile.checkType == "https") && answered(err) {
+		m.recordResponseTime(probeKey{address: address, profile: svcCheck.profile}, latency)
+	}
+	switch {
+	case err != nil:
+	case svcCheck.maxLatency > 0 && latency > svcCheck.maxLatency:
+		err = &LatencyError{Address: address, Latency: latency, MaxLatency: svcCheck.maxLatency}
+	case svcCheck.degradedLatency > 0 && latency > svcCheck.degradedLatency:
+		err = &DegradedError{Address: address, Latency: latency}
+	}
+	m.handleCheckResult(address, err, svcCheck)
+}
+
 // handleCheckResult processes a single health check result and updates the backend status.
 // Triggers onChange callback if the health status transitions.
 func (m *Manager) handleCheckResult(address string, checkErr error, svcCheck *serviceCheckConfig) {
 	m.mu.Lock()
 
-	status, exists := m.statuses[address]
+	status, exists := m.statuses[probeKey{address: address, profile: svcCheck.profile}]
 	if !exists {
 		m.mu.Unlock()
 		return
 	}
 
 	previouslyHealthy := status.healthy
-
-	if checkErr != nil {
-		// Check failed
+	previouslyDegraded := status.degraded
+
+	var degradedErr *DegradedError
+	var identityErr *IdentityError
+	isDegraded := errors.As(checkErr, &degradedErr)
+	firstProbe := !status.probed
+	status.probed = true
+	switch {
+	case errors.As(checkErr, &identityErr):
+		// A reachable backend running the wrong application must not receive
+		// traffic, so it is removed at once instead of after fail_count.
+		status.consecutiveFails++
+		status.consecutiveOK = 0
+		status.degraded = false
+		if status.healthy || firstProbe {
+			m.logger.Error("backend failed identity verification",
+				zap.String("address", address),
+				zap.String("reason", identityErr.Reason),
+			)
+		}
+		if status.healthy {
+			status.unhealthySince = time.Now()
+		}
+		status.healthy = false
+		status.lastError = checkErr.Error()
+	case isDegraded && degradedErr.RetryAfter > 0:
+		// The backend answered but asked to back off: keep it in its current
+		// state, mark it degraded and pause probing until Retry-After.
+		status.consecutiveFails = 0
+		status.consecutiveOK = 0
+		status.degraded = status.healthy
+		status.retryAt = time.Now().Add(min(degradedErr.RetryAfter, maxRetryAfter))
+	case checkErr != nil && !(isDegraded && degradedErr.Latency > 0):
+		// Check failed. A server error keeps a healthy backend in rotation as
+		// degraded until fail_count consecutive failures remove it.
 		status.consecutiveFails++
 		status.consecutiveOK = 0
 
+		if !status.healthy || status.consecutiveFails >= svcCheck.failCount {
+			status.lastError = checkErr.Error()
+		}
 		if status.healthy && status.consecutiveFails >= svcCheck.failCount {
 			status.healthy = false
+			status.unhealthySince = time.Now()
 			m.logger.Warn("backend marked unhealthy",
 				zap.String("address", address),
 				zap.Int("consecutive_fails", status.consecutiveFails),
 				zap.Error(checkErr),
 			)
 		}
-	} else {
-		// Check succeeded
+		status.degraded = isDegraded && status.healthy
+	default:
+		// Check succeeded, possibly slower than the degraded latency threshold
 		status.consecutiveOK++
 		status.consecutiveFails = 0
 
-		if !status.healthy && status.consecutiveOK >= svcCheck.riseCount {
+		// A new backend whose identity was just verified is admitted at once.
+		if !status.healthy && (status.consecutiveOK >= svcCheck.riseCount || (firstProbe && svcCheck.verifyIdentity)) {
 			status.healthy = true
+			status.lastError = ""
+			status.unhealthySince = time.Time{}
 			m.logger.Info("backend marked healthy",
 				zap.String("address", address),
 				zap.Int("consecutive_ok", status.consecutiveOK),
 			)
 		}
+		status.degraded = isDegraded && status.healthy
+	}
+
+	if status.degraded && !previouslyDegraded {
+		m.logger.Warn("backend marked degraded",
+			zap.String("address", address),
+			zap.Error(checkErr),
+		)
+	} else if previouslyDegraded && !status.degraded && status.healthy {
+		m.logger.Info("backend recovered from degraded", zap.String("address", address))
 	}
 
-	statusChanged := previouslyHealthy != status.healthy
+	statusChanged := previouslyHealthy != status.healthy || previouslyDegraded != status.degraded
 	m.mu.Unlock()
 
 	if statusChanged && m.onChange != nil {
@@ -240,15 +559,41 @@ func (m *Manager) handleCheckResult(address string, checkErr error, svcCheck *se
 	}
 }
 
-// GetAllStatuses returns a copy of all backend health statuses.
-// The key format is "serviceName/backendAddress".
-func (m *Manager) GetAllStatuses() map[string]bool {
+// IsDegraded returns whether the given backend is healthy but impaired: its last
+// probe was slow, returned a server error, or asked to back off via Retry-After.
+// A backend probed with several check profiles is degraded if any probe says so.
+func (m *Manager) IsDegraded(address string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for key, status := range m.statuses {
+		if key.address == address && status.degraded {
+			return true
+		}
+	}
+	return false
+}
+
+// probePaused reports whether probing of a backend is paused by Retry-After.
+func (m *Manager) probePaused(address string, svcCheck *serviceCheckConfig) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status, exists := m.statuses[probeKey{address: address, profile: svcCheck.profile}]
+	return exists && time.Now().Before(status.retryAt)
+}
+
+// Snapshot returns a copy of all backend health statuses, keyed by backend
+// address. A backend probed with several check profiles is healthy only if
+// every probe reports it healthy.
+func (m *Manager) Snapshot() map[string]bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make(map[string]bool, len(m.statuses))
-	for address, status := range m.statuses {
-		result[address] = status.healthy
+	for key, status := range m.statuses {
+		healthy, seen := result[key.address]
+		result[key.address] = status.healthy && (healthy || !seen)
 	}
 	return result
 }
@@ -258,14 +603,24 @@ func (m *Manager) Stop() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for address, status := range m.statuses {
+	for key, status := range m.statuses {
 		if status.cancel != nil {
 			status.cancel()
 		}
-		m.logger.Debug("stopped health check", zap.String("address", address))
+		m.logger.Debug("stopped health check", zap.String("address", key.address))
 	}
 
-	m.statuses = make(map[string]*backendStatus)
+	m.statuses = make(map[probeKey]*backendStatus)
 	m.services = make(map[string]*serviceCheckConfig)
 	m.logger.Info("all health checks stopped")
 }
+
+// sortedNames returns the keys of a set in order.
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,13 +2,17 @@ package healthcheck
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/easzlab/ezlb/pkg/config"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // boolPtr creates a pointer to a bool value.
@@ -16,6 +20,22 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+// intPtr creates a pointer to an int value.
+func intPtr(i int) *int {
+	return &i
+}
+
+// trackedStatus returns the probe of a backend, whatever its check profile.
+// Must be called with mgr.mu held.
+func trackedStatus(mgr *Manager, address string) (*backendStatus, bool) {
+	for key, status := range mgr.statuses {
+		if key.address == address {
+			return status, true
+		}
+	}
+	return nil, false
+}
+
 // --- IsHealthy tests ---
 
 func TestIsHealthy_UnknownAddress(t *testing.T) {
@@ -28,7 +48,7 @@ func TestIsHealthy_UnknownAddress(t *testing.T) {
 func TestIsHealthy_HealthyBackend(t *testing.T) {
 	mgr := NewManager(nil, zap.NewNop())
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: true,
 	}
@@ -42,7 +62,7 @@ func TestIsHealthy_HealthyBackend(t *testing.T) {
 func TestIsHealthy_UnhealthyBackend(t *testing.T) {
 	mgr := NewManager(nil, zap.NewNop())
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: false,
 	}
@@ -71,7 +91,7 @@ func TestUpdateTargets_RegisterBackend(t *testing.T) {
 				Timeout:  "50ms",
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
 			},
 		},
 	}
@@ -81,10 +101,11 @@ func TestUpdateTargets_RegisterBackend(t *testing.T) {
 	mgr.mu.RLock()
 	defer mgr.mu.RUnlock()
 
-	if _, exists := mgr.statuses["192.168.1.1:8080"]; !exists {
+	status, exists := trackedStatus(mgr, "192.168.1.1:8080")
+	if !exists {
 		t.Fatal("expected backend to be registered in statuses")
 	}
-	if !mgr.statuses["192.168.1.1:8080"].healthy {
+	if !status.healthy {
 		t.Error("expected initial status to be healthy")
 	}
 }
@@ -106,8 +127,8 @@ func TestUpdateTargets_RemoveBackend(t *testing.T) {
 				Timeout:  "50ms",
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
-				{Address: "192.168.1.2:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
+				{Address: "192.168.1.2:8080", Weight: intPtr(1)},
 			},
 		},
 	}
@@ -125,7 +146,7 @@ func TestUpdateTargets_RemoveBackend(t *testing.T) {
 				Timeout:  "50ms",
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
 			},
 		},
 	}
@@ -134,10 +155,10 @@ func TestUpdateTargets_RemoveBackend(t *testing.T) {
 	mgr.mu.RLock()
 	defer mgr.mu.RUnlock()
 
-	if _, exists := mgr.statuses["192.168.1.2:8080"]; exists {
+	if _, exists := trackedStatus(mgr, "192.168.1.2:8080"); exists {
 		t.Error("expected removed backend to be cleaned up from statuses")
 	}
-	if _, exists := mgr.statuses["192.168.1.1:8080"]; !exists {
+	if _, exists := trackedStatus(mgr, "192.168.1.1:8080"); !exists {
 		t.Error("expected remaining backend to still be in statuses")
 	}
 }
@@ -156,7 +177,7 @@ func TestUpdateTargets_DisabledHealthCheck(t *testing.T) {
 				Enabled: boolPtr(false),
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
 			},
 		},
 	}
@@ -165,7 +186,7 @@ func TestUpdateTargets_DisabledHealthCheck(t *testing.T) {
 
 	// Backend should not be tracked when health check is disabled
 	mgr.mu.RLock()
-	_, exists := mgr.statuses["192.168.1.1:8080"]
+	_, exists := trackedStatus(mgr, "192.168.1.1:8080")
 	mgr.mu.RUnlock()
 
 	if exists {
@@ -195,14 +216,14 @@ func TestUpdateTargets_EnabledToDisabledTransition(t *testing.T) {
 				Timeout:  "50ms",
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
 			},
 		},
 	}
 	mgr.UpdateTargets(ctx, services1)
 
 	mgr.mu.RLock()
-	_, tracked := mgr.statuses["192.168.1.1:8080"]
+	_, tracked := trackedStatus(mgr, "192.168.1.1:8080")
 	mgr.mu.RUnlock()
 	if !tracked {
 		t.Fatal("expected backend to be tracked when health check is enabled")
@@ -218,14 +239,14 @@ func TestUpdateTargets_EnabledToDisabledTransition(t *testing.T) {
 				Enabled: boolPtr(false),
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
 			},
 		},
 	}
 	mgr.UpdateTargets(ctx, services2)
 
 	mgr.mu.RLock()
-	_, stillTracked := mgr.statuses["192.168.1.1:8080"]
+	_, stillTracked := trackedStatus(mgr, "192.168.1.1:8080")
 	mgr.mu.RUnlock()
 	if stillTracked {
 		t.Error("expected backend to be untracked after disabling health check")
@@ -248,7 +269,7 @@ func TestHandleCheckResult_ConsecutiveFailsMarkUnhealthy(t *testing.T) {
 
 	// Manually inject a backend status
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: true,
 	}
@@ -261,7 +282,7 @@ func TestHandleCheckResult_ConsecutiveFailsMarkUnhealthy(t *testing.T) {
 	mgr.handleCheckResult("192.168.1.1:8080", checkErr, svcCheck)
 
 	mgr.mu.RLock()
-	stillHealthy := mgr.statuses["192.168.1.1:8080"].healthy
+	stillHealthy := mgr.statuses[probeKey{address: "192.168.1.1:8080"}].healthy
 	mgr.mu.RUnlock()
 	if !stillHealthy {
 		t.Error("expected backend to still be healthy after 2 failures (threshold is 3)")
@@ -271,7 +292,7 @@ func TestHandleCheckResult_ConsecutiveFailsMarkUnhealthy(t *testing.T) {
 	mgr.handleCheckResult("192.168.1.1:8080", checkErr, svcCheck)
 
 	mgr.mu.RLock()
-	nowUnhealthy := !mgr.statuses["192.168.1.1:8080"].healthy
+	nowUnhealthy := !mgr.statuses[probeKey{address: "192.168.1.1:8080"}].healthy
 	mgr.mu.RUnlock()
 	if !nowUnhealthy {
 		t.Error("expected backend to be unhealthy after 3 consecutive failures")
@@ -282,6 +303,40 @@ func TestHandleCheckResult_ConsecutiveFailsMarkUnhealthy(t *testing.T) {
 	}
 }
 
+func TestServiceFailure(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+	svcCheck := &serviceCheckConfig{failCount: 2, riseCount: 1, enabled: true}
+	mgr.mu.Lock()
+	mgr.services["web"] = svcCheck
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{address: "192.168.1.1:8080", healthy: true}
+	mgr.mu.Unlock()
+
+	checkErr := fmt.Errorf("connection refused")
+	mgr.handleCheckResult("192.168.1.1:8080", checkErr, svcCheck)
+	if _, unhealthy := mgr.ServiceFailure("web", "192.168.1.1:8080"); unhealthy {
+		t.Fatal("expected no failure while the backend is still healthy")
+	}
+
+	before := time.Now()
+	mgr.handleCheckResult("192.168.1.1:8080", checkErr, svcCheck)
+	mgr.handleCheckResult("192.168.1.1:8080", checkErr, svcCheck)
+	failure, unhealthy := mgr.ServiceFailure("web", "192.168.1.1:8080")
+	if !unhealthy {
+		t.Fatal("expected a failure for the unhealthy backend")
+	}
+	if failure.LastError != "connection refused" || failure.ConsecutiveFails != 3 || failure.Since.Before(before) {
+		t.Errorf("unexpected failure: %+v", failure)
+	}
+
+	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
+	if _, unhealthy := mgr.ServiceFailure("web", "192.168.1.1:8080"); unhealthy {
+		t.Error("expected no failure once the backend recovered")
+	}
+	if _, unhealthy := mgr.ServiceFailure("web", "192.168.1.2:8080"); unhealthy {
+		t.Error("expected no failure for an untracked backend")
+	}
+}
+
 func TestHandleCheckResult_ConsecutiveSuccessMarkHealthy(t *testing.T) {
 	var onChangeCalled atomic.Int32
 	mgr := NewManager(func() {
@@ -296,7 +351,7 @@ func TestHandleCheckResult_ConsecutiveSuccessMarkHealthy(t *testing.T) {
 
 	// Start with unhealthy backend
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: false,
 	}
@@ -306,7 +361,7 @@ func TestHandleCheckResult_ConsecutiveSuccessMarkHealthy(t *testing.T) {
 	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
 
 	mgr.mu.RLock()
-	stillUnhealthy := !mgr.statuses["192.168.1.1:8080"].healthy
+	stillUnhealthy := !mgr.statuses[probeKey{address: "192.168.1.1:8080"}].healthy
 	mgr.mu.RUnlock()
 	if !stillUnhealthy {
 		t.Error("expected backend to still be unhealthy after 1 success (threshold is 2)")
@@ -316,7 +371,7 @@ func TestHandleCheckResult_ConsecutiveSuccessMarkHealthy(t *testing.T) {
 	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
 
 	mgr.mu.RLock()
-	nowHealthy := mgr.statuses["192.168.1.1:8080"].healthy
+	nowHealthy := mgr.statuses[probeKey{address: "192.168.1.1:8080"}].healthy
 	mgr.mu.RUnlock()
 	if !nowHealthy {
 		t.Error("expected backend to be healthy after 2 consecutive successes")
@@ -341,7 +396,7 @@ func TestHandleCheckResult_NoChangeNoCallback(t *testing.T) {
 
 	// Healthy backend, successful check -> no state change
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: true,
 	}
@@ -364,7 +419,7 @@ func TestHandleCheckResult_FailResetsConsecutiveOK(t *testing.T) {
 	}
 
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: false,
 	}
@@ -376,7 +431,7 @@ func TestHandleCheckResult_FailResetsConsecutiveOK(t *testing.T) {
 	mgr.handleCheckResult("192.168.1.1:8080", fmt.Errorf("fail"), svcCheck)
 
 	mgr.mu.RLock()
-	status := mgr.statuses["192.168.1.1:8080"]
+	status := mgr.statuses[probeKey{address: "192.168.1.1:8080"}]
 	consecutiveOK := status.consecutiveOK
 	consecutiveFails := status.consecutiveFails
 	mgr.mu.RUnlock()
@@ -402,6 +457,239 @@ func TestHandleCheckResult_UnknownAddressIgnored(t *testing.T) {
 	mgr.handleCheckResult("unknown:1234", nil, svcCheck)
 }
 
+func TestHandleCheckResult_DegradedKeepsBackendHealthy(t *testing.T) {
+	var onChangeCalled atomic.Int32
+	mgr := NewManager(func() {
+		onChangeCalled.Add(1)
+	}, zap.NewNop())
+
+	svcCheck := &serviceCheckConfig{
+		failCount: 1,
+		riseCount: 1,
+		enabled:   true,
+	}
+
+	mgr.mu.Lock()
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
+		address: "192.168.1.1:8080",
+		healthy: true,
+	}
+	mgr.mu.Unlock()
+
+	degradedErr := &DegradedError{Address: "192.168.1.1:8080", StatusCode: 503, RetryAfter: time.Minute}
+	mgr.handleCheckResult("192.168.1.1:8080", degradedErr, svcCheck)
+
+	if !mgr.IsHealthy("192.168.1.1:8080") {
+		t.Error("expected degraded backend to stay healthy")
+	}
+	if !mgr.IsDegraded("192.168.1.1:8080") {
+		t.Error("expected backend to be degraded")
+	}
+	if !mgr.probePaused("192.168.1.1:8080", svcCheck) {
+		t.Error("expected probing to be paused until Retry-After")
+	}
+	if onChangeCalled.Load() != 1 {
+		t.Errorf("expected onChange to be called once, got %d", onChangeCalled.Load())
+	}
+
+	// A successful probe clears the degraded state.
+	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
+	if mgr.IsDegraded("192.168.1.1:8080") {
+		t.Error("expected degraded state to clear after a successful check")
+	}
+	if onChangeCalled.Load() != 2 {
+		t.Errorf("expected onChange to be called twice, got %d", onChangeCalled.Load())
+	}
+}
+
+func TestHandleCheckResult_ServerErrorDegradesUntilFailCount(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+
+	svcCheck := &serviceCheckConfig{
+		failCount: 2,
+		riseCount: 1,
+		enabled:   true,
+	}
+
+	mgr.mu.Lock()
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
+		address: "192.168.1.1:8080",
+		healthy: true,
+	}
+	mgr.mu.Unlock()
+
+	serverErr := &DegradedError{Address: "192.168.1.1:8080", StatusCode: 502}
+	mgr.handleCheckResult("192.168.1.1:8080", serverErr, svcCheck)
+	if !mgr.IsHealthy("192.168.1.1:8080") || !mgr.IsDegraded("192.168.1.1:8080") {
+		t.Fatal("expected a single server error to leave the backend healthy but degraded")
+	}
+	if mgr.probePaused("192.168.1.1:8080", svcCheck) {
+		t.Error("expected server errors not to pause probing")
+	}
+
+	mgr.handleCheckResult("192.168.1.1:8080", serverErr, svcCheck)
+	if mgr.IsHealthy("192.168.1.1:8080") || mgr.IsDegraded("192.168.1.1:8080") {
+		t.Error("expected backend to be unhealthy and not degraded after fail_count server errors")
+	}
+}
+
+func TestHandleCheckResult_SlowResponseDegrades(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+
+	svcCheck := &serviceCheckConfig{
+		failCount: 1,
+		riseCount: 2,
+		enabled:   true,
+	}
+
+	mgr.mu.Lock()
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
+		address: "192.168.1.1:8080",
+		healthy: false,
+	}
+	mgr.mu.Unlock()
+
+	slow := &DegradedError{Address: "192.168.1.1:8080", Latency: 2 * time.Second}
+
+	// Slow responses count toward rise_count but an unhealthy backend is not degraded
+	mgr.handleCheckResult("192.168.1.1:8080", slow, svcCheck)
+	if mgr.IsHealthy("192.168.1.1:8080") || mgr.IsDegraded("192.168.1.1:8080") {
+		t.Fatal("expected backend to stay unhealthy after one slow success")
+	}
+	mgr.handleCheckResult("192.168.1.1:8080", slow, svcCheck)
+	if !mgr.IsHealthy("192.168.1.1:8080") || !mgr.IsDegraded("192.168.1.1:8080") {
+		t.Fatal("expected backend to rise as healthy but degraded")
+	}
+
+	mgr.handleCheckResult("192.168.1.1:8080", nil, svcCheck)
+	if mgr.IsDegraded("192.168.1.1:8080") {
+		t.Error("expected a fast success to clear the degraded state")
+	}
+}
+
+// sleepChecker succeeds after sleeping, simulating a slow backend.
+type sleepChecker struct {
+	delay time.Duration
+}
+
+func (c sleepChecker) Check(string) error {
+	time.Sleep(c.delay)
+	return nil
+}
+
+func TestProbe_MaxLatencyCountsAsFailure(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+
+	svcCheck := &serviceCheckConfig{
+		checker:         sleepChecker{delay: 20 * time.Millisecond},
+		degradedLatency: time.Millisecond,
+		maxLatency:      5 * time.Millisecond,
+		failCount:       2,
+		riseCount:       1,
+		enabled:         true,
+	}
+
+	mgr.mu.Lock()
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
+		address: "192.168.1.1:8080",
+		healthy: true,
+	}
+	mgr.mu.Unlock()
+
+	mgr.probe("192.168.1.1:8080", svcCheck)
+	if !mgr.IsHealthy("192.168.1.1:8080") || mgr.IsDegraded("192.168.1.1:8080") {
+		t.Fatal("expected one slow probe to count as a failure below fail_count, not as degraded")
+	}
+	mgr.probe("192.168.1.1:8080", svcCheck)
+	if mgr.IsHealthy("192.168.1.1:8080") {
+		t.Fatal("expected fail_count slow probes to mark the backend unhealthy")
+	}
+	mgr.mu.Lock()
+	lastError := mgr.statuses[probeKey{address: "192.168.1.1:8080"}].lastError
+	mgr.mu.Unlock()
+	if !strings.Contains(lastError, "max_latency is 5ms") {
+		t.Errorf("expected the latency error to be reported, got %q", lastError)
+	}
+
+	// Below max_latency the slow probe only degrades the backend
+	svcCheck.maxLatency = time.Second
+	mgr.probe("192.168.1.1:8080", svcCheck)
+	if !mgr.IsHealthy("192.168.1.1:8080") || !mgr.IsDegraded("192.168.1.1:8080") {
+		t.Error("expected a probe within max_latency to restore the backend as degraded")
+	}
+}
+
+// failingChecker always fails with err.
+type failingChecker struct {
+	err error
+}
+
+func (c failingChecker) Check(string) error {
+	return c.err
+}
+
+func TestServiceResponseTimes(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+	profile := checkProfile{checkType: "http"}
+	svcCheck := &serviceCheckConfig{
+		checker:   sleepChecker{delay: time.Millisecond},
+		profile:   profile,
+		failCount: 3,
+		riseCount: 1,
+		enabled:   true,
+	}
+	mgr.mu.Lock()
+	mgr.services["web"] = svcCheck
+	mgr.statuses[probeKey{address: "192.168.1.1:8080", profile: profile}] = &backendStatus{address: "192.168.1.1:8080", healthy: true}
+	mgr.mu.Unlock()
+
+	if _, ok := mgr.ServiceResponseTimes("web", "192.168.1.1:8080"); ok {
+		t.Fatal("expected no response times before the first probe")
+	}
+
+	mgr.probe("192.168.1.1:8080", svcCheck)
+	svcCheck.checker = failingChecker{err: fmt.Errorf("connection refused")}
+	mgr.probe("192.168.1.1:8080", svcCheck)
+	times, ok := mgr.ServiceResponseTimes("web", "192.168.1.1:8080")
+	if !ok || times.Samples != 1 {
+		t.Fatalf("expected only the answered probe to be recorded, got %+v (ok=%v)", times, ok)
+	}
+	if times.P50 < time.Millisecond || times.Max != times.P50 {
+		t.Errorf("unexpected response times: %+v", times)
+	}
+
+	// Only the most recent samples are kept
+	for i := 1; i <= 2*responseTimeSamples; i++ {
+		mgr.recordResponseTime(probeKey{address: "192.168.1.1:8080", profile: profile}, time.Duration(i)*time.Millisecond)
+	}
+	times, _ = mgr.ServiceResponseTimes("web", "192.168.1.1:8080")
+	want := ResponseTimes{
+		Samples: responseTimeSamples,
+		P50:     150 * time.Millisecond,
+		P90:     190 * time.Millisecond,
+		P99:     199 * time.Millisecond,
+		Max:     200 * time.Millisecond,
+	}
+	if times != want {
+		t.Errorf("expected %+v, got %+v", want, times)
+	}
+}
+
+func TestProbe_TCPChecksRecordNoResponseTimes(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+	profile := checkProfile{checkType: "tcp"}
+	svcCheck := &serviceCheckConfig{checker: sleepChecker{}, profile: profile, failCount: 1, riseCount: 1, enabled: true}
+	mgr.mu.Lock()
+	mgr.services["db"] = svcCheck
+	mgr.statuses[probeKey{address: "192.168.1.1:3306", profile: profile}] = &backendStatus{address: "192.168.1.1:3306", healthy: true}
+	mgr.mu.Unlock()
+
+	mgr.probe("192.168.1.1:3306", svcCheck)
+	if _, ok := mgr.ServiceResponseTimes("db", "192.168.1.1:3306"); ok {
+		t.Error("expected TCP checks to record no response times")
+	}
+}
+
 // --- Stop tests ---
 
 func TestStop_ClearsAllState(t *testing.T) {
@@ -420,8 +708,8 @@ func TestStop_ClearsAllState(t *testing.T) {
 				Timeout:  "50ms",
 			},
 			Backends: []config.BackendConfig{
-				{Address: "192.168.1.1:8080", Weight: 1},
-				{Address: "192.168.1.2:8080", Weight: 1},
+				{Address: "192.168.1.1:8080", Weight: intPtr(1)},
+				{Address: "192.168.1.2:8080", Weight: intPtr(1)},
 			},
 		},
 	}
@@ -465,7 +753,7 @@ func TestManager_FullLifecycle(t *testing.T) {
 
 	// Register backend manually
 	mgr.mu.Lock()
-	mgr.statuses["192.168.1.1:8080"] = &backendStatus{
+	mgr.statuses[probeKey{address: "192.168.1.1:8080"}] = &backendStatus{
 		address: "192.168.1.1:8080",
 		healthy: true,
 	}
@@ -501,3 +789,138 @@ func TestManager_FullLifecycle(t *testing.T) {
 	// Allow goroutines to settle
 	time.Sleep(10 * time.Millisecond)
 }
+
+// --- Certificate expiry tests ---
+
+func TestObserveCertificate_WarnsOncePerCertificate(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	mgr := NewManager(nil, zap.New(core))
+	mgr.statuses[probeKey{address: "192.168.1.1:443"}] = &backendStatus{address: "192.168.1.1:443", healthy: true}
+
+	expiring := &x509.Certificate{NotAfter: time.Now().Add(3 * 24 * time.Hour)}
+	mgr.observeCertificate(probeKey{address: "192.168.1.1:443"}, expiring, 14*24*time.Hour)
+	mgr.observeCertificate(probeKey{address: "192.168.1.1:443"}, expiring, 14*24*time.Hour)
+	if n := logs.FilterMessage("backend certificate expires soon").Len(); n != 1 {
+		t.Fatalf("expected a single expiry warning, got %d", n)
+	}
+
+	renewed := &x509.Certificate{NotAfter: time.Now().Add(90 * 24 * time.Hour)}
+	mgr.observeCertificate(probeKey{address: "192.168.1.1:443"}, renewed, 14*24*time.Hour)
+	if logs.Len() != 1 {
+		t.Errorf("expected no warning for a certificate outside the window, got %d logs", logs.Len())
+	}
+
+	expired := &x509.Certificate{NotAfter: time.Now().Add(-time.Hour)}
+	mgr.observeCertificate(probeKey{address: "192.168.1.1:443"}, expired, 14*24*time.Hour)
+	if n := logs.FilterMessage("backend certificate has expired").Len(); n != 1 {
+		t.Errorf("expected an expired certificate error, got %d", n)
+	}
+}
+
+// --- Shared probe tests ---
+
+func sharedTestService(name string, hc config.HealthCheckConfig, addresses ...string) config.ServiceConfig {
+	hc.Enabled = boolPtr(true)
+	svc := config.ServiceConfig{Name: name, Listen: "10.0.0.1:80", Protocol: "tcp", HealthCheck: hc}
+	for _, address := range addresses {
+		svc.Backends = append(svc.Backends, config.BackendConfig{Address: address, Weight: intPtr(1)})
+	}
+	return svc
+}
+
+func TestUpdateTargets_SharesProbeAcrossServices(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer mgr.Stop()
+
+	hc := config.HealthCheckConfig{Interval: "1h", Timeout: "50ms"}
+	svcA := sharedTestService("svc-a", hc, "192.168.1.1:8080")
+	svcB := sharedTestService("svc-b", hc, "192.168.1.1:8080")
+	mgr.UpdateTargets(ctx, []config.ServiceConfig{svcA, svcB})
+
+	mgr.mu.RLock()
+	if len(mgr.statuses) != 1 {
+		t.Fatalf("expected one shared probe, got %d", len(mgr.statuses))
+	}
+	status, _ := trackedStatus(mgr, "192.168.1.1:8080")
+	if len(status.services) != 2 {
+		t.Errorf("expected the probe to be referenced by 2 services, got %v", status.services)
+	}
+	mgr.mu.RUnlock()
+
+	// Dropping one service keeps the probe, and its state, for the other
+	mgr.UpdateTargets(ctx, []config.ServiceConfig{svcB})
+	mgr.mu.RLock()
+	remaining, exists := trackedStatus(mgr, "192.168.1.1:8080")
+	if !exists || remaining != status || len(remaining.services) != 1 || !remaining.services["svc-b"] {
+		t.Errorf("expected the probe to be kept for svc-b only, got %+v", remaining)
+	}
+	mgr.mu.RUnlock()
+
+	// Dropping the last service stops it
+	mgr.UpdateTargets(ctx, nil)
+	mgr.mu.RLock()
+	defer mgr.mu.RUnlock()
+	if len(mgr.statuses) != 0 {
+		t.Errorf("expected no probes after the last service is removed, got %d", len(mgr.statuses))
+	}
+}
+
+func TestUpdateTargets_ProbesPerDistinctProfile(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer mgr.Stop()
+
+	tcp := sharedTestService("tcp-svc", config.HealthCheckConfig{Interval: "1h", HTTPPath: "/ignored"}, "192.168.1.1:8080")
+	tcpSame := sharedTestService("tcp-svc-2", config.HealthCheckConfig{Interval: "1h"}, "192.168.1.1:8080")
+	http := sharedTestService("http-svc", config.HealthCheckConfig{Type: "http", Interval: "1h", HTTPPath: "/healthz"}, "192.168.1.1:8080")
+	mgr.UpdateTargets(ctx, []config.ServiceConfig{tcp, tcpSame, http})
+
+	mgr.mu.Lock()
+	if len(mgr.statuses) != 2 {
+		mgr.mu.Unlock()
+		t.Fatalf("expected one probe per distinct profile, got %d", len(mgr.statuses))
+	}
+	// The http probe fails while the tcp probe stays healthy
+	httpKey := probeKey{address: "192.168.1.1:8080", profile: mgr.services["http-svc"].profile}
+	mgr.statuses[httpKey].healthy = false
+	mgr.mu.Unlock()
+
+	if !mgr.IsServiceHealthy("tcp-svc", "192.168.1.1:8080") || !mgr.IsServiceHealthy("tcp-svc-2", "192.168.1.1:8080") {
+		t.Error("expected the backend to be healthy for the tcp services")
+	}
+	if mgr.IsServiceHealthy("http-svc", "192.168.1.1:8080") {
+		t.Error("expected the backend to be unhealthy for the http service")
+	}
+	if mgr.IsHealthy("192.168.1.1:8080") {
+		t.Error("expected the address-wide view to report the failing probe")
+	}
+	if healthy := mgr.Snapshot()["192.168.1.1:8080"]; healthy {
+		t.Error("expected the snapshot to report the failing probe")
+	}
+}
+
+func TestUpdateTargets_ProfileChangeRestartsProbe(t *testing.T) {
+	mgr := NewManager(nil, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer mgr.Stop()
+
+	mgr.UpdateTargets(ctx, []config.ServiceConfig{
+		sharedTestService("svc", config.HealthCheckConfig{Interval: "1h"}, "192.168.1.1:8080"),
+	})
+	mgr.UpdateTargets(ctx, []config.ServiceConfig{
+		sharedTestService("svc", config.HealthCheckConfig{Interval: "30m"}, "192.168.1.1:8080"),
+	})
+
+	mgr.mu.RLock()
+	defer mgr.mu.RUnlock()
+	if len(mgr.statuses) != 1 {
+		t.Fatalf("expected the old profile's probe to be replaced, got %d probes", len(mgr.statuses))
+	}
+	if mgr.services["svc"].interval != 30*time.Minute {
+		t.Errorf("expected the new interval, got %v", mgr.services["svc"].interval)
+	}
+}
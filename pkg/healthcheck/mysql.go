@@ -0,0 +1,155 @@
+package healthcheck
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// mysqlProbeUser is the user name the MySQL checker logs in with.
+const mysqlProbeUser = "ezlb"
+
+// MySQL client capability flags sent in the handshake response.
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSecureConnection = 0x00008000
+)
+
+// mysqlAuthErrors are the error codes a server answers a login with when it
+// is up but rejects the probe's credentials: access denied, unsupported
+// authentication method and secure transport required.
+var mysqlAuthErrors = map[uint16]bool{
+	1045: true,
+	1251: true,
+	3159: true,
+}
+
+// MySQLChecker implements health checking by completing the MySQL protocol
+// handshake with a backend. Unlike a TCP check, it fails on a server that
+// accepts connections but refuses clients, e.g. with too many connections or
+// a blocked host, or does not speak the protocol.
+//
+// The checker logs in as mysqlProbeUser without a password rather than
+// closing the connection after the server greeting: aborted handshakes count
+// towards max_connect_errors and would eventually get the director blocked,
+// rejected logins do not.
+type MySQLChecker struct {
+	timeout time.Duration
+}
+
+// NewMySQLChecker creates a new MySQLChecker with the given timeout.
+func NewMySQLChecker(timeout time.Duration) *MySQLChecker {
+	return &MySQLChecker{
+		timeout: timeout,
+	}
+}
+
+// Check performs the handshake with the given address. Returns nil if the
+// server sends a valid greeting and answers the login, including by denying
+// access (healthy), or an error otherwise (unhealthy).
+func (c *MySQLChecker) Check(address string) error {
+	conn, err := net.DialTimeout("tcp", address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("mysql health check failed for %s: %w", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if err := c.handshake(conn); err != nil {
+		return fmt.Errorf("mysql health check failed for %s: %w", address, err)
+	}
+	return nil
+}
+
+// handshake reads the server greeting, sends the login and reads its result.
+func (c *MySQLChecker) handshake(conn net.Conn) error {
+	greeting, seq, err := readMySQLPacket(conn)
+	if err != nil {
+		return err
+	}
+	if len(greeting) == 0 {
+		return errors.New("empty server greeting")
+	}
+	if greeting[0] == 0xff {
+		return mysqlError(greeting)
+	}
+	if greeting[0] != 10 {
+		return fmt.Errorf("unsupported protocol version %d", greeting[0])
+	}
+
+	if err := writeMySQLPacket(conn, seq+1, mysqlHandshakeResponse()); err != nil {
+		return err
+	}
+	result, _, err := readMySQLPacket(conn)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(result) == 0:
+		return errors.New("empty login result")
+	case result[0] == 0x00:
+		// Logged in: say goodbye so the server does not count an aborted connection
+		return writeMySQLPacket(conn, 0, []byte{0x01})
+	case result[0] == 0xff:
+		if len(result) >= 3 && mysqlAuthErrors[binary.LittleEndian.Uint16(result[1:])] {
+			return nil
+		}
+		return mysqlError(result)
+	}
+	// An authentication method switch or more authentication data
+	return nil
+}
+
+// mysqlHandshakeResponse encodes a protocol 4.1 login as mysqlProbeUser with
+// an empty password.
+func mysqlHandshakeResponse() []byte {
+	flags := uint32(mysqlClientLongPassword | mysqlClientProtocol41 | mysqlClientSecureConnection)
+	payload := binary.LittleEndian.AppendUint32(nil, flags)
+	payload = binary.LittleEndian.AppendUint32(payload, 1<<24) // max packet size
+	payload = append(payload, 33)                              // utf8_general_ci
+	payload = append(payload, make([]byte, 23)...)
+	payload = append(payload, mysqlProbeUser...)
+	return append(payload, 0, 0) // user terminator, empty auth response
+}
+
+// readMySQLPacket reads a MySQL protocol packet and returns its payload and
+// sequence number.
+func readMySQLPacket(conn net.Conn) ([]byte, byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, 0, err
+	}
+	length := uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, 0, err
+	}
+	return payload, header[3], nil
+}
+
+// writeMySQLPacket writes payload as a MySQL protocol packet with sequence
+// number seq.
+func writeMySQLPacket(conn net.Conn, seq byte, payload []byte) error {
+	length := len(payload)
+	packet := append([]byte{byte(length), byte(length >> 8), byte(length >> 16), seq}, payload...)
+	_, err := conn.Write(packet)
+	return err
+}
+
+// mysqlError formats an ERR packet.
+func mysqlError(packet []byte) error {
+	if len(packet) < 3 {
+		return errors.New("malformed error packet")
+	}
+	code := binary.LittleEndian.Uint16(packet[1:])
+	message := packet[3:]
+	// Protocol 4.1 prefixes the message with '#' and a five-character SQL state
+	if len(message) >= 6 && message[0] == '#' {
+		message = message[6:]
+	}
+	return fmt.Errorf("server error %d: %s", code, message)
+}
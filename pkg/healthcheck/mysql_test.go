@@ -0,0 +1,97 @@
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mysqlGreeting is a minimal protocol 10 server greeting.
+var mysqlGreeting = append([]byte{10}, "8.0.36\x00"...)
+
+// mysqlErrPacket encodes an ERR packet with code and message.
+func mysqlErrPacket(code uint16, message string) []byte {
+	packet := binary.LittleEndian.AppendUint16([]byte{0xff}, code)
+	return append(packet, "#HY000"+message...)
+}
+
+// startMySQLServer sends greeting and, if loginResult is set, answers the
+// login with it. Logins it receives are sent on the returned channel.
+func startMySQLServer(t *testing.T, greeting, loginResult []byte) (string, <-chan []byte) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	logins := make(chan []byte, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if writeMySQLPacket(conn, 0, greeting) == nil && loginResult != nil {
+				if login, seq, err := readMySQLPacket(conn); err == nil {
+					logins <- login
+					writeMySQLPacket(conn, seq+1, loginResult)
+					readMySQLPacket(conn)
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), logins
+}
+
+func TestMySQLChecker(t *testing.T) {
+	tests := []struct {
+		name        string
+		greeting    []byte
+		loginResult []byte
+		wantErr     string
+	}{
+		{"logged in", mysqlGreeting, []byte{0x00, 0, 0, 2, 0, 0, 0}, ""},
+		{"access denied", mysqlGreeting, mysqlErrPacket(1045, "Access denied for user 'ezlb'"), ""},
+		{"auth switch", mysqlGreeting, append([]byte{0xfe}, "caching_sha2_password\x00"...), ""},
+		{"too many connections", binary.LittleEndian.AppendUint16([]byte{0xff}, 1040), nil, "server error 1040"},
+		{"host blocked", mysqlGreeting, mysqlErrPacket(1129, "Host is blocked"), "server error 1129: Host is blocked"},
+		{"not mysql", []byte("SSH-2.0"), nil, "unsupported protocol version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, logins := startMySQLServer(t, tt.greeting, tt.loginResult)
+			err := NewMySQLChecker(time.Second).Check(address)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected successful health check, got error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.loginResult == nil {
+				return
+			}
+			login := <-logins
+			if !bytes.Contains(login, []byte(mysqlProbeUser+"\x00\x00")) {
+				t.Errorf("expected a login as %q without password, got %x", mysqlProbeUser, login)
+			}
+		})
+	}
+}
+
+func TestMySQLChecker_NoGreeting(t *testing.T) {
+	// A listener that accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start listener: %v", err)
+	}
+	defer listener.Close()
+
+	if err := NewMySQLChecker(50 * time.Millisecond).Check(listener.Addr().String()); err == nil {
+		t.Fatal("expected a silent server to fail the check")
+	}
+}
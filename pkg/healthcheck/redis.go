@@ -0,0 +1,53 @@
+package healthcheck
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// redisPing is the PING command in the Redis serialization protocol.
+const redisPing = "*1\r\n$4\r\nPING\r\n"
+
+// RedisChecker implements health checking by sending PING to a Redis server.
+// Unlike a TCP check, it fails while the server is still loading its dataset
+// (LOADING), is a replica that lost its master (MASTERDOWN) or is busy
+// running a script (BUSY).
+type RedisChecker struct {
+	timeout time.Duration
+}
+
+// NewRedisChecker creates a new RedisChecker with the given timeout.
+func NewRedisChecker(timeout time.Duration) *RedisChecker {
+	return &RedisChecker{
+		timeout: timeout,
+	}
+}
+
+// Check sends PING to the given address. Returns nil if the server answers
+// PONG (healthy), or an error if it answers with an error or not at all
+// (unhealthy). A server that requires authentication answers NOAUTH, which
+// also counts as healthy since the checker has no credentials.
+func (c *RedisChecker) Check(address string) error {
+	conn, err := net.DialTimeout("tcp", address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("redis health check failed for %s: %w", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err := conn.Write([]byte(redisPing)); err != nil {
+		return fmt.Errorf("redis health check failed for %s: %w", address, err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("redis health check failed for %s: %w", address, err)
+	}
+	reply = strings.TrimRight(reply, "\r\n")
+	if reply == "+PONG" || strings.HasPrefix(reply, "-NOAUTH") {
+		return nil
+	}
+	return fmt.Errorf("redis health check failed for %s: PING returned %q", address, reply)
+}
@@ -0,0 +1,76 @@
+package healthcheck
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startRedisServer answers every PING with reply and returns its address.
+func startRedisServer(t *testing.T, reply string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, len(redisPing))
+			if _, err := bufio.NewReader(conn).Read(buf); err == nil && string(buf) == redisPing {
+				conn.Write([]byte(reply))
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedisChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		wantErr string
+	}{
+		{"pong", "+PONG\r\n", ""},
+		{"auth required", "-NOAUTH Authentication required.\r\n", ""},
+		{"loading", "-LOADING Redis is loading the dataset in memory\r\n", "PING returned \"-LOADING"},
+		{"master down", "-MASTERDOWN Link with MASTER is down\r\n", "PING returned \"-MASTERDOWN"},
+		{"not redis", "SSH-2.0-OpenSSH_9.6\r\n", "PING returned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewRedisChecker(time.Second)
+			err := checker.Check(startRedisServer(t, tt.reply))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected successful health check, got error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRedisChecker_NoReply(t *testing.T) {
+	// A listener that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start listener: %v", err)
+	}
+	defer listener.Close()
+
+	checker := NewRedisChecker(50 * time.Millisecond)
+	if err := checker.Check(listener.Addr().String()); err == nil {
+		t.Fatal("expected a silent server to fail the check")
+	}
+}
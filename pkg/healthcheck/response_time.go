@@ -0,0 +1,85 @@
+package healthcheck
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/metrics"
+)
+
+// responseTimeSamples is how many recent HTTP check response times are kept
+// per probe for the percentiles shown in status.
+const responseTimeSamples = 100
+
+// ResponseTimes summarizes the response times of the recent HTTP checks of a
+// backend. Only probes the backend answered are counted.
+type ResponseTimes struct {
+	Samples int
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// recordResponseTime adds the duration of an answered HTTP check to the
+// backend's recent samples and to the response time histogram of every
+// service using the probe.
+func (m *Manager) recordResponseTime(key probeKey, latency time.Duration) {
+	m.mu.Lock()
+	status, exists := m.statuses[key]
+	var services []string
+	if exists {
+		if len(status.responseTimes) < responseTimeSamples {
+			status.responseTimes = append(status.responseTimes, latency)
+		} else {
+			status.responseTimes[status.responseTimeNext] = latency
+		}
+		status.responseTimeNext = (status.responseTimeNext + 1) % responseTimeSamples
+		services = sortedNames(status.services)
+	}
+	m.mu.Unlock()
+
+	for _, service := range services {
+		metrics.ObserveBackendResponseTime(service, key.address, latency.Seconds())
+	}
+}
+
+// ServiceResponseTimes returns the recent HTTP check response times of a
+// backend according to the health check of the given service. It reports
+// false if the service does not use an HTTP check or no probe was answered yet.
+func (m *Manager) ServiceResponseTimes(service, address string) (ResponseTimes, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.serviceStatusLocked(service, address)
+	if status == nil || len(status.responseTimes) == 0 {
+		return ResponseTimes{}, false
+	}
+	sorted := slices.Clone(status.responseTimes)
+	slices.Sort(sorted)
+	return ResponseTimes{
+		Samples: len(sorted),
+		P50:     percentile(sorted, 50),
+		P90:     percentile(sorted, 90),
+		P99:     percentile(sorted, 99),
+		Max:     sorted[len(sorted)-1],
+	}, true
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// answered reports whether a checker result means the backend sent an HTTP
+// response whose duration is worth recording: a pass, or a degraded answer
+// such as a 5xx or Retry-After.
+func answered(checkErr error) bool {
+	var degradedErr *DegradedError
+	return checkErr == nil || errors.As(checkErr, &degradedErr)
+}
@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// consulLock holds a Consul KV key through a session. The session is created
+// with behavior "delete", so the key goes away with it once its TTL lapses.
+type consulLock struct {
+	client
+	key     string
+	holder  string
+	ttl     time.Duration
+	session string
+}
+
+func (l *consulLock) TryAcquire(ctx context.Context) (bool, error) {
+	if l.session == "" {
+		var created struct {
+			ID string `json:"ID"`
+		}
+		body := map[string]string{
+			"Name":     "ezlb-lock-" + l.holder,
+			"TTL":      fmt.Sprintf("%ds", int(l.ttl/time.Second)),
+			"Behavior": "delete",
+		}
+		if err := l.do(ctx, http.MethodPut, "/v1/session/create", body, &created); err != nil {
+			return false, fmt.Errorf("failed to create consul session: %w", err)
+		}
+		l.session = created.ID
+	}
+
+	var acquired bool
+	path := "/v1/kv/" + l.key + "?acquire=" + url.QueryEscape(l.session)
+	if err := l.do(ctx, http.MethodPut, path, l.holder, &acquired); err != nil {
+		return false, fmt.Errorf("failed to acquire consul lock %q: %w", l.key, err)
+	}
+	return acquired, nil
+}
+
+func (l *consulLock) Release(ctx context.Context) error {
+	if l.session == "" {
+		return nil
+	}
+	path := "/v1/kv/" + l.key + "?release=" + url.QueryEscape(l.session)
+	if err := l.do(ctx, http.MethodPut, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to release consul lock %q: %w", l.key, err)
+	}
+	if err := l.do(ctx, http.MethodPut, "/v1/session/destroy/"+url.PathEscape(l.session), nil, nil); err != nil {
+		return fmt.Errorf("failed to destroy consul session: %w", err)
+	}
+	l.session = ""
+	return nil
+}
@@ -0,0 +1,70 @@
+package lock
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// etcdLock holds an etcd key attached to a lease through the v3 JSON gateway.
+// The key is created only if it does not exist, and goes away with the lease
+// once its TTL lapses.
+type etcdLock struct {
+	client
+	key    string
+	holder string
+	ttl    time.Duration
+	lease  string
+}
+
+func (l *etcdLock) TryAcquire(ctx context.Context) (bool, error) {
+	if l.lease == "" {
+		var granted struct {
+			ID string `json:"ID"`
+		}
+		body := map[string]string{"TTL": strconv.Itoa(int(l.ttl / time.Second))}
+		if err := l.do(ctx, http.MethodPost, "/v3/lease/grant", body, &granted); err != nil {
+			return false, fmt.Errorf("failed to grant etcd lease: %w", err)
+		}
+		l.lease = granted.ID
+	}
+
+	key := base64.StdEncoding.EncodeToString([]byte(l.key))
+	txn := map[string]any{
+		"compare": []map[string]any{{
+			"key":             key,
+			"target":          "CREATE",
+			"result":          "EQUAL",
+			"create_revision": "0",
+		}},
+		"success": []map[string]any{{
+			"request_put": map[string]string{
+				"key":   key,
+				"value": base64.StdEncoding.EncodeToString([]byte(l.holder)),
+				"lease": l.lease,
+			},
+		}},
+	}
+	var result struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := l.do(ctx, http.MethodPost, "/v3/kv/txn", txn, &result); err != nil {
+		return false, fmt.Errorf("failed to acquire etcd lock %q: %w", l.key, err)
+	}
+	return result.Succeeded, nil
+}
+
+func (l *etcdLock) Release(ctx context.Context) error {
+	if l.lease == "" {
+		return nil
+	}
+	// Revoking the lease deletes the key attached to it
+	if err := l.do(ctx, http.MethodPost, "/v3/lease/revoke", map[string]string{"ID": l.lease}, nil); err != nil {
+		return fmt.Errorf("failed to release etcd lock %q: %w", l.key, err)
+	}
+	l.lease = ""
+	return nil
+}
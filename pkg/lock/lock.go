@@ -0,0 +1,122 @@
+// Package lock implements the distributed lock `ezlb once` acquires before
+// applying changes, so that when many hosts run it against shared state only
+// one of them applies at a time. Consul and etcd are reached through their
+// HTTP APIs.
+package lock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/secret"
+)
+
+// retryInterval is how often a lock held by another node is retried while
+// waiting for it.
+const retryInterval = time.Second
+
+// Locker is a lock held by at most one node at a time.
+type Locker interface {
+	// TryAcquire makes one attempt to take the lock and reports whether it
+	// is now held by this node.
+	TryAcquire(ctx context.Context) (bool, error)
+	// Release gives up the lock, if held.
+	Release(ctx context.Context) error
+}
+
+// New returns the Locker for the configured backend. holder identifies this
+// node in the lock's value, e.g. its hostname.
+func New(cfg config.LockConfig, holder string) (Locker, error) {
+	c := client{
+		address: strings.TrimRight(cfg.Address, "/"),
+		token:   secret.New(cfg.Token),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	switch cfg.Backend {
+	case "consul":
+		c.authHeader = "X-Consul-Token"
+		return &consulLock{client: c, key: strings.TrimLeft(cfg.Key, "/"), holder: holder, ttl: cfg.GetTTL()}, nil
+	case "etcd":
+		c.authHeader = "Authorization"
+		return &etcdLock{client: c, key: cfg.Key, holder: holder, ttl: cfg.GetTTL()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported lock backend %q", cfg.Backend)
+	}
+}
+
+// Acquire takes the lock, retrying while another node holds it until wait
+// elapses. It reports false if the lock is still held elsewhere by then.
+func Acquire(ctx context.Context, l Locker, wait time.Duration) (bool, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		held, err := l.TryAcquire(ctx)
+		if err != nil || held {
+			return held, err
+		}
+		if !time.Now().Add(retryInterval).Before(deadline) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// client issues JSON requests against a lock backend's HTTP API.
+type client struct {
+	address string
+	token   *secret.Value
+	http    *http.Client
+	// authHeader is the header carrying token, set by each backend.
+	authHeader string
+}
+
+// do sends body (if not nil) as JSON and decodes a 2xx response into out (if
+// not nil).
+func (c client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	token, err := c.token.Get()
+	if err != nil {
+		return fmt.Errorf("failed to read lock token: %w", err)
+	}
+	if token != "" {
+		req.Header.Set(c.authHeader, token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package lock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// fakeConsul implements the session and KV endpoints used by consulLock.
+type fakeConsul struct {
+	mu       sync.Mutex
+	sessions int
+	holder   string // session holding the key
+	token    string
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = r.Header.Get("X-Consul-Token")
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		f.sessions++
+		json.NewEncoder(w).Encode(map[string]string{"ID": "session-" + strconv.Itoa(f.sessions)})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		if strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/") == f.holder {
+			f.holder = ""
+		}
+		w.Write([]byte("true"))
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		if session := r.URL.Query().Get("acquire"); session != "" {
+			if f.holder == "" {
+				f.holder = session
+			}
+			json.NewEncoder(w).Encode(f.holder == session)
+			return
+		}
+		if session := r.URL.Query().Get("release"); session == f.holder {
+			f.holder = ""
+		}
+		w.Write([]byte("true"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestConsulLock(t *testing.T) {
+	fake := &fakeConsul{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := config.LockConfig{Backend: "consul", Address: server.URL, Key: "ezlb/once", Token: "secret"}
+	first, err := New(cfg, "node-a")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	second, _ := New(cfg, "node-b")
+
+	if held, err := first.TryAcquire(context.Background()); err != nil || !held {
+		t.Fatalf("expected node-a to acquire the lock, got %t, %v", held, err)
+	}
+	if fake.token != "secret" {
+		t.Errorf("expected the ACL token to be sent, got %q", fake.token)
+	}
+	if held, err := second.TryAcquire(context.Background()); err != nil || held {
+		t.Fatalf("expected node-b not to acquire a held lock, got %t, %v", held, err)
+	}
+
+	if err := first.Release(context.Background()); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if held, err := second.TryAcquire(context.Background()); err != nil || !held {
+		t.Fatalf("expected node-b to acquire the released lock, got %t, %v", held, err)
+	}
+}
+
+// fakeEtcd implements the lease and txn endpoints of the etcd v3 JSON gateway
+// used by etcdLock.
+type fakeEtcd struct {
+	mu     sync.Mutex
+	leases int
+	key    string
+	value  string
+	lease  string
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.leases++
+		json.NewEncoder(w).Encode(map[string]string{"ID": strconv.Itoa(f.leases), "TTL": body["TTL"].(string)})
+	case "/v3/lease/revoke":
+		if body["ID"] == f.lease {
+			f.key, f.value, f.lease = "", "", ""
+		}
+		w.Write([]byte("{}"))
+	case "/v3/kv/txn":
+		succeeded := f.key == ""
+		if succeeded {
+			put := body["success"].([]any)[0].(map[string]any)["request_put"].(map[string]any)
+			key, _ := base64.StdEncoding.DecodeString(put["key"].(string))
+			value, _ := base64.StdEncoding.DecodeString(put["value"].(string))
+			f.key, f.value, f.lease = string(key), string(value), put["lease"].(string)
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"succeeded": succeeded})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestConsulLock_TokenFromFile(t *testing.T) {
+	fake := &fakeConsul{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.LockConfig{Backend: "consul", Address: server.URL, Key: "ezlb/once", Token: "file:" + tokenFile}
+	locker, err := New(cfg, "node-a")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if held, err := locker.TryAcquire(context.Background()); err != nil || !held {
+		t.Fatalf("expected the lock to be acquired, got %t, %v", held, err)
+	}
+	if fake.token != "from-file" {
+		t.Errorf("expected the token from the file to be sent, got %q", fake.token)
+	}
+}
+
+func TestEtcdLock(t *testing.T) {
+	fake := &fakeEtcd{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := config.LockConfig{Backend: "etcd", Address: server.URL, Key: "/ezlb/once"}
+	first, _ := New(cfg, "node-a")
+	second, _ := New(cfg, "node-b")
+
+	if held, err := first.TryAcquire(context.Background()); err != nil || !held {
+		t.Fatalf("expected node-a to acquire the lock, got %t, %v", held, err)
+	}
+	if fake.key != "/ezlb/once" || fake.value != "node-a" {
+		t.Errorf("expected the key to name its holder, got %q=%q", fake.key, fake.value)
+	}
+	if held, err := second.TryAcquire(context.Background()); err != nil || held {
+		t.Fatalf("expected node-b not to acquire a held lock, got %t, %v", held, err)
+	}
+
+	// Releasing a lock never acquired must not delete the holder's key
+	if err := second.Release(context.Background()); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if fake.value != "node-a" {
+		t.Fatal("expected node-a to still hold the lock")
+	}
+
+	if err := first.Release(context.Background()); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if held, err := second.TryAcquire(context.Background()); err != nil || !held {
+		t.Fatalf("expected node-b to acquire the released lock, got %t, %v", held, err)
+	}
+}
+
+func TestAcquire_GivesUpAfterWait(t *testing.T) {
+	server := httptest.NewServer(&fakeConsul{holder: "other-session"})
+	defer server.Close()
+
+	locker, _ := New(config.LockConfig{Backend: "consul", Address: server.URL, Key: "ezlb/once"}, "node-a")
+	start := time.Now()
+	held, err := Acquire(context.Background(), locker, 0)
+	if err != nil || held {
+		t.Fatalf("expected the lock not to be acquired, got %t, %v", held, err)
+	}
+	if elapsed := time.Since(start); elapsed > retryInterval {
+		t.Errorf("expected a single attempt without wait, took %v", elapsed)
+	}
+}
+
+func TestAcquire_BackendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "permission denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	locker, _ := New(config.LockConfig{Backend: "etcd", Address: server.URL, Key: "ezlb"}, "node-a")
+	_, err := Acquire(context.Background(), locker, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected a 403 error, got: %v", err)
+	}
+}
@@ -14,6 +14,9 @@ func ServiceFields(svc config.ServiceConfig) []zap.Field {
 		zap.String("protocol", svc.Protocol),
 		zap.String("scheduler", svc.Scheduler),
 	}
+	if svc.FWMark != 0 {
+		fields = append(fields, zap.Uint32("fwmark", svc.FWMark))
+	}
 	if svc.FullNAT {
 		fields = append(fields, zap.Bool("full_nat", svc.FullNAT))
 		if svc.SnatIP != "" {
@@ -41,7 +41,7 @@ func TestBackendFields(t *testing.T) {
 	}
 	backend := config.BackendConfig{
 		Address: "192.168.1.10:8080",
-		Weight:  5,
+		Weight:  intPtr(5),
 	}
 
 	fields := BackendFields(svc, backend)
@@ -104,6 +104,11 @@ func TestServiceFields_FullNATWithoutSnatIP(t *testing.T) {
 	}
 }
 
+// intPtr creates a pointer to an int value.
+func intPtr(i int) *int {
+	return &i
+}
+
 // fieldsToMap converts zap fields to a map for easy assertion.
 func fieldsToMap(fields []zap.Field) map[string]string {
 	enc := zapcore.NewMapObjectEncoder()
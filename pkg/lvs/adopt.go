@@ -0,0 +1,123 @@
+package lvs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// ParseServiceKey parses a service key in the form printed by
+// ServiceKey.String: VIP:port/protocol, e.g. 10.0.0.1:80/tcp or
+// [2001:db8::1]:443/tcp, or fwmark:MARK with an optional /ipv6 suffix.
+func ParseServiceKey(s string) (ServiceKey, error) {
+	if mark, ok := strings.CutPrefix(s, "fwmark:"); ok {
+		address := net.IPv4zero.String()
+		if m, ok := strings.CutSuffix(mark, "/ipv6"); ok {
+			mark, address = m, net.IPv6unspecified.String()
+		}
+		value, err := strconv.ParseUint(mark, 10, 32)
+		if err != nil || value == 0 {
+			return ServiceKey{}, fmt.Errorf("invalid service %q: fwmark must be a positive integer", s)
+		}
+		return ServiceKey{Address: address, FWMark: uint32(value)}, nil
+	}
+
+	address, protocolName, ok := strings.Cut(s, "/")
+	if !ok {
+		return ServiceKey{}, fmt.Errorf("invalid service %q: expected VIP:port/protocol or fwmark:MARK", s)
+	}
+	protocol, err := protocolFromString(protocolName)
+	if err != nil {
+		return ServiceKey{}, fmt.Errorf("invalid service %q: %w", s, err)
+	}
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return ServiceKey{}, fmt.Errorf("invalid service %q: %w", s, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ServiceKey{}, fmt.Errorf("invalid service %q: invalid IP address %q", s, host)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return ServiceKey{}, fmt.Errorf("invalid service %q: invalid port %q", s, portStr)
+	}
+	return ServiceKey{Address: ip.String(), Port: uint16(port), Protocol: protocol}, nil
+}
+
+// IPVSToServiceConfig builds the config of an existing IPVS service and its
+// destinations, so that ezlb can take it over: reconciling the returned
+// config leaves the service unchanged. Settings at their defaults are left
+// unset. A forwarding method shared by every destination is set on the
+// service. Services using an IPVS persistence engine cannot be expressed in
+// the config and are rejected.
+func IPVSToServiceConfig(name string, svc *Service, dests []*Destination) (config.ServiceConfig, error) {
+	if svc.PEName != "" {
+		return config.ServiceConfig{}, fmt.Errorf("service %s uses persistence engine %q, which ezlb does not support", ServiceKeyFromIPVS(svc), svc.PEName)
+	}
+
+	svcCfg := config.ServiceConfig{Name: name, Scheduler: svc.SchedName}
+	if svc.FWMark != 0 {
+		svcCfg.FWMark = svc.FWMark
+	} else {
+		svcCfg.Listen = net.JoinHostPort(svc.Address.String(), strconv.Itoa(int(svc.Port)))
+		svcCfg.Protocol = protocolToString(svc.Protocol)
+	}
+	svcCfg.OPS = svc.Flags&ServiceFlagOnePacket != 0
+	if svc.Flags&ServiceFlagPersistent != 0 {
+		svcCfg.Persistence.Timeout = fmt.Sprintf("%ds", svc.Timeout)
+		if ones := prefixFromNetmask(svc.AddressFamily, svc.Netmask); ones != netmaskBits(svc.AddressFamily) {
+			svcCfg.Persistence.Netmask = strconv.Itoa(ones)
+		}
+	}
+
+	methods := make(map[string]bool)
+	for _, dst := range dests {
+		method := forwardMethodFromFlags(dst.ConnectionFlags)
+		if _, err := forwardMethodToFlags(method); err != nil {
+			return config.ServiceConfig{}, fmt.Errorf("destination %s: %w", DestinationKeyFromIPVS(dst), err)
+		}
+		methods[method] = true
+		weight := dst.Weight
+		svcCfg.Backends = append(svcCfg.Backends, config.BackendConfig{
+			Address:       DestinationKeyFromIPVS(dst).String(),
+			ForwardMethod: method,
+			Weight:        &weight,
+		})
+	}
+	if len(methods) == 1 {
+		for i := range svcCfg.Backends {
+			svcCfg.ForwardMethod = svcCfg.Backends[i].ForwardMethod
+			svcCfg.Backends[i].ForwardMethod = ""
+		}
+	}
+	if svcCfg.ForwardMethod == "nat" {
+		svcCfg.ForwardMethod = ""
+	}
+	return svcCfg, nil
+}
+
+// prefixFromNetmask returns the prefix length of an IPVS persistence
+// netmask; it is the inverse of netmaskFromPrefix.
+func prefixFromNetmask(family uint16, netmask uint32) int {
+	if family != syscall.AF_INET {
+		return int(netmask)
+	}
+	mask := make(net.IPMask, net.IPv4len)
+	binary.NativeEndian.PutUint32(mask, netmask)
+	ones, _ := mask.Size()
+	return ones
+}
+
+// netmaskBits returns the host prefix length of an address family.
+func netmaskBits(family uint16) int {
+	if family == syscall.AF_INET {
+		return net.IPv4len * 8
+	}
+	return net.IPv6len * 8
+}
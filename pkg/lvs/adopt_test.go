@@ -0,0 +1,99 @@
+package lvs
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestParseServiceKey(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ServiceKey
+		wantErr bool
+	}{
+		{input: "10.0.0.1:80/tcp", want: ServiceKey{Address: "10.0.0.1", Port: 80, Protocol: syscall.IPPROTO_TCP}},
+		{input: "[2001:db8::1]:53/udp", want: ServiceKey{Address: "2001:db8::1", Port: 53, Protocol: syscall.IPPROTO_UDP}},
+		{input: "fwmark:100", want: ServiceKey{Address: "0.0.0.0", FWMark: 100}},
+		{input: "fwmark:100/ipv6", want: ServiceKey{Address: "::", FWMark: 100}},
+		{input: "10.0.0.1:80", wantErr: true},
+		{input: "10.0.0.1:80/sctp", wantErr: true},
+		{input: "10.0.0.1:0/tcp", wantErr: true},
+		{input: "web:80/tcp", wantErr: true},
+		{input: "fwmark:0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseServiceKey(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseServiceKey(%q): unexpected error %v", tt.input, err)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseServiceKey(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+		if !tt.wantErr && got.String() != tt.input {
+			t.Errorf("ParseServiceKey(%q).String() = %q", tt.input, got.String())
+		}
+	}
+}
+
+func TestIPVSToServiceConfig_RoundTrip(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	// A service set up outside ezlb, e.g. with ipvsadm
+	original := makeServiceConfig("manual", "10.0.0.1:80", "wlc", false,
+		makeBackend("192.168.1.1:8080", 3),
+		makeBackend("192.168.1.2:8080", 1))
+	original.ForwardMethod = "dr"
+	original.Persistence = config.PersistenceConfig{Timeout: "600s", Netmask: "24"}
+	// Validation hands the service's forward_method down to its backends
+	cfg := &config.Config{Services: []config.ServiceConfig{original}}
+	if err := config.Validate(cfg); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if err := reconciler.Reconcile(cfg.Services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	key, _ := ParseServiceKey("10.0.0.1:80/tcp")
+	svc, err := mgr.GetService(key)
+	if err != nil {
+		t.Fatalf("GetService failed: %v", err)
+	}
+	dests, _ := mgr.GetDestinations(svc)
+	adopted, err := IPVSToServiceConfig("web", svc, dests)
+	if err != nil {
+		t.Fatalf("IPVSToServiceConfig failed: %v", err)
+	}
+
+	want := config.ServiceConfig{
+		Name:          "web",
+		Listen:        "10.0.0.1:80",
+		Protocol:      "tcp",
+		Scheduler:     "wlc",
+		ForwardMethod: "dr",
+		Persistence:   config.PersistenceConfig{Timeout: "600s", Netmask: "24"},
+	}
+	for _, dst := range dests {
+		want.Backends = append(want.Backends, config.BackendConfig{Address: DestinationKeyFromIPVS(dst).String(), Weight: intPtr(dst.Weight)})
+	}
+	if !reflect.DeepEqual(adopted, want) {
+		t.Fatalf("unexpected adopted config:\ngot:  %+v\nwant: %+v", adopted, want)
+	}
+
+	// Managing the adopted config leaves the service as it is
+	adopted.HealthCheck.Enabled = boolPtr(false)
+	if err := config.Validate(&config.Config{Services: []config.ServiceConfig{adopted}}); err != nil {
+		t.Fatalf("adopted config is invalid: %v", err)
+	}
+	fresh := NewReconciler(mgr, newMockHealthChecker(), reconciler.snatMgr, reconciler.logger)
+	if err := fresh.Reconcile([]config.ServiceConfig{adopted}); err != nil {
+		t.Fatalf("Reconcile of the adopted config failed: %v", err)
+	}
+	if ops := fresh.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected no changes when managing the adopted service, got %v", ops)
+	}
+}
@@ -0,0 +1,90 @@
+package lvs
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// DestinationOp is one destination change of a batch: ActionCreate,
+// ActionUpdate or ActionDelete.
+type DestinationOp struct {
+	Action      string
+	Destination *Destination
+}
+
+// batchHandle is implemented by an IPVSHandle that applies the destination
+// changes of one service more cheaply than one call each.
+type batchHandle interface {
+	ApplyDestinations(svc *Service, ops []DestinationOp) []error
+}
+
+// ApplyDestinations applies destination changes of one service in order and
+// returns the error of each, nil where it succeeded. A failed change does not
+// stop the following ones. It is equivalent to calling CreateDestination,
+// UpdateDestination and DeleteDestination for each change, but the service is
+// encoded for netlink once and the changes are logged at debug level with a
+// single summary, which matters for services with thousands of destinations.
+func (m *Manager) ApplyDestinations(svc *Service, ops []DestinationOp) []error {
+	if len(ops) == 0 {
+		return nil
+	}
+	errs := make([]error, len(ops))
+	if m.readOnly.Load() {
+		for i := range errs {
+			errs[i] = ErrReadOnly
+		}
+	} else if batch, ok := m.handle.(batchHandle); ok {
+		defer m.invalidateDestinations(svc)
+		copy(errs, batch.ApplyDestinations(svc, ops))
+	} else {
+		defer m.invalidateDestinations(svc)
+		for i, op := range ops {
+			errs[i] = applyDestinationOp(m.handle, svc, op)
+		}
+	}
+
+	service := fmt.Sprintf("%s:%d", svc.Address, svc.Port)
+	debug := m.logger.Core().Enabled(zap.DebugLevel)
+	counts := make(map[string]int)
+	failed := 0
+	for i, op := range ops {
+		dst := op.Destination
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("failed to %s destination %s:%d for service %s: %w",
+				op.Action, dst.Address, dst.Port, service, errs[i])
+			failed++
+			continue
+		}
+		counts[op.Action]++
+		if debug {
+			m.logger.Debug(op.Action+"d IPVS destination",
+				zap.String("service", service),
+				zap.String("destination", fmt.Sprintf("%s:%d", dst.Address, dst.Port)),
+				zap.Int("weight", dst.Weight),
+			)
+		}
+	}
+	m.logger.Info("applied IPVS destination changes",
+		zap.String("service", service),
+		zap.Int("created", counts[ActionCreate]),
+		zap.Int("updated", counts[ActionUpdate]),
+		zap.Int("deleted", counts[ActionDelete]),
+		zap.Int("failed", failed),
+	)
+	return errs
+}
+
+// applyDestinationOp applies a single destination change through handle.
+func applyDestinationOp(handle IPVSHandle, svc *Service, op DestinationOp) error {
+	switch op.Action {
+	case ActionCreate:
+		return handle.NewDestination(svc, op.Destination)
+	case ActionUpdate:
+		return handle.UpdateDestination(svc, op.Destination)
+	case ActionDelete:
+		return handle.DelDestination(svc, op.Destination)
+	default:
+		return fmt.Errorf("unknown destination action %q", op.Action)
+	}
+}
@@ -0,0 +1,224 @@
+package lvs
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// benchSizes are the config sizes, services x backends per service, that the
+// reconcile benchmarks run against.
+var benchSizes = []struct {
+	services int
+	backends int
+}{
+	{services: 10, backends: 10},
+	{services: 100, backends: 10},
+	{services: 500, backends: 20},
+}
+
+// syntheticServices generates a config of n services with m backends each.
+// VIPs and backend addresses are unique, and health checks are disabled so
+// the benchmarks measure the reconciler rather than health lookups.
+func syntheticServices(n, m int) []config.ServiceConfig {
+	services := make([]config.ServiceConfig, n)
+	for i := range services {
+		backends := make([]config.BackendConfig, m)
+		for j := range backends {
+			backends[j] = makeBackend(fmt.Sprintf("172.%d.%d.%d:8080", 16+i/256, i%256, j+1), 1+j%5)
+		}
+		services[i] = makeServiceConfig(fmt.Sprintf("svc-%d", i),
+			fmt.Sprintf("10.%d.%d.1:80", i/256, i%256), "wrr", false, backends...)
+	}
+	return services
+}
+
+// benchEach runs fn as a sub-benchmark for every benchmark size.
+func benchEach(b *testing.B, fn func(b *testing.B, configs []config.ServiceConfig)) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("%dx%d", size.services, size.backends), func(b *testing.B) {
+			fn(b, syntheticServices(size.services, size.backends))
+		})
+	}
+}
+
+// BenchmarkBuildDesiredState measures converting the config into the desired
+// IPVS state, without touching IPVS.
+func BenchmarkBuildDesiredState(b *testing.B) {
+	benchEach(b, func(b *testing.B, configs []config.ServiceConfig) {
+		mgr, _, reconciler := newReconcilerTestEnv(b)
+		defer mgr.Close()
+
+		b.ReportAllocs()
+		for b.Loop() {
+			reconciler.mu.Lock()
+			_, err := reconciler.buildDesiredState(configs)
+			reconciler.mu.Unlock()
+			if err != nil {
+				b.Fatalf("buildDesiredState failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkDrift measures diffing the desired state against IPVS rules that
+// are already in sync.
+func BenchmarkDrift(b *testing.B) {
+	benchEach(b, func(b *testing.B, configs []config.ServiceConfig) {
+		mgr, _, reconciler := newReconcilerTestEnv(b)
+		defer mgr.Close()
+		if err := reconciler.Reconcile(configs); err != nil {
+			b.Fatalf("Reconcile failed: %v", err)
+		}
+
+		b.ReportAllocs()
+		for b.Loop() {
+			drifts, err := reconciler.Drift(configs)
+			if err != nil {
+				b.Fatalf("Drift failed: %v", err)
+			}
+			if len(drifts) != 0 {
+				b.Fatalf("expected no drift, got %d", len(drifts))
+			}
+		}
+	})
+}
+
+// BenchmarkReconcileCreate measures applying the whole config to empty IPVS.
+func BenchmarkReconcileCreate(b *testing.B) {
+	benchEach(b, func(b *testing.B, configs []config.ServiceConfig) {
+		mgr, healthMgr, base := newReconcilerTestEnv(b)
+		defer mgr.Close()
+
+		b.ReportAllocs()
+		for b.Loop() {
+			b.StopTimer()
+			if err := mgr.Flush(); err != nil {
+				b.Fatalf("Flush failed: %v", err)
+			}
+			// A fresh reconciler, as in `ezlb once`, with nothing marked managed
+			reconciler := NewReconciler(mgr, healthMgr, base.snatMgr, zap.NewNop())
+			b.StartTimer()
+
+			if err := reconciler.Reconcile(configs); err != nil {
+				b.Fatalf("Reconcile failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkReconcileSteady measures a reconcile that finds nothing to change,
+// the common case of the periodic loop.
+func BenchmarkReconcileSteady(b *testing.B) {
+	benchEach(b, func(b *testing.B, configs []config.ServiceConfig) {
+		mgr, _, reconciler := newReconcilerTestEnv(b)
+		defer mgr.Close()
+		if err := reconciler.Reconcile(configs); err != nil {
+			b.Fatalf("Reconcile failed: %v", err)
+		}
+
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := reconciler.Reconcile(configs); err != nil {
+				b.Fatalf("Reconcile failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkReconcileWeightChurn measures a reconcile that updates the weight
+// of every destination, e.g. after a config push rebalancing all pools.
+func BenchmarkReconcileWeightChurn(b *testing.B) {
+	benchEach(b, func(b *testing.B, configs []config.ServiceConfig) {
+		mgr, _, reconciler := newReconcilerTestEnv(b)
+		defer mgr.Close()
+
+		// Two configs differing in every backend weight, applied alternately
+		churned := syntheticServices(len(configs), len(configs[0].Backends))
+		for i := range churned {
+			for j := range churned[i].Backends {
+				churned[i].Backends[j].Weight = intPtr(churned[i].Backends[j].GetWeight() + 10)
+			}
+		}
+		if err := reconciler.Reconcile(configs); err != nil {
+			b.Fatalf("Reconcile failed: %v", err)
+		}
+
+		b.ReportAllocs()
+		toggle := false
+		for b.Loop() {
+			next := configs
+			if toggle = !toggle; toggle {
+				next = churned
+			}
+			if err := reconciler.Reconcile(next); err != nil {
+				b.Fatalf("Reconcile failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkApplyDestinations compares adding and removing the destinations of
+// a service with one Manager call each to a single batch, with the manager
+// logging at info level as the daemon does.
+func BenchmarkApplyDestinations(b *testing.B) {
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zap.InfoLevel))
+	for _, n := range []int{100, 1000} {
+		svc := newTestService("10.0.0.1", 80, 6, "wrr")
+		dests := make([]*Destination, n)
+		for i := range dests {
+			dests[i] = newTestDestination(fmt.Sprintf("172.16.%d.%d", i/256, i%256), 8080, 1)
+		}
+
+		b.Run(fmt.Sprintf("single/%d", n), func(b *testing.B) {
+			mgr := newTestManager(b)
+			defer mgr.Close()
+			mgr.logger = logger
+			if err := mgr.CreateService(svc); err != nil {
+				b.Fatalf("CreateService failed: %v", err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, dst := range dests {
+					if err := mgr.CreateDestination(svc, dst); err != nil {
+						b.Fatalf("CreateDestination failed: %v", err)
+					}
+				}
+				for _, dst := range dests {
+					if err := mgr.DeleteDestination(svc, dst); err != nil {
+						b.Fatalf("DeleteDestination failed: %v", err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			mgr := newTestManager(b)
+			defer mgr.Close()
+			mgr.logger = logger
+			if err := mgr.CreateService(svc); err != nil {
+				b.Fatalf("CreateService failed: %v", err)
+			}
+			creates := make([]DestinationOp, n)
+			deletes := make([]DestinationOp, n)
+			for i, dst := range dests {
+				creates[i] = DestinationOp{Action: ActionCreate, Destination: dst}
+				deletes[i] = DestinationOp{Action: ActionDelete, Destination: dst}
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, ops := range [][]DestinationOp{creates, deletes} {
+					for _, err := range mgr.ApplyDestinations(svc, ops) {
+						if err != nil {
+							b.Fatalf("ApplyDestinations failed: %v", err)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package lvs
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/metrics"
+)
+
+// stateCache holds the last services and destinations read from the kernel
+// for the reconciler's reads (see SetCacheTTL). Entries expire after ttl and
+// are dropped when the Manager changes what they describe. generation counts
+// the invalidations, so that a read that raced with a change is not stored.
+type stateCache struct {
+	mu           sync.Mutex
+	ttl          time.Duration
+	generation   uint64
+	services     []*Service
+	servicesAt   time.Time
+	destinations map[ServiceKey]cachedDestinations
+}
+
+// cachedDestinations is the cached destination list of one service.
+type cachedDestinations struct {
+	destinations []*Destination
+	at           time.Time
+}
+
+// SetCacheTTL enables caching of the kernel state read by the reconciler
+// for up to ttl, so that frequent reconciles, e.g. during health flaps, do
+// not dump every service and destination over netlink each time. Changes
+// made through the Manager drop the cached state they affect; changes made
+// by other tools are seen once the entries expire or after InvalidateCache.
+// GetServices and GetDestinations always read the kernel, so connection
+// counts and statistics stay current, and refresh the cache. A ttl of 0
+// disables the cache.
+func (m *Manager) SetCacheTTL(ttl time.Duration) {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	m.cache.ttl = ttl
+	m.cache.generation++
+	m.cache.services = nil
+	m.cache.destinations = nil
+}
+
+// InvalidateCache drops the cached kernel state, so the next read sees
+// changes made outside ezlb.
+func (m *Manager) InvalidateCache() {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	m.cache.generation++
+	m.cache.services = nil
+	m.cache.destinations = nil
+}
+
+// cachedServices returns the services from the cache if it is enabled and
+// fresh, and reads them from the kernel otherwise. The returned services
+// must not be modified.
+func (m *Manager) cachedServices() ([]*Service, error) {
+	m.cache.mu.Lock()
+	if m.cache.ttl > 0 && m.cache.services != nil && time.Since(m.cache.servicesAt) < m.cache.ttl {
+		services := slices.Clone(m.cache.services)
+		m.cache.mu.Unlock()
+		metrics.IncIPVSCacheLookup(true)
+		return services, nil
+	}
+	enabled := m.cache.ttl > 0
+	m.cache.mu.Unlock()
+	if enabled {
+		metrics.IncIPVSCacheLookup(false)
+	}
+	return m.GetServices()
+}
+
+// cachedDestinations returns the destinations of a service from the cache
+// if it is enabled and fresh, and reads them from the kernel otherwise. The
+// returned destinations must not be modified.
+func (m *Manager) cachedDestinations(svc *Service) ([]*Destination, error) {
+	key := ServiceKeyFromIPVS(svc)
+	m.cache.mu.Lock()
+	if cached, ok := m.cache.destinations[key]; ok && m.cache.ttl > 0 && time.Since(cached.at) < m.cache.ttl {
+		destinations := slices.Clone(cached.destinations)
+		m.cache.mu.Unlock()
+		metrics.IncIPVSCacheLookup(true)
+		return destinations, nil
+	}
+	enabled := m.cache.ttl > 0
+	m.cache.mu.Unlock()
+	if enabled {
+		metrics.IncIPVSCacheLookup(false)
+	}
+	return m.GetDestinations(svc)
+}
+
+// cacheGeneration returns the current cache generation, taken before a read
+// of the kernel whose result is stored.
+func (m *Manager) cacheGeneration() uint64 {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	return m.cache.generation
+}
+
+// storeServices caches services read from the kernel at cache generation
+// generation, unless the cache was invalidated since.
+func (m *Manager) storeServices(services []*Service, generation uint64, at time.Time) {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	if m.cache.ttl > 0 && m.cache.generation == generation {
+		m.cache.services = slices.Clone(services)
+		m.cache.servicesAt = at
+	}
+}
+
+// storeDestinations caches the destinations of a service read from the
+// kernel at cache generation generation, unless the cache was invalidated
+// since.
+func (m *Manager) storeDestinations(svc *Service, destinations []*Destination, generation uint64, at time.Time) {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	if m.cache.ttl <= 0 || m.cache.generation != generation {
+		return
+	}
+	if m.cache.destinations == nil {
+		m.cache.destinations = make(map[ServiceKey]cachedDestinations)
+	}
+	m.cache.destinations[ServiceKeyFromIPVS(svc)] = cachedDestinations{destinations: slices.Clone(destinations), at: at}
+}
+
+// invalidateService drops the cached service list and the destinations of
+// svc after a change of the service.
+func (m *Manager) invalidateService(svc *Service) {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	m.cache.generation++
+	m.cache.services = nil
+	delete(m.cache.destinations, ServiceKeyFromIPVS(svc))
+}
+
+// invalidateDestinations drops the cached destinations of svc after a
+// change of its destinations.
+func (m *Manager) invalidateDestinations(svc *Service) {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	m.cache.generation++
+	delete(m.cache.destinations, ServiceKeyFromIPVS(svc))
+}
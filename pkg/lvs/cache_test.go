@@ -0,0 +1,122 @@
+//go:build !integration
+
+package lvs
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReconcile_CacheAvoidsRereadingIPVS(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+	mgr.SetCacheTTL(time.Minute)
+
+	configs := syntheticServices(2, 2)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// Both services are cached after the second reconcile read them
+	services, destinations := faults.callCount(opGetServices), faults.callCount(opGetDestinations)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if got := faults.callCount(opGetServices) - services; got != 0 {
+		t.Errorf("expected the services to come from the cache, got %d reads", got)
+	}
+	if got := faults.callCount(opGetDestinations) - destinations; got != 0 {
+		t.Errorf("expected the destinations to come from the cache, got %d reads", got)
+	}
+
+	// A change through the Manager drops the destinations of that service
+	configs[0].Backends = configs[0].Backends[1:]
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	destinations = faults.callCount(opGetDestinations)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if got := faults.callCount(opGetDestinations) - destinations; got != 1 {
+		t.Errorf("expected only the changed service to be read again, got %d reads", got)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected no changes, got %+v", ops)
+	}
+}
+
+func TestReconcile_InvalidateCacheSeesOutsideChanges(t *testing.T) {
+	mgr, _, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+	mgr.SetCacheTTL(time.Minute)
+
+	configs := syntheticServices(1, 2)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// Deleting a destination behind the Manager's back, like ipvsadm would,
+	// is not seen while the cache is fresh
+	services, err := mgr.GetServices()
+	if err != nil || len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d (%v)", len(services), err)
+	}
+	dests, err := mgr.GetDestinations(services[0])
+	if err != nil || len(dests) != 2 {
+		t.Fatalf("expected 2 destinations, got %d (%v)", len(dests), err)
+	}
+	if err := mgr.handle.DelDestination(services[0], dests[0]); err != nil {
+		t.Fatalf("DelDestination failed: %v", err)
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Fatalf("expected the cached state to hide the change, got %+v", ops)
+	}
+
+	mgr.InvalidateCache()
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 1 || ops[0].Action != ActionCreate {
+		t.Errorf("expected the deleted destination to be recreated, got %+v", ops)
+	}
+}
+
+func TestManager_CacheDisabledByDefault(t *testing.T) {
+	mgr, faults := newFaultyTestManager(t)
+	defer mgr.Close()
+
+	if err := mgr.CreateService(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr")); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	for range 2 {
+		if _, err := mgr.cachedServices(); err != nil {
+			t.Fatalf("cachedServices failed: %v", err)
+		}
+	}
+	if got := faults.callCount(opGetServices); got != 2 {
+		t.Errorf("expected every read to list the services, got %d reads", got)
+	}
+
+	mgr.SetCacheTTL(time.Nanosecond)
+	if _, err := mgr.cachedServices(); err != nil {
+		t.Fatalf("cachedServices failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := mgr.cachedServices(); err != nil {
+		t.Fatalf("cachedServices failed: %v", err)
+	}
+	if got := faults.callCount(opGetServices); got != 4 {
+		t.Errorf("expected expired entries to be read again, got %d reads", got)
+	}
+}
@@ -0,0 +1,37 @@
+package lvs
+
+import (
+	"go.uber.org/zap"
+)
+
+// ConntrackFlusher deletes the connection tracking entries of connections
+// through a service to a destination that was just deleted.
+type ConntrackFlusher func(svc *Service, dst *Destination) error
+
+// SetConntrackFlusher registers the flusher called for the deleted
+// destinations of services with flush_conntrack set.
+func (r *Reconciler) SetConntrackFlusher(flusher ConntrackFlusher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conntrackFlusher = flusher
+}
+
+// flushConntrack deletes the connection tracking entries of a deleted
+// destination if the service asks for it. Only NAT destinations are flushed,
+// as the entries of other forwarding methods do not name the backend. A
+// failure is logged and does not fail the reconcile, since the destination
+// is already gone.
+func (r *Reconciler) flushConntrack(desired *DesiredService, key DestinationKey, dst *Destination) {
+	if r.conntrackFlusher == nil || !desired.Config.FlushConntrack ||
+		dst.ConnectionFlags&ConnectionFlagFwdMask != ConnectionFlagMasq {
+		return
+	}
+	if err := r.conntrackFlusher(desired.Service, dst); err != nil {
+		r.logger.Warn("failed to flush conntrack entries of deleted destination",
+			zap.String("service", desired.Config.Name),
+			zap.String("destination", key.String()),
+			zap.Error(err))
+		return
+	}
+	r.record(desired.Config.Name, ResourceConntrack, ActionDelete, key.String(), nil)
+}
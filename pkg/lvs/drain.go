@@ -0,0 +1,144 @@
+package lvs
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// Results of a completed drain.
+const (
+	DrainResultDrained = "drained"
+	DrainResultTimeout = "timeout"
+)
+
+// Drain is a destination kept in IPVS with weight 0 so that its existing
+// connections can finish, e.g. because of a weight override or maintenance.
+type Drain struct {
+	Since               time.Time
+	Service             string // config service name
+	Backend             string
+	Result              string // empty while the drain is in progress
+	ActiveConnections   int
+	InactiveConnections int
+}
+
+// drainKey identifies a destination within an IPVS service.
+type drainKey struct {
+	service ServiceKey
+	dest    DestinationKey
+}
+
+// drainState tracks a draining destination between polls.
+type drainState struct {
+	since time.Time
+	done  bool
+}
+
+// CheckDrains polls the destinations of the configured services and returns
+// the drains in progress plus those that completed in this call: the
+// destination has no connections left, or timeout (if positive) has passed
+// since its weight dropped to 0. A completed drain is reported once. With
+// remove set, a drained destination is left out of the desired state, so the
+// next Reconcile deletes it, until its weight is raised again.
+func (r *Reconciler) CheckDrains(configs []config.ServiceConfig, timeout time.Duration, remove bool, now time.Time) ([]Drain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make(map[ServiceKey]string, len(configs))
+	for _, svcCfg := range config.ExpandListens(configs) {
+		key, err := ServiceKeyFromConfig(svcCfg)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", svcCfg.Name, err)
+		}
+		names[key] = svcCfg.Name
+	}
+
+	services, err := r.manager.GetServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current IPVS services: %w", err)
+	}
+
+	var drains []Drain
+	seen := make(map[drainKey]bool)
+	for _, svc := range services {
+		svcKey := ServiceKeyFromIPVS(svc)
+		name, configured := names[svcKey]
+		if !configured {
+			continue
+		}
+		dests, err := r.manager.GetDestinations(svc)
+		if err != nil {
+			return nil, fmt.Errorf("get destinations for %s: %w", svcKey, err)
+		}
+
+		for _, dst := range dests {
+			if dst.Weight != 0 {
+				continue
+			}
+			key := drainKey{service: svcKey, dest: DestinationKeyFromIPVS(dst)}
+			if r.backupStandby[key] {
+				continue
+			}
+			seen[key] = true
+			state, tracked := r.draining[key]
+			if !tracked {
+				state = &drainState{since: now}
+				r.draining[key] = state
+			}
+			if state.done {
+				continue
+			}
+
+			drain := Drain{
+				Since:               state.since,
+				Service:             name,
+				Backend:             key.dest.String(),
+				ActiveConnections:   dst.ActiveConnections,
+				InactiveConnections: dst.InactiveConnections,
+			}
+			switch {
+			case dst.ActiveConnections == 0 && dst.InactiveConnections == 0:
+				drain.Result = DrainResultDrained
+			case timeout > 0 && now.Sub(state.since) >= timeout:
+				drain.Result = DrainResultTimeout
+			}
+			if drain.Result != "" {
+				state.done = true
+				if remove {
+					r.drained[key] = true
+				}
+			}
+			drains = append(drains, drain)
+		}
+	}
+
+	// Forget destinations whose weight was raised or that were deleted
+	for key := range r.draining {
+		if !seen[key] {
+			delete(r.draining, key)
+		}
+	}
+
+	sort.Slice(drains, func(i, j int) bool {
+		if drains[i].Service != drains[j].Service {
+			return drains[i].Service < drains[j].Service
+		}
+		return drains[i].Backend < drains[j].Backend
+	})
+	return drains, nil
+}
+
+// removeDrained reports whether a destination with the given desired weight
+// was drained and should stay deleted. A positive weight clears the mark.
+// Must be called with r.mu held.
+func (r *Reconciler) removeDrained(svcKey ServiceKey, dst *Destination) bool {
+	key := drainKey{service: svcKey, dest: DestinationKeyFromIPVS(dst)}
+	if dst.Weight > 0 {
+		delete(r.drained, key)
+		return false
+	}
+	return r.drained[key]
+}
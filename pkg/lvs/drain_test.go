@@ -0,0 +1,110 @@
+package lvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestCheckDrains_CompletesAndRemoves(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	reconciler.SetWeightOverride(WeightOverride{Service: "svc1", Backend: "192.168.1.1:8080", Weight: 0})
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// The drained destination still has connections
+	services, _ := mgr.GetServices()
+	dests, _ := mgr.GetDestinations(services[0])
+	for _, dst := range dests {
+		if dst.Weight == 0 {
+			busy := *dst
+			busy.ActiveConnections = 3
+			if err := mgr.UpdateDestination(services[0], &busy); err != nil {
+				t.Fatalf("UpdateDestination failed: %v", err)
+			}
+		}
+	}
+
+	start := time.Now()
+	drains, err := reconciler.CheckDrains(configs, time.Minute, true, start)
+	if err != nil {
+		t.Fatalf("CheckDrains failed: %v", err)
+	}
+	if len(drains) != 1 || drains[0].Backend != "192.168.1.1:8080" || drains[0].Result != "" || drains[0].ActiveConnections != 3 {
+		t.Fatalf("expected one drain in progress, got %+v", drains)
+	}
+
+	drains, err = reconciler.CheckDrains(configs, time.Minute, true, start.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("CheckDrains failed: %v", err)
+	}
+	if len(drains) != 1 || drains[0].Result != DrainResultTimeout || !drains[0].Since.Equal(start) {
+		t.Fatalf("expected drain to time out, got %+v", drains)
+	}
+	if drains, _ := reconciler.CheckDrains(configs, time.Minute, true, start.Add(3*time.Minute)); len(drains) != 0 {
+		t.Fatalf("expected a completed drain to be reported once, got %+v", drains)
+	}
+
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weights := destinationWeights(t, mgr); len(weights) != 1 {
+		t.Fatalf("expected drained destination to be removed, got %v", weights)
+	}
+
+	// Raising the weight again restores the destination
+	reconciler.ResetWeightOverride("svc1", "192.168.1.1:8080")
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weights := destinationWeights(t, mgr); weights["192.168.1.1:8080"] != 1 {
+		t.Fatalf("expected destination restored with weight 1, got %v", weights)
+	}
+}
+
+func TestCheckDrains_IdleDestinationDrainsImmediately(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1)),
+	}
+	reconciler.SetMaintenance(true)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	drains, err := reconciler.CheckDrains(configs, 0, false, time.Now())
+	if err != nil {
+		t.Fatalf("CheckDrains failed: %v", err)
+	}
+	if len(drains) != 1 || drains[0].Result != DrainResultDrained {
+		t.Fatalf("expected idle destination to be drained, got %+v", drains)
+	}
+
+	// Without remove the destination stays in IPVS with weight 0
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weights := destinationWeights(t, mgr); weights["192.168.1.1:8080"] != 0 || len(weights) != 1 {
+		t.Fatalf("expected destination kept with weight 0, got %v", weights)
+	}
+}
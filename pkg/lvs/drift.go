@@ -0,0 +1,275 @@
+package lvs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+)
+
+// Drift kinds reported by Reconciler.Drift.
+const (
+	DriftMissingService        = "missing_service"
+	DriftSchedulerMismatch     = "scheduler_mismatch"
+	DriftPersistenceMismatch   = "persistence_mismatch"
+	DriftOPSMismatch           = "ops_mismatch"
+	DriftMissingDestination    = "missing_destination"
+	DriftUnexpectedDestination = "unexpected_destination"
+	DriftWeightMismatch        = "weight_mismatch"
+	DriftForwardMismatch       = "forward_mismatch"
+	DriftMissingSNATRule       = "missing_snat_rule"
+	DriftUnexpectedSNATRule    = "unexpected_snat_rule"
+	DriftUnconfiguredService   = "unconfigured_service"
+)
+
+// Drift is a single difference between the desired state and the kernel.
+type Drift struct {
+	Service string // config service name
+	Kind    string
+	Target  string // service or destination key
+	Detail  string
+}
+
+// String returns a human-readable representation of the Drift.
+func (d Drift) String() string {
+	if d.Service == "" {
+		if d.Detail == "" {
+			return fmt.Sprintf("%s %s", d.Kind, d.Target)
+		}
+		return fmt.Sprintf("%s %s (%s)", d.Kind, d.Target, d.Detail)
+	}
+	if d.Detail == "" {
+		return fmt.Sprintf("service %q: %s %s", d.Service, d.Kind, d.Target)
+	}
+	return fmt.Sprintf("service %q: %s %s (%s)", d.Service, d.Kind, d.Target, d.Detail)
+}
+
+// Drift computes the changes Reconcile would apply without touching the
+// kernel. Only the configured virtual services are inspected, so IPVS rules
+// owned by another tool (e.g. keepalived) on other VIPs are not reported.
+// Disabled services count as absent. SNAT rules are compared as well if the
+// SNAT manager can list the rules in the kernel (see snat.StatsProvider); an
+// unexpected rule has no service. The result is sorted by service name,
+// target and kind.
+func (r *Reconciler) Drift(desiredConfigs []config.ServiceConfig) ([]Drift, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	desiredConfigs = config.ExpandListens(config.EnabledServices(desiredConfigs))
+
+	desiredMap, err := r.buildDesiredState(desiredConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build desired state: %w", err)
+	}
+
+	actualServices, err := r.manager.cachedServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current IPVS services: %w", err)
+	}
+	actualMap := make(map[ServiceKey]*Service, len(actualServices))
+	for _, svc := range actualServices {
+		actualMap[ServiceKeyFromIPVS(svc)] = svc
+	}
+
+	var drifts []Drift
+	for key, desired := range desiredMap {
+		name := desired.Config.Name
+		actual, exists := actualMap[key]
+		if !exists {
+			drifts = append(drifts, Drift{Service: name, Kind: DriftMissingService, Target: key.String()})
+			continue
+		}
+		if actual.SchedName != desired.Service.SchedName {
+			drifts = append(drifts, Drift{
+				Service: name,
+				Kind:    DriftSchedulerMismatch,
+				Target:  key.String(),
+				Detail:  fmt.Sprintf("want %s, have %s", desired.Service.SchedName, actual.SchedName),
+			})
+		}
+		if persistenceDiffers(actual, desired.Service) {
+			drifts = append(drifts, Drift{
+				Service: name,
+				Kind:    DriftPersistenceMismatch,
+				Target:  key.String(),
+				Detail:  fmt.Sprintf("want %s, have %s", persistenceString(desired.Service), persistenceString(actual)),
+			})
+		}
+		if onePacketDiffers(actual, desired.Service) {
+			drifts = append(drifts, Drift{
+				Service: name,
+				Kind:    DriftOPSMismatch,
+				Target:  key.String(),
+				Detail:  fmt.Sprintf("want ops=%t, have ops=%t", desired.Service.Flags&ServiceFlagOnePacket != 0, actual.Flags&ServiceFlagOnePacket != 0),
+			})
+		}
+
+		destDrifts, err := r.destinationDrift(name, desired, actual)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, destDrifts...)
+	}
+
+	snatDrifts, err := r.snatDrift(desiredConfigs)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, snatDrifts...)
+
+	sortDrifts(drifts)
+	return drifts, nil
+}
+
+// UnconfiguredServices reports the IPVS services in the kernel that are not
+// in the given configs, as DriftUnconfiguredService items sorted by target.
+// Drift leaves them out because they may belong to another tool; a daemon
+// deletes them only if it created them itself. Ignored services are not
+// reported (see SetIgnoredServices).
+func (r *Reconciler) UnconfiguredServices(desiredConfigs []config.ServiceConfig) ([]Drift, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	desiredKeys := make(map[ServiceKey]bool)
+	for _, svcCfg := range config.ExpandListens(config.EnabledServices(desiredConfigs)) {
+		key, err := ServiceKeyFromConfig(svcCfg)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", svcCfg.Name, err)
+		}
+		desiredKeys[key] = true
+	}
+
+	actualServices, err := r.manager.cachedServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current IPVS services: %w", err)
+	}
+	var drifts []Drift
+	for _, svc := range actualServices {
+		key := ServiceKeyFromIPVS(svc)
+		if !desiredKeys[key] && !r.ignoredService(key) {
+			drifts = append(drifts, Drift{Kind: DriftUnconfiguredService, Target: key.String(), Detail: "sched " + svc.SchedName})
+		}
+	}
+	sortDrifts(drifts)
+	return drifts, nil
+}
+
+// snatDrift compares the desired SNAT rules with the rules in the kernel,
+// if the SNAT manager can list them.
+func (r *Reconciler) snatDrift(desiredConfigs []config.ServiceConfig) ([]Drift, error) {
+	lister, ok := r.snatMgr.(snat.StatsProvider)
+	if !ok {
+		return nil, nil
+	}
+	actual, err := lister.Stats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SNAT rules: %w", err)
+	}
+	desired, err := r.buildSNATRules(desiredConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	desiredKeys := make(map[string]bool, len(desired))
+	for _, rule := range desired {
+		key := rule.SNAT.Key()
+		desiredKeys[key] = true
+		if _, exists := actual[key]; !exists {
+			drifts = append(drifts, Drift{Service: rule.Service, Kind: DriftMissingSNATRule, Target: key})
+		}
+	}
+	for key := range actual {
+		if !desiredKeys[key] {
+			drifts = append(drifts, Drift{Kind: DriftUnexpectedSNATRule, Target: key})
+		}
+	}
+	return drifts, nil
+}
+
+// sortDrifts sorts drift items by service name, target and kind.
+func sortDrifts(drifts []Drift) {
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Service != drifts[j].Service {
+			return drifts[i].Service < drifts[j].Service
+		}
+		if drifts[i].Target != drifts[j].Target {
+			return drifts[i].Target < drifts[j].Target
+		}
+		return drifts[i].Kind < drifts[j].Kind
+	})
+}
+
+// destinationDrift compares the desired destinations of a service with the kernel.
+func (r *Reconciler) destinationDrift(name string, desired *DesiredService, actual *Service) ([]Drift, error) {
+	actualDests, err := r.manager.cachedDestinations(actual)
+	if err != nil {
+		return nil, fmt.Errorf("get destinations for %s:%d: %w", actual.Address, actual.Port, err)
+	}
+	actualDestMap := make(map[DestinationKey]*Destination, len(actualDests))
+	for _, dst := range actualDests {
+		actualDestMap[DestinationKeyFromIPVS(dst)] = dst
+	}
+
+	var drifts []Drift
+	desiredKeys := make(map[DestinationKey]bool, len(desired.Destinations))
+	for _, desiredDst := range desired.Destinations {
+		key := DestinationKeyFromIPVS(desiredDst)
+		desiredKeys[key] = true
+		actualDst, exists := actualDestMap[key]
+		if !exists {
+			drifts = append(drifts, Drift{Service: name, Kind: DriftMissingDestination, Target: key.String()})
+			continue
+		}
+		if actualDst.Weight != desiredDst.Weight {
+			drifts = append(drifts, Drift{
+				Service: name,
+				Kind:    DriftWeightMismatch,
+				Target:  key.String(),
+				Detail:  fmt.Sprintf("want %d, have %d", desiredDst.Weight, actualDst.Weight),
+			})
+		}
+		if actualDst.ConnectionFlags&ConnectionFlagFwdMask != desiredDst.ConnectionFlags&ConnectionFlagFwdMask {
+			drifts = append(drifts, Drift{
+				Service: name,
+				Kind:    DriftForwardMismatch,
+				Target:  key.String(),
+				Detail: fmt.Sprintf("want %s, have %s",
+					forwardMethodFromFlags(desiredDst.ConnectionFlags), forwardMethodFromFlags(actualDst.ConnectionFlags)),
+			})
+		}
+	}
+
+	service := ServiceKeyFromIPVS(actual)
+	for key := range actualDestMap {
+		if !desiredKeys[key] && !r.foreignDestination(desired, service, key) {
+			drifts = append(drifts, Drift{Service: name, Kind: DriftUnexpectedDestination, Target: key.String()})
+		}
+	}
+	return drifts, nil
+}
+
+// persistenceString describes a service's persistence settings.
+func persistenceString(svc *Service) string {
+	if svc.Flags&ServiceFlagPersistent == 0 {
+		return "no persistence"
+	}
+	return fmt.Sprintf("persistence %ds netmask %#x", svc.Timeout, svc.Netmask)
+}
+
+// forwardMethodFromFlags returns the config name of a destination's forwarding method.
+func forwardMethodFromFlags(flags uint32) string {
+	switch flags & ConnectionFlagFwdMask {
+	case ConnectionFlagMasq:
+		return "nat"
+	case ConnectionFlagDirectRoute:
+		return "dr"
+	case ConnectionFlagTunnel:
+		return "tunnel"
+	case ConnectionFlagLocalNode:
+		return "local"
+	default:
+		return fmt.Sprintf("unknown(%d)", flags&ConnectionFlagFwdMask)
+	}
+}
@@ -0,0 +1,115 @@
+package lvs
+
+import (
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestDrift_ReportsDifferencesWithoutChangingKernel(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false,
+			makeBackend("192.168.1.1:8080", 5),
+			makeBackend("192.168.1.2:8080", 3)),
+	}
+
+	drifts, err := reconciler.Drift(configs)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Kind != DriftMissingService {
+		t.Fatalf("expected a single missing service, got %v", drifts)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 0 {
+		t.Fatalf("expected Drift not to create services, got %d", len(services))
+	}
+
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if drifts, _ := reconciler.Drift(configs); len(drifts) != 0 {
+		t.Fatalf("expected no drift after reconcile, got %v", drifts)
+	}
+
+	// Another tool changes a weight and adds a destination behind ezlb's back
+	services, _ := mgr.GetServices()
+	dests, _ := mgr.GetDestinations(services[0])
+	changed := *dests[0]
+	changed.Weight = 9
+	if err := mgr.UpdateDestination(services[0], &changed); err != nil {
+		t.Fatalf("UpdateDestination failed: %v", err)
+	}
+	extra, err := ConfigToIPVSDestination(makeBackend("192.168.1.3:8080", 1))
+	if err != nil {
+		t.Fatalf("ConfigToIPVSDestination failed: %v", err)
+	}
+	if err := mgr.CreateDestination(services[0], extra); err != nil {
+		t.Fatalf("CreateDestination failed: %v", err)
+	}
+
+	drifts, err = reconciler.Drift(configs)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	kinds := make(map[string]string)
+	for _, drift := range drifts {
+		kinds[drift.Target] = drift.Kind
+	}
+	if len(drifts) != 2 ||
+		kinds[DestinationKeyFromIPVS(&changed).String()] != DriftWeightMismatch ||
+		kinds["192.168.1.3:8080"] != DriftUnexpectedDestination {
+		t.Fatalf("unexpected drift: %v", drifts)
+	}
+
+	configs[0].Persistence = config.PersistenceConfig{Timeout: "300s"}
+	drifts, err = reconciler.Drift(configs)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	kinds = make(map[string]string)
+	for _, drift := range drifts {
+		kinds[drift.Target] = drift.Kind
+	}
+	if kinds["10.0.0.1:80/tcp"] != DriftPersistenceMismatch {
+		t.Fatalf("expected persistence drift, got %v", drifts)
+	}
+}
+
+func TestUnconfiguredServices(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+		makeServiceConfig("svc2", "10.0.0.2:80", "rr", false, makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	drifts, err := reconciler.UnconfiguredServices(configs)
+	if err != nil {
+		t.Fatalf("UnconfiguredServices failed: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("expected no unconfigured services, got %v", drifts)
+	}
+
+	// Drift ignores a service dropped from the config, UnconfiguredServices does not
+	if drifts, _ := reconciler.Drift(configs[:1]); len(drifts) != 0 {
+		t.Fatalf("expected Drift to ignore unconfigured services, got %v", drifts)
+	}
+	drifts, err = reconciler.UnconfiguredServices(configs[:1])
+	if err != nil {
+		t.Fatalf("UnconfiguredServices failed: %v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Kind != DriftUnconfiguredService || drifts[0].Target != "10.0.0.2:80/tcp" {
+		t.Fatalf("expected svc2 to be unconfigured, got %v", drifts)
+	}
+	if got, want := drifts[0].String(), "unconfigured_service 10.0.0.2:80/tcp (sched rr)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
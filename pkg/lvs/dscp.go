@@ -0,0 +1,58 @@
+package lvs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+)
+
+// hasDSCP reports whether any service marks its traffic with a DSCP value
+// and thus needs DSCP rules.
+func hasDSCP(configs []config.ServiceConfig) bool {
+	for _, svcCfg := range configs {
+		if svcCfg.DSCP != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileDSCP builds the DSCP rules of all services with dscp set, one per
+// VIP:port (the listen address, or the mark group of a fwmark service), and
+// delegates to the SNAT manager for declarative reconciliation.
+func (r *Reconciler) reconcileDSCP(configs []config.ServiceConfig) error {
+	var desired []snat.DSCPRule
+	for _, svcCfg := range configs {
+		if svcCfg.DSCP == nil {
+			continue
+		}
+		protocol := svcCfg.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		addresses := svcCfg.MarkGroup
+		if svcCfg.FWMark == 0 {
+			addresses = []string{svcCfg.Listen}
+		}
+		for _, address := range addresses {
+			host, portStr, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("service %q, dscp address %q: invalid address: %w", svcCfg.Name, address, err)
+			}
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				return fmt.Errorf("service %q, dscp address %q: invalid port: %w", svcCfg.Name, address, err)
+			}
+			desired = append(desired, snat.DSCPRule{
+				VIP:      host,
+				Protocol: protocol,
+				Port:     uint16(port),
+				DSCP:     uint8(*svcCfg.DSCP),
+			})
+		}
+	}
+	return r.snatMgr.ReconcileDSCP(desired)
+}
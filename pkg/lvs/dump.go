@@ -0,0 +1,65 @@
+package lvs
+
+import (
+	"net"
+	"sort"
+	"strconv"
+)
+
+// KernelService is an IPVS service as found in the kernel, in a form meant
+// to be stored, e.g. in a config snapshot.
+type KernelService struct {
+	Service      string              `json:"service"`
+	Scheduler    string              `json:"scheduler"`
+	PEName       string              `json:"pe_name,omitempty"`
+	Flags        uint32              `json:"flags"`
+	Timeout      uint32              `json:"timeout,omitempty"`
+	Netmask      uint32              `json:"netmask,omitempty"`
+	Destinations []KernelDestination `json:"destinations"`
+}
+
+// KernelDestination is an IPVS destination of a KernelService.
+type KernelDestination struct {
+	Address             string `json:"address"`
+	Weight              int    `json:"weight"`
+	ForwardMethod       string `json:"forward_method"`
+	ActiveConnections   int    `json:"active_connections"`
+	InactiveConnections int    `json:"inactive_connections"`
+}
+
+// Dump returns every IPVS service with its destinations, sorted by service
+// key and destination address.
+func (m *Manager) Dump() ([]KernelService, error) {
+	services, err := m.GetServicesWithDestinations()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]KernelService, 0, len(services))
+	for _, entry := range services {
+		svc, dests := entry.Service, entry.Destinations
+		dumped := KernelService{
+			Service:      ServiceKeyFromIPVS(svc).String(),
+			Scheduler:    svc.SchedName,
+			PEName:       svc.PEName,
+			Flags:        svc.Flags,
+			Timeout:      svc.Timeout,
+			Netmask:      svc.Netmask,
+			Destinations: make([]KernelDestination, 0, len(dests)),
+		}
+		for _, dst := range dests {
+			dumped.Destinations = append(dumped.Destinations, KernelDestination{
+				Address:             net.JoinHostPort(dst.Address.String(), strconv.Itoa(int(dst.Port))),
+				Weight:              dst.Weight,
+				ForwardMethod:       forwardMethodFromFlags(dst.ConnectionFlags),
+				ActiveConnections:   dst.ActiveConnections,
+				InactiveConnections: dst.InactiveConnections,
+			})
+		}
+		sort.Slice(dumped.Destinations, func(i, j int) bool {
+			return dumped.Destinations[i].Address < dumped.Destinations[j].Address
+		})
+		result = append(result, dumped)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Service < result[j].Service })
+	return result, nil
+}
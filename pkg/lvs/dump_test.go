@@ -0,0 +1,35 @@
+package lvs
+
+import (
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestManager_Dump(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	services := []config.ServiceConfig{
+		makeServiceConfig("web", "10.0.0.1:80", "wrr", false,
+			makeBackend("192.168.1.11:8080", 3), makeBackend("192.168.1.10:8080", 5)),
+		makeServiceConfig("api", "10.0.0.0:443", "rr", false, makeBackend("192.168.2.10:9090", 1)),
+	}
+	if err := reconciler.Reconcile(services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	dumped, err := mgr.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if len(dumped) != 2 || dumped[0].Service != "10.0.0.0:443/tcp" || dumped[1].Service != "10.0.0.1:80/tcp" {
+		t.Fatalf("expected services sorted by key, got %+v", dumped)
+	}
+	web := dumped[1]
+	if web.Scheduler != "wrr" || len(web.Destinations) != 2 {
+		t.Fatalf("unexpected web service %+v", web)
+	}
+	first := web.Destinations[0]
+	if first.Address != "192.168.1.10:8080" || first.Weight != 5 || first.ForwardMethod != "nat" {
+		t.Errorf("unexpected first destination %+v", first)
+	}
+}
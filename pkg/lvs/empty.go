@@ -0,0 +1,30 @@
+package lvs
+
+import (
+	"slices"
+)
+
+// emptyServiceNames returns the sorted names of the desired services left
+// without destinations, e.g. because every backend is unhealthy. Their VIPs
+// accept connections but drop them.
+func emptyServiceNames(desiredMap map[ServiceKey]*DesiredService) []string {
+	var names []string
+	for _, desired := range desiredMap {
+		if len(desired.Destinations) > 0 {
+			continue
+		}
+		if !slices.Contains(names, desired.Config.Name) {
+			names = append(names, desired.Config.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// EmptyServices returns the services the last Reconcile left without
+// destinations, sorted by name.
+func (r *Reconciler) EmptyServices() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.empty)
+}
@@ -0,0 +1,82 @@
+package lvs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/healthcheck"
+)
+
+// FailureReporter is optionally implemented by a HealthChecker that can tell
+// why a backend is unhealthy. The reconciler attaches the failure to the
+// backend's Exclusion, so status can show more than its absence.
+type FailureReporter interface {
+	ServiceFailure(service, address string) (healthcheck.Failure, bool)
+}
+
+// Exclusion records that a configured backend was left out of the desired
+// state because it is unhealthy. The failure details are zero if the health
+// checker does not implement FailureReporter.
+type Exclusion struct {
+	Since            time.Time
+	Service          string
+	Address          string
+	LastError        string
+	ConsecutiveFails int
+}
+
+// String describes the exclusion, e.g. "excluded: connection refused for 4m
+// (3 consecutive failures)".
+func (e Exclusion) String() string {
+	var b strings.Builder
+	b.WriteString("excluded: ")
+	if e.LastError != "" {
+		b.WriteString(e.LastError)
+	} else {
+		b.WriteString("unhealthy")
+	}
+	if !e.Since.IsZero() {
+		fmt.Fprintf(&b, " for %s", formatAge(time.Since(e.Since)))
+	}
+	if e.ConsecutiveFails > 0 {
+		fmt.Fprintf(&b, " (%d consecutive failures)", e.ConsecutiveFails)
+	}
+	return b.String()
+}
+
+// formatAge formats how long ago something happened, to the second below a
+// minute and to the minute above it.
+func formatAge(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	s := d.Round(time.Minute).String()
+	s = strings.TrimSuffix(s, "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// exclusion describes why an unhealthy backend of a service is excluded.
+func (r *Reconciler) exclusion(svcCfg config.ServiceConfig, address string) Exclusion {
+	exclusion := Exclusion{Service: svcCfg.Name, Address: address}
+	if reporter, ok := r.healthMgr.(FailureReporter); ok {
+		if failure, unhealthy := reporter.ServiceFailure(svcCfg.Name, address); unhealthy {
+			exclusion.Since = failure.Since
+			exclusion.LastError = failure.LastError
+			exclusion.ConsecutiveFails = failure.ConsecutiveFails
+		}
+	}
+	return exclusion
+}
+
+// Exclusions returns the backends the last reconcile left out because they
+// are unhealthy, sorted by service and address.
+func (r *Reconciler) Exclusions() []Exclusion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exclusion(nil), r.exclusions...)
+}
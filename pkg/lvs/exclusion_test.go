@@ -0,0 +1,87 @@
+package lvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/healthcheck"
+	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
+)
+
+// failureHealthChecker is a mockHealthChecker that also reports failures.
+type failureHealthChecker struct {
+	*mockHealthChecker
+	failures map[string]healthcheck.Failure
+}
+
+func (f *failureHealthChecker) ServiceFailure(service, address string) (healthcheck.Failure, bool) {
+	failure, ok := f.failures[service+"/"+address]
+	return failure, ok
+}
+
+func TestReconcile_RecordsExclusions(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+	since := time.Now().Add(-4 * time.Minute)
+	health := &failureHealthChecker{
+		mockHealthChecker: newMockHealthChecker(),
+		failures: map[string]healthcheck.Failure{
+			"svc1/192.168.1.2:8080": {Since: since, LastError: "connection refused", ConsecutiveFails: 3},
+		},
+	}
+	health.status["192.168.1.2:8080"] = false
+	health.status["192.168.1.3:8080"] = false
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	reconciler := NewReconciler(mgr, health, snatMgr, zap.NewNop())
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1),
+			makeBackend("192.168.1.3:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	exclusions := reconciler.Exclusions()
+	if len(exclusions) != 2 {
+		t.Fatalf("expected 2 exclusions, got %+v", exclusions)
+	}
+	if got, want := exclusions[0].String(), "excluded: connection refused for 4m (3 consecutive failures)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	// Without failure details the exclusion still names the backend
+	if exclusions[1].Address != "192.168.1.3:8080" || exclusions[1].String() != "excluded: unhealthy" {
+		t.Errorf("unexpected exclusion: %+v", exclusions[1])
+	}
+
+	health.status["192.168.1.2:8080"] = true
+	health.status["192.168.1.3:8080"] = true
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if exclusions := reconciler.Exclusions(); len(exclusions) != 0 {
+		t.Errorf("expected no exclusions once healthy, got %+v", exclusions)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{1500 * time.Millisecond, "2s"},
+		{45 * time.Second, "45s"},
+		{4*time.Minute + 10*time.Second, "4m"},
+		{2*time.Hour + 20*time.Second, "2h"},
+		{90 * time.Minute, "1h30m"},
+	}
+	for _, tt := range tests {
+		if got := formatAge(tt.d); got != tt.want {
+			t.Errorf("formatAge(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
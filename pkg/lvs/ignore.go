@@ -0,0 +1,21 @@
+package lvs
+
+// SetIgnoredServices sets the IPVS services owned by other systems, such as
+// kube-proxy or keepalived, which the Reconciler never changes or deletes:
+// prune mode and Cleanup skip them, a managed service that becomes ignored
+// is left in place, and drift checks do not report them as unconfigured.
+func (r *Reconciler) SetIgnoredServices(keys []ServiceKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ignored = make(map[ServiceKey]bool, len(keys))
+	for _, key := range keys {
+		r.ignored[key] = true
+	}
+}
+
+// ignoredService reports whether the IPVS service with key must be left
+// alone. The caller must hold r.mu.
+func (r *Reconciler) ignoredService(key ServiceKey) bool {
+	return r.ignored[key]
+}
@@ -0,0 +1,51 @@
+package lvs
+
+import (
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestReconciler_IgnoredServicesAreNeverTouched(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	foreign, err := ConfigToIPVSService(makeServiceConfig("foreign", "10.0.0.9:80", "rr", false))
+	if err != nil {
+		t.Fatalf("ConfigToIPVSService failed: %v", err)
+	}
+	if err := mgr.CreateService(foreign); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.10:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// Once ignored, neither the foreign service under prune nor the managed
+	// web service after leaving the config is deleted
+	foreignKey, _ := ParseServiceKey("10.0.0.9:80/tcp")
+	webKey, _ := ParseServiceKey("10.0.0.1:80/tcp")
+	reconciler.SetIgnoredServices([]ServiceKey{foreignKey, webKey})
+	reconciler.SetPrune(true)
+	if err := reconciler.Reconcile(nil); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected ignored services to be left alone, got %+v", ops)
+	}
+	if err := reconciler.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 2 {
+		t.Errorf("expected both services to be kept, got %d", len(services))
+	}
+
+	drifts, err := reconciler.UnconfiguredServices(nil)
+	if err != nil {
+		t.Fatalf("UnconfiguredServices failed: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected ignored services not to be reported, got %+v", drifts)
+	}
+}
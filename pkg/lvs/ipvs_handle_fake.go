@@ -4,12 +4,15 @@ package lvs
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
 // fakeServiceKey is used internally by fakeHandle to index services.
 type fakeServiceKey struct {
 	address  string
+	fwmark   uint32
 	port     uint16
 	protocol uint16
 }
@@ -17,6 +20,7 @@ type fakeServiceKey struct {
 func makeFakeServiceKey(svc *Service) fakeServiceKey {
 	return fakeServiceKey{
 		address:  svc.Address.String(),
+		fwmark:   svc.FWMark,
 		port:     svc.Port,
 		protocol: svc.Protocol,
 	}
@@ -37,12 +41,133 @@ func makeFakeDestinationKey(dst *Destination) fakeDestinationKey {
 
 // fakeHandle provides an in-memory IPVS implementation for non-Linux systems.
 // It simulates IPVS kernel behavior using maps, enabling development and testing on macOS.
+// Tests can inject failures through faults, which is nil otherwise.
 type fakeHandle struct {
 	services     map[fakeServiceKey]*Service
 	destinations map[fakeServiceKey]map[fakeDestinationKey]*Destination
+	faults       *fakeFaults
 	mu           sync.Mutex
 }
 
+// fakeOp names a fakeHandle method for fault injection.
+type fakeOp string
+
+const (
+	opNewService        fakeOp = "NewService"
+	opUpdateService     fakeOp = "UpdateService"
+	opDelService        fakeOp = "DelService"
+	opGetServices       fakeOp = "GetServices"
+	opGetService        fakeOp = "GetService"
+	opNewDestination    fakeOp = "NewDestination"
+	opUpdateDestination fakeOp = "UpdateDestination"
+	opDelDestination    fakeOp = "DelDestination"
+	opGetDestinations   fakeOp = "GetDestinations"
+	opApplyDestinations fakeOp = "ApplyDestinations"
+	opFlush             fakeOp = "Flush"
+)
+
+// fakeFaults are failures injected into fakeHandle calls, so that error
+// handling, retries and partial application in the reconciler can be tested
+// deterministically. Calls are counted per operation.
+type fakeFaults struct {
+	mu           sync.Mutex
+	calls        map[fakeOp]int
+	rules        []faultRule
+	serviceLimit int
+}
+
+// faultRule fails call number call of op with err, or every call if call is
+// 0, and delays every call of op by delay.
+type faultRule struct {
+	op    fakeOp
+	call  int
+	err   error
+	delay time.Duration
+}
+
+func newFakeFaults() *fakeFaults {
+	return &fakeFaults{calls: make(map[fakeOp]int), serviceLimit: -1}
+}
+
+// failOn makes the nth call of op from now on fail with err; n of 0 makes
+// every call fail.
+func (f *fakeFaults) failOn(op fakeOp, n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := 0
+	if n > 0 {
+		call = f.calls[op] + n
+	}
+	f.rules = append(f.rules, faultRule{op: op, call: call, err: err})
+}
+
+// delay adds latency to every call of op.
+func (f *fakeFaults) delay(op fakeOp, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, faultRule{op: op, delay: d})
+}
+
+// limitServices makes GetServices return at most n services, the first in
+// ServiceKey string order, as if the netlink dump had been cut short. A
+// negative n removes the limit.
+func (f *fakeFaults) limitServices(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.serviceLimit = n
+}
+
+// callCount returns how many times op has been called.
+func (f *fakeFaults) callCount(op fakeOp) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// reset removes all faults; call counts are kept.
+func (f *fakeFaults) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+	f.serviceLimit = -1
+}
+
+// inject records a call of op, sleeps for its injected latency and returns
+// its injected error, if any.
+func (f *fakeFaults) inject(op fakeOp) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	f.calls[op]++
+	call := f.calls[op]
+	var delay time.Duration
+	var err error
+	for _, rule := range f.rules {
+		if rule.op != op {
+			continue
+		}
+		delay += rule.delay
+		if rule.err != nil && (rule.call == 0 || rule.call == call) {
+			err = rule.err
+		}
+	}
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+	return err
+}
+
+// limit returns the GetServices limit, or -1 for none.
+func (f *fakeFaults) limit() int {
+	if f == nil {
+		return -1
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.serviceLimit
+}
+
 // NewIPVSHandle creates a fake in-memory IPVS handle for non-Linux systems.
 func NewIPVSHandle(_ string) (IPVSHandle, error) {
 	return &fakeHandle{
@@ -59,6 +184,9 @@ func (h *fakeHandle) Close() {
 }
 
 func (h *fakeHandle) NewService(svc *Service) error {
+	if err := h.faults.inject(opNewService); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -73,6 +201,9 @@ func (h *fakeHandle) NewService(svc *Service) error {
 }
 
 func (h *fakeHandle) UpdateService(svc *Service) error {
+	if err := h.faults.inject(opUpdateService); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -86,6 +217,9 @@ func (h *fakeHandle) UpdateService(svc *Service) error {
 }
 
 func (h *fakeHandle) DelService(svc *Service) error {
+	if err := h.faults.inject(opDelService); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -100,6 +234,9 @@ func (h *fakeHandle) DelService(svc *Service) error {
 }
 
 func (h *fakeHandle) GetServices() ([]*Service, error) {
+	if err := h.faults.inject(opGetServices); err != nil {
+		return nil, err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -107,10 +244,33 @@ func (h *fakeHandle) GetServices() ([]*Service, error) {
 	for _, svc := range h.services {
 		result = append(result, cloneService(svc))
 	}
+	if limit := h.faults.limit(); limit >= 0 && limit < len(result) {
+		sort.Slice(result, func(i, j int) bool {
+			return ServiceKeyFromIPVS(result[i]).String() < ServiceKeyFromIPVS(result[j]).String()
+		})
+		result = result[:limit]
+	}
 	return result, nil
 }
 
+func (h *fakeHandle) GetService(svc *Service) (*Service, error) {
+	if err := h.faults.inject(opGetService); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	existing, exists := h.services[makeFakeServiceKey(svc)]
+	if !exists {
+		return nil, nil
+	}
+	return cloneService(existing), nil
+}
+
 func (h *fakeHandle) NewDestination(svc *Service, dst *Destination) error {
+	if err := h.faults.inject(opNewDestination); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -131,6 +291,9 @@ func (h *fakeHandle) NewDestination(svc *Service, dst *Destination) error {
 }
 
 func (h *fakeHandle) UpdateDestination(svc *Service, dst *Destination) error {
+	if err := h.faults.inject(opUpdateDestination); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -151,6 +314,9 @@ func (h *fakeHandle) UpdateDestination(svc *Service, dst *Destination) error {
 }
 
 func (h *fakeHandle) DelDestination(svc *Service, dst *Destination) error {
+	if err := h.faults.inject(opDelDestination); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -170,7 +336,27 @@ func (h *fakeHandle) DelDestination(svc *Service, dst *Destination) error {
 	return nil
 }
 
+// ApplyDestinations applies each change through the single-change methods,
+// so their injected faults still apply; the batch itself is counted and may
+// fail or be delayed as a whole.
+func (h *fakeHandle) ApplyDestinations(svc *Service, ops []DestinationOp) []error {
+	errs := make([]error, len(ops))
+	if err := h.faults.inject(opApplyDestinations); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	for i, op := range ops {
+		errs[i] = applyDestinationOp(h, svc, op)
+	}
+	return errs
+}
+
 func (h *fakeHandle) GetDestinations(svc *Service) ([]*Destination, error) {
+	if err := h.faults.inject(opGetDestinations); err != nil {
+		return nil, err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -188,6 +374,9 @@ func (h *fakeHandle) GetDestinations(svc *Service) ([]*Destination, error) {
 }
 
 func (h *fakeHandle) Flush() error {
+	if err := h.faults.inject(opFlush); err != nil {
+		return err
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
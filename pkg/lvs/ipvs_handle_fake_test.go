@@ -3,8 +3,10 @@
 package lvs
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestFakeHandle_NewAndGetServices(t *testing.T) {
@@ -378,3 +380,74 @@ func TestFakeHandle_ConcurrentAccess(t *testing.T) {
 		t.Fatalf("expected %d destinations, got %d", concurrency, len(destinations))
 	}
 }
+
+func TestFakeFaults_FailOn(t *testing.T) {
+	mgr, faults := newFaultyTestManager(t)
+	defer mgr.Close()
+
+	errInjected := errors.New("injected")
+	faults.failOn(opNewService, 2, errInjected)
+
+	if err := mgr.handle.NewService(newTestService("10.0.0.1", 80, 6, "rr")); err != nil {
+		t.Fatalf("expected the first call to succeed, got: %v", err)
+	}
+	if err := mgr.handle.NewService(newTestService("10.0.0.2", 80, 6, "rr")); !errors.Is(err, errInjected) {
+		t.Fatalf("expected the second call to fail with the injected error, got: %v", err)
+	}
+	if err := mgr.handle.NewService(newTestService("10.0.0.2", 80, 6, "rr")); err != nil {
+		t.Fatalf("expected the third call to succeed, got: %v", err)
+	}
+	if got := faults.callCount(opNewService); got != 3 {
+		t.Errorf("expected 3 recorded calls, got %d", got)
+	}
+
+	// n of 0 fails every call until reset
+	faults.failOn(opGetServices, 0, errInjected)
+	for range 2 {
+		if _, err := mgr.handle.GetServices(); !errors.Is(err, errInjected) {
+			t.Fatalf("expected every GetServices call to fail, got: %v", err)
+		}
+	}
+	faults.reset()
+	if _, err := mgr.handle.GetServices(); err != nil {
+		t.Fatalf("expected GetServices to succeed after reset, got: %v", err)
+	}
+}
+
+func TestFakeFaults_Delay(t *testing.T) {
+	mgr, faults := newFaultyTestManager(t)
+	defer mgr.Close()
+
+	faults.delay(opFlush, 20*time.Millisecond)
+	start := time.Now()
+	if err := mgr.handle.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected Flush to take at least 20ms, took %v", elapsed)
+	}
+}
+
+func TestFakeFaults_LimitServices(t *testing.T) {
+	mgr, faults := newFaultyTestManager(t)
+	defer mgr.Close()
+
+	for _, address := range []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"} {
+		if err := mgr.handle.NewService(newTestService(address, 80, 6, "rr")); err != nil {
+			t.Fatalf("NewService failed: %v", err)
+		}
+	}
+	faults.limitServices(2)
+	services, err := mgr.handle.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 2 || services[0].Address.String() != "10.0.0.1" || services[1].Address.String() != "10.0.0.2" {
+		t.Fatalf("expected the first two services in key order, got %d services", len(services))
+	}
+
+	faults.limitServices(-1)
+	if services, _ := mgr.handle.GetServices(); len(services) != 3 {
+		t.Errorf("expected all services without a limit, got %d", len(services))
+	}
+}
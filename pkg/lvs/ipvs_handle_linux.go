@@ -3,6 +3,10 @@
 package lvs
 
 import (
+	"errors"
+	"fmt"
+	"syscall"
+
 	mobyipvs "github.com/moby/ipvs"
 )
 
@@ -48,6 +52,19 @@ func (h *linuxHandle) GetServices() ([]*Service, error) {
 	return services, nil
 }
 
+// GetService asks the kernel for a single service. The kernel answers ESRCH
+// if there is no such service.
+func (h *linuxHandle) GetService(svc *Service) (*Service, error) {
+	mobySvc, err := h.handle.GetService(toMobyService(svc))
+	if errors.Is(err, syscall.ESRCH) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromMobyService(mobySvc), nil
+}
+
 func (h *linuxHandle) NewDestination(svc *Service, dst *Destination) error {
 	return h.handle.NewDestination(toMobyService(svc), toMobyDestination(dst))
 }
@@ -60,6 +77,27 @@ func (h *linuxHandle) DelDestination(svc *Service, dst *Destination) error {
 	return h.handle.DelDestination(toMobyService(svc), toMobyDestination(dst))
 }
 
+// ApplyDestinations sends the changes over the handle's netlink socket one
+// after the other, with the service converted once.
+func (h *linuxHandle) ApplyDestinations(svc *Service, ops []DestinationOp) []error {
+	mobySvc := toMobyService(svc)
+	errs := make([]error, len(ops))
+	for i, op := range ops {
+		dst := toMobyDestination(op.Destination)
+		switch op.Action {
+		case ActionCreate:
+			errs[i] = h.handle.NewDestination(mobySvc, dst)
+		case ActionUpdate:
+			errs[i] = h.handle.UpdateDestination(mobySvc, dst)
+		case ActionDelete:
+			errs[i] = h.handle.DelDestination(mobySvc, dst)
+		default:
+			errs[i] = fmt.Errorf("unknown destination action %q", op.Action)
+		}
+	}
+	return errs
+}
+
 func (h *linuxHandle) GetDestinations(svc *Service) ([]*Destination, error) {
 	mobyDsts, err := h.handle.GetDestinations(toMobyService(svc))
 	if err != nil {
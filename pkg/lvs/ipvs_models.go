@@ -77,14 +77,30 @@ const (
 	ConnectionFlagDirectRoute = 0x0003
 )
 
+// Service flag constants.
+const (
+	// ServiceFlagPersistent enables session persistence (IP_VS_SVC_F_PERSISTENT).
+	ServiceFlagPersistent = 0x0001
+	// ServiceFlagOnePacket schedules every UDP datagram on its own
+	// (IP_VS_SVC_F_ONEPACKET).
+	ServiceFlagOnePacket = 0x0004
+)
+
 // Scheduling algorithm constants.
 const (
-	RoundRobin              = "rr"
-	LeastConnection         = "lc"
-	DestinationHashing      = "dh"
-	SourceHashing           = "sh"
-	WeightedRoundRobin      = "wrr"
-	WeightedLeastConnection = "wlc"
+	RoundRobin                  = "rr"
+	LeastConnection             = "lc"
+	DestinationHashing          = "dh"
+	SourceHashing               = "sh"
+	WeightedRoundRobin          = "wrr"
+	WeightedLeastConnection     = "wlc"
+	MaglevHashing               = "mh"
+	ShortestExpectedDelay       = "sed"
+	NeverQueue                  = "nq"
+	WeightedFailover            = "fo"
+	WeightedOverflow            = "ovf"
+	LocalityLeastConnection     = "lblc"
+	LocalityLeastConnReplicated = "lblcr"
 )
 
 // Connection forwarding method constants (aliases).
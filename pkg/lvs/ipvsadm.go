@@ -0,0 +1,414 @@
+package lvs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Save writes every IPVS service and its destinations to w in the rule
+// format of `ipvsadm -S -n`, which `ipvsadm -R` and Restore read back.
+// Services are sorted by key and destinations by address.
+func (m *Manager) Save(w io.Writer) error {
+	services, err := m.GetServicesWithDestinations()
+	if err != nil {
+		return err
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return ServiceKeyFromIPVS(services[i].Service).String() < ServiceKeyFromIPVS(services[j].Service).String()
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, entry := range services {
+		svc, dests := entry.Service, entry.Destinations
+		sort.Slice(dests, func(i, j int) bool {
+			return DestinationKeyFromIPVS(dests[i]).String() < DestinationKeyFromIPVS(dests[j]).String()
+		})
+
+		target := ipvsadmServiceTarget(svc)
+		line := "-A " + target + " -s " + svc.SchedName
+		if svc.Flags&ServiceFlagPersistent != 0 {
+			line += fmt.Sprintf(" -p %d", svc.Timeout)
+			if ones := prefixFromNetmask(svc.AddressFamily, svc.Netmask); ones != netmaskBits(svc.AddressFamily) {
+				line += " -M " + ipvsadmNetmask(svc.AddressFamily, ones)
+			}
+		}
+		if svc.PEName != "" {
+			line += " --pe " + svc.PEName
+		}
+		if svc.Flags&ServiceFlagOnePacket != 0 {
+			line += " -o"
+		}
+		fmt.Fprintln(bw, line)
+
+		for _, dst := range dests {
+			line := fmt.Sprintf("-a %s -r %s %s -w %d", target,
+				net.JoinHostPort(dst.Address.String(), strconv.Itoa(int(dst.Port))),
+				ipvsadmForwardSwitch(dst.ConnectionFlags), dst.Weight)
+			if dst.UpperThreshold != 0 {
+				line += fmt.Sprintf(" -x %d", dst.UpperThreshold)
+			}
+			if dst.LowerThreshold != 0 {
+				line += fmt.Sprintf(" -y %d", dst.LowerThreshold)
+			}
+			fmt.Fprintln(bw, line)
+		}
+	}
+	return bw.Flush()
+}
+
+// Restore reads rules in the format written by Save or `ipvsadm -S` and
+// applies them in order, like `ipvsadm -R`: -A adds a service, -a adds a
+// destination and -C clears every service. Blank lines and lines starting
+// with # are skipped. Restore stops at the first rule that cannot be parsed
+// or applied; the rules before it stay applied.
+func (m *Manager) Restore(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	services := make(map[string]*Service)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := m.restoreRule(fields, services); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// restoreRule applies a single ipvsadm rule. services holds the services
+// added so far by their ipvsadm target, for the destinations that follow.
+func (m *Manager) restoreRule(fields []string, services map[string]*Service) error {
+	switch fields[0] {
+	case "-C", "--clear":
+		if len(fields) != 1 {
+			return fmt.Errorf("unexpected argument %q", fields[1])
+		}
+		return m.Flush()
+	case "-A", "--add-service":
+		svc, err := parseIPVSAdmService(fields[1:])
+		if err != nil {
+			return err
+		}
+		if err := m.CreateService(svc); err != nil {
+			return err
+		}
+		services[ipvsadmServiceTarget(svc)] = svc
+		return nil
+	case "-a", "--add-server":
+		target, dst, err := parseIPVSAdmDestination(fields[1:])
+		if err != nil {
+			return err
+		}
+		svc, ok := services[target]
+		if !ok {
+			if svc, err = m.findIPVSAdmService(target); err != nil {
+				return err
+			}
+		}
+		if dst.Port == 0 {
+			dst.Port = svc.Port
+		}
+		return m.CreateDestination(svc, dst)
+	default:
+		return fmt.Errorf("unsupported command %q, expected -A, -a or -C", fields[0])
+	}
+}
+
+// findIPVSAdmService returns the existing kernel service of an ipvsadm
+// target, for destinations of services not added by the same restore.
+func (m *Manager) findIPVSAdmService(target string) (*Service, error) {
+	services, err := m.GetServices()
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		if ipvsadmServiceTarget(svc) == target {
+			return svc, nil
+		}
+	}
+	return nil, fmt.Errorf("service %s does not exist", target)
+}
+
+// parseIPVSAdmService parses the arguments of an ipvsadm -A rule.
+func parseIPVSAdmService(args []string) (*Service, error) {
+	svc := &Service{SchedName: WeightedLeastConnection}
+	var ipv6, persistent bool
+	netmask := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-t", "--tcp-service", "-u", "--udp-service", "-f", "--fwmark-service", "-s", "--scheduler", "-M", "--netmask", "--pe":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option %s needs a value", arg)
+			}
+		}
+		switch arg {
+		case "-t", "--tcp-service", "-u", "--udp-service":
+			i++
+			if err := parseIPVSAdmVirtualAddress(svc, args[i]); err != nil {
+				return nil, err
+			}
+			svc.Protocol = syscall.IPPROTO_TCP
+			if arg == "-u" || arg == "--udp-service" {
+				svc.Protocol = syscall.IPPROTO_UDP
+			}
+		case "-f", "--fwmark-service":
+			i++
+			mark, err := strconv.ParseUint(args[i], 10, 32)
+			if err != nil || mark == 0 {
+				return nil, fmt.Errorf("invalid fwmark %q", args[i])
+			}
+			svc.FWMark = uint32(mark)
+		case "-6", "--ipv6":
+			ipv6 = true
+		case "-s", "--scheduler":
+			i++
+			svc.SchedName = args[i]
+		case "-p", "--persistent":
+			persistent = true
+			svc.Timeout = 300
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				timeout, err := strconv.ParseUint(args[i], 10, 32)
+				if err != nil || timeout == 0 {
+					return nil, fmt.Errorf("invalid persistence timeout %q", args[i])
+				}
+				svc.Timeout = uint32(timeout)
+			}
+		case "-M", "--netmask":
+			i++
+			netmask = args[i]
+		case "--pe":
+			i++
+			svc.PEName = args[i]
+		case "-o", "--ops":
+			svc.Flags |= ServiceFlagOnePacket
+		default:
+			return nil, fmt.Errorf("unsupported service option %q", arg)
+		}
+	}
+
+	if svc.FWMark != 0 {
+		if svc.Address != nil {
+			return nil, fmt.Errorf("service has both an address and a fwmark")
+		}
+		svc.Address = net.IPv4zero.To4()
+		if ipv6 {
+			svc.Address = net.IPv6unspecified
+		}
+	} else if svc.Address == nil {
+		return nil, fmt.Errorf("service needs -t, -u or -f")
+	}
+	svc.AddressFamily = addressFamilyFromIP(svc.Address)
+	svc.Netmask = netmaskFromFamily(svc.AddressFamily)
+	if persistent {
+		svc.Flags |= ServiceFlagPersistent
+		if netmask != "" {
+			ones, err := parseIPVSAdmNetmask(svc.AddressFamily, netmask)
+			if err != nil {
+				return nil, err
+			}
+			svc.Netmask = netmaskFromPrefix(svc.AddressFamily, ones)
+		}
+	}
+	return svc, nil
+}
+
+// parseIPVSAdmDestination parses the arguments of an ipvsadm -a rule and
+// returns the target of its service. A destination without a port gets
+// port 0, which stands for the service's port.
+func parseIPVSAdmDestination(args []string) (string, *Destination, error) {
+	dst := &Destination{Weight: 1, ConnectionFlags: ConnectionFlagDirectRoute}
+	svc := &Service{}
+	var ipv6 bool
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-t", "--tcp-service", "-u", "--udp-service", "-f", "--fwmark-service", "-r", "--real-server", "-w", "--weight", "-x", "--u-threshold", "-y", "--l-threshold":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("option %s needs a value", arg)
+			}
+		}
+		switch arg {
+		case "-t", "--tcp-service", "-u", "--udp-service":
+			i++
+			if err := parseIPVSAdmVirtualAddress(svc, args[i]); err != nil {
+				return "", nil, err
+			}
+			svc.Protocol = syscall.IPPROTO_TCP
+			if arg == "-u" || arg == "--udp-service" {
+				svc.Protocol = syscall.IPPROTO_UDP
+			}
+		case "-f", "--fwmark-service":
+			i++
+			mark, err := strconv.ParseUint(args[i], 10, 32)
+			if err != nil || mark == 0 {
+				return "", nil, fmt.Errorf("invalid fwmark %q", args[i])
+			}
+			svc.FWMark = uint32(mark)
+		case "-6", "--ipv6":
+			ipv6 = true
+		case "-r", "--real-server":
+			i++
+			if err := parseIPVSAdmRealServer(dst, args[i]); err != nil {
+				return "", nil, err
+			}
+		case "-g", "--gatewaying":
+			dst.ConnectionFlags = ConnectionFlagDirectRoute
+		case "-i", "--ipip":
+			dst.ConnectionFlags = ConnectionFlagTunnel
+		case "-m", "--masquerading":
+			dst.ConnectionFlags = ConnectionFlagMasq
+		case "-w", "--weight":
+			i++
+			weight, err := strconv.Atoi(args[i])
+			if err != nil || weight < 0 {
+				return "", nil, fmt.Errorf("invalid weight %q", args[i])
+			}
+			dst.Weight = weight
+		case "-x", "--u-threshold", "-y", "--l-threshold":
+			i++
+			threshold, err := strconv.ParseUint(args[i], 10, 32)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid threshold %q", args[i])
+			}
+			if arg == "-x" || arg == "--u-threshold" {
+				dst.UpperThreshold = uint32(threshold)
+			} else {
+				dst.LowerThreshold = uint32(threshold)
+			}
+		default:
+			return "", nil, fmt.Errorf("unsupported server option %q", arg)
+		}
+	}
+
+	if dst.Address == nil {
+		return "", nil, fmt.Errorf("server needs -r")
+	}
+	if svc.FWMark != 0 {
+		svc.Address = net.IPv4zero.To4()
+		if ipv6 {
+			svc.Address = net.IPv6unspecified
+		}
+	} else if svc.Address == nil {
+		return "", nil, fmt.Errorf("server needs -t, -u or -f")
+	}
+	svc.AddressFamily = addressFamilyFromIP(svc.Address)
+	return ipvsadmServiceTarget(svc), dst, nil
+}
+
+// parseIPVSAdmVirtualAddress sets the address and port of a service from an
+// ipvsadm VIP:port argument.
+func parseIPVSAdmVirtualAddress(svc *Service, value string) error {
+	host, portStr, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("invalid service address %q: %w", value, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid service address %q: invalid IP address %q", value, host)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid service address %q: invalid port %q", value, portStr)
+	}
+	svc.Address = ip
+	svc.Port = uint16(port)
+	return nil
+}
+
+// parseIPVSAdmRealServer sets the address and port of a destination from an
+// ipvsadm -r argument, which may omit the port.
+func parseIPVSAdmRealServer(dst *Destination, value string) error {
+	host, portStr, err := net.SplitHostPort(value)
+	if err != nil {
+		host, portStr = strings.Trim(value, "[]"), "0"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid server address %q", value)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid server address %q: invalid port %q", value, portStr)
+	}
+	dst.Address = ip
+	dst.Port = uint16(port)
+	dst.AddressFamily = addressFamilyFromIP(ip)
+	return nil
+}
+
+// parseIPVSAdmNetmask parses a persistence netmask: a dotted mask for IPv4
+// and a prefix length for IPv6.
+func parseIPVSAdmNetmask(family uint16, value string) (int, error) {
+	if family == syscall.AF_INET {
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			return 0, fmt.Errorf("invalid netmask %q", value)
+		}
+		ones, bits := net.IPMask(ip).Size()
+		if bits == 0 {
+			return 0, fmt.Errorf("invalid netmask %q: not contiguous", value)
+		}
+		return ones, nil
+	}
+	ones, err := strconv.Atoi(value)
+	if err != nil || ones < 0 || ones > netmaskBits(family) {
+		return 0, fmt.Errorf("invalid netmask %q", value)
+	}
+	return ones, nil
+}
+
+// ipvsadmServiceTarget returns the ipvsadm arguments that select a service,
+// e.g. "-t 10.0.0.1:80" or "-f 100 -6".
+func ipvsadmServiceTarget(svc *Service) string {
+	if svc.FWMark != 0 {
+		if svc.AddressFamily == syscall.AF_INET6 {
+			return fmt.Sprintf("-f %d -6", svc.FWMark)
+		}
+		return fmt.Sprintf("-f %d", svc.FWMark)
+	}
+	option := "-t"
+	if svc.Protocol == syscall.IPPROTO_UDP {
+		option = "-u"
+	}
+	return option + " " + net.JoinHostPort(svc.Address.String(), strconv.Itoa(int(svc.Port)))
+}
+
+// ipvsadmNetmask formats a persistence netmask as ipvsadm does: a dotted
+// mask for IPv4 and a prefix length for IPv6.
+func ipvsadmNetmask(family uint16, ones int) string {
+	if family == syscall.AF_INET {
+		return net.IP(net.CIDRMask(ones, 32)).String()
+	}
+	return strconv.Itoa(ones)
+}
+
+// ipvsadmForwardSwitch returns the ipvsadm option of a forwarding method.
+// Like ipvsadm, it writes -g for local destinations, which the kernel turns
+// back into local forwarding as their address is local.
+func ipvsadmForwardSwitch(flags uint32) string {
+	switch flags & ConnectionFlagFwdMask {
+	case ConnectionFlagMasq:
+		return "-m"
+	case ConnectionFlagTunnel:
+		return "-i"
+	default:
+		return "-g"
+	}
+}
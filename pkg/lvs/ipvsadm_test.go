@@ -0,0 +1,91 @@
+package lvs
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestManager_SaveRestoreRoundTrip(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+
+	rules := `-A -t 10.0.0.1:80 -s wrr -p 600 -M 255.255.255.0
+-a -t 10.0.0.1:80 -r 192.168.1.10:8080 -m -w 5
+-a -t 10.0.0.1:80 -r 192.168.1.11:8080 -g -w 0 -x 100 -y 50
+-A -u 10.0.0.3:53 -s rr -o
+-a -u 10.0.0.3:53 -r 192.168.3.10:53 -i -w 1
+-A -f 100 -s sh
+-a -f 100 -r 192.168.4.10:0 -g -w 2
+`
+	if err := mgr.Restore(strings.NewReader("# saved by ipvsadm\n\n" + rules)); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	var saved bytes.Buffer
+	if err := mgr.Save(&saved); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved.String() != rules {
+		t.Errorf("Save() =\n%s\nwant\n%s", saved.String(), rules)
+	}
+
+	svc := newTestService("10.0.0.3", 53, syscall.IPPROTO_UDP, "rr")
+	dests, err := mgr.GetDestinations(svc)
+	if err != nil {
+		t.Fatalf("GetDestinations failed: %v", err)
+	}
+	if len(dests) != 1 || dests[0].ConnectionFlags&ConnectionFlagFwdMask != ConnectionFlagTunnel {
+		t.Errorf("expected one tunnel destination, got %+v", dests)
+	}
+}
+
+func TestManager_RestoreDestinationOfExistingService(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+
+	if err := mgr.CreateService(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr")); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	// Like ipvsadm, a server without a port gets the service's port and
+	// direct routing with weight 1 by default.
+	if err := mgr.Restore(strings.NewReader("-a -t 10.0.0.1:80 -r 192.168.1.10\n")); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	var saved bytes.Buffer
+	if err := mgr.Save(&saved); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	want := "-A -t 10.0.0.1:80 -s rr\n-a -t 10.0.0.1:80 -r 192.168.1.10:80 -g -w 1\n"
+	if saved.String() != want {
+		t.Errorf("Save() = %q, want %q", saved.String(), want)
+	}
+}
+
+func TestManager_RestoreErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{"unknown command", "-E -t 10.0.0.1:80 -s rr\n", "line 1: unsupported command"},
+		{"unknown option", "-A -t 10.0.0.1:80 -s rr -b flag-1\n", "line 1: unsupported service option"},
+		{"missing value", "-A -t 10.0.0.1:80 -s\n", "line 1: option -s needs a value"},
+		{"missing service", "-A -s rr\n", "line 1: service needs -t, -u or -f"},
+		{"unknown service", "-a -t 10.0.0.9:80 -r 192.168.1.10:80\n", "line 1: service -t 10.0.0.9:80 does not exist"},
+		{"bad weight", "-A -t 10.0.0.1:80 -s rr\n-a -t 10.0.0.1:80 -r 192.168.1.10:80 -w x\n", "line 2: invalid weight"},
+		{"bad netmask", "-A -t 10.0.0.1:80 -s rr -p 300 -M 255.0.255.0\n", "line 1: invalid netmask"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newTestManager(t)
+			defer mgr.Close()
+			err := mgr.Restore(strings.NewReader(tt.rules))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Restore() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
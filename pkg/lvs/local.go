@@ -0,0 +1,61 @@
+package lvs
+
+import (
+	"net"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// interfaceAddrs lists the addresses assigned to local interfaces; tests
+// replace it.
+var interfaceAddrs = net.InterfaceAddrs
+
+// SetLocalBackendDetection enables or disables local backend detection, in
+// which Reconcile programs backends whose IP is assigned to this host with
+// the local-node forwarding method, as IPVS requires, unless their
+// forward_method is set explicitly. Takes effect on the next Reconcile.
+func (r *Reconciler) SetLocalBackendDetection(enabled bool) {
+	r.detectLocal.Store(enabled)
+}
+
+// localAddresses returns the IP addresses of this host if local backend
+// detection is enabled, and nil otherwise or if they cannot be listed.
+func (r *Reconciler) localAddresses() map[string]bool {
+	if !r.detectLocal.Load() {
+		return nil
+	}
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		r.logger.Warn("failed to list local addresses, local backends are not detected", zap.Error(err))
+		return nil
+	}
+	local := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local[ipNet.IP.String()] = true
+		}
+	}
+	return local
+}
+
+// useLocalNode switches a destination to local-node forwarding if its
+// backend's IP is one of the local addresses and no forward_method is set
+// for it. Full NAT services keep masquerading, which their SNAT rules rely
+// on. A backend is logged when it is first seen local. The caller must hold
+// r.mu.
+func (r *Reconciler) useLocalNode(local map[string]bool, svcCfg config.ServiceConfig, backendCfg config.BackendConfig, dst *Destination) {
+	key := svcCfg.Name + "/" + backendCfg.Address
+	if !local[dst.Address.String()] || backendCfg.ForwardMethod != "" || svcCfg.FullNAT {
+		delete(r.localBackends, key)
+		return
+	}
+	dst.ConnectionFlags = dst.ConnectionFlags&^ConnectionFlagFwdMask | ConnectionFlagLocalNode
+	if !r.localBackends[key] {
+		r.localBackends[key] = true
+		r.logger.Info("backend address is local, using local-node forwarding",
+			zap.String("service", svcCfg.Name),
+			zap.String("backend", backendCfg.Address),
+		)
+	}
+}
@@ -0,0 +1,64 @@
+package lvs
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestReconcile_LocalBackendsUseLocalNode(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}}, nil
+	}
+	defer func() { interfaceAddrs = net.InterfaceAddrs }()
+
+	explicit := makeBackend("192.168.1.10:9090", 1)
+	explicit.ForwardMethod = "nat"
+	svcCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", false,
+		makeBackend("192.168.1.10:8080", 1),
+		makeBackend("192.168.1.11:8080", 1),
+		explicit)
+	configs := []config.ServiceConfig{svcCfg}
+
+	forwardFlags := func() map[string]uint32 {
+		t.Helper()
+		dests, err := mgr.GetDestinations(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr"))
+		if err != nil {
+			t.Fatalf("GetDestinations failed: %v", err)
+		}
+		flags := make(map[string]uint32)
+		for _, dst := range dests {
+			flags[DestinationKeyFromIPVS(dst).String()] = dst.ConnectionFlags & ConnectionFlagFwdMask
+		}
+		return flags
+	}
+
+	// Without detection, the local backend is masqueraded like the others
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if flags := forwardFlags(); flags["192.168.1.10:8080"] != ConnectionFlagMasq {
+		t.Fatalf("expected masquerading without detection, got %v", flags)
+	}
+
+	reconciler.SetLocalBackendDetection(true)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	want := map[string]uint32{
+		"192.168.1.10:8080": ConnectionFlagLocalNode,
+		"192.168.1.11:8080": ConnectionFlagMasq,
+		"192.168.1.10:9090": ConnectionFlagMasq,
+	}
+	flags := forwardFlags()
+	for dest, flag := range want {
+		if flags[dest] != flag {
+			t.Errorf("destination %s: expected forwarding flags %d, got %d", dest, flag, flags[dest])
+		}
+	}
+}
@@ -1,15 +1,23 @@
 package lvs
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// ErrReadOnly is returned for every IPVS change attempted in read-only mode.
+var ErrReadOnly = errors.New("read-only mode, kernel changes are refused")
+
 // Manager wraps the IPVSHandle and provides IPVS CRUD operations with logging.
 type Manager struct {
-	handle IPVSHandle
-	logger *zap.Logger
+	handle   IPVSHandle
+	logger   *zap.Logger
+	readOnly atomic.Bool
+	cache    stateCache
 }
 
 // NewManager creates a new IPVS Manager by initializing a platform-specific handle.
@@ -43,26 +51,52 @@ func (m *Manager) Close() {
 
 // GetServices returns all IPVS virtual services currently configured.
 func (m *Manager) GetServices() ([]*Service, error) {
+	generation, at := m.cacheGeneration(), time.Now()
 	services, err := m.handle.GetServices()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ipvs services: %w", err)
 	}
+	m.storeServices(services, generation, at)
 	return services, nil
 }
 
 // GetDestinations returns all real servers (destinations) for the given IPVS service.
 func (m *Manager) GetDestinations(svc *Service) ([]*Destination, error) {
+	generation, at := m.cacheGeneration(), time.Now()
 	destinations, err := m.handle.GetDestinations(svc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get destinations for service %s:%d: %w",
 			svc.Address, svc.Port, err)
 	}
+	m.storeDestinations(svc, destinations, generation, at)
 	return destinations, nil
 }
 
+// SetReadOnly enables or disables read-only mode, in which every IPVS change
+// fails with ErrReadOnly while reads keep working.
+func (m *Manager) SetReadOnly(enabled bool) {
+	m.readOnly.Store(enabled)
+}
+
+// IsReadOnly reports whether read-only mode is enabled.
+func (m *Manager) IsReadOnly() bool {
+	return m.readOnly.Load()
+}
+
+// mutate runs an IPVS change unless read-only mode is enabled.
+func (m *Manager) mutate(change func() error) error {
+	if m.readOnly.Load() {
+		return ErrReadOnly
+	}
+	return change()
+}
+
 // CreateService creates a new IPVS virtual service.
 func (m *Manager) CreateService(svc *Service) error {
-	if err := m.handle.NewService(svc); err != nil {
+	if err := m.mutate(func() error {
+		defer m.invalidateService(svc)
+		return m.handle.NewService(svc)
+	}); err != nil {
 		return fmt.Errorf("failed to create service %s:%d: %w",
 			svc.Address, svc.Port, err)
 	}
@@ -76,7 +110,10 @@ func (m *Manager) CreateService(svc *Service) error {
 
 // UpdateService updates an existing IPVS virtual service.
 func (m *Manager) UpdateService(svc *Service) error {
-	if err := m.handle.UpdateService(svc); err != nil {
+	if err := m.mutate(func() error {
+		defer m.invalidateService(svc)
+		return m.handle.UpdateService(svc)
+	}); err != nil {
 		return fmt.Errorf("failed to update service %s:%d: %w",
 			svc.Address, svc.Port, err)
 	}
@@ -90,7 +127,10 @@ func (m *Manager) UpdateService(svc *Service) error {
 
 // DeleteService removes an IPVS virtual service.
 func (m *Manager) DeleteService(svc *Service) error {
-	if err := m.handle.DelService(svc); err != nil {
+	if err := m.mutate(func() error {
+		defer m.invalidateService(svc)
+		return m.handle.DelService(svc)
+	}); err != nil {
 		return fmt.Errorf("failed to delete service %s:%d: %w",
 			svc.Address, svc.Port, err)
 	}
@@ -103,7 +143,10 @@ func (m *Manager) DeleteService(svc *Service) error {
 
 // CreateDestination adds a new real server to the given IPVS service.
 func (m *Manager) CreateDestination(svc *Service, dst *Destination) error {
-	if err := m.handle.NewDestination(svc, dst); err != nil {
+	if err := m.mutate(func() error {
+		defer m.invalidateDestinations(svc)
+		return m.handle.NewDestination(svc, dst)
+	}); err != nil {
 		return fmt.Errorf("failed to create destination %s:%d for service %s:%d: %w",
 			dst.Address, dst.Port, svc.Address, svc.Port, err)
 	}
@@ -117,7 +160,10 @@ func (m *Manager) CreateDestination(svc *Service, dst *Destination) error {
 
 // UpdateDestination updates an existing real server in the given IPVS service.
 func (m *Manager) UpdateDestination(svc *Service, dst *Destination) error {
-	if err := m.handle.UpdateDestination(svc, dst); err != nil {
+	if err := m.mutate(func() error {
+		defer m.invalidateDestinations(svc)
+		return m.handle.UpdateDestination(svc, dst)
+	}); err != nil {
 		return fmt.Errorf("failed to update destination %s:%d for service %s:%d: %w",
 			dst.Address, dst.Port, svc.Address, svc.Port, err)
 	}
@@ -131,7 +177,10 @@ func (m *Manager) UpdateDestination(svc *Service, dst *Destination) error {
 
 // DeleteDestination removes a real server from the given IPVS service.
 func (m *Manager) DeleteDestination(svc *Service, dst *Destination) error {
-	if err := m.handle.DelDestination(svc, dst); err != nil {
+	if err := m.mutate(func() error {
+		defer m.invalidateDestinations(svc)
+		return m.handle.DelDestination(svc, dst)
+	}); err != nil {
 		return fmt.Errorf("failed to delete destination %s:%d for service %s:%d: %w",
 			dst.Address, dst.Port, svc.Address, svc.Port, err)
 	}
@@ -144,7 +193,10 @@ func (m *Manager) DeleteDestination(svc *Service, dst *Destination) error {
 
 // Flush removes all IPVS services and destinations.
 func (m *Manager) Flush() error {
-	if err := m.handle.Flush(); err != nil {
+	if err := m.mutate(func() error {
+		defer m.InvalidateCache()
+		return m.handle.Flush()
+	}); err != nil {
 		return fmt.Errorf("failed to flush IPVS rules: %w", err)
 	}
 	m.logger.Info("flushed all IPVS rules")
@@ -1,6 +1,7 @@
 package lvs
 
 import (
+	"errors"
 	"net"
 	"testing"
 )
@@ -281,3 +282,93 @@ func TestManager_MultiServiceMultiDestination_Isolation(t *testing.T) {
 		t.Errorf("svc2 destinations have unexpected addresses: %v", destAddrs)
 	}
 }
+
+func TestManager_ReadOnlyRefusesChanges(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+
+	svc := newTestService("10.0.0.1", 80, 6, "rr")
+	if err := mgr.CreateService(svc); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+
+	mgr.SetReadOnly(true)
+	dst := newTestDestination("192.168.1.10", 8080, 1)
+	changes := map[string]error{
+		"CreateService":     mgr.CreateService(newTestService("10.0.0.2", 80, 6, "rr")),
+		"UpdateService":     mgr.UpdateService(svc),
+		"DeleteService":     mgr.DeleteService(svc),
+		"CreateDestination": mgr.CreateDestination(svc, dst),
+		"Flush":             mgr.Flush(),
+	}
+	for name, err := range changes {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: expected ErrReadOnly, got %v", name, err)
+		}
+		if class := ClassifyError(err); class != ErrorClassReadOnly {
+			t.Errorf("%s: expected error class %q, got %q", name, ErrorClassReadOnly, class)
+		}
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed in read-only mode: %v", err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expected the existing service to be kept, got %d", len(services))
+	}
+
+	mgr.SetReadOnly(false)
+	if err := mgr.DeleteService(svc); err != nil {
+		t.Errorf("DeleteService failed after leaving read-only mode: %v", err)
+	}
+}
+
+func TestManager_ApplyDestinations(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+
+	svc := newTestService("10.0.0.1", 80, 6, "rr")
+	if err := mgr.CreateService(svc); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	kept := newTestDestination("192.168.1.10", 8080, 1)
+	removed := newTestDestination("192.168.1.11", 8080, 1)
+	for _, dst := range []*Destination{kept, removed} {
+		if err := mgr.CreateDestination(svc, dst); err != nil {
+			t.Fatalf("CreateDestination failed: %v", err)
+		}
+	}
+
+	reweighted := newTestDestination("192.168.1.10", 8080, 5)
+	errs := mgr.ApplyDestinations(svc, []DestinationOp{
+		{Action: ActionUpdate, Destination: reweighted},
+		{Action: ActionCreate, Destination: newTestDestination("192.168.1.12", 8080, 2)},
+		{Action: ActionDelete, Destination: newTestDestination("192.168.1.99", 8080, 1)},
+		{Action: ActionDelete, Destination: removed},
+	})
+	if len(errs) != 4 {
+		t.Fatalf("expected an error slot per change, got %d", len(errs))
+	}
+	for i, err := range errs {
+		if (err != nil) != (i == 2) {
+			t.Errorf("change %d: unexpected error %v", i, err)
+		}
+	}
+
+	dests, _ := mgr.GetDestinations(svc)
+	weights := make(map[string]int)
+	for _, dst := range dests {
+		weights[dst.Address.String()] = dst.Weight
+	}
+	if len(weights) != 2 || weights["192.168.1.10"] != 5 || weights["192.168.1.12"] != 2 {
+		t.Errorf("expected the failed change not to stop the others, got %v", weights)
+	}
+
+	mgr.SetReadOnly(true)
+	for _, err := range mgr.ApplyDestinations(svc, []DestinationOp{{Action: ActionDelete, Destination: reweighted}}) {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("expected ErrReadOnly, got %v", err)
+		}
+	}
+}
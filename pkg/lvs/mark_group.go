@@ -0,0 +1,51 @@
+package lvs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+)
+
+// hasMarkGroups reports whether any service marks its own traffic and thus
+// needs MARK rules.
+func hasMarkGroups(configs []config.ServiceConfig) bool {
+	for _, svcCfg := range configs {
+		if len(svcCfg.MarkGroup) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileMarks builds the MARK rules of all mark groups, one per VIP:port,
+// and delegates to the SNAT manager for declarative reconciliation. Rules of
+// groups no longer configured are removed.
+func (r *Reconciler) reconcileMarks(configs []config.ServiceConfig) error {
+	var desired []snat.MarkRule
+	for _, svcCfg := range configs {
+		protocol := svcCfg.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		for _, address := range svcCfg.MarkGroup {
+			host, portStr, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("service %q, mark_group %q: invalid address: %w", svcCfg.Name, address, err)
+			}
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				return fmt.Errorf("service %q, mark_group %q: invalid port: %w", svcCfg.Name, address, err)
+			}
+			desired = append(desired, snat.MarkRule{
+				VIP:      host,
+				Protocol: protocol,
+				Mark:     svcCfg.FWMark,
+				Port:     uint16(port),
+			})
+		}
+	}
+	return r.snatMgr.ReconcileMark(desired)
+}
@@ -0,0 +1,134 @@
+package lvs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// foreignDestination reports whether a destination of a merge service was
+// not created by this reconciler and must therefore be left alone. The
+// caller must hold r.mu.
+func (r *Reconciler) foreignDestination(desired *DesiredService, service ServiceKey, dest DestinationKey) bool {
+	return desired.Config.Merge && !r.owned[drainKey{service: service, dest: dest}]
+}
+
+// setMerge records whether a managed service is in merge mode. A service
+// leaving merge mode forgets its owned destinations, as every destination
+// not in the config is then deleted anyway. The caller must hold r.mu.
+func (r *Reconciler) setMerge(service ServiceKey, merge bool) {
+	if merge {
+		r.merged[service] = true
+	} else if r.merged[service] {
+		r.forgetMerge(service)
+	}
+}
+
+// forgetMerge forgets the merge mode and owned destinations of a service.
+// The caller must hold r.mu.
+func (r *Reconciler) forgetMerge(service ServiceKey) {
+	delete(r.merged, service)
+	for key := range r.owned {
+		if key.service == service {
+			delete(r.owned, key)
+		}
+	}
+}
+
+// trackOwnership records a destination created in a merge service as owned
+// and forgets a deleted one. The caller must hold r.mu.
+func (r *Reconciler) trackOwnership(desired *DesiredService, action string, change DestinationChange) {
+	key := drainKey{service: change.Service, dest: change.Key}
+	switch {
+	case action == ActionDelete:
+		delete(r.owned, key)
+	case action == ActionCreate && desired.Config.Merge:
+		r.owned[key] = true
+	}
+}
+
+// deleteMergedService deletes the owned destinations of a merge service that
+// left the config, and the service itself only if no destinations of another
+// controller remain. The caller must hold r.mu.
+func (r *Reconciler) deleteMergedService(service ServiceKey, svc *Service) error {
+	dests, err := r.manager.GetDestinations(svc)
+	if err != nil {
+		return fmt.Errorf("get destinations for %s: %w", service, err)
+	}
+	var ops []DestinationOp
+	for _, dst := range dests {
+		if r.owned[drainKey{service: service, dest: DestinationKeyFromIPVS(dst)}] {
+			ops = append(ops, DestinationOp{Action: ActionDelete, Destination: dst})
+		}
+	}
+
+	var errs []error
+	for i, err := range r.manager.ApplyDestinations(svc, ops) {
+		dest := DestinationKeyFromIPVS(ops[i].Destination)
+		r.record("", ResourceDestination, ActionDelete, dest.String(), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delete destination %s of service %s: %w", dest, service, err))
+		} else {
+			delete(r.owned, drainKey{service: service, dest: dest})
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if foreign := len(dests) - len(ops); foreign > 0 {
+		r.logger.Info("kept merge service with destinations of another controller",
+			zap.String("service", service.String()),
+			zap.Int("destinations", foreign),
+		)
+	} else {
+		err = r.manager.DeleteService(svc)
+		r.record("", ResourceService, ActionDelete, service.String(), err)
+		if err != nil {
+			return fmt.Errorf("delete service %s: %w", service, err)
+		}
+	}
+	r.forgetMerge(service)
+	return nil
+}
+
+// ownedDestinations returns, by service, the owned destinations of the
+// merge services for the state file. The caller must hold r.mu.
+func (r *Reconciler) ownedDestinations() map[string][]string {
+	if len(r.merged) == 0 {
+		return nil
+	}
+	owned := make(map[string][]string, len(r.merged))
+	for service := range r.merged {
+		owned[service.String()] = []string{}
+	}
+	for key := range r.owned {
+		service := key.service.String()
+		owned[service] = append(owned[service], key.dest.String())
+	}
+	for _, dests := range owned {
+		slices.Sort(dests)
+	}
+	return owned
+}
+
+// parseDestinationKey parses a DestinationKey in the form of its String method.
+func parseDestinationKey(s string) (DestinationKey, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return DestinationKey{}, fmt.Errorf("invalid destination %q: %w", s, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return DestinationKey{}, fmt.Errorf("invalid destination %q: invalid IP address %q", s, host)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return DestinationKey{}, fmt.Errorf("invalid destination %q: invalid port %q", s, portStr)
+	}
+	return DestinationKey{Address: ip.String(), Port: uint16(port)}, nil
+}
@@ -0,0 +1,146 @@
+package lvs
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"syscall"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
+)
+
+// destinationAddresses returns the sorted destination addresses of the
+// service at 10.0.0.1:80, or nil if the service does not exist.
+func destinationAddresses(t *testing.T, mgr *Manager) []string {
+	t.Helper()
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	for _, svc := range services {
+		if svc.Address.String() != "10.0.0.1" {
+			continue
+		}
+		dests, err := mgr.GetDestinations(svc)
+		if err != nil {
+			t.Fatalf("GetDestinations failed: %v", err)
+		}
+		addresses := []string{}
+		for _, dst := range dests {
+			addresses = append(addresses, dst.Address.String())
+		}
+		slices.Sort(addresses)
+		return addresses
+	}
+	return nil
+}
+
+func TestReconcile_MergeKeepsForeignDestinations(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	svcCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", false,
+		makeBackend("192.168.1.1:8080", 1),
+		makeBackend("192.168.1.2:8080", 1))
+	svcCfg.Merge = true
+	configs := []config.ServiceConfig{svcCfg}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// Another controller adds its own destination to the same service
+	foreign := newTestDestination("192.168.9.9", 8080, 1)
+	if err := mgr.CreateDestination(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr"), foreign); err != nil {
+		t.Fatalf("CreateDestination failed: %v", err)
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if want, got := []string{"192.168.1.1", "192.168.1.2", "192.168.9.9"}, destinationAddresses(t, mgr); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected destinations %v, got %v", want, got)
+	}
+	drifts, err := reconciler.Drift(configs)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift for the foreign destination, got %+v", drifts)
+	}
+
+	// Removing a backend deletes only the destination ezlb created
+	configs[0].Backends = configs[0].Backends[:1]
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if want, got := []string{"192.168.1.1", "192.168.9.9"}, destinationAddresses(t, mgr); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected destinations %v, got %v", want, got)
+	}
+
+	// Removing the service keeps it for the other controller's destination
+	if err := reconciler.Reconcile(nil); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if want, got := []string{"192.168.9.9"}, destinationAddresses(t, mgr); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected destinations %v, got %v", want, got)
+	}
+}
+
+func TestReconcile_MergeOffDeletesForeignDestinations(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	svcCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1))
+	svcCfg.Merge = true
+	configs := []config.ServiceConfig{svcCfg}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := mgr.CreateDestination(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr"), newTestDestination("192.168.9.9", 8080, 1)); err != nil {
+		t.Fatalf("CreateDestination failed: %v", err)
+	}
+
+	configs[0].Merge = false
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if want, got := []string{"192.168.1.1"}, destinationAddresses(t, mgr); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected destinations %v, got %v", want, got)
+	}
+}
+
+func TestReconciler_StateKeepsOwnedDestinationsOfMergeServices(t *testing.T) {
+	mgr, _, first := newReconcilerTestEnv(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	svcCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", false,
+		makeBackend("192.168.1.1:8080", 1),
+		makeBackend("192.168.1.2:8080", 1))
+	svcCfg.Merge = true
+	if err := first.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := mgr.CreateDestination(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr"), newTestDestination("192.168.9.9", 8080, 1)); err != nil {
+		t.Fatalf("CreateDestination failed: %v", err)
+	}
+	if err := first.SaveState(path); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	// After a restart, the destinations created by the first run are still
+	// deleted when they leave the config, and the foreign one is kept.
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	second := NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.NewNop())
+	if err := second.LoadState(path); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	svcCfg.Backends = svcCfg.Backends[:1]
+	if err := second.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if want, got := []string{"192.168.1.1", "192.168.9.9"}, destinationAddresses(t, mgr); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected destinations %v, got %v", want, got)
+	}
+}
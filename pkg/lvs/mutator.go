@@ -0,0 +1,78 @@
+package lvs
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DesiredStateMutator is a compiled-in plugin that adjusts the desired IPVS
+// state after it has been built from config and backend health, and before it
+// is applied or compared for drift. Mutators can inject policy such as weight
+// caps, mandatory backends or blocklists without changing the reconciler.
+//
+// MutateDesired may change destinations and service settings in place, add
+// services or delete them from the map; deleted services that exist in IPVS
+// are removed like services dropped from the config. Returning an error
+// aborts the reconcile so no partially mutated state is applied.
+type DesiredStateMutator interface {
+	Name() string
+	MutateDesired(desired map[ServiceKey]*DesiredService) error
+}
+
+var (
+	registeredMutators   []DesiredStateMutator
+	registeredMutatorsMu sync.Mutex
+)
+
+// RegisterMutator registers a mutator for every Reconciler created afterwards.
+// Plugins call it from an init function, so importing the plugin package into
+// a custom build is enough to enable it. Mutators run in registration order.
+func RegisterMutator(mutator DesiredStateMutator) {
+	registeredMutatorsMu.Lock()
+	defer registeredMutatorsMu.Unlock()
+	registeredMutators = append(registeredMutators, mutator)
+}
+
+// defaultMutators returns the mutators registered so far.
+func defaultMutators() []DesiredStateMutator {
+	registeredMutatorsMu.Lock()
+	defer registeredMutatorsMu.Unlock()
+	return append([]DesiredStateMutator(nil), registeredMutators...)
+}
+
+// AddMutator appends a mutator to this Reconciler only. It takes effect on
+// the next Reconcile.
+func (r *Reconciler) AddMutator(mutator DesiredStateMutator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mutators = append(r.mutators, mutator)
+}
+
+// mutateDesired runs the mutators over the desired state. Maintenance mode
+// is enforced again afterwards, so a mutator cannot undo the drain.
+func (r *Reconciler) mutateDesired(desired map[ServiceKey]*DesiredService) error {
+	if len(r.mutators) == 0 {
+		return nil
+	}
+
+	for _, mutator := range r.mutators {
+		if err := mutator.MutateDesired(desired); err != nil {
+			return fmt.Errorf("mutator %q: %w", mutator.Name(), err)
+		}
+		r.logger.Debug("applied desired state mutator", zap.String("mutator", mutator.Name()))
+	}
+
+	for key, svc := range desired {
+		if svc == nil || svc.Service == nil {
+			return fmt.Errorf("mutator left service %s without an IPVS service", key)
+		}
+		if r.InMaintenance() {
+			for _, dst := range svc.Destinations {
+				dst.Weight = 0
+			}
+		}
+	}
+	return nil
+}
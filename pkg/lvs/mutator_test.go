@@ -0,0 +1,181 @@
+package lvs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// weightCapMutator caps every destination weight.
+type weightCapMutator struct {
+	max int
+}
+
+func (m weightCapMutator) Name() string { return "weight-cap" }
+
+func (m weightCapMutator) MutateDesired(desired map[ServiceKey]*DesiredService) error {
+	for _, svc := range desired {
+		for _, dst := range svc.Destinations {
+			dst.Weight = min(dst.Weight, m.max)
+		}
+	}
+	return nil
+}
+
+// blocklistMutator drops a service by name.
+type blocklistMutator struct {
+	service string
+}
+
+func (m blocklistMutator) Name() string { return "blocklist" }
+
+func (m blocklistMutator) MutateDesired(desired map[ServiceKey]*DesiredService) error {
+	for key, svc := range desired {
+		if svc.Config.Name == m.service {
+			delete(desired, key)
+		}
+	}
+	return nil
+}
+
+// failingMutator rejects every desired state.
+type failingMutator struct{}
+
+func (failingMutator) Name() string { return "failing" }
+
+func (failingMutator) MutateDesired(map[ServiceKey]*DesiredService) error {
+	return errors.New("policy violated")
+}
+
+func TestReconcile_MutatorCapsWeights(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+	reconciler.AddMutator(weightCapMutator{max: 3})
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", false,
+			makeBackend("192.168.1.1:8080", 10),
+			makeBackend("192.168.1.2:8080", 2)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil || len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d (err %v)", len(services), err)
+	}
+	dests, err := mgr.GetDestinations(services[0])
+	if err != nil {
+		t.Fatalf("GetDestinations failed: %v", err)
+	}
+	weights := make(map[string]int)
+	for _, dst := range dests {
+		weights[dst.Address.String()] = dst.Weight
+	}
+	if weights["192.168.1.1"] != 3 || weights["192.168.1.2"] != 2 {
+		t.Errorf("expected weights capped at 3, got %v", weights)
+	}
+}
+
+func TestReconcile_MutatorRemovesService(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+		makeServiceConfig("svc2", "10.0.0.2:80", "rr", false, makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	reconciler.AddMutator(blocklistMutator{service: "svc2"})
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 1 || services[0].Address.String() != "10.0.0.1" {
+		t.Errorf("expected only svc1 to remain, got %d services", len(services))
+	}
+}
+
+func TestReconcile_MutatorErrorAbortsReconcile(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+	reconciler.AddMutator(failingMutator{})
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+	}
+	err := reconciler.Reconcile(configs)
+	if err == nil || !strings.Contains(err.Error(), `mutator "failing"`) {
+		t.Fatalf("expected mutator error, got %v", err)
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 0 {
+		t.Errorf("expected nothing applied after a mutator error, got %d services", len(services))
+	}
+}
+
+func TestReconcile_MutatorCannotOverrideMaintenance(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+	reconciler.AddMutator(mutatorFunc(func(desired map[ServiceKey]*DesiredService) error {
+		for _, svc := range desired {
+			for _, dst := range svc.Destinations {
+				dst.Weight = 7
+			}
+		}
+		return nil
+	}))
+	reconciler.SetMaintenance(true)
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 5)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, _ := mgr.GetServices()
+	dests, err := mgr.GetDestinations(services[0])
+	if err != nil || len(dests) != 1 {
+		t.Fatalf("expected 1 destination, got %d (err %v)", len(dests), err)
+	}
+	if dests[0].Weight != 0 {
+		t.Errorf("expected weight 0 in maintenance, got %d", dests[0].Weight)
+	}
+}
+
+func TestRegisterMutator(t *testing.T) {
+	saved := registeredMutators
+	t.Cleanup(func() { registeredMutators = saved })
+
+	RegisterMutator(weightCapMutator{max: 1})
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	if len(reconciler.mutators) != len(saved)+1 {
+		t.Fatalf("expected registered mutator to be picked up, got %d mutators", len(reconciler.mutators))
+	}
+}
+
+// mutatorFunc adapts a function to DesiredStateMutator.
+type mutatorFunc func(map[ServiceKey]*DesiredService) error
+
+func (f mutatorFunc) Name() string { return "func" }
+
+func (f mutatorFunc) MutateDesired(desired map[ServiceKey]*DesiredService) error {
+	return f(desired)
+}
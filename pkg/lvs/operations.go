@@ -0,0 +1,109 @@
+package lvs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Resource kinds of an Operation.
+const (
+	ResourceService     = "service"
+	ResourceDestination = "destination"
+	ResourceSNAT        = "snat"
+	ResourceMark        = "mark"
+	ResourceDSCP        = "dscp"
+	ResourceConntrack   = "conntrack"
+)
+
+// Actions of an Operation.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionSync   = "sync"
+)
+
+// Error classes returned by ClassifyError.
+const (
+	ErrorClassPermission  = "permission_denied"
+	ErrorClassNotFound    = "not_found"
+	ErrorClassExists      = "already_exists"
+	ErrorClassUnsupported = "unsupported"
+	ErrorClassInvalid     = "invalid_argument"
+	ErrorClassBusy        = "busy"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassReadOnly    = "read_only"
+	ErrorClassOther       = "other"
+)
+
+// Operation is a single change attempted by Reconcile.
+type Operation struct {
+	Err      error
+	Service  string // config service name, empty for services no longer configured
+	Resource string
+	Action   string
+	Target   string // service or destination key
+}
+
+// String returns a human-readable representation of the Operation, without
+// its error.
+func (o Operation) String() string {
+	if o.Service == "" {
+		return fmt.Sprintf("%s %s %s", o.Action, o.Resource, o.Target)
+	}
+	return fmt.Sprintf("service %q: %s %s %s", o.Service, o.Action, o.Resource, o.Target)
+}
+
+// LastOperations returns the changes attempted by the most recent Reconcile,
+// in the order they were applied.
+func (r *Reconciler) LastOperations() []Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make([]Operation, len(r.operations))
+	copy(ops, r.operations)
+	return ops
+}
+
+// record appends an attempted change to the current Reconcile's operations.
+// Must be called with r.mu held.
+func (r *Reconciler) record(service, resource, action, target string, err error) {
+	r.operations = append(r.operations, Operation{
+		Err:      err,
+		Service:  service,
+		Resource: resource,
+		Action:   action,
+		Target:   target,
+	})
+}
+
+// ClassifyError maps an IPVS or iptables error to a coarse class that
+// automation can act on without parsing messages.
+func ClassifyError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrReadOnly):
+		return ErrorClassReadOnly
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		return ErrorClassPermission
+	case errors.Is(err, syscall.ENOENT), errors.Is(err, syscall.ESRCH), errors.Is(err, os.ErrNotExist):
+		return ErrorClassNotFound
+	case errors.Is(err, syscall.EEXIST):
+		return ErrorClassExists
+	case errors.Is(err, syscall.EOPNOTSUPP), errors.Is(err, syscall.EAFNOSUPPORT),
+		errors.Is(err, syscall.EPROTONOSUPPORT), errors.Is(err, syscall.ENOPROTOOPT):
+		return ErrorClassUnsupported
+	case errors.Is(err, syscall.EINVAL):
+		return ErrorClassInvalid
+	case errors.Is(err, syscall.EBUSY), errors.Is(err, syscall.EAGAIN):
+		return ErrorClassBusy
+	case errors.Is(err, syscall.ETIMEDOUT), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	default:
+		return ErrorClassOther
+	}
+}
@@ -0,0 +1,78 @@
+package lvs
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestReconcile_RecordsOperations(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	ops := reconciler.LastOperations()
+	if len(ops) != 3 {
+		t.Fatalf("expected 1 service and 2 destination operations, got %v", ops)
+	}
+	if ops[0].Resource != ResourceService || ops[0].Action != ActionCreate || ops[0].Target != "10.0.0.1:80/tcp" {
+		t.Errorf("unexpected first operation: %+v", ops[0])
+	}
+	for _, op := range ops {
+		if op.Err != nil || op.Service != "svc1" {
+			t.Errorf("expected successful operation of svc1, got %+v", op)
+		}
+	}
+
+	// A reconcile without changes attempts nothing
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected no operations for an unchanged config, got %v", ops)
+	}
+
+	if err := reconciler.Reconcile(nil); err != nil {
+		t.Fatalf("third Reconcile failed: %v", err)
+	}
+	ops = reconciler.LastOperations()
+	if len(ops) != 1 || ops[0].Action != ActionDelete || ops[0].Service != "" {
+		t.Errorf("expected a single service deletion, got %v", ops)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("create service: %w", syscall.EPERM), ErrorClassPermission},
+		{errors.Join(errors.New("first"), fmt.Errorf("delete: %w", syscall.ENOENT)), ErrorClassNotFound},
+		{syscall.EEXIST, ErrorClassExists},
+		{syscall.EAFNOSUPPORT, ErrorClassUnsupported},
+		{syscall.EINVAL, ErrorClassInvalid},
+		{syscall.EBUSY, ErrorClassBusy},
+		{syscall.ETIMEDOUT, ErrorClassTimeout},
+		{errors.New("boom"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package lvs
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WeightOverride is a runtime weight that takes precedence over the configured
+// weight of a single backend until it expires or is explicitly reset.
+type WeightOverride struct {
+	ExpiresAt time.Time // zero means the override never expires
+	Service   string
+	Backend   string
+	Weight    int
+}
+
+// overrideKey identifies a backend within a named service.
+type overrideKey struct {
+	service string
+	backend string
+}
+
+// expired reports whether the override is no longer in effect at the given time.
+func (o WeightOverride) expired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !now.Before(o.ExpiresAt)
+}
+
+// SetWeightOverride registers (or replaces) a runtime weight override for a backend.
+// The override is applied on the next Reconcile.
+func (r *Reconciler) SetWeightOverride(override WeightOverride) {
+	r.overrideMu.Lock()
+	defer r.overrideMu.Unlock()
+	r.overrides[overrideKey{service: override.Service, backend: override.Backend}] = override
+}
+
+// ResetWeightOverride removes the runtime weight override for a backend.
+// It returns false if no override was registered.
+func (r *Reconciler) ResetWeightOverride(service, backend string) bool {
+	r.overrideMu.Lock()
+	defer r.overrideMu.Unlock()
+
+	key := overrideKey{service: service, backend: backend}
+	if _, exists := r.overrides[key]; !exists {
+		return false
+	}
+	delete(r.overrides, key)
+	return true
+}
+
+// WeightOverrides returns all overrides that are currently in effect, sorted by
+// service and backend. Expired overrides are pruned.
+func (r *Reconciler) WeightOverrides() []WeightOverride {
+	r.overrideMu.Lock()
+	defer r.overrideMu.Unlock()
+
+	now := time.Now()
+	result := make([]WeightOverride, 0, len(r.overrides))
+	for key, override := range r.overrides {
+		if override.expired(now) {
+			delete(r.overrides, key)
+			continue
+		}
+		result = append(result, override)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+		return result[i].Backend < result[j].Backend
+	})
+	return result
+}
+
+// weightOverride returns the active override weight for a backend, if any.
+// Expired overrides are pruned.
+func (r *Reconciler) weightOverride(service, backend string) (int, bool) {
+	r.overrideMu.Lock()
+	defer r.overrideMu.Unlock()
+
+	key := overrideKey{service: service, backend: backend}
+	override, exists := r.overrides[key]
+	if !exists {
+		return 0, false
+	}
+	if override.expired(time.Now()) {
+		delete(r.overrides, key)
+		r.logger.Info("weight override expired",
+			zap.String("service", service),
+			zap.String("backend", backend),
+		)
+		return 0, false
+	}
+	return override.Weight, true
+}
@@ -0,0 +1,127 @@
+package lvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestReconcile_WeightOverrideApplied(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", false,
+			makeBackend("192.168.1.1:8080", 5),
+			makeBackend("192.168.1.2:8080", 5)),
+	}
+
+	reconciler.SetWeightOverride(WeightOverride{
+		Service: "svc1",
+		Backend: "192.168.1.1:8080",
+		Weight:  0,
+	})
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	weights := destinationWeights(t, mgr)
+	if weights["192.168.1.1:8080"] != 0 {
+		t.Errorf("expected overridden weight 0, got %d", weights["192.168.1.1:8080"])
+	}
+	if weights["192.168.1.2:8080"] != 5 {
+		t.Errorf("expected configured weight 5, got %d", weights["192.168.1.2:8080"])
+	}
+
+	// Reset restores the configured weight
+	if !reconciler.ResetWeightOverride("svc1", "192.168.1.1:8080") {
+		t.Fatal("expected ResetWeightOverride to report an existing override")
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weight := destinationWeights(t, mgr)["192.168.1.1:8080"]; weight != 5 {
+		t.Errorf("expected weight 5 after reset, got %d", weight)
+	}
+	if reconciler.ResetWeightOverride("svc1", "192.168.1.1:8080") {
+		t.Error("expected second ResetWeightOverride to report no override")
+	}
+}
+
+func TestReconcile_WeightOverrideExpires(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", false,
+			makeBackend("192.168.1.1:8080", 5)),
+	}
+
+	reconciler.SetWeightOverride(WeightOverride{
+		Service:   "svc1",
+		Backend:   "192.168.1.1:8080",
+		Weight:    1,
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if weight := destinationWeights(t, mgr)["192.168.1.1:8080"]; weight != 5 {
+		t.Errorf("expected expired override to be ignored, got weight %d", weight)
+	}
+	if overrides := reconciler.WeightOverrides(); len(overrides) != 0 {
+		t.Errorf("expected expired override to be pruned, got %v", overrides)
+	}
+}
+
+// destinationWeights returns the weight of every destination of the single configured service.
+func destinationWeights(t *testing.T, mgr *Manager) map[string]int {
+	t.Helper()
+	services, err := mgr.GetServices()
+	if err != nil || len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d (err=%v)", len(services), err)
+	}
+	dests, err := mgr.GetDestinations(services[0])
+	if err != nil {
+		t.Fatalf("GetDestinations failed: %v", err)
+	}
+	weights := make(map[string]int, len(dests))
+	for _, dst := range dests {
+		weights[DestinationKeyFromIPVS(dst).String()] = dst.Weight
+	}
+	return weights
+}
+
+func TestReconcile_WeightOverrideKeepsBackupInStandby(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.2.1:8080"] = true
+	backup := makeBackend("192.168.2.1:8080", 3)
+	backup.Role = config.RoleBackup
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			backup),
+	}
+
+	reconciler.SetWeightOverride(WeightOverride{Service: "svc1", Backend: "192.168.2.1:8080", Weight: 5})
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weight := destinationWeights(t, mgr)["192.168.2.1:8080"]; weight != 0 {
+		t.Errorf("expected backup to stay in standby despite the override, got weight %d", weight)
+	}
+
+	// Once the primary is down, the override applies to the backup
+	healthMgr.status["192.168.1.1:8080"] = false
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weight := destinationWeights(t, mgr)["192.168.2.1:8080"]; weight != 5 {
+		t.Errorf("expected overridden weight 5 for the active backup, got %d", weight)
+	}
+}
@@ -0,0 +1,78 @@
+package lvs
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// ReconcileServices is Reconcile limited to the named services, for when only
+// the health of their backends changed. Only their virtual services and
+// destinations are diffed, and only they and their destinations are read from
+// the kernel, so the cost does not grow with the number of services. The SNAT rules are reconciled if
+// one of them uses FullNAT; other services are left alone, none is deleted, and
+// the MARK and DSCP rules, which do not depend on health, are not touched.
+// Services a mutator removes are deleted by the next full Reconcile.
+func (r *Reconciler) ReconcileServices(desiredConfigs []config.ServiceConfig, names []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	desiredConfigs = config.ExpandListens(config.EnabledServices(desiredConfigs))
+	var selected []config.ServiceConfig
+	for _, svcCfg := range desiredConfigs {
+		if slices.Contains(names, svcCfg.Name) {
+			selected = append(selected, svcCfg)
+		}
+	}
+
+	r.logger.Info("starting partial reconcile", zap.Strings("services", names))
+	r.operations = nil
+
+	r.forgetServiceState(selected)
+	desiredMap, err := r.buildServices(selected)
+	if err != nil {
+		return fmt.Errorf("failed to build desired state: %w", err)
+	}
+	plan, err := r.planFor(desiredMap, true)
+	if err != nil {
+		return err
+	}
+	r.empty = slices.DeleteFunc(r.empty, func(name string) bool { return slices.Contains(names, name) })
+	r.empty = append(r.empty, emptyServiceNames(desiredMap)...)
+	slices.Sort(r.empty)
+
+	reconcileErrors := append(plan.errs, r.applyPlan(plan)...)
+
+	if hasFullNAT(selected) && !r.manager.IsReadOnly() {
+		snatErr := r.reconcileSNAT(desiredConfigs)
+		r.record("", ResourceSNAT, ActionSync, "iptables", snatErr)
+		if snatErr != nil {
+			reconcileErrors = append(reconcileErrors, fmt.Errorf("snat reconcile: %w", snatErr))
+		}
+	}
+
+	return r.reconcileResult(reconcileErrors)
+}
+
+// forgetServiceState drops the exclusions and backup standby marks of the
+// given services before their desired state is built again.
+func (r *Reconciler) forgetServiceState(configs []config.ServiceConfig) {
+	keys := make(map[ServiceKey]bool, len(configs))
+	names := make(map[string]bool, len(configs))
+	for _, svcCfg := range configs {
+		names[svcCfg.Name] = true
+		if key, err := ServiceKeyFromConfig(svcCfg); err == nil {
+			keys[key] = true
+		}
+	}
+	r.exclusions = slices.DeleteFunc(r.exclusions, func(exclusion Exclusion) bool {
+		return names[exclusion.Service]
+	})
+	for key := range r.backupStandby {
+		if keys[key.service] {
+			delete(r.backupStandby, key)
+		}
+	}
+}
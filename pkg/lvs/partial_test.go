@@ -0,0 +1,59 @@
+package lvs
+
+import (
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestReconciler_ReconcileServices(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+	healthMgr.status["192.168.2.1:8080"] = false
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.1:8080", 1), makeBackend("192.168.1.2:8080", 1))
+	api := makeServiceConfig("api", "10.0.0.2:80", "rr", true, makeBackend("192.168.2.1:8080", 1))
+	old := makeServiceConfig("old", "10.0.0.3:80", "rr", false, makeBackend("192.168.3.1:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{web, api, old}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if empty := reconciler.EmptyServices(); len(empty) != 1 || empty[0] != "api" {
+		t.Fatalf("expected api to be empty, got %v", empty)
+	}
+
+	// Only web is reconciled: api's recovered backend and the removed old
+	// service wait for the next full reconcile
+	healthMgr.status["192.168.1.2:8080"] = false
+	healthMgr.status["192.168.2.1:8080"] = true
+	if err := reconciler.ReconcileServices([]config.ServiceConfig{web, api}, []string{"web"}); err != nil {
+		t.Fatalf("ReconcileServices failed: %v", err)
+	}
+	ops := reconciler.LastOperations()
+	if len(ops) != 1 || ops[0].Service != "web" || ops[0].Action != ActionDelete || ops[0].Target != "192.168.1.2:8080" {
+		t.Fatalf("expected only web's unhealthy destination to be deleted, got %+v", ops)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 3 {
+		t.Errorf("expected the other services to be kept, got %d", len(services))
+	}
+	exclusions := reconciler.Exclusions()
+	if len(exclusions) != 2 || exclusions[0].Service != "api" || exclusions[1].Service != "web" {
+		t.Errorf("expected api's exclusion to be kept next to web's, got %+v", exclusions)
+	}
+	if empty := reconciler.EmptyServices(); len(empty) != 1 || empty[0] != "api" {
+		t.Errorf("expected api to stay reported as empty, got %v", empty)
+	}
+
+	// A full reconcile catches up with the rest
+	if err := reconciler.Reconcile([]config.ServiceConfig{web, api}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 2 {
+		t.Errorf("expected old to be deleted by the full reconcile, got %d services", len(services))
+	}
+	if empty := reconciler.EmptyServices(); len(empty) != 0 {
+		t.Errorf("expected no empty services, got %v", empty)
+	}
+}
@@ -0,0 +1,120 @@
+package lvs
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// connTablePath is the kernel's IPVS connection table, which also lists the
+// persistence templates. It is a variable so tests can use a fixture.
+var connTablePath = "/proc/net/ip_vs_conn"
+
+// PersistenceTemplate is an IPVS persistence template: the destination that
+// a client, or a client network with a persistence netmask, sticks to until
+// the template expires. A template outlives the client's connections by the
+// service's persistence timeout, so it keeps a client on a drained backend.
+type PersistenceTemplate struct {
+	Client      net.IP
+	Service     ServiceKey
+	Destination DestinationKey
+	Expires     time.Duration
+}
+
+// PersistenceTemplates reads the persistence templates from the kernel's
+// connection table.
+func PersistenceTemplates() ([]PersistenceTemplate, error) {
+	raw, err := os.ReadFile(connTablePath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", connTablePath, err)
+	}
+	templates, err := parseConnTable(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", connTablePath, err)
+	}
+	return templates, nil
+}
+
+// parseConnTable parses /proc/net/ip_vs_conn and returns its persistence
+// templates. Templates are the entries without a client port; firewall-mark
+// templates have protocol IP and carry the mark in place of the VIP.
+func parseConnTable(content string) ([]PersistenceTemplate, error) {
+	var templates []PersistenceTemplate
+	for i, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		// Skip the header line, connections and anything too short to hold
+		// the expiry
+		if i == 0 || len(fields) < 9 || fields[2] != "0000" {
+			continue
+		}
+		client, err := parseConnAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		vip, err := parseConnAddress(fields[3])
+		if err != nil {
+			return nil, err
+		}
+		vport, err := strconv.ParseUint(fields[4], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", fields[4])
+		}
+		backend, err := parseConnAddress(fields[5])
+		if err != nil {
+			return nil, err
+		}
+		backendPort, err := strconv.ParseUint(fields[6], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", fields[6])
+		}
+		expires, err := strconv.ParseUint(fields[8], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry %q", fields[8])
+		}
+
+		template := PersistenceTemplate{
+			Client:      client,
+			Destination: DestinationKey{Address: backend.String(), Port: uint16(backendPort)},
+			Expires:     time.Duration(expires) * time.Second,
+		}
+		switch fields[0] {
+		case "TCP":
+			template.Service = ServiceKey{Address: vip.String(), Port: uint16(vport), Protocol: syscall.IPPROTO_TCP}
+		case "UDP":
+			template.Service = ServiceKey{Address: vip.String(), Port: uint16(vport), Protocol: syscall.IPPROTO_UDP}
+		case "IP":
+			if vip.To4() != nil {
+				template.Service = ServiceKey{Address: net.IPv4zero.String(), FWMark: binary.BigEndian.Uint32(vip.To4())}
+			} else {
+				template.Service = ServiceKey{Address: net.IPv6unspecified.String(), FWMark: binary.BigEndian.Uint32(vip)}
+			}
+		default:
+			// Protocols ezlb does not configure, e.g. SCTP
+			continue
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// parseConnAddress decodes an address of the connection table: IPv4 as eight
+// hex digits in network byte order, IPv6 in colon notation.
+func parseConnAddress(value string) (net.IP, error) {
+	if strings.Contains(value, ":") {
+		if ip := net.ParseIP(value); ip != nil {
+			return ip, nil
+		}
+		return nil, fmt.Errorf("invalid address %q", value)
+	}
+	raw, err := hex.DecodeString(value)
+	if err != nil || len(raw) != net.IPv4len {
+		return nil, fmt.Errorf("invalid address %q", value)
+	}
+	return net.IP(raw), nil
+}
@@ -0,0 +1,67 @@
+package lvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPersistenceTemplates(t *testing.T) {
+	table := `Pro FromIP   FPrt ToIP     TPrt DestIP   DPrt State       Expires PEName PEData
+TCP C0A80001 D2A4 0A000001 0050 C0A8010A 1F90 ESTABLISHED     899
+TCP C0A80000 0000 0A000001 0050 C0A8010A 1F90 NONE            359
+UDP C0A80002 0000 0A000003 0035 C0A8030A 0035 UDP             120
+IP  C0A80001 0000 00000064 0000 C0A8040A 0000 NONE            299
+TCP 2001:0db8:0000:0000:0000:0000:0000:0064 0000 2001:0db8:0000:0000:0000:0000:0000:0001 01BB 2001:0db8:0000:0000:0000:0000:0000:000a 20FB NONE 60
+SCTP C0A80001 0000 0A000005 0B59 C0A8050A 0B59 NONE            30
+`
+	path := filepath.Join(t.TempDir(), "ip_vs_conn")
+	if err := os.WriteFile(path, []byte(table), 0o644); err != nil {
+		t.Fatalf("failed to write connection table: %v", err)
+	}
+	original := connTablePath
+	connTablePath = path
+	t.Cleanup(func() { connTablePath = original })
+
+	templates, err := PersistenceTemplates()
+	if err != nil {
+		t.Fatalf("PersistenceTemplates failed: %v", err)
+	}
+
+	want := []struct {
+		client      string
+		service     ServiceKey
+		destination string
+		expires     time.Duration
+	}{
+		{"192.168.0.0", ServiceKey{Address: "10.0.0.1", Port: 80, Protocol: syscall.IPPROTO_TCP}, "192.168.1.10:8080", 359 * time.Second},
+		{"192.168.0.2", ServiceKey{Address: "10.0.0.3", Port: 53, Protocol: syscall.IPPROTO_UDP}, "192.168.3.10:53", 120 * time.Second},
+		{"192.168.0.1", ServiceKey{Address: "0.0.0.0", FWMark: 100}, "192.168.4.10:0", 299 * time.Second},
+		{"2001:db8::64", ServiceKey{Address: "2001:db8::1", Port: 443, Protocol: syscall.IPPROTO_TCP}, "[2001:db8::a]:8443", 60 * time.Second},
+	}
+	if len(templates) != len(want) {
+		t.Fatalf("expected %d templates, got %d: %+v", len(want), len(templates), templates)
+	}
+	for i, tt := range want {
+		got := templates[i]
+		if got.Client.String() != tt.client || got.Service != tt.service || got.Destination.String() != tt.destination || got.Expires != tt.expires {
+			t.Errorf("template %d = %+v, want %+v", i, got, tt)
+		}
+	}
+}
+
+func TestPersistenceTemplates_Invalid(t *testing.T) {
+	if _, err := parseConnTable("Pro FromIP\nTCP C0A8 0000 0A000001 0050 C0A8010A 1F90 NONE 359\n"); err == nil || !strings.Contains(err.Error(), "invalid address") {
+		t.Errorf("expected an invalid address error, got %v", err)
+	}
+
+	original := connTablePath
+	connTablePath = filepath.Join(t.TempDir(), "missing")
+	t.Cleanup(func() { connTablePath = original })
+	if _, err := PersistenceTemplates(); err == nil {
+		t.Error("expected an error without a connection table")
+	}
+}
@@ -0,0 +1,352 @@
+package lvs
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// ServiceChange is a planned change of an IPVS service. Service is the
+// desired service, or the kernel's one for a delete.
+type ServiceChange struct {
+	Service *Service
+	Name    string // config service name, empty for deletes
+	Key     ServiceKey
+}
+
+// DestinationChange is a planned change of a destination of a desired
+// service. Destination is the desired destination, or the kernel's one for a
+// delete.
+type DestinationChange struct {
+	Destination *Destination
+	Name        string // config service name
+	Service     ServiceKey
+	Key         DestinationKey
+}
+
+// Plan lists the IPVS changes that bring the kernel in line with the desired
+// state. It is computed from a single read of the kernel before anything is
+// changed, and Reconcile applies it. The lists are sorted by service key,
+// then destination key; DestinationAdds are sorted by weight within a
+// service, the order in which they are created. iptables rules are not
+// planned, and destinations that a pre-stop hook holds are listed as deleted.
+type Plan struct {
+	ServiceAdds        []ServiceChange
+	ServiceUpdates     []ServiceChange
+	ServiceDeletes     []ServiceChange
+	DestinationAdds    []DestinationChange
+	DestinationUpdates []DestinationChange
+	DestinationDeletes []DestinationChange
+	// desired is the desired state the plan was computed for.
+	desired map[ServiceKey]*DesiredService
+	// partial marks a plan limited to some services (see ReconcileServices).
+	partial bool
+	// errs lists why the destinations of some services could not be
+	// planned; their destination changes are missing.
+	errs []error
+}
+
+// Empty reports whether the plan changes nothing.
+func (p *Plan) Empty() bool {
+	return len(p.ServiceAdds) == 0 && len(p.ServiceUpdates) == 0 && len(p.ServiceDeletes) == 0 &&
+		len(p.DestinationAdds) == 0 && len(p.DestinationUpdates) == 0 && len(p.DestinationDeletes) == 0
+}
+
+// Operations returns the plan as the operations Reconcile would record, in
+// the order it applies them: each service in key order followed by its
+// destination updates, creates and deletes, then the deletions of services no
+// longer desired.
+func (p *Plan) Operations() []Operation {
+	adds := serviceChangesByKey(p.ServiceAdds)
+	updates := serviceChangesByKey(p.ServiceUpdates)
+	destUpdates := destinationChangesByService(p.DestinationUpdates)
+	destAdds := destinationChangesByService(p.DestinationAdds)
+	destDeletes := destinationChangesByService(p.DestinationDeletes)
+
+	keys := make(map[ServiceKey]bool)
+	for _, changes := range []map[ServiceKey][]DestinationChange{destUpdates, destAdds, destDeletes} {
+		for key := range changes {
+			keys[key] = true
+		}
+	}
+	for key := range adds {
+		keys[key] = true
+	}
+	for key := range updates {
+		keys[key] = true
+	}
+
+	var ops []Operation
+	for _, key := range sortedKeys(keys) {
+		if change, ok := adds[key]; ok {
+			ops = append(ops, Operation{Service: change.Name, Resource: ResourceService, Action: ActionCreate, Target: key.String()})
+		}
+		if change, ok := updates[key]; ok {
+			ops = append(ops, Operation{Service: change.Name, Resource: ResourceService, Action: ActionUpdate, Target: key.String()})
+		}
+		ops = appendDestinationOperations(ops, ActionUpdate, destUpdates[key])
+		ops = appendDestinationOperations(ops, ActionCreate, destAdds[key])
+		ops = appendDestinationOperations(ops, ActionDelete, destDeletes[key])
+	}
+	for _, change := range p.ServiceDeletes {
+		ops = append(ops, Operation{Resource: ResourceService, Action: ActionDelete, Target: change.Key.String()})
+	}
+	return ops
+}
+
+// appendDestinationOperations appends an operation with the given action for
+// every destination change.
+func appendDestinationOperations(ops []Operation, action string, changes []DestinationChange) []Operation {
+	for _, change := range changes {
+		ops = append(ops, Operation{Service: change.Name, Resource: ResourceDestination, Action: action, Target: change.Key.String()})
+	}
+	return ops
+}
+
+// Plan computes the changes Reconcile would apply for the given configs
+// without touching the kernel. Services and destinations are selected exactly
+// as Reconcile does, including the managed set and prune mode, so a service
+// dropped from the config since the last run is planned for deletion. The
+// exclusions, backup standby and drained state the plan is built with are
+// restored afterwards, so planning does not change the next Reconcile.
+func (r *Reconciler) Plan(desiredConfigs []config.ServiceConfig) (*Plan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.restoreDesiredState(r.saveDesiredState())
+
+	plan, err := r.buildPlan(config.ExpandListens(config.EnabledServices(desiredConfigs)))
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.errs) > 0 {
+		return nil, errors.Join(plan.errs...)
+	}
+	return plan, nil
+}
+
+// desiredStateSnapshot holds the reconciler state written while building the
+// desired state.
+type desiredStateSnapshot struct {
+	exclusions    []Exclusion
+	backupStandby map[drainKey]bool
+	drained       map[drainKey]bool
+}
+
+// saveDesiredState returns a copy of the state buildDesiredState writes.
+// Must be called with r.mu held.
+func (r *Reconciler) saveDesiredState() desiredStateSnapshot {
+	return desiredStateSnapshot{
+		exclusions:    slices.Clone(r.exclusions),
+		backupStandby: maps.Clone(r.backupStandby),
+		drained:       maps.Clone(r.drained),
+	}
+}
+
+// restoreDesiredState puts back state saved by saveDesiredState. Must be
+// called with r.mu held.
+func (r *Reconciler) restoreDesiredState(snapshot desiredStateSnapshot) {
+	r.exclusions = snapshot.exclusions
+	r.backupStandby = snapshot.backupStandby
+	r.drained = snapshot.drained
+}
+
+// buildPlan builds the desired state of the expanded configs and diffs it
+// against the kernel. Must be called with r.mu held.
+func (r *Reconciler) buildPlan(desiredConfigs []config.ServiceConfig) (*Plan, error) {
+	desiredMap, err := r.buildDesiredState(desiredConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build desired state: %w", err)
+	}
+	return r.planFor(desiredMap, false)
+}
+
+// planFor diffs a desired state against the kernel. A partial plan only
+// covers the services of the desired state and deletes none. Must be called
+// with r.mu held.
+func (r *Reconciler) planFor(desiredMap map[ServiceKey]*DesiredService, partial bool) (*Plan, error) {
+	actualServices, err := r.actualServices(desiredMap, partial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current IPVS services: %w", err)
+	}
+
+	// Include services that are either managed by ezlb or present in the
+	// desired state. A fresh Reconciler, after a restart or in `once` mode,
+	// thereby adopts the kernel services that match the config and diffs them
+	// in place, keeping their destinations and connections. Prune mode
+	// includes every service, so the unmanaged ones are deleted. Ignored
+	// services are left out, so they are never changed or deleted.
+	prune := r.prune.Load() && !partial
+	actualMap := make(map[ServiceKey]*Service)
+	for _, svc := range actualServices {
+		key := ServiceKeyFromIPVS(svc)
+		if r.ignoredService(key) {
+			continue
+		}
+		if (r.managed[key] && !partial) || desiredMap[key] != nil || prune {
+			actualMap[key] = svc
+		}
+	}
+
+	plan := &Plan{desired: desiredMap, partial: partial}
+	for _, key := range sortedServiceKeys(desiredMap) {
+		desired := desiredMap[key]
+		name := desired.Config.Name
+		actual, exists := actualMap[key]
+		if !exists {
+			plan.ServiceAdds = append(plan.ServiceAdds, ServiceChange{Service: desired.Service, Name: name, Key: key})
+			plan.DestinationAdds = append(plan.DestinationAdds, destinationChanges(name, key, sortedForCreate(slices.Clone(desired.Destinations)))...)
+			continue
+		}
+		if serviceNeedsUpdate(actual, desired.Service) {
+			plan.ServiceUpdates = append(plan.ServiceUpdates, ServiceChange{Service: desired.Service, Name: name, Key: key})
+		}
+		if err := r.planDestinations(plan, desired, key, actual); err != nil {
+			plan.errs = append(plan.errs, err)
+		}
+	}
+
+	for key, actual := range actualMap {
+		if desiredMap[key] == nil {
+			plan.ServiceDeletes = append(plan.ServiceDeletes, ServiceChange{Service: actual, Key: key})
+		}
+	}
+	sort.Slice(plan.ServiceDeletes, func(i, j int) bool {
+		return plan.ServiceDeletes[i].Key.String() < plan.ServiceDeletes[j].Key.String()
+	})
+	return plan, nil
+}
+
+// actualServices returns the kernel services to plan against. A partial
+// plan only covers the desired services, so each is looked up on its own
+// rather than listing every service.
+func (r *Reconciler) actualServices(desiredMap map[ServiceKey]*DesiredService, partial bool) ([]*Service, error) {
+	if !partial {
+		return r.manager.cachedServices()
+	}
+	var services []*Service
+	for _, key := range sortedServiceKeys(desiredMap) {
+		svc, err := r.manager.GetService(key)
+		if errors.Is(err, ErrServiceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+// planDestinations adds the destination changes of an existing service to
+// the plan.
+func (r *Reconciler) planDestinations(plan *Plan, desired *DesiredService, key ServiceKey, actual *Service) error {
+	actualDests, err := r.manager.cachedDestinations(actual)
+	if err != nil {
+		return fmt.Errorf("get destinations for %s:%d: %w", actual.Address, actual.Port, err)
+	}
+	actualDestMap := make(map[DestinationKey]*Destination, len(actualDests))
+	for _, dst := range actualDests {
+		actualDestMap[DestinationKeyFromIPVS(dst)] = dst
+	}
+
+	name := desired.Config.Name
+	var updates, creates, deletes []*Destination
+	desiredKeys := make(map[DestinationKey]bool, len(desired.Destinations))
+	for _, desiredDst := range desired.Destinations {
+		destKey := DestinationKeyFromIPVS(desiredDst)
+		desiredKeys[destKey] = true
+		actualDst, exists := actualDestMap[destKey]
+		switch {
+		case !exists:
+			creates = append(creates, desiredDst)
+		case actualDst.Weight != desiredDst.Weight ||
+			actualDst.ConnectionFlags&ConnectionFlagFwdMask != desiredDst.ConnectionFlags&ConnectionFlagFwdMask:
+			updates = append(updates, desiredDst)
+		}
+	}
+	for destKey, actualDst := range actualDestMap {
+		if !desiredKeys[destKey] && !r.foreignDestination(desired, key, destKey) {
+			deletes = append(deletes, actualDst)
+		}
+	}
+
+	plan.DestinationUpdates = append(plan.DestinationUpdates, destinationChanges(name, key, sortedByKey(updates))...)
+	plan.DestinationAdds = append(plan.DestinationAdds, destinationChanges(name, key, sortedForCreate(creates))...)
+	plan.DestinationDeletes = append(plan.DestinationDeletes, destinationChanges(name, key, sortedByKey(deletes))...)
+	return nil
+}
+
+// destinationChanges returns a change of the given service for every
+// destination.
+func destinationChanges(name string, service ServiceKey, dests []*Destination) []DestinationChange {
+	changes := make([]DestinationChange, 0, len(dests))
+	for _, dst := range dests {
+		changes = append(changes, DestinationChange{Destination: dst, Name: name, Service: service, Key: DestinationKeyFromIPVS(dst)})
+	}
+	return changes
+}
+
+// sortedForCreate sorts destinations in the order applyDestinations adds
+// them, from the lowest to the highest weight.
+func sortedForCreate(dests []*Destination) []*Destination {
+	sort.Slice(dests, func(i, j int) bool {
+		if dests[i].Weight != dests[j].Weight {
+			return dests[i].Weight < dests[j].Weight
+		}
+		return DestinationKeyFromIPVS(dests[i]).String() < DestinationKeyFromIPVS(dests[j]).String()
+	})
+	return dests
+}
+
+// sortedByKey sorts destinations by their key.
+func sortedByKey(dests []*Destination) []*Destination {
+	sort.Slice(dests, func(i, j int) bool {
+		return DestinationKeyFromIPVS(dests[i]).String() < DestinationKeyFromIPVS(dests[j]).String()
+	})
+	return dests
+}
+
+// serviceChangesByKey indexes service changes by service key.
+func serviceChangesByKey(changes []ServiceChange) map[ServiceKey]ServiceChange {
+	byKey := make(map[ServiceKey]ServiceChange, len(changes))
+	for _, change := range changes {
+		byKey[change.Key] = change
+	}
+	return byKey
+}
+
+// destinationChangesByService groups destination changes by service key,
+// keeping their order.
+func destinationChangesByService(changes []DestinationChange) map[ServiceKey][]DestinationChange {
+	byService := make(map[ServiceKey][]DestinationChange)
+	for _, change := range changes {
+		byService[change.Service] = append(byService[change.Service], change)
+	}
+	return byService
+}
+
+// sortedServiceKeys returns the keys of a desired state sorted by their
+// string form.
+func sortedServiceKeys(desiredMap map[ServiceKey]*DesiredService) []ServiceKey {
+	keys := make([]ServiceKey, 0, len(desiredMap))
+	for key := range desiredMap {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
+
+// sortedKeys returns the keys of a service key set sorted by their string
+// form.
+func sortedKeys(set map[ServiceKey]bool) []ServiceKey {
+	keys := make([]ServiceKey, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
@@ -0,0 +1,137 @@
+package lvs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func operationStrings(ops []Operation) []string {
+	lines := make([]string, 0, len(ops))
+	for _, op := range ops {
+		lines = append(lines, op.String())
+	}
+	return lines
+}
+
+func TestReconciler_Plan(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", false,
+		makeBackend("192.168.1.10:8080", 1), makeBackend("192.168.1.11:8080", 1))
+	api := makeServiceConfig("api", "10.0.0.2:443", "rr", false, makeBackend("192.168.2.10:9090", 1))
+
+	plan, err := reconciler.Plan([]config.ServiceConfig{web})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	want := []string{
+		`service "web": create service 10.0.0.1:80/tcp`,
+		`service "web": create destination 192.168.1.10:8080`,
+		`service "web": create destination 192.168.1.11:8080`,
+	}
+	if got := operationStrings(plan.Operations()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected plan for an empty kernel:\n got %q\nwant %q", got, want)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 0 {
+		t.Fatalf("expected Plan not to create services, got %d", len(services))
+	}
+
+	if err := reconciler.Reconcile([]config.ServiceConfig{web, api}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// Change a weight, replace a backend and the scheduler, and drop api
+	web.Scheduler = "wrr"
+	web.Backends = []config.BackendConfig{makeBackend("192.168.1.10:8080", 5), makeBackend("192.168.1.12:8080", 1)}
+	plan, err = reconciler.Plan([]config.ServiceConfig{web})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	want = []string{
+		`service "web": update service 10.0.0.1:80/tcp`,
+		`service "web": update destination 192.168.1.10:8080`,
+		`service "web": create destination 192.168.1.12:8080`,
+		`service "web": delete destination 192.168.1.11:8080`,
+		"delete service 10.0.0.2:443/tcp",
+	}
+	if got := operationStrings(plan.Operations()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected plan after config changes:\n got %q\nwant %q", got, want)
+	}
+	if len(plan.ServiceUpdates) != 1 || plan.ServiceUpdates[0].Service.SchedName != "wrr" {
+		t.Errorf("expected the desired scheduler in the service update, got %+v", plan.ServiceUpdates)
+	}
+	if len(plan.DestinationUpdates) != 1 || plan.DestinationUpdates[0].Destination.Weight != 5 {
+		t.Errorf("expected the desired weight in the destination update, got %+v", plan.DestinationUpdates)
+	}
+	if len(plan.DestinationDeletes) != 1 || plan.DestinationDeletes[0].Key.Address != "192.168.1.11" {
+		t.Errorf("expected the removed backend in the destination deletes, got %+v", plan.DestinationDeletes)
+	}
+	if len(plan.ServiceDeletes) != 1 || plan.ServiceDeletes[0].Name != "" {
+		t.Errorf("expected api in the service deletes, got %+v", plan.ServiceDeletes)
+	}
+
+	// Applying the same configs leaves nothing to plan
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !reflect.DeepEqual(operationStrings(reconciler.LastOperations()), want) {
+		t.Errorf("expected Reconcile to apply the planned operations, got %q", operationStrings(reconciler.LastOperations()))
+	}
+	plan, err = reconciler.Plan([]config.ServiceConfig{web})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected an empty plan after reconcile, got %q", operationStrings(plan.Operations()))
+	}
+}
+
+func TestReconciler_PlanLeavesReconcileStateAlone(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	// Drain an idle destination, so the next Reconcile deletes it
+	reconciler.SetWeightOverride(WeightOverride{Service: "svc1", Backend: "192.168.1.1:8080", Weight: 0})
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if _, err := reconciler.CheckDrains(configs, 0, true, time.Now()); err != nil {
+		t.Fatalf("CheckDrains failed: %v", err)
+	}
+	exclusions := reconciler.Exclusions()
+
+	// A plan in which the drained destination has a weight again, and the
+	// other backend is down, must change neither the drain nor the exclusions
+	renamed := []config.ServiceConfig{
+		makeServiceConfig("svc2", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1)),
+	}
+	healthMgr.status["192.168.1.2:8080"] = false
+	if _, err := reconciler.Plan(renamed); err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := reconciler.Exclusions(); !reflect.DeepEqual(got, exclusions) {
+		t.Errorf("expected Plan to keep the exclusions %+v, got %+v", exclusions, got)
+	}
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weights := destinationWeights(t, mgr); len(weights) != 1 || weights["192.168.1.2:8080"] != 1 {
+		t.Fatalf("expected the drained destination to stay deleted, got %v", weights)
+	}
+}
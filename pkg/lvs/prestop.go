@@ -0,0 +1,121 @@
+package lvs
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// PreStopHook is called before a destination whose backend was removed from
+// a service's config is deleted, and returns once the hook acknowledged. ctx
+// carries the service's pre_stop.timeout.
+type PreStopHook func(ctx context.Context, svcCfg config.ServiceConfig, backend string) error
+
+// preStopState tracks a destination held in IPVS while its pre-stop hook runs.
+type preStopState struct {
+	done atomic.Bool
+}
+
+// SetPreStopHook registers the hook called for services with pre_stop set.
+// done is called after each hook returns, so the caller can reconcile again
+// and delete the destination.
+func (r *Reconciler) SetPreStopHook(hook PreStopHook, done func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.preStopHook = hook
+	r.preStopDone = done
+}
+
+// holdForPreStop reports whether a destination that is no longer desired must
+// be kept because its backend was removed from the service's config and the
+// pre-stop hook has not finished yet. The first call quiesces the destination
+// to weight 0 and starts the hook; once it finished the destination is let go.
+// Destinations left out for health, priority or drain reasons are not held.
+func (r *Reconciler) holdForPreStop(desired *DesiredService, key DestinationKey, dst *Destination) bool {
+	svcCfg := desired.Config
+	if r.preStopHook == nil || !svcCfg.PreStop.IsEnabled() || inConfig(svcCfg, key) {
+		return false
+	}
+
+	stateKey := drainKey{service: ServiceKeyFromIPVS(desired.Service), dest: key}
+	if state, ok := r.preStop[stateKey]; ok {
+		if !state.done.Load() {
+			return true
+		}
+		delete(r.preStop, stateKey)
+		return false
+	}
+
+	if dst.Weight != 0 {
+		quiesced := *dst
+		quiesced.Weight = 0
+		err := r.manager.UpdateDestination(desired.Service, &quiesced)
+		r.record(svcCfg.Name, ResourceDestination, ActionUpdate, key.String(), err)
+		if err != nil {
+			r.logger.Warn("failed to quiesce destination before pre-stop hook",
+				zap.String("service", svcCfg.Name),
+				zap.String("backend", key.String()),
+				zap.Error(err),
+			)
+		}
+	}
+
+	state := &preStopState{}
+	r.preStop[stateKey] = state
+	backend := net.JoinHostPort(key.Address, strconv.Itoa(int(key.Port)))
+	hook, done := r.preStopHook, r.preStopDone
+	r.logger.Info("calling pre-stop hook before removing backend",
+		zap.String("service", svcCfg.Name),
+		zap.String("backend", backend),
+	)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), svcCfg.PreStop.GetTimeout())
+		defer cancel()
+		if err := hook(ctx, svcCfg, backend); err != nil {
+			r.logger.Warn("pre-stop hook failed, removing backend anyway",
+				zap.String("service", svcCfg.Name),
+				zap.String("backend", backend),
+				zap.Error(err),
+			)
+		} else {
+			r.logger.Info("pre-stop hook acknowledged",
+				zap.String("service", svcCfg.Name),
+				zap.String("backend", backend),
+			)
+		}
+		state.done.Store(true)
+		if done != nil {
+			done()
+		}
+	}()
+	return true
+}
+
+// forgetPreStop drops the pre-stop state of a destination that is desired
+// again, e.g. because its backend was added back to the config.
+func (r *Reconciler) forgetPreStop(svc *Service, key DestinationKey) {
+	delete(r.preStop, drainKey{service: ServiceKeyFromIPVS(svc), dest: key})
+}
+
+// inConfig reports whether the destination belongs to a backend still listed
+// in the service's config.
+func inConfig(svcCfg config.ServiceConfig, key DestinationKey) bool {
+	for _, backendCfg := range svcCfg.Backends {
+		host, portStr, err := net.SplitHostPort(backendCfg.Address)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port != int(key.Port) {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.Equal(net.ParseIP(key.Address)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package lvs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestReconcile_PreStopHookHoldsRemovedBackend(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	called := make(chan string, 1)
+	release := make(chan struct{})
+	finished := make(chan struct{}, 1)
+	reconciler.SetPreStopHook(func(ctx context.Context, svcCfg config.ServiceConfig, backend string) error {
+		called <- backend
+		<-release
+		return nil
+	}, func() { finished <- struct{}{} })
+
+	svcCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.1:8080", 5), makeBackend("192.168.1.2:8080", 5))
+	svcCfg.PreStop = config.PreStopConfig{URL: "http://orchestrator.local/pre-stop"}
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	// Removing a backend from the config quiesces it and calls the hook
+	svcCfg.Backends = svcCfg.Backends[:1]
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	select {
+	case backend := <-called:
+		if backend != "192.168.1.2:8080" {
+			t.Errorf("expected hook for 192.168.1.2:8080, got %s", backend)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the pre-stop hook to be called")
+	}
+	if weight, ok := destinationWeights(t, mgr)["192.168.1.2:8080"]; !ok || weight != 0 {
+		t.Fatalf("expected removed backend to be held with weight 0, got %d (present: %t)", weight, ok)
+	}
+
+	// A reconcile while the hook runs keeps holding it without calling it again
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("third Reconcile failed: %v", err)
+	}
+	if _, ok := destinationWeights(t, mgr)["192.168.1.2:8080"]; !ok {
+		t.Fatal("expected removed backend to be held while the hook runs")
+	}
+
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the done callback after the hook returned")
+	}
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("fourth Reconcile failed: %v", err)
+	}
+	weights := destinationWeights(t, mgr)
+	if _, ok := weights["192.168.1.2:8080"]; ok || len(weights) != 1 {
+		t.Errorf("expected removed backend to be deleted after the hook, got %v", weights)
+	}
+	if len(called) != 0 {
+		t.Error("expected the hook to be called only once")
+	}
+}
+
+func TestReconcile_PreStopHookSkipsUnhealthyBackend(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	reconciler.SetPreStopHook(func(ctx context.Context, svcCfg config.ServiceConfig, backend string) error {
+		t.Errorf("unexpected pre-stop hook for %s", backend)
+		return nil
+	}, nil)
+
+	svcCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.1:8080", 5), makeBackend("192.168.1.2:8080", 5))
+	svcCfg.PreStop = config.PreStopConfig{Command: []string{"/usr/local/bin/migrate"}}
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	// A backend failing its health check is removed right away
+	healthMgr.status["192.168.1.2:8080"] = false
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if _, ok := destinationWeights(t, mgr)["192.168.1.2:8080"]; ok {
+		t.Error("expected unhealthy backend to be removed without the pre-stop hook")
+	}
+}
@@ -0,0 +1,94 @@
+package lvs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrServiceNotFound is returned by GetService for a service not in the kernel.
+var ErrServiceNotFound = errors.New("IPVS service not found")
+
+// ServiceWithDestinations is an IPVS service with its destinations.
+type ServiceWithDestinations struct {
+	Service      *Service
+	Destinations []*Destination
+}
+
+// serviceQueryHandle is implemented by an IPVSHandle that looks up a single
+// service without listing all of them. GetService returns nil and no error
+// if the service does not exist.
+type serviceQueryHandle interface {
+	GetService(svc *Service) (*Service, error)
+}
+
+// GetService returns the IPVS service with the given key, or an error
+// wrapping ErrServiceNotFound if there is none. The kernel is asked for that
+// service alone, so the cost does not grow with the number of services.
+func (m *Manager) GetService(key ServiceKey) (*Service, error) {
+	query, err := serviceFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var svc *Service
+	if handle, ok := m.handle.(serviceQueryHandle); ok {
+		if svc, err = handle.GetService(query); err != nil {
+			return nil, fmt.Errorf("failed to get ipvs service %s: %w", key, err)
+		}
+	} else {
+		services, err := m.GetServices()
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range services {
+			if ServiceKeyFromIPVS(candidate) == key {
+				svc = candidate
+				break
+			}
+		}
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, key)
+	}
+	return svc, nil
+}
+
+// GetServicesWithDestinations returns every IPVS service with its
+// destinations, in the order of GetServices.
+func (m *Manager) GetServicesWithDestinations() ([]ServiceWithDestinations, error) {
+	services, err := m.GetServices()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ServiceWithDestinations, 0, len(services))
+	for _, svc := range services {
+		dests, err := m.GetDestinations(svc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ServiceWithDestinations{Service: svc, Destinations: dests})
+	}
+	return result, nil
+}
+
+// serviceFromKey returns a Service carrying only the identity of a key, as
+// needed to look it up.
+func serviceFromKey(key ServiceKey) (*Service, error) {
+	ip := net.ParseIP(key.Address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid service %s: invalid IP address %q", key, key.Address)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	family := addressFamilyFromIP(ip)
+	return &Service{
+		Address:       ip,
+		FWMark:        key.FWMark,
+		Protocol:      key.Protocol,
+		Port:          key.Port,
+		AddressFamily: family,
+		Netmask:       netmaskFromFamily(family),
+	}, nil
+}
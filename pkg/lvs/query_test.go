@@ -0,0 +1,63 @@
+package lvs
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestManager_GetService(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+
+	if err := mgr.CreateService(newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr")); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	if err := mgr.CreateService(newTestService("10.0.0.1", 53, syscall.IPPROTO_UDP, "wrr")); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+
+	key, _ := ParseServiceKey("10.0.0.1:53/udp")
+	svc, err := mgr.GetService(key)
+	if err != nil {
+		t.Fatalf("GetService failed: %v", err)
+	}
+	if ServiceKeyFromIPVS(svc) != key || svc.SchedName != "wrr" {
+		t.Errorf("expected service %s with scheduler wrr, got %s with %s", key, ServiceKeyFromIPVS(svc), svc.SchedName)
+	}
+
+	missing, _ := ParseServiceKey("10.0.0.1:53/tcp")
+	if _, err := mgr.GetService(missing); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("expected ErrServiceNotFound for a service the kernel does not have, got %v", err)
+	}
+}
+
+func TestManager_GetServicesWithDestinations(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+
+	web := newTestService("10.0.0.1", 80, syscall.IPPROTO_TCP, "rr")
+	empty := newTestService("10.0.0.2", 80, syscall.IPPROTO_TCP, "rr")
+	for _, svc := range []*Service{web, empty} {
+		if err := mgr.CreateService(svc); err != nil {
+			t.Fatalf("CreateService failed: %v", err)
+		}
+	}
+	for _, dst := range []*Destination{newTestDestination("192.168.1.1", 8080, 1), newTestDestination("192.168.1.2", 8080, 2)} {
+		if err := mgr.CreateDestination(web, dst); err != nil {
+			t.Fatalf("CreateDestination failed: %v", err)
+		}
+	}
+
+	services, err := mgr.GetServicesWithDestinations()
+	if err != nil {
+		t.Fatalf("GetServicesWithDestinations failed: %v", err)
+	}
+	counts := make(map[string]int, len(services))
+	for _, entry := range services {
+		counts[entry.Service.Address.String()] = len(entry.Destinations)
+	}
+	if len(counts) != 2 || counts["10.0.0.1"] != 2 || counts["10.0.0.2"] != 0 {
+		t.Errorf("expected 2 and 0 destinations, got %v", counts)
+	}
+}
@@ -3,9 +3,13 @@ package lvs
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net"
+	"slices"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/easzlab/ezlb/pkg/config"
 	"github.com/easzlab/ezlb/pkg/metrics"
@@ -19,6 +23,22 @@ type HealthChecker interface {
 	IsHealthy(address string) bool
 }
 
+// DegradedChecker is optionally implemented by a HealthChecker that also
+// reports backends which are up but impaired. Degraded backends stay in
+// rotation with their weight scaled by health_check.degraded_weight_factor.
+type DegradedChecker interface {
+	IsDegraded(address string) bool
+}
+
+// ServiceHealthChecker is optionally implemented by a HealthChecker whose
+// verdict depends on the service asking, e.g. because a backend shared by
+// several services is probed once per distinct health check profile. When
+// available it is preferred over the address-only methods.
+type ServiceHealthChecker interface {
+	IsServiceHealthy(service, address string) bool
+	IsServiceDegraded(service, address string) bool
+}
+
 // Reconciler implements declarative reconciliation between desired state (config + health)
 // and actual state (IPVS kernel rules + iptables SNAT rules).
 type Reconciler struct {
@@ -27,116 +47,346 @@ type Reconciler struct {
 	snatMgr   snat.Manager
 	logger    *zap.Logger
 	managed   map[ServiceKey]bool // tracks services managed by ezlb
-	mu        sync.Mutex
+	// merged marks the managed services in merge mode; owned tracks the
+	// destinations created in them, the only ones that are deleted.
+	merged map[ServiceKey]bool
+	owned  map[drainKey]bool
+	// savedState identifies the managed set last loaded or saved (see SaveState).
+	savedState string
+	overrides  map[overrideKey]WeightOverride
+	// operations records the changes attempted by the last Reconcile.
+	operations []Operation
+	// draining tracks weight-0 destinations; drained marks those whose drain
+	// completed and that are left out of the desired state.
+	draining map[drainKey]*drainState
+	drained  map[drainKey]bool
+	// backupStandby marks backup destinations held at weight 0 while a
+	// primary backend is active; they are not draining.
+	backupStandby map[drainKey]bool
+	// exclusions lists the unhealthy backends left out of the last desired state.
+	exclusions []Exclusion
+	// empty lists the services left without destinations by the last Reconcile.
+	empty []string
+	// lastError is the error of the last Reconcile, empty if it succeeded.
+	lastError string
+	// preStop tracks destinations held while their pre-stop hook runs.
+	preStop     map[drainKey]*preStopState
+	preStopHook PreStopHook
+	preStopDone func()
+	// conntrackFlusher deletes the conntrack entries of deleted destinations.
+	conntrackFlusher ConntrackFlusher
+	mu               sync.Mutex
+	// overrideMu guards overrides separately so the admin API is not blocked
+	// while a reconcile is in progress.
+	overrideMu sync.Mutex
+	// maintenance drains every managed service by forcing all destination weights to 0.
+	maintenance atomic.Bool
+	// prune deletes every IPVS service that is not desired, managed or not.
+	prune atomic.Bool
+	// ignored holds the IPVS services of other systems that are never touched.
+	ignored map[ServiceKey]bool
+	// detectLocal programs backends on this host with local-node forwarding;
+	// localBackends records, by service and backend, those logged as local.
+	detectLocal   atomic.Bool
+	localBackends map[string]bool
+	// mutators adjust the desired state before it is applied.
+	mutators []DesiredStateMutator
+	// weightScaling records, by service, the weight scaling last logged.
+	weightScaling map[string]string
 }
 
 // NewReconciler creates a new Reconciler.
 func NewReconciler(manager *Manager, healthMgr HealthChecker, snatMgr snat.Manager, logger *zap.Logger) *Reconciler {
 	return &Reconciler{
-		manager:   manager,
-		healthMgr: healthMgr,
-		snatMgr:   snatMgr,
-		logger:    logger,
-		managed:   make(map[ServiceKey]bool),
+		manager:       manager,
+		healthMgr:     healthMgr,
+		snatMgr:       snatMgr,
+		logger:        logger,
+		managed:       make(map[ServiceKey]bool),
+		merged:        make(map[ServiceKey]bool),
+		owned:         make(map[drainKey]bool),
+		overrides:     make(map[overrideKey]WeightOverride),
+		draining:      make(map[drainKey]*drainState),
+		drained:       make(map[drainKey]bool),
+		backupStandby: make(map[drainKey]bool),
+		preStop:       make(map[drainKey]*preStopState),
+		mutators:      defaultMutators(),
+		weightScaling: make(map[string]string),
+		localBackends: make(map[string]bool),
 	}
 }
 
-// desiredService holds the desired IPVS service and its destinations after health filtering.
-type desiredService struct {
-	service      *Service
-	destinations []*Destination
-	config       config.ServiceConfig
+// SetMaintenance enables or disables director-wide maintenance mode.
+// While enabled, every desired destination is programmed with weight 0 so that
+// existing connections drain and no new connections are scheduled. The change
+// takes effect on the next Reconcile.
+func (r *Reconciler) SetMaintenance(enabled bool) {
+	r.maintenance.Store(enabled)
+}
+
+// SetPrune enables or disables prune mode, in which Reconcile deletes every
+// IPVS service that is not in the desired state, including services created
+// by other tools or by ezlb processes whose state was lost.
+func (r *Reconciler) SetPrune(enabled bool) {
+	r.prune.Store(enabled)
+}
+
+// InMaintenance reports whether maintenance mode is enabled.
+func (r *Reconciler) InMaintenance() bool {
+	return r.maintenance.Load()
+}
+
+// DesiredService holds the desired IPVS service and its destinations after
+// health filtering. Config is the service's configuration and must not be
+// modified by mutators. Excluded lists the unhealthy backends left out.
+type DesiredService struct {
+	Service      *Service
+	Destinations []*Destination
+	Excluded     []Exclusion
+	Config       config.ServiceConfig
 }
 
 // Reconcile compares the desired state (from config + health check) with the actual IPVS state
-// and applies the necessary changes to bring the kernel in sync.
+// and applies the necessary changes to bring the kernel in sync. Disabled
+// services are treated as absent, and a service with several listen addresses
+// is diffed as one virtual service per address.
 func (r *Reconciler) Reconcile(desiredConfigs []config.ServiceConfig) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	desiredConfigs = config.ExpandListens(config.EnabledServices(desiredConfigs))
+
 	r.logger.Info("starting reconcile", zap.Int("desired_services", len(desiredConfigs)))
+	r.operations = nil
 
-	// Phase 1: Build desired state
-	desiredMap, err := r.buildDesiredState(desiredConfigs)
+	// Phase 1: Build the desired state and plan the IPVS changes against
+	// the actual state of the kernel
+	plan, err := r.buildPlan(desiredConfigs)
 	if err != nil {
-		return fmt.Errorf("failed to build desired state: %w", err)
+		return err
 	}
+	r.empty = emptyServiceNames(plan.desired)
+
+	// Phase 2: Apply the planned service and destination changes
+	reconcileErrors := append(plan.errs, r.applyPlan(plan)...)
+
+	// iptables rules are diffed by applying them, so read-only mode leaves
+	// them untouched instead of refusing every reconcile
+	if r.manager.IsReadOnly() {
+		r.logger.Debug("read-only mode, skipping iptables reconcile")
+	} else {
+		// Phase 3: Reconcile SNAT rules for services with full_nat enabled
+		snatErr := r.reconcileSNAT(desiredConfigs)
+		if snatErr != nil || hasFullNAT(desiredConfigs) {
+			r.record("", ResourceSNAT, ActionSync, "iptables", snatErr)
+		}
+		if snatErr != nil {
+			reconcileErrors = append(reconcileErrors, fmt.Errorf("snat reconcile: %w", snatErr))
+		}
 
-	// Phase 2: Get actual state from IPVS kernel
-	actualServices, err := r.manager.GetServices()
-	if err != nil {
-		return fmt.Errorf("failed to get current IPVS services: %w", err)
+		// Phase 4: Reconcile MARK rules for fwmark services with a mark group
+		markErr := r.reconcileMarks(desiredConfigs)
+		if markErr != nil || hasMarkGroups(desiredConfigs) {
+			r.record("", ResourceMark, ActionSync, "iptables", markErr)
+		}
+		if markErr != nil {
+			reconcileErrors = append(reconcileErrors, fmt.Errorf("mark reconcile: %w", markErr))
+		}
+
+		// Phase 5: Reconcile DSCP rules for services with dscp set
+		dscpErr := r.reconcileDSCP(desiredConfigs)
+		if dscpErr != nil || hasDSCP(desiredConfigs) {
+			r.record("", ResourceDSCP, ActionSync, "iptables", dscpErr)
+		}
+		if dscpErr != nil {
+			reconcileErrors = append(reconcileErrors, fmt.Errorf("dscp reconcile: %w", dscpErr))
+		}
 	}
 
-	actualMap := make(map[ServiceKey]*Service)
-	for _, svc := range actualServices {
-		key := ServiceKeyFromIPVS(svc)
-		// Include services that are either managed by ezlb or present in the
-		// desired state. This ensures that `once` mode (fresh Reconciler with
-		// empty managed map) can still detect and update pre-existing IPVS
-		// services that match the current config, avoiding duplicate creation.
-		if r.managed[key] || desiredMap[key] != nil {
-			actualMap[key] = svc
+	return r.reconcileResult(reconcileErrors)
+}
+
+// reconcileResult logs and counts the errors of a reconcile and joins them.
+// The same errors as the last reconcile's are only logged at debug level, as
+// the caller reports repeated failures.
+func (r *Reconciler) reconcileResult(reconcileErrors []error) error {
+	if len(reconcileErrors) > 0 {
+		err := errors.Join(reconcileErrors...)
+		if err.Error() != r.lastError {
+			r.logger.Error("reconcile completed with errors", zap.Int("error_count", len(reconcileErrors)))
+		} else {
+			r.logger.Debug("reconcile completed with the same errors", zap.Int("error_count", len(reconcileErrors)))
 		}
+		r.lastError = err.Error()
+		// Increment error counter for each error
+		for range reconcileErrors {
+			metrics.IncReconcileErrors()
+		}
+		return err
 	}
 
-	var reconcileErrors []error
+	r.lastError = ""
+	r.logger.Info("reconcile completed successfully")
+	return nil
+}
 
-	// Phase 3: Service-level diff
-	// Create or update services that are in desired but missing or different in actual
-	for key, desired := range desiredMap {
-		actual, exists := actualMap[key]
-		if !exists {
-			// Service does not exist in IPVS -> create it
-			if err := r.manager.CreateService(desired.service); err != nil {
-				reconcileErrors = append(reconcileErrors, fmt.Errorf("create service %s: %w", key, err))
+// applyPlan applies the service and destination changes of a plan and
+// updates the managed set. The destinations of a service whose create or
+// update failed are left alone. Must be called with r.mu held.
+func (r *Reconciler) applyPlan(plan *Plan) []error {
+	adds := serviceChangesByKey(plan.ServiceAdds)
+	updates := serviceChangesByKey(plan.ServiceUpdates)
+	destUpdates := destinationChangesByService(plan.DestinationUpdates)
+	destAdds := destinationChangesByService(plan.DestinationAdds)
+	destDeletes := destinationChangesByService(plan.DestinationDeletes)
+
+	var errs []error
+	for _, key := range sortedServiceKeys(plan.desired) {
+		desired := plan.desired[key]
+		_, created := adds[key]
+		if created {
+			err := r.manager.CreateService(desired.Service)
+			r.record(desired.Config.Name, ResourceService, ActionCreate, key.String(), err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("create service %s: %w", key, withSchedulerHint(err, desired.Service.SchedName)))
 				continue
 			}
-			r.managed[key] = true
-		} else {
-			// Service exists -> mark as managed and check if scheduler needs update
-			r.managed[key] = true
-			if actual.SchedName != desired.service.SchedName {
-				if err := r.manager.UpdateService(desired.service); err != nil {
-					reconcileErrors = append(reconcileErrors, fmt.Errorf("update service %s: %w", key, err))
-					continue
-				}
-			}
+		} else if !r.managed[key] {
+			r.logger.Info("adopted existing IPVS service",
+				zap.String("service", desired.Config.Name),
+				zap.String("key", key.String()),
+			)
 		}
+		r.managed[key] = true
+		r.setMerge(key, desired.Config.Merge)
 
-		// Phase 4: Destination-level diff for this service
-		if err := r.reconcileDestinations(desired); err != nil {
-			reconcileErrors = append(reconcileErrors, err)
+		if _, update := updates[key]; update {
+			err := r.manager.UpdateService(desired.Service)
+			r.record(desired.Config.Name, ResourceService, ActionUpdate, key.String(), err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("update service %s: %w", key, withSchedulerHint(err, desired.Service.SchedName)))
+				continue
+			}
 		}
+
+		errs = append(errs, r.applyDestinations(desired, created, destUpdates[key], destAdds[key], destDeletes[key])...)
 	}
 
-	// Delete services that are in actual (and managed by ezlb) but not in desired
-	for key, actual := range actualMap {
-		if _, exists := desiredMap[key]; !exists {
-			if err := r.manager.DeleteService(actual); err != nil {
-				reconcileErrors = append(reconcileErrors, fmt.Errorf("delete service %s: %w", key, err))
+	deleted := make(map[ServiceKey]bool, len(plan.ServiceDeletes))
+	for _, change := range plan.ServiceDeletes {
+		deleted[change.Key] = true
+		if !r.managed[change.Key] {
+			r.logger.Info("pruning IPVS service not in the config", zap.String("service", change.Key.String()))
+		}
+		if r.merged[change.Key] {
+			if err := r.deleteMergedService(change.Key, change.Service); err != nil {
+				errs = append(errs, err)
 			} else {
-				delete(r.managed, key)
+				delete(r.managed, change.Key)
 			}
+			continue
+		}
+		err := r.manager.DeleteService(change.Service)
+		r.record("", ResourceService, ActionDelete, change.Key.String(), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delete service %s: %w", change.Key, err))
+		} else {
+			delete(r.managed, change.Key)
+		}
+	}
+	// Forget managed services deleted by someone else, e.g. an ipvsadm flush.
+	// A partial plan does not cover the other services, so they are kept.
+	for key := range r.managed {
+		if !plan.partial && plan.desired[key] == nil && !deleted[key] {
+			delete(r.managed, key)
+			r.forgetMerge(key)
 		}
 	}
+	return errs
+}
 
-	// Phase 5: Reconcile SNAT rules for services with full_nat enabled
-	if err := r.reconcileSNAT(desiredConfigs); err != nil {
-		reconcileErrors = append(reconcileErrors, fmt.Errorf("snat reconcile: %w", err))
+// applyDestinations applies the destination changes of a single service in
+// one batch: updates, then creates from the lowest to the highest weight,
+// then deletes. serviceCreated tells whether the service was created in this
+// pass; if so and several destinations are added, all are first added with
+// weight 0 and raised afterwards in a second batch, so the first backend added
+// does not take every new connection while the others are still pending.
+func (r *Reconciler) applyDestinations(desired *DesiredService, serviceCreated bool, updates, adds, deletes []DestinationChange) []error {
+	for _, dst := range desired.Destinations {
+		r.forgetPreStop(desired.Service, DestinationKeyFromIPVS(dst))
 	}
 
-	if len(reconcileErrors) > 0 {
-		r.logger.Error("reconcile completed with errors", zap.Int("error_count", len(reconcileErrors)))
-		// Increment error counter for each error
-		for range reconcileErrors {
-			metrics.IncReconcileErrors()
+	staged := serviceCreated && len(adds) > 1
+	adds = slices.Clone(adds)
+	sort.Slice(adds, func(i, j int) bool {
+		if adds[i].Destination.Weight != adds[j].Destination.Weight {
+			return adds[i].Destination.Weight < adds[j].Destination.Weight
+		}
+		return adds[i].Key.String() < adds[j].Key.String()
+	})
+
+	var changes []DestinationChange
+	var ops []DestinationOp
+	for _, change := range updates {
+		changes = append(changes, change)
+		ops = append(ops, DestinationOp{Action: ActionUpdate, Destination: change.Destination})
+	}
+	for _, change := range adds {
+		initial := change.Destination
+		if staged {
+			zero := *initial
+			zero.Weight = 0
+			initial = &zero
+		}
+		changes = append(changes, change)
+		ops = append(ops, DestinationOp{Action: ActionCreate, Destination: initial})
+	}
+	for _, change := range deletes {
+		if r.holdForPreStop(desired, change.Key, change.Destination) {
+			continue
 		}
-		return errors.Join(reconcileErrors...)
+		changes = append(changes, change)
+		ops = append(ops, DestinationOp{Action: ActionDelete, Destination: change.Destination})
 	}
 
-	r.logger.Info("reconcile completed successfully")
-	return nil
+	var errs []error
+	var raised []DestinationChange
+	var raises []DestinationOp
+	for i, err := range r.manager.ApplyDestinations(desired.Service, ops) {
+		change, action := changes[i], ops[i].Action
+		if err == nil {
+			r.trackOwnership(desired, action, change)
+		}
+		if action == ActionCreate && staged && err == nil {
+			raised = append(raised, change)
+			if change.Destination.Weight != 0 {
+				raises = append(raises, DestinationOp{Action: ActionUpdate, Destination: change.Destination})
+			}
+			continue
+		}
+		r.record(desired.Config.Name, ResourceDestination, action, change.Key.String(), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s destination %s: %w", action, change.Key, err))
+		} else if action == ActionDelete {
+			r.flushConntrack(desired, change.Key, change.Destination)
+		}
+	}
+	if len(raised) == 0 {
+		return errs
+	}
+
+	raiseErrs := r.manager.ApplyDestinations(desired.Service, raises)
+	for _, change := range raised {
+		var err error
+		if change.Destination.Weight != 0 {
+			err, raiseErrs = raiseErrs[0], raiseErrs[1:]
+		}
+		r.record(desired.Config.Name, ResourceDestination, ActionCreate, change.Key.String(), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("raise weight of destination %s: %w", change.Key, err))
+		}
+	}
+	return errs
 }
 
 // Cleanup removes all IPVS services currently managed by this Reconciler.
@@ -158,9 +408,21 @@ func (r *Reconciler) Cleanup() error {
 
 	var errs []error
 	for key := range r.managed {
+		if r.ignoredService(key) {
+			continue
+		}
 		svc, exists := actualMap[key]
 		if !exists {
 			delete(r.managed, key)
+			r.forgetMerge(key)
+			continue
+		}
+		if r.merged[key] {
+			if err := r.deleteMergedService(key, svc); err != nil {
+				errs = append(errs, err)
+			} else {
+				delete(r.managed, key)
+			}
 			continue
 		}
 		if err := r.manager.DeleteService(svc); err != nil {
@@ -177,32 +439,69 @@ func (r *Reconciler) Cleanup() error {
 	return nil
 }
 
+// hasFullNAT reports whether any service uses FullNAT and thus SNAT rules.
+func hasFullNAT(configs []config.ServiceConfig) bool {
+	for _, svcCfg := range configs {
+		if svcCfg.FullNAT {
+			return true
+		}
+	}
+	return false
+}
+
 // reconcileSNAT builds the desired SNAT and FORWARD rules from configs with
 // full_nat enabled and delegates to the SNAT manager for declarative reconciliation.
 // FORWARD rules are needed because IPVS NAT mode requires packets to traverse
 // the FORWARD chain, which may have a DROP policy (e.g. Docker environments).
 func (r *Reconciler) reconcileSNAT(configs []config.ServiceConfig) error {
-	var desiredSNATRules []snat.SNATRule
-	var desiredForwardRules []snat.ForwardRule
+	desired, err := r.buildSNATRules(configs)
+	if err != nil {
+		return err
+	}
+
+	desiredSNATRules := make([]snat.SNATRule, 0, len(desired))
+	desiredForwardRules := make([]snat.ForwardRule, 0, len(desired))
+	for _, rule := range desired {
+		desiredSNATRules = append(desiredSNATRules, rule.SNAT)
+		desiredForwardRules = append(desiredForwardRules, rule.Forward)
+	}
+
+	if err := r.snatMgr.Reconcile(desiredSNATRules); err != nil {
+		return fmt.Errorf("snat rules: %w", err)
+	}
+
+	if err := r.snatMgr.ReconcileForward(desiredForwardRules); err != nil {
+		return fmt.Errorf("forward rules: %w", err)
+	}
+
+	return nil
+}
+
+// desiredSNATRule is the SNAT and FORWARD rule pair of one FullNAT backend.
+type desiredSNATRule struct {
+	Service string
+	SNAT    snat.SNATRule
+	Forward snat.ForwardRule
+}
 
+// buildSNATRules returns the SNAT and FORWARD rules for the backends of the
+// configs with full_nat enabled that currently receive traffic.
+func (r *Reconciler) buildSNATRules(configs []config.ServiceConfig) ([]desiredSNATRule, error) {
+	var rules []desiredSNATRule
 	for _, svcCfg := range configs {
 		if !svcCfg.FullNAT {
 			continue
 		}
 
-		for _, backendCfg := range svcCfg.Backends {
-			// Only create rules for healthy backends
-			if svcCfg.HealthCheck.IsEnabled() && !r.healthMgr.IsHealthy(backendCfg.Address) {
-				continue
-			}
-
+		active, _, _ := r.activeBackends(svcCfg)
+		for _, backendCfg := range active {
 			backendHost, backendPortStr, err := net.SplitHostPort(backendCfg.Address)
 			if err != nil {
-				return fmt.Errorf("service %q, backend %q: invalid address: %w", svcCfg.Name, backendCfg.Address, err)
+				return nil, fmt.Errorf("service %q, backend %q: invalid address: %w", svcCfg.Name, backendCfg.Address, err)
 			}
 			backendPort, err := strconv.Atoi(backendPortStr)
 			if err != nil {
-				return fmt.Errorf("service %q, backend %q: invalid port: %w", svcCfg.Name, backendCfg.Address, err)
+				return nil, fmt.Errorf("service %q, backend %q: invalid port: %w", svcCfg.Name, backendCfg.Address, err)
 			}
 
 			protocol := svcCfg.Protocol
@@ -210,37 +509,40 @@ func (r *Reconciler) reconcileSNAT(configs []config.ServiceConfig) error {
 				protocol = "tcp"
 			}
 
-			desiredSNATRules = append(desiredSNATRules, snat.SNATRule{
-				BackendIP:   backendHost,
-				BackendPort: uint16(backendPort),
-				Protocol:    protocol,
-				SnatIP:      svcCfg.SnatIP,
-			})
-
-			desiredForwardRules = append(desiredForwardRules, snat.ForwardRule{
-				BackendIP:   backendHost,
-				BackendPort: uint16(backendPort),
-				Protocol:    protocol,
+			rules = append(rules, desiredSNATRule{
+				Service: svcCfg.Name,
+				SNAT: snat.SNATRule{
+					BackendIP:       backendHost,
+					BackendPort:     uint16(backendPort),
+					Protocol:        protocol,
+					SnatIP:          svcCfg.SnatIP,
+					OutputInterface: svcCfg.OutputInterface,
+				},
+				Forward: snat.ForwardRule{
+					BackendIP:       backendHost,
+					BackendPort:     uint16(backendPort),
+					Protocol:        protocol,
+					OutputInterface: svcCfg.OutputInterface,
+				},
 			})
 		}
 	}
-
-	if err := r.snatMgr.Reconcile(desiredSNATRules); err != nil {
-		return fmt.Errorf("snat rules: %w", err)
-	}
-
-	if err := r.snatMgr.ReconcileForward(desiredForwardRules); err != nil {
-		return fmt.Errorf("forward rules: %w", err)
-	}
-
-	return nil
+	return rules, nil
 }
 
 // buildDesiredState converts config services into the desired IPVS state,
-// filtering out unhealthy backends.
-func (r *Reconciler) buildDesiredState(configs []config.ServiceConfig) (map[ServiceKey]*desiredService, error) {
-	result := make(map[ServiceKey]*desiredService)
+// filtering out unhealthy backends, and applies the registered mutators.
+func (r *Reconciler) buildDesiredState(configs []config.ServiceConfig) (map[ServiceKey]*DesiredService, error) {
+	clear(r.backupStandby)
+	r.exclusions = nil
+	return r.buildServices(configs)
+}
 
+// buildServices adds the desired state of the given services to the
+// exclusions and backup standby state, and returns it.
+func (r *Reconciler) buildServices(configs []config.ServiceConfig) (map[ServiceKey]*DesiredService, error) {
+	result := make(map[ServiceKey]*DesiredService)
+	local := r.localAddresses()
 	for _, svcCfg := range configs {
 		ipvsSvc, err := ConfigToIPVSService(svcCfg)
 		if err != nil {
@@ -252,90 +554,167 @@ func (r *Reconciler) buildDesiredState(configs []config.ServiceConfig) (map[Serv
 			return nil, fmt.Errorf("service %q: %w", svcCfg.Name, err)
 		}
 
-		var destinations []*Destination
-		for _, backendCfg := range svcCfg.Backends {
-			// Filter out unhealthy backends (only when health check is enabled)
-			if svcCfg.HealthCheck.IsEnabled() && !r.healthMgr.IsHealthy(backendCfg.Address) {
-				r.logger.Info("skipping unhealthy backend",
-					zap.String("service", svcCfg.Name),
-					zap.String("backend", backendCfg.Address),
+		// Filter out unhealthy backends (only when health check is enabled)
+		// and standby backends whose priority tier is not needed
+		active, unhealthy, standby := r.activeBackends(svcCfg)
+		var excluded []Exclusion
+		for _, backendCfg := range unhealthy {
+			exclusion := r.exclusion(svcCfg, backendCfg.Address)
+			excluded = append(excluded, exclusion)
+			fields := []zap.Field{
+				zap.String("service", svcCfg.Name),
+				zap.String("backend", backendCfg.Address),
+			}
+			if exclusion.LastError != "" {
+				fields = append(fields,
+					zap.String("last_error", exclusion.LastError),
+					zap.Int("consecutive_fails", exclusion.ConsecutiveFails),
+					zap.Time("since", exclusion.Since),
 				)
-				continue
 			}
+			r.logger.Info("skipping unhealthy backend", fields...)
+		}
+		r.exclusions = append(r.exclusions, excluded...)
+		for _, backendCfg := range standby {
+			r.logger.Debug("holding standby backend in reserve",
+				zap.String("service", svcCfg.Name),
+				zap.String("backend", backendCfg.Address),
+				zap.Int("priority", backendCfg.Priority),
+			)
+		}
+
+		primaryActive := slices.ContainsFunc(active, func(backendCfg config.BackendConfig) bool {
+			return !backendCfg.IsBackup()
+		})
 
+		// Weights are scaled over all backends, so they do not shift as
+		// backends fail or recover
+		scaled := r.normalizedWeights(svcCfg)
+
+		var destinations []*Destination
+		for _, backendCfg := range active {
 			dst, err := ConfigToIPVSDestination(backendCfg)
 			if err != nil {
 				return nil, fmt.Errorf("service %q, backend %q: %w", svcCfg.Name, backendCfg.Address, err)
 			}
+			r.useLocalNode(local, svcCfg, backendCfg, dst)
+			if weight, ok := scaled[backendCfg.Address]; ok {
+				dst.Weight = weight
+			}
+			standby := backendCfg.IsBackup() && primaryActive
+			if standby {
+				dst.Weight = 0
+				r.backupStandby[drainKey{service: key, dest: DestinationKeyFromIPVS(dst)}] = true
+			}
+			if r.isDegraded(svcCfg, backendCfg.Address) {
+				dst.Weight = scaleWeight(dst.Weight, svcCfg.HealthCheck.GetDegradedWeightFactor())
+				r.logger.Debug("scaling weight of degraded backend",
+					zap.String("service", svcCfg.Name),
+					zap.String("backend", backendCfg.Address),
+					zap.Int("weight", dst.Weight),
+				)
+			}
+			// An override does not bring a backup in standby into rotation
+			if weight, overridden := r.weightOverride(svcCfg.Name, backendCfg.Address); overridden && !standby {
+				dst.Weight = weight
+			}
+			if r.InMaintenance() {
+				dst.Weight = 0
+			}
+			if r.removeDrained(key, dst) {
+				continue
+			}
 			destinations = append(destinations, dst)
 		}
 
-		result[key] = &desiredService{
-			service:      ipvsSvc,
-			destinations: destinations,
-			config:       svcCfg,
+		result[key] = &DesiredService{
+			Service:      ipvsSvc,
+			Destinations: destinations,
+			Excluded:     excluded,
+			Config:       svcCfg,
 		}
 	}
+	sort.Slice(r.exclusions, func(i, j int) bool {
+		if r.exclusions[i].Service != r.exclusions[j].Service {
+			return r.exclusions[i].Service < r.exclusions[j].Service
+		}
+		return r.exclusions[i].Address < r.exclusions[j].Address
+	})
+
+	if err := r.mutateDesired(result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
-// reconcileDestinations performs a diff on destinations for a single service.
-func (r *Reconciler) reconcileDestinations(desired *desiredService) error {
-	// Get actual destinations from IPVS
-	actualDests, err := r.manager.GetDestinations(desired.service)
-	if err != nil {
-		return fmt.Errorf("get destinations for %s:%d: %w",
-			desired.service.Address, desired.service.Port, err)
+// activeBackends splits the backends of a service into those that should
+// be in IPVS, those excluded as unhealthy, and healthy standby backends.
+// Only the most preferred priority tier (lowest priority value) that still has
+// a healthy primary backend is active, so lower-priority pools take over only
+// when every backend of all preferred tiers is down. Healthy backup backends
+// are always active, regardless of priority; buildDesiredState zeroes their
+// weight while a primary backend is active.
+func (r *Reconciler) activeBackends(svcCfg config.ServiceConfig) (active, unhealthy, standby []config.BackendConfig) {
+	var healthy, backups []config.BackendConfig
+	for _, backendCfg := range svcCfg.Backends {
+		if svcCfg.HealthCheck.IsEnabled() && !r.isHealthy(svcCfg, backendCfg.Address) {
+			unhealthy = append(unhealthy, backendCfg)
+			continue
+		}
+		if backendCfg.IsBackup() {
+			backups = append(backups, backendCfg)
+			continue
+		}
+		healthy = append(healthy, backendCfg)
 	}
-
-	// Build maps for comparison
-	actualDestMap := make(map[DestinationKey]*Destination)
-	for _, dst := range actualDests {
-		key := DestinationKeyFromIPVS(dst)
-		actualDestMap[key] = dst
+	if len(healthy) == 0 {
+		return backups, unhealthy, nil
 	}
 
-	desiredDestMap := make(map[DestinationKey]*Destination)
-	for _, dst := range desired.destinations {
-		key := DestinationKey{
-			Address: dst.Address.String(),
-			Port:    dst.Port,
+	activePriority := healthy[0].Priority
+	for _, backendCfg := range healthy[1:] {
+		if backendCfg.Priority < activePriority {
+			activePriority = backendCfg.Priority
 		}
-		desiredDestMap[key] = dst
 	}
 
-	var reconcileErrors []error
-
-	// Create or update destinations
-	for key, desiredDst := range desiredDestMap {
-		actualDst, exists := actualDestMap[key]
-		if !exists {
-			// Destination does not exist -> create
-			if err := r.manager.CreateDestination(desired.service, desiredDst); err != nil {
-				reconcileErrors = append(reconcileErrors, fmt.Errorf("create destination %s: %w", key, err))
-			}
+	for _, backendCfg := range healthy {
+		if backendCfg.Priority == activePriority {
+			active = append(active, backendCfg)
 		} else {
-			// Destination exists -> check if weight needs update
-			if actualDst.Weight != desiredDst.Weight {
-				if err := r.manager.UpdateDestination(desired.service, desiredDst); err != nil {
-					reconcileErrors = append(reconcileErrors, fmt.Errorf("update destination %s: %w", key, err))
-				}
-			}
+			standby = append(standby, backendCfg)
 		}
 	}
+	active = append(active, backups...)
+	return active, unhealthy, standby
+}
 
-	// Delete destinations that are in actual but not in desired
-	for key, actualDst := range actualDestMap {
-		if _, exists := desiredDestMap[key]; !exists {
-			if err := r.manager.DeleteDestination(desired.service, actualDst); err != nil {
-				reconcileErrors = append(reconcileErrors, fmt.Errorf("delete destination %s: %w", key, err))
-			}
-		}
+// isHealthy reports whether a backend is healthy for the given service.
+func (r *Reconciler) isHealthy(svcCfg config.ServiceConfig, address string) bool {
+	if checker, ok := r.healthMgr.(ServiceHealthChecker); ok {
+		return checker.IsServiceHealthy(svcCfg.Name, address)
 	}
+	return r.healthMgr.IsHealthy(address)
+}
 
-	if len(reconcileErrors) > 0 {
-		return errors.Join(reconcileErrors...)
+// isDegraded reports whether a backend of a health-checked service is degraded.
+func (r *Reconciler) isDegraded(svcCfg config.ServiceConfig, address string) bool {
+	if !svcCfg.HealthCheck.IsEnabled() {
+		return false
 	}
-	return nil
+	if checker, ok := r.healthMgr.(ServiceHealthChecker); ok {
+		return checker.IsServiceDegraded(svcCfg.Name, address)
+	}
+	checker, ok := r.healthMgr.(DegradedChecker)
+	return ok && checker.IsDegraded(address)
+}
+
+// scaleWeight applies the degraded weight factor. A positive weight never
+// drops below 1, so a degraded backend keeps receiving some traffic.
+func scaleWeight(weight int, factor float64) int {
+	if weight <= 0 {
+		return weight
+	}
+	return max(1, int(math.Round(float64(weight)*factor)))
 }
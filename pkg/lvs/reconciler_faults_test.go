@@ -0,0 +1,244 @@
+//go:build !integration
+
+package lvs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
+)
+
+var errInjected = errors.New("injected fault")
+
+// newFaultyReconcilerTestEnv is newReconcilerTestEnv with fault injection in
+// the fake IPVS handle.
+func newFaultyReconcilerTestEnv(t *testing.T) (*Manager, *fakeFaults, *Reconciler) {
+	t.Helper()
+	mgr, faults := newFaultyTestManager(t)
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	return mgr, faults, NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.NewNop())
+}
+
+// failedOperations returns the targets of the failed operations of the last
+// Reconcile.
+func failedOperations(reconciler *Reconciler) []string {
+	var targets []string
+	for _, op := range reconciler.LastOperations() {
+		if op.Err != nil {
+			targets = append(targets, op.Target)
+		}
+	}
+	return targets
+}
+
+func TestReconcile_Faults_DestinationCreateRetried(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false,
+			makeBackend("192.168.1.1:8080", 1), makeBackend("192.168.1.2:8080", 1)),
+	}
+	faults.failOn(opNewDestination, 1, errInjected)
+
+	err := reconciler.Reconcile(configs)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("expected the injected error to be returned, got: %v", err)
+	}
+	if failed := failedOperations(reconciler); len(failed) != 1 {
+		t.Fatalf("expected one failed operation, got %v", failed)
+	}
+	// The other destination is still applied
+	if weights := destinationWeights(t, mgr); len(weights) != 1 {
+		t.Fatalf("expected one destination after the failure, got %v", weights)
+	}
+
+	// The next reconcile only creates the missing destination
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("retry Reconcile failed: %v", err)
+	}
+	ops := reconciler.LastOperations()
+	if len(ops) != 1 || ops[0].Resource != ResourceDestination || ops[0].Action != ActionCreate {
+		t.Errorf("expected only the failed destination to be created on retry, got %+v", ops)
+	}
+	if weights := destinationWeights(t, mgr); len(weights) != 2 {
+		t.Errorf("expected both destinations after the retry, got %v", weights)
+	}
+}
+
+func TestReconcile_Faults_ServiceCreateSkipsDestinations(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+	}
+	faults.failOn(opNewService, 1, errInjected)
+
+	if err := reconciler.Reconcile(configs); !errors.Is(err, errInjected) {
+		t.Fatalf("expected the injected error to be returned, got: %v", err)
+	}
+	if got := faults.callCount(opNewDestination); got != 0 {
+		t.Errorf("expected no destination to be created without its service, got %d calls", got)
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("retry Reconcile failed: %v", err)
+	}
+	if weights := destinationWeights(t, mgr); weights["192.168.1.1:8080"] != 1 {
+		t.Errorf("expected the service and destination after the retry, got %v", weights)
+	}
+}
+
+func TestReconcile_Faults_GetServicesFailureChangesNothing(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	faults.failOn(opGetServices, 1, errInjected)
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); !errors.Is(err, errInjected) {
+		t.Fatalf("expected the injected error to be returned, got: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected no changes without the actual state, got %+v", ops)
+	}
+	if got := faults.callCount(opNewService); got != 0 {
+		t.Errorf("expected no service to be created, got %d calls", got)
+	}
+}
+
+func TestReconcile_Faults_DeleteFailureRetried(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	faults.failOn(opDelService, 1, errInjected)
+	if err := reconciler.Reconcile(nil); !errors.Is(err, errInjected) {
+		t.Fatalf("expected the injected error to be returned, got: %v", err)
+	}
+	// The service stays managed, so the next reconcile deletes it
+	if err := reconciler.Reconcile(nil); err != nil {
+		t.Fatalf("retry Reconcile failed: %v", err)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 0 {
+		t.Errorf("expected the service to be deleted on retry, got %d services", len(services))
+	}
+}
+
+func TestReconcile_Faults_PartialGetServicesKeepsServices(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1)),
+		makeServiceConfig("svc2", "10.0.0.2:80", "rr", false, makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// A truncated dump makes svc2 look missing; recreating it fails, but
+	// nothing that exists is deleted
+	faults.limitServices(1)
+	if err := reconciler.Reconcile(configs); err == nil {
+		t.Fatal("expected recreating the hidden service to fail")
+	}
+	for _, op := range reconciler.LastOperations() {
+		if op.Action == ActionDelete {
+			t.Errorf("expected no deletions on a partial dump, got %+v", op)
+		}
+	}
+
+	faults.limitServices(-1)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile after a full dump failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected no changes once the dump is complete, got %+v", ops)
+	}
+}
+
+func TestReconcile_OneDestinationBatchPerService(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := syntheticServices(3, 4)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	// New services add their destinations at weight 0 and raise them after
+	if got := faults.callCount(opApplyDestinations); got != 6 {
+		t.Errorf("expected 2 batches per new service, got %d", got)
+	}
+
+	for i := range configs {
+		configs[i].Backends = configs[i].Backends[1:]
+		configs[i].Backends[0].Weight = intPtr(configs[i].Backends[0].GetWeight() + 10)
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if got := faults.callCount(opApplyDestinations) - 6; got != 3 {
+		t.Errorf("expected 1 batch per changed service, got %d", got)
+	}
+	if got := len(reconciler.LastOperations()); got != 6 {
+		t.Errorf("expected an update and a delete per service, got %d operations", got)
+	}
+}
+
+func TestReconcileServices_LooksUpOnlySelectedServices(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := syntheticServices(20, 2)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	listed, looked := faults.callCount(opGetServices), faults.callCount(opGetService)
+
+	configs[0].Backends[0].Weight = intPtr(configs[0].Backends[0].GetWeight() + 10)
+	if err := reconciler.ReconcileServices(configs, []string{configs[0].Name}); err != nil {
+		t.Fatalf("ReconcileServices failed: %v", err)
+	}
+	if got := faults.callCount(opGetServices) - listed; got != 0 {
+		t.Errorf("expected no listing of every service, got %d", got)
+	}
+	if got := faults.callCount(opGetService) - looked; got != 1 {
+		t.Errorf("expected one service lookup, got %d", got)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 1 || ops[0].Action != ActionUpdate {
+		t.Errorf("expected a single destination update, got %+v", ops)
+	}
+}
+
+func TestReconcile_Faults_DestinationBatchFails(t *testing.T) {
+	mgr, faults, reconciler := newFaultyReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := syntheticServices(1, 3)
+	faults.failOn(opApplyDestinations, 1, errInjected)
+	if err := reconciler.Reconcile(configs); !errors.Is(err, errInjected) {
+		t.Fatalf("expected the injected fault, got %v", err)
+	}
+	if got := len(failedOperations(reconciler)); got != 3 {
+		t.Errorf("expected every destination of the batch to fail, got %d", got)
+	}
+
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("expected the next Reconcile to add the destinations, got %v", err)
+	}
+	services, _ := mgr.GetServices()
+	if dests, _ := mgr.GetDestinations(services[0]); len(dests) != 3 {
+		t.Errorf("expected 3 destinations, got %d", len(dests))
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/easzlab/ezlb/pkg/config"
 	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
 )
 
 func TestReconcile_FullNATGeneratesSNATRules(t *testing.T) {
@@ -103,3 +104,161 @@ func TestReconcile_FullNATDisabledSkipsSNAT(t *testing.T) {
 		t.Fatalf("expected 0 FORWARD rules when full_nat is disabled, got %d", len(managedForward))
 	}
 }
+
+func TestReconcile_MarkGroupGeneratesMarkRules(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	svcCfg := makeServiceConfig("web-ports", "", "wlc", false,
+		makeBackend("192.168.1.1:80", 1), makeBackend("192.168.1.2:80", 1))
+	svcCfg.FWMark = 100
+	svcCfg.MarkGroup = []string{"10.0.0.4:80", "10.0.0.4:443"}
+	svcCfg.Persistence = config.PersistenceConfig{Timeout: "300s"}
+
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, _ := mgr.GetServices()
+	if len(services) != 1 || services[0].FWMark != 100 || services[0].Flags&ServiceFlagPersistent == 0 {
+		t.Fatalf("expected one persistent service with fwmark 100, got %+v", services)
+	}
+
+	fakeSnatMgr := reconciler.snatMgr.(*snat.FakeManager)
+	managed := fakeSnatMgr.GetManagedMark()
+	if len(managed) != 2 {
+		t.Fatalf("expected 2 MARK rules, got %d", len(managed))
+	}
+	if rule := managed["10.0.0.4:443/tcp"]; rule.Mark != 100 {
+		t.Errorf("expected 10.0.0.4:443/tcp to be marked 100, got %+v", rule)
+	}
+
+	// Removing the service removes its MARK rules
+	if err := reconciler.Reconcile(nil); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if managed := fakeSnatMgr.GetManagedMark(); len(managed) != 0 {
+		t.Errorf("expected MARK rules to be removed, got %d", len(managed))
+	}
+}
+
+func TestReconcile_DSCPGeneratesDSCPRules(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	dscp := 46
+	voice := makeServiceConfig("voice", "10.0.0.1:5060", "rr", false, makeBackend("192.168.1.1:5060", 1))
+	voice.Protocol = "udp"
+	voice.DSCP = &dscp
+	ports := makeServiceConfig("web-ports", "", "wlc", false, makeBackend("192.168.1.2:80", 1))
+	ports.FWMark = 100
+	ports.MarkGroup = []string{"10.0.0.4:80", "10.0.0.4:443"}
+	ports.DSCP = &dscp
+	plain := makeServiceConfig("plain", "10.0.0.2:80", "rr", false, makeBackend("192.168.1.3:80", 1))
+
+	if err := reconciler.Reconcile([]config.ServiceConfig{voice, ports, plain}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	fakeSnatMgr := reconciler.snatMgr.(*snat.FakeManager)
+	managed := fakeSnatMgr.GetManagedDSCP()
+	if len(managed) != 3 {
+		t.Fatalf("expected 3 DSCP rules, got %d: %+v", len(managed), managed)
+	}
+	if rule := managed["10.0.0.1:5060/udp"]; rule.DSCP != 46 {
+		t.Errorf("expected 10.0.0.1:5060/udp to be marked 46, got %+v", rule)
+	}
+	if _, ok := managed["10.0.0.4:443/tcp"]; !ok {
+		t.Error("expected a DSCP rule for each mark group address")
+	}
+
+	// Removing dscp removes the service's rules
+	voice.DSCP = nil
+	if err := reconciler.Reconcile([]config.ServiceConfig{voice, plain}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if managed := fakeSnatMgr.GetManagedDSCP(); len(managed) != 0 {
+		t.Errorf("expected DSCP rules to be removed, got %d", len(managed))
+	}
+}
+
+func TestReconcile_FullNATOutputInterface(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	svcCfg := makeServiceConfig("uplink2", "10.0.0.1:80", "rr", false,
+		makeBackend("192.168.1.1:8080", 1))
+	svcCfg.FullNAT = true
+	svcCfg.OutputInterface = "eth1"
+
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	fakeSnatMgr := reconciler.snatMgr.(*snat.FakeManager)
+	if rule := fakeSnatMgr.GetManaged()["192.168.1.1:8080/tcp"]; rule.OutputInterface != "eth1" {
+		t.Errorf("expected SNAT rule on eth1, got %+v", rule)
+	}
+	if rule := fakeSnatMgr.GetManagedForward()["192.168.1.1:8080/tcp"]; rule.OutputInterface != "eth1" {
+		t.Errorf("expected FORWARD rule on eth1, got %+v", rule)
+	}
+}
+
+// listingSNATManager is a fake SNAT manager that lists its rules like the
+// iptables manager does through snat.StatsProvider.
+type listingSNATManager struct {
+	*snat.FakeManager
+	extra []string
+}
+
+func (m *listingSNATManager) Stats() (map[string]snat.SNATRuleStats, error) {
+	stats := make(map[string]snat.SNATRuleStats)
+	for key := range m.GetManaged() {
+		stats[key] = snat.SNATRuleStats{}
+	}
+	for _, key := range m.extra {
+		stats[key] = snat.SNATRuleStats{}
+	}
+	return stats, nil
+}
+
+func TestDrift_SNATRules(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+	fake, _ := snat.NewManager(zap.NewNop())
+	snatMgr := &listingSNATManager{FakeManager: fake.(*snat.FakeManager)}
+	reconciler := NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.NewNop())
+
+	svc := makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.1:8080", 1))
+	svc.FullNAT = true
+	svc.SnatIP = "10.0.0.1"
+	configs := []config.ServiceConfig{svc}
+
+	drifts, err := reconciler.Drift(configs)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	kinds := make(map[string]string)
+	for _, drift := range drifts {
+		kinds[drift.Target] = drift.Kind
+	}
+	if kinds["192.168.1.1:8080/tcp"] != DriftMissingSNATRule {
+		t.Fatalf("expected a missing SNAT rule, got %v", drifts)
+	}
+
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if drifts, _ := reconciler.Drift(configs); len(drifts) != 0 {
+		t.Fatalf("expected no drift after reconcile, got %v", drifts)
+	}
+
+	snatMgr.extra = []string{"192.168.9.9:80/tcp"}
+	drifts, err = reconciler.Drift(configs)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Kind != DriftUnexpectedSNATRule || drifts[0].Service != "" {
+		t.Fatalf("expected an unexpected SNAT rule without service, got %v", drifts)
+	}
+}
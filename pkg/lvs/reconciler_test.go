@@ -1,25 +1,37 @@
 package lvs
 
 import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/easzlab/ezlb/pkg/config"
 	"github.com/easzlab/ezlb/pkg/snat"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // mockHealthChecker is a test double for the HealthChecker interface.
 type mockHealthChecker struct {
-	status map[string]bool
+	status   map[string]bool
+	degraded map[string]bool
 }
 
 func newMockHealthChecker() *mockHealthChecker {
 	return &mockHealthChecker{
-		status: make(map[string]bool),
+		status:   make(map[string]bool),
+		degraded: make(map[string]bool),
 	}
 }
 
+func (m *mockHealthChecker) IsDegraded(address string) bool {
+	return m.degraded[address]
+}
+
 func (m *mockHealthChecker) IsHealthy(address string) bool {
 	healthy, ok := m.status[address]
 	if !ok {
@@ -33,9 +45,14 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+// intPtr creates a pointer to an int value.
+func intPtr(i int) *int {
+	return &i
+}
+
 // newReconcilerTestEnv creates a Manager, mock HealthChecker, and Reconciler for testing.
 // It uses newTestManager which handles platform-specific setup and IPVS cleanup.
-func newReconcilerTestEnv(t *testing.T) (*Manager, *mockHealthChecker, *Reconciler) {
+func newReconcilerTestEnv(t testing.TB) (*Manager, *mockHealthChecker, *Reconciler) {
 	t.Helper()
 	mgr := newTestManager(t)
 	healthMgr := newMockHealthChecker()
@@ -62,7 +79,7 @@ func makeServiceConfig(name, listen, scheduler string, healthEnabled bool, backe
 func makeBackend(address string, weight int) config.BackendConfig {
 	return config.BackendConfig{
 		Address: address,
-		Weight:  weight,
+		Weight:  intPtr(weight),
 	}
 }
 
@@ -283,8 +300,372 @@ func TestReconcile_UpdateScheduler(t *testing.T) {
 	}
 }
 
+func TestReconcile_UpdatePersistence(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+
+	svcCfg := makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.1:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	svcCfg.Persistence = config.PersistenceConfig{Timeout: "300s"}
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	services, _ := mgr.GetServices()
+	if services[0].Flags&ServiceFlagPersistent == 0 || services[0].Timeout != 300 {
+		t.Fatalf("expected persistence with timeout 300, got flags=%#x timeout=%d", services[0].Flags, services[0].Timeout)
+	}
+
+	// A new timeout updates the service in place
+	svcCfg.Persistence.Timeout = "30m"
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile with a new timeout failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 1 || ops[0].Resource != ResourceService || ops[0].Action != ActionUpdate {
+		t.Fatalf("expected a single service update, got %+v", ops)
+	}
+	services, _ = mgr.GetServices()
+	if services[0].Timeout != 1800 {
+		t.Fatalf("expected timeout 1800, got %d", services[0].Timeout)
+	}
+
+	// Disabling persistence clears the flag again
+	svcCfg.Persistence = config.PersistenceConfig{}
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("third Reconcile failed: %v", err)
+	}
+	services, _ = mgr.GetServices()
+	if services[0].Flags&ServiceFlagPersistent != 0 {
+		t.Errorf("expected persistence to be disabled, got flags=%#x", services[0].Flags)
+	}
+}
+
+func TestReconcile_UpdateOnePacketScheduling(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:53"] = true
+
+	svcCfg := makeServiceConfig("dns", "10.0.0.1:53", "rr", true,
+		makeBackend("192.168.1.1:53", 1))
+	svcCfg.Protocol = "udp"
+	svcCfg.OPS = true
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+	services, _ := mgr.GetServices()
+	if services[0].Flags&ServiceFlagOnePacket == 0 {
+		t.Fatalf("expected one-packet scheduling flag, got flags=%#x", services[0].Flags)
+	}
+
+	// Disabling ops clears the flag on the existing service
+	svcCfg.OPS = false
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	services, _ = mgr.GetServices()
+	if services[0].Flags&ServiceFlagOnePacket != 0 {
+		t.Errorf("expected one-packet scheduling to be disabled, got flags=%#x", services[0].Flags)
+	}
+
+	svcCfg.OPS = true
+	drifts, err := reconciler.Drift([]config.ServiceConfig{svcCfg})
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Kind != DriftOPSMismatch {
+		t.Errorf("expected a single ops drift, got %v", drifts)
+	}
+}
+
+func TestReconcile_OnePacketSchedulingTCPAndUDP(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:53"] = true
+
+	svcCfg := makeServiceConfig("dns", "10.0.0.1:53", "rr", true,
+		makeBackend("192.168.1.1:53", 1))
+	svcCfg.Protocol = "tcp+udp"
+	svcCfg.OPS = true
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	services, _ := mgr.GetServices()
+	if len(services) != 2 {
+		t.Fatalf("expected a TCP and a UDP service, got %d", len(services))
+	}
+	for _, svc := range services {
+		onePacket := svc.Flags&ServiceFlagOnePacket != 0
+		if onePacket != (svc.Protocol == syscall.IPPROTO_UDP) {
+			t.Errorf("expected one-packet scheduling only on the UDP service, got protocol %d with flags=%#x", svc.Protocol, svc.Flags)
+		}
+	}
+
+	drifts, err := reconciler.Drift([]config.ServiceConfig{svcCfg})
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift, got %v", drifts)
+	}
+}
+
+func TestReconcile_DualStack(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["[2001:db8::10]:8080"] = true
+
+	svc := makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.1:8080", 5), makeBackend("[2001:db8::10]:8080", 3))
+	svc.ListenV6 = "[2001:db8::1]:80"
+	if err := reconciler.Reconcile([]config.ServiceConfig{svc}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("expected an IPv4 and an IPv6 virtual service, got %d", len(services))
+	}
+	want := map[string]string{
+		"10.0.0.1:80/tcp":      "192.168.1.1",
+		"[2001:db8::1]:80/tcp": "2001:db8::10",
+	}
+	for _, ipvsSvc := range services {
+		key := ServiceKeyFromIPVS(ipvsSvc).String()
+		dests, _ := mgr.GetDestinations(ipvsSvc)
+		if len(dests) != 1 || dests[0].Address.String() != want[key] {
+			t.Errorf("expected %s to get only backend %s, got %d destinations", key, want[key], len(dests))
+		}
+	}
+
+	drifts, err := reconciler.Drift([]config.ServiceConfig{svc})
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift after reconcile, got %v", drifts)
+	}
+}
+
+func TestReconcile_MultipleListenAddresses(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80,10.0.0.2:80-81", "rr", true,
+			makeBackend("192.168.1.1:8080", 5)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	listens := func() []string {
+		t.Helper()
+		services, _ := mgr.GetServices()
+		var result []string
+		for _, svc := range services {
+			dests, _ := mgr.GetDestinations(svc)
+			if len(dests) != 1 || dests[0].Weight != 5 {
+				t.Errorf("expected the shared backend on %s, got %d destinations", ServiceKeyFromIPVS(svc), len(dests))
+			}
+			result = append(result, ServiceKeyFromIPVS(svc).String())
+		}
+		sort.Strings(result)
+		return result
+	}
+	want := []string{"10.0.0.1:80/tcp", "10.0.0.2:80/tcp", "10.0.0.2:81/tcp"}
+	if got := listens(); !slices.Equal(got, want) {
+		t.Fatalf("expected virtual services %v, got %v", want, got)
+	}
+
+	// Dropping one address deletes only its virtual service
+	configs[0].Listen = "10.0.0.1:80,10.0.0.2:80"
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if got := listens(); !slices.Equal(got, want[:2]) {
+		t.Fatalf("expected virtual services %v, got %v", want[:2], got)
+	}
+	for _, op := range reconciler.LastOperations() {
+		if op.Target != "10.0.0.2:81/tcp" {
+			t.Errorf("expected only 10.0.0.2:81/tcp to change, got %+v", op)
+		}
+	}
+}
+
+func TestReconcile_MultiProtocolService(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:53"] = true
+	healthMgr.status["192.168.1.2:53"] = false
+
+	dns := makeServiceConfig("dns", "10.0.0.1:53", "rr", true,
+		makeBackend("192.168.1.1:53", 1), makeBackend("192.168.1.2:53", 1))
+	dns.Protocol = "tcp+udp"
+	if err := reconciler.Reconcile([]config.ServiceConfig{dns}); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	services, _ := mgr.GetServices()
+	var keys []string
+	for _, svc := range services {
+		keys = append(keys, ServiceKeyFromIPVS(svc).String())
+		dests, _ := mgr.GetDestinations(svc)
+		if len(dests) != 1 || dests[0].Address.String() != "192.168.1.1" {
+			t.Errorf("expected only the healthy backend on %s, got %d destinations", ServiceKeyFromIPVS(svc), len(dests))
+		}
+	}
+	sort.Strings(keys)
+	if want := []string{"10.0.0.1:53/tcp", "10.0.0.1:53/udp"}; !slices.Equal(keys, want) {
+		t.Fatalf("expected virtual services %v, got %v", want, keys)
+	}
+
+	// Narrowing to udp deletes only the TCP service
+	dns.Protocol = "udp"
+	if err := reconciler.Reconcile([]config.ServiceConfig{dns}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	ops := reconciler.LastOperations()
+	if len(ops) != 1 || ops[0].Action != ActionDelete || ops[0].Target != "10.0.0.1:53/tcp" {
+		t.Errorf("expected only the TCP service to be deleted, got %+v", ops)
+	}
+}
+
+func TestReconcile_DisabledServiceIsRemoved(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", true, makeBackend("192.168.1.1:8080", 1))
+	api := makeServiceConfig("api", "10.0.0.2:80", "rr", true, makeBackend("192.168.1.1:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{web, api}); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	api.Enabled = boolPtr(false)
+	if err := reconciler.Reconcile([]config.ServiceConfig{web, api}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	services, _ := mgr.GetServices()
+	if len(services) != 1 || ServiceKeyFromIPVS(services[0]).String() != "10.0.0.1:80/tcp" {
+		t.Fatalf("expected only the enabled service to remain, got %+v", services)
+	}
+	if drifts, err := reconciler.Drift([]config.ServiceConfig{web, api}); err != nil || len(drifts) != 0 {
+		t.Errorf("expected no drift for a disabled service, got %v (err: %v)", drifts, err)
+	}
+
+	// Enabling it again recreates it
+	api.Enabled = boolPtr(true)
+	if err := reconciler.Reconcile([]config.ServiceConfig{web, api}); err != nil {
+		t.Fatalf("third Reconcile failed: %v", err)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 2 {
+		t.Errorf("expected 2 services after enabling, got %d", len(services))
+	}
+}
+
+func TestReconcile_FWMarkService(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	markCfg := makeServiceConfig("grouped", "", "wrr", true,
+		makeBackend("192.168.1.1:8080", 1), makeBackend("192.168.1.2:8080", 2))
+	markCfg.FWMark = 10
+	listenCfg := makeServiceConfig("web", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.1:8080", 1))
+	configs := []config.ServiceConfig{markCfg, listenCfg}
+
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+	services, _ := mgr.GetServices()
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+	var markSvc *Service
+	for _, svc := range services {
+		if svc.FWMark == 10 {
+			markSvc = svc
+		}
+	}
+	if markSvc == nil {
+		t.Fatal("expected a service with fwmark 10")
+	}
+	dests, _ := mgr.GetDestinations(markSvc)
+	if len(dests) != 2 {
+		t.Fatalf("expected 2 destinations on the fwmark service, got %d", len(dests))
+	}
+
+	// A second pass finds the fwmark service in sync
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if ops := reconciler.LastOperations(); len(ops) != 0 {
+		t.Errorf("expected no operations on an unchanged config, got %+v", ops)
+	}
+}
+
 // --- Destination-level diff ---
 
+func TestReconcile_CreatesDestinationsByAscendingWeight(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	for _, addr := range []string{"192.168.1.1:8080", "192.168.1.2:8080", "192.168.1.3:8080", "192.168.1.4:8080"} {
+		healthMgr.status[addr] = true
+	}
+
+	svcCfg := makeServiceConfig("svc1", "10.0.0.1:80", "wrr", true,
+		makeBackend("192.168.1.1:8080", 5),
+		makeBackend("192.168.1.2:8080", 1),
+		makeBackend("192.168.1.3:8080", 3))
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	var order []string
+	for _, op := range reconciler.LastOperations() {
+		if op.Resource == ResourceDestination {
+			order = append(order, op.Target)
+		}
+	}
+	want := []string{"192.168.1.2:8080", "192.168.1.3:8080", "192.168.1.1:8080"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("expected destinations created in order %v, got %v", want, order)
+	}
+	// Staged weight-0 destinations end up with their configured weights
+	weights := destinationWeights(t, mgr)
+	if weights["192.168.1.1:8080"] != 5 || weights["192.168.1.2:8080"] != 1 || weights["192.168.1.3:8080"] != 3 {
+		t.Errorf("unexpected weights after creation: %v", weights)
+	}
+
+	svcCfg.Backends = append(svcCfg.Backends, makeBackend("192.168.1.4:8080", 2))
+	if err := reconciler.Reconcile([]config.ServiceConfig{svcCfg}); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	if weights := destinationWeights(t, mgr); weights["192.168.1.4:8080"] != 2 {
+		t.Errorf("expected backend added to an existing service with weight 2, got %v", weights)
+	}
+}
+
 func TestReconcile_AddBackend(t *testing.T) {
 	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
 	defer mgr.Close()
@@ -379,6 +760,40 @@ func TestReconcile_UpdateWeight(t *testing.T) {
 	}
 }
 
+func TestReconcile_ZeroWeightDrainsBackend(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", true,
+			makeBackend("192.168.1.1:8080", 5), makeBackend("192.168.1.2:8080", 5)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("first Reconcile failed: %v", err)
+	}
+
+	// Setting the configured weight to 0 keeps the destination for draining
+	configs[0].Backends[1].Weight = intPtr(0)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("second Reconcile failed: %v", err)
+	}
+	weights := destinationWeights(t, mgr)
+	if len(weights) != 2 || weights["192.168.1.1:8080"] != 5 {
+		t.Fatalf("expected both destinations to be kept, got %v", weights)
+	}
+	if weight, ok := weights["192.168.1.2:8080"]; !ok || weight != 0 {
+		t.Errorf("expected 192.168.1.2:8080 with weight 0, got %d (present: %t)", weight, ok)
+	}
+	for _, op := range reconciler.LastOperations() {
+		if op.Action == ActionDelete {
+			t.Errorf("expected no deletions, got %+v", op)
+		}
+	}
+}
+
 // --- Health check filtering ---
 
 func TestReconcile_HealthCheckEnabled_UnhealthyBackendExcluded(t *testing.T) {
@@ -741,3 +1156,465 @@ func TestReconciler_Cleanup_WithFullNATService(t *testing.T) {
 		t.Fatalf("expected 0 IPVS services after cleanup, got %d", len(services))
 	}
 }
+
+func TestReconcile_UpdateForwardMethod(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	backend := makeBackend("192.168.1.1:8080", 1)
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false, backend),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	backend.ForwardMethod = "tunnel"
+	configs[0].Backends = []config.BackendConfig{backend}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, _ := mgr.GetServices()
+	dests, _ := mgr.GetDestinations(services[0])
+	if len(dests) != 1 {
+		t.Fatalf("expected 1 destination, got %d", len(dests))
+	}
+	if dests[0].ConnectionFlags&ConnectionFlagFwdMask != ConnectionFlagTunnel {
+		t.Errorf("expected tunnel forwarding flags, got %d", dests[0].ConnectionFlags)
+	}
+}
+
+func TestReconcile_MixedForwardMethods(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	local := makeBackend("127.0.0.1:8080", 1)
+	local.ForwardMethod = "local"
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", false,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1),
+			local),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	flagsByAddress := func() map[string]uint32 {
+		t.Helper()
+		services, _ := mgr.GetServices()
+		dests, _ := mgr.GetDestinations(services[0])
+		flags := make(map[string]uint32, len(dests))
+		for _, dst := range dests {
+			flags[dst.Address.String()] = dst.ConnectionFlags & ConnectionFlagFwdMask
+		}
+		return flags
+	}
+	want := map[string]uint32{"192.168.1.1": ConnectionFlagMasq, "192.168.1.2": ConnectionFlagMasq, "127.0.0.1": ConnectionFlagLocalNode}
+	if got := flagsByAddress(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected per-backend forwarding flags %v, got %v", want, got)
+	}
+
+	// Switching one backend to direct routing updates only its destination
+	configs[0].Backends[1].ForwardMethod = "dr"
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	ops := reconciler.LastOperations()
+	if len(ops) != 1 || ops[0].Action != ActionUpdate || ops[0].Target != "192.168.1.2:8080" {
+		t.Errorf("expected a single destination update, got %+v", ops)
+	}
+	want["192.168.1.2"] = ConnectionFlagDirectRoute
+	if got := flagsByAddress(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected per-backend forwarding flags %v, got %v", want, got)
+	}
+}
+
+func TestReconcile_FlushConntrackOfDeletedNATDestinations(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	var flushed []string
+	reconciler.SetConntrackFlusher(func(svc *Service, dst *Destination) error {
+		flushed = append(flushed, DestinationKey{Address: dst.Address.String(), Port: dst.Port}.String())
+		return nil
+	})
+
+	direct := makeBackend("192.168.1.3:8080", 1)
+	direct.ForwardMethod = "dr"
+	flushing := makeServiceConfig("svc1", "10.0.0.1:80", "rr", false,
+		makeBackend("192.168.1.1:8080", 1),
+		makeBackend("192.168.1.2:8080", 1),
+		direct)
+	flushing.FlushConntrack = true
+	plain := makeServiceConfig("svc2", "10.0.0.2:80", "rr", false,
+		makeBackend("192.168.2.1:8080", 1),
+		makeBackend("192.168.2.2:8080", 1))
+	configs := []config.ServiceConfig{flushing, plain}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(flushed) != 0 {
+		t.Fatalf("expected no flush while creating destinations, got %v", flushed)
+	}
+
+	// Drop a NAT and a direct-routing backend of svc1 and a backend of svc2
+	configs[0].Backends = configs[0].Backends[:1]
+	configs[1].Backends = configs[1].Backends[:1]
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if want := []string{"192.168.1.2:8080"}; !reflect.DeepEqual(flushed, want) {
+		t.Errorf("expected conntrack flush of %v, got %v", want, flushed)
+	}
+	var recorded []string
+	for _, op := range reconciler.LastOperations() {
+		if op.Resource == ResourceConntrack {
+			recorded = append(recorded, op.Service+" "+op.Target)
+		}
+	}
+	if want := []string{"svc1 192.168.1.2:8080"}; !reflect.DeepEqual(recorded, want) {
+		t.Errorf("expected conntrack operation %v, got %v", want, recorded)
+	}
+}
+
+// --- Priority failover ---
+
+func TestReconcile_PriorityFailover(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	standby := makeBackend("192.168.2.1:8080", 1)
+	standby.Priority = 1
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1),
+			standby),
+	}
+
+	activeAddresses := func() map[string]bool {
+		t.Helper()
+		if err := reconciler.Reconcile(configs); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		result := make(map[string]bool)
+		for address := range destinationWeights(t, mgr) {
+			result[address] = true
+		}
+		return result
+	}
+
+	// All primaries healthy: standby is held in reserve
+	active := activeAddresses()
+	if len(active) != 2 || active["192.168.2.1:8080"] {
+		t.Fatalf("expected only primary backends, got %v", active)
+	}
+
+	// One primary down: remaining primary keeps serving
+	healthMgr.status["192.168.1.1:8080"] = false
+	active = activeAddresses()
+	if len(active) != 1 || !active["192.168.1.2:8080"] {
+		t.Fatalf("expected surviving primary only, got %v", active)
+	}
+
+	// All primaries down: standby tier takes over
+	healthMgr.status["192.168.1.2:8080"] = false
+	active = activeAddresses()
+	if len(active) != 1 || !active["192.168.2.1:8080"] {
+		t.Fatalf("expected standby backend to take over, got %v", active)
+	}
+
+	// Primary recovers: standby is withdrawn again
+	healthMgr.status["192.168.1.1:8080"] = true
+	active = activeAddresses()
+	if len(active) != 1 || !active["192.168.1.1:8080"] {
+		t.Fatalf("expected recovered primary only, got %v", active)
+	}
+}
+
+func TestReconcile_BackupBackend(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	healthMgr.status["192.168.1.1:8080"] = true
+	healthMgr.status["192.168.1.2:8080"] = true
+	healthMgr.status["192.168.2.1:8080"] = true
+
+	backup := makeBackend("192.168.2.1:8080", 3)
+	backup.Role = config.RoleBackup
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", true,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 1),
+			backup),
+	}
+	reconcile := func() map[string]int {
+		t.Helper()
+		if err := reconciler.Reconcile(configs); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		return destinationWeights(t, mgr)
+	}
+
+	// A healthy primary keeps the backup in IPVS with weight 0
+	weights := reconcile()
+	if weight, ok := weights["192.168.2.1:8080"]; !ok || weight != 0 || len(weights) != 3 {
+		t.Fatalf("expected backup held with weight 0, got %v", weights)
+	}
+	drains, err := reconciler.CheckDrains(configs, 0, true, time.Now())
+	if err != nil {
+		t.Fatalf("CheckDrains failed: %v", err)
+	}
+	if len(drains) != 0 {
+		t.Errorf("expected an idle backup not to be reported as draining, got %+v", drains)
+	}
+
+	// One primary down: the backup still waits
+	healthMgr.status["192.168.1.1:8080"] = false
+	if weights = reconcile(); weights["192.168.2.1:8080"] != 0 {
+		t.Fatalf("expected backup to stay at weight 0, got %v", weights)
+	}
+
+	// All primaries down: the backup gets its configured weight
+	healthMgr.status["192.168.1.2:8080"] = false
+	if weights = reconcile(); len(weights) != 1 || weights["192.168.2.1:8080"] != 3 {
+		t.Fatalf("expected backup with weight 3 only, got %v", weights)
+	}
+
+	// A primary recovers: the backup is set back to weight 0
+	healthMgr.status["192.168.1.1:8080"] = true
+	if weights = reconcile(); weights["192.168.1.1:8080"] != 1 || weights["192.168.2.1:8080"] != 0 {
+		t.Fatalf("expected recovered primary and backup at weight 0, got %v", weights)
+	}
+}
+
+// --- Maintenance mode ---
+
+func TestReconcile_MaintenanceDrainsAllDestinations(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", false,
+			makeBackend("192.168.1.1:8080", 5),
+			makeBackend("192.168.1.2:8080", 3)),
+	}
+
+	reconciler.SetMaintenance(true)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, _ := mgr.GetServices()
+	dests, _ := mgr.GetDestinations(services[0])
+	if len(dests) != 2 {
+		t.Fatalf("expected destinations to be kept during maintenance, got %d", len(dests))
+	}
+	for _, dst := range dests {
+		if dst.Weight != 0 {
+			t.Errorf("expected weight 0 during maintenance for %s, got %d", DestinationKeyFromIPVS(dst), dst.Weight)
+		}
+	}
+
+	reconciler.SetMaintenance(false)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	dests, _ = mgr.GetDestinations(services[0])
+	for _, dst := range dests {
+		if dst.Weight == 0 {
+			t.Errorf("expected configured weight to be restored for %s", DestinationKeyFromIPVS(dst))
+		}
+	}
+}
+
+func TestReconcile_DegradedBackendWeightScaled(t *testing.T) {
+	mgr, healthMgr, reconciler := newReconcilerTestEnv(t)
+	defer mgr.Close()
+
+	svc := makeServiceConfig("svc1", "10.0.0.1:80", "wrr", true,
+		makeBackend("192.168.1.1:8080", 10),
+		makeBackend("192.168.1.2:8080", 1))
+	svc.HealthCheck.DegradedWeightFactor = 0.3
+	configs := []config.ServiceConfig{svc}
+
+	healthMgr.degraded["192.168.1.1:8080"] = true
+	healthMgr.degraded["192.168.1.2:8080"] = true
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	weights := destinationWeights(t, mgr)
+	if weights["192.168.1.1:8080"] != 3 {
+		t.Errorf("expected degraded weight 3, got %d", weights["192.168.1.1:8080"])
+	}
+	if weights["192.168.1.2:8080"] != 1 {
+		t.Errorf("expected degraded weight to stay at least 1, got %d", weights["192.168.1.2:8080"])
+	}
+
+	healthMgr.degraded["192.168.1.1:8080"] = false
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if weight := destinationWeights(t, mgr)["192.168.1.1:8080"]; weight != 10 {
+		t.Errorf("expected configured weight after recovery, got %d", weight)
+	}
+}
+
+// serviceHealthChecker reports health per service and address.
+type serviceHealthChecker struct {
+	mockHealthChecker
+	unhealthy map[string]bool // "service/address"
+}
+
+func (s *serviceHealthChecker) IsServiceHealthy(service, address string) bool {
+	return !s.unhealthy[service+"/"+address]
+}
+
+func (s *serviceHealthChecker) IsServiceDegraded(service, address string) bool {
+	return false
+}
+
+func TestReconcile_ServiceHealthChecker(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+	healthMgr := &serviceHealthChecker{
+		mockHealthChecker: *newMockHealthChecker(),
+		unhealthy:         map[string]bool{"svc-http/192.168.1.1:8080": true},
+	}
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	reconciler := NewReconciler(mgr, healthMgr, snatMgr, zap.NewNop())
+
+	// The shared backend fails only the http service's check
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc-tcp", "10.0.0.1:80", "rr", true, makeBackend("192.168.1.1:8080", 1)),
+		makeServiceConfig("svc-http", "10.0.0.2:80", "rr", true,
+			makeBackend("192.168.1.1:8080", 1), makeBackend("192.168.1.2:8080", 1)),
+	}
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	for _, svc := range services {
+		dests, err := mgr.GetDestinations(svc)
+		if err != nil {
+			t.Fatalf("GetDestinations failed: %v", err)
+		}
+		switch svc.Address.String() {
+		case "10.0.0.1":
+			if len(dests) != 1 {
+				t.Errorf("expected the shared backend in svc-tcp, got %d destinations", len(dests))
+			}
+		case "10.0.0.2":
+			if len(dests) != 1 || dests[0].Address.String() != "192.168.1.2" {
+				t.Errorf("expected only 192.168.1.2 in svc-http, got %d destinations", len(dests))
+			}
+		}
+	}
+}
+
+func TestReconciler_PruneDeletesUnmanagedServices(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+
+	// A service created by another tool is left alone unless pruning
+	foreign, err := ConfigToIPVSService(makeServiceConfig("foreign", "10.0.0.9:80", "rr", false))
+	if err != nil {
+		t.Fatalf("ConfigToIPVSService failed: %v", err)
+	}
+	if err := mgr.CreateService(foreign); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.10:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 2 {
+		t.Fatalf("expected the foreign service to be kept without prune, got %d services", len(services))
+	}
+
+	reconciler.SetPrune(true)
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 1 || services[0].Address.String() != "10.0.0.1" {
+		t.Fatalf("expected only web to remain after pruning, got %v", services)
+	}
+	ops := reconciler.LastOperations()
+	if len(ops) != 1 || ops[0].Action != ActionDelete || ops[0].Target != "10.0.0.9:80/tcp" {
+		t.Errorf("expected one delete of the foreign service, got %+v", ops)
+	}
+}
+
+func TestReconciler_AdoptsMatchingServicesAfterRestart(t *testing.T) {
+	mgr, _, first := newReconcilerTestEnv(t)
+	web := makeServiceConfig("web", "10.0.0.1:80", "wrr", false,
+		makeBackend("192.168.1.10:8080", 5), makeBackend("192.168.1.11:8080", 3))
+	if err := first.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// A restarted daemon starts with an empty managed map
+	core, logs := observer.New(zap.InfoLevel)
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	restarted := NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.New(core))
+
+	web.Backends[1].Weight = intPtr(4)
+	if err := restarted.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	ops := restarted.LastOperations()
+	if len(ops) != 1 || ops[0].Action != ActionUpdate || ops[0].Target != "192.168.1.11:8080" {
+		t.Fatalf("expected the adopted service to be diffed in place, got %+v", ops)
+	}
+	if n := logs.FilterMessage("adopted existing IPVS service").Len(); n != 1 {
+		t.Errorf("expected one adoption log, got %d", n)
+	}
+
+	if err := restarted.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if n := logs.FilterMessage("adopted existing IPVS service").Len(); n != 1 {
+		t.Errorf("expected a managed service not to be adopted again, got %d logs", n)
+	}
+
+	// Once adopted, the service is managed and removed with the config
+	if err := restarted.Reconcile(nil); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if services, _ := mgr.GetServices(); len(services) != 0 {
+		t.Errorf("expected the adopted service to be deleted, got %d services", len(services))
+	}
+}
+
+func TestReconciler_EmptyServices(t *testing.T) {
+	_, healthMgr, reconciler := newReconcilerTestEnv(t)
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", true,
+		makeBackend("192.168.1.10:8080", 1), makeBackend("192.168.1.11:8080", 1))
+	api := makeServiceConfig("api", "10.0.0.2:80", "rr", true, makeBackend("192.168.2.10:8080", 1))
+	services := []config.ServiceConfig{web, api}
+
+	healthMgr.status["192.168.1.10:8080"] = false
+	if err := reconciler.Reconcile(services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if empty := reconciler.EmptyServices(); len(empty) != 0 {
+		t.Fatalf("expected no empty services while a backend is healthy, got %v", empty)
+	}
+
+	healthMgr.status["192.168.1.11:8080"] = false
+	if err := reconciler.Reconcile(services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if empty := reconciler.EmptyServices(); !slices.Equal(empty, []string{"web"}) {
+		t.Errorf("expected web to be empty, got %v", empty)
+	}
+}
@@ -0,0 +1,82 @@
+package lvs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSchedulerUnavailable is returned by CheckScheduler when the kernel has
+// no module for a scheduler.
+var ErrSchedulerUnavailable = errors.New("IPVS scheduler unavailable")
+
+// Paths consulted by CheckScheduler; tests point them at a temp directory.
+var (
+	sysModuleDir   = "/sys/module"
+	libModulesDir  = "/lib/modules"
+	osReleasePath  = "/proc/sys/kernel/osrelease"
+	readModuleFile = os.ReadFile
+)
+
+// CheckScheduler reports whether the kernel can provide an IPVS scheduler:
+// its ip_vs_<name> module is loaded, built in, or installed so that IPVS
+// loads it on first use. The returned error names the missing module.
+func CheckScheduler(name string) error {
+	module := "ip_vs_" + name
+	if _, err := os.Stat(filepath.Join(sysModuleDir, module)); err == nil {
+		return nil
+	}
+
+	raw, err := readModuleFile(osReleasePath)
+	if err != nil {
+		return fmt.Errorf("scheduler %q: cannot determine kernel release: %w", name, err)
+	}
+	release := strings.TrimSpace(string(raw))
+
+	var readErrs []error
+	for _, index := range []string{"modules.builtin", "modules.dep"} {
+		found, err := moduleListed(filepath.Join(libModulesDir, release, index), module)
+		if err != nil {
+			readErrs = append(readErrs, err)
+			continue
+		}
+		if found {
+			return nil
+		}
+	}
+	if len(readErrs) == 2 {
+		return fmt.Errorf("scheduler %q: cannot read kernel module index: %w", name, errors.Join(readErrs...))
+	}
+	return fmt.Errorf("%w: scheduler %q needs kernel module %s, which is neither loaded nor installed for kernel %s",
+		ErrSchedulerUnavailable, name, module, release)
+}
+
+// withSchedulerHint explains a failed service create or update by a missing
+// scheduler module, which the kernel only reports as ENOENT.
+func withSchedulerHint(err error, scheduler string) error {
+	if probeErr := CheckScheduler(scheduler); errors.Is(probeErr, ErrSchedulerUnavailable) {
+		return fmt.Errorf("%w (%v)", err, probeErr)
+	}
+	return err
+}
+
+// moduleListed reports whether a modules.builtin or modules.dep index lists
+// the module, e.g. "kernel/net/netfilter/ipvs/ip_vs_mh.ko.xz: ...".
+func moduleListed(path, module string) (bool, error) {
+	raw, err := readModuleFile(path)
+	if err != nil {
+		return false, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+	for scanner.Scan() {
+		entry, _, _ := strings.Cut(scanner.Text(), ":")
+		base := filepath.Base(entry)
+		if base == module+".ko" || strings.HasPrefix(base, module+".ko.") {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
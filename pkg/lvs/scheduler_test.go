@@ -0,0 +1,71 @@
+package lvs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setSchedulerProbePaths points CheckScheduler at a fake sysfs and module tree.
+func setSchedulerProbePaths(t *testing.T, loaded []string, builtin, dep string) {
+	t.Helper()
+	dir := t.TempDir()
+	oldSys, oldLib, oldRelease := sysModuleDir, libModulesDir, osReleasePath
+	t.Cleanup(func() {
+		sysModuleDir, libModulesDir, osReleasePath = oldSys, oldLib, oldRelease
+	})
+
+	sysModuleDir = filepath.Join(dir, "sys")
+	libModulesDir = filepath.Join(dir, "lib")
+	osReleasePath = filepath.Join(dir, "osrelease")
+	for _, module := range loaded {
+		if err := os.MkdirAll(filepath.Join(sysModuleDir, module), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	releaseDir := filepath.Join(libModulesDir, "6.1.0-test")
+	if err := os.MkdirAll(releaseDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		osReleasePath: "6.1.0-test\n",
+		filepath.Join(releaseDir, "modules.builtin"): builtin,
+		filepath.Join(releaseDir, "modules.dep"):     dep,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckScheduler(t *testing.T) {
+	setSchedulerProbePaths(t, []string{"ip_vs_rr"},
+		"kernel/net/netfilter/ipvs/ip_vs_wlc.ko\n",
+		"kernel/net/netfilter/ipvs/ip_vs_mh.ko.xz: kernel/net/netfilter/ipvs/ip_vs.ko.xz\n")
+
+	for _, scheduler := range []string{"rr", "wlc", "mh"} {
+		if err := CheckScheduler(scheduler); err != nil {
+			t.Errorf("expected scheduler %s to be available, got: %v", scheduler, err)
+		}
+	}
+
+	err := CheckScheduler("lblcr")
+	if !errors.Is(err, ErrSchedulerUnavailable) {
+		t.Fatalf("expected ErrSchedulerUnavailable for lblcr, got: %v", err)
+	}
+}
+
+func TestWithSchedulerHint(t *testing.T) {
+	setSchedulerProbePaths(t, []string{"ip_vs_rr"}, "", "")
+
+	createErr := errors.New("no such file or directory")
+	if err := withSchedulerHint(createErr, "rr"); err != createErr {
+		t.Errorf("expected error of an available scheduler to be unchanged, got: %v", err)
+	}
+	err := withSchedulerHint(createErr, "ovf")
+	if !errors.Is(err, createErr) || err == createErr {
+		t.Errorf("expected a wrapped error naming the missing module, got: %v", err)
+	}
+}
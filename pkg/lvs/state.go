@@ -0,0 +1,167 @@
+package lvs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// stateVersion is the format version of the state file.
+const stateVersion = 1
+
+// state is the content of the state file: the services a reconciler
+// manages, by ServiceKey.String, and for those in merge mode the
+// destinations it created, by DestinationKey.String.
+type state struct {
+	Version int                 `json:"version"`
+	Managed []string            `json:"managed"`
+	Merged  map[string][]string `json:"merged,omitempty"`
+}
+
+// LoadState marks the services recorded in a state file written by SaveState
+// as managed, so that a new process, such as the next `ezlb once` run, deletes
+// those that have left the config. A missing file is an empty state.
+func (r *Reconciler) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var saved state
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if saved.Version != stateVersion {
+		return fmt.Errorf("state file %s has unsupported version %d", path, saved.Version)
+	}
+
+	keys := make([]ServiceKey, 0, len(saved.Managed))
+	for _, value := range saved.Managed {
+		key, err := ParseServiceKey(value)
+		if err != nil {
+			return fmt.Errorf("state file %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	merged := make(map[ServiceKey]bool, len(saved.Merged))
+	var owned []drainKey
+	for value, dests := range saved.Merged {
+		service, err := ParseServiceKey(value)
+		if err != nil {
+			return fmt.Errorf("state file %s: %w", path, err)
+		}
+		merged[service] = true
+		for _, dest := range dests {
+			key, err := parseDestinationKey(dest)
+			if err != nil {
+				return fmt.Errorf("state file %s: %w", path, err)
+			}
+			owned = append(owned, drainKey{service: service, dest: key})
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		r.managed[key] = true
+	}
+	maps.Copy(r.merged, merged)
+	for _, key := range owned {
+		r.owned[key] = true
+	}
+	r.savedState = r.stateSignature()
+	r.logger.Info("loaded managed services from state file",
+		zap.String("path", path),
+		zap.Int("services", len(keys)),
+	)
+	return nil
+}
+
+// SaveState records the managed services in a state file, replacing it
+// atomically. It does nothing if they have not changed since the last
+// LoadState or SaveState.
+func (r *Reconciler) SaveState(path string) error {
+	r.mu.Lock()
+	managed := r.managedKeys()
+	merged := r.ownedDestinations()
+	signature := r.stateSignature()
+	unchanged := signature == r.savedState
+	r.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state{Version: stateVersion, Managed: managed, Merged: merged}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	r.mu.Lock()
+	r.savedState = signature
+	r.mu.Unlock()
+	return nil
+}
+
+// managedKeys returns the managed services in sorted order. The caller must
+// hold r.mu.
+func (r *Reconciler) managedKeys() []string {
+	keys := make([]string, 0, len(r.managed))
+	for key := range r.managed {
+		keys = append(keys, key.String())
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// stateSignature identifies the managed set and the owned destinations for
+// SaveState. The caller must hold r.mu.
+func (r *Reconciler) stateSignature() string {
+	signature := strings.Join(r.managedKeys(), ",")
+	merged := r.ownedDestinations()
+	for _, service := range slices.Sorted(maps.Keys(merged)) {
+		signature += ";" + service + "=" + strings.Join(merged[service], ",")
+	}
+	return signature
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path, creating the directory if needed, so readers never see a
+// partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,136 @@
+package lvs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
+)
+
+func TestReconciler_StateDeletesServicesRemovedBetweenRuns(t *testing.T) {
+	mgr, _, first := newReconcilerTestEnv(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.10:8080", 1))
+	api := makeServiceConfig("api", "10.0.0.2:443", "rr", false, makeBackend("192.168.2.10:9090", 1))
+	if err := first.Reconcile([]config.ServiceConfig{web, api}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := first.SaveState(path); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	// A second reconciler stands for the next `ezlb once` run.
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	second := NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.NewNop())
+	if err := second.LoadState(path); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if err := second.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 1 || services[0].Address.String() != "10.0.0.1" {
+		t.Fatalf("expected only web to remain, got %v", services)
+	}
+
+	if err := second.SaveState(path); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	want := "{\n  \"version\": 1,\n  \"managed\": [\n    \"10.0.0.1:80/tcp\"\n  ]\n}\n"
+	if string(data) != want {
+		t.Errorf("state file = %q, want %q", data, want)
+	}
+}
+
+func TestReconciler_StateLeavesUnrecordedServices(t *testing.T) {
+	mgr, _, reconciler := newReconcilerTestEnv(t)
+
+	// A service created outside ezlb is not in the state file.
+	foreign := makeServiceConfig("foreign", "10.0.0.9:80", "rr", false, makeBackend("192.168.9.10:8080", 1))
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	other := NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.NewNop())
+	if err := other.Reconcile([]config.ServiceConfig{foreign}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if err := reconciler.LoadState(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("LoadState of a missing file failed: %v", err)
+	}
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.10:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	services, err := mgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("expected the foreign service to be kept, got %d services", len(services))
+	}
+}
+
+func TestReconciler_SaveStateSkipsUnchanged(t *testing.T) {
+	_, _, reconciler := newReconcilerTestEnv(t)
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+
+	web := makeServiceConfig("web", "10.0.0.1:80", "rr", false, makeBackend("192.168.1.10:8080", 1))
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := reconciler.SaveState(path); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	old := time.Unix(1, 0)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	if err := reconciler.Reconcile([]config.ServiceConfig{web}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := reconciler.SaveState(path); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Error("expected an unchanged state not to be rewritten")
+	}
+}
+
+func TestReconciler_LoadStateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", "{"},
+		{"unsupported version", `{"version": 2, "managed": []}`},
+		{"invalid key", `{"version": 1, "managed": ["not-a-key"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, reconciler := newReconcilerTestEnv(t)
+			path := filepath.Join(t.TempDir(), "state.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if err := reconciler.LoadState(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
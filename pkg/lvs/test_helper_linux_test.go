@@ -12,7 +12,7 @@ import (
 // newTestManager creates a Manager backed by the real Linux IPVS handle.
 // Tests must run serially (go test -p 1) because IPVS is a global kernel resource.
 // TestMain handles the initial Flush; each test flushes before and after via Cleanup.
-func newTestManager(t *testing.T) *Manager {
+func newTestManager(t testing.TB) *Manager {
 	t.Helper()
 	mgr, err := NewManager(zap.NewNop())
 	if err != nil {
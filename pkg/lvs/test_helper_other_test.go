@@ -10,7 +10,7 @@ import (
 )
 
 // newTestManager creates a Manager backed by the fake in-memory IPVS handle.
-func newTestManager(t *testing.T) *Manager {
+func newTestManager(t testing.TB) *Manager {
 	t.Helper()
 	mgr, err := NewManager(zap.NewNop())
 	if err != nil {
@@ -19,6 +19,19 @@ func newTestManager(t *testing.T) *Manager {
 	return mgr
 }
 
+// newFaultyTestManager creates a Manager backed by the fake in-memory IPVS
+// handle with fault injection, and returns the faults to configure.
+func newFaultyTestManager(t testing.TB) (*Manager, *fakeFaults) {
+	t.Helper()
+	handle, err := NewIPVSHandle("")
+	if err != nil {
+		t.Fatalf("NewIPVSHandle failed: %v", err)
+	}
+	fake := handle.(*fakeHandle)
+	fake.faults = newFakeFaults()
+	return newManagerWithHandle(fake, zap.NewNop()), fake.faults
+}
+
 func newTestService(address string, port uint16, protocol uint16, scheduler string) *Service {
 	return &Service{
 		Address:       net.ParseIP(address),
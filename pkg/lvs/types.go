@@ -1,24 +1,36 @@
 package lvs
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/easzlab/ezlb/pkg/config"
 )
 
-// ServiceKey uniquely identifies an IPVS virtual service.
+// ServiceKey uniquely identifies an IPVS virtual service. Firewall-mark
+// services are identified by mark and address family: Address is then the
+// family's unspecified address and Port and Protocol are zero.
 type ServiceKey struct {
 	Address  string
+	FWMark   uint32
 	Port     uint16
 	Protocol uint16
 }
 
 // String returns a human-readable representation of the ServiceKey.
 func (k ServiceKey) String() string {
-	return fmt.Sprintf("%s:%d/%s", k.Address, k.Port, protocolToString(k.Protocol))
+	if k.FWMark != 0 {
+		if k.Address == net.IPv6unspecified.String() {
+			return fmt.Sprintf("fwmark:%d/ipv6", k.FWMark)
+		}
+		return fmt.Sprintf("fwmark:%d", k.FWMark)
+	}
+	return fmt.Sprintf("%s/%s", net.JoinHostPort(k.Address, strconv.Itoa(int(k.Port))), protocolToString(k.Protocol))
 }
 
 // protocolToString converts a protocol number to its string name.
@@ -41,7 +53,7 @@ type DestinationKey struct {
 
 // String returns a human-readable representation of the DestinationKey.
 func (k DestinationKey) String() string {
-	return fmt.Sprintf("%s:%d", k.Address, k.Port)
+	return net.JoinHostPort(k.Address, strconv.Itoa(int(k.Port)))
 }
 
 // protocolFromString converts a protocol string to its syscall constant.
@@ -72,8 +84,36 @@ func netmaskFromFamily(family uint16) uint32 {
 	return 128
 }
 
+// fwmarkAddress returns the unspecified address of a firewall-mark service's
+// address family, taken from its first backend.
+func fwmarkAddress(svcCfg config.ServiceConfig) (net.IP, error) {
+	if len(svcCfg.Backends) == 0 {
+		return nil, fmt.Errorf("fwmark service needs a backend to determine its address family")
+	}
+	host, _, err := net.SplitHostPort(svcCfg.Backends[0].Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend address %q: %w", svcCfg.Backends[0].Address, err)
+	}
+	ipAddress := net.ParseIP(host)
+	if ipAddress == nil {
+		return nil, fmt.Errorf("invalid IP address %q", host)
+	}
+	if ipAddress.To4() != nil {
+		return net.IPv4zero.To4(), nil
+	}
+	return net.IPv6unspecified, nil
+}
+
 // ServiceKeyFromConfig generates a ServiceKey from a ServiceConfig.
 func ServiceKeyFromConfig(svcCfg config.ServiceConfig) (ServiceKey, error) {
+	if svcCfg.FWMark != 0 {
+		ipAddress, err := fwmarkAddress(svcCfg)
+		if err != nil {
+			return ServiceKey{}, err
+		}
+		return ServiceKey{Address: ipAddress.String(), FWMark: svcCfg.FWMark}, nil
+	}
+
 	host, portStr, err := net.SplitHostPort(svcCfg.Listen)
 	if err != nil {
 		return ServiceKey{}, fmt.Errorf("invalid listen address %q: %w", svcCfg.Listen, err)
@@ -98,6 +138,14 @@ func ServiceKeyFromConfig(svcCfg config.ServiceConfig) (ServiceKey, error) {
 
 // ServiceKeyFromIPVS generates a ServiceKey from a Service.
 func ServiceKeyFromIPVS(svc *Service) ServiceKey {
+	if svc.FWMark != 0 {
+		// The kernel reports no address for firewall-mark services
+		address := net.IPv4zero.String()
+		if svc.AddressFamily == syscall.AF_INET6 {
+			address = net.IPv6unspecified.String()
+		}
+		return ServiceKey{Address: address, FWMark: svc.FWMark}
+	}
 	return ServiceKey{
 		Address:  svc.Address.String(),
 		Port:     svc.Port,
@@ -115,6 +163,21 @@ func DestinationKeyFromIPVS(dst *Destination) DestinationKey {
 
 // ConfigToIPVSService converts a ServiceConfig to a Service struct.
 func ConfigToIPVSService(svcCfg config.ServiceConfig) (*Service, error) {
+	if svcCfg.FWMark != 0 {
+		ipAddress, err := fwmarkAddress(svcCfg)
+		if err != nil {
+			return nil, err
+		}
+		return withPersistence(svcCfg, &Service{
+			Address:       ipAddress,
+			FWMark:        svcCfg.FWMark,
+			Flags:         onePacketFlag(svcCfg),
+			SchedName:     svcCfg.Scheduler,
+			AddressFamily: addressFamilyFromIP(ipAddress),
+			Netmask:       netmaskFromFamily(addressFamilyFromIP(ipAddress)),
+		})
+	}
+
 	host, portStr, err := net.SplitHostPort(svcCfg.Listen)
 	if err != nil {
 		return nil, fmt.Errorf("invalid listen address %q: %w", svcCfg.Listen, err)
@@ -140,14 +203,91 @@ func ConfigToIPVSService(svcCfg config.ServiceConfig) (*Service, error) {
 
 	family := addressFamilyFromIP(ipAddress)
 
-	return &Service{
+	return withPersistence(svcCfg, &Service{
 		Address:       ipAddress,
 		Protocol:      protocol,
 		Port:          uint16(port),
 		SchedName:     svcCfg.Scheduler,
+		Flags:         onePacketFlag(svcCfg),
 		AddressFamily: family,
 		Netmask:       netmaskFromFamily(family),
-	}, nil
+	})
+}
+
+// onePacketFlag returns the IPVS flag for the service's ops setting. Only
+// UDP services are scheduled per packet, so the TCP half of a tcp+udp
+// service keeps its connection entries.
+func onePacketFlag(svcCfg config.ServiceConfig) uint32 {
+	if svcCfg.OPS && slices.Contains(svcCfg.Protocols(), "udp") {
+		return ServiceFlagOnePacket
+	}
+	return 0
+}
+
+// withPersistence applies the service's session persistence settings.
+func withPersistence(svcCfg config.ServiceConfig, svc *Service) (*Service, error) {
+	if !svcCfg.Persistence.IsEnabled() {
+		return svc, nil
+	}
+	ones, err := svcCfg.Persistence.GetPrefixLength(svc.AddressFamily == syscall.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	svc.Flags |= ServiceFlagPersistent
+	svc.Timeout = uint32(svcCfg.Persistence.GetTimeout() / time.Second)
+	svc.Netmask = netmaskFromPrefix(svc.AddressFamily, ones)
+	return svc, nil
+}
+
+// netmaskFromPrefix returns the IPVS persistence netmask for a prefix length:
+// the mask itself for IPv4, in the byte order netlink carries it, and the
+// prefix length for IPv6.
+func netmaskFromPrefix(family uint16, ones int) uint32 {
+	if family == syscall.AF_INET {
+		return binary.NativeEndian.Uint32(net.CIDRMask(ones, 32))
+	}
+	return uint32(ones)
+}
+
+// serviceNeedsUpdate reports whether an existing IPVS service differs from
+// the desired one in a property that UpdateService can change.
+func serviceNeedsUpdate(actual, desired *Service) bool {
+	return actual.SchedName != desired.SchedName ||
+		onePacketDiffers(actual, desired) ||
+		persistenceDiffers(actual, desired)
+}
+
+// persistenceDiffers reports whether the persistence settings of two services differ.
+func persistenceDiffers(actual, desired *Service) bool {
+	if actual.Flags&ServiceFlagPersistent != desired.Flags&ServiceFlagPersistent {
+		return true
+	}
+	if desired.Flags&ServiceFlagPersistent == 0 {
+		return false
+	}
+	return actual.Timeout != desired.Timeout || actual.Netmask != desired.Netmask
+}
+
+// onePacketDiffers reports whether one of two services uses one-packet scheduling
+// and the other does not.
+func onePacketDiffers(actual, desired *Service) bool {
+	return actual.Flags&ServiceFlagOnePacket != desired.Flags&ServiceFlagOnePacket
+}
+
+// forwardMethodToFlags maps a configured forwarding method to IPVS destination connection flags.
+func forwardMethodToFlags(method string) (uint32, error) {
+	switch method {
+	case "", "nat":
+		return ConnectionFlagMasq, nil
+	case "dr":
+		return ConnectionFlagDirectRoute, nil
+	case "tunnel":
+		return ConnectionFlagTunnel, nil
+	case "local":
+		return ConnectionFlagLocalNode, nil
+	default:
+		return 0, fmt.Errorf("unsupported forward method: %s", method)
+	}
 }
 
 // ConfigToIPVSDestination converts a BackendConfig to a Destination struct.
@@ -170,13 +310,18 @@ func ConfigToIPVSDestination(backendCfg config.BackendConfig) (*Destination, err
 		ipAddress = ipv4
 	}
 
+	connectionFlags, err := forwardMethodToFlags(backendCfg.ForwardMethod)
+	if err != nil {
+		return nil, err
+	}
+
 	family := addressFamilyFromIP(ipAddress)
 
 	return &Destination{
 		Address:         ipAddress,
 		Port:            uint16(port),
-		Weight:          backendCfg.Weight,
-		ConnectionFlags: ConnectionFlagMasq,
+		Weight:          backendCfg.GetWeight(),
+		ConnectionFlags: connectionFlags,
 		AddressFamily:   family,
 	}, nil
 }
@@ -1,6 +1,7 @@
 package lvs
 
 import (
+	"encoding/binary"
 	"net"
 	"syscall"
 	"testing"
@@ -176,6 +177,18 @@ func TestServiceKey_String(t *testing.T) {
 	}
 }
 
+func TestServiceKey_String_IPv6(t *testing.T) {
+	key := ServiceKey{
+		Address:  "2001:db8::1",
+		Port:     53,
+		Protocol: syscall.IPPROTO_UDP,
+	}
+	expected := "[2001:db8::1]:53/udp"
+	if key.String() != expected {
+		t.Errorf("expected %q, got %q", expected, key.String())
+	}
+}
+
 // --- DestinationKey tests ---
 
 func TestDestinationKeyFromIPVS(t *testing.T) {
@@ -203,6 +216,17 @@ func TestDestinationKey_String(t *testing.T) {
 	}
 }
 
+func TestDestinationKey_String_IPv6(t *testing.T) {
+	key := DestinationKey{
+		Address: "2001:db8::10",
+		Port:    8080,
+	}
+	expected := "[2001:db8::10]:8080"
+	if key.String() != expected {
+		t.Errorf("expected %q, got %q", expected, key.String())
+	}
+}
+
 // --- ConfigToIPVS conversion tests ---
 
 func TestConfigToIPVSService_ValidTCP(t *testing.T) {
@@ -275,10 +299,94 @@ func TestConfigToIPVSService_IPv6(t *testing.T) {
 	}
 }
 
+func TestConfigToIPVSService_Persistence(t *testing.T) {
+	tests := []struct {
+		name        string
+		listen      string
+		persistence config.PersistenceConfig
+		wantTimeout uint32
+		wantNetmask uint32
+	}{
+		{"ipv4 host mask", "10.0.0.1:80", config.PersistenceConfig{Timeout: "10m"}, 600, 0xFFFFFFFF},
+		{"ipv4 dotted mask", "10.0.0.1:80", config.PersistenceConfig{Timeout: "300s", Netmask: "255.255.255.0"}, 300, binary.NativeEndian.Uint32([]byte{255, 255, 255, 0})},
+		{"ipv4 prefix", "10.0.0.1:80", config.PersistenceConfig{Timeout: "300s", Netmask: "24"}, 300, binary.NativeEndian.Uint32([]byte{255, 255, 255, 0})},
+		{"ipv6 prefix", "[2001:db8::1]:80", config.PersistenceConfig{Timeout: "60s", Netmask: "64"}, 60, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := ConfigToIPVSService(config.ServiceConfig{
+				Listen: tt.listen, Protocol: "tcp", Scheduler: "rr", Persistence: tt.persistence,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if svc.Flags&ServiceFlagPersistent == 0 {
+				t.Error("expected persistent flag to be set")
+			}
+			if svc.Timeout != tt.wantTimeout {
+				t.Errorf("expected timeout %d, got %d", tt.wantTimeout, svc.Timeout)
+			}
+			if svc.Netmask != tt.wantNetmask {
+				t.Errorf("expected netmask %#x, got %#x", tt.wantNetmask, svc.Netmask)
+			}
+		})
+	}
+}
+
+func TestConfigToIPVSService_FWMark(t *testing.T) {
+	tests := []struct {
+		name       string
+		backend    string
+		wantFamily uint16
+		wantKey    string
+	}{
+		{"ipv4", "192.168.1.1:8080", syscall.AF_INET, "fwmark:7"},
+		{"ipv6", "[2001:db8::10]:8080", syscall.AF_INET6, "fwmark:7/ipv6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svcCfg := config.ServiceConfig{
+				FWMark: 7, Protocol: "tcp", Scheduler: "rr",
+				Backends: []config.BackendConfig{{Address: tt.backend, Weight: intPtr(1)}},
+			}
+			svc, err := ConfigToIPVSService(svcCfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if svc.FWMark != 7 || svc.Port != 0 || svc.Protocol != 0 {
+				t.Errorf("expected fwmark 7 without port and protocol, got fwmark=%d port=%d protocol=%d", svc.FWMark, svc.Port, svc.Protocol)
+			}
+			if svc.AddressFamily != tt.wantFamily {
+				t.Errorf("expected address family %d, got %d", tt.wantFamily, svc.AddressFamily)
+			}
+
+			key, err := ServiceKeyFromConfig(svcCfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key.String() != tt.wantKey {
+				t.Errorf("expected key %q, got %q", tt.wantKey, key.String())
+			}
+			// The kernel reports fwmark services without an address
+			fromKernel := &Service{FWMark: 7, AddressFamily: tt.wantFamily, Protocol: syscall.IPPROTO_TCP}
+			if ipvsKey := ServiceKeyFromIPVS(fromKernel); ipvsKey != key {
+				t.Errorf("expected IPVS key %+v to match config key %+v", ipvsKey, key)
+			}
+		})
+	}
+}
+
+func TestConfigToIPVSService_FWMarkWithoutBackends(t *testing.T) {
+	_, err := ConfigToIPVSService(config.ServiceConfig{FWMark: 7, Scheduler: "rr"})
+	if err == nil {
+		t.Fatal("expected error for fwmark service without backends, got nil")
+	}
+}
+
 func TestConfigToIPVSDestination_Valid(t *testing.T) {
 	backendCfg := config.BackendConfig{
 		Address: "192.168.1.10:8080",
-		Weight:  5,
+		Weight:  intPtr(5),
 	}
 	dst, err := ConfigToIPVSDestination(backendCfg)
 	if err != nil {
@@ -304,7 +412,7 @@ func TestConfigToIPVSDestination_Valid(t *testing.T) {
 func TestConfigToIPVSDestination_InvalidAddress(t *testing.T) {
 	backendCfg := config.BackendConfig{
 		Address: "not-valid",
-		Weight:  1,
+		Weight:  intPtr(1),
 	}
 	_, err := ConfigToIPVSDestination(backendCfg)
 	if err == nil {
@@ -315,10 +423,44 @@ func TestConfigToIPVSDestination_InvalidAddress(t *testing.T) {
 func TestConfigToIPVSDestination_InvalidIP(t *testing.T) {
 	backendCfg := config.BackendConfig{
 		Address: "bad-ip:8080",
-		Weight:  1,
+		Weight:  intPtr(1),
 	}
 	_, err := ConfigToIPVSDestination(backendCfg)
 	if err == nil {
 		t.Fatal("expected error for invalid backend IP, got nil")
 	}
 }
+
+func TestConfigToIPVSDestination_ForwardMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		want   uint32
+	}{
+		{method: "", want: ConnectionFlagMasq},
+		{method: "nat", want: ConnectionFlagMasq},
+		{method: "dr", want: ConnectionFlagDirectRoute},
+		{method: "tunnel", want: ConnectionFlagTunnel},
+		{method: "local", want: ConnectionFlagLocalNode},
+	}
+	for _, tt := range tests {
+		dst, err := ConfigToIPVSDestination(config.BackendConfig{
+			Address:       "192.168.1.10:8080",
+			Weight:        intPtr(1),
+			ForwardMethod: tt.method,
+		})
+		if err != nil {
+			t.Fatalf("forward method %q: unexpected error: %v", tt.method, err)
+		}
+		if dst.ConnectionFlags != tt.want {
+			t.Errorf("forward method %q: expected flags %d, got %d", tt.method, tt.want, dst.ConnectionFlags)
+		}
+	}
+
+	if _, err := ConfigToIPVSDestination(config.BackendConfig{
+		Address:       "192.168.1.10:8080",
+		Weight:        intPtr(1),
+		ForwardMethod: "bogus",
+	}); err == nil {
+		t.Fatal("expected error for unsupported forward method, got nil")
+	}
+}
@@ -0,0 +1,55 @@
+package lvs
+
+import (
+	"fmt"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// normalizedWeights returns the weights a service's backends are programmed
+// with, by address, when some exceed the service's max_weight, and nil when
+// every weight fits. Scaling and any loss of precision are logged when they
+// change rather than on every reconcile.
+func (r *Reconciler) normalizedWeights(svcCfg config.ServiceConfig) map[string]int {
+	scaled := config.NormalizeWeights(svcCfg)
+	if scaled == nil {
+		if _, logged := r.weightScaling[svcCfg.Name]; logged {
+			delete(r.weightScaling, svcCfg.Name)
+			r.logger.Info("backend weights fit max_weight again, no longer scaling",
+				zap.String("service", svcCfg.Name),
+			)
+		}
+		return nil
+	}
+
+	weights := make(map[string]int, len(scaled))
+	var lossy []zap.Field
+	for _, weight := range scaled {
+		weights[weight.Address] = weight.Scaled
+		if weight.Lossy {
+			lossy = append(lossy, zap.String(weight.Address, formatScaling(weight)))
+		}
+	}
+
+	signature := fmt.Sprint(scaled)
+	if r.weightScaling[svcCfg.Name] == signature {
+		return weights
+	}
+	r.weightScaling[svcCfg.Name] = signature
+	r.logger.Info("scaling backend weights down to max_weight",
+		zap.String("service", svcCfg.Name),
+		zap.Int("max_weight", svcCfg.GetMaxWeight()),
+	)
+	if len(lossy) > 0 {
+		r.logger.Warn("scaling backend weights loses precision, traffic shares differ from the configured ratio",
+			append([]zap.Field{zap.String("service", svcCfg.Name), zap.Int("max_weight", svcCfg.GetMaxWeight())}, lossy...)...,
+		)
+	}
+	return weights
+}
+
+// formatScaling describes a scaled weight for logs, e.g. "3→1".
+func formatScaling(weight config.ScaledWeight) string {
+	return fmt.Sprintf("%d→%d", weight.Weight, weight.Scaled)
+}
@@ -0,0 +1,58 @@
+package lvs
+
+import (
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReconcile_ScalesWeightsToMaxWeight(t *testing.T) {
+	mgr := newTestManager(t)
+	defer mgr.Close()
+	core, logs := observer.New(zapcore.InfoLevel)
+	snatMgr, _ := snat.NewManager(zap.NewNop())
+	reconciler := NewReconciler(mgr, newMockHealthChecker(), snatMgr, zap.New(core))
+
+	configs := []config.ServiceConfig{
+		makeServiceConfig("svc1", "10.0.0.1:80", "wrr", false,
+			makeBackend("192.168.1.1:8080", 1),
+			makeBackend("192.168.1.2:8080", 100000),
+			makeBackend("192.168.1.3:8080", 50000)),
+	}
+	// The second reconcile checks the scaling is not logged again
+	for range 2 {
+		if err := reconciler.Reconcile(configs); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+	}
+
+	services, _ := mgr.GetServices()
+	dests, _ := mgr.GetDestinations(services[0])
+	want := map[string]int{"192.168.1.1": 1, "192.168.1.2": 65535, "192.168.1.3": 32768}
+	for _, dst := range dests {
+		if dst.Weight != want[dst.Address.String()] {
+			t.Errorf("destination %s: expected weight %d, got %d", dst.Address, want[dst.Address.String()], dst.Weight)
+		}
+	}
+	if warnings := logs.FilterLevelExact(zapcore.WarnLevel).Len(); warnings != 1 {
+		t.Errorf("expected one precision warning, got %d", warnings)
+	}
+
+	// Weights that fit are programmed unchanged
+	configs[0].Backends[1].Weight = intPtr(3)
+	configs[0].Backends[2].Weight = intPtr(2)
+	if err := reconciler.Reconcile(configs); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	dests, _ = mgr.GetDestinations(services[0])
+	want = map[string]int{"192.168.1.1": 1, "192.168.1.2": 3, "192.168.1.3": 2}
+	for _, dst := range dests {
+		if dst.Weight != want[dst.Address.String()] {
+			t.Errorf("destination %s: expected weight %d, got %d", dst.Address, want[dst.Address.String()], dst.Weight)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -47,6 +49,31 @@ var (
 		[]string{"service", "listen", "protocol"},
 	)
 
+	// Service-level traffic rates over the service's stats window (Gauge)
+	serviceConnectionRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_service_connection_rate",
+			Help: "New connections per second for a service, averaged over its stats window",
+		},
+		[]string{"service", "listen", "protocol"},
+	)
+
+	serviceBytesInRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_service_bytes_in_rate",
+			Help: "Incoming bytes per second for a service, averaged over its stats window",
+		},
+		[]string{"service", "listen", "protocol"},
+	)
+
+	serviceBytesOutRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_service_bytes_out_rate",
+			Help: "Outgoing bytes per second for a service, averaged over its stats window",
+		},
+		[]string{"service", "listen", "protocol"},
+	)
+
 	// Backend-level traffic metrics (Counter)
 	backendConnectionsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -98,6 +125,31 @@ var (
 		[]string{"service", "backend"},
 	)
 
+	backendDegraded = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_backend_degraded",
+			Help: "Whether a healthy backend is degraded by slow responses, server errors or Retry-After (1=degraded, 0=not degraded)",
+		},
+		[]string{"service", "backend"},
+	)
+
+	backendCertExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_backend_cert_expiry_timestamp_seconds",
+			Help: "notAfter of the certificate presented to https health checks, as a Unix timestamp",
+		},
+		[]string{"service", "backend"},
+	)
+
+	backendResponseTime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ezlb_backend_response_time_seconds",
+			Help:    "Response time of the HTTP health checks a backend answered",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "backend"},
+	)
+
 	// Config reload metrics (Counter)
 	configReloadTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -106,6 +158,118 @@ var (
 		},
 	)
 
+	// Maintenance mode metrics (Gauge)
+	maintenanceMode = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ezlb_maintenance_mode",
+			Help: "Whether director-wide maintenance mode is enabled (1=enabled, 0=disabled)",
+		},
+	)
+
+	// Observe-only drift metrics (Gauge)
+	observeOnly = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ezlb_observe_only",
+			Help: "Whether the daemon runs in observe-only mode and never changes the kernel (1=observe-only, 0=enforcing)",
+		},
+	)
+
+	serviceEmpty = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_service_empty",
+			Help: "Whether a service with configured backends was left without destinations by the last reconcile, so its VIP drops traffic (1=empty)",
+		},
+		[]string{"service"},
+	)
+
+	// Synthetic probe metrics, a service-level SLI distinct from backend health
+	syntheticUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_service_synthetic_up",
+			Help: "Whether the last synthetic probe through the service VIP succeeded (1=success, 0=failure)",
+		},
+		[]string{"service", "listen"},
+	)
+
+	syntheticDuration = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_service_synthetic_duration_seconds",
+			Help: "Duration of the last synthetic probe through the service VIP",
+		},
+		[]string{"service", "listen"},
+	)
+
+	syntheticProbes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ezlb_service_synthetic_probes_total",
+			Help: "Synthetic probes through the service VIP by result (success or failure)",
+		},
+		[]string{"service", "listen", "result"},
+	)
+
+	driftItems = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_drift_items",
+			Help: "Number of differences between the configured and the actual IPVS state, found in observe-only mode or by the drift check",
+		},
+		[]string{"service", "kind"},
+	)
+
+	driftDetected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ezlb_drift_detected_total",
+			Help: "Differences between the configured and the actual IPVS state, counted when first seen",
+		},
+		[]string{"service", "kind"},
+	)
+
+	backendDraining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ezlb_backend_draining_connections",
+			Help: "Remaining active plus inactive connections of a backend draining with weight 0",
+		},
+		[]string{"service", "backend"},
+	)
+
+	drainCompletionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ezlb_drain_completions_total",
+			Help: "Number of completed backend drains by result (drained or timeout)",
+		},
+		[]string{"service", "result"},
+	)
+
+	// Process self-monitor metrics (Gauge)
+	selfGoroutines = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ezlb_self_goroutines",
+			Help: "Number of goroutines in the ezlb process",
+		},
+	)
+
+	selfHeapBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ezlb_self_heap_bytes",
+			Help: "Heap bytes allocated by the ezlb process",
+		},
+	)
+
+	selfOpenFDs = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ezlb_self_open_fds",
+			Help: "Number of open file descriptors (including sockets) in the ezlb process",
+		},
+	)
+
+	// Process self-monitor alarm metrics (Counter)
+	selfMonitorAlarmsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ezlb_self_monitor_alarms_total",
+			Help: "Total number of self-monitor samples exceeding a configured budget",
+		},
+		[]string{"resource"},
+	)
+
 	// Reconcile error metrics (Counter)
 	reconcileErrorsTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -113,6 +277,37 @@ var (
 			Help: "Total number of reconcile errors",
 		},
 	)
+
+	// Reconcile error storm metrics
+	reconcileFailingSince = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ezlb_reconcile_failing_since_seconds",
+			Help: "Unix time of the first reconcile failure since the last successful reconcile (0=reconciles succeed)",
+		},
+	)
+
+	reconcileErrorsSuppressedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ezlb_reconcile_errors_suppressed_total",
+			Help: "Total number of repeated reconcile errors not logged individually during an error storm",
+		},
+	)
+
+	ipvsCacheLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ezlb_ipvs_cache_lookups_total",
+			Help: "Reads of IPVS state by the reconciler with global.ipvs_cache_ttl set, by result (hit or miss)",
+		},
+		[]string{"result"},
+	)
+
+	// Reconcile rate limit metrics (Counter)
+	reconcilesDeferredTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ezlb_reconciles_deferred_total",
+			Help: "Total number of reconcile triggers batched into a later reconcile by global.min_reconcile_interval",
+		},
+	)
 )
 
 // SetServiceTraffic updates service-level traffic counters.
@@ -130,6 +325,18 @@ func SetServiceTraffic(service, listen, protocol string, connections, bytesIn, b
 	servicePacketsOutTotal.With(labels).Add(float64(packetsOut))
 }
 
+// SetServiceRates updates the service-level traffic rate gauges.
+func SetServiceRates(service, listen, protocol string, connectionsPerSec, bytesInPerSec, bytesOutPerSec float64) {
+	labels := prometheus.Labels{
+		"service":  service,
+		"listen":   listen,
+		"protocol": protocol,
+	}
+	serviceConnectionRate.With(labels).Set(connectionsPerSec)
+	serviceBytesInRate.With(labels).Set(bytesInPerSec)
+	serviceBytesOutRate.With(labels).Set(bytesOutPerSec)
+}
+
 // SetBackendTraffic updates backend-level traffic counters.
 func SetBackendTraffic(service, backend, protocol string, connections, bytesIn, bytesOut uint64) {
 	labels := prometheus.Labels{
@@ -166,6 +373,34 @@ func SetBackendHealth(service, backend string, healthy bool) {
 	backendHealthStatus.With(labels).Set(value)
 }
 
+// SetBackendDegraded updates the backend degraded gauge.
+func SetBackendDegraded(service, backend string, degraded bool) {
+	value := float64(0)
+	if degraded {
+		value = 1
+	}
+	backendDegraded.With(prometheus.Labels{
+		"service": service,
+		"backend": backend,
+	}).Set(value)
+}
+
+// SetBackendCertExpiry records the notAfter time of a backend's TLS certificate.
+func SetBackendCertExpiry(service, backend string, notAfter time.Time) {
+	backendCertExpiry.With(prometheus.Labels{
+		"service": service,
+		"backend": backend,
+	}).Set(float64(notAfter.Unix()))
+}
+
+// ObserveBackendResponseTime records the duration of an answered HTTP health check.
+func ObserveBackendResponseTime(service, backend string, seconds float64) {
+	backendResponseTime.With(prometheus.Labels{
+		"service": service,
+		"backend": backend,
+	}).Observe(seconds)
+}
+
 // IncConfigReload increments the config reload counter.
 func IncConfigReload() {
 	configReloadTotal.Inc()
@@ -176,6 +411,131 @@ func IncReconcileErrors() {
 	reconcileErrorsTotal.Inc()
 }
 
+// SetReconcileFailingSince sets when reconciles started failing; a zero time
+// means they succeed.
+func SetReconcileFailingSince(since time.Time) {
+	if since.IsZero() {
+		reconcileFailingSince.Set(0)
+		return
+	}
+	reconcileFailingSince.Set(float64(since.Unix()))
+}
+
+// IncReconcileErrorsSuppressed increments the suppressed reconcile error
+// counter.
+func IncReconcileErrorsSuppressed() {
+	reconcileErrorsSuppressedTotal.Inc()
+}
+
+// IncReconcilesDeferred increments the deferred reconcile trigger counter.
+func IncReconcilesDeferred() {
+	reconcilesDeferredTotal.Inc()
+}
+
+// SetMaintenanceMode updates the maintenance mode gauge.
+func SetMaintenanceMode(enabled bool) {
+	value := float64(0)
+	if enabled {
+		value = 1
+	}
+	maintenanceMode.Set(value)
+}
+
+// SetObserveOnly updates the observe-only mode gauge.
+func SetObserveOnly(enabled bool) {
+	value := float64(0)
+	if enabled {
+		value = 1
+	}
+	observeOnly.Set(value)
+}
+
+// SetDriftItems replaces the drift gauges with the given counts, keyed by
+// service name and drift kind.
+func SetDriftItems(counts map[[2]string]int) {
+	driftItems.Reset()
+	for key, count := range counts {
+		driftItems.With(prometheus.Labels{
+			"service": key[0],
+			"kind":    key[1],
+		}).Set(float64(count))
+	}
+}
+
+// IncIPVSCacheLookup counts a read of IPVS state through the cache.
+func IncIPVSCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	ipvsCacheLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// IncDriftDetected increments the detected drift counter of a service and
+// drift kind.
+func IncDriftDetected(service, kind string) {
+	driftDetected.WithLabelValues(service, kind).Inc()
+}
+
+// SetSyntheticResult records the outcome of a synthetic probe of a service VIP.
+func SetSyntheticResult(service, listen string, success bool, duration float64) {
+	up, result := float64(0), "failure"
+	if success {
+		up, result = 1, "success"
+	}
+	syntheticUp.WithLabelValues(service, listen).Set(up)
+	syntheticDuration.WithLabelValues(service, listen).Set(duration)
+	syntheticProbes.WithLabelValues(service, listen, result).Inc()
+}
+
+// DeleteSyntheticMetrics removes the synthetic probe metrics of a service VIP.
+func DeleteSyntheticMetrics(service, listen string) {
+	labels := prometheus.Labels{"service": service, "listen": listen}
+	syntheticUp.Delete(labels)
+	syntheticDuration.Delete(labels)
+	syntheticProbes.DeletePartialMatch(labels)
+}
+
+// SetEmptyServices replaces the empty service gauges with the given services.
+func SetEmptyServices(services []string) {
+	serviceEmpty.Reset()
+	for _, service := range services {
+		serviceEmpty.WithLabelValues(service).Set(1)
+	}
+}
+
+// SetDrainingBackends replaces the draining gauges with the remaining
+// connections, keyed by service name and backend address.
+func SetDrainingBackends(connections map[[2]string]int) {
+	backendDraining.Reset()
+	for key, count := range connections {
+		backendDraining.With(prometheus.Labels{
+			"service": key[0],
+			"backend": key[1],
+		}).Set(float64(count))
+	}
+}
+
+// IncDrainCompletions increments the drain completion counter.
+func IncDrainCompletions(service, result string) {
+	drainCompletionsTotal.WithLabelValues(service, result).Inc()
+}
+
+// SetSelfMonitorStats updates the process self-monitor gauges.
+// A negative openFDs value means the count is unavailable and is not reported.
+func SetSelfMonitorStats(goroutines int, heapBytes uint64, openFDs int) {
+	selfGoroutines.Set(float64(goroutines))
+	selfHeapBytes.Set(float64(heapBytes))
+	if openFDs >= 0 {
+		selfOpenFDs.Set(float64(openFDs))
+	}
+}
+
+// IncSelfMonitorAlarm increments the self-monitor alarm counter for a resource.
+func IncSelfMonitorAlarm(resource string) {
+	selfMonitorAlarmsTotal.WithLabelValues(resource).Inc()
+}
+
 // DeleteBackendMetrics removes all metrics for a specific backend.
 func DeleteBackendMetrics(service, backend, protocol string) {
 	backendLabels := prometheus.Labels{
@@ -194,6 +554,9 @@ func DeleteBackendMetrics(service, backend, protocol string) {
 		"backend": backend,
 	}
 	backendHealthStatus.Delete(healthLabels)
+	backendDegraded.Delete(healthLabels)
+	backendCertExpiry.Delete(healthLabels)
+	backendResponseTime.Delete(healthLabels)
 }
 
 // DeleteServiceMetrics removes all metrics for a specific service.
@@ -208,4 +571,7 @@ func DeleteServiceMetrics(service, listen, protocol string) {
 	serviceBytesOutTotal.Delete(labels)
 	servicePacketsInTotal.Delete(labels)
 	servicePacketsOutTotal.Delete(labels)
+	serviceConnectionRate.Delete(labels)
+	serviceBytesInRate.Delete(labels)
+	serviceBytesOutRate.Delete(labels)
 }
@@ -3,6 +3,7 @@ package metrics
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -94,6 +95,24 @@ func TestIncReconcileErrors(t *testing.T) {
 	}
 }
 
+func TestReconcileErrorStormMetrics(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+	SetReconcileFailingSince(since)
+	if got := testutil.ToFloat64(reconcileFailingSince); got != 1700000000 {
+		t.Errorf("expected failing since to be 1700000000, got %f", got)
+	}
+	SetReconcileFailingSince(time.Time{})
+	if got := testutil.ToFloat64(reconcileFailingSince); got != 0 {
+		t.Errorf("expected failing since to be reset, got %f", got)
+	}
+
+	initial := testutil.ToFloat64(reconcileErrorsSuppressedTotal)
+	IncReconcileErrorsSuppressed()
+	if after := testutil.ToFloat64(reconcileErrorsSuppressedTotal); after != initial+1 {
+		t.Errorf("expected suppressed errors counter to increment by 1, got %f -> %f", initial, after)
+	}
+}
+
 func TestDeleteBackendMetrics(t *testing.T) {
 	// First set some metrics
 	SetBackendTraffic("web", "192.168.1.10:8080", "tcp", 50, 2500, 1500)
@@ -247,3 +266,26 @@ func TestMetricNamePrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestIncDriftDetected(t *testing.T) {
+	IncDriftDetected("web", "weight_mismatch")
+	IncDriftDetected("web", "weight_mismatch")
+
+	if got := testutil.ToFloat64(driftDetected.WithLabelValues("web", "weight_mismatch")); got != 2 {
+		t.Errorf("expected 2 detected drift items, got %v", got)
+	}
+}
+
+func TestObserveBackendResponseTime(t *testing.T) {
+	ObserveBackendResponseTime("api", "192.168.1.20:8080", 0.004)
+	ObserveBackendResponseTime("api", "192.168.1.20:8080", 0.3)
+
+	if got := testutil.CollectAndCount(backendResponseTime, "ezlb_backend_response_time_seconds"); got != 1 {
+		t.Fatalf("expected 1 response time series, got %d", got)
+	}
+
+	DeleteBackendMetrics("api", "192.168.1.20:8080", "tcp")
+	if got := testutil.CollectAndCount(backendResponseTime, "ezlb_backend_response_time_seconds"); got != 0 {
+		t.Errorf("expected the series to be deleted with the backend, got %d", got)
+	}
+}
@@ -0,0 +1,73 @@
+// Package prestop calls the pre-stop hooks of services before backends
+// removed from their config are deleted from IPVS.
+package prestop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// Request is the JSON body POSTed to a pre-stop webhook.
+type Request struct {
+	Service string `json:"service"`
+	Backend string `json:"backend"`
+}
+
+// Run calls the service's pre-stop hook for a backend and waits until the
+// hook acknowledges or ctx is done. A webhook acknowledges with a 2xx
+// response; a command with exit status 0. Commands get the service name and
+// backend address in EZLB_SERVICE and EZLB_BACKEND.
+func Run(ctx context.Context, hook config.PreStopConfig, service, backend string) error {
+	if hook.URL != "" {
+		return post(ctx, hook.URL, Request{Service: service, Backend: backend})
+	}
+	if len(hook.Command) > 0 {
+		return run(ctx, hook.Command, service, backend)
+	}
+	return nil
+}
+
+func post(ctx context.Context, url string, body Request) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func run(ctx context.Context, command []string, service, backend string) error {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), "EZLB_SERVICE="+service, "EZLB_BACKEND="+backend)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+		}
+		return err
+	}
+	return nil
+}
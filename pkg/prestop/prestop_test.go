@@ -0,0 +1,86 @@
+package prestop
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestRun_Webhook(t *testing.T) {
+	var got Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := Run(context.Background(), config.PreStopConfig{URL: server.URL}, "web", "192.168.1.1:8080"); err != nil {
+		t.Fatalf("expected acknowledgment, got: %v", err)
+	}
+	if got.Service != "web" || got.Backend != "192.168.1.1:8080" {
+		t.Errorf("unexpected request body: %+v", got)
+	}
+}
+
+func TestRun_WebhookRejects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := Run(context.Background(), config.PreStopConfig{URL: server.URL}, "web", "192.168.1.1:8080")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected a 503 error, got: %v", err)
+	}
+}
+
+func TestRun_WebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := Run(ctx, config.PreStopConfig{URL: server.URL}, "web", "192.168.1.1:8080"); err == nil {
+		t.Fatal("expected a timeout error, got nil")
+	}
+}
+
+func TestRun_Command(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.out")
+	hook := config.PreStopConfig{Command: []string{"sh", "-c", `echo "$EZLB_SERVICE $EZLB_BACKEND" > ` + out}}
+	if err := Run(context.Background(), hook, "web", "192.168.1.1:8080"); err != nil {
+		t.Fatalf("expected acknowledgment, got: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read hook output: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "web 192.168.1.1:8080" {
+		t.Errorf("unexpected hook environment: %q", got)
+	}
+}
+
+func TestRun_CommandFails(t *testing.T) {
+	hook := config.PreStopConfig{Command: []string{"sh", "-c", "echo not ready; exit 1"}}
+	err := Run(context.Background(), hook, "web", "192.168.1.1:8080")
+	if err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Fatalf("expected the command output in the error, got: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+// Package resolver resolves backends addressed by hostname and re-resolves
+// them periodically, so DNS changes reach IPVS without a config reload.
+package resolver
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// lookupTimeout bounds a single hostname lookup.
+const lookupTimeout = 5 * time.Second
+
+// LookupFunc resolves a hostname to its IP addresses.
+type LookupFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+// defaultLookup resolves hostnames with the system resolver.
+func defaultLookup(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// Resolver tracks the hostnames used by backends and the IPs they resolve to.
+// A failed lookup keeps the previously resolved IPs, so a DNS outage does not
+// remove destinations.
+type Resolver struct {
+	lookup   LookupFunc
+	logger   *zap.Logger
+	hosts    map[string][]string // hostname -> sorted resolved IPs
+	changes  chan struct{}
+	cancel   context.CancelFunc
+	done     chan struct{}
+	interval time.Duration
+	mu       sync.RWMutex
+	loopMu   sync.Mutex
+}
+
+// NewResolver creates a Resolver. A nil lookup uses the system resolver.
+func NewResolver(lookup LookupFunc, logger *zap.Logger) *Resolver {
+	if lookup == nil {
+		lookup = defaultLookup
+	}
+	return &Resolver{
+		lookup:  lookup,
+		logger:  logger,
+		hosts:   make(map[string][]string),
+		changes: make(chan struct{}, 1),
+	}
+}
+
+// OnChange returns a channel that receives a notification whenever a periodic
+// re-resolution changed the IPs of a tracked hostname.
+func (r *Resolver) OnChange() <-chan struct{} {
+	return r.changes
+}
+
+// Update tracks the hostnames used by the given services, resolving those not
+// seen before and forgetting those no longer used. It reports whether the
+// resolved addresses changed.
+func (r *Resolver) Update(ctx context.Context, services []config.ServiceConfig) bool {
+	wanted := hostnames(services)
+
+	r.mu.Lock()
+	changed := false
+	for host := range r.hosts {
+		if !wanted[host] {
+			delete(r.hosts, host)
+			changed = true
+		}
+	}
+	var unresolved []string
+	for host := range wanted {
+		if _, ok := r.hosts[host]; !ok {
+			r.hosts[host] = nil
+			unresolved = append(unresolved, host)
+		}
+	}
+	r.mu.Unlock()
+
+	slices.Sort(unresolved)
+	for _, host := range unresolved {
+		if r.resolve(ctx, host) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Refresh re-resolves every tracked hostname and reports whether any of them
+// now resolves to a different set of IPs.
+func (r *Resolver) Refresh(ctx context.Context) bool {
+	r.mu.RLock()
+	hosts := make([]string, 0, len(r.hosts))
+	for host := range r.hosts {
+		hosts = append(hosts, host)
+	}
+	r.mu.RUnlock()
+
+	slices.Sort(hosts)
+	changed := false
+	for _, host := range hosts {
+		if r.resolve(ctx, host) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// resolve looks up a single hostname and stores the result, reporting whether
+// its IPs changed. On failure the previous IPs are kept.
+func (r *Resolver) resolve(ctx context.Context, host string) bool {
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	ips, err := r.lookup(lookupCtx, host)
+	if err != nil {
+		r.logger.Warn("failed to resolve backend hostname, keeping previous addresses",
+			zap.String("host", host),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	resolved := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		resolved = append(resolved, ip.String())
+	}
+	slices.Sort(resolved)
+	resolved = slices.Compact(resolved)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, tracked := r.hosts[host]
+	if !tracked || slices.Equal(previous, resolved) {
+		return false
+	}
+	r.hosts[host] = resolved
+	r.logger.Info("backend hostname resolved",
+		zap.String("host", host),
+		zap.Strings("addresses", resolved),
+	)
+	return true
+}
+
+// Expand returns the services with every hostname backend replaced by one
+// backend per resolved IP, keeping its weight, priority and forwarding
+// method. IPs of a different address family than the service's VIP are
+// skipped, as are addresses already listed explicitly. Hostnames without
+// resolved IPs contribute no backends. Services without hostname backends are
+// returned unchanged.
+func (r *Resolver) Expand(services []config.ServiceConfig) []config.ServiceConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expanded := make([]config.ServiceConfig, len(services))
+	for i, svc := range services {
+		expanded[i] = svc
+		if !slices.ContainsFunc(svc.Backends, config.BackendConfig.IsHostname) {
+			continue
+		}
+
+		var vipIPv6, familyKnown bool
+		// Listen addresses share one family, so the first one decides. A
+		// dual-stack service keeps both, split by family in ExpandListens.
+		if listens, err := svc.ListenAddresses(); err == nil && len(listens) > 0 && !svc.IsDualStack() {
+			if host, _, err := net.SplitHostPort(listens[0]); err == nil {
+				if ip := net.ParseIP(host); ip != nil {
+					vipIPv6, familyKnown = ip.To4() == nil, true
+				}
+			}
+		}
+
+		seen := make(map[string]bool, len(svc.Backends))
+		for _, backend := range svc.Backends {
+			if !backend.IsHostname() {
+				seen[backend.Address] = true
+			}
+		}
+
+		backends := make([]config.BackendConfig, 0, len(svc.Backends))
+		for _, backend := range svc.Backends {
+			if !backend.IsHostname() {
+				backends = append(backends, backend)
+				continue
+			}
+			host, port, _ := net.SplitHostPort(backend.Address)
+			for _, ip := range r.hosts[host] {
+				if familyKnown && (net.ParseIP(ip).To4() == nil) != vipIPv6 {
+					continue
+				}
+				address := net.JoinHostPort(ip, port)
+				if seen[address] {
+					continue
+				}
+				seen[address] = true
+				resolved := backend
+				resolved.Address = address
+				backends = append(backends, resolved)
+			}
+		}
+		expanded[i].Backends = backends
+	}
+	return expanded
+}
+
+// Start re-resolves the tracked hostnames every interval until Stop is
+// called. Calling Start again with a different interval restarts the loop.
+func (r *Resolver) Start(interval time.Duration) {
+	r.loopMu.Lock()
+	defer r.loopMu.Unlock()
+
+	if r.cancel != nil {
+		if r.interval == interval {
+			return
+		}
+		r.stopLocked()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.interval = interval
+	r.done = make(chan struct{})
+	go r.loop(ctx, interval, r.done)
+}
+
+// Stop ends the periodic re-resolution.
+func (r *Resolver) Stop() {
+	r.loopMu.Lock()
+	defer r.loopMu.Unlock()
+	r.stopLocked()
+}
+
+func (r *Resolver) stopLocked() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+	r.done = nil
+}
+
+func (r *Resolver) loop(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.Refresh(ctx) {
+				continue
+			}
+			// Non-blocking send; one pending notification is enough
+			select {
+			case r.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// hostnames returns the set of hostnames used by the services' backends.
+func hostnames(services []config.ServiceConfig) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, svc := range services {
+		for _, backend := range svc.Backends {
+			if !backend.IsHostname() {
+				continue
+			}
+			host, _, _ := net.SplitHostPort(backend.Address)
+			hosts[host] = true
+		}
+	}
+	return hosts
+}
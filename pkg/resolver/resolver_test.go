@@ -0,0 +1,212 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// stubLookup serves lookups from a mutable table.
+type stubLookup struct {
+	records map[string][]string
+	err     error
+	mu      sync.Mutex
+}
+
+func (s *stubLookup) set(host string, ips ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[host] = ips
+}
+
+func (s *stubLookup) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *stubLookup) lookup(_ context.Context, host string) ([]net.IP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var ips []net.IP
+	for _, ip := range s.records[host] {
+		ips = append(ips, net.ParseIP(ip))
+	}
+	return ips, nil
+}
+
+// intPtr creates a pointer to an int value.
+func intPtr(i int) *int {
+	return &i
+}
+
+func newTestResolver(records map[string][]string) (*Resolver, *stubLookup) {
+	stub := &stubLookup{records: records}
+	return NewResolver(stub.lookup, zap.NewNop()), stub
+}
+
+func testServices(listen string, backends ...config.BackendConfig) []config.ServiceConfig {
+	return []config.ServiceConfig{{
+		Name:      "web",
+		Listen:    listen,
+		Protocol:  "tcp",
+		Scheduler: "wrr",
+		Backends:  backends,
+	}}
+}
+
+func addresses(services []config.ServiceConfig) []string {
+	var result []string
+	for _, backend := range services[0].Backends {
+		result = append(result, backend.Address)
+	}
+	return result
+}
+
+func TestExpand_MultipleRecords(t *testing.T) {
+	resolver, _ := newTestResolver(map[string][]string{
+		"app.example.com": {"10.0.0.2", "10.0.0.1", "fd00::1"},
+	})
+	services := testServices("10.0.0.100:80",
+		config.BackendConfig{Address: "10.0.0.1:8080", Weight: intPtr(1)},
+		config.BackendConfig{Address: "app.example.com:8080", Weight: intPtr(5), Priority: 1},
+	)
+
+	if !resolver.Update(context.Background(), services) {
+		t.Fatal("expected first resolution to report a change")
+	}
+	expanded := resolver.Expand(services)
+
+	got := addresses(expanded)
+	want := []string{"10.0.0.1:8080", "10.0.0.2:8080"}
+	if len(got) != len(want) {
+		t.Fatalf("expected backends %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected backends %v, got %v", want, got)
+		}
+	}
+	// The explicit backend keeps its own settings, the resolved one inherits the hostname's
+	if expanded[0].Backends[1].GetWeight() != 5 || expanded[0].Backends[1].Priority != 1 {
+		t.Errorf("expected resolved backend to inherit weight and priority, got %+v", expanded[0].Backends[1])
+	}
+	// The input is left untouched
+	if services[0].Backends[1].Address != "app.example.com:8080" {
+		t.Errorf("expected input services to be unchanged, got %q", services[0].Backends[1].Address)
+	}
+}
+
+func TestExpand_IPv6VIP(t *testing.T) {
+	resolver, _ := newTestResolver(map[string][]string{
+		"app.example.com": {"10.0.0.1", "fd00::1"},
+	})
+	services := testServices("[fd00::100]:80", config.BackendConfig{Address: "app.example.com:8080", Weight: intPtr(1)})
+	resolver.Update(context.Background(), services)
+
+	got := addresses(resolver.Expand(services))
+	if len(got) != 1 || got[0] != "[fd00::1]:8080" {
+		t.Errorf("expected only the IPv6 address, got %v", got)
+	}
+}
+
+func TestExpand_DualStack(t *testing.T) {
+	resolver, _ := newTestResolver(map[string][]string{
+		"app.example.com": {"10.0.0.1", "fd00::1"},
+	})
+	services := testServices("10.0.0.100:80", config.BackendConfig{Address: "app.example.com:8080", Weight: intPtr(1)})
+	services[0].ListenV6 = "[fd00::100]:80"
+	resolver.Update(context.Background(), services)
+
+	got := addresses(resolver.Expand(services))
+	if len(got) != 2 || got[0] != "10.0.0.1:8080" || got[1] != "[fd00::1]:8080" {
+		t.Errorf("expected the addresses of both families, got %v", got)
+	}
+}
+
+func TestExpand_Unresolved(t *testing.T) {
+	resolver, _ := newTestResolver(map[string][]string{})
+	services := testServices("10.0.0.100:80", config.BackendConfig{Address: "missing.example.com:8080", Weight: intPtr(1)})
+	resolver.Update(context.Background(), services)
+
+	if got := addresses(resolver.Expand(services)); len(got) != 0 {
+		t.Errorf("expected no backends for an unresolved hostname, got %v", got)
+	}
+}
+
+func TestRefresh_DetectsChanges(t *testing.T) {
+	resolver, stub := newTestResolver(map[string][]string{
+		"app.example.com": {"10.0.0.1"},
+	})
+	services := testServices("10.0.0.100:80", config.BackendConfig{Address: "app.example.com:8080", Weight: intPtr(1)})
+	resolver.Update(context.Background(), services)
+
+	if resolver.Refresh(context.Background()) {
+		t.Error("expected no change when records are unchanged")
+	}
+
+	stub.set("app.example.com", "10.0.0.1", "10.0.0.3")
+	if !resolver.Refresh(context.Background()) {
+		t.Fatal("expected a change after a record was added")
+	}
+	if got := addresses(resolver.Expand(services)); len(got) != 2 {
+		t.Errorf("expected two backends, got %v", got)
+	}
+
+	// A failed lookup keeps the previous addresses
+	stub.fail(errors.New("no such host"))
+	if resolver.Refresh(context.Background()) {
+		t.Error("expected no change on lookup failure")
+	}
+	if got := addresses(resolver.Expand(services)); len(got) != 2 {
+		t.Errorf("expected previous backends to be kept, got %v", got)
+	}
+}
+
+func TestUpdate_ForgetsRemovedHostnames(t *testing.T) {
+	resolver, _ := newTestResolver(map[string][]string{
+		"app.example.com": {"10.0.0.1"},
+	})
+	services := testServices("10.0.0.100:80", config.BackendConfig{Address: "app.example.com:8080", Weight: intPtr(1)})
+	resolver.Update(context.Background(), services)
+
+	if resolver.Update(context.Background(), services) {
+		t.Error("expected no change for already resolved hostnames")
+	}
+	if !resolver.Update(context.Background(), testServices("10.0.0.100:80", config.BackendConfig{Address: "10.0.0.1:8080", Weight: intPtr(1)})) {
+		t.Error("expected a change when a hostname is no longer used")
+	}
+	if len(resolver.hosts) != 0 {
+		t.Errorf("expected no tracked hostnames, got %v", resolver.hosts)
+	}
+}
+
+func TestStart_NotifiesOnChange(t *testing.T) {
+	resolver, stub := newTestResolver(map[string][]string{
+		"app.example.com": {"10.0.0.1"},
+	})
+	services := testServices("10.0.0.100:80", config.BackendConfig{Address: "app.example.com:8080", Weight: intPtr(1)})
+	resolver.Update(context.Background(), services)
+
+	resolver.Start(10 * time.Millisecond)
+	defer resolver.Stop()
+
+	stub.set("app.example.com", "10.0.0.2")
+	select {
+	case <-resolver.OnChange():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a change notification")
+	}
+	if got := addresses(resolver.Expand(services)); len(got) != 1 || got[0] != "10.0.0.2:8080" {
+		t.Errorf("expected re-resolved backend, got %v", got)
+	}
+}
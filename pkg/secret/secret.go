@@ -0,0 +1,122 @@
+// Package secret resolves credentials referenced from the config, so that API
+// tokens and keys need not be inlined in ezlb.yaml. A reference is
+// file:/path/to/secret, read from a file that other users cannot access, or
+// env:NAME, read from an environment variable; any other value is the secret
+// itself. Files are read again when they change, so rotating a secret does
+// not require a restart.
+package secret
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference prefixes.
+const (
+	filePrefix = "file:"
+	envPrefix  = "env:"
+)
+
+// Validate checks the syntax of a reference without reading the secret.
+func Validate(ref string) error {
+	if path, ok := strings.CutPrefix(ref, filePrefix); ok && path == "" {
+		return fmt.Errorf("empty file path in %q", ref)
+	}
+	if name, ok := strings.CutPrefix(ref, envPrefix); ok && name == "" {
+		return fmt.Errorf("empty environment variable name in %q", ref)
+	}
+	return nil
+}
+
+// Resolve returns the secret a reference names. Surrounding whitespace, such
+// as the trailing newline of a file, is trimmed.
+func Resolve(ref string) (string, error) {
+	return New(ref).Get()
+}
+
+// CheckFile returns an error unless path is a regular file that users other
+// than its owner and group can neither read nor write.
+func CheckFile(path string) error {
+	_, err := statFile(path)
+	return err
+}
+
+// statFile returns the file info of a secret file after checking it as
+// CheckFile does.
+func statFile(path string) (os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("secret file %s is not a regular file", path)
+	}
+	if perm := info.Mode().Perm(); perm&0007 != 0 {
+		return nil, fmt.Errorf("secret file %s is accessible by other users (mode %04o), run chmod o-rwx on it", path, perm)
+	}
+	return info, nil
+}
+
+// Value is a reference resolved each time it is used. A file is read again
+// only when its modification time or size changed since the last read.
+type Value struct {
+	ref string
+
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+	size    int64
+	loaded  bool
+}
+
+// New returns a Value for a reference. An empty reference yields an empty
+// secret.
+func New(ref string) *Value {
+	return &Value{ref: ref}
+}
+
+// IsSet reports whether a secret is configured.
+func (v *Value) IsSet() bool {
+	return v != nil && v.ref != ""
+}
+
+// Get returns the current secret.
+func (v *Value) Get() (string, error) {
+	if !v.IsSet() {
+		return "", nil
+	}
+	if err := Validate(v.ref); err != nil {
+		return "", err
+	}
+	if name, ok := strings.CutPrefix(v.ref, envPrefix); ok {
+		value, found := os.LookupEnv(name)
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return strings.TrimSpace(value), nil
+	}
+	path, ok := strings.CutPrefix(v.ref, filePrefix)
+	if !ok {
+		return v.ref, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	info, err := statFile(path)
+	if err != nil {
+		return "", err
+	}
+	if v.loaded && info.ModTime().Equal(v.modTime) && info.Size() == v.size {
+		return v.value, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	v.value = strings.TrimSpace(string(data))
+	v.modTime, v.size, v.loaded = info.ModTime(), info.Size(), true
+	return v.value, nil
+}
@@ -0,0 +1,85 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EZLB_TEST_TOKEN", "from-env")
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"", ""},
+		{"inline", "inline"},
+		{"file:" + path, "from-file"},
+		{"env:EZLB_TEST_TOKEN", "from-env"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.ref)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	dir := t.TempDir()
+	open := filepath.Join(dir, "open")
+	if err := os.WriteFile(open, []byte("token"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref     string
+		wantErr string
+	}{
+		{"file:", "empty file path"},
+		{"env:", "empty environment variable name"},
+		{"env:EZLB_TEST_UNSET", "is not set"},
+		{"file:" + filepath.Join(dir, "missing"), "no such file"},
+		{"file:" + dir, "not a regular file"},
+		{"file:" + open, "accessible by other users"},
+	}
+	for _, tt := range tests {
+		_, err := Resolve(tt.ref)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Resolve(%q): expected error containing %q, got %v", tt.ref, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValue_ReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	value := New("file:" + path)
+	if got, err := value.Get(); err != nil || got != "old" {
+		t.Fatalf("Get() = %q, %v; want old", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte("rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := value.Get(); err != nil || got != "rotated" {
+		t.Errorf("Get() = %q, %v; want rotated", got, err)
+	}
+}
@@ -0,0 +1,140 @@
+package selfmon
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Sample holds a single measurement of the process resource usage.
+type Sample struct {
+	Goroutines int
+	HeapBytes  uint64
+	OpenFDs    int // -1 when the count is unavailable on this platform
+}
+
+// Monitor periodically samples goroutine count, heap usage and open file
+// descriptors, exports them as metrics and warns when a configured budget is
+// exceeded. Leaked health-check goroutines or netlink sockets otherwise go
+// unnoticed in long-running directors.
+type Monitor struct {
+	cfg     config.SelfMonitorConfig
+	logger  *zap.Logger
+	sample  func() Sample
+	stopCh  chan struct{}
+	stopped chan struct{}
+	mu      sync.RWMutex
+}
+
+// NewMonitor creates a new self-monitor with the given configuration.
+func NewMonitor(cfg config.SelfMonitorConfig, logger *zap.Logger) *Monitor {
+	return &Monitor{
+		cfg:     cfg,
+		logger:  logger,
+		sample:  takeSample,
+		stopCh:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start begins periodic sampling in a background goroutine.
+func (m *Monitor) Start() {
+	go m.run()
+}
+
+// Stop stops the sampling goroutine and waits for it to finish.
+func (m *Monitor) Stop() {
+	close(m.stopCh)
+	<-m.stopped
+}
+
+// UpdateConfig dynamically updates the sampling interval and alarm thresholds.
+func (m *Monitor) UpdateConfig(cfg config.SelfMonitorConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+}
+
+// run is the main sampling loop.
+func (m *Monitor) run() {
+	defer close(m.stopped)
+
+	m.mu.RLock()
+	interval := m.cfg.GetInterval()
+	m.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.check()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.mu.RLock()
+			newInterval := m.cfg.GetInterval()
+			m.mu.RUnlock()
+
+			// Adjust ticker if interval changed
+			if newInterval != interval {
+				ticker.Reset(newInterval)
+				interval = newInterval
+			}
+
+			m.check()
+		}
+	}
+}
+
+// check takes a sample, updates metrics and evaluates alarm thresholds.
+func (m *Monitor) check() {
+	sample := m.sample()
+	metrics.SetSelfMonitorStats(sample.Goroutines, sample.HeapBytes, sample.OpenFDs)
+
+	m.mu.RLock()
+	cfg := m.cfg
+	m.mu.RUnlock()
+
+	if cfg.MaxGoroutines > 0 && sample.Goroutines > cfg.MaxGoroutines {
+		m.alarm("goroutines", zap.Int("goroutines", sample.Goroutines), zap.Int("max_goroutines", cfg.MaxGoroutines))
+	}
+	if cfg.MaxHeapMB > 0 && sample.HeapBytes > uint64(cfg.MaxHeapMB)*1024*1024 {
+		m.alarm("heap", zap.Uint64("heap_bytes", sample.HeapBytes), zap.Int("max_heap_mb", cfg.MaxHeapMB))
+	}
+	if cfg.MaxOpenFDs > 0 && sample.OpenFDs > cfg.MaxOpenFDs {
+		m.alarm("open_fds", zap.Int("open_fds", sample.OpenFDs), zap.Int("max_open_fds", cfg.MaxOpenFDs))
+	}
+}
+
+// alarm logs a budget violation and increments the alarm counter.
+func (m *Monitor) alarm(resource string, fields ...zap.Field) {
+	metrics.IncSelfMonitorAlarm(resource)
+	m.logger.Warn("self-monitor budget exceeded", append([]zap.Field{zap.String("resource", resource)}, fields...)...)
+}
+
+// takeSample measures the current process resource usage.
+func takeSample() Sample {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return Sample{
+		Goroutines: runtime.NumGoroutine(),
+		HeapBytes:  memStats.HeapAlloc,
+		OpenFDs:    countOpenFDs(),
+	}
+}
+
+// countOpenFDs returns the number of open file descriptors of the current
+// process, or -1 if /proc is not available (e.g. on macOS).
+func countOpenFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
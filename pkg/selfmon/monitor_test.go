@@ -0,0 +1,67 @@
+package selfmon
+
+import (
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMonitor_NoAlarmWithoutThresholds(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	monitor := NewMonitor(config.SelfMonitorConfig{}, zap.New(core))
+	monitor.sample = func() Sample {
+		return Sample{Goroutines: 100000, HeapBytes: 1 << 40, OpenFDs: 100000}
+	}
+
+	monitor.check()
+
+	if logs.Len() != 0 {
+		t.Fatalf("expected no alarms when thresholds are unset, got %d", logs.Len())
+	}
+}
+
+func TestMonitor_AlarmsWhenBudgetExceeded(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	cfg := config.SelfMonitorConfig{
+		MaxGoroutines: 10,
+		MaxHeapMB:     1,
+		MaxOpenFDs:    10,
+	}
+	monitor := NewMonitor(cfg, zap.New(core))
+	monitor.sample = func() Sample {
+		return Sample{Goroutines: 11, HeapBytes: 512 * 1024, OpenFDs: 20}
+	}
+
+	monitor.check()
+
+	entries := logs.FilterMessage("self-monitor budget exceeded").All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 alarms (goroutines, open_fds), got %d", len(entries))
+	}
+	resources := map[string]bool{}
+	for _, entry := range entries {
+		resources[entry.ContextMap()["resource"].(string)] = true
+	}
+	if !resources["goroutines"] || !resources["open_fds"] {
+		t.Errorf("unexpected alarm resources: %v", resources)
+	}
+}
+
+func TestMonitor_StartStop(t *testing.T) {
+	monitor := NewMonitor(config.SelfMonitorConfig{Interval: "10ms"}, zap.NewNop())
+	monitor.Start()
+	monitor.Stop()
+}
+
+func TestTakeSample(t *testing.T) {
+	sample := takeSample()
+	if sample.Goroutines <= 0 {
+		t.Errorf("expected positive goroutine count, got %d", sample.Goroutines)
+	}
+	if sample.HeapBytes == 0 {
+		t.Error("expected non-zero heap usage")
+	}
+}
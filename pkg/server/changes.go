@@ -0,0 +1,95 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"go.uber.org/zap"
+)
+
+// changeLogSize is the number of recent applied changes kept for the change
+// history endpoint.
+const changeLogSize = 1000
+
+// Reasons a reconcile ran, recorded with the changes it applied.
+const (
+	reasonStartup = "startup"
+	reasonConfig  = "config change"
+	reasonHealth  = "health change"
+	reasonRuntime = "runtime change"
+	reasonDNS     = "dns change"
+	reasonResync  = "resync"
+)
+
+// changeLog is a bounded in-memory record of the IPVS changes applied by
+// reconciles and why they ran.
+type changeLog struct {
+	changes []admin.AppliedChange
+	mu      sync.Mutex
+}
+
+// record appends the operations of a reconcile, discarding the oldest
+// changes when the log is full.
+func (l *changeLog) record(reason string, at time.Time, ops []lvs.Operation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, op := range ops {
+		change := admin.AppliedChange{
+			Time:     at,
+			Reason:   reason,
+			Service:  op.Service,
+			Resource: op.Resource,
+			Action:   op.Action,
+			Target:   op.Target,
+		}
+		if op.Err != nil {
+			change.Error = op.Err.Error()
+		}
+		l.changes = append(l.changes, change)
+	}
+	if len(l.changes) > changeLogSize {
+		l.changes = append([]admin.AppliedChange(nil), l.changes[len(l.changes)-changeLogSize:]...)
+	}
+}
+
+// list returns a copy of the recorded changes, oldest first.
+func (l *changeLog) list() []admin.AppliedChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]admin.AppliedChange{}, l.changes...)
+}
+
+// recordChanges records the changes of the reconcile that just ran for
+// reason, and logs a summary of them.
+func (s *Server) recordChanges(reason string) {
+	ops := s.reconciler.LastOperations()
+	if len(ops) == 0 {
+		return
+	}
+	s.changes.record(reason, time.Now(), ops)
+
+	failed := 0
+	for _, op := range ops {
+		if op.Err != nil {
+			failed++
+		}
+	}
+	s.logger.Info("reconcile applied changes",
+		zap.String("reason", reason),
+		zap.Int("changes", len(ops)),
+		zap.Int("failed", failed),
+	)
+}
+
+// changeAdapter implements admin.ChangeProvider with the server's change log.
+type changeAdapter struct {
+	server *Server
+}
+
+// Changes returns the recent applied changes, oldest first.
+func (a *changeAdapter) Changes() []admin.AppliedChange {
+	return a.server.changes.list()
+}
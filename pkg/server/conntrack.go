@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/conntrack"
+	"github.com/easzlab/ezlb/pkg/lvs"
+)
+
+// conntrackFlushTimeout bounds a single run of the conntrack tool.
+const conntrackFlushTimeout = 5 * time.Second
+
+// flushConntrack deletes the conntrack entries of connections through svc
+// to the deleted destination dst.
+func flushConntrack(svc *lvs.Service, dst *lvs.Destination) error {
+	filter := conntrack.Filter{Backend: dst.Address, BackendPort: dst.Port}
+	if svc.FWMark == 0 {
+		filter.VIP = svc.Address
+		filter.VIPPort = svc.Port
+		switch svc.Protocol {
+		case syscall.IPPROTO_TCP:
+			filter.Protocol = "tcp"
+		case syscall.IPPROTO_UDP:
+			filter.Protocol = "udp"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), conntrackFlushTimeout)
+	defer cancel()
+	return conntrack.Delete(ctx, filter)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+	"github.com/easzlab/ezlb/pkg/trafficlog"
+)
+
+// dashboardAdapter implements admin.DashboardProvider from the current
+// config, health statuses, weight overrides, traffic history and event log.
+type dashboardAdapter struct {
+	server *Server
+}
+
+// DashboardState assembles the read-only snapshot shown by the admin web UI.
+func (a *dashboardAdapter) DashboardState() admin.DashboardState {
+	s := a.server
+	cfg := s.resolvedConfig()
+	statuses := s.healthMgr.Snapshot()
+	history := s.trafficHistory()
+
+	overrides := make(map[string]int)
+	for _, override := range s.reconciler.WeightOverrides() {
+		overrides[override.Service+"/"+override.Backend] = override.Weight
+	}
+	exclusions := make(map[string]string)
+	for _, exclusion := range s.reconciler.Exclusions() {
+		exclusions[exclusion.Service+"/"+exclusion.Address] = exclusion.String()
+	}
+
+	state := admin.DashboardState{
+		GeneratedAt: time.Now(),
+		Services:    make([]admin.DashboardService, 0, len(cfg.Services)),
+		Events:      s.events.list(),
+		Maintenance: s.InMaintenance(),
+	}
+	for _, svc := range cfg.Services {
+		listen := svc.Listen
+		if svc.IsDualStack() {
+			listen += "," + svc.ListenV6
+		}
+		if svc.FWMark != 0 {
+			listen = fmt.Sprintf("fwmark:%d", svc.FWMark)
+		}
+		service := admin.DashboardService{
+			Name:      svc.Name,
+			Listen:    listen,
+			Protocol:  svc.Protocol,
+			Scheduler: svc.Scheduler,
+			Backends:  make([]admin.DashboardBackend, 0, len(svc.Backends)),
+			Traffic:   make([]admin.TrafficPoint, 0, len(history[svc.Name])),
+		}
+		for _, backend := range svc.Backends {
+			healthy, known := statuses[backend.Address]
+			entry := admin.DashboardBackend{
+				Address:       backend.Address,
+				ForwardMethod: backend.GetForwardMethod(),
+				Weight:        backend.GetWeight(),
+				Priority:      backend.Priority,
+				// Backends without a health check are always treated as healthy.
+				Healthy:      healthy || !known,
+				Degraded:     s.isDegraded(backend.Address),
+				Excluded:     exclusions[svc.Name+"/"+backend.Address],
+				ResponseTime: s.backendResponseTime(svc.Name, backend.Address),
+			}
+			if weight, ok := overrides[svc.Name+"/"+backend.Address]; ok {
+				entry.OverrideWeight = &weight
+			}
+			service.Backends = append(service.Backends, entry)
+		}
+		for _, point := range history[svc.Name] {
+			service.Traffic = append(service.Traffic, admin.TrafficPoint{
+				Time:        point.Time,
+				Connections: point.Connections,
+				InBytes:     point.InBytes,
+				OutBytes:    point.OutBytes,
+			})
+		}
+		state.Services = append(state.Services, service)
+	}
+	return state
+}
+
+// trafficHistory returns the traffic collector's recent samples, if it is running.
+func (s *Server) trafficHistory() map[string][]trafficlog.TrafficPoint {
+	s.collectorMu.RLock()
+	defer s.collectorMu.RUnlock()
+
+	if s.collector == nil {
+		return nil
+	}
+	return s.collector.History()
+}
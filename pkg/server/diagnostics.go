@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/easzlab/ezlb/pkg/lvs"
+)
+
+// Stages at which a single reconcile run can fail.
+const (
+	StageInit      = "init"
+	StageReconcile = "reconcile"
+	StageLock      = "lock"
+)
+
+// errorClassConfig classifies config load and validation failures.
+const errorClassConfig = "config"
+
+// OnceDiagnostics is the machine-readable outcome of a single reconcile run,
+// written by `ezlb once --diagnostics` for configuration pipelines.
+type OnceDiagnostics struct {
+	Status     string                `json:"status"` // "ok", "failed", "skipped" or "drift"
+	Stage      string                `json:"stage,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	ErrorClass string                `json:"error_class,omitempty"`
+	Operations []OperationDiagnostic `json:"operations"`
+	Succeeded  int                   `json:"succeeded"`
+	Failed     int                   `json:"failed"`
+}
+
+// OperationDiagnostic is the outcome of one attempted IPVS or SNAT change.
+type OperationDiagnostic struct {
+	Service    string `json:"service,omitempty"`
+	Resource   string `json:"resource"`
+	Action     string `json:"action"`
+	Target     string `json:"target"`
+	Result     string `json:"result"` // "ok" or "failed"
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+// LastOperations returns the changes attempted by the most recent reconcile.
+func (s *Server) LastOperations() []lvs.Operation {
+	return s.reconciler.LastOperations()
+}
+
+// NewOnceDiagnostics summarizes a run that ended with err (nil on success)
+// at the given stage, including every attempted operation.
+func NewOnceDiagnostics(stage string, err error, ops []lvs.Operation) OnceDiagnostics {
+	diag := OnceDiagnostics{Status: "ok", Operations: []OperationDiagnostic{}}
+	if errors.Is(err, ErrLockNotHeld) {
+		diag.Status = "skipped"
+		diag.Stage = StageLock
+		diag.Error = err.Error()
+	} else if errors.Is(err, ErrDrift) {
+		diag.Status = "drift"
+		diag.Stage = stage
+		diag.Error = err.Error()
+	} else if err != nil {
+		diag.Status = "failed"
+		diag.Stage = stage
+		diag.Error = err.Error()
+		diag.ErrorClass = classifyError(err)
+	}
+
+	for _, op := range ops {
+		opDiag := OperationDiagnostic{
+			Service:  op.Service,
+			Resource: op.Resource,
+			Action:   op.Action,
+			Target:   op.Target,
+			Result:   "ok",
+		}
+		if op.Err != nil {
+			opDiag.Result = "failed"
+			opDiag.Error = op.Err.Error()
+			opDiag.ErrorClass = lvs.ClassifyError(op.Err)
+			diag.Failed++
+		} else {
+			diag.Succeeded++
+		}
+		diag.Operations = append(diag.Operations, opDiag)
+	}
+	return diag
+}
+
+// classifyError classifies a run-level error, treating config errors separately.
+func classifyError(err error) string {
+	if errors.Is(err, ErrConfig) {
+		return errorClassConfig
+	}
+	return lvs.ClassifyError(err)
+}
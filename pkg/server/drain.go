@@ -0,0 +1,59 @@
+package server
+
+import (
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// checkDrains polls destinations draining with weight 0, exports their
+// remaining connections and reports drains that completed. With
+// global.drain.remove, completed destinations are deleted by a reconcile.
+func (s *Server) checkDrains() {
+	cfg := s.resolvedConfig()
+	drainCfg := cfg.Global.Drain
+	if !drainCfg.IsEnabled() {
+		metrics.SetDrainingBackends(nil)
+		return
+	}
+
+	remove := drainCfg.IsRemove() && s.changesKernel()
+	drains, err := s.reconciler.CheckDrains(cfg.Services, drainCfg.GetTimeout(), remove, time.Now())
+	if err != nil {
+		s.logger.Error("drain check failed", zap.Error(err))
+		return
+	}
+
+	draining := make(map[[2]string]int)
+	completed := false
+	for _, drain := range drains {
+		if drain.Result == "" {
+			draining[[2]string{drain.Service, drain.Backend}] = drain.ActiveConnections + drain.InactiveConnections
+			continue
+		}
+		completed = true
+		metrics.IncDrainCompletions(drain.Service, drain.Result)
+		fields := []zap.Field{
+			zap.String("service", drain.Service),
+			zap.String("backend", drain.Backend),
+			zap.Duration("duration", time.Since(drain.Since).Round(time.Second)),
+		}
+		if drain.Result == lvs.DrainResultTimeout {
+			s.logger.Warn("backend drain timed out", append(fields,
+				zap.Int("active_connections", drain.ActiveConnections),
+				zap.Int("inactive_connections", drain.InactiveConnections))...)
+			s.events.record("drain", "drain of backend %s in service %s timed out with %d connections left",
+				drain.Backend, drain.Service, drain.ActiveConnections+drain.InactiveConnections)
+		} else {
+			s.logger.Info("backend drained", fields...)
+			s.events.record("drain", "backend %s in service %s drained", drain.Backend, drain.Service)
+		}
+	}
+	metrics.SetDrainingBackends(draining)
+
+	if completed && remove {
+		s.triggerReconcile()
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// reportEmptyServices warns about the services the last reconcile left
+// without destinations, whose VIPs now drop traffic, and reports those that
+// have destinations again. Each change is logged and recorded as an event
+// once; the ezlb_service_empty gauge reflects the current set.
+func (s *Server) reportEmptyServices() {
+	empty := s.reconciler.EmptyServices()
+	metrics.SetEmptyServices(empty)
+
+	s.emptyMu.Lock()
+	defer s.emptyMu.Unlock()
+	current := make(map[string]bool, len(empty))
+	for _, service := range empty {
+		current[service] = true
+		if s.lastEmpty[service] {
+			continue
+		}
+		s.logger.Warn("service has no destinations, its VIP drops all traffic", zap.String("service", service))
+		s.events.record("empty", "service %s has no destinations", service)
+	}
+	for service := range s.lastEmpty {
+		if !current[service] {
+			s.logger.Info("service has destinations again", zap.String("service", service))
+			s.events.record("empty", "service %s has destinations again", service)
+		}
+	}
+	s.lastEmpty = current
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+)
+
+// eventLogSize is the number of recent events kept for the dashboard.
+const eventLogSize = 100
+
+// eventLog is a bounded in-memory record of notable director changes.
+type eventLog struct {
+	events []admin.Event
+	mu     sync.Mutex
+}
+
+// record appends an event, discarding the oldest one when the log is full.
+func (l *eventLog) record(kind, format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, admin.Event{
+		Time:    time.Now(),
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+	if len(l.events) > eventLogSize {
+		l.events = append([]admin.Event(nil), l.events[len(l.events)-eventLogSize:]...)
+	}
+}
+
+// list returns a copy of the recorded events, oldest first.
+func (l *eventLog) list() []admin.Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]admin.Event{}, l.events...)
+}
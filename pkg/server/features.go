@@ -0,0 +1,45 @@
+package server
+
+import (
+	"maps"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/featuregate"
+	"go.uber.org/zap"
+)
+
+// Features returns the feature gates resolved at startup.
+func (s *Server) Features() *featuregate.Gates {
+	return s.features
+}
+
+// logFeatureGates logs the state of every known feature gate, so it is clear
+// which experimental subsystems this process runs with.
+func (s *Server) logFeatureGates() {
+	var enabled []string
+	for _, gate := range s.features.List() {
+		if gate.Enabled {
+			enabled = append(enabled, gate.Name)
+			s.logger.Warn("experimental feature enabled",
+				zap.String("feature", gate.Name),
+				zap.String("stage", string(gate.Stage)),
+			)
+		}
+		s.logger.Debug("feature gate",
+			zap.String("feature", gate.Name),
+			zap.String("stage", string(gate.Stage)),
+			zap.Bool("enabled", gate.Enabled),
+			zap.Bool("default", gate.Default),
+		)
+	}
+	s.logger.Info("feature gates resolved", zap.Strings("enabled", enabled))
+}
+
+// warnFeatureGateChange logs when a reloaded config changes feature gates,
+// which only take effect after a restart.
+func (s *Server) warnFeatureGateChange(cfg *config.Config) {
+	if cfg == nil || maps.Equal(cfg.Global.FeatureGates, s.featureSettings) {
+		return
+	}
+	s.logger.Warn("global.feature_gates changed, restart ezlb to apply")
+}
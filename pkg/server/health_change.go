@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"go.uber.org/zap"
+)
+
+// Health states of a backend as seen by a service, in backendStates.
+const (
+	backendUp       = "up"
+	backendDown     = "down"
+	backendDegraded = "degraded"
+)
+
+// reconcileHealthChange is called by the health provider when the health of
+// a backend changes. It reconciles only the services whose view of one of
+// their backends changed, so a flap in a config with hundreds of VIPs does
+// not re-diff all of them; if every service is affected, all are reconciled.
+func (s *Server) reconcileHealthChange() {
+	cfg := s.resolvedConfig()
+	services := s.servicesWithHealthChanges(cfg)
+	if len(services) == 0 {
+		return
+	}
+	if len(services) == len(config.EnabledServices(cfg.Services)) {
+		s.triggerReconcileServices(reasonHealth, nil)
+		return
+	}
+	s.logger.Debug("reconciling services after health change", zap.Strings("services", services))
+	s.triggerReconcileServices(reasonHealth, services)
+}
+
+// servicesWithHealthChanges updates backendStates and returns, sorted, the
+// services that see one of their backends in another state than before.
+// Backends seen for the first time count as changed only if they are not up,
+// as new backends are added healthy by the reconcile that follows a reload.
+// Services without health checks ignore backend health and are skipped.
+func (s *Server) servicesWithHealthChanges(cfg *config.Config) []string {
+	s.backendStatesMu.Lock()
+	defer s.backendStatesMu.Unlock()
+
+	states := make(map[string]map[string]string)
+	changed := make(map[string]bool)
+	for _, svc := range config.EnabledServices(cfg.Services) {
+		if !svc.HealthCheck.IsEnabled() {
+			continue
+		}
+		for _, backend := range svc.Backends {
+			state := s.backendState(svc.Name, backend.Address)
+			if states[backend.Address] == nil {
+				states[backend.Address] = make(map[string]string)
+			}
+			states[backend.Address][svc.Name] = state
+
+			previous, known := s.backendStates[backend.Address][svc.Name]
+			if (known && previous != state) || (!known && state != backendUp) {
+				changed[svc.Name] = true
+			}
+		}
+	}
+	s.backendStates = states
+
+	services := make([]string, 0, len(changed))
+	for name := range changed {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// backendState returns the health of a backend as the given service sees it.
+func (s *Server) backendState(service, address string) string {
+	var healthy, degraded bool
+	if checker, ok := s.healthMgr.(lvs.ServiceHealthChecker); ok {
+		healthy = checker.IsServiceHealthy(service, address)
+		degraded = checker.IsServiceDegraded(service, address)
+	} else {
+		healthy = s.healthMgr.IsHealthy(address)
+		degraded = s.isDegraded(address)
+	}
+	switch {
+	case !healthy:
+		return backendDown
+	case degraded:
+		return backendDegraded
+	default:
+		return backendUp
+	}
+}
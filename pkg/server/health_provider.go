@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/healthcheck"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"go.uber.org/zap"
+)
+
+// HealthProvider is the source of backend health that the server and the
+// reconciler act on. The built-in healthcheck.Manager probes backends itself;
+// other implementations can relay an external source such as Consul checks or
+// Kubernetes readiness. A provider may also implement lvs.DegradedChecker.
+type HealthProvider interface {
+	// UpdateTargets replaces the services whose backends are tracked.
+	UpdateTargets(ctx context.Context, services []config.ServiceConfig)
+	// IsHealthy reports whether a backend address may receive traffic.
+	IsHealthy(address string) bool
+	// Snapshot returns a copy of the health of every tracked backend.
+	Snapshot() map[string]bool
+	// Stop releases the provider's resources.
+	Stop()
+}
+
+// HealthProviderFactory creates a HealthProvider that calls onChange whenever
+// the health of a backend changes.
+type HealthProviderFactory func(onChange func(), logger *zap.Logger) HealthProvider
+
+// NewBuiltinHealthProvider creates the built-in active health checker.
+func NewBuiltinHealthProvider(onChange func(), logger *zap.Logger) HealthProvider {
+	return healthcheck.NewManager(onChange, logger)
+}
+
+// responseTimeReporter is optionally implemented by a HealthProvider that
+// times the HTTP checks of backends.
+type responseTimeReporter interface {
+	ServiceResponseTimes(service, address string) (healthcheck.ResponseTimes, bool)
+}
+
+// backendResponseTime returns the recent HTTP check response times of a
+// service's backend for the dashboard, or nil if none were recorded.
+func (s *Server) backendResponseTime(service, address string) *admin.BackendResponseTime {
+	reporter, ok := s.healthMgr.(responseTimeReporter)
+	if !ok {
+		return nil
+	}
+	times, ok := reporter.ServiceResponseTimes(service, address)
+	if !ok {
+		return nil
+	}
+	return &admin.BackendResponseTime{
+		Samples: times.Samples,
+		P50:     milliseconds(times.P50),
+		P90:     milliseconds(times.P90),
+		P99:     milliseconds(times.P99),
+		Max:     milliseconds(times.Max),
+	}
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// isDegraded reports whether the health provider considers a backend degraded.
+func (s *Server) isDegraded(address string) bool {
+	checker, ok := s.healthMgr.(lvs.DegradedChecker)
+	return ok && checker.IsDegraded(address)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Socket states in /proc/net/{tcp,udp}[6] that denote a listening socket.
+const (
+	tcpStateListen = "0A"
+	udpStateClose  = "07" // bound, unconnected UDP socket
+)
+
+// readProcNetFile reads a socket table such as /proc/net/tcp; tests replace it.
+var readProcNetFile = os.ReadFile
+
+// hostListener is a local listening socket.
+type hostListener struct {
+	ip       net.IP
+	protocol string
+	port     int
+}
+
+// logHostListenerCollisions warns about configured VIP:ports that a local
+// daemon also listens on, either on the VIP itself or on a wildcard address.
+func (s *Server) logHostListenerCollisions(cfg *config.Config) {
+	if !kernelParamCheckEnabled || s.logger == nil || cfg == nil || !cfg.Global.IsCheckHostListeners() {
+		return
+	}
+
+	listeners, err := readHostListeners()
+	if err != nil {
+		s.logger.Error("failed to read host listening sockets", zap.Error(err))
+		return
+	}
+
+	for _, svc := range config.ExpandListens(cfg.Services) {
+		for _, listener := range hostListenerCollisions(svc, listeners) {
+			s.logger.Warn("service VIP collides with a local listening socket",
+				zap.String("service", svc.Name),
+				zap.String("listen", svc.Listen),
+				zap.String("protocol", listener.protocol),
+				zap.String("local_listener", net.JoinHostPort(listener.ip.String(), strconv.Itoa(listener.port))),
+			)
+		}
+	}
+}
+
+// hostListenerCollisions returns the listeners that overlap a service's VIP:port.
+func hostListenerCollisions(svc config.ServiceConfig, listeners []hostListener) []hostListener {
+	host, portStr, err := net.SplitHostPort(svc.Listen)
+	if err != nil {
+		return nil
+	}
+	vip := net.ParseIP(host)
+	port, err := strconv.Atoi(portStr)
+	if vip == nil || err != nil {
+		return nil
+	}
+	protocol := svc.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	var collisions []hostListener
+	for _, listener := range listeners {
+		if listener.protocol != protocol || listener.port != port {
+			continue
+		}
+		if listener.ip.IsUnspecified() || listener.ip.Equal(vip) {
+			collisions = append(collisions, listener)
+		}
+	}
+	return collisions
+}
+
+// readHostListeners collects listening TCP and bound UDP sockets from procfs.
+func readHostListeners() ([]hostListener, error) {
+	tables := []struct {
+		file     string
+		protocol string
+		state    string
+	}{
+		{"/proc/net/tcp", "tcp", tcpStateListen},
+		{"/proc/net/tcp6", "tcp", tcpStateListen},
+		{"/proc/net/udp", "udp", udpStateClose},
+		{"/proc/net/udp6", "udp", udpStateClose},
+	}
+
+	var listeners []hostListener
+	for _, table := range tables {
+		raw, err := readProcNetFile(table.file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// IPv6 may be disabled
+				continue
+			}
+			return nil, fmt.Errorf("read %s: %w", table.file, err)
+		}
+		parsed, err := parseSocketTable(string(raw), table.protocol, table.state)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", table.file, err)
+		}
+		listeners = append(listeners, parsed...)
+	}
+	return listeners, nil
+}
+
+// parseSocketTable parses a /proc/net socket table and returns the sockets in
+// the given state.
+func parseSocketTable(content, protocol, state string) ([]hostListener, error) {
+	var listeners []hostListener
+	for i, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		// Skip the header line and anything too short to hold local_address and st
+		if i == 0 || len(fields) < 4 || fields[3] != state {
+			continue
+		}
+		ip, port, err := parseProcNetAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, hostListener{ip: ip, protocol: protocol, port: port})
+	}
+	return listeners, nil
+}
+
+// parseProcNetAddress decodes an address such as "0100007F:0050". The IP is
+// stored as 32-bit words in host (little-endian) byte order.
+func parseProcNetAddress(value string) (net.IP, int, error) {
+	hexIP, hexPort, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid socket address %q", value)
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid socket address %q", value)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid socket port %q", value)
+	}
+
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		binary.BigEndian.PutUint32(ip[word:], binary.LittleEndian.Uint32(raw[word:]))
+	}
+	return ip, int(port), nil
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+)
+
+// ignoredServices returns the IPVS services of global.ignore_services.
+// Entries were validated with the config, so none fails to parse.
+func ignoredServices(cfg *config.Config) []lvs.ServiceKey {
+	keys := make([]lvs.ServiceKey, 0, len(cfg.Global.IgnoreServices))
+	for _, service := range cfg.Global.IgnoreServices {
+		if key, err := lvs.ParseServiceKey(service); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
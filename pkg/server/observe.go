@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// defaultObserveDriftInterval is how often observe-only mode compares the
+// config with the kernel, in addition to every config or health change,
+// unless global.drift_check_interval is set.
+const defaultObserveDriftInterval = 30 * time.Second
+
+// SetObserveOnly enables observe-only mode, in which the daemon never changes
+// IPVS, iptables or tunnel devices and only reports drift between the config
+// and the kernel. It must be called before Run or RunOnce.
+func (s *Server) SetObserveOnly(enabled bool) {
+	s.observeOnly = enabled
+	metrics.SetObserveOnly(enabled)
+}
+
+// SetReadOnly enables read-only mode, in which every IPVS change is refused
+// with lvs.ErrReadOnly and iptables rules and tunnel devices are left alone,
+// while health checks, metrics and the admin API keep running.
+// global.read_only enables it regardless. It must be called before Run.
+func (s *Server) SetReadOnly(enabled bool) {
+	s.configReadOnly = s.configMgr.GetConfig().Global.IsReadOnly()
+	s.readOnly = enabled || s.configReadOnly
+	s.lvsMgr.SetReadOnly(s.readOnly)
+}
+
+// changesKernel reports whether the server may change IPVS, iptables or
+// tunnel devices, i.e. it is neither in observe-only nor read-only mode.
+func (s *Server) changesKernel() bool {
+	return !s.observeOnly && !s.readOnly
+}
+
+// SetPrune forces prune mode, in which every IPVS service that is not in the
+// config is deleted, as if global.prune were set.
+func (s *Server) SetPrune(enabled bool) {
+	s.prune = enabled
+}
+
+// apply reconciles the kernel with the given services and records the
+// changes under reason or, in observe-only mode, reports what a reconcile
+// would change.
+func (s *Server) apply(reason string, services []config.ServiceConfig) error {
+	if s.observeOnly {
+		s.reportDrift(services)
+		return nil
+	}
+	s.reconciler.SetPrune(s.prune || s.configMgr.GetConfig().Global.IsPrune())
+	err := s.reconciler.Reconcile(services)
+	s.recordChanges(reason)
+	s.saveState()
+	s.reportEmptyServices()
+	return err
+}
+
+// applyServices is apply limited to the named services, whose backends
+// changed health (see lvs.Reconciler.ReconcileServices).
+func (s *Server) applyServices(reason string, services []config.ServiceConfig, names []string) error {
+	if s.observeOnly {
+		s.reportDrift(services)
+		return nil
+	}
+	err := s.reconciler.ReconcileServices(services, names)
+	s.recordChanges(reason)
+	s.saveState()
+	s.reportEmptyServices()
+	return err
+}
+
+// driftCheckInterval returns how often Run checks for drift:
+// global.drift_check_interval, or defaultObserveDriftInterval in observe-only
+// mode. Zero disables the check.
+func (s *Server) driftCheckInterval(cfg *config.Config) time.Duration {
+	if interval := cfg.Global.GetDriftCheckInterval(); interval > 0 || !s.observeOnly {
+		return interval
+	}
+	return defaultObserveDriftInterval
+}
+
+// checkDrift reports the drift between the current config and the kernel.
+// It is skipped while a reconcile is deferred by min_reconcile_interval, as
+// the kernel then legitimately lags behind the health state.
+func (s *Server) checkDrift() {
+	s.triggerMu.Lock()
+	pending := s.pendingReconcile != nil
+	s.triggerMu.Unlock()
+	if pending && !s.observeOnly {
+		return
+	}
+	s.lvsMgr.InvalidateCache()
+	s.reportDrift(s.resolvedConfig().Services)
+}
+
+// reportDrift computes the drift for the given services, exports it as
+// metrics and logs the items whenever the set of differences changes. Items
+// not seen by the previous check are counted in ezlb_drift_detected_total.
+// Outside observe-only mode, which typically runs next to the tool it is
+// meant to replace, IPVS services that are not configured count as drift.
+func (s *Server) reportDrift(services []config.ServiceConfig) {
+	drifts, err := s.reconciler.Drift(services)
+	if err != nil {
+		s.logger.Error("drift check failed", zap.Error(err))
+		return
+	}
+	if !s.observeOnly {
+		unconfigured, err := s.reconciler.UnconfiguredServices(services)
+		if err != nil {
+			s.logger.Error("drift check failed", zap.Error(err))
+			return
+		}
+		drifts = append(drifts, unconfigured...)
+	}
+	s.publishDrift(drifts)
+}
+
+// publishDrift updates the drift metrics and, if the set of differences
+// changed since the last check, logs them and records a drift event.
+func (s *Server) publishDrift(drifts []lvs.Drift) {
+	counts := make(map[[2]string]int)
+	current := make(map[string]bool, len(drifts))
+	for _, drift := range drifts {
+		counts[[2]string{drift.Service, drift.Kind}]++
+		current[drift.String()] = true
+	}
+	metrics.SetDriftItems(counts)
+
+	s.driftMu.Lock()
+	changed := len(current) != len(s.lastDrift)
+	for _, drift := range drifts {
+		if !s.lastDrift[drift.String()] {
+			changed = true
+			metrics.IncDriftDetected(drift.Service, drift.Kind)
+		}
+	}
+	s.lastDrift = current
+	s.driftMu.Unlock()
+
+	if !changed {
+		return
+	}
+	if len(drifts) == 0 {
+		s.logger.Info("no drift between config and IPVS state")
+		s.events.record("drift", "no drift between config and IPVS state")
+		return
+	}
+	for _, drift := range drifts {
+		s.logger.Warn("drift detected",
+			zap.String("service", drift.Service),
+			zap.String("kind", drift.Kind),
+			zap.String("target", drift.Target),
+			zap.String("detail", drift.Detail),
+		)
+	}
+	s.events.record("drift", "%d differences between config and IPVS state", len(drifts))
+}
+
+// Plan returns the IPVS changes a single reconcile pass would apply, like
+// RunOnce but without taking the lock or touching the kernel. Health checks
+// do not run, so every backend counts as healthy. Plan closes the IPVS
+// handle, so the Server cannot be used after.
+func (s *Server) Plan() (*lvs.Plan, error) {
+	defer s.lvsMgr.Close()
+
+	cfg := s.configMgr.GetConfig()
+	if s.resolver != nil {
+		s.resolver.Update(context.Background(), cfg.Services)
+		cfg = s.expandConfig(cfg)
+	}
+	s.reconciler.SetPrune(s.prune || cfg.Global.IsPrune())
+	return s.reconciler.Plan(cfg.Services)
+}
+
+// Diff reports what a single reconcile pass would change, like RunOnce but
+// without touching the kernel. Besides the drift of the configured services
+// it lists the IPVS services that are not configured, which a daemon deletes
+// if it created them. Health checks do not run, so every backend counts as
+// healthy. Diff closes the IPVS handle, so the Server cannot be used after.
+func (s *Server) Diff() ([]lvs.Drift, error) {
+	defer s.lvsMgr.Close()
+
+	cfg := s.configMgr.GetConfig()
+	if s.resolver != nil {
+		s.resolver.Update(context.Background(), cfg.Services)
+		cfg = s.expandConfig(cfg)
+	}
+
+	drifts, err := s.reconciler.Drift(cfg.Services)
+	if err != nil {
+		return nil, err
+	}
+	unconfigured, err := s.reconciler.UnconfiguredServices(cfg.Services)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifts, unconfigured...), nil
+}
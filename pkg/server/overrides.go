@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+)
+
+// weightOverrideAdapter implements admin.WeightOverrideHandler on top of the
+// Reconciler's runtime weight overrides.
+type weightOverrideAdapter struct {
+	server *Server
+}
+
+// SetWeightOverride validates the target backend against the current config,
+// and the weight against the service's max_weight, which also caps the
+// configured weights, registers the override and reconciles immediately. When ttl is positive a
+// reconcile is scheduled at expiry so the configured weight is restored.
+func (a *weightOverrideAdapter) SetWeightOverride(service, backend string, weight int, ttl time.Duration) error {
+	svcCfg, _, ok := findBackend(a.server.resolvedConfig(), service, backend)
+	if !ok {
+		return fmt.Errorf("backend %q in service %q: %w", backend, service, admin.ErrNotFound)
+	}
+	if maxWeight := svcCfg.GetMaxWeight(); weight > maxWeight {
+		return fmt.Errorf("weight %d exceeds max_weight %d of service %q", weight, maxWeight, service)
+	}
+
+	override := lvs.WeightOverride{
+		Service: service,
+		Backend: backend,
+		Weight:  weight,
+	}
+	if ttl > 0 {
+		override.ExpiresAt = time.Now().Add(ttl)
+	}
+	a.server.reconciler.SetWeightOverride(override)
+	a.server.scheduleOverrideExpiry(service, backend, ttl)
+	a.server.events.record("override", "weight of backend %s in service %s overridden to %d", backend, service, weight)
+	a.server.triggerReconcile()
+	return nil
+}
+
+// ResetWeightOverride removes the override and reconciles back to the configured weight.
+func (a *weightOverrideAdapter) ResetWeightOverride(service, backend string) error {
+	if !a.server.reconciler.ResetWeightOverride(service, backend) {
+		return fmt.Errorf("weight override for backend %q in service %q: %w", backend, service, admin.ErrNotFound)
+	}
+	a.server.scheduleOverrideExpiry(service, backend, 0)
+	a.server.events.record("override", "weight override of backend %s in service %s reset", backend, service)
+	a.server.triggerReconcile()
+	return nil
+}
+
+// WeightOverrides reports all active overrides alongside their configured weights.
+func (a *weightOverrideAdapter) WeightOverrides() []admin.WeightOverride {
+	cfg := a.server.resolvedConfig()
+	overrides := a.server.reconciler.WeightOverrides()
+
+	result := make([]admin.WeightOverride, 0, len(overrides))
+	for _, override := range overrides {
+		entry := admin.WeightOverride{
+			Service: override.Service,
+			Backend: override.Backend,
+			Status:  "overridden",
+			Weight:  override.Weight,
+		}
+		if _, backendCfg, ok := findBackend(cfg, override.Service, override.Backend); ok {
+			entry.ConfiguredWeight = backendCfg.GetWeight()
+		}
+		if !override.ExpiresAt.IsZero() {
+			expiresAt := override.ExpiresAt
+			entry.ExpiresAt = &expiresAt
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// scheduleOverrideExpiry (re)arms the timer that reconciles when an override expires.
+// A non-positive ttl only cancels any pending timer for the backend.
+func (s *Server) scheduleOverrideExpiry(service, backend string, ttl time.Duration) {
+	s.overrideMu.Lock()
+	defer s.overrideMu.Unlock()
+
+	key := service + "/" + backend
+	if timer, exists := s.overrideTimers[key]; exists {
+		timer.Stop()
+		delete(s.overrideTimers, key)
+	}
+	if ttl <= 0 {
+		return
+	}
+	s.overrideTimers[key] = time.AfterFunc(ttl, func() {
+		defer s.recoverPanic()
+		s.overrideMu.Lock()
+		delete(s.overrideTimers, key)
+		s.overrideMu.Unlock()
+		s.triggerReconcile()
+	})
+}
+
+// stopOverrideTimers cancels all pending override expiry timers.
+func (s *Server) stopOverrideTimers() {
+	s.overrideMu.Lock()
+	defer s.overrideMu.Unlock()
+
+	for key, timer := range s.overrideTimers {
+		timer.Stop()
+		delete(s.overrideTimers, key)
+	}
+}
+
+// findBackend looks up a backend and its service by service name and address
+// in the given config.
+func findBackend(cfg *config.Config, service, backend string) (config.ServiceConfig, config.BackendConfig, bool) {
+	if cfg == nil {
+		return config.ServiceConfig{}, config.BackendConfig{}, false
+	}
+	for _, svc := range cfg.Services {
+		if svc.Name != service {
+			continue
+		}
+		for _, backendCfg := range svc.Backends {
+			if backendCfg.Address == backend {
+				return svc, backendCfg, true
+			}
+		}
+	}
+	return config.ServiceConfig{}, config.BackendConfig{}, false
+}
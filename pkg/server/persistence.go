@@ -0,0 +1,68 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+)
+
+// readPersistenceTemplates reads the kernel's persistence templates; tests
+// replace it.
+var readPersistenceTemplates = lvs.PersistenceTemplates
+
+// persistenceAdapter implements admin.PersistenceProvider with the kernel's
+// connection table.
+type persistenceAdapter struct {
+	server *Server
+}
+
+// PersistenceTemplates returns the persistence templates of the configured
+// services.
+func (a *persistenceAdapter) PersistenceTemplates() ([]admin.PersistenceTemplate, error) {
+	return a.server.PersistenceTemplates()
+}
+
+// PersistenceTemplates returns the persistence templates of the configured
+// services, sorted by service, client and backend. Templates of IPVS
+// services that are not configured are left out.
+func (s *Server) PersistenceTemplates() ([]admin.PersistenceTemplate, error) {
+	names := make(map[lvs.ServiceKey]string)
+	for _, svc := range config.ExpandListens(s.resolvedConfig().Services) {
+		if key, err := lvs.ServiceKeyFromConfig(svc); err == nil {
+			names[key] = svc.Name
+		}
+	}
+
+	templates, err := readPersistenceTemplates()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := []admin.PersistenceTemplate{}
+	for _, template := range templates {
+		name, configured := names[template.Service]
+		if !configured {
+			continue
+		}
+		result = append(result, admin.PersistenceTemplate{
+			ExpiresAt: now.Add(template.Expires),
+			Service:   name,
+			Client:    template.Client.String(),
+			Backend:   template.Destination.String(),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Client != b.Client {
+			return a.Client < b.Client
+		}
+		return a.Backend < b.Backend
+	})
+	return result, nil
+}
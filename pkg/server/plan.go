@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/easzlab/ezlb/pkg/admin"
+)
+
+// planAdapter implements admin.PlanProvider with the running reconciler.
+type planAdapter struct {
+	server *Server
+}
+
+// Plan returns the IPVS changes a reconcile of the current config would
+// apply, given the current backend health.
+func (a *planAdapter) Plan() (admin.ReconcilePlan, error) {
+	plan, err := a.server.reconciler.Plan(a.server.resolvedConfig().Services)
+	if err != nil {
+		return admin.ReconcilePlan{}, err
+	}
+
+	ops := plan.Operations()
+	result := admin.ReconcilePlan{Changes: make([]admin.PlannedChange, 0, len(ops))}
+	for _, op := range ops {
+		result.Changes = append(result.Changes, admin.PlannedChange{
+			Service:  op.Service,
+			Resource: op.Resource,
+			Action:   op.Action,
+			Target:   op.Target,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// policyRoute is an "ip rule" sending traffic to a backend through the
+// routing table of a service's output interface.
+type policyRoute struct {
+	backend string
+	table   uint32
+	ipv6    bool
+}
+
+// args returns the "ip" arguments that add or delete the rule.
+func (r policyRoute) args(action string) []string {
+	family := "-4"
+	if r.ipv6 {
+		family = "-6"
+	}
+	return []string{family, "rule", action, "to", r.backend, "lookup", strconv.FormatUint(uint64(r.table), 10)}
+}
+
+// desiredPolicyRoutes returns the rules of all services with a route_table.
+func desiredPolicyRoutes(cfg *config.Config) map[policyRoute]bool {
+	routes := make(map[policyRoute]bool)
+	for _, svc := range cfg.Services {
+		if svc.RouteTable == 0 {
+			continue
+		}
+		for _, backend := range svc.Backends {
+			host, _, err := net.SplitHostPort(backend.Address)
+			ip := net.ParseIP(host)
+			if err != nil || ip == nil {
+				continue
+			}
+			routes[policyRoute{backend: ip.String(), table: svc.RouteTable, ipv6: ip.To4() == nil}] = true
+		}
+	}
+	return routes
+}
+
+// syncPolicyRoutes adds the policy-routing rules of services with a
+// route_table and removes those it added for backends no longer configured.
+// A rule is deleted before it is added, so restarts do not duplicate it.
+func (s *Server) syncPolicyRoutes(cfg *config.Config) {
+	if !kernelParamCheckEnabled || s.logger == nil || cfg == nil || !s.changesKernel() {
+		return
+	}
+
+	desired := desiredPolicyRoutes(cfg)
+	for _, route := range sortedPolicyRoutes(s.policyRoutes) {
+		if desired[route] {
+			continue
+		}
+		if err := runSetupCommand("ip", route.args("del")...); err != nil {
+			s.logger.Error("failed to delete policy routing rule",
+				zap.String("backend", route.backend), zap.Uint32("table", route.table), zap.Error(err))
+			continue
+		}
+		delete(s.policyRoutes, route)
+	}
+
+	for _, route := range sortedPolicyRoutes(desired) {
+		if s.policyRoutes[route] {
+			continue
+		}
+		// The rule may be left over from a previous run
+		_ = runSetupCommand("ip", route.args("del")...)
+		if err := runSetupCommand("ip", route.args("add")...); err != nil {
+			s.logger.Error("failed to add policy routing rule",
+				zap.String("backend", route.backend), zap.Uint32("table", route.table), zap.Error(err))
+			continue
+		}
+		s.policyRoutes[route] = true
+		s.logger.Info("added policy routing rule",
+			zap.String("backend", route.backend), zap.Uint32("table", route.table))
+	}
+}
+
+// cleanupPolicyRoutes removes every policy-routing rule ezlb added.
+func (s *Server) cleanupPolicyRoutes() {
+	for _, route := range sortedPolicyRoutes(s.policyRoutes) {
+		if err := runSetupCommand("ip", route.args("del")...); err != nil {
+			s.logger.Error("failed to delete policy routing rule",
+				zap.String("backend", route.backend), zap.Uint32("table", route.table), zap.Error(err))
+			continue
+		}
+		delete(s.policyRoutes, route)
+	}
+}
+
+// sortedPolicyRoutes returns the routes of a set in a stable order.
+func sortedPolicyRoutes(set map[policyRoute]bool) []policyRoute {
+	routes := make([]policyRoute, 0, len(set))
+	for route := range set {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].table != routes[j].table {
+			return routes[i].table < routes[j].table
+		}
+		return routes[i].backend < routes[j].backend
+	})
+	return routes
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// errorSummaryInterval is how often a reconcile error that keeps recurring
+// is logged again, with the number of occurrences not logged since.
+const errorSummaryInterval = 5 * time.Minute
+
+// reconcileFailures tracks the streak of failed daemon reconciles since the
+// last successful one.
+type reconcileFailures struct {
+	mu sync.Mutex
+	// since is when the streak started, zero while reconciles succeed.
+	since    time.Time
+	failures int
+	// lastErr is the last error logged; suppressed counts its repetitions
+	// since lastLogged.
+	lastErr    string
+	lastLogged time.Time
+	suppressed int
+}
+
+// failingSince returns when reconciles started failing and the last error,
+// or a zero time if the last reconcile succeeded.
+func (f *reconcileFailures) failingSince() (time.Time, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.since, f.lastErr
+}
+
+// reportReconcile logs the result of a daemon reconcile started by trigger,
+// e.g. "periodic resync". The first failure, and a failure with another
+// error than the last one logged, is logged as an error and recorded as an
+// event. While the same error repeats, e.g. after netlink permissions were
+// lost, it is only logged every errorSummaryInterval with the number of
+// repetitions, so an extended incident does not fill the disk with identical
+// logs. The first success after failures is logged once.
+func (s *Server) reportReconcile(trigger string, err error) {
+	f := &s.failures
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+
+	if err == nil {
+		if f.since.IsZero() {
+			return
+		}
+		s.logger.Info("reconcile recovered", zap.String("trigger", trigger),
+			zap.Int("failures", f.failures), zap.Duration("failing_for", now.Sub(f.since).Round(time.Second)))
+		s.events.record("reconcile", "reconcile recovered after %d failures in %s", f.failures, now.Sub(f.since).Round(time.Second))
+		f.since, f.failures, f.lastErr, f.lastLogged, f.suppressed = time.Time{}, 0, "", time.Time{}, 0
+		metrics.SetReconcileFailingSince(time.Time{})
+		return
+	}
+
+	if f.since.IsZero() {
+		f.since = now
+		metrics.SetReconcileFailingSince(now)
+	}
+	f.failures++
+	if msg := err.Error(); msg != f.lastErr {
+		s.logger.Error(trigger+" failed", zap.Error(err))
+		s.events.record("reconcile", "%s failed: %v", trigger, err)
+		f.lastErr, f.lastLogged, f.suppressed = msg, now, 0
+		return
+	}
+
+	f.suppressed++
+	metrics.IncReconcileErrorsSuppressed()
+	if now.Sub(f.lastLogged) < errorSummaryInterval {
+		return
+	}
+	s.logger.Error("reconcile still failing", zap.String("trigger", trigger), zap.Error(err),
+		zap.Int("repeated", f.suppressed), zap.Time("failing_since", f.since))
+	s.events.record("reconcile", "reconcile still failing since %s, %d repeated errors: %v",
+		f.since.Format(time.RFC3339), f.suppressed, err)
+	f.lastLogged, f.suppressed = now, 0
+}
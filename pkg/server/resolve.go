@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// resolvedConfig returns the current config with hostname backends replaced
+// by the IPs they currently resolve to. Everything that programs IPVS, runs
+// health checks or reports on backends works on this view.
+func (s *Server) resolvedConfig() *config.Config {
+	return s.expandConfig(s.configMgr.GetConfig())
+}
+
+// expandConfig returns a copy of cfg with its hostname backends expanded.
+func (s *Server) expandConfig(cfg *config.Config) *config.Config {
+	if cfg == nil || s.resolver == nil {
+		return cfg
+	}
+	expanded := *cfg
+	expanded.Services = s.resolver.Expand(cfg.Services)
+	return &expanded
+}
+
+// resolveConfig resolves hostnames newly used by cfg's backends, (re)starts
+// periodic re-resolution at global.dns.interval and returns the expanded config.
+func (s *Server) resolveConfig(ctx context.Context, cfg *config.Config) *config.Config {
+	if s.resolver == nil {
+		return cfg
+	}
+	s.resolver.Update(ctx, cfg.Services)
+	s.resolver.Start(cfg.Global.DNS.GetInterval())
+	return s.expandConfig(cfg)
+}
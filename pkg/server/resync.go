@@ -0,0 +1,21 @@
+package server
+
+import "go.uber.org/zap"
+
+// resync reconciles with the current config although nothing changed, so
+// rules edited with ipvsadm or flushed by other tools are repaired.
+func (s *Server) resync() {
+	s.lvsMgr.InvalidateCache()
+	err := s.apply(reasonResync, s.resolvedConfig().Services)
+	s.reportReconcile("periodic resync", err)
+	if err != nil {
+		return
+	}
+	if s.observeOnly {
+		return
+	}
+	if ops := s.reconciler.LastOperations(); len(ops) > 0 {
+		s.logger.Warn("periodic resync repaired IPVS state changed outside ezlb", zap.Int("operations", len(ops)))
+		s.events.record("resync", "periodic resync repaired %d IPVS changes made outside ezlb", len(ops))
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"go.uber.org/zap"
+)
+
+// checkScheduler probes the kernel for an IPVS scheduler; tests replace it.
+var checkScheduler = lvs.CheckScheduler
+
+// logSchedulerPreflight reports configured schedulers whose kernel module is
+// unavailable, before the reconcile that would fail with a bare ENOENT.
+func (s *Server) logSchedulerPreflight(cfg *config.Config) {
+	if !kernelParamCheckEnabled || s.logger == nil || cfg == nil {
+		return
+	}
+
+	services := make(map[string][]string)
+	for _, svc := range cfg.Services {
+		services[svc.Scheduler] = append(services[svc.Scheduler], svc.Name)
+	}
+	schedulers := make([]string, 0, len(services))
+	for scheduler := range services {
+		schedulers = append(schedulers, scheduler)
+	}
+	sort.Strings(schedulers)
+
+	for _, scheduler := range schedulers {
+		if err := checkScheduler(scheduler); err != nil {
+			s.logger.Error("IPVS scheduler preflight failed",
+				zap.String("scheduler", scheduler),
+				zap.Strings("services", services[scheduler]),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -2,86 +2,242 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/easzlab/ezlb/pkg/admin"
 	"github.com/easzlab/ezlb/pkg/config"
-	"github.com/easzlab/ezlb/pkg/healthcheck"
+	"github.com/easzlab/ezlb/pkg/featuregate"
+	"github.com/easzlab/ezlb/pkg/lock"
 	"github.com/easzlab/ezlb/pkg/lvs"
 	"github.com/easzlab/ezlb/pkg/metrics"
+	"github.com/easzlab/ezlb/pkg/prestop"
+	"github.com/easzlab/ezlb/pkg/resolver"
+	"github.com/easzlab/ezlb/pkg/selfmon"
 	"github.com/easzlab/ezlb/pkg/snat"
+	"github.com/easzlab/ezlb/pkg/synthetic"
+	"github.com/easzlab/ezlb/pkg/syslogsink"
 	"github.com/easzlab/ezlb/pkg/trafficlog"
 	"go.uber.org/zap"
 )
 
+// ErrPanic is returned by Run when the main loop, or a health change callback
+// or timer it started, crashed and was recovered. Callers can use errors.Is
+// to exit with a distinct status code.
+var ErrPanic = errors.New("server panicked")
+
+// ErrConfig is returned by NewServer when the config file cannot be loaded
+// or fails validation.
+var ErrConfig = errors.New("invalid configuration")
+
+// ErrLockNotHeld is returned by RunOnce when global.lock is configured and
+// another node held the lock for the whole wait, so nothing was applied.
+var ErrLockNotHeld = errors.New("distributed lock held by another node")
+
+// ErrDrift is returned by RunOnce in observe-only mode when IPVS differs from
+// the desired state.
+var ErrDrift = errors.New("drift between config and IPVS state")
+
 // Server coordinates all modules and manages the overall service lifecycle.
 type Server struct {
 	configMgr     *config.Manager
 	lvsMgr        *lvs.Manager
 	reconciler    *lvs.Reconciler
-	healthMgr     *healthcheck.Manager
+	healthMgr     HealthProvider
 	snatMgr       snat.Manager
 	adminServer   *admin.Server
 	logger        *zap.Logger
 	trafficLogger *zap.Logger
 	collector     *trafficlog.Collector
+	collectorMu   sync.RWMutex
+	selfMonitor   *selfmon.Monitor
+	synthetic     *synthetic.Prober
+	// panics hands a panic recovered outside the main loop to Run.
+	panics chan error
+	// events records notable changes shown on the admin dashboard.
+	events eventLog
+	// changes records the IPVS changes applied by reconciles and why.
+	changes changeLog
+	// failures rate-limits the logs of repeated reconcile failures.
+	failures reconcileFailures
+	// lastHealth and lastDegraded are the previously observed backend health
+	// and service degradation, used to record state transitions.
+	lastHealth   map[string]bool
+	lastDegraded map[string]bool
+	syslogSink   *syslogsink.Sink
+	healthMu     sync.Mutex
+	// observeOnly reports drift instead of reconciling; lastDrift holds the
+	// previously reported differences so unchanged drift is not logged again.
+	observeOnly bool
+	lastDrift   map[string]bool
+	driftMu     sync.Mutex
+	// lastEmpty holds the services left without destinations by the
+	// previous reconcile, so each one is reported once.
+	lastEmpty map[string]bool
+	emptyMu   sync.Mutex
+	// prune is set by --prune; global.prune enables it as well.
+	prune bool
+	// readOnly refuses every IPVS change and leaves iptables rules and
+	// tunnel devices untouched; set by --read-only or global.read_only.
+	// configReadOnly is global.read_only at startup.
+	readOnly       bool
+	configReadOnly bool
+	// overrideTimers holds pending reconciles for expiring weight overrides.
+	overrideTimers map[string]*time.Timer
+	overrideMu     sync.Mutex
+	// features are the feature gates resolved from featureSettings at startup.
+	features        *featuregate.Gates
+	featureSettings map[string]bool
+	// instance is global.instance at startup, which names the SNAT chains.
+	instance string
+	// stateFile is global.state_file at startup, which records the managed
+	// IPVS services across processes.
+	stateFile string
+	// lastTriggered is when triggerReconcile last applied; pendingReconcile
+	// is the batched reconcile scheduled by global.min_reconcile_interval.
+	lastTriggered    time.Time
+	pendingReconcile *time.Timer
+	triggerMu        sync.Mutex
+	// backendStates indexes, by backend address and service, the health
+	// each service last saw, so a health change reconciles only the
+	// services it affects.
+	backendStates   map[string]map[string]string
+	backendStatesMu sync.Mutex
+	// policyRoutes are the "ip rule" entries added for route_table services.
+	policyRoutes map[policyRoute]bool
+	// resolver resolves backends addressed by hostname.
+	resolver *resolver.Resolver
 }
 
+// newSNATManager creates the SNAT manager of a Server; tests replace it.
+var newSNATManager = snat.NewManagerForInstance
+
 // NewServer initializes all modules and returns a ready-to-run Server.
 func NewServer(configPath string, logger *zap.Logger, trafficLogger *zap.Logger) (*Server, error) {
+	return NewServerWithHealthProvider(configPath, logger, trafficLogger, NewBuiltinHealthProvider)
+}
+
+// NewServerWithHealthProvider is like NewServer but takes backend health from
+// the provider created by newHealthProvider instead of the built-in checker.
+func NewServerWithHealthProvider(configPath string, logger *zap.Logger, trafficLogger *zap.Logger, newHealthProvider HealthProviderFactory) (*Server, error) {
 	// Initialize IPVS manager
 	lvsMgr, err := lvs.NewManager(logger.Named("lvs"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize IPVS manager: %w", err)
 	}
 
-	return newServerWithManager(configPath, lvsMgr, logger, trafficLogger)
+	return newServerWithHealthProvider(configPath, lvsMgr, logger, trafficLogger, newHealthProvider)
 }
 
 // newServerWithManager initializes a Server with a pre-created LVS Manager.
 // This allows tests to inject a platform-appropriate Manager instance.
 func newServerWithManager(configPath string, lvsMgr *lvs.Manager, logger *zap.Logger, trafficLogger *zap.Logger) (*Server, error) {
+	return newServerWithHealthProvider(configPath, lvsMgr, logger, trafficLogger, NewBuiltinHealthProvider)
+}
+
+// newServerWithHealthProvider initializes a Server with a pre-created LVS
+// Manager and the given health provider.
+func newServerWithHealthProvider(configPath string, lvsMgr *lvs.Manager, logger *zap.Logger, trafficLogger *zap.Logger, newHealthProvider HealthProviderFactory) (*Server, error) {
 	// Initialize config manager
 	configMgr, err := config.NewManager(configPath, logger.Named("config"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize config manager: %w", err)
+		return nil, fmt.Errorf("failed to initialize config manager: %w: %w", ErrConfig, err)
+	}
+
+	// Tag every log line of a named instance, so the logs of instances
+	// sharing a host can be told apart
+	instance := configMgr.GetConfig().Global.Instance
+	if instance != "" {
+		logger = logger.With(zap.String("instance", instance))
+	}
+
+	featureSettings := maps.Clone(configMgr.GetConfig().Global.FeatureGates)
+	features, err := featuregate.New(featureSettings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: global.feature_gates: %w", ErrConfig, err)
 	}
 
 	// Initialize SNAT manager
-	snatMgr, err := snat.NewManager(logger.Named("snat"))
+	snatMgr, err := newSNATManager(instance, logger.Named("snat"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize SNAT manager: %w", err)
 	}
 
 	server := &Server{
-		configMgr:     configMgr,
-		lvsMgr:        lvsMgr,
-		snatMgr:       snatMgr,
-		logger:        logger,
-		trafficLogger: trafficLogger,
+		configMgr:       configMgr,
+		lvsMgr:          lvsMgr,
+		snatMgr:         snatMgr,
+		logger:          logger,
+		trafficLogger:   trafficLogger,
+		overrideTimers:  make(map[string]*time.Timer),
+		panics:          make(chan error, 1),
+		lastHealth:      make(map[string]bool),
+		lastDegraded:    make(map[string]bool),
+		policyRoutes:    make(map[policyRoute]bool),
+		resolver:        resolver.NewResolver(nil, logger.Named("resolver")),
+		synthetic:       synthetic.NewProber(logger.Named("synthetic")),
+		features:        features,
+		featureSettings: featureSettings,
+		instance:        instance,
+		stateFile:       configMgr.GetConfig().Global.GetStateFile(),
 	}
 
-	// Initialize health check manager with onChange callback that triggers reconcile
-	server.healthMgr = healthcheck.NewManager(func() {
-		server.triggerReconcile()
+	// Initialize health provider with onChange callback that triggers reconcile
+	server.healthMgr = newHealthProvider(func() {
+		defer server.recoverPanic()
+		server.reconcileHealthChange()
 		server.updateHealthMetrics()
 	}, logger.Named("healthcheck"))
 
 	// Initialize reconciler with health checker and SNAT manager
 	server.reconciler = lvs.NewReconciler(lvsMgr, server.healthMgr, snatMgr, logger.Named("reconciler"))
+	server.loadState()
+	server.SetReadOnly(false)
+
+	// Call pre-stop hooks before deleting backends removed from the config,
+	// and reconcile again once a hook returns to delete the backend
+	server.reconciler.SetPreStopHook(func(ctx context.Context, svcCfg config.ServiceConfig, backend string) error {
+		return prestop.Run(ctx, svcCfg.PreStop, svcCfg.Name, backend)
+	}, server.triggerReconcile)
+	server.reconciler.SetConntrackFlusher(flushConntrack)
+	lvsMgr.SetCacheTTL(configMgr.GetConfig().Global.GetIPVSCacheTTL())
+	server.reconciler.SetIgnoredServices(ignoredServices(configMgr.GetConfig()))
+	server.reconciler.SetLocalBackendDetection(configMgr.GetConfig().Global.IsDetectLocalBackends())
 
 	return server, nil
 }
 
 // Run starts the server in daemon mode: performs initial reconcile, starts health checks
 // and config watching, then enters the main event loop until context is cancelled.
-func (s *Server) Run(ctx context.Context) error {
-	cfg := s.configMgr.GetConfig()
+func (s *Server) Run(ctx context.Context) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = s.handlePanic(recovered)
+		}
+	}()
+
+	cfg := s.resolveConfig(ctx, s.configMgr.GetConfig())
+	s.logFeatureGates()
 	s.logKernelParamPreflight()
+	s.logSchedulerPreflight(cfg)
+	s.logHostListenerCollisions(cfg)
+	s.logSNATPreflight(cfg)
+	if s.observeOnly {
+		s.logger.Warn("observe-only mode: IPVS and iptables rules will not be changed")
+	} else if s.readOnly {
+		s.logger.Warn("read-only mode: IPVS changes will be refused, iptables rules and tunnel devices left untouched")
+	} else {
+		s.ensureTunnelSetup(cfg)
+		s.syncPolicyRoutes(cfg)
+	}
 
 	// Initialize admin server if configured
-	if cfg.Global.AdminAddress != "" {
+	if cfg.Global.AdminAddress != "" || cfg.Global.MetricsAddress != "" {
 		s.initAdminServer(cfg)
 	}
 
@@ -90,32 +246,108 @@ func (s *Server) Run(ctx context.Context) error {
 		metrics.IncConfigReload()
 	})
 
-	// Register health check targets and start checking
-	s.healthMgr.UpdateTargets(ctx, cfg.Services)
+	// Register health check targets of enabled services and start checking
+	s.healthMgr.UpdateTargets(ctx, config.EnabledServices(cfg.Services))
 
 	// Perform initial reconcile
-	if err := s.reconciler.Reconcile(cfg.Services); err != nil {
-		s.logger.Error("initial reconcile failed", zap.Error(err))
-	}
+	s.reportReconcile("initial reconcile", s.apply(reasonStartup, cfg.Services))
 
 	s.syncTrafficCollector(cfg)
+	s.syncSelfMonitor(cfg)
+	s.syncSyslogSink(cfg)
+	s.synthetic.Update(synthetic.Targets(cfg.Services))
 
 	// Start config file watching
 	s.configMgr.WatchConfig()
 	s.logger.Info("config watcher started")
 
+	// Periodically re-check drift so changes made by other tools are noticed
+	// without a config or health change.
+	var driftTicker intervalTicker
+	driftTicker.reset(s.driftCheckInterval(cfg))
+	defer driftTicker.stop()
+
+	// Watch destinations draining with weight 0 until their connections finish
+	drainTicker := time.NewTicker(cfg.Global.Drain.GetInterval())
+	defer drainTicker.Stop()
+
+	// Snapshot the config and the kernel state for audits
+	s.takeSnapshot(time.Now())
+	snapshotTicker := time.NewTicker(cfg.Global.Snapshot.GetInterval())
+	defer snapshotTicker.Stop()
+
+	// Periodically reconcile to repair rules changed outside ezlb
+	var resyncTicker intervalTicker
+	resyncTicker.reset(cfg.Global.GetResyncInterval())
+	defer resyncTicker.stop()
+
 	// Main event loop
 	s.logger.Info("server started, entering main loop")
 	for {
 		select {
-		case <-s.configMgr.OnChange():
-			s.logger.Info("config change detected, triggering reconcile")
-			newCfg := s.configMgr.GetConfig()
-			s.healthMgr.UpdateTargets(ctx, newCfg.Services)
-			if err := s.reconciler.Reconcile(newCfg.Services); err != nil {
-				s.logger.Error("reconcile after config change failed", zap.Error(err))
+		case <-driftTicker.C:
+			s.checkDrift()
+
+		case <-drainTicker.C:
+			s.checkDrains()
+
+		case now := <-snapshotTicker.C:
+			s.takeSnapshot(now)
+
+		case <-resyncTicker.C:
+			s.resync()
+
+		case change := <-s.configMgr.OnChange():
+			s.logger.Info("config change detected, triggering reconcile", zap.Uint64("generation", change.Generation))
+			for _, diff := range change.Diffs {
+				s.logger.Info("config changed", zap.String("change", diff.String()))
 			}
+			newCfg := s.resolveConfig(ctx, s.configMgr.GetConfig())
+			s.events.record("config", "configuration reloaded (%d services)", len(newCfg.Services))
+			s.warnFeatureGateChange(newCfg)
+			if newCfg.Global.Instance != s.instance {
+				s.logger.Warn("global.instance changed, restart ezlb to apply", zap.String("instance", s.instance))
+			}
+			if newCfg.Global.GetStateFile() != s.stateFile {
+				s.logger.Warn("global.state_file changed, restart ezlb to apply", zap.String("state_file", s.stateFile))
+			}
+			s.logSchedulerPreflight(newCfg)
+			s.logHostListenerCollisions(newCfg)
+			s.logSNATPreflight(newCfg)
+			if newCfg.Global.IsReadOnly() != s.configReadOnly {
+				s.logger.Warn("global.read_only changed, restart ezlb to apply", zap.Bool("read_only", s.readOnly))
+			}
+			if s.changesKernel() {
+				s.ensureTunnelSetup(newCfg)
+				s.syncPolicyRoutes(newCfg)
+			}
+			s.lvsMgr.SetCacheTTL(newCfg.Global.GetIPVSCacheTTL())
+			s.reconciler.SetIgnoredServices(ignoredServices(newCfg))
+			s.reconciler.SetLocalBackendDetection(newCfg.Global.IsDetectLocalBackends())
+			s.healthMgr.UpdateTargets(ctx, config.EnabledServices(newCfg.Services))
+			s.reportReconcile("reconcile after config change", s.apply(reasonConfig, newCfg.Services))
 			s.syncTrafficCollector(newCfg)
+			s.syncSelfMonitor(newCfg)
+			s.syncSyslogSink(newCfg)
+			s.synthetic.Update(synthetic.Targets(newCfg.Services))
+			drainTicker.Reset(newCfg.Global.Drain.GetInterval())
+			snapshotTicker.Reset(newCfg.Global.Snapshot.GetInterval())
+			resyncTicker.reset(newCfg.Global.GetResyncInterval())
+			driftTicker.reset(s.driftCheckInterval(newCfg))
+
+		case <-s.resolver.OnChange():
+			s.logger.Info("backend hostnames resolved to new addresses, triggering reconcile")
+			resolvedCfg := s.resolvedConfig()
+			s.events.record("dns", "backend hostnames resolved to new addresses")
+			if s.changesKernel() {
+				s.syncPolicyRoutes(resolvedCfg)
+			}
+			s.healthMgr.UpdateTargets(ctx, config.EnabledServices(resolvedCfg.Services))
+			s.reportReconcile("reconcile after hostname re-resolution", s.apply(reasonDNS, resolvedCfg.Services))
+			s.syncTrafficCollector(resolvedCfg)
+
+		case err := <-s.panics:
+			return err
 
 		case <-ctx.Done():
 			s.logger.Info("shutdown signal received, stopping server")
@@ -128,12 +360,44 @@ func (s *Server) Run(ctx context.Context) error {
 // RunOnce performs a single reconcile pass and then exits.
 // IPVS rules and iptables rules are intentionally preserved after exit —
 // cleanup_on_exit does not apply to once mode, whose purpose is to apply
-// the desired state and leave it in place.
+// the desired state and leave it in place. In observe-only mode it compares
+// the desired state with the kernel instead and returns ErrDrift if they
+// differ.
 func (s *Server) RunOnce() error {
 	cfg := s.configMgr.GetConfig()
+	// Observe-only changes nothing, so it does not wait for the lock
+	if cfg.Global.Lock.IsEnabled() && !s.observeOnly {
+		release, err := s.acquireOnceLock(cfg.Global.ScopedLock())
+		if err != nil {
+			s.lvsMgr.Close()
+			return err
+		}
+		defer release()
+	}
+	if s.resolver != nil {
+		s.resolver.Update(context.Background(), cfg.Services)
+		cfg = s.expandConfig(cfg)
+	}
+	s.logFeatureGates()
 	s.logKernelParamPreflight()
+	s.logSchedulerPreflight(cfg)
+	s.logHostListenerCollisions(cfg)
+	s.logSNATPreflight(cfg)
+	if s.observeOnly {
+		return s.onceDrift(cfg.Services)
+	}
+	if s.changesKernel() {
+		s.ensureTunnelSetup(cfg)
+		s.syncPolicyRoutes(cfg)
+	}
 
+	// A single pass cannot wait for pre-stop hooks, so removed backends are
+	// deleted right away
+	s.reconciler.SetPreStopHook(nil, nil)
+	s.reconciler.SetPrune(s.prune || cfg.Global.IsPrune())
 	err := s.reconciler.Reconcile(cfg.Services)
+	s.saveState()
+	s.reportEmptyServices()
 	s.lvsMgr.Close()
 
 	if err != nil {
@@ -142,18 +406,128 @@ func (s *Server) RunOnce() error {
 	return nil
 }
 
-// triggerReconcile is called by the health check manager when a backend's health status changes.
+// onceDrift reports the drift of services like an observe-only daemon and
+// closes the IPVS handle.
+func (s *Server) onceDrift(services []config.ServiceConfig) error {
+	defer s.lvsMgr.Close()
+	drifts, err := s.reconciler.Drift(services)
+	if err != nil {
+		return fmt.Errorf("drift check failed: %w", err)
+	}
+	s.publishDrift(drifts)
+	if len(drifts) > 0 {
+		return fmt.Errorf("%w: %d differences found", ErrDrift, len(drifts))
+	}
+	return nil
+}
+
+// acquireOnceLock takes the once-mode distributed lock, waiting up to
+// lock.wait for another holder to finish. The returned func releases it.
+func (s *Server) acquireOnceLock(lockCfg config.LockConfig) (func(), error) {
+	hostname, _ := os.Hostname()
+	locker, err := lock.New(lockCfg, s.configMgr.GetConfig().Global.InstanceScoped(hostname))
+	if err != nil {
+		return nil, err
+	}
+	held, err := lock.Acquire(context.Background(), locker, lockCfg.GetWait())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire distributed lock: %w", err)
+	}
+	if !held {
+		// Drop the session or lease created for the attempt
+		if err := locker.Release(context.Background()); err != nil {
+			s.logger.Warn("failed to clean up distributed lock attempt", zap.Error(err))
+		}
+		return nil, ErrLockNotHeld
+	}
+	s.logger.Info("acquired distributed lock",
+		zap.String("backend", lockCfg.Backend),
+		zap.String("key", lockCfg.Key),
+	)
+	return func() {
+		if err := locker.Release(context.Background()); err != nil {
+			s.logger.Warn("failed to release distributed lock", zap.Error(err))
+		}
+	}, nil
+}
+
+// SetMaintenance enables or disables director-wide maintenance mode and
+// reconciles immediately so every destination is drained (or restored).
+func (s *Server) SetMaintenance(enabled bool) {
+	if s.reconciler.InMaintenance() == enabled {
+		return
+	}
+	s.reconciler.SetMaintenance(enabled)
+	metrics.SetMaintenanceMode(enabled)
+	if enabled {
+		s.logger.Warn("maintenance mode enabled, draining all services")
+		s.events.record("maintenance", "maintenance mode enabled")
+	} else {
+		s.logger.Info("maintenance mode disabled, restoring configured weights")
+		s.events.record("maintenance", "maintenance mode disabled")
+	}
+	s.triggerReconcile()
+}
+
+// InMaintenance reports whether director-wide maintenance mode is enabled.
+func (s *Server) InMaintenance() bool {
+	return s.reconciler.InMaintenance()
+}
+
+// triggerReconcile is called by runtime actions such as weight overrides and
+// by the health check manager when a backend's health status changes.
+// With global.min_reconcile_interval set, triggers arriving sooner than the
+// interval after the last reconcile are batched into a single reconcile at
+// the end of it, so health flap storms do not thrash the kernel.
 func (s *Server) triggerReconcile() {
-	cfg := s.configMgr.GetConfig()
-	if err := s.reconciler.Reconcile(cfg.Services); err != nil {
-		s.logger.Error("reconcile after health change failed", zap.Error(err))
+	s.triggerReconcileServices(reasonRuntime, nil)
+}
+
+// triggerReconcileServices is triggerReconcile for reason, limited to the
+// named services or covering all of them if names is nil. A reconcile
+// deferred by global.min_reconcile_interval always covers all services and
+// is recorded under the reason of the trigger that deferred it.
+func (s *Server) triggerReconcileServices(reason string, names []string) {
+	interval := s.configMgr.GetConfig().Global.GetMinReconcileInterval()
+	if interval > 0 {
+		s.triggerMu.Lock()
+		if s.pendingReconcile != nil {
+			s.triggerMu.Unlock()
+			metrics.IncReconcilesDeferred()
+			return
+		}
+		if wait := interval - time.Since(s.lastTriggered); wait > 0 {
+			s.pendingReconcile = time.AfterFunc(wait, func() {
+				defer s.recoverPanic()
+				s.triggerMu.Lock()
+				s.pendingReconcile = nil
+				s.triggerMu.Unlock()
+				s.triggerReconcileServices(reason, nil)
+			})
+			s.triggerMu.Unlock()
+			metrics.IncReconcilesDeferred()
+			s.logger.Debug("deferring reconcile", zap.Duration("wait", wait))
+			return
+		}
+		s.lastTriggered = time.Now()
+		s.triggerMu.Unlock()
 	}
+
+	cfg := s.resolvedConfig()
+	apply := s.apply
+	if names != nil {
+		apply = func(reason string, services []config.ServiceConfig) error {
+			return s.applyServices(reason, services, names)
+		}
+	}
+	s.reportReconcile("reconcile after "+reason, apply(reason, cfg.Services))
 }
 
 // updateHealthMetrics updates the health status metrics for all backends.
 func (s *Server) updateHealthMetrics() {
-	cfg := s.configMgr.GetConfig()
-	statuses := s.healthMgr.GetAllStatuses()
+	cfg := s.resolvedConfig()
+	statuses := s.healthMgr.Snapshot()
+	s.recordHealthTransitions(cfg, statuses)
 
 	// Build a map of backend address to service name
 	backendToService := make(map[string]string)
@@ -170,6 +544,7 @@ func (s *Server) updateHealthMetrics() {
 			serviceName = "unknown"
 		}
 		metrics.SetBackendHealth(serviceName, address, healthy)
+		metrics.SetBackendDegraded(serviceName, address, s.isDegraded(address))
 	}
 }
 
@@ -185,14 +560,17 @@ func (s *Server) syncTrafficCollector(cfg *config.Config) {
 
 		lvsStats := trafficlog.NewLVSStatsAdapter(s.lvsMgr)
 
-		s.collector = trafficlog.NewCollector(
+		collector := trafficlog.NewCollector(
 			lvsStats,
 			s.trafficLogger,
 			s.logger,
 			cfg.Services,
 			cfg.Global.Log.Traffic,
 		)
-		s.collector.Start()
+		collector.Start()
+		s.collectorMu.Lock()
+		s.collector = collector
+		s.collectorMu.Unlock()
 		s.logger.Info("traffic collector started",
 			zap.Duration("interval", cfg.Global.Log.Traffic.GetInterval()),
 		)
@@ -202,26 +580,123 @@ func (s *Server) syncTrafficCollector(cfg *config.Config) {
 	s.collector.UpdateConfig(cfg.Services, cfg.Global.Log.Traffic)
 }
 
+// syncSelfMonitor starts, stops or reconfigures the process self-monitor
+// according to global.self_monitor.
+func (s *Server) syncSelfMonitor(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+
+	monitorCfg := cfg.Global.SelfMonitor
+	if !monitorCfg.IsEnabled() {
+		if s.selfMonitor != nil {
+			s.selfMonitor.Stop()
+			s.selfMonitor = nil
+			s.logger.Info("self-monitor stopped")
+		}
+		return
+	}
+
+	if s.selfMonitor == nil {
+		s.selfMonitor = selfmon.NewMonitor(monitorCfg, s.logger.Named("selfmon"))
+		s.selfMonitor.Start()
+		s.logger.Info("self-monitor started", zap.Duration("interval", monitorCfg.GetInterval()))
+		return
+	}
+
+	s.selfMonitor.UpdateConfig(monitorCfg)
+}
+
 // initAdminServer initializes and starts the admin HTTP server.
 func (s *Server) initAdminServer(cfg *config.Config) {
 	adminCfg := admin.Config{
-		ListenAddr:     cfg.Global.AdminAddress,
+		ListenAddr:     cfg.Global.GetAdminAddress(),
+		MetricsAddr:    cfg.Global.MetricsAddress,
 		MetricsEnabled: cfg.Global.IsMetricsEnabled(),
 		MetricsPath:    cfg.Global.GetMetricsPath(),
+		TLS:            adminTLSConfig(cfg.Global.AdminTLS),
+		MetricsTLS:     adminTLSConfig(cfg.Global.MetricsTLS),
 	}
 
 	s.adminServer = admin.NewServer(adminCfg, s.logger.Named("admin"))
 
 	// Set up health check function for admin server
 	s.adminServer.SetHealthCheckFunc(func() map[string]bool {
-		return s.healthMgr.GetAllStatuses()
+		return s.healthMgr.Snapshot()
 	})
+	s.adminServer.SetWeightOverrideHandler(&weightOverrideAdapter{server: s})
+	s.adminServer.SetMaintenanceController(s)
+	s.adminServer.SetDashboardProvider(&dashboardAdapter{server: s})
+	s.adminServer.SetStatusProvider(&statusAdapter{server: s})
+	s.adminServer.SetPlanProvider(&planAdapter{server: s})
+	s.adminServer.SetChangeProvider(&changeAdapter{server: s})
+	s.adminServer.SetPersistenceProvider(&persistenceAdapter{server: s})
 
 	if err := s.adminServer.Start(); err != nil {
 		s.logger.Error("failed to start admin server", zap.Error(err))
 	}
 }
 
+// adminTLSConfig converts a listener's TLS settings for the admin server.
+func adminTLSConfig(t config.ListenerTLSConfig) admin.TLSConfig {
+	return admin.TLSConfig{CertFile: t.CertFile, KeyFile: t.KeyFile, ClientCAFile: t.ClientCAFile}
+}
+
+// handlePanic logs a recovered panic with its stack trace and, if
+// cleanup_on_panic allows it, makes a best-effort attempt to remove managed
+// IPVS services and SNAT rules before the process exits.
+func (s *Server) handlePanic(recovered any) error {
+	s.logger.Error("server panicked",
+		zap.Any("panic", recovered),
+		zap.ByteString("stack", debug.Stack()),
+	)
+
+	cfg := s.configMgr.GetConfig()
+	if !s.changesKernel() {
+		s.logger.Info("observe-only or read-only mode, leaving IPVS and iptables rules untouched")
+	} else if cfg.Global.IsCleanupOnPanic() {
+		s.tryCleanup("ipvs", s.reconciler.Cleanup)
+		s.tryCleanup("snat", s.snatMgr.Cleanup)
+	} else {
+		s.logger.Warn("cleanup_on_panic is false, preserving IPVS and iptables rules")
+	}
+
+	return fmt.Errorf("%w: %v", ErrPanic, recovered)
+}
+
+// recoverPanic recovers a panic in a goroutine outside the main loop, such as
+// the health change callback or a timer, handles it like a main loop panic
+// and hands the error to Run, which returns it. Must be deferred directly.
+func (s *Server) recoverPanic() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	err := s.handlePanic(recovered)
+	select {
+	case s.panics <- err:
+	default:
+	}
+}
+
+// tryCleanup runs a cleanup step after a crash, guarding against a second panic.
+func (s *Server) tryCleanup(name string, cleanup func() error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.logger.Error("cleanup after panic also panicked",
+				zap.String("step", name),
+				zap.Any("panic", recovered),
+			)
+		}
+	}()
+
+	if err := cleanup(); err != nil {
+		s.logger.Error("cleanup after panic failed", zap.String("step", name), zap.Error(err))
+		return
+	}
+	s.logger.Info("cleanup after panic completed", zap.String("step", name))
+}
+
 // shutdown gracefully stops all modules.
 func (s *Server) shutdown() {
 	// Stop admin server first
@@ -233,21 +708,43 @@ func (s *Server) shutdown() {
 		}
 	}
 
+	// Stop pending weight override expiry reconciles and batched reconciles
+	s.stopOverrideTimers()
+	s.triggerMu.Lock()
+	if s.pendingReconcile != nil {
+		s.pendingReconcile.Stop()
+		s.pendingReconcile = nil
+	}
+	s.triggerMu.Unlock()
+
 	// Stop traffic collector
 	if s.collector != nil {
 		s.collector.Stop()
 		s.logger.Info("traffic collector stopped")
 	}
 
+	// Stop self-monitor
+	if s.selfMonitor != nil {
+		s.selfMonitor.Stop()
+		s.logger.Info("self-monitor stopped")
+	}
+
+	s.synthetic.Stop()
 	s.healthMgr.Stop()
+	s.resolver.Stop()
+	s.closeSyslogSink()
 	cfg := s.configMgr.GetConfig()
-	if cfg.Global.IsCleanupOnExit() {
+	if !s.changesKernel() {
+		s.logger.Info("observe-only or read-only mode, leaving IPVS and iptables rules untouched")
+	} else if cfg.Global.IsCleanupOnExit() {
 		if err := s.reconciler.Cleanup(); err != nil {
 			s.logger.Error("failed to cleanup IPVS rules", zap.Error(err))
 		}
+		s.saveState()
 		if err := s.snatMgr.Cleanup(); err != nil {
 			s.logger.Error("failed to cleanup SNAT rules", zap.Error(err))
 		}
+		s.cleanupPolicyRoutes()
 	} else {
 		s.logger.Info("cleanup_on_exit is false, preserving IPVS and iptables rules")
 	}
@@ -3,10 +3,26 @@
 package server
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/easzlab/ezlb/pkg/admin"
 	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/featuregate"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"github.com/easzlab/ezlb/pkg/resolver"
+	"github.com/easzlab/ezlb/pkg/snat"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -124,6 +140,103 @@ services:
 	}
 }
 
+func TestLogHostListenerCollisions(t *testing.T) {
+	configYAML := `
+global:
+  check_host_listeners: true
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+  - name: dns-service
+    listen: 10.0.0.2:53
+    protocol: udp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.20:53
+        weight: 1
+  - name: api-service
+    listen: 10.0.0.3:443
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.30:443
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+
+	oldEnabled := kernelParamCheckEnabled
+	oldReader := readProcNetFile
+	kernelParamCheckEnabled = true
+	readProcNetFile = func(path string) ([]byte, error) {
+		header := "  sl  local_address rem_address   st tx_queue rx_queue\n"
+		switch path {
+		case "/proc/net/tcp":
+			// 0.0.0.0:80 listening, 127.0.0.1:443 listening, 10.0.0.3:443 established
+			return []byte(header +
+				"   0: 00000000:0050 00000000:0000 0A 00000000:00000000\n" +
+				"   1: 0100007F:01BB 00000000:0000 0A 00000000:00000000\n" +
+				"   2: 0300000A:01BB 0200000A:C350 01 00000000:00000000\n"), nil
+		case "/proc/net/udp":
+			// 10.0.0.2:53 bound
+			return []byte(header + "   0: 0200000A:0035 00000000:0000 07 00000000:00000000\n"), nil
+		default:
+			return nil, os.ErrNotExist
+		}
+	}
+	t.Cleanup(func() {
+		kernelParamCheckEnabled = oldEnabled
+		readProcNetFile = oldReader
+	})
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	srv, err := newServerWithManager(configPath, newTestLVSManager(t), zap.New(core), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	srv.logHostListenerCollisions(srv.configMgr.GetConfig())
+
+	entries := logs.FilterMessage("service VIP collides with a local listening socket").All()
+	got := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		got[fields["service"].(string)] = fields["local_listener"].(string)
+	}
+	want := map[string]string{
+		"web-service": "0.0.0.0:80",
+		"dns-service": "10.0.0.2:53",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected collisions %v, got %v", want, got)
+	}
+	for service, listener := range want {
+		if got[service] != listener {
+			t.Errorf("service %s: expected collision with %s, got %q", service, listener, got[service])
+		}
+	}
+}
+
+func TestParseProcNetAddressIPv6(t *testing.T) {
+	// ::1 port 8080
+	ip, port, err := parseProcNetAddress("00000000000000000000000001000000:1F90")
+	if err != nil {
+		t.Fatalf("parseProcNetAddress failed: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("::1")) || port != 8080 {
+		t.Errorf("expected [::1]:8080, got %s:%d", ip, port)
+	}
+}
+
 func TestLogKernelParamPreflightLogsReadFailures(t *testing.T) {
 	oldEnabled := kernelParamCheckEnabled
 	oldReader := readKernelParamFile
@@ -191,19 +304,1764 @@ func TestLogKernelParamPreflightLogsInfoWhenAllMatch(t *testing.T) {
 	}
 }
 
-func cloneConfig(cfg *config.Config) *config.Config {
-	if cfg == nil {
-		return nil
+func TestHandlePanicCleansUpManagedServices(t *testing.T) {
+	configYAML := `
+global:
+  log:
+    level: info
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(srv.lvsMgr.Close)
+
+	if err := srv.reconciler.Reconcile(srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
 	}
 
-	cloned := *cfg
-	cloned.Services = append([]config.ServiceConfig(nil), cfg.Services...)
-	for i := range cfg.Services {
-		cloned.Services[i].Backends = append([]config.BackendConfig(nil), cfg.Services[i].Backends...)
+	err := srv.handlePanic("boom")
+	if !errors.Is(err, ErrPanic) {
+		t.Fatalf("expected ErrPanic, got %v", err)
+	}
+
+	services, err := srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 0 {
+		t.Fatalf("expected managed services to be cleaned up after panic, got %d", len(services))
 	}
-	return &cloned
 }
 
-func boolPtr(v bool) *bool {
-	return &v
+func TestRecoverPanicHandsErrorToRun(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(srv.lvsMgr.Close)
+
+	if err := srv.reconciler.Reconcile(srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// A panic in a timer callback is recovered like one in the main loop
+	done := make(chan struct{})
+	time.AfterFunc(0, func() {
+		defer close(done)
+		defer srv.recoverPanic()
+		panic("boom")
+	})
+	<-done
+
+	select {
+	case err := <-srv.panics:
+		if !errors.Is(err, ErrPanic) {
+			t.Fatalf("expected ErrPanic, got %v", err)
+		}
+	default:
+		t.Fatal("expected the panic to be handed to Run")
+	}
+	if services, _ := srv.lvsMgr.GetServices(); len(services) != 0 {
+		t.Fatalf("expected managed services to be cleaned up after panic, got %d", len(services))
+	}
+}
+
+func TestObserveOnlyReportsDriftWithoutChanges(t *testing.T) {
+	configYAML := `
+global:
+  log:
+    level: info
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(srv.lvsMgr.Close)
+	srv.SetObserveOnly(true)
+
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	services, err := srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 0 {
+		t.Fatalf("expected observe-only mode not to create services, got %d", len(services))
+	}
+	if len(srv.lastDrift) != 1 {
+		t.Fatalf("expected one drift item, got %v", srv.lastDrift)
+	}
+	if events := srv.events.list(); len(events) != 1 || events[0].Kind != "drift" {
+		t.Fatalf("expected a drift event, got %v", events)
+	}
+
+	// Unchanged drift is not recorded again
+	srv.triggerReconcile()
+	if events := srv.events.list(); len(events) != 1 {
+		t.Fatalf("expected unchanged drift not to be recorded again, got %v", events)
+	}
+}
+
+// recordingSNATManager counts the calls made to a SNAT manager.
+type recordingSNATManager struct {
+	calls []string
+}
+
+func (m *recordingSNATManager) Reconcile([]snat.SNATRule) error {
+	m.calls = append(m.calls, "Reconcile")
+	return nil
+}
+
+func (m *recordingSNATManager) ReconcileForward([]snat.ForwardRule) error {
+	m.calls = append(m.calls, "ReconcileForward")
+	return nil
+}
+
+func (m *recordingSNATManager) ReconcileMark([]snat.MarkRule) error {
+	m.calls = append(m.calls, "ReconcileMark")
+	return nil
+}
+
+func (m *recordingSNATManager) ReconcileDSCP([]snat.DSCPRule) error {
+	m.calls = append(m.calls, "ReconcileDSCP")
+	return nil
+}
+
+func (m *recordingSNATManager) Cleanup() error {
+	m.calls = append(m.calls, "Cleanup")
+	return nil
+}
+
+func TestNonMutatingModesLeaveIptablesAlone(t *testing.T) {
+	configYAML := `
+global:
+  log:
+    level: info
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    full_nat: true
+    snat_ip: 10.0.0.100
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	newServer := func(t *testing.T) (*Server, *recordingSNATManager) {
+		recorder := &recordingSNATManager{}
+		previous := newSNATManager
+		newSNATManager = func(string, *zap.Logger) (snat.Manager, error) { return recorder, nil }
+		t.Cleanup(func() { newSNATManager = previous })
+		srv := newTestServer(t, configPath)
+		t.Cleanup(srv.lvsMgr.Close)
+		return srv, recorder
+	}
+
+	tests := []struct {
+		name string
+		run  func(srv *Server)
+	}{
+		{"read-only", func(srv *Server) {
+			srv.SetReadOnly(true)
+			srv.RunOnce()
+		}},
+		{"observe-only", func(srv *Server) {
+			srv.SetObserveOnly(true)
+			srv.apply(reasonStartup, srv.configMgr.GetConfig().Services)
+		}},
+		{"diff", func(srv *Server) {
+			if _, err := srv.Diff(); err != nil {
+				t.Fatalf("Diff failed: %v", err)
+			}
+		}},
+		{"plan", func(srv *Server) {
+			if _, err := srv.Plan(); err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, recorder := newServer(t)
+			tt.run(srv)
+			if len(recorder.calls) != 0 {
+				t.Errorf("expected no SNAT manager calls, got %v", recorder.calls)
+			}
+		})
+	}
+
+	// A normal run does reconcile iptables, so the recorder sees its calls
+	srv, recorder := newServer(t)
+	if err := srv.RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if len(recorder.calls) == 0 {
+		t.Error("expected a normal run to reconcile iptables rules")
+	}
+}
+
+func TestDiffReportsChangesWithoutApplying(t *testing.T) {
+	configYAML := `
+global:
+  log:
+    level: info
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+
+	// A service another tool created is reported, but not as drift of the config
+	other := &lvs.Service{Address: net.ParseIP("10.0.0.9"), Port: 80, Protocol: syscall.IPPROTO_TCP, SchedName: "rr", AddressFamily: syscall.AF_INET, Netmask: 0xffffffff}
+	if err := srv.lvsMgr.CreateService(other); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+
+	drifts, err := srv.Diff()
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	kinds := make(map[string]string)
+	for _, drift := range drifts {
+		kinds[drift.Target] = drift.Kind
+	}
+	if len(drifts) != 2 || kinds["10.0.0.1:80/tcp"] != lvs.DriftMissingService || kinds["10.0.0.9:80/tcp"] != lvs.DriftUnconfiguredService {
+		t.Fatalf("unexpected diff: %v", drifts)
+	}
+}
+
+func TestTriggerReconcileHonorsMinInterval(t *testing.T) {
+	configYAML := `
+global:
+  min_reconcile_interval: 200ms
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(func() { srv.shutdown() })
+
+	serviceCount := func() int {
+		services, err := srv.lvsMgr.GetServices()
+		if err != nil {
+			t.Fatalf("GetServices failed: %v", err)
+		}
+		return len(services)
+	}
+
+	srv.triggerReconcile()
+	if serviceCount() != 1 {
+		t.Fatal("expected the first trigger to reconcile at once")
+	}
+
+	// Another tool removes the service; triggers within the interval are
+	// batched into one reconcile at its end
+	services, _ := srv.lvsMgr.GetServices()
+	if err := srv.lvsMgr.DeleteService(services[0]); err != nil {
+		t.Fatalf("DeleteService failed: %v", err)
+	}
+	for range 5 {
+		srv.triggerReconcile()
+	}
+	if serviceCount() != 0 {
+		t.Fatal("expected triggers within the interval to be deferred")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for serviceCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if serviceCount() != 1 {
+		t.Fatal("expected the batched reconcile to run after the interval")
+	}
+}
+
+func TestServerScopesSNATChainsToInstance(t *testing.T) {
+	configYAML := `
+global:
+  instance: edge-a
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.shutdown)
+
+	fake, ok := srv.snatMgr.(*snat.FakeManager)
+	if !ok {
+		t.Fatalf("expected the fake SNAT manager, got %T", srv.snatMgr)
+	}
+	if got := fake.Chains().SNAT; got != "EZLB-SNAT-edge-a" {
+		t.Errorf("expected the instance's SNAT chain, got %q", got)
+	}
+	if srv.instance != "edge-a" {
+		t.Errorf("expected the instance to be recorded, got %q", srv.instance)
+	}
+}
+
+func TestEnsureTunnelSetupPreparesDirector(t *testing.T) {
+	oldEnabled := kernelParamCheckEnabled
+	oldReader := readKernelParamFile
+	oldWriter := writeKernelParamFile
+	oldExists := tunnelDeviceExists
+	oldRun := runSetupCommand
+	t.Cleanup(func() {
+		kernelParamCheckEnabled = oldEnabled
+		readKernelParamFile = oldReader
+		writeKernelParamFile = oldWriter
+		tunnelDeviceExists = oldExists
+		runSetupCommand = oldRun
+	})
+
+	kernelParamCheckEnabled = true
+	tunnelDeviceExists = func(string) bool { return false }
+	readKernelParamFile = func(string) ([]byte, error) { return []byte("1\n"), nil }
+	var commands []string
+	runSetupCommand = func(name string, args ...string) error {
+		commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+		return nil
+	}
+	written := map[string]string{}
+	writeKernelParamFile = func(path string, value []byte) error {
+		written[path] = string(value)
+		return nil
+	}
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{TunnelSetup: boolPtr(true)},
+		Services: []config.ServiceConfig{{
+			Name: "web",
+			Backends: []config.BackendConfig{
+				{Address: "192.168.1.10:80", Weight: intPtr(1), ForwardMethod: "tunnel"},
+			},
+		}},
+	}
+	srv := &Server{logger: zap.NewNop()}
+	srv.ensureTunnelSetup(cfg)
+
+	if len(commands) != 2 || commands[0] != "modprobe ipip" || commands[1] != "ip link set tunl0 up" {
+		t.Fatalf("unexpected setup commands: %v", commands)
+	}
+	if written["/proc/sys/net/ipv4/conf/tunl0/rp_filter"] != "0\n" {
+		t.Fatalf("expected tunl0 rp_filter to be set to 0, got %v", written)
+	}
+}
+
+func TestEnsureTunnelSetupVerifyOnly(t *testing.T) {
+	oldEnabled := kernelParamCheckEnabled
+	oldExists := tunnelDeviceExists
+	oldRun := runSetupCommand
+	t.Cleanup(func() {
+		kernelParamCheckEnabled = oldEnabled
+		tunnelDeviceExists = oldExists
+		runSetupCommand = oldRun
+	})
+
+	kernelParamCheckEnabled = true
+	tunnelDeviceExists = func(string) bool { return false }
+	runSetupCommand = func(name string, args ...string) error {
+		t.Fatalf("unexpected setup command %s %v without tunnel_setup", name, args)
+		return nil
+	}
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	cfg := &config.Config{
+		Services: []config.ServiceConfig{{
+			Name: "web",
+			Backends: []config.BackendConfig{
+				{Address: "192.168.1.10:80", Weight: intPtr(1), ForwardMethod: "tunnel"},
+			},
+		}},
+	}
+	srv := &Server{logger: zap.New(core)}
+	srv.ensureTunnelSetup(cfg)
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected 1 error about the missing ipip device, got %d", logs.Len())
+	}
+}
+
+func cloneConfig(cfg *config.Config) *config.Config {
+	if cfg == nil {
+		return nil
+	}
+
+	cloned := *cfg
+	cloned.Services = append([]config.ServiceConfig(nil), cfg.Services...)
+	for i := range cfg.Services {
+		cloned.Services[i].Backends = append([]config.BackendConfig(nil), cfg.Services[i].Backends...)
+	}
+	return &cloned
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestWeightOverrideRespectsMaxWeight(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: wrr
+    max_weight: 10
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 3
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(srv.lvsMgr.Close)
+
+	adapter := &weightOverrideAdapter{server: srv}
+	err := adapter.SetWeightOverride("web-service", "192.168.1.10:8080", 11, 0)
+	if err == nil || !strings.Contains(err.Error(), "exceeds max_weight 10") {
+		t.Fatalf("expected an override above max_weight to be rejected, got %v", err)
+	}
+	if err := adapter.SetWeightOverride("web-service", "192.168.1.10:8080", 10, 0); err != nil {
+		t.Fatalf("SetWeightOverride failed: %v", err)
+	}
+}
+
+func TestDashboardStateReportsBackendsAndEvents(t *testing.T) {
+	configYAML := `
+global:
+  log:
+    level: info
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 3
+      - address: 192.168.1.11:8080
+        weight: 1
+        priority: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(srv.lvsMgr.Close)
+
+	adapter := &weightOverrideAdapter{server: srv}
+	if err := adapter.SetWeightOverride("web-service", "192.168.1.10:8080", 0, 0); err != nil {
+		t.Fatalf("SetWeightOverride failed: %v", err)
+	}
+	srv.recordHealthTransitions(srv.configMgr.GetConfig(), map[string]bool{"192.168.1.11:8080": false})
+
+	state := (&dashboardAdapter{server: srv}).DashboardState()
+	if len(state.Services) != 1 || len(state.Services[0].Backends) != 2 {
+		t.Fatalf("unexpected dashboard services: %+v", state.Services)
+	}
+	primary := state.Services[0].Backends[0]
+	if primary.OverrideWeight == nil || *primary.OverrideWeight != 0 || primary.Weight != 3 {
+		t.Errorf("expected override weight 0 over configured weight 3, got %+v", primary)
+	}
+	if standby := state.Services[0].Backends[1]; standby.Priority != 1 || !standby.Healthy {
+		t.Errorf("unexpected standby backend: %+v", standby)
+	}
+
+	if len(state.Events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", state.Events)
+	}
+	if state.Events[0].Kind != "override" || state.Events[1].Kind != "health" || state.Events[2].Kind != "health" {
+		t.Errorf("unexpected event order: %+v", state.Events)
+	}
+}
+
+func TestRecordHealthTransitions(t *testing.T) {
+	collector, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer collector.Close()
+
+	srv := &Server{
+		logger:       zap.NewNop(),
+		lastHealth:   make(map[string]bool),
+		lastDegraded: make(map[string]bool),
+	}
+	cfg := &config.Config{Services: []config.ServiceConfig{{
+		Name:     "web-service",
+		Backends: []config.BackendConfig{{Address: "192.168.1.10:8080"}, {Address: "192.168.1.11:8080"}},
+	}}}
+	cfg.Global.Log.Syslog = config.SyslogConfig{Enabled: boolPtr(true), Address: collector.LocalAddr().String()}
+	srv.syncSyslogSink(cfg)
+	defer srv.closeSyslogSink()
+
+	srv.recordHealthTransitions(cfg, map[string]bool{"192.168.1.10:8080": true})
+	srv.recordHealthTransitions(cfg, map[string]bool{"192.168.1.10:8080": true})
+	if events := srv.events.list(); len(events) != 0 {
+		t.Fatalf("expected no events for a steady healthy backend, got %+v", events)
+	}
+
+	srv.recordHealthTransitions(cfg, map[string]bool{"192.168.1.10:8080": false})
+	srv.recordHealthTransitions(cfg, map[string]bool{"192.168.1.10:8080": true})
+	events := srv.events.list()
+	var messages []string
+	for _, event := range events {
+		messages = append(messages, event.Message)
+	}
+	want := []string{
+		"backend 192.168.1.10:8080 is unhealthy",
+		"service web-service is degraded",
+		"backend 192.168.1.10:8080 is healthy",
+		"service web-service is restored",
+	}
+	if strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected events:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf := make([]byte, 2048)
+	collector.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := collector.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("expected a syslog message, got: %v", err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<28>1 ") || !strings.Contains(msg, " BACKEND_DOWN [ezlb@32473 backend=\"192.168.1.10:8080\" service=\"web-service\" state=\"down\"] ") {
+		t.Errorf("unexpected syslog message: %s", msg)
+	}
+}
+
+func TestEventLogIsBounded(t *testing.T) {
+	var log eventLog
+	for i := 0; i < eventLogSize+10; i++ {
+		log.record("test", "event %d", i)
+	}
+	events := log.list()
+	if len(events) != eventLogSize {
+		t.Fatalf("expected %d events, got %d", eventLogSize, len(events))
+	}
+	if events[0].Message != "event 10" {
+		t.Errorf("expected oldest events to be discarded, got %q", events[0].Message)
+	}
+}
+
+func TestReportReconcileRateLimitsRepeatedErrors(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	status := &statusAdapter{server: srv}
+
+	permErr := errors.New("create service: operation not permitted")
+	for range 10 {
+		srv.reportReconcile("periodic resync", permErr)
+	}
+	if events := srv.events.list(); len(events) != 1 {
+		t.Fatalf("expected the repeated error to be recorded once, got %+v", events)
+	}
+	if got := status.NodeStatus(); got.ReconcileFailingSince == nil || got.ReconcileError != permErr.Error() {
+		t.Errorf("expected the node status to report the failure, got %v, %q", got.ReconcileFailingSince, got.ReconcileError)
+	}
+
+	// A summary is logged once the interval has passed.
+	srv.failures.lastLogged = srv.failures.lastLogged.Add(-errorSummaryInterval)
+	srv.reportReconcile("periodic resync", permErr)
+	events := srv.events.list()
+	if len(events) != 2 || !strings.Contains(events[1].Message, "10 repeated errors") {
+		t.Fatalf("expected a summary of the repeated errors, got %+v", events)
+	}
+
+	// Another error is reported at once.
+	srv.reportReconcile("periodic resync", errors.New("get services: netlink timeout"))
+	if events := srv.events.list(); len(events) != 3 {
+		t.Fatalf("expected a new error to be recorded, got %+v", events)
+	}
+
+	srv.reportReconcile("periodic resync", nil)
+	events = srv.events.list()
+	if len(events) != 4 || !strings.Contains(events[3].Message, "recovered after 12 failures") {
+		t.Fatalf("expected the recovery to be recorded, got %+v", events)
+	}
+	if got := status.NodeStatus(); got.ReconcileFailingSince != nil || got.ReconcileError != "" {
+		t.Errorf("expected no failure after the recovery, got %v, %q", got.ReconcileFailingSince, got.ReconcileError)
+	}
+	srv.reportReconcile("periodic resync", nil)
+	if events := srv.events.list(); len(events) != 4 {
+		t.Errorf("expected successes to record nothing, got %+v", events)
+	}
+}
+
+func TestNodeStatusReportsHeldVIPs(t *testing.T) {
+	configYAML := `
+global:
+  log:
+    level: info
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+  - name: dns-service
+    listen: 10.0.0.2:53
+    protocol: udp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:53
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(srv.lvsMgr.Close)
+
+	original := localInterfaceAddrs
+	t.Cleanup(func() { localInterfaceAddrs = original })
+	localInterfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(32, 32)},
+		}, nil
+	}
+
+	status := (&statusAdapter{server: srv}).NodeStatus()
+	if len(status.HeldVIPs) != 1 || status.HeldVIPs[0] != "10.0.0.1" {
+		t.Errorf("expected only 10.0.0.1 to be held, got %v", status.HeldVIPs)
+	}
+	if status.Services != 2 || status.ConfigGeneration != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.ConfigHash != srv.configMgr.GetConfig().ServicesHash() {
+		t.Errorf("unexpected config hash %q", status.ConfigHash)
+	}
+}
+
+func TestOnceDiagnostics(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv, err := newServerWithManager(configPath, newTestLVSManager(t), zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	err = srv.RunOnce()
+	diag := NewOnceDiagnostics(StageReconcile, err, srv.LastOperations())
+	if diag.Status != "ok" || diag.Succeeded != 2 || diag.Failed != 0 {
+		t.Fatalf("expected 2 successful operations, got %+v", diag)
+	}
+
+	ops := []lvs.Operation{
+		{Service: "web-service", Resource: lvs.ResourceService, Action: lvs.ActionCreate, Target: "10.0.0.1:80/tcp"},
+		{Service: "web-service", Resource: lvs.ResourceDestination, Action: lvs.ActionCreate, Target: "192.168.1.10:8080", Err: syscall.EPERM},
+	}
+	diag = NewOnceDiagnostics(StageReconcile, fmt.Errorf("reconcile failed: %w", syscall.EPERM), ops)
+	if diag.Status != "failed" || diag.Stage != StageReconcile || diag.ErrorClass != lvs.ErrorClassPermission {
+		t.Errorf("unexpected run-level diagnostics: %+v", diag)
+	}
+	if diag.Succeeded != 1 || diag.Failed != 1 || diag.Operations[1].Result != "failed" ||
+		diag.Operations[1].ErrorClass != lvs.ErrorClassPermission {
+		t.Errorf("unexpected operation diagnostics: %+v", diag.Operations)
+	}
+
+	_, err = newServerWithManager(writeYAMLFile(t, t.TempDir(), "services: []\n"), newTestLVSManager(t), zap.NewNop(), zap.NewNop())
+	if diag := NewOnceDiagnostics(StageInit, err, nil); diag.ErrorClass != "config" {
+		t.Errorf("expected config error class, got %+v", diag)
+	}
+}
+
+func TestRunOnceSkipsWhenLockHeldElsewhere(t *testing.T) {
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/session/create" {
+			w.Write([]byte(`{"ID":"session-1"}`))
+			return
+		}
+		// The key is held by another node's session
+		w.Write([]byte("false"))
+	}))
+	defer consul.Close()
+
+	configYAML := fmt.Sprintf(`
+global:
+  lock:
+    backend: consul
+    address: %s
+    key: ezlb/once
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`, consul.URL)
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv, err := newServerWithManager(configPath, newTestLVSManager(t), zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	err = srv.RunOnce()
+	if !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("expected ErrLockNotHeld, got: %v", err)
+	}
+	diag := NewOnceDiagnostics(StageReconcile, err, srv.LastOperations())
+	if diag.Status != "skipped" || diag.Stage != StageLock || len(diag.Operations) != 0 {
+		t.Errorf("expected a skipped run without operations, got %+v", diag)
+	}
+}
+
+func TestRunOnceObserveOnlyReportsDrift(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv, err := newServerWithManager(configPath, newTestLVSManager(t), zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	srv.SetObserveOnly(true)
+	err = srv.RunOnce()
+	if !errors.Is(err, ErrDrift) {
+		t.Fatalf("expected ErrDrift for a missing service, got: %v", err)
+	}
+	diag := NewOnceDiagnostics(StageReconcile, err, srv.LastOperations())
+	if diag.Status != "drift" || len(diag.Operations) != 0 {
+		t.Errorf("expected a drift run without operations, got %+v", diag)
+	}
+
+	// Once the kernel matches the config there is no drift
+	srv, err = newServerWithManager(configPath, newTestLVSManager(t), zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	if err := srv.reconciler.Reconcile(srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	srv.SetObserveOnly(true)
+	if err := srv.RunOnce(); err != nil {
+		t.Errorf("expected no drift after a reconcile, got: %v", err)
+	}
+}
+
+func TestCheckDrainsRemovesDrainedBackends(t *testing.T) {
+	configYAML := `
+global:
+  drain:
+    remove: true
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+      - address: 192.168.1.11:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	srv := newTestServer(t, configPath)
+	t.Cleanup(func() {
+		srv.shutdown()
+	})
+
+	services := srv.configMgr.GetConfig().Services
+	srv.reconciler.SetWeightOverride(lvs.WeightOverride{Service: "web-service", Backend: "192.168.1.10:8080", Weight: 0})
+	if err := srv.reconciler.Reconcile(services); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	srv.checkDrains()
+
+	events := srv.events.list()
+	if len(events) != 1 || events[0].Kind != "drain" {
+		t.Fatalf("expected a drain event, got %+v", events)
+	}
+	ipvsServices, _ := srv.lvsMgr.GetServices()
+	dests, _ := srv.lvsMgr.GetDestinations(ipvsServices[0])
+	if len(dests) != 1 || dests[0].Port != 8080 || dests[0].Address.String() != "192.168.1.11" {
+		t.Fatalf("expected drained backend to be removed, got %d destinations", len(dests))
+	}
+}
+
+func TestFeatureGatesResolvedAtStartup(t *testing.T) {
+	configYAML := `
+global:
+  feature_gates:
+    adaptive_weights: true
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	core, logs := observer.New(zapcore.InfoLevel)
+	srv, err := newServerWithManager(configPath, newTestLVSManager(t), zap.New(core), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+
+	if !srv.Features().Enabled(featuregate.AdaptiveWeights) {
+		t.Error("expected adaptive_weights to be enabled")
+	}
+	if srv.Features().Enabled(featuregate.BGPAnnouncer) {
+		t.Error("expected bgp_announcer to stay disabled")
+	}
+
+	srv.logFeatureGates()
+	entries := logs.FilterMessage("experimental feature enabled").All()
+	if len(entries) != 1 || entries[0].ContextMap()["feature"] != featuregate.AdaptiveWeights {
+		t.Fatalf("expected one log for adaptive_weights, got %+v", entries)
+	}
+
+	// Gates are fixed at startup; a reload only warns
+	srv.warnFeatureGateChange(&config.Config{})
+	if logs.FilterMessage("global.feature_gates changed, restart ezlb to apply").Len() != 1 {
+		t.Error("expected a warning about changed feature gates")
+	}
+}
+
+func TestLogSchedulerPreflight(t *testing.T) {
+	oldEnabled, oldCheck := kernelParamCheckEnabled, checkScheduler
+	kernelParamCheckEnabled = true
+	checkScheduler = func(name string) error {
+		if name == "mh" {
+			return fmt.Errorf("%w: ip_vs_mh missing", lvs.ErrSchedulerUnavailable)
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		kernelParamCheckEnabled, checkScheduler = oldEnabled, oldCheck
+	})
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	srv := &Server{logger: zap.New(core)}
+	srv.logSchedulerPreflight(&config.Config{Services: []config.ServiceConfig{
+		{Name: "a", Scheduler: "mh"},
+		{Name: "b", Scheduler: "rr"},
+		{Name: "c", Scheduler: "mh"},
+	}})
+
+	entries := logs.FilterMessage("IPVS scheduler preflight failed").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 preflight failure, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()["scheduler"]; got != "mh" {
+		t.Errorf("expected scheduler mh, got %v", got)
+	}
+}
+
+func TestSyncPolicyRoutes(t *testing.T) {
+	oldEnabled, oldRun := kernelParamCheckEnabled, runSetupCommand
+	t.Cleanup(func() {
+		kernelParamCheckEnabled, runSetupCommand = oldEnabled, oldRun
+	})
+	kernelParamCheckEnabled = true
+	var commands []string
+	runSetupCommand = func(name string, args ...string) error {
+		commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+		return nil
+	}
+
+	svc := config.ServiceConfig{
+		Name:            "web",
+		FullNAT:         true,
+		OutputInterface: "eth1",
+		RouteTable:      100,
+		Backends:        []config.BackendConfig{{Address: "192.168.1.10:80", Weight: intPtr(1)}},
+	}
+	srv := &Server{logger: zap.NewNop(), policyRoutes: make(map[policyRoute]bool)}
+	srv.syncPolicyRoutes(&config.Config{Services: []config.ServiceConfig{svc}})
+	want := []string{
+		"ip -4 rule del to 192.168.1.10 lookup 100",
+		"ip -4 rule add to 192.168.1.10 lookup 100",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+
+	// An unchanged config runs nothing; a replaced backend swaps its rule
+	commands = nil
+	srv.syncPolicyRoutes(&config.Config{Services: []config.ServiceConfig{svc}})
+	if len(commands) != 0 {
+		t.Fatalf("expected no commands for an unchanged config, got %v", commands)
+	}
+	svc.Backends = []config.BackendConfig{{Address: "192.168.1.11:80", Weight: intPtr(1)}}
+	srv.syncPolicyRoutes(&config.Config{Services: []config.ServiceConfig{svc}})
+	if len(commands) != 3 || commands[0] != "ip -4 rule del to 192.168.1.10 lookup 100" {
+		t.Fatalf("expected the stale rule to be deleted first, got %v", commands)
+	}
+
+	commands = nil
+	srv.cleanupPolicyRoutes()
+	if len(commands) != 1 || commands[0] != "ip -4 rule del to 192.168.1.11 lookup 100" {
+		t.Fatalf("unexpected cleanup commands: %v", commands)
+	}
+}
+
+// staticHealthProvider is an external health source with fixed results.
+type staticHealthProvider struct {
+	healthy map[string]bool
+	targets int
+}
+
+func (p *staticHealthProvider) UpdateTargets(_ context.Context, services []config.ServiceConfig) {
+	p.targets = len(services)
+}
+func (p *staticHealthProvider) IsHealthy(address string) bool { return p.healthy[address] }
+func (p *staticHealthProvider) Snapshot() map[string]bool     { return p.healthy }
+func (p *staticHealthProvider) Stop()                         {}
+
+func TestServerUsesExternalHealthProvider(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: true
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+      - address: 192.168.1.11:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	provider := &staticHealthProvider{healthy: map[string]bool{"192.168.1.10:8080": true}}
+	lvsMgr := newTestLVSManager(t)
+	srv, err := newServerWithHealthProvider(configPath, lvsMgr, zap.NewNop(), zap.NewNop(),
+		func(func(), *zap.Logger) HealthProvider { return provider })
+	if err != nil {
+		t.Fatalf("newServerWithHealthProvider failed: %v", err)
+	}
+
+	cfg := srv.configMgr.GetConfig()
+	srv.healthMgr.UpdateTargets(context.Background(), cfg.Services)
+	if provider.targets != 1 {
+		t.Fatalf("expected the provider to track 1 service, got %d", provider.targets)
+	}
+	if err := srv.apply(reasonConfig, cfg.Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	services, _ := lvsMgr.GetServices()
+	if len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(services))
+	}
+	dests, _ := lvsMgr.GetDestinations(services[0])
+	if len(dests) != 1 || dests[0].Address.String() != "192.168.1.10" {
+		t.Fatalf("expected only the healthy backend, got %+v", dests)
+	}
+	if status := (&statusAdapter{server: srv}).NodeStatus(); !status.Backends["192.168.1.10:8080"] || status.Backends["192.168.1.11:8080"] {
+		t.Errorf("expected node status to report the provider's health, got %v", status.Backends)
+	}
+}
+
+func TestReconcileHealthChange_OnlyAffectedServices(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: true
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+      - address: 192.168.1.11:8080
+        weight: 1
+  - name: api-service
+    listen: 10.0.0.2:443
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: true
+    backends:
+      - address: 192.168.1.20:8443
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	provider := &staticHealthProvider{healthy: map[string]bool{
+		"192.168.1.10:8080": true,
+		"192.168.1.11:8080": true,
+		"192.168.1.20:8443": true,
+	}}
+	lvsMgr := newTestLVSManager(t)
+	srv, err := newServerWithHealthProvider(configPath, lvsMgr, zap.NewNop(), zap.NewNop(),
+		func(func(), *zap.Logger) HealthProvider { return provider })
+	if err != nil {
+		t.Fatalf("newServerWithHealthProvider failed: %v", err)
+	}
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	// Nothing changed since the reconcile: no service is reconciled again.
+	if services := srv.servicesWithHealthChanges(srv.resolvedConfig()); len(services) != 0 {
+		t.Fatalf("expected no affected services, got %v", services)
+	}
+
+	provider.healthy["192.168.1.11:8080"] = false
+	srv.reconcileHealthChange()
+
+	ops := srv.LastOperations()
+	if len(ops) != 1 || ops[0].Service != "web-service" || ops[0].Action != lvs.ActionDelete {
+		t.Fatalf("expected only the unhealthy web-service backend to be deleted, got %+v", ops)
+	}
+	services, _ := lvsMgr.GetServices()
+	for _, svc := range services {
+		if dests, _ := lvsMgr.GetDestinations(svc); len(dests) != 1 {
+			t.Errorf("expected 1 destination for %s, got %d", svc.Address, len(dests))
+		}
+	}
+	if services := srv.servicesWithHealthChanges(srv.resolvedConfig()); len(services) != 0 {
+		t.Errorf("expected the change to be recorded, got %v", services)
+	}
+}
+
+func TestChangeHistoryRecordsReasons(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: true
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+      - address: 192.168.1.11:8080
+        weight: 1
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+	provider := &staticHealthProvider{healthy: map[string]bool{
+		"192.168.1.10:8080": true,
+		"192.168.1.11:8080": true,
+	}}
+	srv, err := newServerWithHealthProvider(configPath, newTestLVSManager(t), zap.NewNop(), zap.NewNop(),
+		func(func(), *zap.Logger) HealthProvider { return provider })
+	if err != nil {
+		t.Fatalf("newServerWithHealthProvider failed: %v", err)
+	}
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	srv.servicesWithHealthChanges(srv.resolvedConfig())
+	provider.healthy["192.168.1.11:8080"] = false
+	srv.reconcileHealthChange()
+
+	changes := (&changeAdapter{server: srv}).Changes()
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", changes)
+	}
+	for _, change := range changes[:3] {
+		if change.Reason != reasonConfig || change.Action != lvs.ActionCreate {
+			t.Errorf("expected a create for the config change, got %+v", change)
+		}
+	}
+	if last := changes[3]; last.Reason != reasonHealth || last.Action != lvs.ActionDelete ||
+		last.Service != "web-service" || last.Target != "192.168.1.11:8080" || last.Time.IsZero() {
+		t.Errorf("expected the unhealthy backend to be deleted for a health change, got %+v", last)
+	}
+}
+
+func TestServerExpandsHostnameBackends(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: web.example.com:8080
+        weight: 3
+`
+	configPath := writeYAMLFile(t, t.TempDir(), configYAML)
+
+	lvsMgr := newTestLVSManager(t)
+	srv, err := newServerWithManager(configPath, lvsMgr, zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	srv.resolver = resolver.NewResolver(func(_ context.Context, host string) ([]net.IP, error) {
+		if host != "web.example.com" {
+			return nil, fmt.Errorf("unexpected lookup of %q", host)
+		}
+		return []net.IP{net.ParseIP("192.168.1.11"), net.ParseIP("192.168.1.10")}, nil
+	}, zap.NewNop())
+	t.Cleanup(srv.resolver.Stop)
+
+	cfg := srv.resolveConfig(context.Background(), srv.configMgr.GetConfig())
+	if err := srv.apply(reasonConfig, cfg.Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	services, err := lvsMgr.GetServices()
+	if err != nil || len(services) != 1 {
+		t.Fatalf("expected 1 IPVS service, got %d (err %v)", len(services), err)
+	}
+	dests, err := lvsMgr.GetDestinations(services[0])
+	if err != nil {
+		t.Fatalf("GetDestinations failed: %v", err)
+	}
+	got := make(map[string]int)
+	for _, dest := range dests {
+		got[net.JoinHostPort(dest.Address.String(), fmt.Sprint(dest.Port))] = dest.Weight
+	}
+	want := map[string]int{"192.168.1.10:8080": 3, "192.168.1.11:8080": 3}
+	if len(got) != len(want) {
+		t.Fatalf("expected destinations %v, got %v", want, got)
+	}
+	for address, weight := range want {
+		if got[address] != weight {
+			t.Errorf("expected destination %s with weight %d, got %v", address, weight, got)
+		}
+	}
+}
+
+func TestRunOnceDeletesServicesRemovedSinceLastRun(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	configYAML := fmt.Sprintf(`
+global:
+  state_file: %s
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+  - name: api-service
+    listen: 10.0.0.2:443
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.2.10:9090
+        weight: 1
+`, statePath)
+	configPath := writeYAMLFile(t, dir, configYAML)
+	srv, err := newServerWithManager(configPath, newTestLVSManager(t), zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	if err := srv.RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("expected a state file: %v", err)
+	}
+	if !strings.Contains(string(data), "10.0.0.2:443/tcp") {
+		t.Fatalf("expected api-service in the state file, got %s", data)
+	}
+
+	// The next run finds api-service in the kernel but not in the config.
+	lvsMgr := newTestLVSManager(t)
+	apiSvc, err := lvs.ConfigToIPVSService(config.ServiceConfig{Listen: "10.0.0.2:443", Protocol: "tcp", Scheduler: "rr"})
+	if err != nil {
+		t.Fatalf("ConfigToIPVSService failed: %v", err)
+	}
+	if err := lvsMgr.CreateService(apiSvc); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+	configPath = writeYAMLFile(t, dir, configYAML[:strings.Index(configYAML, "  - name: api-service")])
+	srv, err = newServerWithManager(configPath, lvsMgr, zap.NewNop(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("newServerWithManager failed: %v", err)
+	}
+	if err := srv.RunOnce(); err != nil {
+		t.Fatalf("second RunOnce failed: %v", err)
+	}
+	deleted := false
+	for _, op := range srv.LastOperations() {
+		if op.Resource == lvs.ResourceService && op.Action == lvs.ActionDelete && op.Target == "10.0.0.2:443/tcp" {
+			deleted = true
+		}
+	}
+	if !deleted {
+		t.Errorf("expected api-service to be deleted, got %+v", srv.LastOperations())
+	}
+}
+
+func TestTakeSnapshotWritesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	snapshotDir := filepath.Join(dir, "snapshots")
+	configYAML := fmt.Sprintf(`
+global:
+  snapshot:
+    dir: %s
+    retention: 48h
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`, snapshotDir)
+	srv := newTestServer(t, writeYAMLFile(t, dir, configYAML))
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	old := time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 10, 4, 2, 0, 0, 0, time.UTC)
+	srv.takeSnapshot(old)
+	srv.takeSnapshot(now.Add(-time.Hour))
+	srv.takeSnapshot(now)
+
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "20261004T010000Z,20261004T020000Z" {
+		t.Fatalf("expected the snapshot past the retention to be removed, got %v", names)
+	}
+
+	latest := filepath.Join(snapshotDir, "20261004T020000Z")
+	configData, err := os.ReadFile(filepath.Join(latest, "config.yaml"))
+	if err != nil {
+		t.Fatalf("expected config.yaml: %v", err)
+	}
+	if _, err := config.NewManager(writeYAMLFile(t, t.TempDir(), string(configData)), zap.NewNop()); err != nil {
+		t.Errorf("expected config.yaml to be a valid config: %v", err)
+	}
+	ipvsData, err := os.ReadFile(filepath.Join(latest, "ipvs.json"))
+	if err != nil {
+		t.Fatalf("expected ipvs.json: %v", err)
+	}
+	for _, want := range []string{`"time": "2026-10-04T02:00:00Z"`, `"service": "10.0.0.1:80/tcp"`, `"address": "192.168.1.10:8080"`} {
+		if !strings.Contains(string(ipvsData), want) {
+			t.Errorf("expected ipvs.json to contain %s, got %s", want, ipvsData)
+		}
+	}
+}
+
+func TestApplyPrunesWithGlobalPrune(t *testing.T) {
+	configYAML := `
+global:
+  prune: true
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	foreign, err := lvs.ConfigToIPVSService(config.ServiceConfig{Listen: "10.0.0.9:80", Protocol: "tcp", Scheduler: "rr"})
+	if err != nil {
+		t.Fatalf("ConfigToIPVSService failed: %v", err)
+	}
+	if err := srv.lvsMgr.CreateService(foreign); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	services, err := srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 1 || services[0].Address.String() != "10.0.0.1" {
+		t.Fatalf("expected the unmanaged service to be pruned, got %v", services)
+	}
+}
+
+func TestApplyRefusesChangesInReadOnlyMode(t *testing.T) {
+	configYAML := `
+global:
+  read_only: true
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	if srv.changesKernel() {
+		t.Fatal("expected global.read_only to enable read-only mode")
+	}
+
+	applyErr := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services)
+	if !errors.Is(applyErr, lvs.ErrReadOnly) {
+		t.Fatalf("expected the reconcile to be refused with ErrReadOnly, got %v", applyErr)
+	}
+	services, err := srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 0 {
+		t.Fatalf("expected no services in read-only mode, got %d", len(services))
+	}
+	diag := NewOnceDiagnostics(StageReconcile, applyErr, srv.LastOperations())
+	if diag.ErrorClass != lvs.ErrorClassReadOnly || diag.Failed != 1 {
+		t.Errorf("expected one read_only failure, got %+v", diag)
+	}
+}
+
+// stubHealth reports the backends in unhealthy as down and all others as up.
+type stubHealth struct {
+	unhealthy map[string]bool
+}
+
+func (h *stubHealth) IsHealthy(address string) bool {
+	return !h.unhealthy[address]
+}
+
+func TestReportEmptyServices(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	health := &stubHealth{unhealthy: map[string]bool{"192.168.1.10:8080": true}}
+	srv.reconciler = lvs.NewReconciler(srv.lvsMgr, health, srv.snatMgr, zap.NewNop())
+	services := srv.configMgr.GetConfig().Services
+
+	for range 2 {
+		if err := srv.apply(reasonConfig, services); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+	}
+	health.unhealthy = nil
+	if err := srv.apply(reasonConfig, services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	var messages []string
+	for _, event := range srv.events.list() {
+		if event.Kind == "empty" {
+			messages = append(messages, event.Message)
+		}
+	}
+	want := []string{
+		"service web-service has no destinations",
+		"service web-service has destinations again",
+	}
+	if strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected events %q, want %q", messages, want)
+	}
+}
+
+func TestResyncRepairsServicesDeletedOutsideEzlb(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	// Nothing to repair yet
+	srv.resync()
+	services, err := srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(services))
+	}
+
+	// Simulate "ipvsadm -D" by another tool
+	if err := srv.lvsMgr.DeleteService(services[0]); err != nil {
+		t.Fatalf("DeleteService failed: %v", err)
+	}
+	srv.resync()
+	services, err = srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(services) != 1 || services[0].Address.String() != "10.0.0.1" {
+		t.Fatalf("expected the deleted service to be recreated, got %v", services)
+	}
+
+	var resyncs int
+	for _, event := range srv.events.list() {
+		if event.Kind == "resync" {
+			resyncs++
+		}
+	}
+	if resyncs != 1 {
+		t.Errorf("expected 1 resync event, got %d", resyncs)
+	}
+}
+
+func TestIntervalTicker(t *testing.T) {
+	var timer intervalTicker
+	timer.reset(0)
+	if timer.C != nil {
+		t.Fatal("expected a zero interval to leave the timer disabled")
+	}
+	timer.reset(time.Millisecond)
+	select {
+	case <-timer.C:
+	case <-time.After(time.Second):
+		t.Fatal("expected the timer to fire")
+	}
+	timer.reset(0)
+	if timer.C != nil {
+		t.Error("expected resetting to zero to disable the timer")
+	}
+	timer.stop()
+}
+
+func TestLogSNATPreflight(t *testing.T) {
+	oldEnabled, oldAddrs := kernelParamCheckEnabled, interfaceAddrs
+	kernelParamCheckEnabled = true
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("10.0.0.2"), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	}
+	t.Cleanup(func() {
+		kernelParamCheckEnabled, interfaceAddrs = oldEnabled, oldAddrs
+	})
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	srv := &Server{logger: zap.New(core)}
+	srv.logSNATPreflight(&config.Config{Services: []config.ServiceConfig{
+		{Name: "a", FullNAT: true, SnatIP: "10.0.0.2"},
+		{Name: "b", FullNAT: true, SnatIP: "10.0.0.9"},
+		{Name: "c", FullNAT: true, SnatIP: "10.0.0.9"},
+	}})
+
+	entries := logs.FilterMessage("snat_ip is not assigned to a local interface, return traffic will be dropped").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 warning, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["snat_ip"] != "10.0.0.9" {
+		t.Errorf("expected snat_ip 10.0.0.9, got %v", fields["snat_ip"])
+	}
+	if services, _ := fields["services"].([]interface{}); len(services) != 2 {
+		t.Errorf("expected services b and c, got %v", fields["services"])
+	}
+}
+
+func TestCheckDriftReportsExternalChanges(t *testing.T) {
+	configYAML := `
+global:
+  drift_check_interval: 1m
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	if got := srv.driftCheckInterval(srv.configMgr.GetConfig()); got != time.Minute {
+		t.Fatalf("expected a 1m drift check, got %v", got)
+	}
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	srv.checkDrift()
+	if len(srv.lastDrift) != 0 {
+		t.Fatalf("expected no drift after apply, got %v", srv.lastDrift)
+	}
+
+	// Another tool changes the weight and adds its own service
+	services, err := srv.lvsMgr.GetServices()
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	dsts, err := srv.lvsMgr.GetDestinations(services[0])
+	if err != nil {
+		t.Fatalf("GetDestinations failed: %v", err)
+	}
+	dsts[0].Weight = 5
+	if err := srv.lvsMgr.UpdateDestination(services[0], dsts[0]); err != nil {
+		t.Fatalf("UpdateDestination failed: %v", err)
+	}
+	foreign, err := lvs.ConfigToIPVSService(config.ServiceConfig{Listen: "10.0.0.9:80", Protocol: "tcp", Scheduler: "rr"})
+	if err != nil {
+		t.Fatalf("ConfigToIPVSService failed: %v", err)
+	}
+	if err := srv.lvsMgr.CreateService(foreign); err != nil {
+		t.Fatalf("CreateService failed: %v", err)
+	}
+
+	srv.checkDrift()
+	want := map[string]bool{
+		`service "web-service": weight_mismatch 192.168.1.10:8080 (want 1, have 5)`: true,
+		"unconfigured_service 10.0.0.9:80/tcp (sched rr)":                           true,
+	}
+	if !reflect.DeepEqual(srv.lastDrift, want) {
+		t.Errorf("unexpected drift %v, want %v", srv.lastDrift, want)
+	}
+
+	// The check only reports; the kernel is left for the next reconcile
+	dsts, err = srv.lvsMgr.GetDestinations(services[0])
+	if err != nil {
+		t.Fatalf("GetDestinations failed: %v", err)
+	}
+	if dsts[0].Weight != 5 {
+		t.Errorf("expected the drift check not to change the weight, got %d", dsts[0].Weight)
+	}
+}
+
+func TestDriftCheckInterval(t *testing.T) {
+	srv := &Server{}
+	cfg := &config.Config{}
+	if got := srv.driftCheckInterval(cfg); got != 0 {
+		t.Errorf("expected the drift check to be disabled by default, got %v", got)
+	}
+	srv.observeOnly = true
+	if got := srv.driftCheckInterval(cfg); got != defaultObserveDriftInterval {
+		t.Errorf("expected observe-only mode to default to %v, got %v", defaultObserveDriftInterval, got)
+	}
+	cfg.Global.DriftCheckInterval = "5m"
+	if got := srv.driftCheckInterval(cfg); got != 5*time.Minute {
+		t.Errorf("expected the configured interval, got %v", got)
+	}
+}
+
+func TestPlanAdapter(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+	adapter := &planAdapter{server: srv}
+
+	plan, err := adapter.Plan()
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	want := []admin.PlannedChange{
+		{Service: "web-service", Resource: "service", Action: "create", Target: "10.0.0.1:80/tcp"},
+		{Service: "web-service", Resource: "destination", Action: "create", Target: "192.168.1.10:8080"},
+	}
+	if !reflect.DeepEqual(plan.Changes, want) {
+		t.Fatalf("expected %+v, got %+v", want, plan.Changes)
+	}
+	if services, _ := srv.lvsMgr.GetServices(); len(services) != 0 {
+		t.Fatalf("expected planning not to create services, got %d", len(services))
+	}
+
+	if err := srv.apply(reasonConfig, srv.configMgr.GetConfig().Services); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	plan, err = adapter.Plan()
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.Changes == nil || len(plan.Changes) != 0 {
+		t.Errorf("expected an empty list of changes after apply, got %+v", plan.Changes)
+	}
+}
+
+func TestPersistenceTemplates(t *testing.T) {
+	configYAML := `
+services:
+  - name: web-service
+    listen: 10.0.0.1:80
+    protocol: tcp
+    scheduler: rr
+    persistence:
+      timeout: 300s
+    health_check:
+      enabled: false
+    backends:
+      - address: 192.168.1.10:8080
+        weight: 1
+      - address: 192.168.1.11:8080
+        weight: 1
+`
+	srv := newTestServer(t, writeYAMLFile(t, t.TempDir(), configYAML))
+	t.Cleanup(srv.lvsMgr.Close)
+
+	web := lvs.ServiceKey{Address: "10.0.0.1", Port: 80, Protocol: syscall.IPPROTO_TCP}
+	original := readPersistenceTemplates
+	readPersistenceTemplates = func() ([]lvs.PersistenceTemplate, error) {
+		return []lvs.PersistenceTemplate{
+			{Client: net.ParseIP("192.168.0.2"), Service: web, Destination: lvs.DestinationKey{Address: "192.168.1.11", Port: 8080}, Expires: time.Minute},
+			{Client: net.ParseIP("192.168.0.1"), Service: web, Destination: lvs.DestinationKey{Address: "192.168.1.10", Port: 8080}, Expires: 5 * time.Minute},
+			// A template of a service ezlb does not manage
+			{Client: net.ParseIP("192.168.0.1"), Service: lvs.ServiceKey{Address: "10.0.0.9", Port: 80, Protocol: syscall.IPPROTO_TCP}, Destination: lvs.DestinationKey{Address: "192.168.9.10", Port: 80}, Expires: time.Minute},
+		}, nil
+	}
+	t.Cleanup(func() { readPersistenceTemplates = original })
+
+	before := time.Now()
+	templates, err := (&persistenceAdapter{server: srv}).PersistenceTemplates()
+	if err != nil {
+		t.Fatalf("PersistenceTemplates failed: %v", err)
+	}
+	if len(templates) != 2 {
+		t.Fatalf("expected the 2 templates of web-service, got %+v", templates)
+	}
+	first := templates[0]
+	if first.Service != "web-service" || first.Client != "192.168.0.1" || first.Backend != "192.168.1.10:8080" {
+		t.Errorf("expected the templates sorted by client, got %+v", templates)
+	}
+	if first.ExpiresAt.Before(before.Add(5*time.Minute)) || first.ExpiresAt.After(time.Now().Add(5*time.Minute)) {
+		t.Errorf("expected the template to expire in 5m, got %v", first.ExpiresAt)
+	}
 }
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"go.uber.org/zap"
+)
+
+// snapshotTimeFormat names snapshot directories after the UTC time they were
+// taken, so they sort chronologically.
+const snapshotTimeFormat = "20060102T150405Z"
+
+// kernelSnapshot is the content of a snapshot's ipvs.json.
+type kernelSnapshot struct {
+	Time       time.Time           `json:"time"`
+	ConfigHash string              `json:"config_hash"`
+	Services   []lvs.KernelService `json:"services"`
+}
+
+// takeSnapshot writes the config and the kernel state to a new subdirectory
+// of global.snapshot.dir and removes snapshots past the retention.
+func (s *Server) takeSnapshot(now time.Time) {
+	cfg := s.configMgr.GetConfig()
+	snapshotCfg := cfg.Global.Snapshot
+	if !snapshotCfg.IsEnabled() {
+		return
+	}
+	path, err := s.writeSnapshot(snapshotCfg.Dir, cfg, now)
+	if err != nil {
+		s.logger.Warn("failed to write config snapshot", zap.String("dir", snapshotCfg.Dir), zap.Error(err))
+	} else {
+		s.logger.Debug("config snapshot written", zap.String("path", path))
+	}
+	pruneSnapshots(snapshotCfg.Dir, snapshotCfg.GetRetention(), now, s.logger)
+}
+
+// writeSnapshot writes config.yaml and ipvs.json to dir/<time>, via a hidden
+// temporary directory so a snapshot is either complete or absent.
+func (s *Server) writeSnapshot(dir string, cfg *config.Config, now time.Time) (string, error) {
+	configData, err := config.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	services, err := s.lvsMgr.Dump()
+	if err != nil {
+		return "", fmt.Errorf("failed to read IPVS state: %w", err)
+	}
+	kernelData, err := json.MarshalIndent(kernelSnapshot{
+		Time:       now.UTC(),
+		ConfigHash: cfg.ServicesHash(),
+		Services:   services,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := now.UTC().Format(snapshotTimeFormat)
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("snapshot %s already exists", path)
+	}
+	tmp, err := os.MkdirTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "config.yaml"), configData, 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "ipvs.json"), append(kernelData, '\n'), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// pruneSnapshots removes the snapshots in dir taken more than retention
+// before now, and temporary directories left by interrupted snapshots. A
+// zero retention keeps every snapshot.
+func pruneSnapshots(dir string, retention time.Duration, now time.Time, logger *zap.Logger) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("failed to list config snapshots", zap.String("dir", dir), zap.Error(err))
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		expired := false
+		if strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp") {
+			info, err := entry.Info()
+			expired = err == nil && now.Sub(info.ModTime()) > time.Hour
+		} else if taken, err := time.Parse(snapshotTimeFormat, name); err == nil {
+			expired = retention > 0 && now.Sub(taken) > retention
+		}
+		if !expired {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			logger.Warn("failed to remove config snapshot", zap.String("snapshot", name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net"
+	"sort"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// interfaceAddrs lists the addresses of the local interfaces; tests replace it.
+var interfaceAddrs = net.InterfaceAddrs
+
+// logSNATPreflight reports snat_ip addresses that no local interface holds.
+// The kernel accepts such a SNAT rule, but the backends' replies to it never
+// reach the director, so the traffic is silently dropped.
+func (s *Server) logSNATPreflight(cfg *config.Config) {
+	if !kernelParamCheckEnabled || s.logger == nil || cfg == nil {
+		return
+	}
+
+	services := make(map[string][]string)
+	for _, svc := range config.EnabledServices(cfg.Services) {
+		if svc.SnatIP != "" {
+			services[svc.SnatIP] = append(services[svc.SnatIP], svc.Name)
+		}
+	}
+	if len(services) == 0 {
+		return
+	}
+
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		s.logger.Error("failed to read local interface addresses", zap.Error(err))
+		return
+	}
+	local := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local = append(local, ipNet.IP)
+		}
+	}
+
+	snatIPs := make([]string, 0, len(services))
+	for snatIP := range services {
+		snatIPs = append(snatIPs, snatIP)
+	}
+	sort.Strings(snatIPs)
+
+	for _, snatIP := range snatIPs {
+		if !containsIP(local, net.ParseIP(snatIP)) {
+			s.logger.Warn("snat_ip is not assigned to a local interface, return traffic will be dropped",
+				zap.String("snat_ip", snatIP),
+				zap.Strings("services", services[snatIP]),
+			)
+		}
+	}
+}
+
+// containsIP reports whether ips contains ip.
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, candidate := range ips {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"go.uber.org/zap"
+)
+
+// loadState marks the services recorded in the state file as managed, so
+// that services removed from the config while ezlb was not running are
+// deleted by the first reconcile.
+func (s *Server) loadState() {
+	if err := s.reconciler.LoadState(s.stateFile); err != nil {
+		s.logger.Warn("failed to load state file, services removed from the config before this run are not deleted",
+			zap.String("path", s.stateFile),
+			zap.Error(err),
+		)
+	}
+}
+
+// saveState records the managed services in the state file after a
+// reconcile. A failed write is logged; the services stay managed by this
+// process.
+func (s *Server) saveState() {
+	if err := s.reconciler.SaveState(s.stateFile); err != nil {
+		s.logger.Warn("failed to save state file", zap.String("path", s.stateFile), zap.Error(err))
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net"
+	"os"
+	"sort"
+
+	"github.com/easzlab/ezlb/pkg/admin"
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// localInterfaceAddrs lists the addresses assigned to local interfaces.
+// It is a variable so tests can simulate which VIPs this node holds.
+var localInterfaceAddrs = net.InterfaceAddrs
+
+// statusAdapter implements admin.StatusProvider for cluster aggregation.
+type statusAdapter struct {
+	server *Server
+}
+
+// NodeStatus reports config identity, held VIPs, maintenance and backend health.
+func (a *statusAdapter) NodeStatus() admin.NodeStatus {
+	s := a.server
+	cfg := s.configMgr.GetConfig()
+	hostname, _ := os.Hostname()
+
+	serviceHashes := make(map[string]string, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		serviceHashes[svc.Name] = svc.Hash()
+	}
+
+	status := admin.NodeStatus{
+		Node:             hostname,
+		ConfigHash:       cfg.ServicesHash(),
+		ServiceHashes:    serviceHashes,
+		ConfigGeneration: s.configMgr.Generation(),
+		HeldVIPs:         heldVIPs(cfg),
+		Services:         len(cfg.Services),
+		Maintenance:      s.InMaintenance(),
+		Backends:         s.healthMgr.Snapshot(),
+	}
+	if since, err := s.failures.failingSince(); !since.IsZero() {
+		status.ReconcileFailingSince = &since
+		status.ReconcileError = err
+	}
+	return status
+}
+
+// heldVIPs returns the service listen IPs that are assigned to a local interface.
+// With keepalived-style failover only the active director holds the VIPs.
+func heldVIPs(cfg *config.Config) []string {
+	addrs, err := localInterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	local := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local[ipNet.IP.String()] = struct{}{}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	result := []string{}
+	for _, svc := range config.ExpandListens(cfg.Services) {
+		host, _, err := net.SplitHostPort(svc.Listen)
+		if err != nil {
+			continue
+		}
+		if _, ok := local[host]; !ok {
+			continue
+		}
+		if _, dup := seen[host]; dup {
+			continue
+		}
+		seen[host] = struct{}{}
+		result = append(result, host)
+	}
+	sort.Strings(result)
+	return result
+}
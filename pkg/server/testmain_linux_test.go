@@ -7,6 +7,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/easzlab/ezlb/pkg/config"
 	"github.com/easzlab/ezlb/pkg/lvs"
 )
 
@@ -24,7 +25,16 @@ func TestMain(m *testing.M) {
 	}
 	handle.Close()
 
+	// Keep the state files of test servers out of /var/lib/ezlb.
+	stateDir, err := os.MkdirTemp("", "ezlb-state-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create state dir: %v\n", err)
+		os.Exit(1)
+	}
+	config.DefaultStateDir = stateDir
+
 	code := m.Run()
+	os.RemoveAll(stateDir)
 
 	// Flush all IPVS rules after running tests to leave a clean state.
 	handle, err = lvs.NewIPVSHandle("")
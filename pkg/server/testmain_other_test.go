@@ -0,0 +1,26 @@
+//go:build !integration
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	// Keep the state files of test servers out of /var/lib/ezlb.
+	stateDir, err := os.MkdirTemp("", "ezlb-state-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create state dir: %v\n", err)
+		os.Exit(1)
+	}
+	config.DefaultStateDir = stateDir
+
+	code := m.Run()
+
+	os.RemoveAll(stateDir)
+	os.Exit(code)
+}
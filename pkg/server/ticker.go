@@ -0,0 +1,35 @@
+package server
+
+import "time"
+
+// intervalTicker is a ticker for an optional periodic task, such as the
+// resync or the drift check. A zero interval disables it, leaving C nil so
+// its select case never fires.
+type intervalTicker struct {
+	ticker   *time.Ticker
+	interval time.Duration
+	C        <-chan time.Time
+}
+
+// reset starts, restarts or stops the ticker for the given interval.
+func (t *intervalTicker) reset(interval time.Duration) {
+	if interval == t.interval {
+		return
+	}
+	t.stop()
+	t.interval = interval
+	if interval > 0 {
+		t.ticker = time.NewTicker(interval)
+		t.C = t.ticker.C
+	}
+}
+
+// stop stops the ticker.
+func (t *intervalTicker) stop() {
+	if t.ticker != nil {
+		t.ticker.Stop()
+	}
+	t.ticker = nil
+	t.interval = 0
+	t.C = nil
+}
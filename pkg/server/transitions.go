@@ -0,0 +1,144 @@
+package server
+
+import (
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/syslogsink"
+	"go.uber.org/zap"
+)
+
+// transition is a health state change forwarded to the syslog sink.
+type transition struct {
+	params   map[string]string
+	msgID    string
+	message  string
+	severity syslogsink.Severity
+}
+
+// recordHealthTransitions records an event for every backend whose health
+// status differs from the previously observed one, and for every service that
+// became degraded (some backends unhealthy) or was restored. When the syslog
+// sink is enabled the transitions are also sent there.
+func (s *Server) recordHealthTransitions(cfg *config.Config, statuses map[string]bool) {
+	s.healthMu.Lock()
+
+	backendToService := make(map[string]string)
+	for _, svc := range cfg.Services {
+		for _, backend := range svc.Backends {
+			backendToService[backend.Address] = svc.Name
+		}
+	}
+
+	var transitions []transition
+	for address, healthy := range statuses {
+		previous, known := s.lastHealth[address]
+		s.lastHealth[address] = healthy
+		// Newly tracked backends start healthy; only report them once they fail.
+		if (known && previous == healthy) || (!known && healthy) {
+			continue
+		}
+
+		params := map[string]string{"service": backendToService[address], "backend": address}
+		if healthy {
+			s.events.record("health", "backend %s is healthy", address)
+			params["state"] = "up"
+			transitions = append(transitions, transition{params: params, msgID: "BACKEND_UP", message: "backend " + address + " is up", severity: syslogsink.SeverityNotice})
+		} else {
+			s.events.record("health", "backend %s is unhealthy", address)
+			params["state"] = "down"
+			transitions = append(transitions, transition{params: params, msgID: "BACKEND_DOWN", message: "backend " + address + " is down", severity: syslogsink.SeverityWarning})
+		}
+	}
+	for address := range s.lastHealth {
+		if _, ok := statuses[address]; !ok {
+			delete(s.lastHealth, address)
+		}
+	}
+
+	services := make(map[string]struct{}, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		services[svc.Name] = struct{}{}
+		degraded := false
+		for _, backend := range svc.Backends {
+			if healthy, ok := statuses[backend.Address]; ok && !healthy {
+				degraded = true
+				break
+			}
+		}
+		if s.lastDegraded[svc.Name] == degraded {
+			continue
+		}
+		s.lastDegraded[svc.Name] = degraded
+
+		params := map[string]string{"service": svc.Name}
+		if degraded {
+			s.events.record("health", "service %s is degraded", svc.Name)
+			params["state"] = "degraded"
+			transitions = append(transitions, transition{params: params, msgID: "SERVICE_DEGRADED", message: "service " + svc.Name + " is degraded", severity: syslogsink.SeverityWarning})
+		} else {
+			s.events.record("health", "service %s is restored", svc.Name)
+			params["state"] = "restored"
+			transitions = append(transitions, transition{params: params, msgID: "SERVICE_RESTORED", message: "service " + svc.Name + " is restored", severity: syslogsink.SeverityNotice})
+		}
+	}
+	for name := range s.lastDegraded {
+		if _, ok := services[name]; !ok {
+			delete(s.lastDegraded, name)
+		}
+	}
+
+	sink := s.syslogSink
+	s.healthMu.Unlock()
+
+	// Send outside the lock so a slow collector does not stall health checks.
+	if sink == nil {
+		return
+	}
+	for _, t := range transitions {
+		if err := sink.Send(t.severity, t.msgID, t.message, t.params); err != nil {
+			s.logger.Warn("failed to send health transition to syslog", zap.String("msg_id", t.msgID), zap.Error(err))
+		}
+	}
+}
+
+// syncSyslogSink opens, replaces or closes the syslog sink according to
+// global.log.syslog.
+func (s *Server) syncSyslogSink(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+
+	syslogCfg := cfg.Global.Log.Syslog
+	var sink *syslogsink.Sink
+	if syslogCfg.IsEnabled() {
+		sink = syslogsink.New(syslogCfg)
+	}
+
+	s.healthMu.Lock()
+	previous := s.syslogSink
+	s.syslogSink = sink
+	s.healthMu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	if sink != nil && previous == nil {
+		s.logger.Info("syslog sink enabled for health transitions",
+			zap.String("network", syslogCfg.GetNetwork()),
+			zap.String("address", syslogCfg.GetAddress()),
+		)
+	} else if sink == nil && previous != nil {
+		s.logger.Info("syslog sink disabled")
+	}
+}
+
+// closeSyslogSink closes the syslog sink on shutdown.
+func (s *Server) closeSyslogSink() {
+	s.healthMu.Lock()
+	sink := s.syslogSink
+	s.syslogSink = nil
+	s.healthMu.Unlock()
+
+	if sink != nil {
+		sink.Close()
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+// tunnelDevice is the fallback ipip device the kernel creates when the ipip module is loaded.
+const tunnelDevice = "tunl0"
+
+var (
+	tunnelDeviceExists = func(name string) bool {
+		_, err := os.Stat("/sys/class/net/" + name)
+		return err == nil
+	}
+	writeKernelParamFile = func(path string, value []byte) error {
+		return os.WriteFile(path, value, 0644)
+	}
+	runSetupCommand = func(name string, args ...string) error {
+		return exec.Command(name, args...).Run()
+	}
+)
+
+// hasTunnelBackends reports whether any backend uses the tunnel forwarding method.
+func hasTunnelBackends(cfg *config.Config) bool {
+	for _, svc := range cfg.Services {
+		for _, backend := range svc.Backends {
+			if backend.GetForwardMethod() == "tunnel" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ensureTunnelSetup verifies (and, if global.tunnel_setup is enabled, prepares)
+// the director side of IPVS tunnel mode: the ipip module with its tunl0 device
+// up, and a reverse-path filter on tunl0 that does not drop decapsulated traffic.
+// It does nothing when no backend uses forward_method tunnel.
+func (s *Server) ensureTunnelSetup(cfg *config.Config) {
+	if !kernelParamCheckEnabled || s.logger == nil || cfg == nil || !hasTunnelBackends(cfg) {
+		return
+	}
+
+	setup := cfg.Global.IsTunnelSetup()
+
+	if !tunnelDeviceExists(tunnelDevice) {
+		if !setup {
+			s.logger.Error("tunnel backends configured but ipip device is missing, load the ipip module or enable global.tunnel_setup",
+				zap.String("device", tunnelDevice),
+			)
+			return
+		}
+		if err := runSetupCommand("modprobe", "ipip"); err != nil {
+			s.logger.Error("failed to load ipip module", zap.Error(err))
+			return
+		}
+		s.logger.Info("loaded ipip module for tunnel backends")
+	}
+
+	if setup {
+		if err := runSetupCommand("ip", "link", "set", tunnelDevice, "up"); err != nil {
+			s.logger.Error("failed to bring up tunnel device", zap.String("device", tunnelDevice), zap.Error(err))
+		}
+	}
+
+	check := kernelParamCheck{
+		name:      "net.ipv4.conf." + tunnelDevice + ".rp_filter",
+		expecteds: map[string]struct{}{"0": {}, "2": {}},
+	}
+	raw, err := readKernelParamFile(kernelParamPath(check.name))
+	if err != nil {
+		s.logger.Error("failed to read kernel parameter", zap.String("name", check.name), zap.Error(err))
+		return
+	}
+	actual := strings.TrimSpace(string(raw))
+	if check.isValid(actual) {
+		s.logger.Info("tunnel preflight passed", zap.String("device", tunnelDevice))
+		return
+	}
+
+	if !setup {
+		s.logger.Error("kernel parameter mismatch",
+			zap.String("name", check.name),
+			zap.String("expected", check.expectedString()),
+			zap.String("actual", actual),
+		)
+		return
+	}
+	if err := writeKernelParamFile(kernelParamPath(check.name), []byte("0\n")); err != nil {
+		s.logger.Error("failed to set kernel parameter", zap.String("name", check.name), zap.Error(err))
+		return
+	}
+	s.logger.Info("set kernel parameter for tunnel backends", zap.String("name", check.name), zap.String("value", "0"))
+}
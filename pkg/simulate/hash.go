@@ -0,0 +1,294 @@
+package simulate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// Table sizes of the IPVS hashing schedulers: IP_VS_SH_TAB_BITS and
+// IP_VS_DH_TAB_BITS are 8, and the mh table holds IP_VS_MH_TAB_SIZE entries,
+// the prime picked by the default CONFIG_IP_VS_MH_TAB_INDEX of 12.
+const (
+	shTableBits = 8
+	shTableSize = 1 << shTableBits
+	dhTableSize = shTableSize
+	mhTableSize = 4093
+	// mhTableBits is IP_VS_MH_TAB_BITS, the number of bits of the largest
+	// weight/gcd ratio that still counts in the mh turns.
+	mhTableBits = 6
+)
+
+// goldenRatio32 is the kernel's GOLDEN_RATIO_32 used by hash_32.
+const goldenRatio32 = 0x61C88647
+
+// The mh scheduler keys hsiphash with fixed secrets (generate_hash_secret),
+// so its table is the same on every host.
+var (
+	mhKey1 = [2]uint64{2654435761, 2654435761}
+	mhKey2 = [2]uint64{2654446892, 2654446892}
+)
+
+// destination is a backend as IPVS holds it in a service's destination list.
+type destination struct {
+	// backend is the index of the backend in the service config.
+	backend int
+	ip      net.IP
+	port    uint16
+	weight  int
+}
+
+// destinations returns the destinations IPVS holds for a service, in the
+// order of the kernel's destination list, with the weights they are
+// programmed with: the backends of the active priority tier, and backups at
+// weight 0 while a primary backend is active. Backends of less preferred
+// tiers are not in IPVS.
+//
+// ezlb creates destinations from the lowest to the highest weight and the
+// kernel adds each new one at the head of the list, so the list runs from
+// the highest weight down. This matches IPVS as long as the destinations were
+// created together, as when the service is created; a backend added to a
+// running service is at the head of the list whatever its weight.
+func destinations(svc config.ServiceConfig) ([]destination, error) {
+	backends := programmedBackends(svc)
+	standby := standbyBackends(backends)
+	var dests []destination
+	var keys []string
+	for i, backend := range backends {
+		if standby[i] && !backend.IsBackup() {
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(backend.Address)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: invalid backend address %q: %w", svc.Name, backend.Address, err)
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: invalid backend port %q: %w", svc.Name, portStr, err)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("service %q: invalid backend IP %q", svc.Name, host)
+		}
+		dest := destination{backend: i, ip: ip, port: uint16(port), weight: backend.GetWeight()}
+		if standby[i] {
+			dest.weight = 0
+		}
+		dests = append(dests, dest)
+		keys = append(keys, net.JoinHostPort(ip.String(), portStr))
+	}
+
+	// Creation order is by weight, then by address like sortedForCreate in
+	// the lvs package; the list is its reverse
+	order := make([]int, len(dests))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if dests[a].weight != dests[b].weight {
+			return dests[a].weight > dests[b].weight
+		}
+		return keys[a] > keys[b]
+	})
+	list := make([]destination, len(dests))
+	for i, index := range order {
+		list[i] = dests[index]
+	}
+	return list, nil
+}
+
+// shTable fills the sh bucket table like ip_vs_sh_reassign: walking the
+// destination list, each destination takes as many consecutive buckets as
+// its weight, at least one, cycling until the table is full.
+func shTable(dests []destination) [shTableSize]int {
+	var table [shTableSize]int
+	dest, used := 0, 0
+	for bucket := range table {
+		table[bucket] = dest
+		used++
+		if used >= dests[dest].weight {
+			dest = (dest + 1) % len(dests)
+			used = 0
+		}
+	}
+	return table
+}
+
+// dhTable fills the dh bucket table like ip_vs_dh_reassign: one bucket per
+// destination in list order, whatever its weight, cycling until the table is
+// full.
+func dhTable(dests []destination) [dhTableSize]int {
+	var table [dhTableSize]int
+	for bucket := range table {
+		table[bucket] = bucket % len(dests)
+	}
+	return table
+}
+
+// mhTable builds the Maglev lookup table like ip_vs_mh_permutate and
+// ip_vs_mh_populate. Each destination walks its own permutation of the
+// table, claiming free entries in turns proportional to its weight; entries
+// are -1 if no destination has a positive weight.
+//
+// The kernel computes the turns from each destination's last non-zero
+// weight. Here that is the programmed weight, as for destinations that were
+// created with it and never set to 0 since.
+func mhTable(dests []destination) [mhTableSize]int {
+	var table [mhTableSize]int
+	for i := range table {
+		table[i] = -1
+	}
+
+	divisor, maxWeight := 0, 0
+	for _, dest := range dests {
+		if dest.weight > 0 {
+			divisor = gcd(divisor, dest.weight)
+			maxWeight = max(maxWeight, dest.weight)
+		}
+	}
+	if divisor < 1 {
+		return table
+	}
+	// Only the top mhTableBits bits of the weight ratios count
+	shift := max(bits.Len(uint(maxWeight/divisor))-mhTableBits, 0)
+
+	perm := make([]int, len(dests))
+	skip := make([]int, len(dests))
+	turns := make([]int, len(dests))
+	for i, dest := range dests {
+		addr := foldIP(dest.ip)
+		perm[i] = int(mhHashKey(addr, dest.port, mhKey1) % mhTableSize)
+		skip[i] = int(mhHashKey(addr, dest.port, mhKey2)%(mhTableSize-1)) + 1
+		turns[i] = (dest.weight / divisor) >> shift
+		if turns[i] == 0 && dest.weight != 0 {
+			turns[i] = 1
+		}
+	}
+
+	filled, dest, taken := 0, 0, 0
+	for filled < mhTableSize {
+		if turns[dest] < 1 {
+			dest = (dest + 1) % len(dests)
+			continue
+		}
+		for table[perm[dest]] >= 0 {
+			perm[dest] = (perm[dest] + skip[dest]) % mhTableSize
+		}
+		table[perm[dest]] = dest
+		filled++
+		if taken++; taken >= turns[dest] {
+			dest = (dest + 1) % len(dests)
+			taken = 0
+		}
+	}
+	return table
+}
+
+// newHashTable builds the lookup table of the service's hashing scheduler
+// over its destination list.
+func newHashTable(scheduler string, dests []destination) []int {
+	switch scheduler {
+	case "sh":
+		table := shTable(dests)
+		return table[:]
+	case "dh":
+		table := dhTable(dests)
+		return table[:]
+	default:
+		table := mhTable(dests)
+		return table[:]
+	}
+}
+
+// hashBucket returns the table entry a hashing scheduler looks up for a
+// folded address: the client for sh and mh, the VIP for dh. ezlb sets
+// neither the sh-port nor the mh-port flag, so the client port is not
+// hashed.
+func hashBucket(scheduler string, addr uint32) int {
+	switch scheduler {
+	case "sh":
+		return shHashKey(addr)
+	case "dh":
+		return dhHashKey(addr)
+	default:
+		return int(mhHashKey(addr, 0, mhKey1) % mhTableSize)
+	}
+}
+
+// hash32 is the kernel's hash_32: a multiplication by GOLDEN_RATIO_32,
+// keeping the top bits.
+func hash32(value uint32, width int) uint32 {
+	return (value * goldenRatio32) >> (32 - width)
+}
+
+// shHashKey is ip_vs_sh_hashkey with an offset and port of 0.
+func shHashKey(addr uint32) int {
+	return int(hash32(addr, shTableBits) % shTableSize)
+}
+
+// dhHashKey is ip_vs_dh_hashkey; unlike sh it keeps the low bits of the
+// product.
+func dhHashKey(addr uint32) int {
+	return int(addr * 2654435761 & (dhTableSize - 1))
+}
+
+// mhHashKey is ip_vs_mh_hashkey with an offset of 0: hsiphash over the sum
+// of the port and the folded address.
+func mhHashKey(addr uint32, port uint16, key [2]uint64) uint32 {
+	return hsiphash32(uint32(port)+addr, key)
+}
+
+// hsiphash32 is the kernel's hsiphash of a 4-byte value on a 64-bit
+// little-endian host, where hsiphash is SipHash-1-3 truncated to 32 bits.
+func hsiphash32(value uint32, key [2]uint64) uint32 {
+	v0 := 0x736f6d6570736575 ^ key[0]
+	v1 := 0x646f72616e646f6d ^ key[1]
+	v2 := 0x6c7967656e657261 ^ key[0]
+	v3 := 0x7465646279746573 ^ key[1]
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	// The message is shorter than a block: only the length-tagged tail
+	b := uint64(4)<<56 | uint64(value)
+	v3 ^= b
+	round()
+	v0 ^= b
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	return uint32(v0 ^ v1 ^ v2 ^ v3)
+}
+
+// foldIP reduces an address to the 32-bit key the kernel hashes; IPv6
+// addresses are XOR-folded.
+func foldIP(ip net.IP) uint32 {
+	if ip4 := ip.To4(); ip4 != nil {
+		return binary.BigEndian.Uint32(ip4)
+	}
+	var folded uint32
+	for word := 0; word < net.IPv6len; word += 4 {
+		folded ^= binary.BigEndian.Uint32(ip[word:])
+	}
+	return folded
+}
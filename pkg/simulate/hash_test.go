@@ -0,0 +1,128 @@
+package simulate
+
+import (
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestHashKeys(t *testing.T) {
+	// Buckets of ip_vs_sh_hashkey, ip_vs_dh_hashkey and ip_vs_mh_hashkey
+	// with the port 0 ezlb uses
+	tests := []struct {
+		addr   string
+		sh     int
+		dh     int
+		maglev int
+	}{
+		{"203.0.113.5", 185, 117, 3219},
+		{"198.51.100.20", 162, 212, 2366},
+		{"10.0.0.1", 39, 177, 3711},
+		{"2001:db8:ffff::5", 115, 173, 3192},
+	}
+	for _, tt := range tests {
+		addr := foldIP(net.ParseIP(tt.addr))
+		if got := hashBucket("sh", addr); got != tt.sh {
+			t.Errorf("%s: expected sh bucket %d, got %d", tt.addr, tt.sh, got)
+		}
+		if got := hashBucket("dh", addr); got != tt.dh {
+			t.Errorf("%s: expected dh bucket %d, got %d", tt.addr, tt.dh, got)
+		}
+		if got := hashBucket("mh", addr); got != tt.maglev {
+			t.Errorf("%s: expected mh bucket %d, got %d", tt.addr, tt.maglev, got)
+		}
+	}
+}
+
+func TestHsiphash32(t *testing.T) {
+	// SipHash-1-3 with a zero key, as computed by CPython's siphash13
+	tests := []struct {
+		value uint32
+		want  uint32
+	}{
+		{0, 2596571888},
+		{1, 3279646864},
+		{0xc0a80101, 1752132769},
+		{0xdeadbeef, 2231075411},
+	}
+	for _, tt := range tests {
+		if got := hsiphash32(tt.value, [2]uint64{}); got != tt.want {
+			t.Errorf("hsiphash32(%#x): expected %d, got %d", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestDestinations_KernelListOrder(t *testing.T) {
+	// Created by ascending weight and address, each one at the list head
+	dests, err := destinations(config.ServiceConfig{Name: "web", Backends: []config.BackendConfig{
+		{Address: "192.168.1.1:80", Weight: intPtr(2)},
+		{Address: "192.168.1.2:80", Weight: intPtr(5)},
+		{Address: "192.168.1.3:80", Weight: intPtr(2)},
+		{Address: "192.168.1.4:80", Weight: intPtr(9), Priority: 1},
+		{Address: "192.168.1.5:80", Weight: intPtr(7), Role: config.RoleBackup},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		backend int
+		weight  int
+	}{{1, 5}, {2, 2}, {0, 2}, {4, 0}}
+	if len(dests) != len(want) {
+		t.Fatalf("expected %d destinations, got %+v", len(want), dests)
+	}
+	for i, dest := range dests {
+		if dest.backend != want[i].backend || dest.weight != want[i].weight {
+			t.Errorf("destination %d: expected backend %d with weight %d, got %+v", i, want[i].backend, want[i].weight, dest)
+		}
+	}
+}
+
+func TestHashTables(t *testing.T) {
+	dests := []destination{
+		{ip: net.ParseIP("192.168.1.3"), port: 80, weight: 3},
+		{ip: net.ParseIP("192.168.1.2"), port: 80, weight: 1},
+		{ip: net.ParseIP("192.168.1.1"), port: 80, weight: 0},
+	}
+
+	// sh: runs as long as the weight, one bucket for weight 0
+	sh := shTable(dests)
+	if want := []int{0, 0, 0, 1, 2, 0, 0, 0}; !slices.Equal(sh[:len(want)], want) {
+		t.Errorf("expected sh buckets %v, got %v", want, sh[:len(want)])
+	}
+	// dh: one bucket each, whatever the weight
+	dh := dhTable(dests)
+	if want := []int{0, 1, 2, 0, 1, 2}; !slices.Equal(dh[:len(want)], want) {
+		t.Errorf("expected dh buckets %v, got %v", want, dh[:len(want)])
+	}
+	// mh: weight 0 claims no entry, the rest split 3:1
+	maglev := mhTable(dests)
+	if want := []int{0, 1, 0, 0, 0, 0, 0, 1, 1, 0}; !slices.Equal(maglev[:len(want)], want) {
+		t.Errorf("expected mh entries %v, got %v", want, maglev[:len(want)])
+	}
+	counts := make([]int, len(dests))
+	for _, dest := range maglev {
+		counts[dest]++
+	}
+	if counts[0] != 3070 || counts[1] != 1023 || counts[2] != 0 {
+		t.Errorf("expected mh entries 3070/1023/0, got %v", counts)
+	}
+}
+
+func TestMhTable_ShiftsLargeWeightRatios(t *testing.T) {
+	// A 200:7:5 ratio needs 8 bits; the turns keep the top 6 (50:1:1)
+	maglev := mhTable([]destination{
+		{ip: net.ParseIP("2001:db8::10"), port: 443, weight: 200},
+		{ip: net.ParseIP("10.1.2.3"), port: 8080, weight: 7},
+		{ip: net.ParseIP("10.1.2.4"), port: 8080, weight: 5},
+	})
+	counts := make([]int, 3)
+	for _, dest := range maglev {
+		counts[dest]++
+	}
+	if counts[0] != 3937 || counts[1] != 78 || counts[2] != 78 {
+		t.Errorf("expected mh entries 3937/78/78, got %v", counts)
+	}
+}
@@ -0,0 +1,150 @@
+package simulate
+
+import (
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// Mapping is the backend a hashing scheduler picks for one client.
+type Mapping struct {
+	Service   string
+	Scheduler string
+	Client    net.IP
+	// HashedAddress is the address the scheduler hashes: the client for sh
+	// and mh, the VIP for dh.
+	HashedAddress net.IP
+	Backend       string
+	Bucket        int
+	ClientPort    int
+	// Dropped is set when Backend has weight 0: IPVS finds no destination
+	// for the connection and drops it.
+	Dropped bool
+}
+
+// Preview computes which backend a client is sent to under the service's sh,
+// dh or mh scheduler and configured weights, assuming every backend of the
+// most preferred priority tier is healthy. The tables are built like the
+// kernel builds them over the service's destination list, whose order
+// depends on how the destinations were created (see destinations).
+//
+// ezlb sets neither the sh-port nor the mh-port scheduler flag, so the client
+// port does not influence the result; it is only reported back.
+func Preview(svc config.ServiceConfig, client net.IP, clientPort int) (Mapping, error) {
+	if client == nil {
+		return Mapping{}, fmt.Errorf("client IP is required")
+	}
+	// A dual-stack client connects to the VIPs of its own address family
+	if halves := config.SplitDualStack(svc); len(halves) > 1 {
+		svc = halves[0]
+		if client.To4() == nil {
+			svc = halves[1]
+		}
+	}
+
+	mapping := Mapping{
+		Service:    svc.Name,
+		Scheduler:  svc.Scheduler,
+		Client:     client,
+		ClientPort: clientPort,
+	}
+	switch svc.Scheduler {
+	case "sh", "mh":
+		mapping.HashedAddress = client
+	case "dh":
+		if svc.FWMark != 0 {
+			return Mapping{}, fmt.Errorf("service %q: dh cannot be previewed for a fwmark service without a VIP", svc.Name)
+		}
+		// With several listen addresses, the first VIP is previewed
+		ips, err := listenIPs(svc)
+		if err != nil {
+			return Mapping{}, err
+		}
+		mapping.HashedAddress = ips[0]
+	default:
+		return Mapping{}, fmt.Errorf("service %q: scheduler %q does not hash, only sh, dh and mh can be previewed", svc.Name, svc.Scheduler)
+	}
+
+	if len(activeBackends(programmedBackends(svc))) == 0 {
+		return Mapping{}, fmt.Errorf("service %q has no backend with a positive weight", svc.Name)
+	}
+	dests, err := destinations(svc)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	mapping.Bucket = hashBucket(svc.Scheduler, foldIP(mapping.HashedAddress))
+	dest := dests[newHashTable(svc.Scheduler, dests)[mapping.Bucket]]
+	mapping.Backend = svc.Backends[dest.backend].Address
+	mapping.Dropped = dest.weight <= 0
+	return mapping, nil
+}
+
+// listenIPs returns the VIPs of a service's listen addresses.
+func listenIPs(svc config.ServiceConfig) ([]net.IP, error) {
+	listens, err := svc.ListenAddresses()
+	if err != nil {
+		return nil, fmt.Errorf("service %q: %w", svc.Name, err)
+	}
+	if len(listens) == 0 {
+		return nil, fmt.Errorf("service %q: listen is required", svc.Name)
+	}
+	ips := make([]net.IP, len(listens))
+	for i, listen := range listens {
+		host, _, err := net.SplitHostPort(listen)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: invalid listen address %q: %w", svc.Name, listen, err)
+		}
+		if ips[i] = net.ParseIP(host); ips[i] == nil {
+			return nil, fmt.Errorf("service %q: invalid listen IP %q", svc.Name, host)
+		}
+	}
+	return ips, nil
+}
+
+// programmedBackends returns a service's backends with the weights IPVS is
+// programmed with, scaled down if some exceed the service's max_weight.
+func programmedBackends(svc config.ServiceConfig) []config.BackendConfig {
+	backends := slices.Clone(svc.Backends)
+	for i, weight := range config.NormalizeWeights(svc) {
+		backends[i].Weight = &weight.Scaled
+	}
+	return backends
+}
+
+// activeBackends returns the indexes of the backends that receive traffic:
+// those not held in reserve with a positive weight.
+func activeBackends(backends []config.BackendConfig) []int {
+	standby := standbyBackends(backends)
+	var active []int
+	for i, backend := range backends {
+		if !standby[i] && backend.GetWeight() > 0 {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+// standbyBackends reports for each backend whether it is held in reserve:
+// primary backends of a less preferred priority tier, and backup backends
+// while the service has a primary backend. Backups ignore priority.
+func standbyBackends(backends []config.BackendConfig) []bool {
+	activePriority := -1
+	for _, backend := range backends {
+		if !backend.IsBackup() && (activePriority < 0 || backend.Priority < activePriority) {
+			activePriority = backend.Priority
+		}
+	}
+
+	standby := make([]bool, len(backends))
+	for i, backend := range backends {
+		if backend.IsBackup() {
+			standby[i] = activePriority >= 0
+		} else {
+			standby[i] = backend.Priority != activePriority
+		}
+	}
+	return standby
+}
@@ -0,0 +1,178 @@
+package simulate
+
+import (
+	"net"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestPreview_SourceHash(t *testing.T) {
+	svc := makeService("sh",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)},
+		config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(1)},
+	)
+	tests := []struct {
+		client     string
+		wantBucket int
+		want       string
+	}{
+		// Equal weights alternate buckets between the backends, starting
+		// with the one created last
+		{"203.0.113.5", 185, "192.168.1.1:80"},
+		{"203.0.113.6", 26, "192.168.1.2:80"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.client, func(t *testing.T) {
+			mapping, err := Preview(svc, net.ParseIP(tt.client), 51234)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mapping.Bucket != tt.wantBucket || mapping.Backend != tt.want {
+				t.Errorf("expected bucket %d -> %s, got bucket %d -> %s", tt.wantBucket, tt.want, mapping.Bucket, mapping.Backend)
+			}
+			if !mapping.HashedAddress.Equal(net.ParseIP(tt.client)) {
+				t.Errorf("expected the client address to be hashed, got %s", mapping.HashedAddress)
+			}
+		})
+	}
+}
+
+func TestPreview_WeightZeroKeepsBuckets(t *testing.T) {
+	// The weight-0 backend takes one bucket in three after the two of
+	// 192.168.1.2; clients hashed to it are dropped. The standby tier is not
+	// in IPVS and takes none
+	svc := makeService("sh",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(0)},
+		config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(2)},
+		config.BackendConfig{Address: "192.168.1.3:80", Weight: intPtr(5), Priority: 1},
+	)
+	tests := []struct {
+		client      string
+		wantBucket  int
+		want        string
+		wantDropped bool
+	}{
+		{"198.51.100.20", 162, "192.168.1.2:80", false},
+		{"198.51.100.2", 194, "192.168.1.1:80", true},
+	}
+	for _, tt := range tests {
+		mapping, err := Preview(svc, net.ParseIP(tt.client), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mapping.Bucket != tt.wantBucket || mapping.Backend != tt.want || mapping.Dropped != tt.wantDropped {
+			t.Errorf("client %s: expected bucket %d -> %s (dropped %v), got bucket %d -> %s (dropped %v)",
+				tt.client, tt.wantBucket, tt.want, tt.wantDropped, mapping.Bucket, mapping.Backend, mapping.Dropped)
+		}
+	}
+
+	result, err := Run(svc, 3000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Dropped < 900 || result.Dropped > 1100 {
+		t.Errorf("expected about a third of the connections dropped, got %d", result.Dropped)
+	}
+}
+
+func TestPreview_Maglev(t *testing.T) {
+	svc := makeService("mh",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)},
+		config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(1)},
+	)
+	tests := []struct {
+		client     string
+		wantBucket int
+		want       string
+	}{
+		{"203.0.113.5", 3219, "192.168.1.1:80"},
+		{"203.0.113.7", 1705, "192.168.1.2:80"},
+	}
+	for _, tt := range tests {
+		mapping, err := Preview(svc, net.ParseIP(tt.client), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mapping.Bucket != tt.wantBucket || mapping.Backend != tt.want {
+			t.Errorf("client %s: expected bucket %d -> %s, got bucket %d -> %s", tt.client, tt.wantBucket, tt.want, mapping.Bucket, mapping.Backend)
+		}
+	}
+}
+
+func TestPreview_DestinationHashIgnoresClient(t *testing.T) {
+	svc := makeService("dh",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(3)},
+		config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(1)},
+	)
+	first, err := Preview(svc, net.ParseIP("203.0.113.5"), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := Preview(svc, net.ParseIP("203.0.113.6"), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Backend != second.Backend || first.Bucket != second.Bucket {
+		t.Errorf("expected every client on the same backend, got %s and %s", first.Backend, second.Backend)
+	}
+	// The VIP 10.0.0.1 hashes to bucket 177; dh ignores weights and gives
+	// odd buckets to the second destination in the list
+	if first.Bucket != 177 || first.Backend != "192.168.1.2:80" {
+		t.Errorf("expected bucket 177 -> 192.168.1.2:80, got bucket %d -> %s", first.Bucket, first.Backend)
+	}
+}
+
+func TestPreview_DualStackUsesClientFamily(t *testing.T) {
+	svc := makeService("sh",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)},
+		config.BackendConfig{Address: "[2001:db8::10]:80", Weight: intPtr(1)},
+	)
+	svc.ListenV6 = "[2001:db8::1]:80"
+	tests := []struct {
+		client string
+		want   string
+	}{
+		{"203.0.113.5", "192.168.1.1:80"},
+		{"2001:db8:ffff::5", "[2001:db8::10]:80"},
+	}
+	for _, tt := range tests {
+		mapping, err := Preview(svc, net.ParseIP(tt.client), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mapping.Backend != tt.want {
+			t.Errorf("expected client %s to map to %s, got %s", tt.client, tt.want, mapping.Backend)
+		}
+	}
+}
+
+func TestPreview_InvalidInput(t *testing.T) {
+	backend := config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)}
+	fwmark := makeService("dh", backend)
+	fwmark.Listen = ""
+	fwmark.FWMark = 1
+
+	tests := []struct {
+		name   string
+		svc    config.ServiceConfig
+		client net.IP
+	}{
+		{"no client", makeService("sh", backend), nil},
+		{"round robin", makeService("rr", backend), net.ParseIP("203.0.113.5")},
+		{"dh fwmark", fwmark, net.ParseIP("203.0.113.5")},
+		{"no weight", makeService("sh", config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(0)}), net.ParseIP("203.0.113.5")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Preview(tt.svc, tt.client, 0); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// intPtr creates a pointer to an int value.
+func intPtr(i int) *int {
+	return &i
+}
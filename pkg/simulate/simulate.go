@@ -0,0 +1,223 @@
+// Package simulate estimates how an IPVS scheduler distributes new
+// connections across a service's backends, so operators can sanity-check
+// weights before applying a config.
+//
+// The model is static: every backend is healthy, connections never close and
+// each connection comes from a different client address. Only the most
+// preferred priority tier receives traffic, as in the reconciler.
+package simulate
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// clientSeed makes the client addresses used by the sh scheduler reproducible.
+const clientSeed = 1
+
+// BackendShare is the simulated load of one backend.
+type BackendShare struct {
+	Address     string
+	Weight      int
+	Priority    int
+	Connections int
+	// Share is the fraction of all simulated connections.
+	Share float64
+	// WeightShare is the fraction the backend's weight represents within its
+	// active tier, i.e. what a perfectly weighted scheduler would give it.
+	WeightShare float64
+	// Standby is set for backends of a less preferred priority tier and for
+	// backup backends of a service with primary backends.
+	Standby bool
+}
+
+// Result is the simulated distribution of a service's connections.
+type Result struct {
+	Service   string
+	Scheduler string
+	Backends  []BackendShare
+	Requests  int
+	// Dropped counts the connections a hashing scheduler maps to a backend
+	// of weight 0, for which IPVS finds no destination.
+	Dropped int
+}
+
+// Run simulates the given number of new connections to a service.
+func Run(svc config.ServiceConfig, requests int) (Result, error) {
+	if requests <= 0 {
+		return Result{}, fmt.Errorf("requests must be positive, got %d", requests)
+	}
+	if len(svc.Backends) == 0 {
+		return Result{}, fmt.Errorf("service %q has no backends", svc.Name)
+	}
+
+	backends := programmedBackends(svc)
+	standby := standbyBackends(backends)
+	result := Result{Service: svc.Name, Scheduler: svc.Scheduler, Requests: requests}
+	for i, backend := range backends {
+		result.Backends = append(result.Backends, BackendShare{
+			Address:  backend.Address,
+			Weight:   backend.GetWeight(),
+			Priority: backend.Priority,
+			Standby:  standby[i],
+		})
+	}
+	active := activeBackends(backends)
+	totalWeight := 0
+	for _, i := range active {
+		totalWeight += backends[i].GetWeight()
+	}
+	if len(active) == 0 {
+		return result, nil
+	}
+	for _, i := range active {
+		result.Backends[i].WeightShare = float64(result.Backends[i].Weight) / float64(totalWeight)
+	}
+
+	counts := make([]int, len(backends))
+	switch svc.Scheduler {
+	case "sh", "dh", "mh":
+		hashed, dropped, err := distributeHashed(svc, requests)
+		if err != nil {
+			return Result{}, err
+		}
+		counts, result.Dropped = hashed, dropped
+	default:
+		weights := make([]int, len(active))
+		for i, backendIndex := range active {
+			weights[i] = result.Backends[backendIndex].Weight
+		}
+		weighted, err := distribute(svc, weights, requests)
+		if err != nil {
+			return Result{}, err
+		}
+		for i, backendIndex := range active {
+			counts[backendIndex] = weighted[i]
+		}
+	}
+	for i, count := range counts {
+		result.Backends[i].Connections = count
+		result.Backends[i].Share = float64(count) / float64(requests)
+	}
+	return result, nil
+}
+
+// distribute returns the number of connections each weighted destination
+// receives under the service's scheduler.
+func distribute(svc config.ServiceConfig, weights []int, requests int) ([]int, error) {
+	counts := make([]int, len(weights))
+	switch svc.Scheduler {
+	case "rr", "lc":
+		// With connections that never close, lc degenerates into rr.
+		for i := 0; i < requests; i++ {
+			counts[i%len(weights)]++
+		}
+	case "wrr":
+		next := newWRR(weights)
+		for i := 0; i < requests; i++ {
+			counts[next()]++
+		}
+	case "wlc":
+		for i := 0; i < requests; i++ {
+			counts[leastWeightedConnections(counts, weights)]++
+		}
+	default:
+		return nil, fmt.Errorf("service %q: unsupported scheduler %q", svc.Name, svc.Scheduler)
+	}
+	return counts, nil
+}
+
+// distributeHashed returns the number of connections each backend receives
+// under the service's sh, dh or mh scheduler, and the number dropped because
+// they hash to a destination of weight 0.
+func distributeHashed(svc config.ServiceConfig, requests int) ([]int, int, error) {
+	dests, err := destinations(svc)
+	if err != nil {
+		return nil, 0, err
+	}
+	table := newHashTable(svc.Scheduler, dests)
+	counts := make([]int, len(svc.Backends))
+	dropped := 0
+	assign := func(addr uint32, connections int) {
+		dest := table[hashBucket(svc.Scheduler, addr)]
+		if dest < 0 || dests[dest].weight <= 0 {
+			dropped += connections
+			return
+		}
+		counts[dests[dest].backend] += connections
+	}
+
+	if svc.Scheduler != "dh" {
+		clients := rand.New(rand.NewSource(clientSeed))
+		for i := 0; i < requests; i++ {
+			assign(clients.Uint32(), 1)
+		}
+		return counts, dropped, nil
+	}
+	// Every connection to a VIP hashes to the same bucket; connections are
+	// spread evenly over the listen addresses.
+	if svc.FWMark != 0 {
+		return nil, 0, fmt.Errorf("service %q: dh cannot be simulated for a fwmark service without a VIP", svc.Name)
+	}
+	ips, err := listenIPs(svc)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, ip := range ips {
+		share := requests / len(ips)
+		if i < requests%len(ips) {
+			share++
+		}
+		assign(foldIP(ip), share)
+	}
+	return counts, dropped, nil
+}
+
+// newWRR returns a picker following the kernel's interleaved weighted
+// round-robin (ip_vs_wrr.c): the current weight steps down by the weights'
+// GCD each round and destinations at or above it are picked in order.
+func newWRR(weights []int) func() int {
+	maxWeight, step := 0, 0
+	for _, weight := range weights {
+		maxWeight = max(maxWeight, weight)
+		step = gcd(step, weight)
+	}
+	index, current := -1, maxWeight
+	return func() int {
+		for {
+			index++
+			if index == len(weights) {
+				// Passed the list head: start the next, lighter round
+				index = 0
+				current -= step
+				if current <= 0 {
+					current = maxWeight
+				}
+			}
+			if weights[index] >= current {
+				return index
+			}
+		}
+	}
+}
+
+// leastWeightedConnections picks the destination with the lowest
+// connections/weight ratio, preferring the earliest on ties like ip_vs_wlc.c.
+func leastWeightedConnections(counts, weights []int) int {
+	least := 0
+	for i := 1; i < len(weights); i++ {
+		if counts[least]*weights[i] > counts[i]*weights[least] {
+			least = i
+		}
+	}
+	return least
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
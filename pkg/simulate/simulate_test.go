@@ -0,0 +1,130 @@
+package simulate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func makeService(scheduler string, backends ...config.BackendConfig) config.ServiceConfig {
+	return config.ServiceConfig{Name: "web", Listen: "10.0.0.1:80", Protocol: "tcp", Scheduler: scheduler, Backends: backends}
+}
+
+func connections(result Result) []int {
+	counts := make([]int, len(result.Backends))
+	for i, backend := range result.Backends {
+		counts[i] = backend.Connections
+	}
+	return counts
+}
+
+func TestRun_Schedulers(t *testing.T) {
+	backends := []config.BackendConfig{
+		{Address: "192.168.1.1:80", Weight: intPtr(3)},
+		{Address: "192.168.1.2:80", Weight: intPtr(1)},
+	}
+	tests := []struct {
+		scheduler string
+		want      []int
+	}{
+		{"rr", []int{500, 500}},
+		{"lc", []int{500, 500}},
+		{"wrr", []int{750, 250}},
+		{"wlc", []int{750, 250}},
+		// Every connection goes to the same VIP, hence the same bucket
+		{"dh", []int{0, 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheduler, func(t *testing.T) {
+			result, err := Run(makeService(tt.scheduler, backends...), 1000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := connections(result)
+			if got[0]+got[1] != 1000 || (tt.want != nil && (got[0] != tt.want[0] || got[1] != tt.want[1])) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRun_HashingFollowsWeights(t *testing.T) {
+	for _, scheduler := range []string{"sh", "mh"} {
+		t.Run(scheduler, func(t *testing.T) {
+			result, err := Run(makeService(scheduler,
+				config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(3)},
+				config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(1)},
+			), 100000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, backend := range result.Backends {
+				if math.Abs(backend.Share-backend.WeightShare) > 0.02 {
+					t.Errorf("backend %s: share %.3f too far from weight share %.3f", backend.Address, backend.Share, backend.WeightShare)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_StandbyTierReceivesNothing(t *testing.T) {
+	result, err := Run(makeService("wrr",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)},
+		config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(1)},
+		config.BackendConfig{Address: "192.168.1.3:80", Weight: intPtr(5), Priority: 1},
+	), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	standby := result.Backends[2]
+	if !standby.Standby || standby.Connections != 0 || standby.WeightShare != 0 {
+		t.Errorf("expected idle standby backend, got %+v", standby)
+	}
+	if result.Backends[0].WeightShare != 0.5 {
+		t.Errorf("expected weight share within the active tier, got %v", result.Backends[0].WeightShare)
+	}
+}
+
+func TestRun_BackupReceivesNothing(t *testing.T) {
+	result, err := Run(makeService("wrr",
+		config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1), Priority: 1},
+		config.BackendConfig{Address: "192.168.1.2:80", Weight: intPtr(5), Role: config.RoleBackup},
+	), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if backup := result.Backends[1]; !backup.Standby || backup.Connections != 0 {
+		t.Errorf("expected idle backup backend, got %+v", backup)
+	}
+	if primary := result.Backends[0]; primary.Standby || primary.Connections != 10 {
+		t.Errorf("expected the primary to take every connection, got %+v", primary)
+	}
+}
+
+func TestRun_InvalidInput(t *testing.T) {
+	if _, err := Run(makeService("rr", config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)}), 0); err == nil {
+		t.Error("expected error for zero requests")
+	}
+	if _, err := Run(makeService("rr"), 10); err == nil {
+		t.Error("expected error for a service without backends")
+	}
+	if _, err := Run(makeService("sed", config.BackendConfig{Address: "192.168.1.1:80", Weight: intPtr(1)}), 10); err == nil {
+		t.Error("expected error for an unsupported scheduler")
+	}
+}
+
+func TestNewWRR_Interleaves(t *testing.T) {
+	next := newWRR([]int{4, 3, 2})
+	var got []int
+	for i := 0; i < 9; i++ {
+		got = append(got, next())
+	}
+	// Kernel order for weights 4,3,2: a a b a b c a b c
+	want := []int{0, 0, 1, 0, 1, 2, 0, 1, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected sequence %v, got %v", want, got)
+		}
+	}
+}
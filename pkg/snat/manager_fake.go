@@ -13,19 +13,36 @@ import (
 type FakeManager struct {
 	managed        map[string]SNATRule
 	managedForward map[string]ForwardRule
+	managedMark    map[string]MarkRule
+	managedDSCP    map[string]DSCPRule
+	chains         Chains
 	logger         *zap.Logger
 	mu             sync.Mutex
 }
 
 // NewManager creates a fake in-memory SNAT Manager for non-Linux systems.
 func NewManager(logger *zap.Logger) (Manager, error) {
+	return NewManagerForInstance("", logger)
+}
+
+// NewManagerForInstance creates a fake SNAT Manager for a named ezlb
+// instance. It records the instance's chain names (see ChainsFor).
+func NewManagerForInstance(instance string, logger *zap.Logger) (Manager, error) {
 	return &FakeManager{
+		chains:         ChainsFor(instance),
 		managed:        make(map[string]SNATRule),
 		managedForward: make(map[string]ForwardRule),
+		managedMark:    make(map[string]MarkRule),
+		managedDSCP:    make(map[string]DSCPRule),
 		logger:         logger,
 	}, nil
 }
 
+// Chains returns the names of the chains the manager would own.
+func (m *FakeManager) Chains() Chains {
+	return m.chains
+}
+
 // Reconcile compares desired SNAT rules with the currently managed set in memory.
 func (m *FakeManager) Reconcile(desired []SNATRule) error {
 	m.mu.Lock()
@@ -47,7 +64,7 @@ func (m *FakeManager) Reconcile(desired []SNATRule) error {
 	// Add or update rules
 	for key, rule := range desiredMap {
 		existing, exists := m.managed[key]
-		if exists && existing.SnatIP == rule.SnatIP {
+		if exists && existing == rule {
 			continue
 		}
 		m.managed[key] = rule
@@ -75,9 +92,9 @@ func (m *FakeManager) ReconcileForward(desired []ForwardRule) error {
 		}
 	}
 
-	// Add missing rules
+	// Add missing or changed rules
 	for key, rule := range desiredMap {
-		if _, exists := m.managedForward[key]; exists {
+		if existing, exists := m.managedForward[key]; exists && existing == rule {
 			continue
 		}
 		m.managedForward[key] = rule
@@ -87,14 +104,78 @@ func (m *FakeManager) ReconcileForward(desired []ForwardRule) error {
 	return nil
 }
 
-// Cleanup removes all managed SNAT and FORWARD rules from memory.
+// ReconcileMark compares desired MARK rules with the currently managed set in memory.
+func (m *FakeManager) ReconcileMark(desired []MarkRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	desiredMap := make(map[string]MarkRule, len(desired))
+	for _, rule := range desired {
+		desiredMap[rule.Key()] = rule
+	}
+
+	// Remove stale rules
+	for key := range m.managedMark {
+		if _, exists := desiredMap[key]; !exists {
+			delete(m.managedMark, key)
+			m.logger.Debug("fake: deleted MARK rule", zap.String("key", key))
+		}
+	}
+
+	// Add or update rules
+	for key, rule := range desiredMap {
+		existing, exists := m.managedMark[key]
+		if exists && existing.Mark == rule.Mark {
+			continue
+		}
+		m.managedMark[key] = rule
+		m.logger.Debug("fake: added MARK rule", zap.String("key", key), zap.Uint32("mark", rule.Mark))
+	}
+
+	return nil
+}
+
+// ReconcileDSCP compares desired DSCP rules with the currently managed set in memory.
+func (m *FakeManager) ReconcileDSCP(desired []DSCPRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	desiredMap := make(map[string]DSCPRule, len(desired))
+	for _, rule := range desired {
+		desiredMap[rule.Key()] = rule
+	}
+
+	// Remove stale rules
+	for key := range m.managedDSCP {
+		if _, exists := desiredMap[key]; !exists {
+			delete(m.managedDSCP, key)
+			m.logger.Debug("fake: deleted DSCP rule", zap.String("key", key))
+		}
+	}
+
+	// Add or update rules
+	for key, rule := range desiredMap {
+		existing, exists := m.managedDSCP[key]
+		if exists && existing.DSCP == rule.DSCP {
+			continue
+		}
+		m.managedDSCP[key] = rule
+		m.logger.Debug("fake: added DSCP rule", zap.String("key", key), zap.Uint8("dscp", rule.DSCP))
+	}
+
+	return nil
+}
+
+// Cleanup removes all managed SNAT, FORWARD, MARK and DSCP rules from memory.
 func (m *FakeManager) Cleanup() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.managed = make(map[string]SNATRule)
 	m.managedForward = make(map[string]ForwardRule)
-	m.logger.Debug("fake: cleaned up all SNAT and FORWARD rules")
+	m.managedMark = make(map[string]MarkRule)
+	m.managedDSCP = make(map[string]DSCPRule)
+	m.logger.Debug("fake: cleaned up all SNAT, FORWARD, MARK and DSCP rules")
 	return nil
 }
 
@@ -121,3 +202,27 @@ func (m *FakeManager) GetManagedForward() map[string]ForwardRule {
 	}
 	return result
 }
+
+// GetManagedMark returns a copy of the currently managed MARK rules (for testing).
+func (m *FakeManager) GetManagedMark() map[string]MarkRule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]MarkRule, len(m.managedMark))
+	for k, v := range m.managedMark {
+		result[k] = v
+	}
+	return result
+}
+
+// GetManagedDSCP returns a copy of the currently managed DSCP rules (for testing).
+func (m *FakeManager) GetManagedDSCP() map[string]DSCPRule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]DSCPRule, len(m.managedDSCP))
+	for k, v := range m.managedDSCP {
+		result[k] = v
+	}
+	return result
+}
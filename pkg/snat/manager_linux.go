@@ -12,23 +12,39 @@ import (
 )
 
 const (
-	natTable     = "nat"
-	filterTable  = "filter"
-	snatChain    = "EZLB-SNAT"
-	forwardChain = "EZLB-FORWARD"
+	natTable    = "nat"
+	filterTable = "filter"
+	mangleTable = "mangle"
 )
 
+// dscpHooks are the mangle chains that jump to EZLB-DSCP: PREROUTING sees
+// client packets to the VIP, POSTROUTING the de-NATed replies from it.
+var dscpHooks = []string{"PREROUTING", "POSTROUTING"}
+
 // linuxManager manages iptables SNAT and FORWARD rules on Linux using coreos/go-iptables.
 type linuxManager struct {
 	ipt            *iptables.IPTables
+	chains         Chains
 	managed        map[string]SNATRule
 	managedForward map[string]ForwardRule
-	mu             sync.Mutex
-	logger         *zap.Logger
+	managedMark    map[string]MarkRule
+	managedDSCP    map[string]DSCPRule
+	// chainsReady is set once the chains and their jump rules exist.
+	chainsReady bool
+	mu          sync.Mutex
+	logger      *zap.Logger
 }
 
 // NewManager creates a new SNAT Manager backed by real iptables operations.
 func NewManager(logger *zap.Logger) (Manager, error) {
+	return NewManagerForInstance("", logger)
+}
+
+// NewManagerForInstance creates a SNAT Manager for a named ezlb instance,
+// owning the instance's chains (see ChainsFor). The chains and their jump
+// rules are created by the first Reconcile call, so a Manager that only
+// plans, observes or is read-only never changes iptables.
+func NewManagerForInstance(instance string, logger *zap.Logger) (Manager, error) {
 	ipt, err := iptables.New()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create iptables handle: %w", err)
@@ -36,36 +52,52 @@ func NewManager(logger *zap.Logger) (Manager, error) {
 
 	mgr := &linuxManager{
 		ipt:            ipt,
+		chains:         ChainsFor(instance),
 		managed:        make(map[string]SNATRule),
 		managedForward: make(map[string]ForwardRule),
+		managedMark:    make(map[string]MarkRule),
+		managedDSCP:    make(map[string]DSCPRule),
 		logger:         logger,
 	}
+	return mgr, nil
+}
 
-	if err := mgr.ensureChain(); err != nil {
-		return nil, fmt.Errorf("failed to initialize SNAT chain: %w", err)
+// ensureChains creates the managed chains and their jump rules unless they
+// were created already. The caller must hold m.mu.
+func (m *linuxManager) ensureChains() error {
+	if m.chainsReady {
+		return nil
 	}
-
-	if err := mgr.ensureForwardChain(); err != nil {
-		return nil, fmt.Errorf("failed to initialize FORWARD chain: %w", err)
+	if err := m.ensureChain(); err != nil {
+		return fmt.Errorf("failed to initialize SNAT chain: %w", err)
 	}
-
-	return mgr, nil
+	if err := m.ensureForwardChain(); err != nil {
+		return fmt.Errorf("failed to initialize FORWARD chain: %w", err)
+	}
+	if err := m.ensureMarkChain(); err != nil {
+		return fmt.Errorf("failed to initialize MARK chain: %w", err)
+	}
+	if err := m.ensureDSCPChain(); err != nil {
+		return fmt.Errorf("failed to initialize DSCP chain: %w", err)
+	}
+	m.chainsReady = true
+	return nil
 }
 
 // ensureChain creates the EZLB-SNAT chain and adds a jump rule from POSTROUTING.
 func (m *linuxManager) ensureChain() error {
-	exists, err := m.ipt.ChainExists(natTable, snatChain)
+	exists, err := m.ipt.ChainExists(natTable, m.chains.SNAT)
 	if err != nil {
 		return fmt.Errorf("failed to check chain existence: %w", err)
 	}
 	if !exists {
-		if err := m.ipt.NewChain(natTable, snatChain); err != nil {
-			return fmt.Errorf("failed to create chain %s: %w", snatChain, err)
+		if err := m.ipt.NewChain(natTable, m.chains.SNAT); err != nil {
+			return fmt.Errorf("failed to create chain %s: %w", m.chains.SNAT, err)
 		}
-		m.logger.Debug("created iptables chain", zap.String("chain", snatChain))
+		m.logger.Debug("created iptables chain", zap.String("chain", m.chains.SNAT))
 	}
 
-	jumpRule := []string{"-j", snatChain}
+	jumpRule := []string{"-j", m.chains.SNAT}
 	if err := m.ipt.AppendUnique(natTable, "POSTROUTING", jumpRule...); err != nil {
 		return fmt.Errorf("failed to add jump rule to POSTROUTING: %w", err)
 	}
@@ -76,20 +108,20 @@ func (m *linuxManager) ensureChain() error {
 // ensureForwardChain creates the EZLB-FORWARD chain in the filter table and adds
 // a jump rule from FORWARD, plus a conntrack ESTABLISHED,RELATED accept rule.
 func (m *linuxManager) ensureForwardChain() error {
-	exists, err := m.ipt.ChainExists(filterTable, forwardChain)
+	exists, err := m.ipt.ChainExists(filterTable, m.chains.Forward)
 	if err != nil {
 		return fmt.Errorf("failed to check chain existence: %w", err)
 	}
 	if !exists {
-		if err := m.ipt.NewChain(filterTable, forwardChain); err != nil {
-			return fmt.Errorf("failed to create chain %s: %w", forwardChain, err)
+		if err := m.ipt.NewChain(filterTable, m.chains.Forward); err != nil {
+			return fmt.Errorf("failed to create chain %s: %w", m.chains.Forward, err)
 		}
-		m.logger.Debug("created iptables chain", zap.String("chain", forwardChain))
+		m.logger.Debug("created iptables chain", zap.String("chain", m.chains.Forward))
 	}
 
 	// Insert jump rule at the top of FORWARD chain so it takes priority.
 	// Use Exists + Insert for idempotency since go-iptables has no InsertUnique.
-	jumpRule := []string{"-j", forwardChain}
+	jumpRule := []string{"-j", m.chains.Forward}
 	jumpExists, err := m.ipt.Exists(filterTable, "FORWARD", jumpRule...)
 	if err != nil {
 		return fmt.Errorf("failed to check jump rule in FORWARD: %w", err)
@@ -102,8 +134,54 @@ func (m *linuxManager) ensureForwardChain() error {
 
 	// Add a conntrack rule to accept ESTABLISHED,RELATED packets (return traffic)
 	conntrackRule := []string{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"}
-	if err := m.ipt.AppendUnique(filterTable, forwardChain, conntrackRule...); err != nil {
-		return fmt.Errorf("failed to add conntrack rule to %s: %w", forwardChain, err)
+	if err := m.ipt.AppendUnique(filterTable, m.chains.Forward, conntrackRule...); err != nil {
+		return fmt.Errorf("failed to add conntrack rule to %s: %w", m.chains.Forward, err)
+	}
+
+	return nil
+}
+
+// ensureMarkChain creates the EZLB-MARK chain in the mangle table and adds a
+// jump rule from PREROUTING, before IPVS sees the packets.
+func (m *linuxManager) ensureMarkChain() error {
+	exists, err := m.ipt.ChainExists(mangleTable, m.chains.Mark)
+	if err != nil {
+		return fmt.Errorf("failed to check chain existence: %w", err)
+	}
+	if !exists {
+		if err := m.ipt.NewChain(mangleTable, m.chains.Mark); err != nil {
+			return fmt.Errorf("failed to create chain %s: %w", m.chains.Mark, err)
+		}
+		m.logger.Debug("created iptables chain", zap.String("chain", m.chains.Mark))
+	}
+
+	jumpRule := []string{"-j", m.chains.Mark}
+	if err := m.ipt.AppendUnique(mangleTable, "PREROUTING", jumpRule...); err != nil {
+		return fmt.Errorf("failed to add jump rule to PREROUTING: %w", err)
+	}
+
+	return nil
+}
+
+// ensureDSCPChain creates the EZLB-DSCP chain in the mangle table and adds
+// jump rules from PREROUTING and POSTROUTING.
+func (m *linuxManager) ensureDSCPChain() error {
+	exists, err := m.ipt.ChainExists(mangleTable, m.chains.DSCP)
+	if err != nil {
+		return fmt.Errorf("failed to check chain existence: %w", err)
+	}
+	if !exists {
+		if err := m.ipt.NewChain(mangleTable, m.chains.DSCP); err != nil {
+			return fmt.Errorf("failed to create chain %s: %w", m.chains.DSCP, err)
+		}
+		m.logger.Debug("created iptables chain", zap.String("chain", m.chains.DSCP))
+	}
+
+	jumpRule := []string{"-j", m.chains.DSCP}
+	for _, hook := range dscpHooks {
+		if err := m.ipt.AppendUnique(mangleTable, hook, jumpRule...); err != nil {
+			return fmt.Errorf("failed to add jump rule to %s: %w", hook, err)
+		}
 	}
 
 	return nil
@@ -114,6 +192,9 @@ func (m *linuxManager) ensureForwardChain() error {
 func (m *linuxManager) Reconcile(desired []SNATRule) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.ensureChains(); err != nil {
+		return err
+	}
 
 	desiredMap := make(map[string]SNATRule, len(desired))
 	for _, rule := range desired {
@@ -132,13 +213,13 @@ func (m *linuxManager) Reconcile(desired []SNATRule) error {
 		}
 	}
 
-	// Add rules that are missing or have changed snat_ip
+	// Add rules that are missing or have changed snat_ip or output interface
 	for key, rule := range desiredMap {
 		existing, exists := m.managed[key]
-		if exists && existing.SnatIP == rule.SnatIP {
+		if exists && existing == rule {
 			continue
 		}
-		// If snat_ip changed, remove the old rule first
+		// If snat_ip or the output interface changed, remove the old rule first
 		if exists {
 			if err := m.deleteRule(existing); err != nil {
 				m.logger.Error("failed to delete old SNAT rule for update", zap.String("key", key), zap.Error(err))
@@ -162,6 +243,9 @@ func (m *linuxManager) Reconcile(desired []SNATRule) error {
 func (m *linuxManager) ReconcileForward(desired []ForwardRule) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.ensureChains(); err != nil {
+		return err
+	}
 
 	desiredMap := make(map[string]ForwardRule, len(desired))
 	for _, rule := range desired {
@@ -180,11 +264,18 @@ func (m *linuxManager) ReconcileForward(desired []ForwardRule) error {
 		}
 	}
 
-	// Add rules that are missing
+	// Add rules that are missing or have changed output interface
 	for key, rule := range desiredMap {
-		if _, exists := m.managedForward[key]; exists {
+		existing, exists := m.managedForward[key]
+		if exists && existing == rule {
 			continue
 		}
+		if exists {
+			if err := m.deleteForwardRule(existing); err != nil {
+				m.logger.Error("failed to delete old FORWARD rule for update", zap.String("key", key), zap.Error(err))
+				continue
+			}
+		}
 		if err := m.addForwardRule(rule); err != nil {
 			m.logger.Error("failed to add FORWARD rule", zap.String("key", key), zap.Error(err))
 		} else {
@@ -196,22 +287,120 @@ func (m *linuxManager) ReconcileForward(desired []ForwardRule) error {
 	return nil
 }
 
-// Cleanup removes all managed SNAT/FORWARD rules, jump rules, and custom chains.
+// ReconcileMark compares desired MARK rules with the currently managed set,
+// adding missing rules, replacing those whose mark changed and removing stale ones.
+func (m *linuxManager) ReconcileMark(desired []MarkRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.ensureChains(); err != nil {
+		return err
+	}
+
+	desiredMap := make(map[string]MarkRule, len(desired))
+	for _, rule := range desired {
+		desiredMap[rule.Key()] = rule
+	}
+
+	// Remove rules that are no longer desired
+	for key, rule := range m.managedMark {
+		if _, exists := desiredMap[key]; !exists {
+			if err := m.deleteMarkRule(rule); err != nil {
+				m.logger.Error("failed to delete MARK rule", zap.String("key", key), zap.Error(err))
+			} else {
+				delete(m.managedMark, key)
+				m.logger.Debug("deleted MARK rule", zap.String("key", key))
+			}
+		}
+	}
+
+	// Add rules that are missing or have changed mark
+	for key, rule := range desiredMap {
+		existing, exists := m.managedMark[key]
+		if exists && existing.Mark == rule.Mark {
+			continue
+		}
+		if exists {
+			if err := m.deleteMarkRule(existing); err != nil {
+				m.logger.Error("failed to delete old MARK rule for update", zap.String("key", key), zap.Error(err))
+				continue
+			}
+		}
+		if err := m.addMarkRule(rule); err != nil {
+			m.logger.Error("failed to add MARK rule", zap.String("key", key), zap.Error(err))
+		} else {
+			m.managedMark[key] = rule
+			m.logger.Debug("added MARK rule", zap.String("key", key), zap.Uint32("mark", rule.Mark))
+		}
+	}
+
+	return nil
+}
+
+// ReconcileDSCP compares desired DSCP rules with the currently managed set,
+// adding missing rules, replacing those whose DSCP changed and removing stale ones.
+func (m *linuxManager) ReconcileDSCP(desired []DSCPRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.ensureChains(); err != nil {
+		return err
+	}
+
+	desiredMap := make(map[string]DSCPRule, len(desired))
+	for _, rule := range desired {
+		desiredMap[rule.Key()] = rule
+	}
+
+	// Remove rules that are no longer desired
+	for key, rule := range m.managedDSCP {
+		if _, exists := desiredMap[key]; !exists {
+			if err := m.deleteDSCPRule(rule); err != nil {
+				m.logger.Error("failed to delete DSCP rule", zap.String("key", key), zap.Error(err))
+			} else {
+				delete(m.managedDSCP, key)
+				m.logger.Debug("deleted DSCP rule", zap.String("key", key))
+			}
+		}
+	}
+
+	// Add rules that are missing or have changed DSCP
+	for key, rule := range desiredMap {
+		existing, exists := m.managedDSCP[key]
+		if exists && existing.DSCP == rule.DSCP {
+			continue
+		}
+		if exists {
+			if err := m.deleteDSCPRule(existing); err != nil {
+				m.logger.Error("failed to delete old DSCP rule for update", zap.String("key", key), zap.Error(err))
+				continue
+			}
+		}
+		if err := m.addDSCPRule(rule); err != nil {
+			m.logger.Error("failed to add DSCP rule", zap.String("key", key), zap.Error(err))
+		} else {
+			m.managedDSCP[key] = rule
+			m.logger.Debug("added DSCP rule", zap.String("key", key), zap.Uint8("dscp", rule.DSCP))
+		}
+	}
+
+	return nil
+}
+
+// Cleanup removes all managed SNAT/FORWARD/MARK/DSCP rules, jump rules, and custom chains.
 func (m *linuxManager) Cleanup() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Clean up SNAT chain
-	if err := m.ipt.ClearChain(natTable, snatChain); err != nil {
+	if err := m.ipt.ClearChain(natTable, m.chains.SNAT); err != nil {
 		m.logger.Error("failed to clear SNAT chain", zap.Error(err))
 	}
 
-	jumpRule := []string{"-j", snatChain}
+	jumpRule := []string{"-j", m.chains.SNAT}
 	if err := m.ipt.DeleteIfExists(natTable, "POSTROUTING", jumpRule...); err != nil {
 		m.logger.Error("failed to delete jump rule from POSTROUTING", zap.Error(err))
 	}
 
-	if err := m.ipt.DeleteChain(natTable, snatChain); err != nil {
+	if err := m.ipt.DeleteChain(natTable, m.chains.SNAT); err != nil {
 		m.logger.Error("failed to delete SNAT chain", zap.Error(err))
 	}
 
@@ -219,22 +408,59 @@ func (m *linuxManager) Cleanup() error {
 	m.logger.Debug("cleaned up all SNAT rules")
 
 	// Clean up FORWARD chain
-	if err := m.ipt.ClearChain(filterTable, forwardChain); err != nil {
+	if err := m.ipt.ClearChain(filterTable, m.chains.Forward); err != nil {
 		m.logger.Error("failed to clear FORWARD chain", zap.Error(err))
 	}
 
-	forwardJumpRule := []string{"-j", forwardChain}
+	forwardJumpRule := []string{"-j", m.chains.Forward}
 	if err := m.ipt.DeleteIfExists(filterTable, "FORWARD", forwardJumpRule...); err != nil {
 		m.logger.Error("failed to delete jump rule from FORWARD", zap.Error(err))
 	}
 
-	if err := m.ipt.DeleteChain(filterTable, forwardChain); err != nil {
+	if err := m.ipt.DeleteChain(filterTable, m.chains.Forward); err != nil {
 		m.logger.Error("failed to delete FORWARD chain", zap.Error(err))
 	}
 
 	m.managedForward = make(map[string]ForwardRule)
 	m.logger.Debug("cleaned up all FORWARD rules")
 
+	// Clean up MARK chain
+	if err := m.ipt.ClearChain(mangleTable, m.chains.Mark); err != nil {
+		m.logger.Error("failed to clear MARK chain", zap.Error(err))
+	}
+
+	markJumpRule := []string{"-j", m.chains.Mark}
+	if err := m.ipt.DeleteIfExists(mangleTable, "PREROUTING", markJumpRule...); err != nil {
+		m.logger.Error("failed to delete jump rule from PREROUTING", zap.Error(err))
+	}
+
+	if err := m.ipt.DeleteChain(mangleTable, m.chains.Mark); err != nil {
+		m.logger.Error("failed to delete MARK chain", zap.Error(err))
+	}
+
+	m.managedMark = make(map[string]MarkRule)
+	m.logger.Debug("cleaned up all MARK rules")
+
+	// Clean up DSCP chain
+	if err := m.ipt.ClearChain(mangleTable, m.chains.DSCP); err != nil {
+		m.logger.Error("failed to clear DSCP chain", zap.Error(err))
+	}
+
+	dscpJumpRule := []string{"-j", m.chains.DSCP}
+	for _, hook := range dscpHooks {
+		if err := m.ipt.DeleteIfExists(mangleTable, hook, dscpJumpRule...); err != nil {
+			m.logger.Error("failed to delete DSCP jump rule", zap.String("chain", hook), zap.Error(err))
+		}
+	}
+
+	if err := m.ipt.DeleteChain(mangleTable, m.chains.DSCP); err != nil {
+		m.logger.Error("failed to delete DSCP chain", zap.Error(err))
+	}
+
+	m.managedDSCP = make(map[string]DSCPRule)
+	m.logger.Debug("cleaned up all DSCP rules")
+
+	m.chainsReady = false
 	return nil
 }
 
@@ -246,6 +472,9 @@ func buildRuleSpec(rule SNATRule) []string {
 		"-p", rule.Protocol,
 		"--dport", portStr,
 	}
+	if rule.OutputInterface != "" {
+		spec = append(spec, "-o", rule.OutputInterface)
+	}
 	if rule.SnatIP != "" {
 		spec = append(spec, "-j", "SNAT", "--to-source", rule.SnatIP)
 	} else {
@@ -256,33 +485,86 @@ func buildRuleSpec(rule SNATRule) []string {
 
 func (m *linuxManager) addRule(rule SNATRule) error {
 	spec := buildRuleSpec(rule)
-	return m.ipt.AppendUnique(natTable, snatChain, spec...)
+	return m.ipt.AppendUnique(natTable, m.chains.SNAT, spec...)
 }
 
 func (m *linuxManager) deleteRule(rule SNATRule) error {
 	spec := buildRuleSpec(rule)
-	return m.ipt.DeleteIfExists(natTable, snatChain, spec...)
+	return m.ipt.DeleteIfExists(natTable, m.chains.SNAT, spec...)
 }
 
 // buildForwardRuleSpec constructs the iptables rule arguments for a FORWARD accept rule.
 func buildForwardRuleSpec(rule ForwardRule) []string {
 	portStr := strconv.Itoa(int(rule.BackendPort))
-	return []string{
+	spec := []string{
 		"-d", rule.BackendIP,
 		"-p", rule.Protocol,
 		"--dport", portStr,
-		"-j", "ACCEPT",
 	}
+	if rule.OutputInterface != "" {
+		spec = append(spec, "-o", rule.OutputInterface)
+	}
+	return append(spec, "-j", "ACCEPT")
 }
 
 func (m *linuxManager) addForwardRule(rule ForwardRule) error {
 	spec := buildForwardRuleSpec(rule)
-	return m.ipt.AppendUnique(filterTable, forwardChain, spec...)
+	return m.ipt.AppendUnique(filterTable, m.chains.Forward, spec...)
 }
 
 func (m *linuxManager) deleteForwardRule(rule ForwardRule) error {
 	spec := buildForwardRuleSpec(rule)
-	return m.ipt.DeleteIfExists(filterTable, forwardChain, spec...)
+	return m.ipt.DeleteIfExists(filterTable, m.chains.Forward, spec...)
+}
+
+// buildMarkRuleSpec constructs the iptables rule arguments for a MARK rule.
+func buildMarkRuleSpec(rule MarkRule) []string {
+	return []string{
+		"-d", rule.VIP,
+		"-p", rule.Protocol,
+		"--dport", strconv.Itoa(int(rule.Port)),
+		"-j", "MARK",
+		"--set-mark", strconv.FormatUint(uint64(rule.Mark), 10),
+	}
+}
+
+func (m *linuxManager) addMarkRule(rule MarkRule) error {
+	spec := buildMarkRuleSpec(rule)
+	return m.ipt.AppendUnique(mangleTable, m.chains.Mark, spec...)
+}
+
+func (m *linuxManager) deleteMarkRule(rule MarkRule) error {
+	spec := buildMarkRuleSpec(rule)
+	return m.ipt.DeleteIfExists(mangleTable, m.chains.Mark, spec...)
+}
+
+// buildDSCPRuleSpecs constructs the iptables rule arguments for a DSCP rule:
+// one for packets to the VIP:port and one for packets from it.
+func buildDSCPRuleSpecs(rule DSCPRule) [][]string {
+	port := strconv.Itoa(int(rule.Port))
+	dscp := strconv.Itoa(int(rule.DSCP))
+	return [][]string{
+		{"-d", rule.VIP, "-p", rule.Protocol, "--dport", port, "-j", "DSCP", "--set-dscp", dscp},
+		{"-s", rule.VIP, "-p", rule.Protocol, "--sport", port, "-j", "DSCP", "--set-dscp", dscp},
+	}
+}
+
+func (m *linuxManager) addDSCPRule(rule DSCPRule) error {
+	for _, spec := range buildDSCPRuleSpecs(rule) {
+		if err := m.ipt.AppendUnique(mangleTable, m.chains.DSCP, spec...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *linuxManager) deleteDSCPRule(rule DSCPRule) error {
+	for _, spec := range buildDSCPRuleSpecs(rule) {
+		if err := m.ipt.DeleteIfExists(mangleTable, m.chains.DSCP, spec...); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Stats implements StatsProvider by parsing iptables -t nat -vnL EZLB-SNAT output.
@@ -291,9 +573,17 @@ func (m *linuxManager) Stats() (map[string]SNATRuleStats, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	stats, err := m.ipt.Stats(natTable, snatChain)
+	// A chain not created yet holds no rules
+	exists, err := m.ipt.ChainExists(natTable, m.chains.SNAT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chain %s: %w", m.chains.SNAT, err)
+	}
+	if !exists {
+		return map[string]SNATRuleStats{}, nil
+	}
+	stats, err := m.ipt.Stats(natTable, m.chains.SNAT)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stats for chain %s: %w", snatChain, err)
+		return nil, fmt.Errorf("failed to get stats for chain %s: %w", m.chains.SNAT, err)
 	}
 
 	result := make(map[string]SNATRuleStats)
@@ -279,3 +279,111 @@ func TestFakeManager_CleanupIncludesForwardRules(t *testing.T) {
 		t.Fatalf("expected 0 FORWARD rules after cleanup, got %d", len(fakeMgr.GetManagedForward()))
 	}
 }
+
+func TestFakeManager_ReconcileMark(t *testing.T) {
+	mgr, err := NewManager(zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	fakeMgr := mgr.(*FakeManager)
+
+	desired := []MarkRule{
+		{VIP: "10.0.0.4", Port: 80, Protocol: "tcp", Mark: 100},
+		{VIP: "10.0.0.4", Port: 443, Protocol: "tcp", Mark: 100},
+	}
+	if err := mgr.ReconcileMark(desired); err != nil {
+		t.Fatalf("ReconcileMark failed: %v", err)
+	}
+	if managed := fakeMgr.GetManagedMark(); len(managed) != 2 {
+		t.Fatalf("expected 2 MARK rules, got %d", len(managed))
+	}
+
+	// Changing the mark replaces the rule; dropping a port removes its rule
+	if err := mgr.ReconcileMark([]MarkRule{{VIP: "10.0.0.4", Port: 80, Protocol: "tcp", Mark: 200}}); err != nil {
+		t.Fatalf("ReconcileMark failed: %v", err)
+	}
+	managed := fakeMgr.GetManagedMark()
+	if len(managed) != 1 {
+		t.Fatalf("expected 1 MARK rule, got %d", len(managed))
+	}
+	if rule := managed["10.0.0.4:80/tcp"]; rule.Mark != 200 {
+		t.Errorf("expected mark 200, got %d", rule.Mark)
+	}
+
+	if err := mgr.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if managed := fakeMgr.GetManagedMark(); len(managed) != 0 {
+		t.Errorf("expected no MARK rules after cleanup, got %d", len(managed))
+	}
+}
+
+func TestFakeManager_ReconcileDSCP(t *testing.T) {
+	mgr, err := NewManager(zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	fakeMgr := mgr.(*FakeManager)
+
+	desired := []DSCPRule{
+		{VIP: "10.0.0.1", Port: 80, Protocol: "tcp", DSCP: 46},
+		{VIP: "10.0.0.2", Port: 53, Protocol: "udp", DSCP: 34},
+	}
+	if err := mgr.ReconcileDSCP(desired); err != nil {
+		t.Fatalf("ReconcileDSCP failed: %v", err)
+	}
+	if managed := fakeMgr.GetManagedDSCP(); len(managed) != 2 {
+		t.Fatalf("expected 2 DSCP rules, got %d", len(managed))
+	}
+
+	// Changing the DSCP replaces the rule; dropping a service removes its rule
+	if err := mgr.ReconcileDSCP([]DSCPRule{{VIP: "10.0.0.1", Port: 80, Protocol: "tcp", DSCP: 10}}); err != nil {
+		t.Fatalf("ReconcileDSCP failed: %v", err)
+	}
+	managed := fakeMgr.GetManagedDSCP()
+	if len(managed) != 1 {
+		t.Fatalf("expected 1 DSCP rule, got %d", len(managed))
+	}
+	if rule := managed["10.0.0.1:80/tcp"]; rule.DSCP != 10 {
+		t.Errorf("expected DSCP 10, got %d", rule.DSCP)
+	}
+
+	if err := mgr.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if managed := fakeMgr.GetManagedDSCP(); len(managed) != 0 {
+		t.Errorf("expected no DSCP rules after cleanup, got %d", len(managed))
+	}
+}
+
+func TestFakeManager_ReconcileUpdateOutputInterface(t *testing.T) {
+	mgr, err := NewManager(zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	fakeMgr := mgr.(*FakeManager)
+
+	forward := []ForwardRule{{BackendIP: "192.168.1.1", BackendPort: 8080, Protocol: "tcp"}}
+	if err := mgr.ReconcileForward(forward); err != nil {
+		t.Fatalf("ReconcileForward failed: %v", err)
+	}
+	forward[0].OutputInterface = "eth1"
+	if err := mgr.ReconcileForward(forward); err != nil {
+		t.Fatalf("ReconcileForward failed: %v", err)
+	}
+	if rule := fakeMgr.GetManagedForward()["192.168.1.1:8080/tcp"]; rule.OutputInterface != "eth1" {
+		t.Errorf("expected FORWARD rule on eth1, got %q", rule.OutputInterface)
+	}
+
+	rules := []SNATRule{{BackendIP: "192.168.1.1", BackendPort: 8080, Protocol: "tcp", OutputInterface: "eth1"}}
+	if err := mgr.Reconcile(rules); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	rules[0].OutputInterface = "eth2"
+	if err := mgr.Reconcile(rules); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if rule := fakeMgr.GetManaged()["192.168.1.1:8080/tcp"]; rule.OutputInterface != "eth2" {
+		t.Errorf("expected SNAT rule on eth2, got %q", rule.OutputInterface)
+	}
+}
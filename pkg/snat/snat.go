@@ -2,12 +2,37 @@ package snat
 
 import "fmt"
 
+// Chains holds the names of the iptables chains a Manager owns.
+type Chains struct {
+	SNAT    string
+	Forward string
+	Mark    string
+	DSCP    string
+}
+
+// ChainsFor returns the chain names of an ezlb instance: EZLB-SNAT,
+// EZLB-FORWARD, EZLB-MARK and EZLB-DSCP for the default instance, with
+// "-<instance>" appended for a named one, so instances on one host never
+// touch each other's rules.
+func ChainsFor(instance string) Chains {
+	chains := Chains{SNAT: "EZLB-SNAT", Forward: "EZLB-FORWARD", Mark: "EZLB-MARK", DSCP: "EZLB-DSCP"}
+	if instance != "" {
+		chains.SNAT += "-" + instance
+		chains.Forward += "-" + instance
+		chains.Mark += "-" + instance
+		chains.DSCP += "-" + instance
+	}
+	return chains
+}
+
 // SNATRule describes a single SNAT/MASQUERADE rule for a backend destination.
+// OutputInterface, if set, restricts the rule to packets leaving through it.
 type SNATRule struct {
-	BackendIP   string
-	Protocol    string
-	SnatIP      string
-	BackendPort uint16
+	BackendIP       string
+	Protocol        string
+	SnatIP          string
+	OutputInterface string
+	BackendPort     uint16
 }
 
 // Key returns a unique string identifier for this rule.
@@ -19,9 +44,10 @@ func (r SNATRule) Key() string {
 // This is needed because IPVS NAT mode requires packets to traverse the FORWARD
 // chain, which may have a DROP policy (e.g. when Docker is installed).
 type ForwardRule struct {
-	BackendIP   string
-	Protocol    string
-	BackendPort uint16
+	BackendIP       string
+	Protocol        string
+	OutputInterface string
+	BackendPort     uint16
 }
 
 // Key returns a unique string identifier for this forward rule.
@@ -29,6 +55,34 @@ func (r ForwardRule) Key() string {
 	return fmt.Sprintf("%s:%d/%s", r.BackendIP, r.BackendPort, r.Protocol)
 }
 
+// MarkRule describes a mangle rule that sets a firewall mark on packets to a
+// VIP:port, so that several ports can be balanced by one fwmark IPVS service.
+type MarkRule struct {
+	VIP      string
+	Protocol string
+	Mark     uint32
+	Port     uint16
+}
+
+// Key returns a unique string identifier for this mark rule.
+func (r MarkRule) Key() string {
+	return fmt.Sprintf("%s:%d/%s", r.VIP, r.Port, r.Protocol)
+}
+
+// DSCPRule describes a mangle rule that sets the DSCP field of packets to and
+// from a VIP:port, so the network can prioritize a service's traffic.
+type DSCPRule struct {
+	VIP      string
+	Protocol string
+	Port     uint16
+	DSCP     uint8
+}
+
+// Key returns a unique string identifier for this DSCP rule.
+func (r DSCPRule) Key() string {
+	return fmt.Sprintf("%s:%d/%s", r.VIP, r.Port, r.Protocol)
+}
+
 // Manager defines the interface for managing iptables SNAT and FORWARD rules.
 // Implementations must be safe for concurrent use.
 type Manager interface {
@@ -41,6 +95,14 @@ type Manager interface {
 	// the default policy is DROP (e.g. Docker environments).
 	ReconcileForward(desired []ForwardRule) error
 
-	// Cleanup removes all SNAT/FORWARD rules and custom chains managed by this Manager.
+	// ReconcileMark ensures the mangle MARK rules of fwmark service groups
+	// match the desired state.
+	ReconcileMark(desired []MarkRule) error
+
+	// ReconcileDSCP ensures the mangle DSCP rules of services with dscp set
+	// match the desired state.
+	ReconcileDSCP(desired []DSCPRule) error
+
+	// Cleanup removes all SNAT/FORWARD/MARK/DSCP rules and custom chains managed by this Manager.
 	Cleanup() error
 }
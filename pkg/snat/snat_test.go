@@ -0,0 +1,23 @@
+package snat
+
+import "testing"
+
+func TestChainsFor(t *testing.T) {
+	if got, want := ChainsFor(""), (Chains{SNAT: "EZLB-SNAT", Forward: "EZLB-FORWARD", Mark: "EZLB-MARK", DSCP: "EZLB-DSCP"}); got != want {
+		t.Errorf("ChainsFor(\"\") = %+v, want %+v", got, want)
+	}
+	got := ChainsFor("tenant-b-edge01")
+	want := Chains{
+		SNAT:    "EZLB-SNAT-tenant-b-edge01",
+		Forward: "EZLB-FORWARD-tenant-b-edge01",
+		Mark:    "EZLB-MARK-tenant-b-edge01",
+		DSCP:    "EZLB-DSCP-tenant-b-edge01",
+	}
+	if got != want {
+		t.Errorf("ChainsFor(instance) = %+v, want %+v", got, want)
+	}
+	// iptables chain names are limited to 28 bytes
+	if len(got.Forward) > 28 {
+		t.Errorf("chain name %q exceeds 28 bytes", got.Forward)
+	}
+}
@@ -0,0 +1,397 @@
+// Package soak runs a long-lived stress test of the reconciler: a sandbox
+// config is mutated continuously, with synthetic health flapping, and after
+// every reconcile the dataplane is checked against invariants. It uses the
+// dataplane of the build, the fake IPVS handle by default or real IPVS with
+// the integration build tag.
+package soak
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/lvs"
+	"github.com/easzlab/ezlb/pkg/snat"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sandbox addresses come from the benchmarking range 198.18.0.0/15 so that
+// they do not collide with real services: VIPs from 198.18.0.0/16 and
+// backends from 198.19.0.0/16.
+var (
+	vipNet     = net.IPv4(198, 18, 0, 0).To4()
+	backendNet = net.IPv4(198, 19, 0, 0).To4()
+)
+
+// goroutineSlack is how many goroutines above the baseline are tolerated
+// before the run is failed as leaking.
+const goroutineSlack = 20
+
+// maxOffset is the number of sandbox addresses in each /16, skipping the
+// network and broadcast addresses.
+const maxOffset = 65534
+
+// Options configures a soak run.
+type Options struct {
+	// Duration bounds the run; Steps, if positive, bounds it as well.
+	Duration time.Duration
+	Steps    int
+	// Interval is the pause between steps.
+	Interval time.Duration
+	// MaxServices and MaxBackends bound the sandbox config.
+	MaxServices int
+	MaxBackends int
+	// Seed makes the sequence of mutations reproducible.
+	Seed int64
+}
+
+// Result summarizes a soak run.
+type Result struct {
+	Steps          int
+	Mutations      map[string]int
+	MaxServices    int
+	BaseGoroutines int
+	MaxGoroutines  int
+	HeapStart      uint64
+	HeapEnd        uint64
+}
+
+// Run mutates the sandbox config and reconciles it until ctx is done or the
+// duration or step budget is used up. It fails at the first violated
+// invariant, reporting the step and seed to reproduce it. The sandbox
+// services are removed from IPVS before returning.
+func Run(ctx context.Context, opts Options, logger *zap.Logger) (Result, error) {
+	if opts.MaxServices <= 0 || opts.MaxBackends <= 0 {
+		return Result{}, fmt.Errorf("max services and backends must be positive")
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	// Every step reconciles, so keep only warnings from the dataplane
+	dataplaneLogger := logger.WithOptions(zap.IncreaseLevel(zapcore.WarnLevel))
+	mgr, err := lvs.NewManager(dataplaneLogger)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create IPVS manager: %w", err)
+	}
+	defer mgr.Close()
+	snatMgr, err := snat.NewManager(dataplaneLogger)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create SNAT manager: %w", err)
+	}
+
+	s := &sandbox{
+		rand:    rand.New(rand.NewSource(opts.Seed)),
+		opts:    opts,
+		health:  &healthState{status: make(map[string]bool)},
+		lvsMgr:  mgr,
+		nextVIP: 1,
+		nextBE:  1,
+	}
+	s.reconciler = lvs.NewReconciler(mgr, s.health, snatMgr, dataplaneLogger)
+
+	result := Result{Mutations: make(map[string]int), HeapStart: heapAlloc()}
+	runErr := s.loop(ctx, &result, logger)
+
+	// Remove every sandbox service, even after a failure
+	if err := s.reconciler.Reconcile(nil); err != nil && runErr == nil {
+		runErr = fmt.Errorf("cleanup reconcile failed: %w", err)
+	}
+	if runErr == nil {
+		if services, err := s.sandboxServices(); err != nil {
+			runErr = err
+		} else if len(services) != 0 {
+			runErr = fmt.Errorf("invariant violated after cleanup: %d sandbox services left in IPVS", len(services))
+		}
+	}
+	result.HeapEnd = heapAlloc()
+	if runErr != nil {
+		return result, fmt.Errorf("seed %d: %w", opts.Seed, runErr)
+	}
+	return result, nil
+}
+
+func (s *sandbox) loop(ctx context.Context, result *Result, logger *zap.Logger) error {
+	lastReport := time.Now()
+	for s.opts.Steps <= 0 || result.Steps < s.opts.Steps {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		mutation := s.mutate()
+		result.Mutations[mutation]++
+		result.Steps++
+		if err := s.reconciler.Reconcile(s.services); err != nil {
+			return fmt.Errorf("step %d (%s): reconcile failed: %w", result.Steps, mutation, err)
+		}
+		if err := s.check(); err != nil {
+			return fmt.Errorf("step %d (%s): invariant violated: %w", result.Steps, mutation, err)
+		}
+		result.MaxServices = max(result.MaxServices, len(s.services))
+
+		// The first steps start the goroutines a steady run keeps
+		goroutines := runtime.NumGoroutine()
+		if result.Steps == 10 {
+			result.BaseGoroutines = goroutines
+		}
+		result.MaxGoroutines = max(result.MaxGoroutines, goroutines)
+		if result.BaseGoroutines > 0 && goroutines > result.BaseGoroutines+goroutineSlack {
+			return fmt.Errorf("step %d: goroutine leak: %d goroutines, baseline %d", result.Steps, goroutines, result.BaseGoroutines)
+		}
+
+		if time.Since(lastReport) >= time.Minute {
+			lastReport = time.Now()
+			logger.Info("soak progress",
+				zap.Int("steps", result.Steps),
+				zap.Int("services", len(s.services)),
+				zap.Int("goroutines", goroutines),
+				zap.Uint64("heap_bytes", heapAlloc()),
+			)
+		}
+
+		if s.opts.Interval > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(s.opts.Interval):
+			}
+		}
+	}
+	return nil
+}
+
+// sandbox is the mutated config and the synthetic health of its backends.
+type sandbox struct {
+	rand       *rand.Rand
+	opts       Options
+	services   []config.ServiceConfig
+	health     *healthState
+	lvsMgr     *lvs.Manager
+	reconciler *lvs.Reconciler
+	nextVIP    int
+	nextBE     int
+}
+
+// healthState is the synthetic health checker of the sandbox backends.
+type healthState struct {
+	mu     sync.Mutex
+	status map[string]bool
+}
+
+func (h *healthState) IsHealthy(address string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status[address]
+}
+
+func (h *healthState) set(address string, healthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status[address] = healthy
+}
+
+// mutate applies one random change to the sandbox and returns its name.
+func (s *sandbox) mutate() string {
+	if len(s.services) == 0 {
+		s.addService()
+		return "add_service"
+	}
+	svc := &s.services[s.rand.Intn(len(s.services))]
+	switch s.rand.Intn(8) {
+	case 0:
+		if len(s.services) < s.opts.MaxServices {
+			s.addService()
+			return "add_service"
+		}
+		fallthrough
+	case 1:
+		i := s.rand.Intn(len(s.services))
+		s.services = slices.Delete(s.services, i, i+1)
+		return "remove_service"
+	case 2:
+		if len(svc.Backends) < s.opts.MaxBackends {
+			svc.Backends = append(svc.Backends, s.newBackend(nil))
+			return "add_backend"
+		}
+		fallthrough
+	case 3:
+		if len(svc.Backends) > 1 {
+			i := s.rand.Intn(len(svc.Backends))
+			svc.Backends = slices.Delete(slices.Clone(svc.Backends), i, i+1)
+			return "remove_backend"
+		}
+		fallthrough
+	case 4:
+		backends := slices.Clone(svc.Backends)
+		weight := s.rand.Intn(11)
+		backends[s.rand.Intn(len(backends))].Weight = &weight
+		svc.Backends = backends
+		return "set_weight"
+	case 5:
+		svc.Scheduler = []string{"rr", "wrr", "lc", "wlc"}[s.rand.Intn(4)]
+		return "set_scheduler"
+	case 6:
+		enabled := !svc.IsEnabled()
+		svc.Enabled = &enabled
+		return "toggle_enabled"
+	default:
+		backend := svc.Backends[s.rand.Intn(len(svc.Backends))]
+		s.health.set(backend.Address, !s.health.IsHealthy(backend.Address))
+		return "flap_health"
+	}
+}
+
+func (s *sandbox) addService() {
+	var vip net.IP
+	for vip == nil || s.inUse(vip.String(), nil) {
+		vip = offsetIP(vipNet, s.nextVIP)
+		s.nextVIP = s.nextVIP%maxOffset + 1
+	}
+	healthCheck := true
+	svc := config.ServiceConfig{
+		Name:        "soak-" + vip.String(),
+		Listen:      net.JoinHostPort(vip.String(), "80"),
+		Protocol:    []string{"tcp", "udp"}[s.rand.Intn(2)],
+		Scheduler:   "wrr",
+		HealthCheck: config.HealthCheckConfig{Enabled: &healthCheck},
+	}
+	for range 1 + s.rand.Intn(s.opts.MaxBackends) {
+		svc.Backends = append(svc.Backends, s.newBackend(svc.Backends))
+	}
+	s.services = append(s.services, svc)
+}
+
+// newBackend returns a backend with an address not used by the sandbox or
+// by pending, the backends of a service not added yet.
+func (s *sandbox) newBackend(pending []config.BackendConfig) config.BackendConfig {
+	var address string
+	for address == "" || s.inUse(address, pending) {
+		address = net.JoinHostPort(offsetIP(backendNet, s.nextBE).String(), "8080")
+		s.nextBE = s.nextBE%maxOffset + 1
+	}
+	s.health.set(address, s.rand.Intn(4) != 0)
+	weight := 1 + s.rand.Intn(10)
+	return config.BackendConfig{Address: address, Weight: &weight}
+}
+
+// inUse reports whether a VIP or backend address is used by the sandbox or
+// by pending. Addresses are allocated round-robin and wrap around in long
+// runs, so they must be checked before reuse.
+func (s *sandbox) inUse(address string, pending []config.BackendConfig) bool {
+	for _, backend := range pending {
+		if backend.Address == address {
+			return true
+		}
+	}
+	for _, svc := range s.services {
+		if host, _, _ := net.SplitHostPort(svc.Listen); host == address {
+			return true
+		}
+		for _, backend := range svc.Backends {
+			if backend.Address == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check verifies the dataplane against the sandbox config, independently of
+// how the reconciler builds its desired state, and that it reports no drift.
+func (s *sandbox) check() error {
+	actual, err := s.sandboxServices()
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]map[string]int)
+	for _, svc := range s.services {
+		if !svc.IsEnabled() {
+			continue
+		}
+		dests := make(map[string]int)
+		for _, backend := range svc.Backends {
+			if s.health.IsHealthy(backend.Address) {
+				dests[backend.Address] = backend.GetWeight()
+			}
+		}
+		want[svc.Listen+"/"+svc.Protocol] = dests
+	}
+
+	if len(actual) != len(want) {
+		return fmt.Errorf("want %d services in IPVS, have %d", len(want), len(actual))
+	}
+	for key, svc := range actual {
+		wantDests, ok := want[key]
+		if !ok {
+			return fmt.Errorf("unexpected service %s in IPVS", key)
+		}
+		dests, err := s.lvsMgr.GetDestinations(svc)
+		if err != nil {
+			return fmt.Errorf("get destinations of %s: %w", key, err)
+		}
+		if len(dests) != len(wantDests) {
+			return fmt.Errorf("service %s: want %d destinations, have %d", key, len(wantDests), len(dests))
+		}
+		for _, dst := range dests {
+			address := net.JoinHostPort(dst.Address.String(), strconv.Itoa(int(dst.Port)))
+			weight, ok := wantDests[address]
+			if !ok {
+				return fmt.Errorf("service %s: unexpected destination %s", key, address)
+			}
+			if dst.Weight != weight {
+				return fmt.Errorf("service %s: destination %s has weight %d, want %d", key, address, dst.Weight, weight)
+			}
+		}
+	}
+
+	drifts, err := s.reconciler.Drift(s.services)
+	if err != nil {
+		return fmt.Errorf("drift check failed: %w", err)
+	}
+	if len(drifts) > 0 {
+		return fmt.Errorf("drift right after reconcile: %s", drifts[0])
+	}
+	return nil
+}
+
+// sandboxServices returns the IPVS services with a sandbox VIP, by key.
+func (s *sandbox) sandboxServices() (map[string]*lvs.Service, error) {
+	services, err := s.lvsMgr.GetServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IPVS services: %w", err)
+	}
+	result := make(map[string]*lvs.Service)
+	for _, svc := range services {
+		if svc.Address.To4() != nil && svc.Address.To4()[0] == vipNet[0] && svc.Address.To4()[1] == vipNet[1] {
+			result[lvs.ServiceKeyFromIPVS(svc).String()] = svc
+		}
+	}
+	return result, nil
+}
+
+// offsetIP returns base plus n, within its /16.
+func offsetIP(base net.IP, n int) net.IP {
+	ip := slices.Clone(base)
+	ip[2] += byte(n >> 8)
+	ip[3] += byte(n)
+	return ip
+}
+
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
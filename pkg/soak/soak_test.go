@@ -0,0 +1,91 @@
+package soak
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRun(t *testing.T) {
+	opts := Options{Steps: 2000, MaxServices: 10, MaxBackends: 4, Seed: 1}
+	result, err := Run(context.Background(), opts, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Steps != opts.Steps {
+		t.Errorf("expected %d steps, got %d", opts.Steps, result.Steps)
+	}
+	for _, mutation := range []string{"add_service", "remove_service", "add_backend", "remove_backend", "set_weight", "toggle_enabled", "flap_health"} {
+		if result.Mutations[mutation] == 0 {
+			t.Errorf("expected mutation %s to be exercised", mutation)
+		}
+	}
+	if result.MaxServices > opts.MaxServices {
+		t.Errorf("expected at most %d services, got %d", opts.MaxServices, result.MaxServices)
+	}
+}
+
+func TestRun_Reproducible(t *testing.T) {
+	opts := Options{Steps: 200, MaxServices: 5, MaxBackends: 3, Seed: 42}
+	first, err := Run(context.Background(), opts, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	second, err := Run(context.Background(), opts, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for mutation, count := range first.Mutations {
+		if second.Mutations[mutation] != count {
+			t.Errorf("expected the same seed to repeat mutation %s %d times, got %d", mutation, count, second.Mutations[mutation])
+		}
+	}
+}
+
+func TestRun_StopsOnContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := Run(ctx, Options{MaxServices: 5, MaxBackends: 3}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Steps != 0 {
+		t.Errorf("expected no steps after cancellation, got %d", result.Steps)
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	if _, err := Run(context.Background(), Options{Steps: 1}, zap.NewNop()); err == nil {
+		t.Fatal("expected an error for zero service and backend bounds")
+	}
+}
+
+func TestSandbox_AddressesWrapAround(t *testing.T) {
+	s := &sandbox{
+		rand:    rand.New(rand.NewSource(1)),
+		opts:    Options{MaxServices: 5, MaxBackends: 1},
+		health:  &healthState{status: make(map[string]bool)},
+		nextVIP: maxOffset,
+		nextBE:  maxOffset,
+	}
+	s.addService()
+	s.addService()
+	// Wrapping onto an address in use skips it
+	s.nextVIP = maxOffset
+	s.addService()
+
+	var listens, backends []string
+	for _, svc := range s.services {
+		listens = append(listens, svc.Listen)
+		backends = append(backends, svc.Backends[0].Address)
+	}
+	if want := []string{"198.18.255.254:80", "198.18.0.1:80", "198.18.0.2:80"}; !slices.Equal(listens, want) {
+		t.Errorf("expected listens %v, got %v", want, listens)
+	}
+	if want := []string{"198.19.255.254:8080", "198.19.0.1:8080", "198.19.0.2:8080"}; !slices.Equal(backends, want) {
+		t.Errorf("expected backends %v, got %v", want, backends)
+	}
+}
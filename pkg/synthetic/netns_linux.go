@@ -0,0 +1,61 @@
+package synthetic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// dialInNamespace dials address from the network namespace at path. The
+// socket is created on an OS thread switched into the namespace, then the
+// thread switches back; a thread that cannot switch back is never unlocked,
+// so the runtime discards it with its goroutine.
+func dialInNamespace(ctx context.Context, path, network, address string) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		conn, restored, err := dialLocked(ctx, path, network, address)
+		if restored {
+			runtime.UnlockOSThread()
+		}
+		results <- result{conn: conn, err: err}
+	}()
+	res := <-results
+	return res.conn, res.err
+}
+
+// dialLocked dials from the namespace at path on the current, locked OS
+// thread and reports whether the thread is back in its original namespace.
+func dialLocked(ctx context.Context, path, network, address string) (net.Conn, bool, error) {
+	origin, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		return nil, true, fmt.Errorf("open current network namespace: %w", err)
+	}
+	defer origin.Close()
+	target, err := os.Open(path)
+	if err != nil {
+		return nil, true, fmt.Errorf("open network namespace: %w", err)
+	}
+	defer target.Close()
+
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		return nil, true, fmt.Errorf("enter network namespace %s: %w", path, err)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if restoreErr := unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); restoreErr != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, false, fmt.Errorf("leave network namespace %s: %w", path, restoreErr)
+	}
+	return conn, true, err
+}
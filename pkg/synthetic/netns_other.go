@@ -0,0 +1,14 @@
+//go:build !linux
+
+package synthetic
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// dialInNamespace is not supported outside Linux.
+func dialInNamespace(ctx context.Context, path, network, address string) (net.Conn, error) {
+	return nil, errors.New("synthetic.netns is only supported on Linux")
+}
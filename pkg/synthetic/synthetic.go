@@ -0,0 +1,226 @@
+// Package synthetic probes service VIPs end to end: it connects to the VIP
+// the way a client would, through IPVS and any SNAT rules, and optionally
+// sends an HTTP request. The result is a service-level SLI, distinct from
+// backend health checks, which probe each backend directly.
+package synthetic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// netnsDir holds the network namespaces named by "ip netns add".
+const netnsDir = "/var/run/netns"
+
+// Target is the synthetic probe of one listen address of a service.
+type Target struct {
+	Service string
+	Listen  string
+	Config  config.SyntheticConfig
+}
+
+// key identifies a Target across config reloads.
+func (t Target) key() string {
+	return t.Service + "|" + t.Listen
+}
+
+// Targets returns the synthetic probe targets of the enabled services with
+// synthetic.enabled set, one per listen address.
+func Targets(services []config.ServiceConfig) []Target {
+	var targets []Target
+	for _, svc := range config.ExpandListens(config.EnabledServices(services)) {
+		// The UDP copy of a tcp+udp service is not probed
+		if svc.Synthetic.IsEnabled() && svc.Listen != "" && svc.Protocol != "udp" {
+			targets = append(targets, Target{Service: svc.Name, Listen: svc.Listen, Config: svc.Synthetic})
+		}
+	}
+	return targets
+}
+
+// Probe runs a single synthetic transaction against the target: a TCP
+// connect to the VIP or, with http_path set, a GET request that must answer
+// with the expected status.
+func Probe(ctx context.Context, target Target) error {
+	ctx, cancel := context.WithTimeout(ctx, target.Config.GetTimeout())
+	defer cancel()
+
+	dial := dialer(target.Config.Netns)
+	if target.Config.HTTPPath == "" {
+		conn, err := dial(ctx, "tcp", target.Listen)
+		if err != nil {
+			return fmt.Errorf("connect to %s: %w", target.Listen, err)
+		}
+		return conn.Close()
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{DialContext: dial, DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	url := "http://" + target.Listen + target.Config.HTTPPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("build request for %s: %w", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request %s: %w", url, err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if want := target.Config.GetHTTPExpectedStatus(); resp.StatusCode != want {
+		return fmt.Errorf("request %s: unexpected status %d, want %d", url, resp.StatusCode, want)
+	}
+	return nil
+}
+
+// dialer returns the dial function for probes from the given network
+// namespace, the director's own if empty.
+func dialer(netns string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if netns == "" {
+		var d net.Dialer
+		return d.DialContext
+	}
+	path := netns
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(netnsDir, netns)
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialInNamespace(ctx, path, network, address)
+	}
+}
+
+// runningProbe is the probe loop of one target.
+type runningProbe struct {
+	cfg    config.SyntheticConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Prober runs the synthetic probes of the configured services on their
+// intervals and exports the results as metrics.
+type Prober struct {
+	logger *zap.Logger
+	probe  func(context.Context, Target) error
+	probes map[string]*runningProbe
+	mu     sync.Mutex
+}
+
+// NewProber creates a Prober without any targets.
+func NewProber(logger *zap.Logger) *Prober {
+	return &Prober{
+		logger: logger,
+		probe:  Probe,
+		probes: make(map[string]*runningProbe),
+	}
+}
+
+// Update starts the probes of new targets, restarts those whose config
+// changed and stops those no longer configured, removing their metrics.
+func (p *Prober) Update(targets []Target) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	wanted := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		key := target.key()
+		wanted[key] = true
+		if running, exists := p.probes[key]; exists {
+			if running.cfg == target.Config {
+				continue
+			}
+			running.stop()
+		}
+		p.probes[key] = p.start(target)
+	}
+
+	for key, running := range p.probes {
+		if wanted[key] {
+			continue
+		}
+		running.stop()
+		delete(p.probes, key)
+	}
+}
+
+// Stop stops every probe and waits for them to finish.
+func (p *Prober) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, running := range p.probes {
+		running.stop()
+		delete(p.probes, key)
+	}
+}
+
+// start launches the probe loop of a target. Must be called with p.mu held.
+func (p *Prober) start(target Target) *runningProbe {
+	ctx, cancel := context.WithCancel(context.Background())
+	running := &runningProbe{cfg: target.Config, cancel: cancel, done: make(chan struct{})}
+	go p.run(ctx, target, running.done)
+	p.logger.Info("synthetic probe started",
+		zap.String("service", target.Service),
+		zap.String("listen", target.Listen),
+		zap.Duration("interval", target.Config.GetInterval()),
+	)
+	return running
+}
+
+// stop cancels the probe loop and waits for it to finish.
+func (r *runningProbe) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// run probes the target on its interval until ctx is cancelled. Failures are
+// logged when the result changes, not on every probe.
+func (p *Prober) run(ctx context.Context, target Target, done chan struct{}) {
+	defer close(done)
+	defer metrics.DeleteSyntheticMetrics(target.Service, target.Listen)
+
+	ticker := time.NewTicker(target.Config.GetInterval())
+	defer ticker.Stop()
+
+	up := true
+	for {
+		start := time.Now()
+		err := p.probe(ctx, target)
+		if ctx.Err() != nil {
+			return
+		}
+		metrics.SetSyntheticResult(target.Service, target.Listen, err == nil, time.Since(start).Seconds())
+		switch {
+		case err != nil && up:
+			p.logger.Warn("synthetic probe failed",
+				zap.String("service", target.Service),
+				zap.String("listen", target.Listen),
+				zap.Error(err),
+			)
+		case err == nil && !up:
+			p.logger.Info("synthetic probe succeeded again",
+				zap.String("service", target.Service),
+				zap.String("listen", target.Listen),
+			)
+		}
+		up = err == nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
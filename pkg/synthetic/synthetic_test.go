@@ -0,0 +1,129 @@
+package synthetic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestTargets(t *testing.T) {
+	enabled := config.SyntheticConfig{Enabled: boolPtr(true)}
+	targets := Targets([]config.ServiceConfig{
+		{Name: "web", Listen: "10.0.0.1:80", ListenV6: "[2001:db8::1]:80", Protocol: "tcp+udp", Synthetic: enabled},
+		{Name: "api", Listen: "10.0.0.2:443"},
+		{Name: "off", Listen: "10.0.0.3:80", Enabled: boolPtr(false), Synthetic: enabled},
+	})
+	var listens []string
+	for _, target := range targets {
+		if target.Service != "web" {
+			t.Errorf("unexpected target of service %q", target.Service)
+		}
+		listens = append(listens, target.Listen)
+	}
+	if got := strings.Join(listens, ","); got != "10.0.0.1:80,[2001:db8::1]:80" {
+		t.Errorf("expected one TCP target per listen address, got %s", got)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer backend.Close()
+	listen := strings.TrimPrefix(backend.URL, "http://")
+
+	tests := []struct {
+		name    string
+		cfg     config.SyntheticConfig
+		wantErr string
+	}{
+		{"tcp connect", config.SyntheticConfig{}, ""},
+		{"http", config.SyntheticConfig{HTTPPath: "/healthz"}, ""},
+		{"unexpected status", config.SyntheticConfig{HTTPPath: "/missing"}, "unexpected status 404, want 200"},
+		{"expected status", config.SyntheticConfig{HTTPPath: "/missing", HTTPExpectedStatus: 404}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Probe(context.Background(), Target{Service: "web", Listen: listen, Config: tt.cfg})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	backend.Close()
+	if err := Probe(context.Background(), Target{Service: "web", Listen: listen}); err == nil {
+		t.Error("expected a probe of a closed listener to fail")
+	}
+}
+
+func TestProber_UpdateAndTransitions(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	prober := NewProber(zap.New(core))
+	var failing atomic.Bool
+	var probes atomic.Int32
+	prober.probe = func(ctx context.Context, target Target) error {
+		probes.Add(1)
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	defer prober.Stop()
+
+	target := Target{Service: "web", Listen: "10.0.0.1:80", Config: config.SyntheticConfig{
+		Enabled: boolPtr(true), Interval: "10ms", Timeout: "5ms",
+	}}
+	prober.Update([]Target{target})
+	waitFor(t, func() bool { return probes.Load() >= 2 })
+
+	failing.Store(true)
+	waitFor(t, func() bool { return logs.FilterMessage("synthetic probe failed").Len() == 1 })
+	failing.Store(false)
+	waitFor(t, func() bool { return logs.FilterMessage("synthetic probe succeeded again").Len() == 1 })
+	if n := logs.FilterMessage("synthetic probe failed").Len(); n != 1 {
+		t.Errorf("expected the failure to be logged once, got %d", n)
+	}
+
+	// Unchanged targets keep running; removed ones stop
+	prober.Update([]Target{target})
+	if n := logs.FilterMessage("synthetic probe started").Len(); n != 1 {
+		t.Errorf("expected an unchanged target not to restart, got %d starts", n)
+	}
+	prober.Update(nil)
+	if len(prober.probes) != 0 {
+		t.Errorf("expected every probe to stop, got %d", len(prober.probes))
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,146 @@
+package syslogsink
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+// Severity is an RFC 5424 message severity.
+type Severity int
+
+// Severities used for state-transition events.
+const (
+	SeverityError   Severity = 3
+	SeverityWarning Severity = 4
+	SeverityNotice  Severity = 5
+)
+
+// sdID is the structured-data element carrying the event fields. 32473 is the
+// example private enterprise number reserved by RFC 5424 for documentation.
+const sdID = "ezlb@32473"
+
+// writeTimeout bounds how long a health-check goroutine may block on syslog.
+const writeTimeout = 2 * time.Second
+
+// Sink sends RFC 5424 formatted state-transition events to a local or remote
+// syslog collector. Datagram transports send one message per packet; tcp uses
+// octet-counting framing (RFC 6587). The connection is (re)established lazily.
+type Sink struct {
+	conn     net.Conn
+	network  string
+	address  string
+	appName  string
+	hostname string
+	facility int
+	mu       sync.Mutex
+}
+
+// New creates a syslog sink from the given configuration.
+func New(cfg config.SyslogConfig) *Sink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &Sink{
+		network:  cfg.GetNetwork(),
+		address:  cfg.GetAddress(),
+		appName:  cfg.GetAppName(),
+		hostname: hostname,
+		facility: config.SyslogFacilities[cfg.GetFacility()],
+	}
+}
+
+// Send writes one event. msgID identifies the event type (e.g. BACKEND_DOWN)
+// and params become structured-data parameters.
+func (s *Sink) Send(severity Severity, msgID, msg string, params map[string]string) error {
+	line := s.format(time.Now(), severity, msgID, msg, params)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Retry once on a fresh connection, e.g. after the collector restarted.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = net.DialTimeout(s.network, s.address, writeTimeout); err != nil {
+				s.conn = nil
+				return fmt.Errorf("failed to connect to syslog %s %s: %w", s.network, s.address, err)
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err = s.conn.Write(s.frame(line)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return fmt.Errorf("failed to write to syslog %s %s: %w", s.network, s.address, err)
+}
+
+// Close closes the connection to the collector.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format renders an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ELEMENT] MSG
+func (s *Sink) format(now time.Time, severity Severity, msgID, msg string, params map[string]string) string {
+	pri := s.facility*8 + int(severity)
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		pri,
+		now.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+		msgID,
+		structuredData(params),
+		msg,
+	)
+}
+
+// frame applies the transport framing to a formatted message.
+func (s *Sink) frame(line string) []byte {
+	if s.network == "tcp" {
+		return []byte(fmt.Sprintf("%d %s", len(line), line))
+	}
+	return []byte(line)
+}
+
+// structuredData renders params as a single SD-ELEMENT with sorted parameter names.
+func structuredData(params map[string]string) string {
+	if len(params) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("[" + sdID)
+	for _, name := range names {
+		fmt.Fprintf(&b, " %s=\"%s\"", name, escapeParamValue(params[name]))
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// escapeParamValue escapes '"', '\' and ']' as required by RFC 5424 section 6.3.3.
+func escapeParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
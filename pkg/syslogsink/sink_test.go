@@ -0,0 +1,78 @@
+package syslogsink
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+)
+
+func TestFormat(t *testing.T) {
+	sink := New(config.SyslogConfig{Facility: "local0", AppName: "lb"})
+	sink.hostname = "director-1"
+
+	now := time.Date(2026, 3, 1, 14, 2, 0, 0, time.UTC)
+	msg := sink.format(now, SeverityWarning, "BACKEND_DOWN", "backend down", map[string]string{
+		"service": "web",
+		"backend": "192.168.1.10:8080",
+		"reason":  `said "no" [x]`,
+	})
+
+	prefix := "<132>1 2026-03-01T14:02:00Z director-1 lb "
+	if !strings.HasPrefix(msg, prefix) {
+		t.Fatalf("expected prefix %q, got %q", prefix, msg)
+	}
+	suffix := ` BACKEND_DOWN [ezlb@32473 backend="192.168.1.10:8080" reason="said \"no\" [x\]" service="web"] backend down`
+	if !strings.HasSuffix(msg, suffix) {
+		t.Errorf("expected suffix %q, got %q", suffix, msg)
+	}
+
+	if got := sink.format(now, SeverityNotice, "X", "m", nil); !strings.HasSuffix(got, " X - m") {
+		t.Errorf("expected nil structured data, got %q", got)
+	}
+}
+
+func TestSendTCPUsesOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString(']')
+		received <- line
+	}()
+
+	sink := New(config.SyslogConfig{Network: "tcp", Address: listener.Addr().String()})
+	defer sink.Close()
+	if err := sink.Send(SeverityNotice, "BACKEND_UP", "backend up", map[string]string{"state": "up"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	select {
+	case line := <-received:
+		length, rest, ok := strings.Cut(line, " ")
+		if !ok || length == "" || !strings.HasPrefix(rest, "<29>1 ") {
+			t.Errorf("expected an octet-counted message, got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for syslog message")
+	}
+}
+
+func TestSendReportsConnectError(t *testing.T) {
+	sink := New(config.SyslogConfig{Network: "tcp", Address: "127.0.0.1:1"})
+	if err := sink.Send(SeverityNotice, "X", "m", nil); err == nil {
+		t.Error("expected an error when the collector is unreachable")
+	}
+}
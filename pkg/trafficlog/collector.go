@@ -7,6 +7,7 @@ import (
 
 	"github.com/easzlab/ezlb/pkg/config"
 	"github.com/easzlab/ezlb/pkg/logutil"
+	"github.com/easzlab/ezlb/pkg/lvs"
 	"github.com/easzlab/ezlb/pkg/metrics"
 	"go.uber.org/zap"
 )
@@ -23,7 +24,12 @@ type Collector struct {
 	stopCh        chan struct{}
 	stopped       chan struct{}
 	services      []config.ServiceConfig
-	mu            sync.RWMutex
+	// history holds the most recent service samples keyed by service name.
+	history map[string][]TrafficPoint
+	// lastSampled records when each service was last sampled, since services
+	// may use a stats interval different from the global one.
+	lastSampled map[string]time.Time
+	mu          sync.RWMutex
 }
 
 // NewCollector creates a new traffic statistics collector.
@@ -40,6 +46,8 @@ func NewCollector(
 		systemLogger:  systemLogger,
 		services:      services,
 		trafficCfg:    trafficCfg,
+		history:       make(map[string][]TrafficPoint),
+		lastSampled:   make(map[string]time.Time),
 		stopCh:        make(chan struct{}),
 		stopped:       make(chan struct{}),
 	}
@@ -70,7 +78,7 @@ func (c *Collector) run() {
 	defer close(c.stopped)
 
 	c.mu.RLock()
-	interval := c.trafficCfg.GetInterval()
+	interval := c.tickInterval()
 	c.mu.RUnlock()
 
 	ticker := time.NewTicker(interval)
@@ -82,7 +90,7 @@ func (c *Collector) run() {
 			return
 		case <-ticker.C:
 			c.mu.RLock()
-			newInterval := c.trafficCfg.GetInterval()
+			newInterval := c.tickInterval()
 			enabled := c.trafficCfg.IsEnabled()
 			c.mu.RUnlock()
 
@@ -101,15 +109,24 @@ func (c *Collector) run() {
 	}
 }
 
-// collect performs a single collection cycle: gather stats and write raw data logs.
+// collect performs a single collection cycle: gather stats, keep the services
+// whose sampling interval has elapsed, and write raw data logs and metrics.
 func (c *Collector) collect() {
 	snapshot := c.gatherSnapshot()
 	if snapshot == nil {
 		return
 	}
 
+	now := time.Now()
+	snapshot = filterSnapshot(snapshot, c.dueServiceKeys(now))
+	if len(snapshot.Services) == 0 && len(snapshot.Backends) == 0 {
+		return
+	}
+
 	c.logRawStats(snapshot)
 	c.updateMetrics(snapshot)
+	c.recordHistory(snapshot, now)
+	c.updateRates(snapshot)
 }
 
 // gatherSnapshot collects current statistics from all providers.
@@ -241,17 +258,28 @@ func backendCurrentConnections(stats BackendTrafficStats) uint64 {
 }
 
 // buildServiceConfigMap builds a lookup map from service key (listen/protocol format)
-// to ServiceConfig. The key format matches ServiceKeyFromIPVS().String().
+// to ServiceConfig. The key format matches ServiceKeyFromIPVS().String(). A
+// service with several listen addresses maps each of them to a copy listening
+// on that address only.
 func buildServiceConfigMap(services []config.ServiceConfig) map[string]config.ServiceConfig {
 	result := make(map[string]config.ServiceConfig, len(services))
-	for _, svc := range services {
-		// Build key matching IPVS format: "ip:port/protocol"
-		key := svc.Listen + "/" + svc.Protocol
-		result[key] = svc
+	for _, svc := range config.ExpandListens(services) {
+		result[serviceKey(svc)] = svc
 	}
 	return result
 }
 
+// serviceKey returns the key of a service in IPVS format: "ip:port/protocol",
+// or "fwmark:N" for firewall-mark services.
+func serviceKey(svc config.ServiceConfig) string {
+	if svc.FWMark != 0 {
+		if key, err := lvs.ServiceKeyFromConfig(svc); err == nil {
+			return key.String()
+		}
+	}
+	return svc.Listen + "/" + svc.Protocol
+}
+
 // isTrafficLogEnabled returns true if the per-service traffic log flag
 // is explicitly set to true. A nil pointer (default) or false means disabled.
 func isTrafficLogEnabled(trafficLog *bool) bool {
@@ -0,0 +1,61 @@
+package trafficlog
+
+import "time"
+
+// historySize is the number of samples kept per service for the dashboard.
+// At the default 10s interval this covers the last 20 minutes.
+const historySize = 120
+
+// recordHistory appends the service counters of a snapshot to the bounded
+// per-service history. The counters of a service's listen addresses are
+// summed. Services no longer in the config are dropped.
+func (c *Collector) recordHistory(snapshot *TrafficSnapshot, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	svcConfigMap := buildServiceConfigMap(c.services)
+	current := make(map[string]struct{}, len(svcConfigMap))
+	for _, svcCfg := range svcConfigMap {
+		current[svcCfg.Name] = struct{}{}
+	}
+	samples := make(map[string]TrafficPoint)
+	for key, stats := range snapshot.Services {
+		svcCfg, ok := svcConfigMap[key]
+		if !ok {
+			continue
+		}
+		sample := samples[svcCfg.Name]
+		sample.Time = now
+		sample.Connections += stats.Connections
+		sample.InBytes += stats.InBytes
+		sample.OutBytes += stats.OutBytes
+		samples[svcCfg.Name] = sample
+	}
+
+	for name, sample := range samples {
+		points := append(c.history[name], sample)
+		if len(points) > historySize {
+			points = points[len(points)-historySize:]
+		}
+		c.history[name] = points
+	}
+
+	for name := range c.history {
+		if _, ok := current[name]; !ok {
+			delete(c.history, name)
+		}
+	}
+}
+
+// History returns a copy of the recent traffic samples keyed by service name,
+// oldest first.
+func (c *Collector) History() map[string][]TrafficPoint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make(map[string][]TrafficPoint, len(c.history))
+	for name, points := range c.history {
+		result[name] = append([]TrafficPoint(nil), points...)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package trafficlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+func TestCollector_RecordHistory(t *testing.T) {
+	services := []config.ServiceConfig{
+		newTestServiceConfig("web", "10.0.0.1:80", "tcp", "rr", nil),
+	}
+	collector := NewCollector(&fakeLVSStatsProvider{}, zap.NewNop(), zap.NewNop(), services, newTestTrafficConfig(true, "10s"))
+
+	start := time.Now()
+	for i := 0; i < historySize+5; i++ {
+		snapshot := &TrafficSnapshot{
+			Services: map[string]ServiceTrafficStats{
+				"10.0.0.1:80/tcp": {Connections: uint64(i)},
+				"10.0.0.9:80/tcp": {Connections: uint64(i)},
+			},
+		}
+		collector.recordHistory(snapshot, start.Add(time.Duration(i)*time.Second))
+	}
+
+	history := collector.History()
+	if len(history) != 1 {
+		t.Fatalf("expected history for 1 configured service, got %d", len(history))
+	}
+	points := history["web"]
+	if len(points) != historySize {
+		t.Fatalf("expected %d points, got %d", historySize, len(points))
+	}
+	if points[0].Connections != 5 || points[len(points)-1].Connections != historySize+4 {
+		t.Errorf("expected oldest samples to be discarded, got first=%d last=%d",
+			points[0].Connections, points[len(points)-1].Connections)
+	}
+
+	collector.UpdateConfig(nil, newTestTrafficConfig(true, "10s"))
+	collector.recordHistory(&TrafficSnapshot{Services: map[string]ServiceTrafficStats{}}, time.Now())
+	if len(collector.History()) != 0 {
+		t.Error("expected history of removed services to be dropped")
+	}
+}
+
+func TestCollector_RecordHistory_SumsListenAddresses(t *testing.T) {
+	services := []config.ServiceConfig{
+		newTestServiceConfig("web", "10.0.0.1:80,10.0.0.2:80", "tcp", "rr", nil),
+	}
+	collector := NewCollector(&fakeLVSStatsProvider{}, zap.NewNop(), zap.NewNop(), services, newTestTrafficConfig(true, "10s"))
+
+	collector.recordHistory(&TrafficSnapshot{
+		Services: map[string]ServiceTrafficStats{
+			"10.0.0.1:80/tcp": {Connections: 3, InBytes: 100},
+			"10.0.0.2:80/tcp": {Connections: 4, InBytes: 200},
+		},
+	}, time.Now())
+
+	points := collector.History()["web"]
+	if len(points) != 1 || points[0].Connections != 7 || points[0].InBytes != 300 {
+		t.Fatalf("expected one sample summing both listen addresses, got %+v", points)
+	}
+}
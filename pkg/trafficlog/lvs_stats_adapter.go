@@ -7,8 +7,8 @@ import (
 )
 
 // lvsStatsAdapter implements LVSStatsProvider by adapting lvs.Manager.
-// It reuses GetServices() and GetDestinations() to retrieve statistics
-// without modifying the IPVSHandle interface.
+// It reuses GetServices() and GetServicesWithDestinations() to retrieve
+// statistics without modifying the IPVSHandle interface.
 type lvsStatsAdapter struct {
 	manager *lvs.Manager
 }
@@ -42,21 +42,15 @@ func (a *lvsStatsAdapter) ServiceStats() (map[string]ServiceTrafficStats, error)
 // BackendStats retrieves cumulative statistics for all IPVS backends (destinations).
 // The key format is "svcKey->dstKey" to uniquely identify each backend across services.
 func (a *lvsStatsAdapter) BackendStats() (map[string]BackendTrafficStats, error) {
-	services, err := a.manager.GetServices()
+	services, err := a.manager.GetServicesWithDestinations()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get IPVS services: %w", err)
+		return nil, fmt.Errorf("failed to get IPVS services with destinations: %w", err)
 	}
 
 	result := make(map[string]BackendTrafficStats)
-	for _, svc := range services {
-		svcKey := lvs.ServiceKeyFromIPVS(svc).String()
-
-		dests, err := a.manager.GetDestinations(svc)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get destinations for service %s: %w", svcKey, err)
-		}
-
-		for _, dst := range dests {
+	for _, entry := range services {
+		svcKey := lvs.ServiceKeyFromIPVS(entry.Service).String()
+		for _, dst := range entry.Destinations {
 			dstKey := lvs.DestinationKeyFromIPVS(dst).String()
 			fullKey := fmt.Sprintf("%s->%s", svcKey, dstKey)
 			activeConnections := connectionCountUint64(dst.ActiveConnections)
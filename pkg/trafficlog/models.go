@@ -1,5 +1,7 @@
 package trafficlog
 
+import "time"
+
 // ServiceTrafficStats holds cumulative IPVS service-level statistics.
 type ServiceTrafficStats struct {
 	Connections uint64
@@ -33,3 +35,12 @@ type LVSStatsProvider interface {
 	ServiceStats() (map[string]ServiceTrafficStats, error)
 	BackendStats() (map[string]BackendTrafficStats, error)
 }
+
+// TrafficPoint is a sample of cumulative service counters kept in the
+// collector's short in-memory history.
+type TrafficPoint struct {
+	Time        time.Time
+	Connections uint64
+	InBytes     uint64
+	OutBytes    uint64
+}
@@ -0,0 +1,123 @@
+package trafficlog
+
+import (
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"github.com/easzlab/ezlb/pkg/metrics"
+)
+
+// tickInterval returns how often the collector wakes up: the shortest
+// sampling interval of the global config and all services.
+// Must be called with c.mu held.
+func (c *Collector) tickInterval() time.Duration {
+	interval := c.trafficCfg.GetInterval()
+	for _, svc := range c.services {
+		interval = min(interval, svc.Stats.GetInterval(c.trafficCfg))
+	}
+	return interval
+}
+
+// dueServiceKeys returns the keys of services whose sampling interval has
+// elapsed since their last sample, and marks them as sampled at now.
+func (c *Collector) dueServiceKeys(now time.Time) map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Allow half a tick of jitter so a service sampled every N ticks is not
+	// pushed to N+1 by timer drift.
+	tolerance := c.tickInterval() / 2
+	due := make(map[string]bool, len(c.services))
+	sampled := make(map[string]time.Time, len(c.services))
+	for _, svc := range c.services {
+		last, ok := c.lastSampled[svc.Name]
+		if ok && now.Sub(last) < svc.Stats.GetInterval(c.trafficCfg)-tolerance {
+			sampled[svc.Name] = last
+			continue
+		}
+		for _, expanded := range config.ExpandListens([]config.ServiceConfig{svc}) {
+			due[serviceKey(expanded)] = true
+		}
+		sampled[svc.Name] = now
+	}
+	c.lastSampled = sampled
+	return due
+}
+
+// filterSnapshot keeps only the service and backend stats of the given services.
+func filterSnapshot(snapshot *TrafficSnapshot, serviceKeys map[string]bool) *TrafficSnapshot {
+	filtered := &TrafficSnapshot{
+		Services: make(map[string]ServiceTrafficStats, len(serviceKeys)),
+		Backends: make(map[string]BackendTrafficStats),
+	}
+	for key, stats := range snapshot.Services {
+		if serviceKeys[key] {
+			filtered.Services[key] = stats
+		}
+	}
+	for key, stats := range snapshot.Backends {
+		if serviceKeys[stats.ServiceKey] {
+			filtered.Backends[key] = stats
+		}
+	}
+	return filtered
+}
+
+// updateRates exports per-second connection and byte rates of the sampled
+// services, computed from their history over each service's stats window.
+func (c *Collector) updateRates(snapshot *TrafficSnapshot) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// History is kept per service name, so rates cover all listen addresses
+	svcConfigMap := buildServiceConfigMap(c.services)
+	sampled := make(map[string]bool, len(snapshot.Services))
+	for key := range snapshot.Services {
+		if svcCfg, ok := svcConfigMap[key]; ok {
+			sampled[svcCfg.Name] = true
+		}
+	}
+	for _, svcCfg := range c.services {
+		if !sampled[svcCfg.Name] {
+			continue
+		}
+		rate, ok := windowRate(c.history[svcCfg.Name], svcCfg.Stats.GetWindow(c.trafficCfg))
+		if !ok {
+			continue
+		}
+		metrics.SetServiceRates(svcCfg.Name, svcCfg.Listen, svcCfg.Protocol,
+			rate.Connections, rate.InBytes, rate.OutBytes)
+	}
+}
+
+// trafficRate holds per-second traffic rates.
+type trafficRate struct {
+	Connections float64
+	InBytes     float64
+	OutBytes    float64
+}
+
+// windowRate computes rates between the newest sample and the oldest sample
+// still inside the window, using at least the previous sample. It reports
+// false if fewer than two samples exist or the counters were reset.
+func windowRate(points []TrafficPoint, window time.Duration) (trafficRate, bool) {
+	if len(points) < 2 {
+		return trafficRate{}, false
+	}
+	newest := points[len(points)-1]
+	oldest := points[len(points)-2]
+	for i := len(points) - 3; i >= 0 && newest.Time.Sub(points[i].Time) <= window; i-- {
+		oldest = points[i]
+	}
+
+	seconds := newest.Time.Sub(oldest.Time).Seconds()
+	if seconds <= 0 || newest.Connections < oldest.Connections ||
+		newest.InBytes < oldest.InBytes || newest.OutBytes < oldest.OutBytes {
+		return trafficRate{}, false
+	}
+	return trafficRate{
+		Connections: float64(newest.Connections-oldest.Connections) / seconds,
+		InBytes:     float64(newest.InBytes-oldest.InBytes) / seconds,
+		OutBytes:    float64(newest.OutBytes-oldest.OutBytes) / seconds,
+	}, true
+}
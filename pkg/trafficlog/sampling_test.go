@@ -0,0 +1,98 @@
+package trafficlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/easzlab/ezlb/pkg/config"
+	"go.uber.org/zap"
+)
+
+func TestCollector_PerServiceSamplingInterval(t *testing.T) {
+	fast := newTestServiceConfig("fast", "10.0.0.1:80", "tcp", "rr", nil)
+	fast.Stats = config.ServiceStatsConfig{Interval: "2s"}
+	slow := newTestServiceConfig("slow", "10.0.0.2:80", "tcp", "rr", nil)
+	collector := NewCollector(&fakeLVSStatsProvider{}, zap.NewNop(), zap.NewNop(),
+		[]config.ServiceConfig{fast, slow}, newTestTrafficConfig(true, "10s"))
+
+	collector.mu.RLock()
+	tick := collector.tickInterval()
+	collector.mu.RUnlock()
+	if tick != 2*time.Second {
+		t.Fatalf("expected tick interval of the fastest service (2s), got %s", tick)
+	}
+
+	start := time.Now()
+	due := collector.dueServiceKeys(start)
+	if !due["10.0.0.1:80/tcp"] || !due["10.0.0.2:80/tcp"] {
+		t.Fatalf("expected all services due on first sample, got %v", due)
+	}
+	due = collector.dueServiceKeys(start.Add(2 * time.Second))
+	if !due["10.0.0.1:80/tcp"] || due["10.0.0.2:80/tcp"] {
+		t.Errorf("expected only fast service due after 2s, got %v", due)
+	}
+	// 9.5s is within half a tick of the 10s interval
+	due = collector.dueServiceKeys(start.Add(9500 * time.Millisecond))
+	if !due["10.0.0.2:80/tcp"] {
+		t.Errorf("expected slow service due after its interval, got %v", due)
+	}
+}
+
+func TestFilterSnapshot(t *testing.T) {
+	snapshot := &TrafficSnapshot{
+		Services: map[string]ServiceTrafficStats{
+			"10.0.0.1:80/tcp": {Connections: 1},
+			"10.0.0.2:80/tcp": {Connections: 2},
+		},
+		Backends: map[string]BackendTrafficStats{
+			"a": {ServiceKey: "10.0.0.1:80/tcp"},
+			"b": {ServiceKey: "10.0.0.2:80/tcp"},
+		},
+	}
+	filtered := filterSnapshot(snapshot, map[string]bool{"10.0.0.2:80/tcp": true})
+	if len(filtered.Services) != 1 || filtered.Services["10.0.0.2:80/tcp"].Connections != 2 {
+		t.Errorf("unexpected filtered services: %v", filtered.Services)
+	}
+	if _, ok := filtered.Backends["b"]; !ok || len(filtered.Backends) != 1 {
+		t.Errorf("unexpected filtered backends: %v", filtered.Backends)
+	}
+}
+
+func TestWindowRate(t *testing.T) {
+	start := time.Now()
+	points := make([]TrafficPoint, 0, 7)
+	for i := 0; i <= 6; i++ {
+		points = append(points, TrafficPoint{
+			Time:        start.Add(time.Duration(i) * 10 * time.Second),
+			Connections: uint64(i * 100),
+			InBytes:     uint64(i * 1000),
+			OutBytes:    uint64(i * i * 1000),
+		})
+	}
+
+	rate, ok := windowRate(points, 30*time.Second)
+	if !ok {
+		t.Fatal("expected rate to be computed")
+	}
+	if rate.Connections != 10 || rate.InBytes != 100 {
+		t.Errorf("expected 10 conn/s and 100 B/s, got %+v", rate)
+	}
+	// out bytes: (36000-9000)/30s
+	if rate.OutBytes != 900 {
+		t.Errorf("expected out rate over the 30s window to be 900 B/s, got %v", rate.OutBytes)
+	}
+
+	// A window shorter than the interval still uses the previous sample
+	rate, ok = windowRate(points, time.Second)
+	if !ok || rate.OutBytes != 1100 {
+		t.Errorf("expected rate over the last two samples, got %+v ok=%v", rate, ok)
+	}
+
+	if _, ok := windowRate(points[:1], time.Minute); ok {
+		t.Error("expected no rate from a single sample")
+	}
+	reset := []TrafficPoint{points[5], {Time: points[6].Time, Connections: 1}}
+	if _, ok := windowRate(reset, time.Minute); ok {
+		t.Error("expected no rate after a counter reset")
+	}
+}
@@ -4,7 +4,9 @@ package e2e
 
 import (
 	"bytes"
+	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
@@ -260,12 +262,8 @@ services:
 }
 
 // --- Test 5: Service removal between two once executions ---
-// Note: In `once` mode, each execution creates a fresh Reconciler with an empty
-// `managed` map. The Reconciler only tracks services it creates during the current
-// run, so it will NOT delete services from a previous run that are no longer in
-// the config. This test verifies this actual behavior: after removing a service
-// from config and running `once` again, the old service still exists in IPVS
-// because the new Reconciler doesn't know about it.
+// Each `once` execution records the services it manages in global.state_file,
+// so the next execution deletes those that have been removed from the config.
 
 func TestE2E_OnceMode_ServiceRemoval(t *testing.T) {
 	flushIPVS(t)
@@ -274,6 +272,7 @@ func TestE2E_OnceMode_ServiceRemoval(t *testing.T) {
 	initialYAML := `
 global:
   log_level: info
+  state_file: %s
 services:
   - name: web-service
     listen: 10.0.0.1:80
@@ -296,7 +295,8 @@ services:
         weight: 1
 `
 	dir := t.TempDir()
-	configPath := writeTestConfig(t, dir, initialYAML)
+	stateFile := filepath.Join(dir, "state.json")
+	configPath := writeTestConfig(t, dir, fmt.Sprintf(initialYAML, stateFile))
 
 	// First execution: creates 2 services
 	runEzlbOnce(t, configPath)
@@ -306,6 +306,7 @@ services:
 	updatedYAML := `
 global:
   log_level: info
+  state_file: %s
 services:
   - name: web-service
     listen: 10.0.0.1:80
@@ -317,16 +318,15 @@ services:
       - address: 192.168.1.10:8080
         weight: 1
 `
-	writeTestConfig(t, dir, updatedYAML)
+	writeTestConfig(t, dir, fmt.Sprintf(updatedYAML, stateFile))
 
-	// Second execution: the new Reconciler's managed map is empty,
-	// so it will create/update web-service but NOT delete api-service.
-	// Both services remain in IPVS.
+	// Second execution: api-service is in the state file but no longer in
+	// the config, so it is deleted.
 	runEzlbOnce(t, configPath)
 
 	services := getIPVSServices(t)
-	if len(services) != 2 {
-		t.Fatalf("expected 2 IPVS services (once mode does not clean up unmanaged services), got %d", len(services))
+	if len(services) != 1 {
+		t.Fatalf("expected 1 IPVS service after removing api-service, got %d", len(services))
 	}
 
 	// Verify web-service still exists and is correct
@@ -335,10 +335,8 @@ services:
 		t.Fatal("expected web-service (10.0.0.1:80) to still exist")
 	}
 
-	// Verify api-service still exists (orphaned from previous run)
-	apiSvc := findServiceByAddress(services, "10.0.0.2", 443)
-	if apiSvc == nil {
-		t.Fatal("expected api-service (10.0.0.2:443) to still exist (once mode does not clean up)")
+	if apiSvc := findServiceByAddress(services, "10.0.0.2", 443); apiSvc != nil {
+		t.Fatal("expected api-service (10.0.0.2:443) to be deleted")
 	}
 }
 